@@ -3,7 +3,9 @@ module github.com/actionsum/actionsum
 go 1.24.1
 
 require (
+	github.com/godbus/dbus/v5 v5.2.2
 	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.32.0
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.31.1
 )
@@ -12,5 +14,5 @@ require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/mattn/go-sqlite3 v1.14.32 // indirect
-	golang.org/x/text v0.32.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
 )
@@ -0,0 +1,223 @@
+// Package alert notifies about conditions worth knowing as they arise, such
+// as a very long day or the tracker having stopped recording.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/internal/tracker"
+)
+
+const checkInterval = time.Minute
+
+// Evaluator checks the configured alert rules every minute. Each rule fires
+// once when its condition starts to hold: active-time rules at most once a
+// day, outage rules again only after recording has resumed, which is also
+// notified.
+type Evaluator struct {
+	cfg      *config.Config
+	repo     *database.Repository
+	live     *tracker.Live
+	notifier notify.Notifier
+	rules    []config.AlertRule
+
+	started time.Time
+	day     time.Time
+
+	// firedAt holds when each rule last fired, by index, while it holds.
+	firedAt map[int]time.Time
+
+	// excusedAt is when the user was last seen locked, idle, paused or
+	// outside working hours, none of which record anything.
+	excusedAt time.Time
+}
+
+// NewEvaluator returns nil when no alerts are configured. notifier may be nil
+// to only log.
+func NewEvaluator(cfg *config.Config, repo *database.Repository, live *tracker.Live, notifier notify.Notifier) *Evaluator {
+	if len(cfg.Notify.Alerts) == 0 {
+		return nil
+	}
+	return &Evaluator{
+		cfg:      cfg,
+		repo:     repo,
+		live:     live,
+		notifier: notifier,
+		rules:    cfg.Notify.Alerts,
+		started:  time.Now(),
+		firedAt:  make(map[int]time.Time),
+	}
+}
+
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := e.check(now); err != nil {
+				log.Printf("Alert check failed: %v", err)
+			}
+		}
+	}
+}
+
+func (e *Evaluator) check(now time.Time) error {
+	if day := startOfDay(now); !day.Equal(e.day) {
+		e.day = day
+		for i, rule := range e.rules {
+			if rule.Kind == config.AlertActive {
+				delete(e.firedAt, i)
+			}
+		}
+	}
+	e.observePresence(now)
+
+	for i, rule := range e.rules {
+		var msg *notify.Message
+		var err error
+		switch rule.Kind {
+		case config.AlertActive:
+			msg, err = e.checkActive(i, rule, now)
+		case config.AlertOutage:
+			msg, err = e.checkOutage(i, rule, now)
+		}
+		if err != nil {
+			return err
+		}
+		if msg != nil {
+			e.send(rule, *msg)
+		}
+	}
+	return nil
+}
+
+// observePresence notes when the tracker last saw a state in which it
+// rightly records nothing. A presence that hasn't been updated for a few
+// polls says nothing: the tracker may be stuck.
+func (e *Evaluator) observePresence(now time.Time) {
+	if e.live == nil {
+		return
+	}
+	p := e.live.Current()
+	if now.Sub(p.UpdatedAt) > 2*e.cfg.Tracker.MaxPollInterval {
+		return
+	}
+	switch p.State {
+	case tracker.PresenceLocked, tracker.PresenceIdle, tracker.PresencePaused, tracker.PresenceOffHours:
+		e.excusedAt = now
+	}
+}
+
+// checkActive returns the notification for an active-time rule once today's
+// active time exceeds its threshold.
+func (e *Evaluator) checkActive(i int, rule config.AlertRule, now time.Time) (*notify.Message, error) {
+	if _, fired := e.firedAt[i]; fired {
+		return nil, nil
+	}
+	totals, err := e.repo.GetDailyAppTotals(database.Query{Since: startOfDay(now), Until: now.Add(time.Minute)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get today's totals: %w", err)
+	}
+	var active time.Duration
+	for _, total := range totals {
+		active += time.Duration(total.TotalSeconds) * time.Second
+	}
+	if active <= rule.Threshold {
+		return nil, nil
+	}
+	e.firedAt[i] = now
+	return &notify.Message{
+		Title:   fmt.Sprintf("Active for over %v today", rule.Threshold),
+		Body:    fmt.Sprintf("%v of active time recorded today. Time to call it a day?", active.Round(time.Minute)),
+		Urgency: "normal",
+		Data: map[string]any{
+			"alert":             rule.String(),
+			"kind":              rule.Kind,
+			"threshold_seconds": int64(rule.Threshold.Seconds()),
+			"active_seconds":    int64(active.Seconds()),
+		},
+	}, nil
+}
+
+// checkOutage returns the notification for an outage rule that starts to
+// hold, or stops holding because something was recorded. The quiet stretch
+// runs from the end of the latest event, the daemon's start or the last
+// excused state, whichever is latest; an outage ended by locking the screen
+// is cleared without notice.
+func (e *Evaluator) checkOutage(i int, rule config.AlertRule, now time.Time) (*notify.Message, error) {
+	latest, err := e.repo.GetLatest()
+	if err != nil {
+		return nil, err
+	}
+	quietSince := e.started
+	var lastRecorded time.Time
+	if latest != nil {
+		lastRecorded = latest.Timestamp.Add(time.Duration(latest.Duration) * time.Second)
+		if lastRecorded.After(quietSince) {
+			quietSince = lastRecorded
+		}
+	}
+	if e.excusedAt.After(quietSince) {
+		quietSince = e.excusedAt
+	}
+
+	quiet := now.Sub(quietSince)
+	data := map[string]any{
+		"alert":             rule.String(),
+		"kind":              rule.Kind,
+		"threshold_seconds": int64(rule.Threshold.Seconds()),
+		"quiet_seconds":     int64(quiet.Seconds()),
+	}
+	firedAt, fired := e.firedAt[i]
+	switch {
+	case quiet >= rule.Threshold && !fired:
+		e.firedAt[i] = now
+		body := fmt.Sprintf("Nothing recorded for %v although the screen is unlocked.", quiet.Round(time.Minute))
+		if !lastRecorded.IsZero() {
+			body = fmt.Sprintf("Nothing recorded since %s although the screen is unlocked.", lastRecorded.Local().Format("15:04"))
+		}
+		return &notify.Message{
+			Title:   "actionsum has stopped recording",
+			Body:    body + " Run \"actionsum doctor\" or check the daemon's log.",
+			Urgency: "critical",
+			Data:    data,
+		}, nil
+	case quiet < rule.Threshold && fired:
+		delete(e.firedAt, i)
+		if !lastRecorded.After(firedAt) {
+			return nil, nil
+		}
+		data["resolved"] = true
+		return &notify.Message{
+			Title:   "actionsum is recording again",
+			Body:    "Activity is being recorded again.",
+			Urgency: "low",
+			Data:    data,
+		}, nil
+	}
+	return nil, nil
+}
+
+func (e *Evaluator) send(rule config.AlertRule, msg notify.Message) {
+	log.Printf("Alert %s: %s. %s", rule, msg.Title, msg.Body)
+	if e.notifier == nil {
+		return
+	}
+	if err := e.notifier.Notify(msg); err != nil {
+		log.Printf("Failed to send alert notification: %v", err)
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
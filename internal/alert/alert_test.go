@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/internal/tracker"
+)
+
+type recorder []notify.Message
+
+func (r *recorder) Notify(msg notify.Message) error {
+	*r = append(*r, msg)
+	return nil
+}
+
+func record(t *testing.T, repo *database.Repository, start time.Time, seconds int64) {
+	t.Helper()
+	event := &models.FocusEvent{Timestamp: start, AppName: "code", WindowTitle: "main.go", Duration: seconds, DisplayServer: "wayland"}
+	if err := repo.Create(event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+}
+
+func TestOutageAlert(t *testing.T) {
+	repo := database.NewRepository(database.OpenTest(t))
+	now := time.Now()
+	record(t, repo, now.Add(-50*time.Minute), 600)
+
+	cfg := config.Default()
+	cfg.Notify.Alerts = []config.AlertRule{{Kind: config.AlertOutage, Threshold: 30 * time.Minute}}
+	live := tracker.NewLive()
+	var sent recorder
+	e := NewEvaluator(cfg, repo, live, &sent)
+	e.started = now.Add(-2 * time.Hour)
+
+	if err := e.check(now); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if err := e.check(now.Add(time.Minute)); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if len(sent) != 1 || sent[0].Urgency != "critical" {
+		t.Fatalf("sent = %+v, want one critical outage alert", sent)
+	}
+
+	// Recording again resolves the outage.
+	record(t, repo, now.Add(2*time.Minute), 60)
+	if err := e.check(now.Add(3 * time.Minute)); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if len(sent) != 2 || sent[1].Data.(map[string]any)["resolved"] != true {
+		t.Fatalf("sent = %+v, want the outage resolved", sent)
+	}
+
+	// Time spent locked is no outage.
+	later := now.Add(2 * time.Hour)
+	live.Set(tracker.Presence{State: tracker.PresenceLocked, UpdatedAt: later.Add(-10 * time.Second)})
+	if err := e.check(later); err != nil {
+		t.Fatalf("check() error = %v", err)
+	}
+	if len(sent) != 2 {
+		t.Errorf("sent = %+v, want no alert while locked", sent[2:])
+	}
+}
+
+func TestActiveAlert(t *testing.T) {
+	now := time.Now()
+	if startOfDay(now.Add(-20 * time.Minute)).Before(startOfDay(now)) {
+		t.Skip("too close to midnight for today's totals to hold the seeded events")
+	}
+	repo := database.NewRepository(database.OpenTest(t))
+	record(t, repo, now.Add(-20*time.Minute), 900)
+
+	cfg := config.Default()
+	cfg.Notify.Alerts = []config.AlertRule{{Kind: config.AlertActive, Threshold: 10 * time.Minute}}
+	var sent recorder
+	e := NewEvaluator(cfg, repo, nil, &sent)
+
+	for range 2 {
+		if err := e.check(now); err != nil {
+			t.Fatalf("check() error = %v", err)
+		}
+	}
+	if len(sent) != 1 || sent[0].Data.(map[string]any)["active_seconds"] != int64(900) {
+		t.Errorf("sent = %+v, want one alert for 15m of active time", sent)
+	}
+
+	if NewEvaluator(config.Default(), repo, nil, &sent) != nil {
+		t.Error("NewEvaluator() without alerts = non-nil")
+	}
+}
@@ -0,0 +1,46 @@
+package analytics
+
+import "github.com/actionsum/actionsum/internal/models"
+
+type achievement struct {
+	id          string
+	name        string
+	description string
+	unlocked    func(stats *models.FocusStats) bool
+}
+
+// Streak achievements count over the whole history; session achievements
+// only over the period the stats were computed for.
+var catalog = []achievement{
+	{"first-goal", "First goal", "Meet the daily goal", streakAtLeast(1)},
+	{"streak-3", "Warming up", "Meet the daily goal 3 days in a row", streakAtLeast(3)},
+	{"streak-7", "Full week", "Meet the daily goal 7 days in a row", streakAtLeast(7)},
+	{"streak-30", "Habit formed", "Meet the daily goal 30 days in a row", streakAtLeast(30)},
+	{"deep-work", "Deep work", "Focus on one app for an hour without switching", sessionAtLeast(3600)},
+	{"in-the-zone", "In the zone", "Focus on one app for two hours without switching", sessionAtLeast(7200)},
+}
+
+func streakAtLeast(days int) func(*models.FocusStats) bool {
+	return func(stats *models.FocusStats) bool {
+		return stats.LongestStreak >= days
+	}
+}
+
+func sessionAtLeast(seconds int64) func(*models.FocusStats) bool {
+	return func(stats *models.FocusStats) bool {
+		return stats.LongestSession != nil && stats.LongestSession.Seconds >= seconds
+	}
+}
+
+func achievements(stats *models.FocusStats) []models.Achievement {
+	result := make([]models.Achievement, len(catalog))
+	for i, a := range catalog {
+		result[i] = models.Achievement{
+			ID:          a.id,
+			Name:        a.name,
+			Description: a.description,
+			Unlocked:    a.unlocked(stats),
+		}
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/budget"
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// historyDays bounds how far back goal streaks are traced.
+const historyDays = 365
+
+const dayLayout = "2006-01-02"
+
+type Analyzer struct {
+	config *config.Config
+	repo   *database.Repository
+}
+
+func New(cfg *config.Config, repo *database.Repository) *Analyzer {
+	return &Analyzer{
+		config: cfg,
+		repo:   repo,
+	}
+}
+
+// FocusStats traces goal streaks up to now and finds the longest focus
+// session in [start, end). Events are limited to profile unless it is empty.
+func (a *Analyzer) FocusStats(start, end, now time.Time, profile string) (*models.FocusStats, error) {
+	stats := &models.FocusStats{GoalSeconds: int64(a.config.Budgets.DailyGoal.Seconds())}
+
+	if err := a.traceStreaks(stats, now, profile); err != nil {
+		return nil, err
+	}
+
+	events, err := a.repo.GetEvents(database.Query{Since: start, Until: end, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	stats.LongestSession = Metrics(Sessions(a.repo, events, a.config.SessionGap())).LongestSession
+
+	stats.Achievements = achievements(stats)
+	return stats, nil
+}
+
+// traceStreaks walks back over the recorded history. A day meets the goal
+// when its focus time reaches the daily goal and no budget was exceeded.
+// Neither days off nor today not having met the goal yet break a streak.
+func (a *Analyzer) traceStreaks(stats *models.FocusStats, now time.Time, profile string) error {
+	today := startOfDay(now)
+	first := today.AddDate(0, 0, -historyDays)
+
+	totals, err := a.repo.GetDailyAppTotals(database.Query{Since: first, Profile: profile})
+	if err != nil {
+		return fmt.Errorf("failed to get daily totals: %w", err)
+	}
+
+	budgets := budget.FromConfig(a.config, a.repo)
+	active := make(map[string]int64)
+	used := make(map[string][]int64)
+	for _, total := range totals {
+		active[total.Day] += total.TotalSeconds
+		if used[total.Day] == nil {
+			used[total.Day] = make([]int64, len(budgets))
+		}
+		for i, b := range budgets {
+			if b.Counts(total.AppName, total.Tag) {
+				used[total.Day][i] += total.TotalSeconds
+			}
+		}
+	}
+
+	met := func(day string) bool {
+		if active[day] == 0 || active[day] < stats.GoalSeconds {
+			return false
+		}
+		for i, b := range budgets {
+			if time.Duration(used[day][i])*time.Second > b.Limit {
+				return false
+			}
+		}
+		return true
+	}
+
+	daysOff := holiday.Load(a.config.Holidays)
+	run := 0
+	for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dayLayout)
+		if !met(key) {
+			if !day.Equal(today) && !daysOff.Contains(day) {
+				run = 0
+			}
+			continue
+		}
+		run++
+		if run > stats.LongestStreak {
+			stats.LongestStreak = run
+		}
+	}
+
+	todayKey := today.Format(dayLayout)
+	stats.TodaySeconds = active[todayKey]
+	stats.GoalMetToday = met(todayKey)
+	stats.CurrentStreak = run
+	return nil
+}
+
+// Sessions joins consecutive events in the same app, separated by no more
+// than maxGap, into focus sessions.
+func Sessions(repo *database.Repository, events []*models.FocusEvent, maxGap time.Duration) []models.FocusSession {
+	var sessions []models.FocusSession
+	for _, event := range events {
+		appName := repo.NormalizeAppName(event.AppName)
+		end := event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+		if n := len(sessions); n > 0 {
+			last := &sessions[n-1]
+			if last.AppName == appName && event.Timestamp.Sub(last.End) <= maxGap {
+				if end.After(last.End) {
+					last.End = end
+				}
+				last.Seconds = int64(last.End.Sub(last.Start).Seconds())
+				continue
+			}
+		}
+		sessions = append(sessions, models.FocusSession{
+			AppName: appName,
+			Start:   event.Timestamp,
+			End:     end,
+			Seconds: event.Duration,
+		})
+	}
+	return sessions
+}
+
+// Metrics counts sessions and the switches between apps, relative to the
+// time actually spent in sessions.
+func Metrics(sessions []models.FocusSession) *models.SessionMetrics {
+	metrics := &models.SessionMetrics{Sessions: len(sessions)}
+	if len(sessions) == 0 {
+		return metrics
+	}
+
+	var totalSeconds int64
+	for i := range sessions {
+		totalSeconds += sessions[i].Seconds
+		if i > 0 && sessions[i].AppName != sessions[i-1].AppName {
+			metrics.Switches++
+		}
+		if metrics.LongestSession == nil || sessions[i].Seconds > metrics.LongestSession.Seconds {
+			metrics.LongestSession = &sessions[i]
+		}
+	}
+
+	metrics.AverageSessionSeconds = totalSeconds / int64(len(sessions))
+	if totalSeconds > 0 {
+		metrics.SwitchesPerHour = float64(metrics.Switches) / (float64(totalSeconds) / 3600.0)
+	}
+	return metrics
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,326 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestTraceStreaks(t *testing.T) {
+	now := time.Date(2025, 3, 14, 18, 0, 0, 0, time.Local)
+	today := startOfDay(now)
+
+	type focus struct {
+		day     int // relative to today
+		app     string
+		minutes int64
+	}
+
+	tests := []struct {
+		name        string
+		focus       []focus
+		daysOff     string
+		limits      map[string]time.Duration
+		wantCurrent int
+		wantLongest int
+		wantToday   bool
+	}{
+		{
+			name:        "consecutive days",
+			focus:       []focus{{-2, "code", 90}, {-1, "code", 90}, {0, "code", 90}},
+			wantCurrent: 3,
+			wantLongest: 3,
+			wantToday:   true,
+		},
+		{
+			name:        "missed day breaks the run",
+			focus:       []focus{{-4, "code", 90}, {-3, "code", 90}, {-1, "code", 90}, {0, "code", 90}},
+			wantCurrent: 2,
+			wantLongest: 2,
+			wantToday:   true,
+		},
+		{
+			name:        "day off inside a run",
+			focus:       []focus{{-4, "code", 90}, {-3, "code", 90}, {-1, "code", 90}, {0, "code", 90}},
+			daysOff:     today.AddDate(0, 0, -2).Format(dayLayout) + " holiday\n",
+			wantCurrent: 4,
+			wantLongest: 4,
+			wantToday:   true,
+		},
+		{
+			name:        "today not yet met",
+			focus:       []focus{{-2, "code", 90}, {-1, "code", 90}, {0, "code", 30}},
+			wantCurrent: 2,
+			wantLongest: 2,
+		},
+		{
+			name:        "nothing yet today",
+			focus:       []focus{{-2, "code", 90}, {-1, "code", 90}},
+			wantCurrent: 2,
+			wantLongest: 2,
+		},
+		{
+			name:        "short day breaks the run",
+			focus:       []focus{{-4, "code", 90}, {-3, "code", 90}, {-2, "code", 90}, {-1, "code", 30}, {0, "code", 90}},
+			wantCurrent: 1,
+			wantLongest: 3,
+			wantToday:   true,
+		},
+		{
+			name:        "exceeded budget",
+			focus:       []focus{{-2, "code", 90}, {-1, "code", 90}, {-1, "firefox", 70}, {0, "code", 90}},
+			limits:      map[string]time.Duration{"firefox": time.Hour},
+			wantCurrent: 1,
+			wantLongest: 1,
+			wantToday:   true,
+		},
+		{
+			name:        "budget kept",
+			focus:       []focus{{-2, "code", 90}, {-1, "code", 90}, {-1, "firefox", 50}, {0, "code", 90}},
+			limits:      map[string]time.Duration{"firefox": time.Hour},
+			wantCurrent: 3,
+			wantLongest: 3,
+			wantToday:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := database.NewRepository(database.OpenTest(t))
+			cfg := config.Default()
+			cfg.Holidays.File = filepath.Join(t.TempDir(), "holidays")
+			if err := os.WriteFile(cfg.Holidays.File, []byte(tt.daysOff), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.limits != nil {
+				cfg.Budgets.Limits = tt.limits
+			}
+
+			offsets := make(map[int]time.Duration)
+			for _, f := range tt.focus {
+				at := today.AddDate(0, 0, f.day).Add(9*time.Hour + offsets[f.day])
+				offsets[f.day] += time.Duration(f.minutes) * time.Minute
+				event := &models.FocusEvent{Timestamp: at, AppName: f.app, Duration: f.minutes * 60, DisplayServer: "wayland"}
+				if err := repo.Create(event); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats := &models.FocusStats{GoalSeconds: int64(cfg.Budgets.DailyGoal.Seconds())}
+			if err := New(cfg, repo).traceStreaks(stats, now, ""); err != nil {
+				t.Fatalf("traceStreaks() error = %v", err)
+			}
+			if stats.CurrentStreak != tt.wantCurrent {
+				t.Errorf("CurrentStreak = %d, want %d", stats.CurrentStreak, tt.wantCurrent)
+			}
+			if stats.LongestStreak != tt.wantLongest {
+				t.Errorf("LongestStreak = %d, want %d", stats.LongestStreak, tt.wantLongest)
+			}
+			if stats.GoalMetToday != tt.wantToday {
+				t.Errorf("GoalMetToday = %v, want %v", stats.GoalMetToday, tt.wantToday)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	base := time.Date(2025, 3, 14, 9, 0, 0, 0, time.Local)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	event := func(minute int, app string, seconds int64) *models.FocusEvent {
+		return &models.FocusEvent{Timestamp: at(minute), AppName: app, Duration: seconds}
+	}
+
+	tests := []struct {
+		name   string
+		events []*models.FocusEvent
+		maxGap time.Duration
+		want   []models.FocusSession
+	}{
+		{
+			name: "no events",
+		},
+		{
+			name:   "adjacent events in one app are joined",
+			events: []*models.FocusEvent{event(0, "code", 300), event(5, "code", 300)},
+			want:   []models.FocusSession{{AppName: "code", Start: at(0), End: at(10), Seconds: 600}},
+		},
+		{
+			name:   "gap within maxGap is joined",
+			events: []*models.FocusEvent{event(0, "code", 300), event(6, "code", 240)},
+			maxGap: time.Minute,
+			want:   []models.FocusSession{{AppName: "code", Start: at(0), End: at(10), Seconds: 600}},
+		},
+		{
+			name:   "gap beyond maxGap splits",
+			events: []*models.FocusEvent{event(0, "code", 300), event(7, "code", 180)},
+			maxGap: time.Minute,
+			want: []models.FocusSession{
+				{AppName: "code", Start: at(0), End: at(5), Seconds: 300},
+				{AppName: "code", Start: at(7), End: at(10), Seconds: 180},
+			},
+		},
+		{
+			name:   "overlapping event ending earlier keeps the later end",
+			events: []*models.FocusEvent{event(0, "code", 600), event(2, "code", 60)},
+			want:   []models.FocusSession{{AppName: "code", Start: at(0), End: at(10), Seconds: 600}},
+		},
+		{
+			name:   "overlapping event ending later extends",
+			events: []*models.FocusEvent{event(0, "code", 300), event(3, "code", 420)},
+			want:   []models.FocusSession{{AppName: "code", Start: at(0), End: at(10), Seconds: 600}},
+		},
+		{
+			name:   "another app starts a new session",
+			events: []*models.FocusEvent{event(0, "code", 300), event(5, "firefox", 120), event(7, "code", 180)},
+			maxGap: time.Hour,
+			want: []models.FocusSession{
+				{AppName: "code", Start: at(0), End: at(5), Seconds: 300},
+				{AppName: "firefox", Start: at(5), End: at(7), Seconds: 120},
+				{AppName: "code", Start: at(7), End: at(10), Seconds: 180},
+			},
+		},
+	}
+
+	repo := database.NewRepository(database.OpenTest(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sessions(repo, tt.events, tt.maxGap)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Sessions() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].AppName != tt.want[i].AppName || !got[i].Start.Equal(tt.want[i].Start) ||
+					!got[i].End.Equal(tt.want[i].End) || got[i].Seconds != tt.want[i].Seconds {
+					t.Errorf("Sessions()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	session := func(app string, seconds int64) models.FocusSession {
+		return models.FocusSession{AppName: app, Seconds: seconds}
+	}
+
+	tests := []struct {
+		name            string
+		sessions        []models.FocusSession
+		wantSwitches    int
+		wantPerHour     float64
+		wantAverage     int64
+		wantLongestApp  string
+		wantLongestSecs int64
+	}{
+		{
+			name: "no sessions",
+		},
+		{
+			name:            "one session",
+			sessions:        []models.FocusSession{session("code", 1800)},
+			wantAverage:     1800,
+			wantLongestApp:  "code",
+			wantLongestSecs: 1800,
+		},
+		{
+			name:            "switches between apps",
+			sessions:        []models.FocusSession{session("code", 1800), session("firefox", 600), session("code", 1200), session("slack", 0)},
+			wantSwitches:    3,
+			wantPerHour:     3,
+			wantAverage:     900,
+			wantLongestApp:  "code",
+			wantLongestSecs: 1800,
+		},
+		{
+			name:            "same app twice is no switch",
+			sessions:        []models.FocusSession{session("code", 900), session("code", 900), session("firefox", 1800)},
+			wantSwitches:    1,
+			wantPerHour:     1,
+			wantAverage:     1200,
+			wantLongestApp:  "firefox",
+			wantLongestSecs: 1800,
+		},
+		{
+			name:            "first of equally long sessions is longest",
+			sessions:        []models.FocusSession{session("code", 600), session("firefox", 600)},
+			wantSwitches:    1,
+			wantPerHour:     3,
+			wantAverage:     600,
+			wantLongestApp:  "code",
+			wantLongestSecs: 600,
+		},
+		{
+			name:           "zero-length sessions",
+			sessions:       []models.FocusSession{session("code", 0), session("firefox", 0)},
+			wantSwitches:   1,
+			wantLongestApp: "code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Metrics(tt.sessions)
+			if got.Sessions != len(tt.sessions) {
+				t.Errorf("Sessions = %d, want %d", got.Sessions, len(tt.sessions))
+			}
+			if got.Switches != tt.wantSwitches {
+				t.Errorf("Switches = %d, want %d", got.Switches, tt.wantSwitches)
+			}
+			if got.SwitchesPerHour != tt.wantPerHour {
+				t.Errorf("SwitchesPerHour = %v, want %v", got.SwitchesPerHour, tt.wantPerHour)
+			}
+			if got.AverageSessionSeconds != tt.wantAverage {
+				t.Errorf("AverageSessionSeconds = %d, want %d", got.AverageSessionSeconds, tt.wantAverage)
+			}
+			if tt.wantLongestApp == "" {
+				if got.LongestSession != nil {
+					t.Errorf("LongestSession = %+v, want nil", got.LongestSession)
+				}
+				return
+			}
+			if got.LongestSession == nil || got.LongestSession.AppName != tt.wantLongestApp || got.LongestSession.Seconds != tt.wantLongestSecs {
+				t.Errorf("LongestSession = %+v, want %s for %ds", got.LongestSession, tt.wantLongestApp, tt.wantLongestSecs)
+			}
+		})
+	}
+}
+
+func TestSessionsMicroBreak(t *testing.T) {
+	base := time.Date(2025, 3, 14, 9, 0, 0, 0, time.Local)
+	// Two minutes in code, a 90 second break, then two more.
+	events := []*models.FocusEvent{
+		{Timestamp: base, AppName: "code", Duration: 120},
+		{Timestamp: base.Add(210 * time.Second), AppName: "code", Duration: 120},
+	}
+
+	tests := []struct {
+		name       string
+		microBreak time.Duration
+		want       int
+	}{
+		{"off", 0, 2},
+		{"shorter than the break", time.Minute, 2},
+		{"as long as the break", 90 * time.Second, 1},
+		{"longer than the break", 2 * time.Minute, 1},
+	}
+
+	repo := database.NewRepository(database.OpenTest(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Report.MicroBreak = tt.microBreak
+			sessions := Sessions(repo, events, cfg.SessionGap())
+			if len(sessions) != tt.want {
+				t.Fatalf("Sessions() = %+v, want %d sessions", sessions, tt.want)
+			}
+			if tt.want == 1 && sessions[0].Seconds != 330 {
+				t.Errorf("joined session lasts %ds, want 330s including the break", sessions[0].Seconds)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// Distributions describes each app's session lengths over days days, busiest
+// app first.
+func Distributions(sessions []models.FocusSession, days int) []models.AppDistribution {
+	if days < 1 {
+		days = 1
+	}
+
+	lengths := make(map[string][]int64)
+	for _, session := range sessions {
+		lengths[session.AppName] = append(lengths[session.AppName], session.Seconds)
+	}
+
+	result := make([]models.AppDistribution, 0, len(lengths))
+	for app, seconds := range lengths {
+		sort.Slice(seconds, func(i, j int) bool { return seconds[i] < seconds[j] })
+
+		dist := models.AppDistribution{
+			AppName:        app,
+			Sessions:       len(seconds),
+			MedianSeconds:  percentile(seconds, 50),
+			P90Seconds:     percentile(seconds, 90),
+			LongestSeconds: seconds[len(seconds)-1],
+			SessionsPerDay: float64(len(seconds)) / float64(days),
+		}
+		for _, s := range seconds {
+			dist.TotalSeconds += s
+		}
+		result = append(result, dist)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSeconds != result[j].TotalSeconds {
+			return result[i].TotalSeconds > result[j].TotalSeconds
+		}
+		return result[i].AppName < result[j].AppName
+	})
+	return result
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestPercentile(t *testing.T) {
+	ten := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		name   string
+		sorted []int64
+		p      int
+		want   int64
+	}{
+		{"one median", []int64{42}, 50, 42},
+		{"one p90", []int64{42}, 90, 42},
+		{"two median is the lower", []int64{10, 20}, 50, 10},
+		{"two p90 is the upper", []int64{10, 20}, 90, 20},
+		{"ten median", ten, 50, 5},
+		{"ten p90", ten, 90, 9},
+		{"ten p100", ten, 100, 10},
+		{"ten p0 is the lowest", ten, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %d) = %d, want %d", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	sessions := func(app string, seconds ...int64) []models.FocusSession {
+		var result []models.FocusSession
+		for _, s := range seconds {
+			result = append(result, models.FocusSession{AppName: app, Seconds: s})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		sessions []models.FocusSession
+		days     int
+		want     models.AppDistribution
+	}{
+		{
+			name:     "one session",
+			sessions: sessions("code", 600),
+			days:     1,
+			want:     models.AppDistribution{AppName: "code", Sessions: 1, TotalSeconds: 600, MedianSeconds: 600, P90Seconds: 600, LongestSeconds: 600, SessionsPerDay: 1},
+		},
+		{
+			name:     "two sessions out of order",
+			sessions: sessions("code", 1200, 600),
+			days:     4,
+			want:     models.AppDistribution{AppName: "code", Sessions: 2, TotalSeconds: 1800, MedianSeconds: 600, P90Seconds: 1200, LongestSeconds: 1200, SessionsPerDay: 0.5},
+		},
+		{
+			name:     "ten sessions",
+			sessions: sessions("code", 1000, 100, 900, 200, 800, 300, 700, 400, 600, 500),
+			days:     5,
+			want:     models.AppDistribution{AppName: "code", Sessions: 10, TotalSeconds: 5500, MedianSeconds: 500, P90Seconds: 900, LongestSeconds: 1000, SessionsPerDay: 2},
+		},
+		{
+			name:     "zero days count as one",
+			sessions: sessions("code", 100, 200),
+			days:     0,
+			want:     models.AppDistribution{AppName: "code", Sessions: 2, TotalSeconds: 300, MedianSeconds: 100, P90Seconds: 200, LongestSeconds: 200, SessionsPerDay: 2},
+		},
+		{
+			name:     "negative days count as one",
+			sessions: sessions("code", 100, 200, 300),
+			days:     -3,
+			want:     models.AppDistribution{AppName: "code", Sessions: 3, TotalSeconds: 600, MedianSeconds: 200, P90Seconds: 300, LongestSeconds: 300, SessionsPerDay: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distributions(tt.sessions, tt.days)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Distributions() = %+v, want [%+v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistributionsOrder(t *testing.T) {
+	sessions := []models.FocusSession{
+		{AppName: "slack", Seconds: 300},
+		{AppName: "code", Seconds: 600},
+		{AppName: "firefox", Seconds: 300},
+		{AppName: "code", Seconds: 600},
+	}
+	got := Distributions(sessions, 1)
+	var names []string
+	for _, dist := range got {
+		names = append(names, dist.AppName)
+	}
+	if len(names) != 3 || names[0] != "code" || names[1] != "firefox" || names[2] != "slack" {
+		t.Errorf("Distributions() order = %v, want [code firefox slack]", names)
+	}
+}
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+const (
+	// FragmentMaxSession is the longest session that still counts as a
+	// quick visit rather than focused work.
+	FragmentMaxSession = 2 * time.Minute
+
+	// FragmentMinSwitches is how many switches a run of short sessions needs
+	// before it is reported as fragmented.
+	FragmentMinSwitches = 4
+)
+
+// Fragments finds runs of short sessions in different apps, each starting
+// within FragmentMaxSession of the previous one ending, and tallies the app
+// pairs switched between in them, most frequent first.
+func Fragments(sessions []models.FocusSession) ([]models.FragmentedSegment, []models.SwitchPattern) {
+	var segments []models.FragmentedSegment
+	var run []models.FocusSession
+	totals := make(map[[2]string]int)
+
+	flush := func() {
+		if segment, pairs, ok := fragment(run); ok {
+			segments = append(segments, segment)
+			for apps, switches := range pairs {
+				totals[apps] += switches
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, session := range sessions {
+		if session.End.Sub(session.Start) > FragmentMaxSession {
+			flush()
+			continue
+		}
+		if n := len(run); n > 0 && session.Start.Sub(run[n-1].End) > FragmentMaxSession {
+			flush()
+		}
+		run = append(run, session)
+	}
+	flush()
+
+	patterns := make([]models.SwitchPattern, 0, len(totals))
+	for apps, switches := range totals {
+		patterns = append(patterns, models.SwitchPattern{Apps: apps, Switches: switches})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Switches != patterns[j].Switches {
+			return patterns[i].Switches > patterns[j].Switches
+		}
+		return pairLess(patterns[i].Apps, patterns[j].Apps)
+	})
+	return segments, patterns
+}
+
+func fragment(run []models.FocusSession) (models.FragmentedSegment, map[[2]string]int, bool) {
+	var switches int
+	pairs := make(map[[2]string]int)
+	seconds := make(map[string]int64)
+	for i, session := range run {
+		seconds[session.AppName] += session.Seconds
+		if i > 0 && session.AppName != run[i-1].AppName {
+			switches++
+			pairs[pairKey(run[i-1].AppName, session.AppName)]++
+		}
+	}
+	if switches < FragmentMinSwitches {
+		return models.FragmentedSegment{}, nil, false
+	}
+
+	apps := make([]string, 0, len(seconds))
+	for app := range seconds {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if seconds[apps[i]] != seconds[apps[j]] {
+			return seconds[apps[i]] > seconds[apps[j]]
+		}
+		return apps[i] < apps[j]
+	})
+
+	var top [2]string
+	best := 0
+	for apps, n := range pairs {
+		if n > best || (n == best && pairLess(apps, top)) {
+			top, best = apps, n
+		}
+	}
+
+	start, end := run[0].Start, run[len(run)-1].End
+	return models.FragmentedSegment{
+		Start:    start,
+		End:      end,
+		Seconds:  int64(end.Sub(start).Seconds()),
+		Switches: switches,
+		Apps:     apps,
+		Pattern:  top[0] + " ↔ " + top[1],
+	}, pairs, true
+}
+
+func pairKey(a, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+func pairLess(a, b [2]string) bool {
+	if a[0] != b[0] {
+		return a[0] < b[0]
+	}
+	return a[1] < b[1]
+}
@@ -0,0 +1,108 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestFragments(t *testing.T) {
+	base := time.Date(2025, 3, 14, 9, 0, 0, 0, time.Local)
+
+	// visits lays out back-to-back one-minute sessions from minute on.
+	visits := func(minute int, apps ...string) []models.FocusSession {
+		var sessions []models.FocusSession
+		for i, app := range apps {
+			start := base.Add(time.Duration(minute+i) * time.Minute)
+			sessions = append(sessions, models.FocusSession{AppName: app, Start: start, End: start.Add(time.Minute), Seconds: 60})
+		}
+		return sessions
+	}
+	concat := func(parts ...[]models.FocusSession) []models.FocusSession {
+		var sessions []models.FocusSession
+		for _, part := range parts {
+			sessions = append(sessions, part...)
+		}
+		return sessions
+	}
+	long := func(minute int, app string) []models.FocusSession {
+		start := base.Add(time.Duration(minute) * time.Minute)
+		return []models.FocusSession{{AppName: app, Start: start, End: start.Add(10 * time.Minute), Seconds: 600}}
+	}
+
+	tests := []struct {
+		name         string
+		sessions     []models.FocusSession
+		wantSegments []string
+		wantPatterns []models.SwitchPattern
+	}{
+		{
+			name: "no sessions",
+		},
+		{
+			name:         "editor and chat back and forth",
+			sessions:     visits(0, "code", "slack", "code", "slack", "code"),
+			wantSegments: []string{"09:00-09:05 4 [code slack] code ↔ slack"},
+			wantPatterns: []models.SwitchPattern{{Apps: [2]string{"code", "slack"}, Switches: 4}},
+		},
+		{
+			name:     "too few switches",
+			sessions: visits(0, "code", "slack", "code", "slack"),
+		},
+		{
+			name:     "staying in one app is no switch",
+			sessions: visits(0, "code", "code", "slack", "slack", "code", "code"),
+		},
+		{
+			name:     "long session splits the run",
+			sessions: concat(visits(0, "code", "slack", "code"), long(3, "code"), visits(13, "slack", "code", "slack")),
+		},
+		{
+			name:     "gap splits the run",
+			sessions: concat(visits(0, "code", "slack", "code"), visits(6, "slack", "code", "slack")),
+		},
+		{
+			name:         "gap within the limit keeps the run",
+			sessions:     concat(visits(0, "code", "slack", "code"), visits(5, "slack", "firefox")),
+			wantSegments: []string{"09:00-09:07 4 [code slack firefox] code ↔ slack"},
+			wantPatterns: []models.SwitchPattern{
+				{Apps: [2]string{"code", "slack"}, Switches: 3},
+				{Apps: [2]string{"firefox", "slack"}, Switches: 1},
+			},
+		},
+		{
+			name:     "patterns add up across runs",
+			sessions: concat(visits(0, "code", "slack", "code", "slack", "code"), long(5, "code"), visits(15, "firefox", "slack", "firefox", "slack", "code")),
+			wantSegments: []string{
+				"09:00-09:05 4 [code slack] code ↔ slack",
+				"09:15-09:20 4 [firefox slack code] firefox ↔ slack",
+			},
+			wantPatterns: []models.SwitchPattern{
+				{Apps: [2]string{"code", "slack"}, Switches: 5},
+				{Apps: [2]string{"firefox", "slack"}, Switches: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, patterns := Fragments(tt.sessions)
+
+			var got []string
+			for _, s := range segments {
+				if s.Seconds != int64(s.End.Sub(s.Start).Seconds()) {
+					t.Errorf("segment %s has %d seconds", s.Pattern, s.Seconds)
+				}
+				got = append(got, fmt.Sprintf("%s-%s %d %v %s", s.Start.Format("15:04"), s.End.Format("15:04"), s.Switches, s.Apps, s.Pattern))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantSegments) {
+				t.Errorf("Fragments() segments = %q, want %q", got, tt.wantSegments)
+			}
+			if fmt.Sprint(patterns) != fmt.Sprint(tt.wantPatterns) {
+				t.Errorf("Fragments() patterns = %v, want %v", patterns, tt.wantPatterns)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package auth issues and checks the API tokens that guard the web API.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// ScopeRead allows reading events, reports and status.
+	ScopeRead = "read:events"
+
+	// ScopeWrite allows submitting events, notes and tags.
+	ScopeWrite = "write:events"
+
+	// ScopeAdmin allows everything, including schema and audit access.
+	ScopeAdmin = "admin"
+)
+
+var scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// tokenPrefix makes tokens recognizable in config files and secret scanners.
+const tokenPrefix = "as_"
+
+// Generate returns a new random token and the hash to store for it.
+func Generate() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = tokenPrefix + hex.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the value stored in place of token.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes validates a comma-separated scope list and returns it in
+// canonical form.
+func ParseScopes(s string) (string, error) {
+	var parsed []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(scopes, scope) {
+			return "", fmt.Errorf("unknown scope %q (valid: %s)", scope, strings.Join(scopes, ", "))
+		}
+		if !slices.Contains(parsed, scope) {
+			parsed = append(parsed, scope)
+		}
+	}
+	if len(parsed) == 0 {
+		return "", fmt.Errorf("at least one scope is required (valid: %s)", strings.Join(scopes, ", "))
+	}
+	return strings.Join(parsed, ","), nil
+}
+
+// CheckUserID validates the user a token acts for on a multi-user server:
+// up to 64 letters, digits, dots, dashes, underscores, '+' and '@', so an
+// email address signed in with OIDC can be one.
+func CheckUserID(user string) error {
+	if user == "" || len(user) > 64 {
+		return fmt.Errorf("user ID must be 1 to 64 characters")
+	}
+	for _, r := range user {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' || r == '+' || r == '@') {
+			return fmt.Errorf("user ID %q may only contain letters, digits, '.', '-', '_', '+' and '@'", user)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether granted includes need. Admin grants every scope.
+func Allows(granted []string, need string) bool {
+	return slices.Contains(granted, ScopeAdmin) || slices.Contains(granted, need)
+}
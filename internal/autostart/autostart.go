@@ -0,0 +1,76 @@
+package autostart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const desktopFileName = "actionsum.desktop"
+
+// Path returns the XDG autostart entry location, honouring XDG_CONFIG_HOME.
+func Path() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "autostart", desktopFileName), nil
+}
+
+// Enable writes an autostart entry running executable with command ("start"
+// or "serve") when the graphical session begins.
+func Enable(executable, command string) (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create autostart directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(DesktopEntry(executable, command)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write autostart entry: %w", err)
+	}
+	return path, nil
+}
+
+// Disable removes the autostart entry. It is not an error if none exists.
+func Disable() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove autostart entry: %w", err)
+	}
+	return path, nil
+}
+
+// DesktopEntry renders the .desktop file contents.
+func DesktopEntry(executable, command string) string {
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	b.WriteString("Name=actionsum\n")
+	b.WriteString("Comment=Application focus time tracker\n")
+	fmt.Fprintf(&b, "Exec=%s %s\n", quoteExec(executable), command)
+	b.WriteString("Terminal=false\n")
+	b.WriteString("NoDisplay=true\n")
+	b.WriteString("X-GNOME-Autostart-enabled=true\n")
+	return b.String()
+}
+
+// quoteExec quotes an Exec argument per the Desktop Entry specification.
+func quoteExec(arg string) string {
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	return `"` + replacer.Replace(arg) + `"`
+}
@@ -0,0 +1,120 @@
+// Package away records why the user was away from the machine. When they
+// return after a long idle or lock, the tracker keeps the interval until a
+// reason is given, from a desktop notification or "actionsum away", and
+// then fills it with manual events named after the reason.
+package away
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+const (
+	// Source is recorded on events filling an away interval.
+	Source = "manual:away"
+
+	// DisplayServer is recorded on those events in place of the display
+	// server a tracked event was seen on.
+	DisplayServer = "manual"
+)
+
+// Interval is a stretch of time the user was away.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Record keeps interval as the one waiting for a reason, replacing any
+// earlier one.
+func Record(stateFile string, interval Interval) error {
+	if stateFile == "" {
+		return fmt.Errorf("away state file is not configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create away directory: %w", err)
+	}
+	data := fmt.Sprintf("%s %s\n", interval.Start.Format(time.RFC3339), interval.End.Format(time.RFC3339))
+	if err := os.WriteFile(stateFile, []byte(data), 0644); err != nil {
+		return fmt.Errorf("failed to save away interval: %w", err)
+	}
+	return nil
+}
+
+// Pending returns the interval waiting for a reason, if any.
+func Pending(stateFile string) (Interval, bool) {
+	if stateFile == "" {
+		return Interval{}, false
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return Interval{}, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return Interval{}, false
+	}
+	start, err := time.Parse(time.RFC3339, fields[0])
+	if err != nil {
+		return Interval{}, false
+	}
+	end, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil || !end.After(start) {
+		return Interval{}, false
+	}
+	return Interval{Start: start, End: end}, true
+}
+
+// Clear forgets the pending interval.
+func Clear(stateFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear away interval: %w", err)
+	}
+	return nil
+}
+
+// Fill stores interval as time spent in an app named after reason, split
+// into events no longer than maxLength like tracked ones, and returns the
+// events stored.
+func Fill(repo *database.Repository, interval Interval, reason, profile string, maxLength time.Duration) ([]*models.FocusEvent, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("away reason is empty")
+	}
+	if maxLength <= 0 {
+		maxLength = interval.Duration()
+	}
+
+	var events []*models.FocusEvent
+	for start := interval.Start; interval.End.Sub(start) >= time.Second; start = start.Add(maxLength) {
+		length := interval.End.Sub(start)
+		if length > maxLength {
+			length = maxLength
+		}
+		events = append(events, &models.FocusEvent{
+			Timestamp:     start,
+			AppName:       reason,
+			WindowTitle:   "Away: " + reason,
+			Duration:      int64(length.Seconds()),
+			DisplayServer: DisplayServer,
+			Profile:       profile,
+			Source:        Source,
+		})
+	}
+	if err := repo.CreateBatch(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,97 @@
+// Package backfill estimates activity from before actionsum was installed
+// out of shell history and editor logs. History only says when something
+// happened, not how long it held focus, so the resulting events are
+// approximations and are stored with a "backfill:" source to keep them
+// distinguishable from tracked time.
+package backfill
+
+import (
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+const (
+	// SourcePrefix starts the source of every backfilled event.
+	SourcePrefix = "backfill:"
+
+	// DisplayServer is recorded on backfilled events in place of the
+	// display server a tracked event was seen on.
+	DisplayServer = "backfill"
+
+	// activityTail is how long an app is assumed to stay in use after each
+	// recorded activity. Activities closer together than this merge.
+	activityTail = 2 * time.Minute
+
+	// maxEventLength splits long stretches of activity so that no event
+	// exceeds the longest poll interval the tracker would have recorded.
+	maxEventLength = 5 * time.Minute
+)
+
+// Activity is a moment something was done in an app, as read from history.
+type Activity struct {
+	Time time.Time
+
+	// Detail becomes the window title, e.g. the program a shell ran.
+	Detail string
+
+	// Elapsed is how long the activity took when the history records it.
+	Elapsed time.Duration
+}
+
+// Events turns activities into focus events for appName. Each activity
+// counts as activityTail of use, or its elapsed time if longer; overlapping
+// stretches are merged. Activities at or after before are dropped, and
+// events are cut short at it, so backfill never overlaps tracked time.
+func Events(activities []Activity, appName string, before time.Time) []*models.FocusEvent {
+	sort.SliceStable(activities, func(i, j int) bool {
+		return activities[i].Time.Before(activities[j].Time)
+	})
+
+	var events []*models.FocusEvent
+	var start, end time.Time
+	var detail string
+	emit := func() {
+		for start.Before(end) {
+			length := end.Sub(start)
+			if length > maxEventLength {
+				length = maxEventLength
+			}
+			events = append(events, &models.FocusEvent{
+				Timestamp:     start,
+				AppName:       appName,
+				WindowTitle:   detail,
+				Duration:      int64(length.Seconds()),
+				DisplayServer: DisplayServer,
+				Profile:       "default",
+			})
+			start = start.Add(length)
+		}
+	}
+
+	for _, activity := range activities {
+		if activity.Time.IsZero() || (!before.IsZero() && !activity.Time.Before(before)) {
+			continue
+		}
+		length := activity.Elapsed
+		if length < activityTail {
+			length = activityTail
+		}
+		activityEnd := activity.Time.Add(length)
+		if !before.IsZero() && activityEnd.After(before) {
+			activityEnd = before
+		}
+
+		if !start.IsZero() && !activity.Time.After(end) {
+			if activityEnd.After(end) {
+				end = activityEnd
+			}
+			continue
+		}
+		emit()
+		start, end, detail = activity.Time, activityEnd, activity.Detail
+	}
+	emit()
+	return events
+}
@@ -0,0 +1,52 @@
+package backfill
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int, detail string) Activity {
+		return Activity{Time: base.Add(time.Duration(seconds) * time.Second), Detail: detail}
+	}
+	took := func(a Activity, elapsed time.Duration) Activity {
+		a.Elapsed = elapsed
+		return a
+	}
+
+	tests := []struct {
+		name       string
+		activities []Activity
+		before     time.Time
+		want       string // offset+duration:title of each event, in seconds
+	}{
+		{"none", nil, time.Time{}, ""},
+		{"single activity gets the tail", []Activity{at(0, "git")}, time.Time{}, "0+120:git"},
+		{"close activities merge", []Activity{at(0, "git"), at(60, "make"), at(150, "vim")}, time.Time{}, "0+270:git"},
+		{"distant activities stay apart", []Activity{at(0, "git"), at(600, "vim")}, time.Time{}, "0+120:git 600+120:vim"},
+		{"unsorted input", []Activity{at(600, "vim"), at(0, "git")}, time.Time{}, "0+120:git 600+120:vim"},
+		{"elapsed longer than the tail", []Activity{took(at(0, "make"), 4*time.Minute)}, time.Time{}, "0+240:make"},
+		{"long stretches split", []Activity{took(at(0, "make"), 12*time.Minute)}, time.Time{}, "0+300:make 300+300:make 600+120:make"},
+		{"cut short before tracking", []Activity{at(0, "git"), at(60, "vim")}, base.Add(90 * time.Second), "0+90:git"},
+		{"activities after tracking dropped", []Activity{at(0, "git"), at(300, "vim")}, base.Add(300 * time.Second), "0+120:git"},
+		{"zero times dropped", []Activity{{Detail: "broken"}, at(0, "git")}, time.Time{}, "0+120:git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := Events(tt.activities, "terminal", tt.before)
+			var parts []string
+			for _, event := range events {
+				if event.AppName != "terminal" || event.DisplayServer != DisplayServer {
+					t.Errorf("event %+v not attributed to the backfill", event)
+				}
+				parts = append(parts, fmt.Sprintf("%d+%d:%s", int(event.Timestamp.Sub(base).Seconds()), event.Duration, event.WindowTitle))
+			}
+			if got := strings.Join(parts, " "); got != tt.want {
+				t.Errorf("Events() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package backfill
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadZshHistory reads a zsh history file written with EXTENDED_HISTORY,
+// whose entries look like ": 1700000000:12;git status". Entries without a
+// timestamp are skipped.
+func ReadZshHistory(r io.Reader) ([]Activity, error) {
+	var activities []Activity
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, ": ") {
+			continue
+		}
+		meta, command, ok := strings.Cut(line[2:], ";")
+		if !ok {
+			continue
+		}
+		stamp, elapsed, _ := strings.Cut(meta, ":")
+		seconds, err := strconv.ParseInt(strings.TrimSpace(stamp), 10, 64)
+		if err != nil {
+			continue
+		}
+		activity := Activity{Time: time.Unix(seconds, 0), Detail: program(command)}
+		if e, err := strconv.Atoi(strings.TrimSpace(elapsed)); err == nil {
+			activity.Elapsed = time.Duration(e) * time.Second
+		}
+		activities = append(activities, activity)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zsh history: %w", err)
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("no timestamped entries found; is EXTENDED_HISTORY enabled?")
+	}
+	return activities, nil
+}
+
+// ReadBashHistory reads a bash history file written with HISTTIMEFORMAT set,
+// where each command follows a "#1700000000" timestamp line.
+func ReadBashHistory(r io.Reader) ([]Activity, error) {
+	var activities []Activity
+	var stamp time.Time
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			if seconds, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				stamp = time.Unix(seconds, 0)
+				continue
+			}
+		}
+		if stamp.IsZero() {
+			continue
+		}
+		activities = append(activities, Activity{Time: stamp, Detail: program(line)})
+		stamp = time.Time{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bash history: %w", err)
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("no timestamped entries found; was HISTTIMEFORMAT set?")
+	}
+	return activities, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+// program returns the program a command line ran. Only the program is kept,
+// since arguments can hold paths, hosts or secrets.
+func program(command string) string {
+	fields := strings.Fields(command)
+	for len(fields) > 0 {
+		field := fields[0]
+		if field == "sudo" || field == "env" || field == "time" || field == "nohup" ||
+			(strings.Contains(field, "=") && !strings.HasPrefix(field, "=")) {
+			fields = fields[1:]
+			continue
+		}
+		return filepath.Base(field)
+	}
+	return "shell"
+}
@@ -0,0 +1,121 @@
+package backfill
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func formatActivities(activities []Activity) string {
+	var parts []string
+	for _, a := range activities {
+		parts = append(parts, fmt.Sprintf("%d:%s:%v", a.Time.Unix(), a.Detail, a.Elapsed))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestReadZshHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "extended history",
+			history: ": 1700000000:0;git status\n: 1700000060:12;make test\n",
+			want:    "1700000000:git:0s 1700000060:make:12s",
+		},
+		{
+			name:    "command containing semicolons and colons",
+			history: ": 1700000000:3;cd /tmp; ls a:b\n",
+			want:    "1700000000:cd:3s",
+		},
+		{
+			name:    "prefixes and paths stripped",
+			history: ": 1700000000:0;sudo FOO=1 /usr/bin/apt upgrade\n: 1700000001:0;env\n",
+			want:    "1700000000:apt:0s 1700000001:shell:0s",
+		},
+		{
+			name:    "malformed entries skipped",
+			history: "plain command\n: nope:0;ls\n: 1700000000:0 no separator\n: 1700000005:x;vim\n",
+			want:    "1700000005:vim:0s",
+		},
+		{
+			name:    "multi-line command continuation skipped",
+			history: ": 1700000000:0;echo one \\\ntwo\n",
+			want:    "1700000000:echo:0s",
+		},
+		{
+			name:    "no extended history",
+			history: "git status\nls\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities, err := ReadZshHistory(strings.NewReader(tt.history))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadZshHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := formatActivities(activities); got != tt.want {
+				t.Errorf("ReadZshHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBashHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "timestamped",
+			history: "#1700000000\ngit status\n#1700000060\nnohup ./server --port 80\n",
+			want:    "1700000000:git:0s 1700000060:server:0s",
+		},
+		{
+			name:    "commands before HISTTIMEFORMAT was set skipped",
+			history: "ls\ncd /tmp\n#1700000000\nvim notes\n",
+			want:    "1700000000:vim:0s",
+		},
+		{
+			name:    "only the first command after a timestamp",
+			history: "#1700000000\nmake\nmake install\n",
+			want:    "1700000000:make:0s",
+		},
+		{
+			name:    "comment commands kept",
+			history: "#1700000000\n#not a timestamp\n",
+			want:    "1700000000:#not:0s",
+		},
+		{
+			name:    "latest of consecutive timestamps",
+			history: "#1700000000\n#1700000030\nhtop\n",
+			want:    "1700000030:htop:0s",
+		},
+		{
+			name:    "no timestamps",
+			history: "git status\nls\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities, err := ReadBashHistory(strings.NewReader(tt.history))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadBashHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := formatActivities(activities); got != tt.want {
+				t.Errorf("ReadBashHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
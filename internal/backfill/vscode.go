@@ -0,0 +1,59 @@
+package backfill
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// vscodeTimeLayout is the timestamp at the start of each VS Code log line,
+// in local time.
+const vscodeTimeLayout = "2006-01-02 15:04:05"
+
+// ReadVSCodeLogs reads the logs VS Code keeps per session under dir, usually
+// ~/.config/Code/logs, and returns an activity for each minute with log
+// output. Extensions also log while the editor sits unused, so this is the
+// coarsest of the importers.
+func ReadVSCodeLogs(dir string) ([]Activity, error) {
+	seen := make(map[int64]bool)
+	var activities []Activity
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".log") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if len(line) < len(vscodeTimeLayout) {
+				continue
+			}
+			t, err := time.ParseInLocation(vscodeTimeLayout, line[:len(vscodeTimeLayout)], time.Local)
+			if err != nil {
+				continue
+			}
+			minute := t.Unix() / 60
+			if seen[minute] {
+				continue
+			}
+			seen[minute] = true
+			activities = append(activities, Activity{Time: t.Truncate(time.Minute), Detail: "VS Code"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VS Code logs: %w", err)
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("no timestamped log lines found in %s", dir)
+	}
+	return activities, nil
+}
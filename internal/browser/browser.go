@@ -0,0 +1,90 @@
+// Package browser receives the active tab from actionsum's browser
+// extension. The browser starts "actionsum native-host" through native
+// messaging and sends it the tab on every change; the host records the
+// latest one in a file the daemon reads when a browser window has focus.
+package browser
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/statefile"
+)
+
+// tabFreshness is how long a recorded tab is believed without a new
+// message. The extension repeats the active tab every 30 seconds, so an
+// older file means the browser or the extension has gone.
+const tabFreshness = 2 * time.Minute
+
+// Tab is the active tab of the browser window that last had focus.
+type Tab struct {
+	URL     string `json:"url,omitempty"`
+	Domain  string `json:"domain,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Browser string `json:"browser,omitempty"` // e.g. "firefox" or "chrome"
+
+	// Focused is false once no browser window has focus, and for private
+	// windows, whose tabs are never recorded.
+	Focused bool `json:"focused"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Record saves tab as the current one.
+func Record(path string, tab *Tab) error {
+	if path == "" {
+		return fmt.Errorf("browser tab file is not configured")
+	}
+	return statefile.WriteJSON(path, tab)
+}
+
+// Current returns the tab recorded at path when a browser window has focus
+// and the extension reported it recently.
+func Current(path string, now time.Time) (*Tab, bool) {
+	if path == "" {
+		return nil, false
+	}
+	var tab Tab
+	if err := statefile.ReadJSON(path, &tab); err != nil {
+		return nil, false
+	}
+	if !tab.Focused || tab.URL == "" || now.Sub(tab.UpdatedAt) > tabFreshness {
+		return nil, false
+	}
+	return &tab, true
+}
+
+// Matches reports whether tab belongs to the focused window: browsers put
+// the tab's title in the window title, and otherwise the app must be the
+// browser that sent the tab.
+func (t *Tab) Matches(appName, windowTitle string) bool {
+	if t.Title != "" && strings.Contains(windowTitle, t.Title) {
+		return true
+	}
+	return t.Browser != "" && strings.Contains(strings.ToLower(appName), t.Browser)
+}
+
+// CleanURL drops the parts of a URL that often carry tokens or personal
+// data, the credentials, query and fragment, and returns the URL and its
+// domain without a leading "www.". Pages other than http and https, such as
+// a new tab or about:config, have no domain.
+func CleanURL(raw string) (string, string) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	domain := u.Hostname()
+	if ip := net.ParseIP(domain); ip == nil {
+		domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+	}
+	return u.String(), domain
+}
@@ -0,0 +1,61 @@
+// Sends the active tab of the focused browser window to actionsum's native
+// messaging host on every change, and again every 30 seconds so the daemon
+// can tell a recent report from a browser that has gone away.
+
+const api = globalThis.browser ?? globalThis.chrome;
+const host = 'io.github.actionsum';
+const browserName = navigator.userAgent.includes('Firefox') ? 'firefox' : 'chrome';
+
+let port = null;
+
+function connect() {
+    if (port)
+        return port;
+    port = api.runtime.connectNative(host);
+    port.onDisconnect.addListener(() => {
+        port = null;
+    });
+    return port;
+}
+
+function send(message) {
+    try {
+        connect().postMessage({...message, browser: browserName});
+    } catch (e) {
+        port = null;
+    }
+}
+
+async function report() {
+    const win = await api.windows.getLastFocused({populate: false});
+    if (!win || !win.focused) {
+        send({focused: false});
+        return;
+    }
+    const [tab] = await api.tabs.query({active: true, windowId: win.id});
+    if (!tab) {
+        send({focused: false});
+        return;
+    }
+    send({
+        url: tab.url ?? '',
+        title: tab.title ?? '',
+        focused: true,
+        incognito: tab.incognito,
+    });
+}
+
+api.tabs.onActivated.addListener(report);
+api.tabs.onUpdated.addListener((tabId, change, tab) => {
+    if (tab.active && (change.url || change.title))
+        report();
+});
+api.windows.onFocusChanged.addListener(report);
+
+api.alarms.create('actionsum-heartbeat', {periodInMinutes: 0.5});
+api.alarms.onAlarm.addListener(alarm => {
+    if (alarm.name === 'actionsum-heartbeat')
+        report();
+});
+
+report();
@@ -0,0 +1,92 @@
+package browser
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// HostName is the native messaging host the extension connects to.
+const HostName = "io.github.actionsum"
+
+// maxMessageSize bounds a message from the browser; tabs are far smaller.
+const maxMessageSize = 1 << 20
+
+// message is what the extension sends on every tab or focus change.
+type message struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Browser   string `json:"browser"`
+	Focused   bool   `json:"focused"`
+	Incognito bool   `json:"incognito"`
+}
+
+// RunHost serves the extension over native messaging, each message a
+// length in native byte order followed by that much JSON, recording every
+// tab it is sent at path. It returns when the browser closes the
+// connection.
+func RunHost(r io.Reader, w io.Writer, path string) error {
+	for {
+		var msg message
+		if err := readMessage(r, &msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		reply := map[string]any{"ok": true}
+		if err := Record(path, tabFrom(&msg, time.Now())); err != nil {
+			reply = map[string]any{"ok": false, "error": err.Error()}
+		}
+		if err := writeMessage(w, reply); err != nil {
+			return err
+		}
+	}
+}
+
+// tabFrom keeps what may be stored of a message's tab.
+func tabFrom(msg *message, now time.Time) *Tab {
+	tab := &Tab{Browser: strings.ToLower(msg.Browser), UpdatedAt: now}
+	if !msg.Focused || msg.Incognito {
+		return tab
+	}
+	tab.URL, tab.Domain = CleanURL(msg.URL)
+	tab.Title = msg.Title
+	tab.Focused = tab.URL != ""
+	return tab
+}
+
+func readMessage(r io.Reader, v any) error {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		return err
+	}
+	if size > maxMessageSize {
+		return fmt.Errorf("message of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	return nil
+}
+
+func writeMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.NativeEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
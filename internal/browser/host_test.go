@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tab.json")
+
+	var in bytes.Buffer
+	for _, msg := range []message{
+		{URL: "https://news.ycombinator.com/item?id=1", Title: "Hacker News", Browser: "Firefox", Focused: true},
+		{URL: "https://bank.example/account", Title: "Account", Browser: "Firefox", Focused: true, Incognito: true},
+	} {
+		if err := writeMessage(&in, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := RunHost(&in, &out, path); err != nil {
+		t.Fatalf("RunHost() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var reply map[string]any
+		if err := readMessage(&out, &reply); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+		if reply["ok"] != true {
+			t.Errorf("reply %d = %v, want ok", i, reply)
+		}
+	}
+
+	// The private window's tab was the last one and must not be kept.
+	if tab, ok := Current(path, time.Now()); ok {
+		t.Errorf("Current() = %+v after a private tab, want none", tab)
+	}
+}
+
+func TestRunHostRejectsOversizedMessage(t *testing.T) {
+	var in bytes.Buffer
+	binary.Write(&in, binary.NativeEndian, uint32(maxMessageSize+1))
+	if err := RunHost(&in, &bytes.Buffer{}, filepath.Join(t.TempDir(), "tab.json")); err == nil {
+		t.Error("RunHost() accepted a message larger than the limit")
+	}
+}
+
+func TestTabFrom(t *testing.T) {
+	now := time.Now()
+	tab := tabFrom(&message{URL: "https://www.youtube.com/watch?v=x", Title: "Video", Browser: "Chrome", Focused: true}, now)
+	want := Tab{URL: "https://www.youtube.com/watch", Domain: "youtube.com", Title: "Video", Browser: "chrome", Focused: true, UpdatedAt: now}
+	if *tab != want {
+		t.Errorf("tabFrom() = %+v, want %+v", *tab, want)
+	}
+
+	if tab := tabFrom(&message{URL: "about:blank", Focused: true}, now); tab.Focused {
+		t.Errorf("tabFrom(about:blank) = %+v, want it not focused", tab)
+	}
+}
@@ -0,0 +1,215 @@
+package browser
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ExtensionID identifies the extension to Firefox.
+const ExtensionID = "actionsum@actionsum.github.io"
+
+//go:embed extension
+var files embed.FS
+
+// Installation is what Install wrote.
+type Installation struct {
+	// Extension is the directory to load the extension from.
+	Extension string
+
+	// Manifests are the native messaging manifests written, one per
+	// browser found.
+	Manifests []string
+}
+
+// hostManifest tells a browser how to start the native messaging host and
+// which extensions may talk to it.
+type hostManifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+}
+
+// browserDir is where one browser looks for native messaging manifests,
+// and whether it is Firefox, whose manifests name extensions rather than
+// origins.
+type browserDir struct {
+	Dir     string
+	Firefox bool
+}
+
+// dataDir is actionsum's directory under XDG_DATA_HOME, holding the host
+// script and the extension.
+func dataDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dir, "actionsum"), nil
+}
+
+// browserDirs lists where the browsers actionsum knows look for native
+// messaging manifests for the user.
+func browserDirs() ([]browserDir, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(homeDir, ".config")
+		}
+		return []browserDir{
+			{filepath.Join(homeDir, ".mozilla", "native-messaging-hosts"), true},
+			{filepath.Join(configDir, "google-chrome", "NativeMessagingHosts"), false},
+			{filepath.Join(configDir, "chromium", "NativeMessagingHosts"), false},
+			{filepath.Join(configDir, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"), false},
+			{filepath.Join(configDir, "microsoft-edge", "NativeMessagingHosts"), false},
+		}, nil
+	case "darwin":
+		support := filepath.Join(homeDir, "Library", "Application Support")
+		return []browserDir{
+			{filepath.Join(support, "Mozilla", "NativeMessagingHosts"), true},
+			{filepath.Join(support, "Google", "Chrome", "NativeMessagingHosts"), false},
+			{filepath.Join(support, "Chromium", "NativeMessagingHosts"), false},
+			{filepath.Join(support, "BraveSoftware", "Brave-Browser", "NativeMessagingHosts"), false},
+			{filepath.Join(support, "Microsoft Edge", "NativeMessagingHosts"), false},
+		}, nil
+	}
+	return nil, fmt.Errorf("browser integration is not supported on %s yet", runtime.GOOS)
+}
+
+// Install writes the extension, a script starting executable as the native
+// messaging host, and a manifest for each browser whose profile directory
+// exists. Chromium-based browsers only let the extensions listed in
+// chromeIDs connect, as an unpacked extension's ID depends on where it was
+// loaded from; without any, only Firefox is set up.
+func Install(executable string, chromeIDs []string) (*Installation, error) {
+	dirs, err := browserDirs()
+	if err != nil {
+		return nil, err
+	}
+	data, err := dataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	extension := filepath.Join(data, "browser-extension")
+	if err := writeExtension(extension); err != nil {
+		return nil, err
+	}
+
+	// Browsers start the host without arguments of ours, so it is a script.
+	script := filepath.Join(data, "native-host")
+	content := fmt.Sprintf("#!/bin/sh\nexec '%s' native-host \"$@\"\n", strings.ReplaceAll(executable, "'", `'\''`))
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		return nil, fmt.Errorf("failed to write native messaging host: %w", err)
+	}
+
+	origins := make([]string, 0, len(chromeIDs))
+	for _, id := range chromeIDs {
+		origins = append(origins, fmt.Sprintf("chrome-extension://%s/", id))
+	}
+
+	inst := &Installation{Extension: extension}
+	for _, dir := range dirs {
+		// The browser isn't used if its profile directory is missing.
+		if _, err := os.Stat(filepath.Dir(dir.Dir)); err != nil {
+			continue
+		}
+		manifest := hostManifest{
+			Name:        HostName,
+			Description: "Reports the active tab to the actionsum time tracker",
+			Path:        script,
+			Type:        "stdio",
+		}
+		if dir.Firefox {
+			manifest.AllowedExtensions = []string{ExtensionID}
+		} else if len(origins) > 0 {
+			manifest.AllowedOrigins = origins
+		} else {
+			continue
+		}
+
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir.Dir, err)
+		}
+		path := filepath.Join(dir.Dir, HostName+".json")
+		if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write native messaging manifest: %w", err)
+		}
+		inst.Manifests = append(inst.Manifests, path)
+	}
+	return inst, nil
+}
+
+func writeExtension(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create extension directory: %w", err)
+	}
+	entries, err := fs.ReadDir(files, "extension")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(files, path.Join("extension", entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Uninstall removes the manifests, the host script and the extension, and
+// returns the manifests removed. It is not an error if nothing is
+// installed.
+func Uninstall() ([]string, error) {
+	dirs, err := browserDirs()
+	if err != nil {
+		return nil, err
+	}
+	data, err := dataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, dir := range dirs {
+		path := filepath.Join(dir.Dir, HostName+".json")
+		if err := os.Remove(path); err == nil {
+			removed = append(removed, path)
+		} else if !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove native messaging manifest: %w", err)
+		}
+	}
+	if err := os.Remove(filepath.Join(data, "native-host")); err != nil && !os.IsNotExist(err) {
+		return removed, fmt.Errorf("failed to remove native messaging host: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(data, "browser-extension")); err != nil {
+		return removed, fmt.Errorf("failed to remove extension: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,148 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/schedule"
+)
+
+// Budget is a daily focus-time limit on an app, or on a category of apps.
+type Budget struct {
+	Name  string
+	Limit time.Duration
+	apps  map[string]bool
+
+	// hours, when set, are the only times that count against the limit.
+	hours []schedule.Window
+}
+
+// Contains reports whether the normalized app name counts against b.
+func (b Budget) Contains(appName string) bool {
+	return b.apps[appName]
+}
+
+// Counts reports whether time in appName counts against b. Tagged time counts
+// only towards the budget named after its tag, whatever the app.
+func (b Budget) Counts(appName, tag string) bool {
+	if tag != "" {
+		return tag == b.Name
+	}
+	return b.Contains(appName)
+}
+
+// FromConfig builds budgets from the configured limits. A limit named after
+// a category covers the category's apps; any other name is an app name.
+// With WorkHours.BudgetsOnly, only time in working hours counts.
+func FromConfig(cfg *config.Config, repo *database.Repository) []Budget {
+	budgets := make([]Budget, 0, len(cfg.Budgets.Limits))
+	for name, limit := range cfg.Budgets.Limits {
+		b := Budget{Name: name, Limit: limit, apps: make(map[string]bool)}
+		if cfg.WorkHours.BudgetsOnly {
+			b.hours = cfg.WorkHours.Windows
+		}
+		if apps, ok := cfg.Budgets.Categories[name]; ok {
+			for _, app := range apps {
+				b.apps[repo.NormalizeAppName(app)] = true
+			}
+		} else {
+			b.apps[repo.NormalizeAppName(name)] = true
+		}
+		budgets = append(budgets, b)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Name < budgets[j].Name
+	})
+	return budgets
+}
+
+type Usage struct {
+	Budget Budget
+	Used   time.Duration
+}
+
+func (u Usage) Exceeded() bool {
+	return u.Used > u.Budget.Limit
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%s: %v of %v", u.Budget.Name, u.Used.Round(time.Minute), u.Budget.Limit)
+}
+
+// UsageBetween sums focus time per budget for [start, end). Budgets limited
+// to working hours count only the part of each event inside them.
+func UsageBetween(repo *database.Repository, budgets []Budget, start, end time.Time) ([]Usage, error) {
+	q := database.Query{Since: start, Until: end}
+	totals, err := repo.GetDailyAppTotals(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app totals: %w", err)
+	}
+
+	var events []*models.FocusEvent
+	usage := make([]Usage, len(budgets))
+	for i, b := range budgets {
+		usage[i].Budget = b
+		if len(b.hours) == 0 {
+			for _, total := range totals {
+				if b.Counts(total.AppName, total.Tag) {
+					usage[i].Used += time.Duration(total.TotalSeconds) * time.Second
+				}
+			}
+			continue
+		}
+
+		if events == nil {
+			if events, err = repo.GetEvents(q); err != nil {
+				return nil, fmt.Errorf("failed to get events: %w", err)
+			}
+		}
+		for _, event := range events {
+			if b.Counts(repo.NormalizeAppName(event.AppName), event.Tag) {
+				from := event.Timestamp.In(time.Local)
+				usage[i].Used += schedule.Overlap(b.hours, from, from.Add(time.Duration(event.Duration)*time.Second))
+			}
+		}
+	}
+	return usage, nil
+}
+
+// Today returns usage since local midnight.
+func Today(repo *database.Repository, budgets []Budget, now time.Time) ([]Usage, error) {
+	return UsageBetween(repo, budgets, startOfDay(now), now.Add(time.Minute))
+}
+
+// Outcome counts the days in a week a budget was exceeded.
+type Outcome struct {
+	Name     string        `json:"name"`
+	Limit    time.Duration `json:"limit"`
+	DaysOver int           `json:"days_over"`
+}
+
+func WeekOutcomes(repo *database.Repository, budgets []Budget, weekStart time.Time) ([]Outcome, error) {
+	outcomes := make([]Outcome, len(budgets))
+	for i, b := range budgets {
+		outcomes[i] = Outcome{Name: b.Name, Limit: b.Limit}
+	}
+
+	for day := 0; day < 7; day++ {
+		start := weekStart.AddDate(0, 0, day)
+		usage, err := UsageBetween(repo, budgets, start, start.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		for i, u := range usage {
+			if u.Exceeded() {
+				outcomes[i].DaysOver++
+			}
+		}
+	}
+	return outcomes, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
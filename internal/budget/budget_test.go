@@ -0,0 +1,70 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/schedule"
+)
+
+// lateEvening is 22:00 yesterday, so that events an hour later are still in
+// the past and on the same day.
+func lateEvening() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day()-1, 22, 0, 0, 0, time.Local)
+}
+
+func testConfig(limits map[string]time.Duration) *config.Config {
+	cfg := config.Default()
+	cfg.Budgets.Limits = limits
+	cfg.Budgets.Categories = map[string][]string{"social": {"slack", "discord"}}
+	cfg.Budgets.RepeatInterval = 5 * time.Minute
+	cfg.Tracker.PollInterval = 10 * time.Second
+	return cfg
+}
+
+func TestUsageBetween(t *testing.T) {
+	repo := database.NewRepository(database.OpenTest(t))
+	start := lateEvening()
+	for i, app := range []string{"youtube", "slack", "discord"} {
+		event := &models.FocusEvent{Timestamp: start.Add(time.Duration(i) * 20 * time.Minute), AppName: app, Duration: 20 * 60, DisplayServer: "wayland"}
+		if err := repo.Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	evening, err := schedule.ParseList("22:10-22:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		budgetsOnly bool
+		want        map[string]time.Duration
+	}{
+		{"all day", false, map[string]time.Duration{"social": 40 * time.Minute, "youtube": 20 * time.Minute}},
+		{"working hours", true, map[string]time.Duration{"social": 10 * time.Minute, "youtube": 10 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(map[string]time.Duration{"youtube": 15 * time.Minute, "social": time.Hour})
+			cfg.WorkHours.Windows = evening
+			cfg.WorkHours.BudgetsOnly = tt.budgetsOnly
+			usage, err := UsageBetween(repo, FromConfig(cfg, repo), start, start.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("UsageBetween() error = %v", err)
+			}
+			for _, u := range usage {
+				if u.Used != tt.want[u.Budget.Name] {
+					t.Errorf("%s used %v, want %v", u.Budget.Name, u.Used, tt.want[u.Budget.Name])
+				}
+			}
+			if exceeded := usage[1].Exceeded(); exceeded != !tt.budgetsOnly {
+				t.Errorf("youtube exceeded = %v, want %v", exceeded, !tt.budgetsOnly)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+const (
+	checkInterval = time.Minute
+	hookTimeout   = 30 * time.Second
+)
+
+// Enforcer warns once when a budget is exceeded for the day. In strict mode
+// it keeps warning, with rising urgency, every RepeatInterval while an app
+// counting against that budget is focused, and runs the configured hook.
+type Enforcer struct {
+	cfg      *config.Config
+	repo     *database.Repository
+	notifier notify.Notifier
+	budgets  []Budget
+
+	day         time.Time
+	warnings    map[string]int
+	lastWarning map[string]time.Time
+}
+
+// NewEnforcer returns nil when no budgets are configured. notifier may be nil
+// if only a strict-mode hook should act.
+func NewEnforcer(cfg *config.Config, repo *database.Repository, notifier notify.Notifier) *Enforcer {
+	budgets := FromConfig(cfg, repo)
+	if len(budgets) == 0 {
+		return nil
+	}
+	return &Enforcer{
+		cfg:      cfg,
+		repo:     repo,
+		notifier: notifier,
+		budgets:  budgets,
+	}
+}
+
+func (e *Enforcer) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := e.check(ctx, now); err != nil {
+				log.Printf("Budget check failed: %v", err)
+			}
+		}
+	}
+}
+
+func (e *Enforcer) check(ctx context.Context, now time.Time) error {
+	if day := startOfDay(now); !day.Equal(e.day) {
+		e.day = day
+		e.warnings = make(map[string]int)
+		e.lastWarning = make(map[string]time.Time)
+	}
+
+	if e.cfg.WorkHours.BudgetsOnly && !e.cfg.InWorkHours(now) {
+		return nil
+	}
+
+	usage, err := Today(e.repo, e.budgets, now)
+	if err != nil {
+		return err
+	}
+
+	focused, focusedTag, err := e.focusedApp(now)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range usage {
+		if !u.Exceeded() {
+			continue
+		}
+
+		name := u.Budget.Name
+		inFocus := focused != "" && u.Budget.Counts(focused, focusedTag)
+		switch {
+		case e.warnings[name] == 0:
+		case e.cfg.Budgets.Strict && inFocus && now.Sub(e.lastWarning[name]) >= e.cfg.Budgets.RepeatInterval:
+		default:
+			continue
+		}
+
+		e.warnings[name]++
+		e.lastWarning[name] = now
+		e.warn(u, e.warnings[name])
+
+		if e.cfg.Budgets.Strict && inFocus && e.cfg.Budgets.Hook != "" {
+			e.runHook(ctx, u, focused, e.warnings[name])
+		}
+	}
+	return nil
+}
+
+// focusedApp returns the app and tag of the latest event if it is still
+// current.
+func (e *Enforcer) focusedApp(now time.Time) (string, string, error) {
+	latest, err := e.repo.GetLatest()
+	if err != nil || latest == nil {
+		return "", "", err
+	}
+	end := latest.Timestamp.Add(time.Duration(latest.Duration)*time.Second + e.cfg.Tracker.PollInterval)
+	if now.After(end) {
+		return "", "", nil
+	}
+	return e.repo.NormalizeAppName(latest.AppName), latest.Tag, nil
+}
+
+func (e *Enforcer) warn(u Usage, warning int) {
+	msg := notify.Message{
+		Title:   fmt.Sprintf("Daily budget exceeded: %s", u.Budget.Name),
+		Body:    fmt.Sprintf("%v used of %v today.", u.Used.Round(time.Minute), u.Budget.Limit),
+		Urgency: "normal",
+		Data: map[string]any{
+			"budget":        u.Budget.Name,
+			"used_seconds":  int64(u.Used.Seconds()),
+			"limit_seconds": int64(u.Budget.Limit.Seconds()),
+			"warning":       warning,
+		},
+	}
+	if warning > 1 {
+		msg.Title = fmt.Sprintf("Still over budget: %s (warning %d)", u.Budget.Name, warning)
+		msg.Body += " Time to switch to something else."
+		msg.Urgency = "critical"
+	}
+
+	log.Printf("Budget exceeded (warning %d): %s", warning, u)
+	if e.notifier == nil {
+		return
+	}
+	if err := e.notifier.Notify(msg); err != nil {
+		log.Printf("Failed to send budget notification: %v", err)
+	}
+}
+
+// runHook runs the user's strict-mode hook, a shell command line, with
+// details of the exceeded budget in its environment.
+func (e *Enforcer) runHook(ctx context.Context, u Usage, appName string, warning int) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	cmd := hookCommand(ctx, e.cfg.Budgets.Hook)
+	sandbox.SetEnv(cmd,
+		"ACTIONSUM_BUDGET="+u.Budget.Name,
+		"ACTIONSUM_APP="+appName,
+		"ACTIONSUM_USED_SECONDS="+strconv.FormatInt(int64(u.Used.Seconds()), 10),
+		"ACTIONSUM_LIMIT_SECONDS="+strconv.FormatInt(int64(u.Budget.Limit.Seconds()), 10),
+		"ACTIONSUM_WARNING="+strconv.Itoa(warning),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Printf("Budget hook failed: %v (%s)", err, output)
+	}
+}
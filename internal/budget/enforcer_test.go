@@ -0,0 +1,102 @@
+//go:build unix
+
+package budget
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notify"
+)
+
+type recorder struct{ messages []notify.Message }
+
+func (r *recorder) Notify(msg notify.Message) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func TestEnforcerCheck(t *testing.T) {
+	start := lateEvening()
+	// youtube was focused from 22:00 to 22:40 and, with a ten-minute poll
+	// interval, still counts as focused until 22:50.
+	focused := start.Add(40 * time.Minute)
+	after := func(d time.Duration) time.Time { return focused.Add(d) }
+
+	tests := []struct {
+		name   string
+		strict bool
+		checks []time.Time
+		want   []string // Urgency of each notification
+		hooks  string
+	}{
+		{"warns once", false, []time.Time{after(0), after(5 * time.Minute), after(10 * time.Minute)}, []string{"normal"}, ""},
+		{"strict repeats while focused", true, []time.Time{after(0), after(time.Minute), after(5 * time.Minute)}, []string{"normal", "critical"}, "1 youtube youtube\n2 youtube youtube\n"},
+		{"strict waits for the repeat interval", true, []time.Time{after(0), after(4 * time.Minute)}, []string{"normal"}, "1 youtube youtube\n"},
+		{"strict stops when focus moves on", true, []time.Time{after(0), after(20 * time.Minute)}, []string{"normal"}, "1 youtube youtube\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := database.NewRepository(database.OpenTest(t))
+			event := &models.FocusEvent{Timestamp: start, AppName: "youtube", Duration: 40 * 60, DisplayServer: "wayland"}
+			if err := repo.Create(event); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := testConfig(map[string]time.Duration{"youtube": 30 * time.Minute, "social": time.Hour})
+			cfg.Budgets.Strict = tt.strict
+			cfg.Tracker.PollInterval = 10 * time.Minute
+			log := filepath.Join(t.TempDir(), "hook.log")
+			// A command line with arguments and expansions, run by the shell.
+			cfg.Budgets.Hook = `echo "$ACTIONSUM_WARNING $ACTIONSUM_BUDGET $ACTIONSUM_APP" >> '` + log + `'`
+			notifier := &recorder{}
+			e := NewEnforcer(cfg, repo, notifier)
+
+			for _, now := range tt.checks {
+				if err := e.check(context.Background(), now); err != nil {
+					t.Fatalf("check(%v) error = %v", now, err)
+				}
+			}
+
+			var urgencies []string
+			for _, msg := range notifier.messages {
+				urgencies = append(urgencies, msg.Urgency)
+			}
+			if strings.Join(urgencies, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("notifications %v, want %v", urgencies, tt.want)
+			}
+			if len(notifier.messages) > 1 && !strings.Contains(notifier.messages[1].Title, "warning 2") {
+				t.Errorf("second title %q, want the warning count", notifier.messages[1].Title)
+			}
+			hooks, _ := os.ReadFile(log)
+			if string(hooks) != tt.hooks {
+				t.Errorf("hook ran with %q, want %q", hooks, tt.hooks)
+			}
+		})
+	}
+}
+
+func TestEnforcerBudgetsOnly(t *testing.T) {
+	repo := database.NewRepository(database.OpenTest(t))
+	start := lateEvening()
+	if err := repo.Create(&models.FocusEvent{Timestamp: start, AppName: "youtube", Duration: 40 * 60, DisplayServer: "wayland"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(map[string]time.Duration{"youtube": 30 * time.Minute})
+	cfg.WorkHours.BudgetsOnly = true
+	cfg.WorkHours.Windows = nil
+	notifier := &recorder{}
+	e := NewEnforcer(cfg, repo, notifier)
+	if err := e.check(context.Background(), start.Add(40*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.messages) != 1 {
+		t.Errorf("%d notifications without working hours set, want 1", len(notifier.messages))
+	}
+}
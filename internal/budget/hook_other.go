@@ -0,0 +1,15 @@
+//go:build !unix
+
+package budget
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// hookCommand runs hook through cmd.exe, so it may carry arguments.
+func hookCommand(ctx context.Context, hook string) *exec.Cmd {
+	return sandbox.CommandContext(ctx, "cmd", "/C", hook)
+}
@@ -0,0 +1,133 @@
+// Package classify labels focus events from their app and window title,
+// e.g. "reading docs" or "social media", through a user-supplied hook. No
+// classifier is built in and nothing is sent over the network; the hook is
+// either a local command or a classifier compiled into the binary.
+package classify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxLabelLength caps stored labels, in runes.
+const maxLabelLength = 64
+
+// pluginPrefix selects a registered classifier instead of a command.
+const pluginPrefix = "plugin:"
+
+// Input is what a classifier sees of an event.
+type Input struct {
+	AppName     string `json:"app_name"`
+	WindowTitle string `json:"window_title"`
+}
+
+// Classifier returns the label for an event, or "" to leave it unlabelled.
+type Classifier interface {
+	Classify(in Input) (string, error)
+}
+
+// Func adapts a function to Classifier.
+type Func func(in Input) (string, error)
+
+func (f Func) Classify(in Input) (string, error) {
+	return f(in)
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = map[string]Classifier{}
+)
+
+// Register makes c available as "plugin:<name>", for builds that compile a
+// classifier in. It is meant to be called from init functions.
+func Register(name string, c Classifier) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	plugins[name] = c
+}
+
+// New returns the classifier described by spec: "plugin:<name>" for a
+// registered classifier, otherwise a command line started as an Exec
+// classifier. Answers are cached per app and title.
+func New(spec string) (*Cached, error) {
+	spec = strings.TrimSpace(spec)
+	if name, ok := strings.CutPrefix(spec, pluginPrefix); ok {
+		pluginsMu.Lock()
+		c, found := plugins[name]
+		pluginsMu.Unlock()
+		if !found {
+			return nil, fmt.Errorf("no classifier plugin named %q is compiled in", name)
+		}
+		return NewCached(c), nil
+	}
+	args := strings.Fields(spec)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("classifier command is empty")
+	}
+	return NewCached(NewExec(args[0], args[1:]...)), nil
+}
+
+// Clean makes a classifier's answer storable: trimmed, free of control
+// characters and at most maxLabelLength runes.
+func Clean(label string) string {
+	label = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, label)
+	label = strings.TrimSpace(label)
+	if runes := []rune(label); len(runes) > maxLabelLength {
+		label = strings.TrimSpace(string(runes[:maxLabelLength]))
+	}
+	return label
+}
+
+// maxCacheEntries bounds the cache; it is emptied when full.
+const maxCacheEntries = 1024
+
+// Cached remembers the label of each app and title, since the same window
+// is sampled many times in a row.
+type Cached struct {
+	classifier Classifier
+
+	mu     sync.Mutex
+	labels map[Input]string
+}
+
+func NewCached(c Classifier) *Cached {
+	return &Cached{classifier: c, labels: make(map[Input]string)}
+}
+
+func (c *Cached) Classify(in Input) (string, error) {
+	c.mu.Lock()
+	label, ok := c.labels[in]
+	c.mu.Unlock()
+	if ok {
+		return label, nil
+	}
+
+	label, err := c.classifier.Classify(in)
+	if err != nil {
+		return "", err
+	}
+	label = Clean(label)
+
+	c.mu.Lock()
+	if len(c.labels) >= maxCacheEntries {
+		c.labels = make(map[Input]string)
+	}
+	c.labels[in] = label
+	c.mu.Unlock()
+	return label, nil
+}
+
+// Close closes the underlying classifier if it holds resources.
+func (c *Cached) Close() error {
+	if closer, ok := c.classifier.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
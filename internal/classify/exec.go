@@ -0,0 +1,133 @@
+package classify
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+const (
+	// answerTimeout is how long the command may take to answer one event.
+	answerTimeout = 2 * time.Second
+
+	// restartDelay is how long to wait before restarting a command that
+	// exited or stopped answering, so a broken hook isn't spawned every poll.
+	restartDelay = time.Minute
+)
+
+// Exec classifies through a long-running command. Each event is written to
+// its stdin as one line of JSON, {"app_name": ..., "window_title": ...}, and
+// the command answers with one line holding the label, or an empty line for
+// none.
+type Exec struct {
+	name string
+	args []string
+
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	answers    chan string
+	retryAfter time.Time
+}
+
+func NewExec(name string, args ...string) *Exec {
+	return &Exec{name: name, args: args}
+}
+
+func (e *Exec) Classify(in Input) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cmd == nil {
+		if time.Now().Before(e.retryAfter) {
+			return "", fmt.Errorf("classifier %s is waiting to restart", e.name)
+		}
+		if err := e.start(); err != nil {
+			e.retryAfter = time.Now().Add(restartDelay)
+			return "", err
+		}
+	}
+
+	line, err := json.Marshal(in)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode classifier input: %w", err)
+	}
+	if _, err := e.stdin.Write(append(line, '\n')); err != nil {
+		e.fail()
+		return "", fmt.Errorf("failed to write to classifier %s: %w", e.name, err)
+	}
+
+	select {
+	case answer, ok := <-e.answers:
+		if !ok {
+			e.fail()
+			return "", fmt.Errorf("classifier %s exited", e.name)
+		}
+		return answer, nil
+	case <-time.After(answerTimeout):
+		e.fail()
+		return "", fmt.Errorf("classifier %s did not answer within %v", e.name, answerTimeout)
+	}
+}
+
+func (e *Exec) start() error {
+	cmd := sandbox.Command(e.name, e.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start classifier %s: %w", e.name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start classifier %s: %w", e.name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start classifier %s: %w", e.name, err)
+	}
+
+	answers := make(chan string)
+	go func() {
+		defer close(answers)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			answers <- scanner.Text()
+		}
+	}()
+
+	e.cmd, e.stdin, e.answers = cmd, stdin, answers
+	return nil
+}
+
+// fail stops the command after an error and delays its restart.
+func (e *Exec) fail() {
+	e.stop()
+	e.retryAfter = time.Now().Add(restartDelay)
+}
+
+func (e *Exec) stop() {
+	if e.cmd == nil {
+		return
+	}
+	e.stdin.Close()
+	e.cmd.Process.Kill()
+	// Unblock the reader if it is waiting to deliver a late answer.
+	go func(answers chan string) {
+		for range answers {
+		}
+	}(e.answers)
+	e.cmd.Wait()
+	e.cmd, e.stdin, e.answers = nil, nil, nil
+}
+
+// Close stops the command.
+func (e *Exec) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stop()
+	return nil
+}
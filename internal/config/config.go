@@ -2,8 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+	"github.com/actionsum/actionsum/pkg/schedule"
 )
 
 type Config struct {
@@ -16,10 +24,48 @@ type Config struct {
 	Report ReportConfig
 
 	Web WebConfig
+
+	AppNames AppNamesConfig
+
+	Profiles ProfilesConfig
+
+	Tags TagConfig
+
+	Locations LocationConfig
+
+	WorkHours WorkHoursConfig
+
+	Notify NotifyConfig
+
+	Budgets BudgetConfig
+
+	Holidays HolidayConfig
+
+	Billing BillingConfig
 }
 
 type DatabaseConfig struct {
 	Path string
+
+	// SizeWarning is the database plus WAL size, in bytes, above which the
+	// daemon warns. Zero disables the warning.
+	SizeWarning int64
+
+	// WALCheckpointSize is the WAL size, in bytes, at which the daemon
+	// checkpoints and truncates it.
+	WALCheckpointSize int64
+
+	// LogLevel is what the database layer logs: "silent", "error", "warn"
+	// (errors and slow queries) or "info" (every query).
+	LogLevel string
+
+	// SlowQueryThreshold is how long a query may take before it is logged
+	// with its SQL at the "warn" level. Zero disables slow query logging.
+	SlowQueryThreshold time.Duration
+
+	// AutoRecover rebuilds a database found corrupt at daemon startup,
+	// keeping the damaged file aside, instead of refusing to start.
+	AutoRecover bool
 }
 
 type TrackerConfig struct {
@@ -27,75 +73,603 @@ type TrackerConfig struct {
 	MinPollInterval time.Duration
 	MaxPollInterval time.Duration
 	IdleThreshold   time.Duration
+
+	// IdleGrace counts idle stretches up to this long as active when the
+	// user returns, crediting them to the window focused before, so reading
+	// or thinking isn't recorded as time away. Zero disables it.
+	IdleGrace time.Duration
+
+	// AppPollIntervals overrides PollInterval while the named (lowercase) app
+	// is focused, e.g. faster sampling for browsers whose titles change per tab.
+	AppPollIntervals   map[string]time.Duration
+	MinAppPollInterval time.Duration
+
+	// ClockJumpThreshold is the wall-clock vs monotonic drift between polls
+	// treated as a suspend/resume or NTP step.
+	ClockJumpThreshold time.Duration
+
+	// PauseFile records a pause started from the mobile page and when it
+	// ends.
+	PauseFile string
+
+	// BrowserTabFile is where the browser extension's native messaging host
+	// records the active tab, which the daemon attaches to browser events.
+	BrowserTabFile string
+
+	// RemoteTerminalFile is where the web API records what a terminal on
+	// another machine runs, as reported by its remote agent.
+	RemoteTerminalFile string
+
+	// EditorFile is where the web API records the project and file an
+	// editor plugin last reported.
+	EditorFile string
+
+	// ShellStateDir is where shell hooks record each shell's command line
+	// and working directory, one file per shell.
+	ShellStateDir string
+
+	// AwayPrompt, when set, asks through a desktop notification what the
+	// user was doing after an idle or locked stretch at least this long, and
+	// fills the stretch with the chosen AwayReasons entry. AwayFile keeps
+	// the last such stretch until a reason is given.
+	AwayPrompt  time.Duration
+	AwayReasons []string
+	AwayFile    string
+
+	// UnlockSummary, when set, shows a notification with the time worked
+	// so far today and the top app on unlocking the screen after it was
+	// locked at least this long.
+	UnlockSummary time.Duration
+
+	// FocusEvents polls as soon as the display server reports a focus
+	// change, where the detector can listen for one, rather than only at
+	// the poll interval.
+	FocusEvents bool
+
+	// TerminalCommands records the command in the foreground of a focused
+	// terminal, such as vim or ssh, with its events.
+	TerminalCommands bool
+
+	// ShellCommands records the command line and working directory the
+	// shell hooks report for a focused terminal, with what matches
+	// ShellRedact, a regular expression, replaced by "***".
+	ShellCommands bool
+	ShellRedact   string
+
+	// MediaPlayers records what an MPRIS media player, such as Spotify, is
+	// playing with each event.
+	MediaPlayers bool
+
+	// AudioActive counts time with sound playing, such as watching a video,
+	// as active even without keyboard or mouse input. A locked screen still
+	// stops tracking.
+	AudioActive bool
+
+	// Meetings marks events recorded while a microphone or camera is in
+	// use as in a meeting.
+	Meetings bool
+
+	// VPNInterfaces are the prefixes of network interface names, such as
+	// "wg", that mark events recorded while one is up as over a VPN.
+	VPNInterfaces []string
+
+	// ProcessFallback guesses the focused app from running processes when
+	// no display-server detector can tell. Such guesses are only stored
+	// when their confidence, from 0 to 1, is at least MinConfidence.
+	ProcessFallback bool
+	MinConfidence   float64
+
+	// Detector is "auto" to detect windows from the desktop session, or
+	// "fake" to play back Scenario, a scripted file of apps, idle and locked
+	// stretches, for runs without a desktop such as CI.
+	Detector string
+	Scenario string
+
+	// Classifier labels each tracked event from its app and window title:
+	// a command kept running and asked once per distinct title, or
+	// "plugin:<name>" for a classifier compiled in. Empty disables labels.
+	Classifier string
 }
 
 type DaemonConfig struct {
 	PIDFile string
+
+	// StatsFile is where the running daemon publishes how often each
+	// detection backend succeeded, for status, doctor and metrics.
+	StatsFile string
+
+	// StartupTimeout bounds how long a daemon launched at login waits and
+	// retries for the display server and window detector to come up.
+	StartupTimeout time.Duration
+
+	// Nice is the scheduling niceness the daemon sets for itself and the
+	// tools it runs, from -20 to 19. Zero leaves it as inherited.
+	Nice int
+
+	// CPUAffinity pins the daemon to these CPUs, e.g. a laptop's efficiency
+	// cores. Empty lets it run on any.
+	CPUAffinity []int
+
+	// PollBudget is how long a poll may take: after one that took longer,
+	// as with a hung detector tool, the next is skipped. Zero never skips.
+	PollBudget time.Duration
 }
 
 type ReportConfig struct {
 	ExcludeIdle bool
-	TimeZone    string
+
+	// ExcludeProcessGuesses leaves events found by the process-based
+	// fallback out of reports.
+	ExcludeProcessGuesses bool
+	TimeZone              string
+
+	// Locale sets number formatting and period labels in text reports and
+	// on the dashboard, e.g. "de-DE" or "fr_FR.UTF-8". Empty means English.
+	Locale string
+
+	// MicroBreak is the longest break inside a focus session: shorter gaps
+	// between events in the same app join the sessions around them. Gaps up
+	// to the poll interval always do.
+	MicroBreak time.Duration
+
+	// SnapshotInterval, when set, makes the web server answer report queries
+	// from a read-only copy of the database refreshed this often, so long
+	// queries never hold up the tracker's writes. Zero queries live data.
+	SnapshotInterval time.Duration
 }
 
 type WebConfig struct {
+	// Disabled keeps "serve" from running the web server, for machines that
+	// only track and report from the command line. Builds tagged noweb
+	// leave the web server out altogether.
+	Disabled bool
+
 	Host string
 	Port int
+
+	// RequireToken rejects API requests that present no token. Requests
+	// that do present one are always checked against its scopes.
+	RequireToken bool
+
+	// Insecure allows serving on an address other machines can reach
+	// without RequireToken. Window titles are sensitive, so this has to be
+	// asked for explicitly.
+	Insecure bool
+
+	// MultiUser keeps the data of each token's user apart: events submitted
+	// with a token are stored under its user, and every query answers from
+	// that user's data alone. Requests without a token, and tokens without
+	// a user, act for this machine's own user.
+	MultiUser bool
+
+	// TeamReports serves aggregate reports over the users of a multi-user
+	// server at /api/team. Each user decides in their own sharing setting
+	// whether and how their time is included; nobody is included by default.
+	TeamReports bool
+
+	// TeamMinMembers is how many users must share their time by category
+	// before a team report shows category totals, so no total is one
+	// person's time under another name.
+	TeamMinMembers int
+
+	// MDNS advertises the web API as _actionsum._tcp on the local network
+	// so companion apps can find it. It only applies when Host is not a
+	// loopback address.
+	MDNS bool
+
+	// TranslationsDir holds extra dashboard translations, one
+	// "<language>.conf" file of "English = translation" lines per language.
+	TranslationsDir string
+
+	// OIDC lets the users of a multi-user server sign in to the dashboard
+	// with an OpenID Connect provider instead of being handed tokens.
+	OIDC OIDCConfig
+}
+
+type OIDCConfig struct {
+	// Issuer is the provider's issuer URL, such as
+	// https://accounts.example.com. Sign-in is off while it is empty.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is this server's /auth/callback as browsers reach it,
+	// registered with the provider.
+	RedirectURL string
+
+	// UserClaim names the ID token claim that becomes the user ID.
+	UserClaim string
+
+	// AllowedUsers limits who may sign in, by user ID or by "@domain" for
+	// user IDs that are email addresses. Sign-in needs at least one entry,
+	// as many providers let anyone create an account.
+	AllowedUsers []string
+
+	// SessionLength is how long a sign-in lasts.
+	SessionLength time.Duration
+}
+
+type AppNamesConfig struct {
+	// MappingFile holds user "variant = canonical" app name aliases.
+	MappingFile string
+
+	// DesktopEntries reads installed apps' .desktop files for their display
+	// names and to count Flatpak and Snap installs as the native app.
+	DesktopEntries bool
+}
+
+type ProfilesConfig struct {
+	// StateFile records the profile selected with "actionsum profile use".
+	StateFile string
+
+	// Rules pick a profile by time of day when none is selected manually.
+	// The first matching rule wins.
+	Rules []ProfileRule
+}
+
+type TagConfig struct {
+	// StateFile records the tag set with "actionsum tag" and its expiry.
+	StateFile string
+
+	// DefaultDuration is how long a tag lasts when no duration is given.
+	DefaultDuration time.Duration
+}
+
+type LocationConfig struct {
+	// Networks maps Wi-Fi network names (SSIDs) to where the user works
+	// when connected to them, e.g. "office" or "home".
+	Networks map[string]string
+
+	// Default is the location on any other network, or with no Wi-Fi.
+	// Without it, such events have no location.
+	Default string
+}
+
+type WorkHoursConfig struct {
+	// Windows are the working hours. When empty every hour counts as work
+	// and reports carry no inside/outside split.
+	Windows []schedule.Window
+
+	// AutoPause stops recording focus events outside Windows.
+	AutoPause bool
+
+	// BudgetsOnly makes budgets apply to working hours only: only time
+	// inside Windows counts against them, and they warn only then.
+	BudgetsOnly bool
+}
+
+type NotifyConfig struct {
+	Desktop    bool
+	WebhookURL string
+	SMTP       SMTPConfig
+
+	// WeeklyDigest sends a summary of the previous week once it ends.
+	WeeklyDigest bool
+
+	// DigestPDF attaches the digest laid out as a PDF to emails.
+	DigestPDF bool
+
+	// Alerts are the conditions the daemon notifies about as they arise.
+	Alerts []AlertRule
+}
+
+// Alert kinds.
+const (
+	// AlertActive fires once a day when active time exceeds the threshold.
+	AlertActive = "active"
+
+	// AlertOutage fires when nothing was recorded for the threshold while
+	// the screen was unlocked and the user neither idle nor paused.
+	AlertOutage = "outage"
+)
+
+type AlertRule struct {
+	Kind      string
+	Threshold time.Duration
+}
+
+func (r AlertRule) String() string {
+	return fmt.Sprintf("%s>%v", r.Kind, r.Threshold)
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+type BudgetConfig struct {
+	// Limits caps daily focus time per app or category name.
+	Limits map[string]time.Duration
+
+	// Categories group app names under a shared budget name.
+	Categories map[string][]string
+
+	// Strict repeats escalating warnings every RepeatInterval while an
+	// over-budget app stays focused, running Hook, a shell command line,
+	// each time if set.
+	Strict         bool
+	Hook           string
+	RepeatInterval time.Duration
+
+	// DailyGoal is the focus time a day needs, with no budget exceeded, to
+	// extend the goal streak.
+	DailyGoal time.Duration
+}
+
+// HolidayConfig lists the days off, public holidays and PTO, that goal
+// streaks, averages and week-over-week comparisons leave out.
+type HolidayConfig struct {
+	// File holds the days marked with "actionsum holiday add".
+	File string
+
+	// Calendar is an iCalendar file or http(s) URL whose all-day events
+	// are public holidays, e.g. a country's holiday feed.
+	Calendar string
+}
+
+// BillingConfig prices tracked time for invoices. Time is billed per
+// project: the tag an event was recorded under, or else the project its
+// editor reported.
+type BillingConfig struct {
+	// Increment is what each session is rounded up to, e.g. 6 or 15
+	// minutes. Zero bills the exact time.
+	Increment time.Duration
+
+	// Rates are hourly rates keyed by lower-case project name.
+	Rates map[string]float64
+
+	// Currency labels the amounts, e.g. "EUR".
+	Currency string
+}
+
+type ProfileRule struct {
+	Profile string
+	Window  schedule.Window
 }
 
 func Default() *Config {
 	return &Config{
 		Database: DatabaseConfig{
-			Path: "",
+			Path:               "",
+			SizeWarning:        500 << 20,
+			WALCheckpointSize:  64 << 20,
+			LogLevel:           "warn",
+			SlowQueryThreshold: 500 * time.Millisecond,
+			AutoRecover:        true,
 		},
 		Tracker: TrackerConfig{
 			PollInterval:    10 * time.Second,
 			MinPollInterval: 10 * time.Second,
 			MaxPollInterval: 300 * time.Second,
 			IdleThreshold:   300 * time.Second,
+
+			AppPollIntervals:   map[string]time.Duration{},
+			MinAppPollInterval: 2 * time.Second,
+			ClockJumpThreshold: 30 * time.Second,
+			PauseFile:          configFile("pause"),
+			BrowserTabFile:     filepath.Join(sandbox.UserRuntimeDir(), "tab.json"),
+			RemoteTerminalFile: filepath.Join(sandbox.UserRuntimeDir(), "terminal.json"),
+			EditorFile:         filepath.Join(sandbox.UserRuntimeDir(), "editor.json"),
+			ShellStateDir:      filepath.Join(sandbox.UserRuntimeDir(), "shell"),
+			ProcessFallback:    !sandbox.Flatpak(),
+			Detector:           "auto",
+			AwayReasons:        []string{"Meeting", "Break", "Lunch"},
+			VPNInterfaces:      []string{"tun", "tap", "wg", "ppp"},
+			AwayFile:           configFile("away"),
 		},
 		Daemon: DaemonConfig{
-			PIDFile: fmt.Sprintf("/tmp/actionsum-%d.pid", os.Getuid()),
+			PIDFile:        filepath.Join(sandbox.RuntimeDir(), fmt.Sprintf("actionsum-%d.pid", os.Getuid())),
+			StatsFile:      filepath.Join(sandbox.RuntimeDir(), fmt.Sprintf("actionsum-%d.stats.json", os.Getuid())),
+			StartupTimeout: 60 * time.Second,
 		},
 		Report: ReportConfig{
 			ExcludeIdle: true,
 			TimeZone:    "Local",
 		},
 		Web: WebConfig{
-			Host: "localhost",
-			Port: 10000 + os.Getuid(),
+			TranslationsDir: configFile("translations"),
+			Host:            "localhost",
+			Port:            10000 + os.Getuid(),
+			TeamMinMembers:  3,
+			OIDC: OIDCConfig{
+				UserClaim:     "preferred_username",
+				SessionLength: 7 * 24 * time.Hour,
+			},
+		},
+		AppNames: AppNamesConfig{
+			MappingFile:    configFile("app-names.conf"),
+			DesktopEntries: true,
+		},
+		Profiles: ProfilesConfig{
+			StateFile: configFile("profile"),
+		},
+		Tags: TagConfig{
+			StateFile:       configFile("tag"),
+			DefaultDuration: 30 * time.Minute,
+		},
+		Locations: LocationConfig{
+			Networks: map[string]string{},
+		},
+		Budgets: BudgetConfig{
+			Limits:         map[string]time.Duration{},
+			Categories:     map[string][]string{},
+			RepeatInterval: 5 * time.Minute,
+			DailyGoal:      time.Hour,
+		},
+		Holidays: HolidayConfig{
+			File: configFile("holidays"),
 		},
+		Billing: BillingConfig{
+			Rates: map[string]float64{},
+		},
+		Notify: NotifyConfig{
+			Desktop: true,
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+	}
+}
+
+// configFile returns the path of name in ~/.config/actionsum, or inside a
+// Flatpak in the app's own config directory, since the sandbox can't reach
+// ~/.config.
+func configFile(name string) string {
+	if configDir := os.Getenv("XDG_CONFIG_HOME"); configDir != "" && sandbox.Flatpak() {
+		return filepath.Join(configDir, "actionsum", name)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
 	}
+	return filepath.Join(homeDir, ".config", "actionsum", name)
 }
 
+// Validate returns the first problem with the configuration, if any.
 func (c *Config) Validate() error {
+	if errs := c.validationErrors(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// WebExposed reports whether the web server's host may be reachable from
+// other machines: anything but localhost or an address that only resolves
+// to loopback.
+func (c *Config) WebExposed() bool {
+	if c.Web.Host == "localhost" {
+		return false
+	}
+	ips := []net.IP{net.ParseIP(c.Web.Host)}
+	if ips[0] == nil {
+		resolved, err := net.LookupIP(c.Web.Host)
+		if err != nil || len(resolved) == 0 {
+			return true
+		}
+		ips = resolved
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
+
+// validationErrors returns every inconsistency within the configuration.
+func (c *Config) validationErrors() []error {
+	var errs []error
+
 	if c.Tracker.PollInterval < c.Tracker.MinPollInterval {
-		return fmt.Errorf("poll interval (%v) cannot be less than minimum (%v)",
-			c.Tracker.PollInterval, c.Tracker.MinPollInterval)
+		errs = append(errs, fmt.Errorf("poll interval (%v) cannot be less than minimum (%v)",
+			c.Tracker.PollInterval, c.Tracker.MinPollInterval))
 	}
 
 	if c.Tracker.PollInterval > c.Tracker.MaxPollInterval {
-		return fmt.Errorf("poll interval (%v) cannot be greater than maximum (%v)",
-			c.Tracker.PollInterval, c.Tracker.MaxPollInterval)
+		errs = append(errs, fmt.Errorf("poll interval (%v) cannot be greater than maximum (%v)",
+			c.Tracker.PollInterval, c.Tracker.MaxPollInterval))
 	}
 
 	if c.Tracker.IdleThreshold < 0 {
-		return fmt.Errorf("idle threshold cannot be negative")
+		errs = append(errs, fmt.Errorf("idle threshold cannot be negative"))
+	}
+
+	if c.Tracker.MinConfidence < 0 || c.Tracker.MinConfidence > 1 {
+		errs = append(errs, fmt.Errorf("minimum confidence must be between 0 and 1, got %v", c.Tracker.MinConfidence))
+	}
+
+	switch c.Tracker.Detector {
+	case "auto":
+	case "fake":
+		if c.Tracker.Scenario == "" {
+			errs = append(errs, fmt.Errorf("the fake detector needs a scenario file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("detector must be auto or fake, got %q", c.Tracker.Detector))
+	}
+
+	if c.Tracker.IdleGrace < 0 {
+		errs = append(errs, fmt.Errorf("idle grace cannot be negative"))
+	}
+
+	if c.Tracker.ClockJumpThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("clock jump threshold must be positive"))
+	}
+
+	apps := make([]string, 0, len(c.Tracker.AppPollIntervals))
+	for app := range c.Tracker.AppPollIntervals {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		interval := c.Tracker.AppPollIntervals[app]
+		if interval < c.Tracker.MinAppPollInterval || interval > c.Tracker.MaxPollInterval {
+			errs = append(errs, fmt.Errorf("poll interval for %s (%v) must be between %v and %v",
+				app, interval, c.Tracker.MinAppPollInterval, c.Tracker.MaxPollInterval))
+		}
+	}
+
+	if c.Budgets.Strict && c.Budgets.RepeatInterval < time.Minute {
+		errs = append(errs, fmt.Errorf("budget repeat interval must be at least 1m, got %v", c.Budgets.RepeatInterval))
+	}
+
+	for _, rule := range c.Notify.Alerts {
+		// Alerts are checked once a minute; an outage is only noticed once
+		// the tracker has had time to record something.
+		if rule.Threshold < time.Minute {
+			errs = append(errs, fmt.Errorf("alert %s must have a threshold of at least 1m", rule))
+		} else if rule.Kind == AlertOutage && rule.Threshold < 2*c.Tracker.MaxPollInterval {
+			errs = append(errs, fmt.Errorf("alert %s must allow at least two of the longest poll intervals (%v)", rule, c.Tracker.MaxPollInterval))
+		}
 	}
 
 	if c.Web.Port < 1 || c.Web.Port > 65535 {
-		return fmt.Errorf("web port must be between 1 and 65535, got %d", c.Web.Port)
+		errs = append(errs, fmt.Errorf("web port must be between 1 and 65535, got %d", c.Web.Port))
 	}
 
 	if c.Web.Host == "" {
-		return fmt.Errorf("web host cannot be empty")
+		errs = append(errs, fmt.Errorf("web host cannot be empty"))
+	} else if c.WebExposed() && !c.Web.RequireToken && !c.Web.Insecure {
+		errs = append(errs, fmt.Errorf("web host %s is reachable from other machines without authentication; "+
+			"set ACTIONSUM_WEB_REQUIRE_TOKEN=true and create a token, or pass --insecure to serve anyway", c.Web.Host))
+	}
+
+	if c.Web.TeamReports && !c.Web.MultiUser {
+		errs = append(errs, fmt.Errorf("team reports need multi-user mode; set ACTIONSUM_WEB_MULTI_USER=true"))
+	}
+
+	if c.Web.OIDC.Issuer != "" {
+		if !c.Web.MultiUser {
+			errs = append(errs, fmt.Errorf("OIDC sign-in needs multi-user mode; set ACTIONSUM_WEB_MULTI_USER=true"))
+		}
+		if c.Web.OIDC.ClientID == "" || c.Web.OIDC.RedirectURL == "" {
+			errs = append(errs, fmt.Errorf("OIDC sign-in needs ACTIONSUM_OIDC_CLIENT_ID and ACTIONSUM_OIDC_REDIRECT_URL"))
+		}
+		if !c.Web.RequireToken {
+			errs = append(errs, fmt.Errorf("OIDC sign-in needs ACTIONSUM_WEB_REQUIRE_TOKEN=true, or visitors who don't sign in see this machine's activity"))
+		}
+		if len(c.Web.OIDC.AllowedUsers) == 0 {
+			errs = append(errs, fmt.Errorf("OIDC sign-in needs ACTIONSUM_OIDC_ALLOWED_USERS, user IDs or @domain entries, or anyone with an account at the provider can sign in"))
+		}
 	}
 
 	if c.Daemon.PIDFile == "" {
-		return fmt.Errorf("PID file path cannot be empty")
+		errs = append(errs, fmt.Errorf("PID file path cannot be empty"))
 	}
 
-	return nil
+	if c.Daemon.Nice < -20 || c.Daemon.Nice > 19 {
+		errs = append(errs, fmt.Errorf("nice level must be between -20 and 19, got %d", c.Daemon.Nice))
+	}
+
+	if c.Daemon.PollBudget < 0 {
+		errs = append(errs, fmt.Errorf("poll budget cannot be negative"))
+	}
+
+	return errs
 }
 
 func (c *Config) SetPollInterval(interval time.Duration) error {
@@ -121,6 +695,140 @@ func (c *Config) GetPollIntervalSeconds() int64 {
 	return int64(c.Tracker.PollInterval.Seconds())
 }
 
+// PollIntervalFor returns the sampling interval to use while appName is
+// focused, falling back to the global poll interval.
+func (c *Config) PollIntervalFor(appName string) time.Duration {
+	if interval, ok := c.Tracker.AppPollIntervals[strings.ToLower(appName)]; ok {
+		return interval
+	}
+	return c.Tracker.PollInterval
+}
+
+// SessionGap is the longest gap between events in one app that still
+// belongs to a single focus session.
+func (c *Config) SessionGap() time.Duration {
+	if c.Report.MicroBreak > c.Tracker.PollInterval {
+		return c.Report.MicroBreak
+	}
+	return c.Tracker.PollInterval
+}
+
+func (c *Config) AppPollIntervalsString() string {
+	if len(c.Tracker.AppPollIntervals) == 0 {
+		return "none"
+	}
+	apps := make([]string, 0, len(c.Tracker.AppPollIntervals))
+	for app := range c.Tracker.AppPollIntervals {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	parts := make([]string, len(apps))
+	for i, app := range apps {
+		parts[i] = fmt.Sprintf("%s=%v", app, c.Tracker.AppPollIntervals[app])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// InWorkHours reports whether t is inside the configured working hours.
+func (c *Config) InWorkHours(t time.Time) bool {
+	if len(c.WorkHours.Windows) == 0 {
+		return true
+	}
+	return schedule.Any(c.WorkHours.Windows, t)
+}
+
+func (c *Config) WorkHoursString() string {
+	if len(c.WorkHours.Windows) == 0 {
+		return "not set"
+	}
+	parts := make([]string, len(c.WorkHours.Windows))
+	for i, w := range c.WorkHours.Windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (c *Config) BillingRatesString() string {
+	if len(c.Billing.Rates) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(c.Billing.Rates))
+	for name := range c.Billing.Rates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%s", name, strconv.FormatFloat(c.Billing.Rates[name], 'f', -1, 64))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Config) BudgetLimitsString() string {
+	if len(c.Budgets.Limits) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(c.Budgets.Limits))
+	for name := range c.Budgets.Limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%v", name, c.Budgets.Limits[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Config) LocationNetworksString() string {
+	if len(c.Locations.Networks) == 0 {
+		return "none"
+	}
+	ssids := make([]string, 0, len(c.Locations.Networks))
+	for ssid := range c.Locations.Networks {
+		ssids = append(ssids, ssid)
+	}
+	sort.Strings(ssids)
+	parts := make([]string, len(ssids))
+	for i, ssid := range ssids {
+		parts[i] = fmt.Sprintf("%s=%s", ssid, c.Locations.Networks[ssid])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Config) CPUAffinityString() string {
+	if len(c.Daemon.CPUAffinity) == 0 {
+		return "any"
+	}
+	parts := make([]string, len(c.Daemon.CPUAffinity))
+	for i, cpu := range c.Daemon.CPUAffinity {
+		parts[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *Config) AlertsString() string {
+	if len(c.Notify.Alerts) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(c.Notify.Alerts))
+	for i, rule := range c.Notify.Alerts {
+		parts[i] = rule.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Config) ProfileRulesString() string {
+	if len(c.Profiles.Rules) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(c.Profiles.Rules))
+	for i, rule := range c.Profiles.Rules {
+		parts[i] = fmt.Sprintf("%s=%s", rule.Profile, rule.Window)
+	}
+	return strings.Join(parts, "; ")
+}
+
 func (c *Config) GetIdleThresholdSeconds() int64 {
 	return int64(c.Tracker.IdleThreshold.Seconds())
 }
@@ -129,28 +837,203 @@ func (c *Config) String() string {
 	return fmt.Sprintf(`Configuration:
   Database:
     Path: %s
+    Size Warning: %d MB
+    WAL Checkpoint: %d MB
+    Log Level: %s
+    Slow Query Threshold: %v
+    Auto Recover: %v
   Tracker:
     Poll Interval: %v
     Min Interval: %v
     Max Interval: %v
     Idle Threshold: %v
+    Idle Grace: %v
+    App Intervals: %s
+    Clock Jump Threshold: %v
+    Pause File: %s
+    Browser Tab File: %s
+    Remote Terminal File: %s
+    Editor File: %s
+    Shell State Dir: %s
+    Away Prompt: %v
+    Away Reasons: %s
+    Away File: %s
+    Unlock Summary: %v
+    Focus Events: %v
+    Terminal Commands: %v
+    Shell Commands: %v (redact %s)
+    Media Players: %v
+    Audio Active: %v
+    Meetings: %v
+    VPN Interfaces: %s
+    Process Fallback: %v (min confidence %.2f)
+    Classifier: %s
+    Detector: %s
   Daemon:
     PID File: %s
+    Stats File: %s
+    Startup Timeout: %v
+    Nice: %d
+    CPU Affinity: %s
+    Poll Budget: %v
   Report:
     Exclude Idle: %v
+    Exclude Process Guesses: %v
     Time Zone: %s
+    Locale: %s
+    Micro Break: %v
+    Snapshot Interval: %v
   Web:
+    Disabled: %v
     Host: %s
-    Port: %d`,
+    Port: %d
+    Require Token: %v
+    Insecure: %v
+    Multi-User: %v
+    Team Reports: %v (categories from %d members)
+    OIDC: %s
+    mDNS: %v
+    Translations: %s
+  App Names:
+    Mapping File: %s
+    Desktop Entries: %v
+  Profiles:
+    State File: %s
+    Rules: %s
+  Tags:
+    State File: %s
+    Default Duration: %v
+  Locations:
+    Networks: %s
+    Default: %s
+  Work Hours:
+    Hours: %s
+    Auto Pause: %v
+  Notifications:
+    Desktop: %v
+    Webhook: %s
+    Email: %s
+    Weekly Digest: %v
+    Digest PDF: %v
+    Alerts: %s
+  Budgets:
+    Limits: %s
+    Strict: %v
+    Daily Goal: %v
+  Holidays:
+    File: %s
+    Calendar: %s
+  Billing:
+    Increment: %v
+    Rates: %s
+    Currency: %s`,
 		c.Database.Path,
+		c.Database.SizeWarning>>20,
+		c.Database.WALCheckpointSize>>20,
+		c.Database.LogLevel,
+		c.Database.SlowQueryThreshold,
+		c.Database.AutoRecover,
 		c.Tracker.PollInterval,
 		c.Tracker.MinPollInterval,
 		c.Tracker.MaxPollInterval,
 		c.Tracker.IdleThreshold,
+		c.Tracker.IdleGrace,
+		c.AppPollIntervalsString(),
+		c.Tracker.ClockJumpThreshold,
+		c.Tracker.PauseFile,
+		c.Tracker.BrowserTabFile,
+		c.Tracker.RemoteTerminalFile,
+		c.Tracker.EditorFile,
+		c.Tracker.ShellStateDir,
+		c.Tracker.AwayPrompt,
+		strings.Join(c.Tracker.AwayReasons, ", "),
+		c.Tracker.AwayFile,
+		c.Tracker.UnlockSummary,
+		c.Tracker.FocusEvents,
+		c.Tracker.TerminalCommands,
+		c.Tracker.ShellCommands,
+		valueOrNone(c.Tracker.ShellRedact),
+		c.Tracker.MediaPlayers,
+		c.Tracker.AudioActive,
+		c.Tracker.Meetings,
+		valueOrNone(strings.Join(c.Tracker.VPNInterfaces, ", ")),
+		c.Tracker.ProcessFallback,
+		c.Tracker.MinConfidence,
+		valueOrNone(c.Tracker.Classifier),
+		c.detectorString(),
 		c.Daemon.PIDFile,
+		c.Daemon.StatsFile,
+		c.Daemon.StartupTimeout,
+		c.Daemon.Nice,
+		c.CPUAffinityString(),
+		c.Daemon.PollBudget,
 		c.Report.ExcludeIdle,
+		c.Report.ExcludeProcessGuesses,
 		c.Report.TimeZone,
+		valueOrNone(c.Report.Locale),
+		c.Report.MicroBreak,
+		c.Report.SnapshotInterval,
+		c.Web.Disabled,
 		c.Web.Host,
 		c.Web.Port,
+		c.Web.RequireToken,
+		c.Web.Insecure,
+		c.Web.MultiUser,
+		c.Web.TeamReports,
+		c.Web.TeamMinMembers,
+		c.OIDCString(),
+		c.Web.MDNS,
+		c.Web.TranslationsDir,
+		c.AppNames.MappingFile,
+		c.AppNames.DesktopEntries,
+		c.Profiles.StateFile,
+		c.ProfileRulesString(),
+		c.Tags.StateFile,
+		c.Tags.DefaultDuration,
+		c.LocationNetworksString(),
+		valueOrNone(c.Locations.Default),
+		c.WorkHoursString(),
+		c.WorkHours.AutoPause,
+		c.Notify.Desktop,
+		valueOrNone(c.Notify.WebhookURL),
+		valueOrNone(strings.Join(c.Notify.SMTP.To, ", ")),
+		c.Notify.WeeklyDigest,
+		c.Notify.DigestPDF,
+		c.AlertsString(),
+		c.BudgetLimitsString(),
+		c.Budgets.Strict,
+		c.Budgets.DailyGoal,
+		c.Holidays.File,
+		valueOrNone(c.Holidays.Calendar),
+		c.Billing.Increment,
+		c.BillingRatesString(),
+		valueOrNone(c.Billing.Currency),
 	)
 }
+
+func (c *Config) detectorString() string {
+	if c.Tracker.Detector == "fake" {
+		return fmt.Sprintf("fake (scenario %s)", c.Tracker.Scenario)
+	}
+	return c.Tracker.Detector
+}
+
+// OIDCString describes the OIDC sign-in settings, without the secret.
+func (c *Config) OIDCString() string {
+	if c.Web.OIDC.Issuer == "" {
+		return "none"
+	}
+	allowed := "anyone"
+	if len(c.Web.OIDC.AllowedUsers) > 0 {
+		allowed = strings.Join(c.Web.OIDC.AllowedUsers, ", ")
+	}
+	return fmt.Sprintf("%s (client %s, user claim %s, allowed %s, sessions %v)",
+		c.Web.OIDC.Issuer, c.Web.OIDC.ClientID, c.Web.OIDC.UserClaim, allowed, c.Web.OIDC.SessionLength)
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
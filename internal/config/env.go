@@ -1,9 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/actionsum/actionsum/pkg/schedule"
 )
 
 func LoadFromEnv(cfg *Config) {
@@ -11,6 +18,37 @@ func LoadFromEnv(cfg *Config) {
 		cfg.Database.Path = dbPath
 	}
 
+	if sizeWarn := os.Getenv("ACTIONSUM_DB_SIZE_WARN"); sizeWarn != "" {
+		if mb, err := strconv.ParseInt(sizeWarn, 10, 64); err == nil && mb >= 0 {
+			cfg.Database.SizeWarning = mb << 20
+		}
+	}
+
+	if walSize := os.Getenv("ACTIONSUM_WAL_CHECKPOINT"); walSize != "" {
+		if mb, err := strconv.ParseInt(walSize, 10, 64); err == nil && mb > 0 {
+			cfg.Database.WALCheckpointSize = mb << 20
+		}
+	}
+
+	if logLevel := os.Getenv("ACTIONSUM_DB_LOG_LEVEL"); logLevel != "" {
+		switch level := strings.ToLower(logLevel); level {
+		case "silent", "error", "warn", "info":
+			cfg.Database.LogLevel = level
+		}
+	}
+
+	if slowQuery := os.Getenv("ACTIONSUM_DB_SLOW_QUERY"); slowQuery != "" {
+		if d, err := time.ParseDuration(slowQuery); err == nil && d >= 0 {
+			cfg.Database.SlowQueryThreshold = d
+		}
+	}
+
+	if autoRecover := os.Getenv("ACTIONSUM_DB_AUTO_RECOVER"); autoRecover != "" {
+		if val, err := strconv.ParseBool(autoRecover); err == nil {
+			cfg.Database.AutoRecover = val
+		}
+	}
+
 	if pollInterval := os.Getenv("ACTIONSUM_POLL_INTERVAL"); pollInterval != "" {
 		if seconds, err := strconv.Atoi(pollInterval); err == nil && seconds > 0 {
 			interval := time.Duration(seconds) * time.Second
@@ -20,16 +58,66 @@ func LoadFromEnv(cfg *Config) {
 		}
 	}
 
+	if appIntervals := os.Getenv("ACTIONSUM_APP_POLL_INTERVALS"); appIntervals != "" {
+		cfg.Tracker.AppPollIntervals = parseAppIntervals(appIntervals)
+	}
+
 	if idleThreshold := os.Getenv("ACTIONSUM_IDLE_THRESHOLD"); idleThreshold != "" {
 		if seconds, err := strconv.Atoi(idleThreshold); err == nil && seconds > 0 {
 			cfg.Tracker.IdleThreshold = time.Duration(seconds) * time.Second
 		}
 	}
 
+	if idleGrace := os.Getenv("ACTIONSUM_IDLE_GRACE"); idleGrace != "" {
+		if d, err := time.ParseDuration(idleGrace); err == nil && d >= 0 {
+			cfg.Tracker.IdleGrace = d
+		}
+	}
+
+	if jumpThreshold := os.Getenv("ACTIONSUM_CLOCK_JUMP_THRESHOLD"); jumpThreshold != "" {
+		if seconds, err := strconv.Atoi(jumpThreshold); err == nil && seconds > 0 {
+			cfg.Tracker.ClockJumpThreshold = time.Duration(seconds) * time.Second
+		}
+	}
+
 	if pidFile := os.Getenv("ACTIONSUM_PID_FILE"); pidFile != "" {
 		cfg.Daemon.PIDFile = pidFile
 	}
 
+	if statsFile := os.Getenv("ACTIONSUM_STATS_FILE"); statsFile != "" {
+		cfg.Daemon.StatsFile = statsFile
+	}
+
+	if startupTimeout := os.Getenv("ACTIONSUM_STARTUP_TIMEOUT"); startupTimeout != "" {
+		if seconds, err := strconv.Atoi(startupTimeout); err == nil && seconds >= 0 {
+			cfg.Daemon.StartupTimeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if nice := os.Getenv("ACTIONSUM_NICE"); nice != "" {
+		if val, err := strconv.Atoi(nice); err == nil && val >= -20 && val <= 19 {
+			cfg.Daemon.Nice = val
+		}
+	}
+
+	if affinity := os.Getenv("ACTIONSUM_CPU_AFFINITY"); affinity != "" {
+		if cpus, err := parseCPUList(affinity); err == nil {
+			cfg.Daemon.CPUAffinity = cpus
+		}
+	}
+
+	if pollBudget := os.Getenv("ACTIONSUM_POLL_BUDGET"); pollBudget != "" {
+		if d, err := time.ParseDuration(pollBudget); err == nil && d >= 0 {
+			cfg.Daemon.PollBudget = d
+		}
+	}
+
+	if excludeGuesses := os.Getenv("ACTIONSUM_EXCLUDE_PROCESS_GUESSES"); excludeGuesses != "" {
+		if val, err := strconv.ParseBool(excludeGuesses); err == nil {
+			cfg.Report.ExcludeProcessGuesses = val
+		}
+	}
+
 	if excludeIdle := os.Getenv("ACTIONSUM_EXCLUDE_IDLE"); excludeIdle != "" {
 		if val, err := strconv.ParseBool(excludeIdle); err == nil {
 			cfg.Report.ExcludeIdle = val
@@ -40,10 +128,392 @@ func LoadFromEnv(cfg *Config) {
 		cfg.Report.TimeZone = timeZone
 	}
 
+	if locale := os.Getenv("ACTIONSUM_LOCALE"); locale != "" {
+		cfg.Report.Locale = locale
+	}
+
+	if microBreak := os.Getenv("ACTIONSUM_MICRO_BREAK"); microBreak != "" {
+		if d, err := time.ParseDuration(microBreak); err == nil && d >= 0 {
+			cfg.Report.MicroBreak = d
+		}
+	}
+
+	if snapshot := os.Getenv("ACTIONSUM_REPORT_SNAPSHOT"); snapshot != "" {
+		if interval, err := time.ParseDuration(snapshot); err == nil && (interval == 0 || interval >= time.Minute) {
+			cfg.Report.SnapshotInterval = interval
+		}
+	}
+
 	if webHost := os.Getenv("ACTIONSUM_WEB_HOST"); webHost != "" {
 		cfg.Web.Host = webHost
 	}
 
+	if requireToken := os.Getenv("ACTIONSUM_WEB_REQUIRE_TOKEN"); requireToken != "" {
+		if val, err := strconv.ParseBool(requireToken); err == nil {
+			cfg.Web.RequireToken = val
+		}
+	}
+
+	if disabled := os.Getenv("ACTIONSUM_WEB_DISABLED"); disabled != "" {
+		if val, err := strconv.ParseBool(disabled); err == nil {
+			cfg.Web.Disabled = val
+		}
+	}
+
+	if insecure := os.Getenv("ACTIONSUM_WEB_INSECURE"); insecure != "" {
+		if val, err := strconv.ParseBool(insecure); err == nil {
+			cfg.Web.Insecure = val
+		}
+	}
+
+	if multiUser := os.Getenv("ACTIONSUM_WEB_MULTI_USER"); multiUser != "" {
+		if val, err := strconv.ParseBool(multiUser); err == nil {
+			cfg.Web.MultiUser = val
+		}
+	}
+
+	if teamReports := os.Getenv("ACTIONSUM_WEB_TEAM_REPORTS"); teamReports != "" {
+		if val, err := strconv.ParseBool(teamReports); err == nil {
+			cfg.Web.TeamReports = val
+		}
+	}
+
+	if minMembers := os.Getenv("ACTIONSUM_WEB_TEAM_MIN_MEMBERS"); minMembers != "" {
+		if val, err := strconv.Atoi(minMembers); err == nil && val >= 1 {
+			cfg.Web.TeamMinMembers = val
+		}
+	}
+
+	if issuer := os.Getenv("ACTIONSUM_OIDC_ISSUER"); issuer != "" {
+		cfg.Web.OIDC.Issuer = strings.TrimSuffix(issuer, "/")
+	}
+
+	if clientID := os.Getenv("ACTIONSUM_OIDC_CLIENT_ID"); clientID != "" {
+		cfg.Web.OIDC.ClientID = clientID
+	}
+
+	if clientSecret := os.Getenv("ACTIONSUM_OIDC_CLIENT_SECRET"); clientSecret != "" {
+		cfg.Web.OIDC.ClientSecret = clientSecret
+	}
+
+	if redirectURL := os.Getenv("ACTIONSUM_OIDC_REDIRECT_URL"); redirectURL != "" {
+		cfg.Web.OIDC.RedirectURL = redirectURL
+	}
+
+	if userClaim := os.Getenv("ACTIONSUM_OIDC_USER_CLAIM"); userClaim != "" {
+		cfg.Web.OIDC.UserClaim = userClaim
+	}
+
+	if allowed := os.Getenv("ACTIONSUM_OIDC_ALLOWED_USERS"); allowed != "" {
+		cfg.Web.OIDC.AllowedUsers = nil
+		for _, user := range strings.Split(allowed, ",") {
+			if user = strings.TrimSpace(user); user != "" {
+				cfg.Web.OIDC.AllowedUsers = append(cfg.Web.OIDC.AllowedUsers, user)
+			}
+		}
+	}
+
+	if session := os.Getenv("ACTIONSUM_OIDC_SESSION"); session != "" {
+		if d, err := time.ParseDuration(session); err == nil && d > 0 {
+			cfg.Web.OIDC.SessionLength = d
+		}
+	}
+
+	if mdns := os.Getenv("ACTIONSUM_MDNS"); mdns != "" {
+		if val, err := strconv.ParseBool(mdns); err == nil {
+			cfg.Web.MDNS = val
+		}
+	}
+
+	if translations := os.Getenv("ACTIONSUM_TRANSLATIONS_DIR"); translations != "" {
+		cfg.Web.TranslationsDir = translations
+	}
+
+	if mappingFile := os.Getenv("ACTIONSUM_APP_NAMES_FILE"); mappingFile != "" {
+		cfg.AppNames.MappingFile = mappingFile
+	}
+
+	if desktopEntries := os.Getenv("ACTIONSUM_DESKTOP_ENTRIES"); desktopEntries != "" {
+		if val, err := strconv.ParseBool(desktopEntries); err == nil {
+			cfg.AppNames.DesktopEntries = val
+		}
+	}
+
+	if profileFile := os.Getenv("ACTIONSUM_PROFILE_FILE"); profileFile != "" {
+		cfg.Profiles.StateFile = profileFile
+	}
+
+	if tagFile := os.Getenv("ACTIONSUM_TAG_FILE"); tagFile != "" {
+		cfg.Tags.StateFile = tagFile
+	}
+
+	if pauseFile := os.Getenv("ACTIONSUM_PAUSE_FILE"); pauseFile != "" {
+		cfg.Tracker.PauseFile = pauseFile
+	}
+
+	if tabFile := os.Getenv("ACTIONSUM_BROWSER_TAB_FILE"); tabFile != "" {
+		cfg.Tracker.BrowserTabFile = tabFile
+	}
+
+	if terminalFile := os.Getenv("ACTIONSUM_REMOTE_TERMINAL_FILE"); terminalFile != "" {
+		cfg.Tracker.RemoteTerminalFile = terminalFile
+	}
+
+	if editorFile := os.Getenv("ACTIONSUM_EDITOR_FILE"); editorFile != "" {
+		cfg.Tracker.EditorFile = editorFile
+	}
+
+	if shellDir := os.Getenv("ACTIONSUM_SHELL_STATE_DIR"); shellDir != "" {
+		cfg.Tracker.ShellStateDir = shellDir
+	}
+
+	if awayPrompt := os.Getenv("ACTIONSUM_AWAY_PROMPT"); awayPrompt != "" {
+		if d, err := time.ParseDuration(awayPrompt); err == nil && d >= 0 {
+			cfg.Tracker.AwayPrompt = d
+		}
+	}
+
+	if unlockSummary := os.Getenv("ACTIONSUM_UNLOCK_SUMMARY"); unlockSummary != "" {
+		if d, err := time.ParseDuration(unlockSummary); err == nil && d >= 0 {
+			cfg.Tracker.UnlockSummary = d
+		}
+	}
+
+	if awayReasons := os.Getenv("ACTIONSUM_AWAY_REASONS"); awayReasons != "" {
+		cfg.Tracker.AwayReasons = nil
+		for _, reason := range strings.Split(awayReasons, ",") {
+			if reason = strings.TrimSpace(reason); reason != "" {
+				cfg.Tracker.AwayReasons = append(cfg.Tracker.AwayReasons, reason)
+			}
+		}
+	}
+
+	if awayFile := os.Getenv("ACTIONSUM_AWAY_FILE"); awayFile != "" {
+		cfg.Tracker.AwayFile = awayFile
+	}
+
+	if focusEvents := os.Getenv("ACTIONSUM_FOCUS_EVENTS"); focusEvents != "" {
+		if val, err := strconv.ParseBool(focusEvents); err == nil {
+			cfg.Tracker.FocusEvents = val
+		}
+	}
+
+	if terminalCommands := os.Getenv("ACTIONSUM_TERMINAL_COMMANDS"); terminalCommands != "" {
+		if val, err := strconv.ParseBool(terminalCommands); err == nil {
+			cfg.Tracker.TerminalCommands = val
+		}
+	}
+
+	if shellCommands := os.Getenv("ACTIONSUM_SHELL_COMMANDS"); shellCommands != "" {
+		if val, err := strconv.ParseBool(shellCommands); err == nil {
+			cfg.Tracker.ShellCommands = val
+		}
+	}
+
+	if shellRedact := os.Getenv("ACTIONSUM_SHELL_REDACT"); shellRedact != "" {
+		if _, err := regexp.Compile(shellRedact); err == nil {
+			cfg.Tracker.ShellRedact = shellRedact
+		}
+	}
+
+	if mediaPlayers := os.Getenv("ACTIONSUM_MEDIA_PLAYERS"); mediaPlayers != "" {
+		if val, err := strconv.ParseBool(mediaPlayers); err == nil {
+			cfg.Tracker.MediaPlayers = val
+		}
+	}
+
+	if audioActive := os.Getenv("ACTIONSUM_AUDIO_ACTIVE"); audioActive != "" {
+		if val, err := strconv.ParseBool(audioActive); err == nil {
+			cfg.Tracker.AudioActive = val
+		}
+	}
+
+	if meetings := os.Getenv("ACTIONSUM_MEETINGS"); meetings != "" {
+		if val, err := strconv.ParseBool(meetings); err == nil {
+			cfg.Tracker.Meetings = val
+		}
+	}
+
+	if vpnInterfaces := os.Getenv("ACTIONSUM_VPN_INTERFACES"); vpnInterfaces != "" {
+		cfg.Tracker.VPNInterfaces = nil
+		if vpnInterfaces != "none" {
+			for _, prefix := range strings.Split(vpnInterfaces, ",") {
+				if prefix = strings.TrimSpace(prefix); prefix != "" {
+					cfg.Tracker.VPNInterfaces = append(cfg.Tracker.VPNInterfaces, prefix)
+				}
+			}
+		}
+	}
+
+	if fallback := os.Getenv("ACTIONSUM_PROCESS_FALLBACK"); fallback != "" {
+		if val, err := strconv.ParseBool(fallback); err == nil {
+			cfg.Tracker.ProcessFallback = val
+		}
+	}
+
+	if minConfidence := os.Getenv("ACTIONSUM_MIN_CONFIDENCE"); minConfidence != "" {
+		if val, err := strconv.ParseFloat(minConfidence, 64); err == nil && val >= 0 && val <= 1 {
+			cfg.Tracker.MinConfidence = val
+		}
+	}
+
+	if detector := os.Getenv("ACTIONSUM_DETECTOR"); detector != "" {
+		cfg.Tracker.Detector = detector
+	}
+
+	if scenario := os.Getenv("ACTIONSUM_SCENARIO"); scenario != "" {
+		cfg.Tracker.Scenario = scenario
+	}
+
+	if classifier := os.Getenv("ACTIONSUM_CLASSIFIER"); classifier != "" {
+		cfg.Tracker.Classifier = classifier
+	}
+
+	if tagDuration := os.Getenv("ACTIONSUM_TAG_DURATION"); tagDuration != "" {
+		if d, err := time.ParseDuration(tagDuration); err == nil && d > 0 {
+			cfg.Tags.DefaultDuration = d
+		}
+	}
+
+	if profileRules := os.Getenv("ACTIONSUM_PROFILE_RULES"); profileRules != "" {
+		cfg.Profiles.Rules = parseProfileRules(profileRules)
+	}
+
+	if workHours := os.Getenv("ACTIONSUM_WORK_HOURS"); workHours != "" {
+		if windows, err := schedule.ParseList(workHours); err == nil {
+			cfg.WorkHours.Windows = windows
+		}
+	}
+
+	if autoPause := os.Getenv("ACTIONSUM_WORK_HOURS_AUTOPAUSE"); autoPause != "" {
+		if val, err := strconv.ParseBool(autoPause); err == nil {
+			cfg.WorkHours.AutoPause = val
+		}
+	}
+
+	if budgetsOnly := os.Getenv("ACTIONSUM_WORK_HOURS_BUDGETS_ONLY"); budgetsOnly != "" {
+		if val, err := strconv.ParseBool(budgetsOnly); err == nil {
+			cfg.WorkHours.BudgetsOnly = val
+		}
+	}
+
+	if locations := os.Getenv("ACTIONSUM_LOCATIONS"); locations != "" {
+		cfg.Locations.Networks = parseLocations(locations)
+	}
+
+	if location := os.Getenv("ACTIONSUM_LOCATION_DEFAULT"); location != "" {
+		cfg.Locations.Default = strings.ToLower(strings.TrimSpace(location))
+	}
+
+	if budgets := os.Getenv("ACTIONSUM_BUDGETS"); budgets != "" {
+		cfg.Budgets.Limits = parseBudgets(budgets)
+	}
+
+	if categories := os.Getenv("ACTIONSUM_CATEGORIES"); categories != "" {
+		cfg.Budgets.Categories = parseCategories(categories)
+	}
+
+	if strict := os.Getenv("ACTIONSUM_BUDGET_STRICT"); strict != "" {
+		if val, err := strconv.ParseBool(strict); err == nil {
+			cfg.Budgets.Strict = val
+		}
+	}
+
+	if hook := os.Getenv("ACTIONSUM_BUDGET_HOOK"); hook != "" {
+		cfg.Budgets.Hook = hook
+	}
+
+	if repeat := os.Getenv("ACTIONSUM_BUDGET_REPEAT"); repeat != "" {
+		if d, err := time.ParseDuration(repeat); err == nil && d > 0 {
+			cfg.Budgets.RepeatInterval = d
+		}
+	}
+
+	if goal := os.Getenv("ACTIONSUM_DAILY_GOAL"); goal != "" {
+		if d, err := time.ParseDuration(goal); err == nil && d > 0 {
+			cfg.Budgets.DailyGoal = d
+		}
+	}
+
+	if holidayFile := os.Getenv("ACTIONSUM_HOLIDAYS_FILE"); holidayFile != "" {
+		cfg.Holidays.File = holidayFile
+	}
+
+	if calendar := os.Getenv("ACTIONSUM_HOLIDAYS_ICS"); calendar != "" {
+		cfg.Holidays.Calendar = strings.TrimSpace(calendar)
+	}
+
+	if increment := os.Getenv("ACTIONSUM_BILLING_INCREMENT"); increment != "" {
+		if d, err := time.ParseDuration(increment); err == nil && d >= 0 {
+			cfg.Billing.Increment = d
+		}
+	}
+
+	if rates := os.Getenv("ACTIONSUM_BILLING_RATES"); rates != "" {
+		cfg.Billing.Rates = parseRates(rates)
+	}
+
+	if currency := os.Getenv("ACTIONSUM_BILLING_CURRENCY"); currency != "" {
+		cfg.Billing.Currency = strings.TrimSpace(currency)
+	}
+
+	if desktop := os.Getenv("ACTIONSUM_NOTIFY_DESKTOP"); desktop != "" {
+		if val, err := strconv.ParseBool(desktop); err == nil {
+			cfg.Notify.Desktop = val
+		}
+	}
+
+	if webhook := os.Getenv("ACTIONSUM_NOTIFY_WEBHOOK"); webhook != "" {
+		cfg.Notify.WebhookURL = webhook
+	}
+
+	if smtpHost := os.Getenv("ACTIONSUM_SMTP_HOST"); smtpHost != "" {
+		cfg.Notify.SMTP.Host = smtpHost
+	}
+
+	if smtpPort := os.Getenv("ACTIONSUM_SMTP_PORT"); smtpPort != "" {
+		if port, err := strconv.Atoi(smtpPort); err == nil && port > 0 && port <= 65535 {
+			cfg.Notify.SMTP.Port = port
+		}
+	}
+
+	if smtpUser := os.Getenv("ACTIONSUM_SMTP_USER"); smtpUser != "" {
+		cfg.Notify.SMTP.Username = smtpUser
+	}
+
+	if smtpPassword := os.Getenv("ACTIONSUM_SMTP_PASSWORD"); smtpPassword != "" {
+		cfg.Notify.SMTP.Password = smtpPassword
+	}
+
+	if smtpFrom := os.Getenv("ACTIONSUM_SMTP_FROM"); smtpFrom != "" {
+		cfg.Notify.SMTP.From = smtpFrom
+	}
+
+	if smtpTo := os.Getenv("ACTIONSUM_SMTP_TO"); smtpTo != "" {
+		cfg.Notify.SMTP.To = nil
+		for _, addr := range strings.Split(smtpTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				cfg.Notify.SMTP.To = append(cfg.Notify.SMTP.To, addr)
+			}
+		}
+	}
+
+	if digest := os.Getenv("ACTIONSUM_WEEKLY_DIGEST"); digest != "" {
+		if val, err := strconv.ParseBool(digest); err == nil {
+			cfg.Notify.WeeklyDigest = val
+		}
+	}
+
+	if digestPDF := os.Getenv("ACTIONSUM_DIGEST_PDF"); digestPDF != "" {
+		if val, err := strconv.ParseBool(digestPDF); err == nil {
+			cfg.Notify.DigestPDF = val
+		}
+	}
+
+	if alerts := os.Getenv("ACTIONSUM_ALERTS"); alerts != "" {
+		cfg.Notify.Alerts = parseAlerts(alerts)
+	}
+
 	if webPort := os.Getenv("ACTIONSUM_WEB_PORT"); webPort != "" {
 		if port, err := strconv.Atoi(webPort); err == nil && port > 0 && port <= 65535 {
 			cfg.Web.Port = port
@@ -51,6 +521,177 @@ func LoadFromEnv(cfg *Config) {
 	}
 }
 
+// parseAppIntervals parses "firefox=5,slack=60" (seconds) into per-app poll
+// intervals keyed by lowercase app name. Malformed entries are skipped.
+func parseAppIntervals(value string) map[string]time.Duration {
+	intervals := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		app, secondsStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		app = strings.ToLower(strings.TrimSpace(app))
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsStr))
+		if app == "" || err != nil || seconds <= 0 {
+			continue
+		}
+		intervals[app] = time.Duration(seconds) * time.Second
+	}
+	return intervals
+}
+
+// parseCPUList parses a CPU list as the kernel prints them, such as
+// "0,2,4-7", into CPU numbers in ascending order.
+func parseCPUList(value string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		firstStr, lastStr, isRange := strings.Cut(entry, "-")
+		first, err := strconv.Atoi(firstStr)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(lastStr)
+		}
+		if err != nil || first < 0 || last < first || last >= 1024 {
+			return nil, fmt.Errorf("invalid CPU or range %q", entry)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			seen[cpu] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no CPUs listed")
+	}
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// parseBudgets parses "youtube=30m,social=1h30m" into daily limits keyed by
+// lowercase app or category name. Malformed entries are skipped.
+func parseBudgets(value string) map[string]time.Duration {
+	limits := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		name, limitStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		limit, err := time.ParseDuration(strings.TrimSpace(limitStr))
+		if name == "" || err != nil || limit <= 0 {
+			continue
+		}
+		limits[name] = limit
+	}
+	return limits
+}
+
+// parseRates parses "acme=95,internal=62.5" into hourly rates keyed by
+// lower-case project name. Malformed entries and negative rates are
+// skipped.
+func parseRates(value string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		name, rateStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if name == "" || err != nil || !(rate >= 0) || math.IsInf(rate, 1) {
+			continue
+		}
+		rates[name] = rate
+	}
+	return rates
+}
+
+// parseLocations parses "Office WiFi=office,HomeNet=home" into locations
+// keyed by network name, which keeps its case. Malformed entries are
+// skipped.
+func parseLocations(value string) map[string]string {
+	locations := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		ssid, location, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		ssid = strings.TrimSpace(ssid)
+		location = strings.ToLower(strings.TrimSpace(location))
+		if ssid == "" || location == "" {
+			continue
+		}
+		locations[ssid] = location
+	}
+	return locations
+}
+
+// parseAlerts parses "active>10h,outage>30m" into alert rules, keeping their
+// order. Malformed entries and unknown kinds are skipped.
+func parseAlerts(value string) []AlertRule {
+	var rules []AlertRule
+	for _, entry := range strings.Split(value, ",") {
+		kind, thresholdStr, ok := strings.Cut(entry, ">")
+		if !ok {
+			continue
+		}
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		threshold, err := time.ParseDuration(strings.TrimSpace(thresholdStr))
+		if (kind != AlertActive && kind != AlertOutage) || err != nil || threshold <= 0 {
+			continue
+		}
+		rules = append(rules, AlertRule{Kind: kind, Threshold: threshold})
+	}
+	return rules
+}
+
+// parseCategories parses "social=slack,discord;video=mpv,vlc" into app lists
+// keyed by lowercase category name. Malformed entries are skipped.
+func parseCategories(value string) map[string][]string {
+	categories := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		name, appsStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		for _, app := range strings.Split(appsStr, ",") {
+			if app = strings.ToLower(strings.TrimSpace(app)); app != "" {
+				categories[name] = append(categories[name], app)
+			}
+		}
+	}
+	return categories
+}
+
+// parseProfileRules parses "work=mon-fri 09:00-17:00;personal=sat,sun 10:00-22:00"
+// into profile rules, keeping their order. Malformed entries are skipped.
+func parseProfileRules(value string) []ProfileRule {
+	var rules []ProfileRule
+	for _, entry := range strings.Split(value, ";") {
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		window, err := schedule.Parse(spec)
+		if name == "" || err != nil {
+			continue
+		}
+		rules = append(rules, ProfileRule{Profile: name, Window: window})
+	}
+	return rules
+}
+
 func New() *Config {
 	cfg := Default()
 	LoadFromEnv(cfg)
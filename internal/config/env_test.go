@@ -0,0 +1,73 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBudgets(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]time.Duration
+	}{
+		{"youtube=30m,Social=1h", map[string]time.Duration{"youtube": 30 * time.Minute, "social": time.Hour}},
+		{" youtube = 1h30m , ", map[string]time.Duration{"youtube": 90 * time.Minute}},
+		{"youtube,=1h,chat=soon,games=0s,news=-5m", map[string]time.Duration{}},
+		{"youtube=30m,youtube=1h", map[string]time.Duration{"youtube": time.Hour}},
+		{"", map[string]time.Duration{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseBudgets(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBudgets(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string][]string
+	}{
+		{"social=slack,discord;video=mpv,vlc", map[string][]string{"social": {"slack", "discord"}, "video": {"mpv", "vlc"}}},
+		{" Social = Slack , , Discord ;", map[string][]string{"social": {"slack", "discord"}}},
+		{"social;=slack;empty=", map[string][]string{}},
+		{"social=slack;social=discord", map[string][]string{"social": {"slack", "discord"}}},
+		{"", map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseCategories(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCategories(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvIdleGraceAndMicroBreak(t *testing.T) {
+	tests := []struct {
+		grace, microBreak string
+		wantGrace         time.Duration
+		wantMicroBreak    time.Duration
+	}{
+		{"2m", "90s", 2 * time.Minute, 90 * time.Second},
+		{"0s", "0s", 0, 0},
+		{"120", "120", time.Minute, 30 * time.Second},
+		{"-1m", "-1m", time.Minute, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grace, func(t *testing.T) {
+			t.Setenv("ACTIONSUM_IDLE_GRACE", tt.grace)
+			t.Setenv("ACTIONSUM_MICRO_BREAK", tt.microBreak)
+			cfg := Default()
+			cfg.Tracker.IdleGrace = time.Minute
+			cfg.Report.MicroBreak = 30 * time.Second
+			LoadFromEnv(cfg)
+			if cfg.Tracker.IdleGrace != tt.wantGrace || cfg.Report.MicroBreak != tt.wantMicroBreak {
+				t.Errorf("IdleGrace, MicroBreak = %v, %v, want %v, %v", cfg.Tracker.IdleGrace, cfg.Report.MicroBreak, tt.wantGrace, tt.wantMicroBreak)
+			}
+		})
+	}
+}
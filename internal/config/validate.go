@@ -0,0 +1,336 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/schedule"
+)
+
+// envChecks says why LoadFromEnv would ignore a variable's value, mirroring
+// its parsing. Variables taken verbatim accept anything.
+var envChecks = map[string]func(string) error{
+	"ACTIONSUM_DB_PATH":                 anyValue,
+	"ACTIONSUM_DB_SIZE_WARN":            intRange(0, -1, "a number of MB"),
+	"ACTIONSUM_WAL_CHECKPOINT":          intRange(1, -1, "a number of MB"),
+	"ACTIONSUM_DB_LOG_LEVEL":            oneOf("silent", "error", "warn", "info"),
+	"ACTIONSUM_DB_SLOW_QUERY":           durationMin(0),
+	"ACTIONSUM_DB_AUTO_RECOVER":         boolValue,
+	"ACTIONSUM_POLL_INTERVAL":           intRange(10, 300, "seconds"),
+	"ACTIONSUM_APP_POLL_INTERVALS":      entries(",", func(v string) int { return len(parseAppIntervals(v)) }, "app=seconds"),
+	"ACTIONSUM_IDLE_THRESHOLD":          intRange(1, -1, "seconds"),
+	"ACTIONSUM_IDLE_GRACE":              durationMin(0),
+	"ACTIONSUM_CLOCK_JUMP_THRESHOLD":    intRange(1, -1, "seconds"),
+	"ACTIONSUM_PID_FILE":                anyValue,
+	"ACTIONSUM_STATS_FILE":              anyValue,
+	"ACTIONSUM_STARTUP_TIMEOUT":         intRange(0, -1, "seconds"),
+	"ACTIONSUM_NICE":                    intRange(-20, 19, "a nice level"),
+	"ACTIONSUM_CPU_AFFINITY":            cpuList,
+	"ACTIONSUM_POLL_BUDGET":             durationMin(0),
+	"ACTIONSUM_EXCLUDE_PROCESS_GUESSES": boolValue,
+	"ACTIONSUM_EXCLUDE_IDLE":            boolValue,
+	"ACTIONSUM_TIMEZONE":                anyValue,
+	"ACTIONSUM_LOCALE":                  anyValue,
+	"ACTIONSUM_MICRO_BREAK":             durationMin(0),
+	"ACTIONSUM_REPORT_SNAPSHOT":         snapshotInterval,
+	"ACTIONSUM_WEB_HOST":                anyValue,
+	"ACTIONSUM_WEB_PORT":                intRange(1, 65535, "a port"),
+	"ACTIONSUM_WEB_REQUIRE_TOKEN":       boolValue,
+	"ACTIONSUM_WEB_DISABLED":            boolValue,
+	"ACTIONSUM_WEB_INSECURE":            boolValue,
+	"ACTIONSUM_WEB_MULTI_USER":          boolValue,
+	"ACTIONSUM_WEB_TEAM_REPORTS":        boolValue,
+	"ACTIONSUM_WEB_TEAM_MIN_MEMBERS":    intRange(1, -1, "a number of users"),
+	"ACTIONSUM_OIDC_ISSUER":             anyValue,
+	"ACTIONSUM_OIDC_CLIENT_ID":          anyValue,
+	"ACTIONSUM_OIDC_CLIENT_SECRET":      anyValue,
+	"ACTIONSUM_OIDC_REDIRECT_URL":       anyValue,
+	"ACTIONSUM_OIDC_USER_CLAIM":         anyValue,
+	"ACTIONSUM_OIDC_ALLOWED_USERS":      anyValue,
+	"ACTIONSUM_OIDC_SESSION":            durationMin(time.Nanosecond),
+	"ACTIONSUM_MDNS":                    boolValue,
+	"ACTIONSUM_TRANSLATIONS_DIR":        anyValue,
+	"ACTIONSUM_APP_NAMES_FILE":          anyValue,
+	"ACTIONSUM_DESKTOP_ENTRIES":         boolValue,
+	"ACTIONSUM_PROFILE_FILE":            anyValue,
+	"ACTIONSUM_PROFILE_RULES":           entries(";", func(v string) int { return len(parseProfileRules(v)) }, "name=days hh:mm-hh:mm"),
+	"ACTIONSUM_TAG_FILE":                anyValue,
+	"ACTIONSUM_TAG_DURATION":            durationMin(time.Nanosecond),
+	"ACTIONSUM_PAUSE_FILE":              anyValue,
+	"ACTIONSUM_BROWSER_TAB_FILE":        anyValue,
+	"ACTIONSUM_REMOTE_TERMINAL_FILE":    anyValue,
+	"ACTIONSUM_EDITOR_FILE":             anyValue,
+	"ACTIONSUM_SHELL_STATE_DIR":         anyValue,
+	"ACTIONSUM_AWAY_PROMPT":             durationMin(0),
+	"ACTIONSUM_AWAY_REASONS":            anyValue,
+	"ACTIONSUM_AWAY_FILE":               anyValue,
+	"ACTIONSUM_UNLOCK_SUMMARY":          durationMin(0),
+	"ACTIONSUM_FOCUS_EVENTS":            boolValue,
+	"ACTIONSUM_TERMINAL_COMMANDS":       boolValue,
+	"ACTIONSUM_SHELL_COMMANDS":          boolValue,
+	"ACTIONSUM_SHELL_REDACT":            pattern,
+	"ACTIONSUM_MEDIA_PLAYERS":           boolValue,
+	"ACTIONSUM_AUDIO_ACTIVE":            boolValue,
+	"ACTIONSUM_MEETINGS":                boolValue,
+	"ACTIONSUM_VPN_INTERFACES":          anyValue,
+	"ACTIONSUM_PROCESS_FALLBACK":        boolValue,
+	"ACTIONSUM_MIN_CONFIDENCE":          confidence,
+	"ACTIONSUM_DETECTOR":                anyValue,
+	"ACTIONSUM_SCENARIO":                anyValue,
+	"ACTIONSUM_CLASSIFIER":              anyValue,
+	"ACTIONSUM_WORK_HOURS":              workHours,
+	"ACTIONSUM_WORK_HOURS_AUTOPAUSE":    boolValue,
+	"ACTIONSUM_WORK_HOURS_BUDGETS_ONLY": boolValue,
+	"ACTIONSUM_LOCATIONS":               entries(",", func(v string) int { return len(parseLocations(v)) }, "network=location"),
+	"ACTIONSUM_LOCATION_DEFAULT":        anyValue,
+	"ACTIONSUM_BUDGETS":                 entries(",", func(v string) int { return len(parseBudgets(v)) }, "name=duration"),
+	"ACTIONSUM_CATEGORIES":              entries(";", func(v string) int { return len(parseCategories(v)) }, "category=app,app"),
+	"ACTIONSUM_BUDGET_STRICT":           boolValue,
+	"ACTIONSUM_BUDGET_HOOK":             anyValue,
+	"ACTIONSUM_BUDGET_REPEAT":           durationMin(time.Nanosecond),
+	"ACTIONSUM_DAILY_GOAL":              durationMin(time.Nanosecond),
+	"ACTIONSUM_HOLIDAYS_FILE":           anyValue,
+	"ACTIONSUM_HOLIDAYS_ICS":            anyValue,
+	"ACTIONSUM_BILLING_INCREMENT":       durationMin(0),
+	"ACTIONSUM_BILLING_RATES":           entries(",", func(v string) int { return len(parseRates(v)) }, "project=hourly rate"),
+	"ACTIONSUM_BILLING_CURRENCY":        anyValue,
+	"ACTIONSUM_NOTIFY_DESKTOP":          boolValue,
+	"ACTIONSUM_NOTIFY_WEBHOOK":          anyValue,
+	"ACTIONSUM_SMTP_HOST":               anyValue,
+	"ACTIONSUM_SMTP_PORT":               intRange(1, 65535, "a port"),
+	"ACTIONSUM_SMTP_USER":               anyValue,
+	"ACTIONSUM_SMTP_PASSWORD":           anyValue,
+	"ACTIONSUM_SMTP_FROM":               anyValue,
+	"ACTIONSUM_SMTP_TO":                 anyValue,
+	"ACTIONSUM_WEEKLY_DIGEST":           boolValue,
+	"ACTIONSUM_DIGEST_PDF":              boolValue,
+	"ACTIONSUM_ALERTS":                  entries(",", func(v string) int { return len(parseAlerts(v)) }, "active>duration or outage>duration"),
+	"ACTIONSUM_DAEMON_CHILD":            anyValue,
+}
+
+func anyValue(string) error { return nil }
+
+func boolValue(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("want true or false")
+	}
+	return nil
+}
+
+// intRange accepts whole numbers from min to max; a negative max means no
+// upper bound.
+func intRange(min, max int, unit string) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			return fmt.Errorf("want %s as a whole number", unit)
+		case n < min:
+			return fmt.Errorf("want at least %d", min)
+		case max >= 0 && n > max:
+			return fmt.Errorf("want at most %d", max)
+		}
+		return nil
+	}
+}
+
+func durationMin(min time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		switch {
+		case err != nil:
+			return fmt.Errorf("want a duration such as 30s, 5m or 1h30m")
+		case d < min && min > 0:
+			return fmt.Errorf("want a duration above zero")
+		case d < min:
+			return fmt.Errorf("want a duration of at least %v", min)
+		}
+		return nil
+	}
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if strings.EqualFold(value, v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("want one of %s", strings.Join(values, ", "))
+	}
+}
+
+// entries reports separated entries that parse drops as malformed.
+func entries(sep string, parse func(string) int, form string) func(string) error {
+	return func(value string) error {
+		var want int
+		for _, entry := range strings.Split(value, sep) {
+			if strings.TrimSpace(entry) != "" {
+				want++
+			}
+		}
+		if got := parse(value); got < want {
+			return fmt.Errorf("%d of %d entries are malformed, want %s", want-got, want, form)
+		}
+		return nil
+	}
+}
+
+func snapshotInterval(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || (d != 0 && d < time.Minute) {
+		return fmt.Errorf("want 0 or a duration of at least 1m")
+	}
+	return nil
+}
+
+func cpuList(value string) error {
+	if _, err := parseCPUList(value); err != nil {
+		return fmt.Errorf("want CPU numbers and ranges such as 0,2-3: %v", err)
+	}
+	return nil
+}
+
+func pattern(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("want a regular expression: %v", err)
+	}
+	return nil
+}
+
+func confidence(value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > 1 {
+		return fmt.Errorf("want a number from 0 to 1")
+	}
+	return nil
+}
+
+func workHours(value string) error {
+	_, err := schedule.ParseList(value)
+	return err
+}
+
+// CheckEnv reports ACTIONSUM_ variables LoadFromEnv ignores, either because
+// their value doesn't parse or because no setting has that name.
+func CheckEnv() []error {
+	var errs []error
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, "ACTIONSUM_") {
+			continue
+		}
+		check, ok := envChecks[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s is not a known setting and is ignored", name))
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		if err := check(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q is ignored: %v", name, value, err))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// ValidateAll returns every problem with the configuration rather than the
+// first: ignored environment variables, Validate's checks, files that cannot
+// be written, a time zone that doesn't load and, when checkPort is set, a
+// web port already in use.
+func (c *Config) ValidateAll(checkPort bool) []error {
+	errs := CheckEnv()
+	errs = append(errs, c.validationErrors()...)
+
+	files := []struct{ name, path string }{
+		{"database", c.Database.Path},
+		{"PID file", c.Daemon.PIDFile},
+		{"stats file", c.Daemon.StatsFile},
+		{"pause file", c.Tracker.PauseFile},
+		{"browser tab file", c.Tracker.BrowserTabFile},
+		{"remote terminal file", c.Tracker.RemoteTerminalFile},
+		{"editor file", c.Tracker.EditorFile},
+		{"away file", c.Tracker.AwayFile},
+		{"profile file", c.Profiles.StateFile},
+		{"tag file", c.Tags.StateFile},
+		{"holiday file", c.Holidays.File},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if err := checkWritable(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", file.name, file.path, err))
+		}
+	}
+
+	if c.Tracker.Detector == "fake" && c.Tracker.Scenario != "" {
+		if _, err := os.Stat(c.Tracker.Scenario); err != nil {
+			errs = append(errs, fmt.Errorf("scenario file: %w", err))
+		}
+	}
+
+	if c.Report.TimeZone != "" {
+		if _, err := time.LoadLocation(c.Report.TimeZone); err != nil {
+			errs = append(errs, fmt.Errorf("time zone %q cannot be loaded: %w", c.Report.TimeZone, err))
+		}
+	}
+
+	if checkPort && c.Web.Port >= 1 && c.Web.Port <= 65535 && c.Web.Host != "" {
+		addr := net.JoinHostPort(c.Web.Host, strconv.Itoa(c.Web.Port))
+		if listener, err := net.Listen("tcp", addr); err != nil {
+			errs = append(errs, fmt.Errorf("web server cannot listen on %s: %w", addr, err))
+		} else {
+			listener.Close()
+		}
+	}
+
+	return errs
+}
+
+// checkWritable reports whether path can be written: the file itself if it
+// exists, or else the nearest existing directory it would be created under.
+func checkWritable(path string) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return errors.New("is a directory")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("not writable: %w", err)
+		}
+		return f.Close()
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing parent directory")
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".actionsum-check-*")
+	if err != nil {
+		return fmt.Errorf("cannot create files in %s: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
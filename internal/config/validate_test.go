@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEnv(t *testing.T) {
+	for _, entry := range os.Environ() {
+		if name, _, _ := strings.Cut(entry, "="); strings.HasPrefix(name, "ACTIONSUM_") {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+	t.Setenv("ACTIONSUM_POLL_INTERVAL", "30")
+	t.Setenv("ACTIONSUM_EXCLUDE_IDLE", "maybe")
+	t.Setenv("ACTIONSUM_BUDGETS", "youtube=30m,social")
+	t.Setenv("ACTIONSUM_IDLE_TRESHOLD", "60")
+	t.Setenv("ACTIONSUM_IDLE_GRACE", "120")
+	t.Setenv("ACTIONSUM_MICRO_BREAK", "2m")
+
+	var got []string
+	for _, err := range CheckEnv() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`ACTIONSUM_BUDGETS="youtube=30m,social" is ignored: 1 of 2 entries are malformed, want name=duration`,
+		`ACTIONSUM_EXCLUDE_IDLE="maybe" is ignored: want true or false`,
+		`ACTIONSUM_IDLE_GRACE="120" is ignored: want a duration such as 30s, 5m or 1h30m`,
+		`ACTIONSUM_IDLE_TRESHOLD is not a known setting and is ignored`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckEnv() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateAllReportsEveryProblem(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Default()
+	cfg.Database.Path = filepath.Join(dir, "data", "actionsum.db")
+	cfg.Daemon.PIDFile = filepath.Join(dir, "actionsum.pid")
+	cfg.Daemon.StatsFile = filepath.Join(dir, "stats.json")
+	cfg.Tracker.PauseFile = filepath.Join(dir, "pause")
+	cfg.Tracker.BrowserTabFile = filepath.Join(dir, "tab.json")
+	cfg.Tracker.RemoteTerminalFile = filepath.Join(dir, "terminal.json")
+	cfg.Tracker.EditorFile = filepath.Join(dir, "editor.json")
+	cfg.Tracker.AwayFile = filepath.Join(dir, "away")
+	cfg.Profiles.StateFile = filepath.Join(dir, "profile")
+	cfg.Tags.StateFile = filepath.Join(dir, "tag")
+	if errs := cfg.ValidateAll(false); len(errs) != 0 {
+		t.Fatalf("ValidateAll() = %v, want no problems", errs)
+	}
+
+	blocker := filepath.Join(dir, "file")
+	os.WriteFile(blocker, nil, 0644)
+	cfg.Database.Path = filepath.Join(blocker, "actionsum.db")
+	cfg.Tracker.MinConfidence = 2
+	cfg.Report.TimeZone = "Nowhere/Special"
+	if errs := cfg.ValidateAll(false); len(errs) != 3 {
+		t.Errorf("ValidateAll() = %v, want 3 problems", errs)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "confidence") {
+		t.Errorf("Validate() = %v, want the confidence problem", err)
+	}
+}
+
+func TestValidateWebExposure(t *testing.T) {
+	tests := []struct {
+		host         string
+		requireToken bool
+		insecure     bool
+		wantErr      bool
+	}{
+		{"localhost", false, false, false},
+		{"127.0.0.1", false, false, false},
+		{"::1", false, false, false},
+		{"0.0.0.0", false, false, true},
+		{"192.168.1.20", false, false, true},
+		{"0.0.0.0", true, false, false},
+		{"0.0.0.0", false, true, false},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Web.Host = tt.host
+		cfg.Web.RequireToken = tt.requireToken
+		cfg.Web.Insecure = tt.insecure
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with host %s, token %v, insecure %v = %v, want error %v",
+				tt.host, tt.requireToken, tt.insecure, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateOIDC(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		wantErr bool
+	}{
+		{"no allowed users", nil, true},
+		{"a user", []string{"alice"}, false},
+		{"a domain", []string{"@example.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Web.MultiUser = true
+			cfg.Web.RequireToken = true
+			cfg.Web.OIDC.Issuer = "https://id.example.com"
+			cfg.Web.OIDC.ClientID = "actionsum"
+			cfg.Web.OIDC.RedirectURL = "https://actionsum.example.com/auth/callback"
+			cfg.Web.OIDC.AllowedUsers = tt.allowed
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr || (err != nil && !strings.Contains(err.Error(), "ACTIONSUM_OIDC_ALLOWED_USERS")) {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("4-5, 0,2,5")
+	if err != nil || fmt.Sprint(cpus) != "[0 2 4 5]" {
+		t.Errorf("parseCPUList() = %v, %v; want [0 2 4 5]", cpus, err)
+	}
+	for _, value := range []string{"", "a", "3-1", "-1", "0,x"} {
+		if _, err := parseCPUList(value); err == nil {
+			t.Errorf("parseCPUList(%q) accepted an invalid list", value)
+		}
+	}
+}
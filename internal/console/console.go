@@ -0,0 +1,85 @@
+// Package console formats command-line output: optional colors, aligned
+// tables and progress bars. Colors and progress bars are only drawn on a
+// terminal, and never with NO_COLOR set or --plain given, so the output of
+// piped and scripted commands stays plain text.
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	dim    = "\x1b[2m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+)
+
+// Console writes command output to Out and progress to Err.
+type Console struct {
+	Out io.Writer
+	Err io.Writer
+
+	color    bool
+	progress bool
+}
+
+// New returns a console on standard output and error. plain turns off
+// colors and progress bars, as does NO_COLOR for colors.
+func New(plain bool) *Console {
+	return &Console{
+		Out:      os.Stdout,
+		Err:      os.Stderr,
+		color:    !plain && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout),
+		progress: !plain && isTerminal(os.Stderr),
+	}
+}
+
+// Plain returns a console writing to out without colors or progress bars.
+func Plain(out io.Writer) *Console {
+	return &Console{Out: out, Err: io.Discard}
+}
+
+// isTerminal reports whether f is a terminal other than a dumb one.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *Console) style(code, s string) string {
+	if !c.color || s == "" {
+		return s
+	}
+	return code + s + reset
+}
+
+func (c *Console) Bold(s string) string   { return c.style(bold, s) }
+func (c *Console) Dim(s string) string    { return c.style(dim, s) }
+func (c *Console) Red(s string) string    { return c.style(red, s) }
+func (c *Console) Green(s string) string  { return c.style(green, s) }
+func (c *Console) Yellow(s string) string { return c.style(yellow, s) }
+
+func (c *Console) Printf(format string, args ...any) {
+	fmt.Fprintf(c.Out, format, args...)
+}
+
+func (c *Console) Println(args ...any) {
+	fmt.Fprintln(c.Out, args...)
+}
+
+// Success prints a line reporting a completed change, in green.
+func (c *Console) Success(format string, args ...any) {
+	fmt.Fprintln(c.Out, c.Green(fmt.Sprintf(format, args...)))
+}
+
+// Warn prints a line starting with "Warning:", in yellow.
+func (c *Console) Warn(format string, args ...any) {
+	fmt.Fprintln(c.Out, c.Yellow("Warning: "+fmt.Sprintf(format, args...)))
+}
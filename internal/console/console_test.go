@@ -0,0 +1,65 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	var out bytes.Buffer
+	c := Plain(&out)
+	table := NewTable("NAME", "TIME", "NOTE").AlignRight(1)
+	table.Row("code", "1h20m", "")
+	table.Row("firefox", "5m", "café")
+	table.Write(c)
+
+	want := "NAME      TIME  NOTE\n" +
+		"code     1h20m\n" +
+		"firefox     5m  café\n"
+	if out.String() != want {
+		t.Errorf("table =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestTableColored(t *testing.T) {
+	var out bytes.Buffer
+	c := &Console{Out: &out, color: true}
+	table := NewTable().Indent("  ")
+	table.Row(c.Red("fail"), "disk")
+	table.Row("ok", "database")
+	table.Write(c)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if lines[0] != "  "+red+"fail"+reset+"  disk" || lines[1] != "  ok    database" {
+		t.Errorf("colored table = %q", lines)
+	}
+}
+
+func TestStyleWithoutColor(t *testing.T) {
+	c := Plain(&bytes.Buffer{})
+	if got := c.Bold("title"); got != "title" {
+		t.Errorf("Bold() without color = %q", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if New(false).color {
+		t.Error("New() enabled colors with NO_COLOR set")
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	p := Plain(&bytes.Buffer{}).Progress("Exporting", 200)
+	p.Add(50)
+	if got, want := p.line(), "Exporting [#######.......................]  25% 50/200"; got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+	p.Set(300, 200)
+	if got := p.line(); !strings.HasSuffix(got, "100% 200/200") {
+		t.Errorf("line() past the total = %q", got)
+	}
+	p.Set(7, 0)
+	if got := p.line(); got != "Exporting 7" {
+		t.Errorf("line() without a total = %q", got)
+	}
+}
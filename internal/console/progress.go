@@ -0,0 +1,75 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	progressWidth    = 30
+	progressInterval = 100 * time.Millisecond
+)
+
+// Progress draws a progress bar on c.Err, redrawn in place at most every
+// progressInterval. On a console without progress bars it draws nothing.
+type Progress struct {
+	c     *Console
+	label string
+	total int64
+	done  int64
+
+	drawn    time.Time
+	lastLine int
+}
+
+// Progress starts a progress bar for total steps. With total unknown, or
+// zero, only the steps done so far are shown.
+func (c *Console) Progress(label string, total int64) *Progress {
+	return &Progress{c: c, label: label, total: total}
+}
+
+// Set records done steps out of total, which may have changed.
+func (p *Progress) Set(done, total int64) {
+	p.done, p.total = done, total
+	if !p.c.progress || time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.draw()
+}
+
+// Add records n more steps done.
+func (p *Progress) Add(n int64) {
+	p.Set(p.done+n, p.total)
+}
+
+// Done clears the bar, leaving the line for the command's summary.
+func (p *Progress) Done() {
+	if !p.c.progress || p.drawn.IsZero() {
+		return
+	}
+	fmt.Fprintf(p.c.Err, "\r%s\r", strings.Repeat(" ", p.lastLine))
+}
+
+func (p *Progress) draw() {
+	p.drawn = time.Now()
+	line := p.line()
+	// Pad over the end of a longer previous line.
+	pad := ""
+	if n := p.lastLine - len(line); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+	p.lastLine = len(line)
+	fmt.Fprintf(p.c.Err, "\r%s%s", line, pad)
+}
+
+func (p *Progress) line() string {
+	if p.total <= 0 {
+		return fmt.Sprintf("%s %d", p.label, p.done)
+	}
+	done := min(p.done, p.total)
+	filled := int(done * progressWidth / p.total)
+	return fmt.Sprintf("%s [%s%s] %3d%% %d/%d", p.label,
+		strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled),
+		done*100/p.total, done, p.total)
+}
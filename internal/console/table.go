@@ -0,0 +1,107 @@
+package console
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Table lines up rows of cells in columns. Cells may be styled by the
+// console; only their visible text counts towards the column widths.
+type Table struct {
+	header []string
+	rows   [][]string
+	right  map[int]bool
+	indent string
+}
+
+// NewTable starts a table, with a header row unless header is empty.
+func NewTable(header ...string) *Table {
+	return &Table{header: header, right: map[int]bool{}}
+}
+
+// AlignRight right-aligns the given columns, as for numbers.
+func (t *Table) AlignRight(columns ...int) *Table {
+	for _, column := range columns {
+		t.right[column] = true
+	}
+	return t
+}
+
+// Indent prefixes every line with indent.
+func (t *Table) Indent(indent string) *Table {
+	t.indent = indent
+	return t
+}
+
+func (t *Table) Row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Write prints the table to c.Out, with a bold header. The last column is
+// not padded, so lines carry no trailing spaces.
+func (t *Table) Write(c *Console) {
+	var widths []int
+	measure := func(cells []string) {
+		for i, cell := range cells {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := visibleWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	measure(t.header)
+	for _, row := range t.rows {
+		measure(row)
+	}
+
+	line := func(cells []string, style func(string) string) {
+		var b strings.Builder
+		b.WriteString(t.indent)
+		for i, cell := range cells {
+			pad := strings.Repeat(" ", widths[i]-visibleWidth(cell))
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			switch {
+			case t.right[i]:
+				b.WriteString(pad + style(cell))
+			case i < len(cells)-1:
+				b.WriteString(style(cell) + pad)
+			default:
+				b.WriteString(style(cell))
+			}
+		}
+		c.Println(strings.TrimRight(b.String(), " "))
+	}
+	if len(t.header) > 0 {
+		line(t.header, c.Bold)
+	}
+	for _, row := range t.rows {
+		line(row, func(s string) string { return s })
+	}
+}
+
+// visibleWidth counts the characters of s, skipping ANSI escape sequences.
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			end := strings.IndexByte(s[i:], 'm')
+			if end < 0 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width++
+	}
+	return width
+}
@@ -0,0 +1,16 @@
+//go:build unix && !linux
+
+package daemon
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setNice(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice)
+}
+
+func setCPUAffinity(cpus []int) error {
+	return errors.New("not supported on this system")
+}
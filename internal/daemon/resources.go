@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/tracker"
+)
+
+// ApplyResourceLimits sets the daemon's niceness and CPU affinity as
+// configured. Tools the daemon runs afterwards inherit both.
+func ApplyResourceLimits(cfg *config.Config) error {
+	if cfg.Daemon.Nice != 0 {
+		if err := setNice(cfg.Daemon.Nice); err != nil {
+			return fmt.Errorf("failed to set nice level %d: %w", cfg.Daemon.Nice, err)
+		}
+	}
+	if len(cfg.Daemon.CPUAffinity) > 0 {
+		if err := setCPUAffinity(cfg.Daemon.CPUAffinity); err != nil {
+			return fmt.Errorf("failed to set CPU affinity %s: %w", cfg.CPUAffinityString(), err)
+		}
+	}
+	return nil
+}
+
+// Overhead is what the daemon costs the machine it runs on.
+type Overhead struct {
+	// CPUSeconds is the processor time used since the daemon started, and
+	// CPUPercent that as a share of one CPU over the time it has run.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	CPUPercent float64 `json:"cpu_percent"`
+
+	// MaxRSSBytes is the most memory the daemon has held, where known.
+	MaxRSSBytes int64 `json:"max_rss_bytes,omitempty"`
+
+	Polls         int64   `json:"polls"`
+	SkippedPolls  int64   `json:"skipped_polls"`
+	AvgPollMillis float64 `json:"avg_poll_ms"`
+	MaxPollMillis float64 `json:"max_poll_ms"`
+}
+
+// measureOverhead returns the daemon's overhead since startedAt, with the
+// tracker's polls when known.
+func measureOverhead(startedAt time.Time, polls *tracker.Polls) *Overhead {
+	o := &Overhead{}
+	if cpu, maxRSS, ok := resourceUsage(); ok {
+		o.CPUSeconds = cpu.Seconds()
+		if elapsed := time.Since(startedAt); elapsed > 0 {
+			o.CPUPercent = float64(cpu) / float64(elapsed) * 100
+		}
+		o.MaxRSSBytes = maxRSS
+	}
+	if polls != nil {
+		stats := polls.Stats()
+		o.Polls = stats.Polls
+		o.SkippedPolls = stats.Skipped
+		o.AvgPollMillis = float64(stats.Average()) / float64(time.Millisecond)
+		o.MaxPollMillis = float64(stats.Max) / float64(time.Millisecond)
+	}
+	return o
+}
+
+// String describes o, e.g. "0.2% CPU (41.3s), 38.0 MB, polls 4ms on average
+// and at most 120ms, 2 skipped".
+func (o *Overhead) String() string {
+	s := fmt.Sprintf("%.1f%% CPU (%.1fs)", o.CPUPercent, o.CPUSeconds)
+	if o.MaxRSSBytes > 0 {
+		s += fmt.Sprintf(", %.1f MB", float64(o.MaxRSSBytes)/(1<<20))
+	}
+	if o.Polls > 0 {
+		s += fmt.Sprintf(", polls %.0fms on average and at most %.0fms", o.AvgPollMillis, o.MaxPollMillis)
+	}
+	if o.SkippedPolls > 0 {
+		s += fmt.Sprintf(", %d skipped", o.SkippedPolls)
+	}
+	return s
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package daemon
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// resourceUsage returns the processor time the daemon has used and the most
+// memory it has held.
+func resourceUsage() (time.Duration, int64, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, 0, false
+	}
+	cpu := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+	maxRSS := int64(usage.Maxrss)
+	// Linux counts kilobytes, macOS and the BSDs bytes.
+	if runtime.GOOS != "darwin" {
+		maxRSS *= 1024
+	}
+	return cpu, maxRSS, true
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package daemon
+
+import (
+	"errors"
+	"time"
+)
+
+func setNice(nice int) error {
+	return errors.New("not supported on Windows")
+}
+
+func setCPUAffinity(cpus []int) error {
+	return errors.New("not supported on Windows")
+}
+
+func resourceUsage() (time.Duration, int64, bool) {
+	return 0, 0, false
+}
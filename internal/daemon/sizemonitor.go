@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+const sizeCheckInterval = 10 * time.Minute
+
+// SizeMonitor checkpoints an oversized WAL and warns once each time the
+// database grows past the configured size.
+type SizeMonitor struct {
+	db       *database.DB
+	cfg      *config.Config
+	notifier notify.Notifier
+	warned   bool
+}
+
+// NewSizeMonitor creates a monitor; notifier may be nil to only log.
+func NewSizeMonitor(db *database.DB, cfg *config.Config, notifier notify.Notifier) *SizeMonitor {
+	return &SizeMonitor{db: db, cfg: cfg, notifier: notifier}
+}
+
+func (m *SizeMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(sizeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *SizeMonitor) check() {
+	size, err := database.FileSizes(m.db.Path())
+	if err != nil {
+		log.Printf("Database size check failed: %v", err)
+		return
+	}
+
+	if size.WALBytes > m.cfg.Database.WALCheckpointSize {
+		if err := m.db.Checkpoint(); err != nil {
+			log.Printf("%v", err)
+		} else {
+			log.Printf("Checkpointed %s write-ahead log", utils.FormatBytes(size.WALBytes))
+			size.WALBytes = 0
+		}
+	}
+
+	warning := size.Warning(m.cfg.Database.SizeWarning)
+	if warning == "" {
+		m.warned = false
+		return
+	}
+	if m.warned {
+		return
+	}
+	m.warned = true
+
+	log.Printf("Warning: %s", warning)
+	if m.notifier == nil {
+		return
+	}
+	if err := m.notifier.Notify(notify.Message{
+		Title:   "actionsum database is large",
+		Body:    warning,
+		Urgency: "normal",
+		Data:    size,
+	}); err != nil {
+		log.Printf("Failed to send size warning: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/detector"
+	"github.com/actionsum/actionsum/pkg/integrations/hybrid"
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const (
+	displayPollInterval = time.Second
+
+	initialRetryDelay = time.Second
+	maxRetryDelay     = 15 * time.Second
+)
+
+// windowBacked is implemented by detectors that can fall back to
+// process-based detection when no display-server detector is available.
+type windowBacked interface {
+	HasWindowDetector() bool
+}
+
+// StartDetector waits for the display server and creates the window detector,
+// retrying with exponential backoff until timeout passes. Daemons launched at
+// login can start before the compositor or X server accepts connections.
+// When the deadline passes with only the process-based fallback available,
+// that detector is returned rather than failing outright.
+func StartDetector(timeout time.Duration, opts ...hybrid.Option) (window.Detector, error) {
+	deadline := time.Now().Add(timeout)
+
+	if err := waitForDisplay(deadline); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		det, err := detector.New(opts...)
+		if err == nil && hasWindowDetector(det) {
+			return det, nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			if err != nil {
+				return nil, fmt.Errorf("window detector unavailable after %d attempts: %w", attempt, err)
+			}
+			log.Printf("Warning: no display-server detector after %d attempts, continuing with %s", attempt, det.GetDisplayServer())
+			return det, nil
+		}
+
+		if err != nil {
+			log.Printf("Window detector not ready (attempt %d): %v, retrying in %v", attempt, err, delay)
+		} else {
+			log.Printf("Display-server detector not ready (attempt %d), retrying in %v", attempt, delay)
+			det.Close()
+		}
+
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+func hasWindowDetector(det window.Detector) bool {
+	if !detector.DisplayConfigured() {
+		return true
+	}
+	wb, ok := det.(windowBacked)
+	return !ok || wb.HasWindowDetector()
+}
+
+// waitForDisplay blocks until the configured display's socket appears or the
+// deadline passes. It returns immediately when no display is configured, as
+// when started from a plain terminal session.
+func waitForDisplay(deadline time.Time) error {
+	if !detector.DisplayConfigured() || detector.DisplayReady() {
+		return nil
+	}
+
+	log.Printf("Waiting up to %v for the display server to start", time.Until(deadline).Round(time.Second))
+	for !detector.DisplayReady() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("display server not ready by startup deadline")
+		}
+		time.Sleep(displayPollInterval)
+	}
+	log.Println("Display server is ready")
+	return nil
+}
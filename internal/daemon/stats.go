@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/tracker"
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const statsPublishInterval = time.Minute
+
+// DetectorStats is what a running daemon publishes about its detection
+// backends.
+type DetectorStats struct {
+	PID       int                            `json:"pid"`
+	StartedAt time.Time                      `json:"started_at"`
+	UpdatedAt time.Time                      `json:"updated_at"`
+	Backends  map[string]window.BackendStats `json:"backends"`
+
+	// Limitations are the windows the detector currently cannot see.
+	Limitations []string `json:"limitations,omitempty"`
+
+	// Overhead is what the daemon has cost the machine so far.
+	Overhead *Overhead `json:"overhead,omitempty"`
+}
+
+// BackendNames returns the backends in s, most used first.
+func (s *DetectorStats) BackendNames() []string {
+	names := make([]string, 0, len(s.Backends))
+	for name := range s.Backends {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := s.Backends[names[i]], s.Backends[names[j]]
+		if a.Successes+a.Failures != b.Successes+b.Failures {
+			return a.Successes+a.Failures > b.Successes+b.Failures
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Successes returns how many lookups any backend answered.
+func (s *DetectorStats) Successes() int64 {
+	var total int64
+	for _, stats := range s.Backends {
+		total += stats.Successes
+	}
+	return total
+}
+
+// ReadStats returns the stats last published to path.
+func ReadStats(path string) (*DetectorStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read detector stats: %w", err)
+	}
+	var stats DetectorStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("invalid detector stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// StatsPublisher writes the detector's per-backend counts and the daemon's
+// overhead to a file every minute, so commands run outside the daemon can
+// show them.
+type StatsPublisher struct {
+	path      string
+	reporter  window.StatsReporter
+	polls     *tracker.Polls
+	startedAt time.Time
+}
+
+// NewStatsPublisher returns nil when det doesn't count its lookups.
+func NewStatsPublisher(path string, det window.Detector) *StatsPublisher {
+	reporter, ok := det.(window.StatsReporter)
+	if !ok || path == "" {
+		return nil
+	}
+	return &StatsPublisher{path: path, reporter: reporter, startedAt: time.Now()}
+}
+
+// UsePolls adds the tracker's poll times to the published overhead.
+func (p *StatsPublisher) UsePolls(polls *tracker.Polls) {
+	p.polls = polls
+}
+
+func (p *StatsPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(statsPublishInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.publish(); err != nil {
+			log.Printf("%v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *StatsPublisher) publish() error {
+	stats := DetectorStats{
+		PID:       os.Getpid(),
+		StartedAt: p.startedAt,
+		UpdatedAt: time.Now(),
+		Backends:  p.reporter.BackendStats(),
+		Overhead:  measureOverhead(p.startedAt, p.polls),
+	}
+	if coverage, ok := p.reporter.(window.CoverageReporter); ok {
+		stats.Limitations = coverage.Limitations()
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode detector stats: %w", err)
+	}
+	// Written aside and renamed so readers never see a partial file.
+	tmp := p.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("failed to create stats directory: %w", err)
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write detector stats: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to write detector stats: %w", err)
+	}
+	return nil
+}
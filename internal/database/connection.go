@@ -2,10 +2,13 @@ package database
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/sandbox"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -19,15 +22,23 @@ const (
 
 type DB struct {
 	*gorm.DB
+	path string
 }
 
+// GetDefaultDBPath returns ~/.config/actionsum/actionsum.db, or inside a
+// Flatpak the app's data directory, creating the directory.
 func GetDefaultDBPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+	var dbDir string
+	if dataDir := os.Getenv("XDG_DATA_HOME"); dataDir != "" && sandbox.Flatpak() {
+		dbDir = filepath.Join(dataDir, "actionsum")
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dbDir = filepath.Join(homeDir, defaultDBDir)
 	}
 
-	dbDir := filepath.Join(homeDir, defaultDBDir)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create database directory: %w", err)
 	}
@@ -35,7 +46,34 @@ func GetDefaultDBPath() (string, error) {
 	return filepath.Join(dbDir, defaultDBName), nil
 }
 
-func Connect(dbPath string) (*DB, error) {
+type ConnectOption func(*gorm.Config)
+
+// WithLogging sends database errors, slow queries or every query, depending
+// on level ("silent", "error", "warn" or "info"), to the standard logger.
+// Queries slower than slowThreshold are logged with their SQL and duration;
+// zero disables that.
+func WithLogging(level string, slowThreshold time.Duration) ConnectOption {
+	levels := map[string]logger.LogLevel{
+		"silent": logger.Silent,
+		"error":  logger.Error,
+		"warn":   logger.Warn,
+		"info":   logger.Info,
+	}
+	logLevel, ok := levels[level]
+	if !ok {
+		logLevel = logger.Silent
+	}
+
+	return func(c *gorm.Config) {
+		c.Logger = logger.New(log.Default(), logger.Config{
+			SlowThreshold:             slowThreshold,
+			LogLevel:                  logLevel,
+			IgnoreRecordNotFoundError: true,
+		})
+	}
+}
+
+func Connect(dbPath string, opts ...ConnectOption) (*DB, error) {
 	if dbPath == "" {
 		var err error
 		dbPath, err = GetDefaultDBPath()
@@ -44,23 +82,91 @@ func Connect(dbPath string) (*DB, error) {
 		}
 	}
 
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	gormConfig := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
-	})
+	}
+	for _, opt := range opts {
+		opt(gormConfig)
+	}
+
+	db, err := gorm.Open(sqlite.Open(dbPath), gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, path: dbPath}, nil
 }
 
 func (db *DB) Initialize() error {
-	err := db.AutoMigrate(&models.FocusEvent{}, &models.ErrorLog{})
+	err := db.AutoMigrate(&models.FocusEvent{}, &models.ErrorLog{}, &models.StateEvent{}, &models.Note{}, &models.AuditEntry{}, &models.APIToken{}, &models.TeamSharing{})
 	if err != nil {
 		return fmt.Errorf("failed to initialize database schema: %w", err)
 	}
 
-	return nil
+	// Events recorded before UUIDs existed get random version 4 ones.
+	err = db.Exec(`UPDATE focus_events SET uuid = lower(
+		hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+		substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+	) WHERE uuid IS NULL OR uuid = ''`).Error
+	if err != nil {
+		return fmt.Errorf("failed to assign event UUIDs: %w", err)
+	}
+
+	// Event UUIDs and token names are unique per user. They used to be
+	// unique across all users.
+	for _, stmt := range []string{
+		"DROP INDEX IF EXISTS idx_focus_events_uuid",
+		"DROP INDEX IF EXISTS idx_api_tokens_name",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_focus_events_user_uuid ON focus_events(user_id, uuid)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_user_name ON api_tokens(user_id, name)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to update unique indexes: %w", err)
+		}
+	}
+
+	return db.initSearch()
+}
+
+// ConnectCopy copies the database at path to a temporary file and connects
+// to the copy, leaving the original untouched even when the copy is migrated.
+// Close the copy with Discard.
+func ConnectCopy(path string, opts ...ConnectOption) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	src, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	source := &DB{DB: src, path: path}
+	defer source.Close()
+
+	tmp, err := os.CreateTemp("", "actionsum-copy-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database copy: %w", err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	if err := source.Exec("VACUUM INTO ?", tmp.Name()).Error; err != nil {
+		return nil, fmt.Errorf("failed to copy database: %w", err)
+	}
+	db, err := Connect(tmp.Name(), opts...)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return db, nil
+}
+
+// Discard closes a database opened with ConnectCopy and removes the copy.
+func (db *DB) Discard() error {
+	err := db.Close()
+	os.Remove(db.path)
+	return err
 }
 
 func (db *DB) Close() error {
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// maxProblems bounds how many integrity_check findings are reported.
+const maxProblems = 10
+
+// CorruptionError reports a database SQLite found damaged.
+type CorruptionError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("database %s is corrupt: %s", e.Path, strings.Join(e.Problems, "; "))
+}
+
+// CheckIntegrity runs SQLite's integrity_check on the database at path and
+// returns a *CorruptionError when it finds damage, including a file that
+// isn't a database at all. A missing file is fine: it is created on connect.
+// An empty path means the default database.
+func CheckIntegrity(path string) error {
+	path, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		if isCorruption(err) {
+			return &CorruptionError{Path: path, Problems: []string{err.Error()}}
+		}
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer (&DB{DB: db, path: path}).Close()
+
+	var results []string
+	if err := db.Raw(fmt.Sprintf("PRAGMA integrity_check(%d)", maxProblems)).Scan(&results).Error; err != nil {
+		if isCorruption(err) {
+			return &CorruptionError{Path: path, Problems: []string{err.Error()}}
+		}
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	if len(results) == 1 && results[0] == "ok" {
+		return nil
+	}
+
+	// Findings may come as one multi-line row under a "*** in database"
+	// heading.
+	var problems []string
+	for _, result := range results {
+		for _, line := range strings.Split(result, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "***") {
+				problems = append(problems, line)
+			}
+		}
+	}
+	if len(problems) > maxProblems {
+		problems = problems[:maxProblems]
+	}
+	return &CorruptionError{Path: path, Problems: problems}
+}
+
+// isCorruption tells SQLite's corruption errors from others, such as a
+// locked or unreadable file, that say nothing about the data.
+func isCorruption(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "malformed") || strings.Contains(msg, "not a database") || strings.Contains(msg, "corrupt")
+}
+
+// RecoveryResult describes a recovered database.
+type RecoveryResult struct {
+	// CorruptPath is where the damaged file was moved.
+	CorruptPath string
+
+	// Recovered counts the rows copied per table.
+	Recovered map[string]int64
+
+	// Lost counts rows that could be seen but not read. Rows in pages too
+	// damaged to list are lost without being counted.
+	Lost int64
+
+	// Unreadable lists tables nothing could be read from.
+	Unreadable []string
+}
+
+// Rows is the number of rows recovered across tables.
+func (r *RecoveryResult) Rows() int64 {
+	var total int64
+	for _, n := range r.Recovered {
+		total += n
+	}
+	return total
+}
+
+// Recover rebuilds the database at path: it creates a fresh database with
+// the current schema, copies whatever rows can still be read from the
+// damaged one, then moves the damaged file (and its WAL) aside as
+// <path>.corrupt-<time> and puts the fresh one in its place. Even when
+// nothing can be read, the result is an empty, working database.
+func Recover(path string) (*RecoveryResult, error) {
+	path, err := resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	fresh := path + ".recovering"
+	os.Remove(fresh)
+
+	result, err := copyReadable(path, fresh)
+	if err != nil {
+		os.Remove(fresh)
+		return nil, err
+	}
+
+	result.CorruptPath = fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(path+suffix, result.CorruptPath+suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(fresh)
+			return nil, fmt.Errorf("failed to move corrupt database aside: %w", err)
+		}
+	}
+	if err := os.Rename(fresh, path); err != nil {
+		return nil, fmt.Errorf("failed to replace corrupt database: %w", err)
+	}
+	return result, nil
+}
+
+// copyReadable creates the database at fresh and copies into it every row
+// of the database at damaged that can be read, table by table, falling back
+// to row by row where a table cannot be read in one go.
+func copyReadable(damaged, fresh string) (*RecoveryResult, error) {
+	db, err := Connect(fresh)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		return nil, err
+	}
+
+	// ATTACH only applies to the connection it runs on.
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	result := &RecoveryResult{Recovered: make(map[string]int64)}
+	if err := db.Exec("ATTACH DATABASE ? AS damaged", damaged).Error; err != nil {
+		result.Unreadable = append(result.Unreadable, "*")
+		return result, nil
+	}
+	defer db.Exec("DETACH DATABASE damaged")
+
+	var tables []string
+	if err := db.Raw("SELECT name FROM main.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		// The search index is rebuilt from the events as they are copied.
+		if isSearchTable(table) {
+			continue
+		}
+		columns := sharedColumns(db, table)
+		if len(columns) == 0 {
+			result.Unreadable = append(result.Unreadable, table)
+			continue
+		}
+		list := strings.Join(columns, ", ")
+		insert := fmt.Sprintf("INSERT OR IGNORE INTO main.%q (%s) SELECT %s FROM damaged.%q", table, list, list, table)
+
+		if tx := db.Exec(insert); tx.Error == nil {
+			result.Recovered[table] = tx.RowsAffected
+			continue
+		}
+
+		// Read around the damage: list rowids from both ends, since a scan
+		// stops at the first bad page, and copy rows one at a time.
+		rowids := readableRowids(db, table)
+		if len(rowids) == 0 {
+			result.Unreadable = append(result.Unreadable, table)
+			continue
+		}
+		for _, rowid := range rowids {
+			if tx := db.Exec(insert+" WHERE rowid = ?", rowid); tx.Error != nil {
+				result.Lost++
+			} else {
+				result.Recovered[table] += tx.RowsAffected
+			}
+		}
+	}
+	return result, nil
+}
+
+// sharedColumns returns the columns table has in both the fresh and the
+// damaged database, quoted, so older schemas copy across too.
+func sharedColumns(db *DB, table string) []string {
+	columnsOf := func(schema string) map[string]bool {
+		var names []string
+		db.Raw(fmt.Sprintf("SELECT name FROM pragma_table_info(%s, %s)", quote(table), quote(schema))).Scan(&names)
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			set[name] = true
+		}
+		return set
+	}
+
+	damaged := columnsOf("damaged")
+	var shared []string
+	for name := range columnsOf("main") {
+		if damaged[name] {
+			shared = append(shared, fmt.Sprintf("%q", name))
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// readableRowids lists the rowids of table that can be reached scanning
+// forwards and then backwards.
+func readableRowids(db *DB, table string) []int64 {
+	seen := make(map[int64]bool)
+	for _, order := range []string{"ASC", "DESC"} {
+		rows, err := db.Raw(fmt.Sprintf("SELECT rowid FROM damaged.%q ORDER BY rowid %s", table, order)).Rows()
+		if err != nil {
+			continue
+		}
+		for rows.Next() {
+			var rowid int64
+			if rows.Scan(&rowid) != nil || seen[rowid] {
+				break
+			}
+			seen[rowid] = true
+		}
+		rows.Close()
+	}
+
+	rowids := make([]int64, 0, len(seen))
+	for rowid := range seen {
+		rowids = append(rowids, rowid)
+	}
+	sort.Slice(rowids, func(i, j int) bool { return rowids[i] < rowids[j] })
+	return rowids
+}
+
+func resolvePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return GetDefaultDBPath()
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// CorruptCopies lists damaged databases Recover moved aside from path.
+func CorruptCopies(path string) []string {
+	path, err := resolvePath(path)
+	if err != nil {
+		return nil
+	}
+	matches, _ := filepath.Glob(path + ".corrupt-*")
+	var copies []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, "-wal") && !strings.HasSuffix(match, "-shm") {
+			copies = append(copies, match)
+		}
+	}
+	return copies
+}
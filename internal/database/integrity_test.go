@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// newDamagedDatabase writes n events to a database at path and then
+// overwrites a page in the middle of the file with garbage.
+func newDamagedDatabase(t *testing.T, path string, n int) {
+	t.Helper()
+	db, err := Connect(path)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	events := benchEvents(time.Now().Add(-time.Hour), n)
+	for _, event := range events {
+		event.WindowTitle = strings.Repeat("a long window title ", 10)
+	}
+	if err := NewRepository(db).CreateBatch(events); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	db.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	const pageSize = 4096
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer f.Close()
+	page := (info.Size() / pageSize) / 2
+	if _, err := f.WriteAt([]byte(strings.Repeat("\xde\xad\xbe\xef", pageSize/4)), page*pageSize); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := CheckIntegrity(filepath.Join(dir, "missing.db")); err != nil {
+		t.Errorf("CheckIntegrity(missing) = %v, want nil", err)
+	}
+
+	healthy := filepath.Join(dir, "healthy.db")
+	db, err := Connect(healthy)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	db.Close()
+	if err := CheckIntegrity(healthy); err != nil {
+		t.Errorf("CheckIntegrity(healthy) = %v, want nil", err)
+	}
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte(strings.Repeat("not sqlite ", 1000)), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	var corrupt *CorruptionError
+	if err := CheckIntegrity(garbage); !errors.As(err, &corrupt) {
+		t.Errorf("CheckIntegrity(garbage) = %v, want a CorruptionError", err)
+	}
+
+	damaged := filepath.Join(dir, "damaged.db")
+	newDamagedDatabase(t, damaged, 1000)
+	if err := CheckIntegrity(damaged); !errors.As(err, &corrupt) {
+		t.Errorf("CheckIntegrity(damaged) = %v, want a CorruptionError", err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "actionsum.db")
+	const n = 1000
+	newDamagedDatabase(t, path, n)
+
+	result, err := Recover(path)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if err := CheckIntegrity(path); err != nil {
+		t.Errorf("CheckIntegrity() after Recover() = %v, want nil", err)
+	}
+	if _, err := os.Stat(result.CorruptPath); err != nil {
+		t.Errorf("damaged copy not kept at %s: %v", result.CorruptPath, err)
+	}
+	if copies := CorruptCopies(path); len(copies) != 1 || copies[0] != result.CorruptPath {
+		t.Errorf("CorruptCopies() = %v, want [%s]", copies, result.CorruptPath)
+	}
+
+	db, err := Connect(path)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	var count int64
+	if err := db.Model(&models.FocusEvent{}).Count(&count).Error; err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count == 0 || count > n {
+		t.Errorf("recovered %d events, want some of the %d written", count, n)
+	}
+	if count != result.Recovered["focus_events"] {
+		t.Errorf("Recovered[focus_events] = %d, database holds %d", result.Recovered["focus_events"], count)
+	}
+
+	// The recovered database takes new events.
+	if err := NewRepository(db).Create(&models.FocusEvent{Timestamp: time.Now(), AppName: "code", Duration: 10}); err != nil {
+		t.Errorf("Create() after Recover() error = %v", err)
+	}
+}
+
+func TestRecoverUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "actionsum.db")
+	if err := os.WriteFile(path, []byte(strings.Repeat("not sqlite ", 1000)), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	result, err := Recover(path)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if result.Rows() != 0 || len(result.Unreadable) == 0 {
+		t.Errorf("Recover() = %+v, want nothing recovered", result)
+	}
+	if err := CheckIntegrity(path); err != nil {
+		t.Errorf("CheckIntegrity() after Recover() = %v, want nil", err)
+	}
+}
@@ -2,36 +2,329 @@ package database
 
 import (
 	"fmt"
+	"log"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/normalize"
 
 	"github.com/pkg/errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidEvent is returned when an event fails ingestion sanity checks.
+var ErrInvalidEvent = errors.New("invalid focus event")
+
+const (
+	// maxFutureSkew is how far ahead of the local clock an event may be stamped.
+	maxFutureSkew = 10 * time.Minute
+
+	// overlapTolerance absorbs poll scheduling jitter before clamping durations.
+	overlapTolerance = time.Second
+
+	// batchInsertRows caps the rows per INSERT in CreateBatch so statements
+	// stay under SQLite's bound variable limit.
+	batchInsertRows = 500
 )
 
 type Repository struct {
-	db *DB
+	db             *DB
+	normalizer     *normalize.Normalizer
+	maxDuration    time.Duration
+	excludeGuesses bool
+
+	// user is whose events, notes and state events the repository reads and
+	// writes. The empty user is this machine's own.
+	user string
+}
+
+type Option func(*Repository)
+
+// WithNormalizer sets the app name normalizer applied on insert and when
+// aggregating summaries. The built-in rules are used by default.
+func WithNormalizer(n *normalize.Normalizer) Option {
+	return func(r *Repository) {
+		r.normalizer = n
+	}
+}
+
+// WithMaxEventDuration clamps stored event durations to d. Zero disables the
+// absolute cap; durations are still limited by the gap to neighbouring events.
+func WithMaxEventDuration(d time.Duration) Option {
+	return func(r *Repository) {
+		r.maxDuration = d
+	}
+}
+
+// WithoutProcessGuesses leaves events found by the process-based fallback
+// out of event lookups, summaries and daily totals.
+func WithoutProcessGuesses() Option {
+	return func(r *Repository) {
+		r.excludeGuesses = true
+	}
+}
+
+func NewRepository(db *DB, opts ...Option) *Repository {
+	r := &Repository{db: db, normalizer: normalize.New()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Using returns a copy of the repository, with the same options, that
+// queries db instead.
+func (r *Repository) Using(db *DB) *Repository {
+	c := *r
+	c.db = db
+	return &c
 }
 
-func NewRepository(db *DB) *Repository {
-	return &Repository{db: db}
+// ForUser returns a copy of the repository, with the same options, that
+// reads and writes the data of user on a multi-user server instead.
+func (r *Repository) ForUser(user string) *Repository {
+	c := *r
+	c.user = user
+	return &c
+}
+
+// User returns whose data the repository reads and writes.
+func (r *Repository) User() string {
+	return r.user
+}
+
+// own limits a query to the repository user's rows.
+func (r *Repository) own(tx *gorm.DB) *gorm.DB {
+	return tx.Where("user_id = ?", r.user)
+}
+
+// WithTx runs fn with a repository whose reads and writes all belong to one
+// transaction, committed when fn returns nil and rolled back otherwise.
+// Calling WithTx on a repository already inside a transaction nests it as a
+// savepoint.
+func (r *Repository) WithTx(fn func(tx *Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(r.Using(&DB{DB: tx, path: r.db.path}))
+	})
+}
+
+// nameApp normalizes event's app name, keeping the name detected when it
+// changes, and the display name of the installed app.
+func (r *Repository) nameApp(event *models.FocusEvent) {
+	normalized := r.normalizer.Normalize(event.AppName)
+	if event.RawAppName == "" && normalized != event.AppName {
+		event.RawAppName = event.AppName
+	}
+	if event.DisplayName == "" {
+		event.DisplayName = r.normalizer.DisplayName(event.AppName)
+	}
+	event.AppName = normalized
 }
 
 func (r *Repository) Create(event *models.FocusEvent) error {
-	event.AppName = strings.ToLower(event.AppName)
-	result := r.db.Create(event)
+	r.nameApp(event)
+	event.UserID = r.user
+	if err := r.checkEvent(event); err != nil {
+		return err
+	}
+	// Trimming the previous event and inserting this one succeed or fail
+	// together, so a failed insert never leaves a shortened neighbour.
+	return r.WithTx(func(tx *Repository) error {
+		if err := tx.clampToNeighbours(event); err != nil {
+			return err
+		}
+		result := tx.db.Create(event)
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed to insert focus event")
+		}
+		return nil
+	})
+}
+
+// CreateBatch stores events with multi-row INSERTs instead of one round trip
+// per event. Events are normalized, validated and clamped against each other
+// and their stored neighbours as Create does; rejected events are logged and
+// skipped. All writes happen in one transaction.
+func (r *Repository) CreateBatch(events []*models.FocusEvent) error {
+	valid := make([]*models.FocusEvent, 0, len(events))
+	for _, event := range events {
+		r.nameApp(event)
+		event.UserID = r.user
+		if err := r.checkEvent(event); err != nil {
+			continue
+		}
+		valid = append(valid, event)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].Timestamp.Before(valid[j].Timestamp)
+	})
+
+	return r.WithTx(func(tx *Repository) error {
+		if err := tx.clampBatch(valid); err != nil {
+			return err
+		}
+		if err := tx.db.CreateInBatches(valid, batchInsertRows).Error; err != nil {
+			return errors.Wrap(err, "failed to insert focus events")
+		}
+		return nil
+	})
+}
+
+// clampBatch applies the clampToNeighbours rules to events, sorted by
+// timestamp, and the stored events around and between them, querying the
+// stored side once for the whole batch.
+func (r *Repository) clampBatch(events []*models.FocusEvent) error {
+	first, last := events[0].Timestamp, events[len(events)-1].Timestamp
+
+	var prev, next []*models.FocusEvent
+	var between []*models.FocusEvent
+	if err := r.db.Scopes(r.own).Where("timestamp <= ?", first).Order("timestamp DESC").Limit(1).Find(&prev).Error; err != nil {
+		return errors.Wrap(err, "failed to query previous event")
+	}
+	if err := r.db.Scopes(r.own).Where("timestamp > ? AND timestamp <= ?", first, last).Order("timestamp ASC").Find(&between).Error; err != nil {
+		return errors.Wrap(err, "failed to query overlapping events")
+	}
+	if err := r.db.Scopes(r.own).Where("timestamp > ?", last).Order("timestamp ASC").Limit(1).Find(&next).Error; err != nil {
+		return errors.Wrap(err, "failed to query next event")
+	}
+
+	// Stored events sort before new ones with the same timestamp, matching
+	// the "timestamp <= ?" lookup in clampToNeighbours.
+	timeline := append(append(prev, between...), next...)
+	timeline = append(timeline, events...)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.Before(timeline[j].Timestamp)
+	})
+
+	for i := 0; i+1 < len(timeline); i++ {
+		event, following := timeline[i], timeline[i+1]
+		if event.ID != 0 && following.ID != 0 {
+			continue
+		}
+		gap := following.Timestamp.Sub(event.Timestamp)
+		if time.Duration(event.Duration)*time.Second <= gap+overlapTolerance {
+			continue
+		}
+
+		clamped := int64(gap.Seconds())
+		if event.ID == 0 {
+			r.logIngestion(fmt.Sprintf("clamped focus event for %q from %ds to %ds: overlaps next event",
+				event.AppName, event.Duration, clamped))
+			event.Duration = clamped
+			continue
+		}
+		r.logIngestion(fmt.Sprintf("clamped focus event #%d (%q) from %ds to %ds: overlaps next event",
+			event.ID, event.AppName, event.Duration, clamped))
+		if err := r.UpdateDuration(event.ID, clamped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEvent rejects events with unusable timestamps or negative durations and
+// clamps durations above the configured maximum. Both are logged to ErrorLog.
+func (r *Repository) checkEvent(event *models.FocusEvent) error {
+	if reason := rejectReason(event); reason != "" {
+		r.logIngestion(fmt.Sprintf("rejected focus event for %q: %s", event.AppName, reason))
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, reason)
+	}
+
+	if maxSeconds := int64(r.maxDuration.Seconds()); maxSeconds > 0 && event.Duration > maxSeconds {
+		r.logIngestion(fmt.Sprintf("clamped focus event for %q from %ds to %ds: exceeds maximum duration",
+			event.AppName, event.Duration, maxSeconds))
+		event.Duration = maxSeconds
+	}
+
+	return nil
+}
+
+// ValidateEvent normalizes event's app name and applies the checks Create
+// and CreateBatch do, without logging: it returns ErrInvalidEvent for an
+// event they would reject, and clamps a duration above the maximum.
+func (r *Repository) ValidateEvent(event *models.FocusEvent) error {
+	r.nameApp(event)
+	if reason := rejectReason(event); reason != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidEvent, reason)
+	}
+	if maxSeconds := int64(r.maxDuration.Seconds()); maxSeconds > 0 && event.Duration > maxSeconds {
+		event.Duration = maxSeconds
+	}
+	return nil
+}
+
+// rejectReason says why event cannot be stored, or is empty if it can.
+func rejectReason(event *models.FocusEvent) string {
+	switch {
+	case event.Timestamp.IsZero() || event.Timestamp.Unix() <= 0:
+		return "timestamp is zero or negative"
+	case event.Timestamp.After(time.Now().Add(maxFutureSkew)):
+		return fmt.Sprintf("timestamp %s is in the future", event.Timestamp.Format(time.RFC3339))
+	case event.Duration < 0:
+		return fmt.Sprintf("duration %ds is negative", event.Duration)
+	}
+	return ""
+}
+
+// clampToNeighbours trims the previous event if it runs past the new event's
+// timestamp, and the new event if it runs past the next one, so that clock
+// jumps and imports cannot produce overlapping time.
+func (r *Repository) clampToNeighbours(event *models.FocusEvent) error {
+	var prev models.FocusEvent
+	result := r.db.Scopes(r.own).Where("timestamp <= ?", event.Timestamp).Order("timestamp DESC").Limit(1).Find(&prev)
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed to query previous event")
+	}
+	if result.RowsAffected > 0 {
+		gap := event.Timestamp.Sub(prev.Timestamp)
+		if time.Duration(prev.Duration)*time.Second > gap+overlapTolerance {
+			clamped := int64(gap.Seconds())
+			r.logIngestion(fmt.Sprintf("clamped focus event #%d (%q) from %ds to %ds: overlaps next event",
+				prev.ID, prev.AppName, prev.Duration, clamped))
+			if err := r.UpdateDuration(prev.ID, clamped); err != nil {
+				return err
+			}
+		}
+	}
+
+	var next models.FocusEvent
+	result = r.db.Scopes(r.own).Where("timestamp > ?", event.Timestamp).Order("timestamp ASC").Limit(1).Find(&next)
 	if result.Error != nil {
-		return errors.Wrap(result.Error, "failed to insert focus event")
+		return errors.Wrap(result.Error, "failed to query next event")
+	}
+	if result.RowsAffected > 0 {
+		gap := next.Timestamp.Sub(event.Timestamp)
+		if time.Duration(event.Duration)*time.Second > gap+overlapTolerance {
+			clamped := int64(gap.Seconds())
+			r.logIngestion(fmt.Sprintf("clamped focus event for %q from %ds to %ds: overlaps next event",
+				event.AppName, event.Duration, clamped))
+			event.Duration = clamped
+		}
 	}
+
 	return nil
 }
 
+func (r *Repository) logIngestion(msg string) {
+	errorLog := &models.ErrorLog{
+		Timestamp: time.Now(),
+		ErrorMsg:  msg,
+	}
+	if err := r.CreateErrorLog(errorLog); err != nil {
+		log.Printf("Failed to store ingestion error: %v (original: %s)", err, msg)
+	}
+}
+
 func (r *Repository) GetByID(id uint) (*models.FocusEvent, error) {
 	var event models.FocusEvent
-	result := r.db.First(&event, id)
+	result := r.db.Scopes(r.own).First(&event, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, gorm.ErrRecordNotFound
@@ -41,9 +334,84 @@ func (r *Repository) GetByID(id uint) (*models.FocusEvent, error) {
 	return &event, nil
 }
 
+// GetByUUID returns the user's event with the given UUID, or nil if there is
+// none.
+func (r *Repository) GetByUUID(uuid string) (*models.FocusEvent, error) {
+	var event models.FocusEvent
+	result := r.db.Scopes(r.own).Where("uuid = ?", uuid).First(&event)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrap(result.Error, "failed to get focus event")
+	}
+	return &event, nil
+}
+
+// GetEventsByUUID returns the user's events with the given UUIDs keyed by
+// UUID, including deleted ones, whose UUIDs stay reserved.
+func (r *Repository) GetEventsByUUID(uuids []string) (map[string]*models.FocusEvent, error) {
+	found := make(map[string]*models.FocusEvent, len(uuids))
+	for start := 0; start < len(uuids); start += batchInsertRows {
+		end := start + batchInsertRows
+		if end > len(uuids) {
+			end = len(uuids)
+		}
+		var events []*models.FocusEvent
+		result := r.db.Unscoped().Scopes(r.own).Where("uuid IN ?", uuids[start:end]).Find(&events)
+		if result.Error != nil {
+			return nil, errors.Wrap(result.Error, "failed to get focus events")
+		}
+		for _, event := range events {
+			found[event.UUID] = event
+		}
+	}
+	return found, nil
+}
+
+// recordedLocalTime is an SQLite date modifier converting an event's UTC
+// timestamp to the local time it was recorded in.
+const recordedLocalTime = "CASE WHEN utc_offset IS NULL THEN 'localtime' ELSE utc_offset || ' seconds' END"
+
+// Query narrows event lookups. Zero-valued fields are not filtered on.
+type Query struct {
+	Since   time.Time
+	Until   time.Time
+	Profile string
+}
+
+func (q Query) scope(tx *gorm.DB) *gorm.DB {
+	tx = tx.Where("timestamp >= ?", q.Since)
+	if !q.Until.IsZero() {
+		tx = tx.Where("timestamp < ?", q.Until)
+	}
+	if q.Profile != "" {
+		tx = tx.Where("profile = ?", q.Profile)
+	}
+	return tx
+}
+
+// DetectionProcess marks events guessed from running processes.
+const DetectionProcess = "process-based"
+
+// scope applies q and the repository's own filters.
+func (r *Repository) scope(q Query) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = r.own(q.scope(tx))
+		if r.excludeGuesses {
+			tx = tx.Where("detection <> ?", DetectionProcess)
+		}
+		return tx
+	}
+}
+
 func (r *Repository) GetEventsSince(since time.Time) ([]*models.FocusEvent, error) {
+	return r.GetEvents(Query{Since: since})
+}
+
+func (r *Repository) GetEvents(q Query) ([]*models.FocusEvent, error) {
 	var events []*models.FocusEvent
-	result := r.db.Where("timestamp >= ?", since).Order("timestamp ASC").Find(&events)
+	result := r.db.Scopes(r.scope(q)).Order("timestamp ASC").Find(&events)
 
 	if result.Error != nil {
 		return nil, errors.Wrap(result.Error, "failed to query focus events")
@@ -52,12 +420,45 @@ func (r *Repository) GetEventsSince(since time.Time) ([]*models.FocusEvent, erro
 	return events, nil
 }
 
+// GetEventsOverlapping returns the stored events, process guesses included,
+// that start in [start, end) or run into it from before, in timestamp order.
+func (r *Repository) GetEventsOverlapping(start, end time.Time) ([]*models.FocusEvent, error) {
+	var longest int64
+	result := r.db.Model(&models.FocusEvent{}).Scopes(r.own).Select("COALESCE(MAX(duration), 0)").Scan(&longest)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query longest focus event")
+	}
+
+	var events []*models.FocusEvent
+	result = r.db.Scopes(r.own).
+		Where("timestamp >= ? AND timestamp < ?", start.Add(-time.Duration(longest)*time.Second), end).
+		Order("timestamp ASC").
+		Find(&events)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query focus events")
+	}
+
+	overlapping := events[:0]
+	for _, event := range events {
+		if !event.Timestamp.Before(start) || event.Timestamp.Add(time.Duration(event.Duration)*time.Second).After(start) {
+			overlapping = append(overlapping, event)
+		}
+	}
+	return overlapping, nil
+}
+
 func (r *Repository) GetAppSummarySince(since time.Time) ([]models.AppSummary, error) {
+	return r.GetAppSummary(Query{Since: since})
+}
+
+func (r *Repository) GetAppSummary(q Query) ([]models.AppSummary, error) {
 	var summaries []models.AppSummary
 
 	result := r.db.Model(&models.FocusEvent{}).
-		Select("app_name, SUM(duration) as total_seconds, COUNT(*) as event_count").
-		Where("timestamp >= ?", since).
+		Select("app_name, SUM(duration) as total_seconds, " +
+			"SUM(CASE WHEN is_fullscreen THEN duration ELSE 0 END) as fullscreen_seconds, " +
+			"COUNT(*) as event_count").
+		Scopes(r.scope(q)).
 		Group("app_name").
 		Order("total_seconds DESC").
 		Scan(&summaries)
@@ -66,11 +467,190 @@ func (r *Repository) GetAppSummarySince(since time.Time) ([]models.AppSummary, e
 		return nil, errors.Wrap(result.Error, "failed to query app summary")
 	}
 
+	return r.mergeSummaries(summaries), nil
+}
+
+// mergeSummaries folds rows whose stored names normalize to the same app, so
+// history recorded before a mapping existed still aggregates correctly.
+func (r *Repository) mergeSummaries(summaries []models.AppSummary) []models.AppSummary {
+	index := make(map[string]int, len(summaries))
+	merged := make([]models.AppSummary, 0, len(summaries))
+
+	for _, summary := range summaries {
+		summary.AppName = r.normalizer.Normalize(summary.AppName)
+		if i, ok := index[summary.AppName]; ok {
+			merged[i].TotalSeconds += summary.TotalSeconds
+			merged[i].FullscreenSeconds += summary.FullscreenSeconds
+			merged[i].EventCount += summary.EventCount
+			continue
+		}
+		summary.DisplayName = r.normalizer.DisplayName(summary.AppName)
+		index[summary.AppName] = len(merged)
+		merged = append(merged, summary)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].TotalSeconds > merged[j].TotalSeconds
+	})
+
+	return merged
+}
+
+// GetDailyAppTotals sums focus time per local calendar day, app and tag, with
+// app names normalized. Rows are ordered by day. Events fall on the calendar
+// day of the UTC offset they were recorded under, so travelling does not move
+// earlier activity to another day; events from before offsets were recorded
+// use the current local time zone.
+func (r *Repository) GetDailyAppTotals(q Query) ([]models.DailyAppTotal, error) {
+	var totals []models.DailyAppTotal
+	result := r.db.Model(&models.FocusEvent{}).
+		Select("date(timestamp, " + recordedLocalTime + ") as day, app_name, tag, SUM(duration) as total_seconds").
+		Scopes(r.scope(q)).
+		Group("day, app_name, tag").
+		Order("day ASC").
+		Scan(&totals)
+
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query daily totals")
+	}
+
+	for i := range totals {
+		totals[i].AppName = r.normalizer.Normalize(totals[i].AppName)
+	}
+	return totals, nil
+}
+
+// GetDetectionSummary splits the time in q by the method that detected it,
+// largest first. Process guesses are counted even when the repository
+// otherwise leaves them out, since the split is about how much of the data
+// they make up.
+func (r *Repository) GetDetectionSummary(q Query) ([]models.DetectionSummary, error) {
+	var summaries []models.DetectionSummary
+	result := r.db.Model(&models.FocusEvent{}).
+		Select("detection as method, SUM(duration) as total_seconds, COUNT(*) as event_count").
+		Scopes(q.scope, r.own).
+		Group("detection").
+		Order("total_seconds DESC").
+		Scan(&summaries)
+
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query detection summary")
+	}
+
+	var total int64
+	for _, summary := range summaries {
+		total += summary.TotalSeconds
+	}
+	if total > 0 {
+		for i := range summaries {
+			summaries[i].Percentage = float64(summaries[i].TotalSeconds) / float64(total) * 100
+		}
+	}
 	return summaries, nil
 }
 
+// GetWaylandClientTotals returns the seconds in q recorded on Wayland per
+// client kind ("native", "xwayland", or "" when the backend couldn't tell).
+func (r *Repository) GetWaylandClientTotals(q Query) (map[string]int64, error) {
+	var rows []struct {
+		Client       string
+		TotalSeconds int64
+	}
+	result := r.db.Model(&models.FocusEvent{}).
+		Select("client, SUM(duration) as total_seconds").
+		Scopes(q.scope, r.own).
+		Where("display_server = ?", "wayland").
+		Group("client").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query wayland client totals")
+	}
+
+	totals := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		totals[row.Client] = row.TotalSeconds
+	}
+	return totals, nil
+}
+
+// recordedMonth is the local month ("2006-01") an event was recorded in.
+const recordedMonth = "strftime('%Y-%m', timestamp, " + recordedLocalTime + ")"
+
+// GetEventMonths returns the local months that have events, oldest first.
+func (r *Repository) GetEventMonths() ([]string, error) {
+	var months []string
+	result := r.db.Model(&models.FocusEvent{}).
+		Scopes(r.own).
+		Distinct(recordedMonth).
+		Order(recordedMonth).
+		Pluck(recordedMonth, &months)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query event months")
+	}
+	return months, nil
+}
+
+// GetChangedMonths returns the local months of events created, updated or
+// deleted since the given time.
+func (r *Repository) GetChangedMonths(since time.Time) ([]string, error) {
+	var months []string
+	result := r.db.Unscoped().Model(&models.FocusEvent{}).
+		Scopes(r.own).
+		Where("created_at >= ? OR updated_at >= ? OR deleted_at >= ?", since, since, since).
+		Distinct(recordedMonth).
+		Pluck(recordedMonth, &months)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query changed months")
+	}
+	return months, nil
+}
+
+// GetProfiles returns the distinct profiles that have recorded events.
+func (r *Repository) GetProfiles() ([]string, error) {
+	var profiles []string
+	result := r.db.Model(&models.FocusEvent{}).Scopes(r.own).Distinct().Order("profile").Pluck("profile", &profiles)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query profiles")
+	}
+	return profiles, nil
+}
+
+// GetEarliestTracked returns the first event recorded by the tracker rather
+// than imported from another source, or nil if there is none.
+func (r *Repository) GetEarliestTracked() (*models.FocusEvent, error) {
+	var event models.FocusEvent
+	result := r.db.Scopes(r.own).Where("source = ''").Order("timestamp ASC").Limit(1).Find(&event)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query earliest tracked event")
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+// ReplaceSource deletes the events previously stored from source and stores
+// events in their place, so an import can be re-run. It returns how many
+// events were deleted.
+func (r *Repository) ReplaceSource(source string, events []*models.FocusEvent) (int64, error) {
+	var deleted int64
+	err := r.WithTx(func(tx *Repository) error {
+		result := tx.db.Scopes(tx.own).Where("source = ?", source).Delete(&models.FocusEvent{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed to delete previous import")
+		}
+		deleted = result.RowsAffected
+		for _, event := range events {
+			event.Source = source
+		}
+		return tx.CreateBatch(events)
+	})
+	return deleted, err
+}
+
 func (r *Repository) DeleteOldEvents(before time.Time) (int64, error) {
-	result := r.db.Where("timestamp < ?", before).Delete(&models.FocusEvent{})
+	result := r.db.Scopes(r.own).Where("timestamp < ?", before).Delete(&models.FocusEvent{})
 	if result.Error != nil {
 		return 0, errors.Wrap(result.Error, "failed to delete old events")
 	}
@@ -79,7 +659,7 @@ func (r *Repository) DeleteOldEvents(before time.Time) (int64, error) {
 
 func (r *Repository) GetLatest() (*models.FocusEvent, error) {
 	var event models.FocusEvent
-	result := r.db.Order("timestamp DESC").First(&event)
+	result := r.db.Scopes(r.own).Order("timestamp DESC").First(&event)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -90,8 +670,14 @@ func (r *Repository) GetLatest() (*models.FocusEvent, error) {
 }
 
 func (r *Repository) Update(event *models.FocusEvent) error {
-	event.AppName = strings.ToLower(event.AppName)
-	result := r.db.Save(event)
+	r.nameApp(event)
+	event.UserID = r.user
+	if err := r.checkEvent(event); err != nil {
+		return err
+	}
+	// Selecting the columns keeps Save from inserting the event when the
+	// user has no event with its ID.
+	result := r.db.Scopes(r.own).Select("*").Save(event)
 	if result.Error != nil {
 		return errors.Wrap(result.Error, "failed to update event")
 	}
@@ -102,7 +688,7 @@ func (r *Repository) Update(event *models.FocusEvent) error {
 }
 
 func (r *Repository) UpdateDuration(id uint, duration int64) error {
-	result := r.db.Model(&models.FocusEvent{}).Where("id = ?", id).Update("duration", duration)
+	result := r.db.Model(&models.FocusEvent{}).Scopes(r.own).Where("id = ?", id).Update("duration", duration)
 	if result.Error != nil {
 		return errors.Wrap(result.Error, "failed to update event duration")
 	}
@@ -117,19 +703,347 @@ func (r *Repository) CreateErrorLog(errorLog *models.ErrorLog) error {
 	return nil
 }
 
+func (r *Repository) CreateStateEvent(event *models.StateEvent) error {
+	event.UserID = r.user
+	result := r.db.Create(event)
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed to insert state event")
+	}
+	return nil
+}
+
+var lockStateTypes = []string{models.StateLock, models.StateUnlock}
+
+// GetLatestStateEvent returns the most recent lock or unlock event.
+func (r *Repository) GetLatestStateEvent() (*models.StateEvent, error) {
+	var event models.StateEvent
+	result := r.db.Scopes(r.own).Where("type IN ?", lockStateTypes).Order("timestamp DESC").First(&event)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrap(result.Error, "failed to get latest state event")
+	}
+	return &event, nil
+}
+
+// GetStateEventsSince returns state events after since, preceded by the last
+// lock or unlock event before since so callers know the lock state at the
+// start of the range.
+func (r *Repository) GetStateEventsSince(since time.Time) ([]*models.StateEvent, error) {
+	var events []*models.StateEvent
+
+	var previous models.StateEvent
+	result := r.db.Scopes(r.own).Where("timestamp < ? AND type IN ?", since, lockStateTypes).Order("timestamp DESC").Limit(1).Find(&previous)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query previous state event")
+	}
+	if result.RowsAffected > 0 {
+		events = append(events, &previous)
+	}
+
+	var recent []*models.StateEvent
+	result = r.db.Scopes(r.own).Where("timestamp >= ?", since).Order("timestamp ASC").Find(&recent)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query state events")
+	}
+
+	return append(events, recent...), nil
+}
+
+// GetLastStateEventBefore returns the newest event of the given types before
+// t, or nil when there is none.
+func (r *Repository) GetLastStateEventBefore(t time.Time, types ...string) (*models.StateEvent, error) {
+	var event models.StateEvent
+	result := r.db.Scopes(r.own).Where("timestamp < ? AND type IN ?", t, types).Order("timestamp DESC").Limit(1).Find(&event)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query state event")
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+func (r *Repository) GetErrorLogsSince(since time.Time) ([]*models.ErrorLog, error) {
+	var logs []*models.ErrorLog
+	result := r.db.Where("timestamp >= ?", since).Order("timestamp ASC").Find(&logs)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query error logs")
+	}
+	return logs, nil
+}
+
+// Clear deletes the user's focus events, state events and notes.
 func (r *Repository) Clear() error {
-	result := r.db.Exec("DELETE FROM focus_events")
+	return r.WithTx(func(tx *Repository) error {
+		result := tx.db.Unscoped().Scopes(tx.own).Delete(&models.FocusEvent{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed to clear focus events")
+		}
+		result = tx.db.Unscoped().Scopes(tx.own).Delete(&models.StateEvent{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed to clear state events")
+		}
+		result = tx.db.Unscoped().Scopes(tx.own).Delete(&models.Note{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed to clear notes")
+		}
+		return nil
+	})
+}
+
+// RecordAudit stores an audit entry, stamping it with the current time if it
+// has none. Audit entries survive Clear.
+func (r *Repository) RecordAudit(entry *models.AuditEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if err := r.db.Create(entry).Error; err != nil {
+		return errors.Wrap(err, "failed to insert audit entry")
+	}
+	return nil
+}
+
+// GetAuditEntries returns the most recent audit entries, newest first.
+func (r *Repository) GetAuditEntries(limit int) ([]*models.AuditEntry, error) {
+	var entries []*models.AuditEntry
+	if err := r.db.Order("timestamp DESC, id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to query audit entries")
+	}
+	return entries, nil
+}
+
+func (r *Repository) CreateToken(token *models.APIToken) error {
+	var count int64
+	if err := r.db.Model(&models.APIToken{}).Where("user_id = ? AND name = ?", token.UserID, token.Name).Count(&count).Error; err != nil {
+		return errors.Wrap(err, "failed to check token name")
+	}
+	if count > 0 {
+		return fmt.Errorf("a token named %q already exists", token.Name)
+	}
+	if err := r.db.Create(token).Error; err != nil {
+		return errors.Wrap(err, "failed to insert token")
+	}
+	return nil
+}
+
+// GetTokenByHash returns the token with the given hash, or nil if there is
+// none or it has expired.
+func (r *Repository) GetTokenByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	result := r.db.Where("hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).Limit(1).Find(&token)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to look up token")
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (r *Repository) GetTokens() ([]*models.APIToken, error) {
+	var tokens []*models.APIToken
+	if err := r.db.Order("name").Find(&tokens).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list tokens")
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes the user's token with the given name, reporting
+// whether it existed.
+func (r *Repository) RevokeToken(user, name string) (bool, error) {
+	result := r.db.Where("user_id = ? AND name = ?", user, name).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "failed to revoke token")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpiredTokens removes tokens that expired before now.
+func (r *Repository) DeleteExpiredTokens(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "failed to delete expired tokens")
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *Repository) TouchToken(id uint, usedAt time.Time) error {
+	result := r.db.Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", usedAt)
 	if result.Error != nil {
-		return errors.Wrap(result.Error, "failed to clear focus events")
+		return errors.Wrap(result.Error, "failed to update token")
 	}
 	return nil
 }
 
-// NormalizeAppNames updates all app_name values to lowercase
-func (r *Repository) NormalizeAppNames() (int64, error) {
-	result := r.db.Exec("UPDATE focus_events SET app_name = LOWER(app_name)")
+// GetShareLevel returns how much of the repository's user's time team
+// reports may include, ShareNone unless they chose otherwise.
+func (r *Repository) GetShareLevel() (string, error) {
+	var sharing models.TeamSharing
+	result := r.db.Where("user_id = ?", r.user).Limit(1).Find(&sharing)
+	if result.Error != nil {
+		return "", errors.Wrap(result.Error, "failed to look up sharing level")
+	}
+	if result.RowsAffected == 0 {
+		return models.ShareNone, nil
+	}
+	return sharing.Level, nil
+}
+
+// SetShareLevel records how much of the repository's user's time team
+// reports may include.
+func (r *Repository) SetShareLevel(level string) error {
+	if _, err := models.ParseShareLevel(level); err != nil {
+		return err
+	}
+	sharing := &models.TeamSharing{UserID: r.user, Level: level}
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "updated_at"}),
+	}).Create(sharing)
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed to save sharing level")
+	}
+	return nil
+}
+
+// GetSharingUsers returns the users who share some of their time with team
+// reports, by user ID. It ignores the repository's user.
+func (r *Repository) GetSharingUsers() ([]*models.TeamSharing, error) {
+	var sharing []*models.TeamSharing
+	if err := r.db.Where("level <> ?", models.ShareNone).Order("user_id").Find(&sharing).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list sharing users")
+	}
+	return sharing, nil
+}
+
+func (r *Repository) CreateNote(note *models.Note) error {
+	if strings.TrimSpace(note.Text) == "" {
+		return errors.New("note text is empty")
+	}
+	if !note.End.After(note.Start) {
+		return errors.New("note must end after it starts")
+	}
+	note.UserID = r.user
+	result := r.db.Create(note)
 	if result.Error != nil {
-		return 0, errors.Wrap(result.Error, "failed to normalize app names")
+		return errors.Wrap(result.Error, "failed to insert note")
+	}
+	return nil
+}
+
+// GetNotes returns notes overlapping [since, until), oldest first.
+func (r *Repository) GetNotes(since, until time.Time) ([]*models.Note, error) {
+	var notes []*models.Note
+	result := r.db.Scopes(r.own).Where("`start` < ? AND `end` > ?", until, since).Order("start ASC").Find(&notes)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to query notes")
+	}
+	return notes, nil
+}
+
+// CountEvents counts the events matching q.
+func (r *Repository) CountEvents(q Query) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.FocusEvent{}).Scopes(r.scope(q)).Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed to count focus events")
+	}
+	return count, nil
+}
+
+// GetEventsAfter pages through events in (timestamp, id) order, returning up
+// to limit events strictly after the given position.
+func (r *Repository) GetEventsAfter(after time.Time, afterID uint, limit int) ([]*models.FocusEvent, error) {
+	var events []*models.FocusEvent
+	result := r.db.Scopes(r.own).Where("timestamp > ? OR (timestamp = ? AND id > ?)", after, after, afterID).
+		Order("timestamp ASC, id ASC").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to page focus events")
+	}
+	return events, nil
+}
+
+func (r *Repository) DeleteEvents(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Scopes(r.own).Delete(&models.FocusEvent{}, ids)
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "failed to delete focus events")
 	}
 	return result.RowsAffected, nil
 }
+
+type AppNameChange struct {
+	From  string
+	To    string
+	Count int64
+}
+
+// AppNameChanges lists stored app names that the normalizer would rewrite,
+// with the number of affected rows. It covers every user's events, since the
+// normalizer rules are the server's.
+func (r *Repository) AppNameChanges() ([]AppNameChange, error) {
+	var rows []struct {
+		AppName string
+		Count   int64
+	}
+	result := r.db.Model(&models.FocusEvent{}).
+		Select("app_name, COUNT(*) as count").
+		Group("app_name").
+		Order("app_name").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to list app names")
+	}
+
+	var changes []AppNameChange
+	for _, row := range rows {
+		if normalized := r.normalizer.Normalize(row.AppName); normalized != row.AppName {
+			changes = append(changes, AppNameChange{From: row.AppName, To: normalized, Count: row.Count})
+		}
+	}
+	return changes, nil
+}
+
+func (r *Repository) NormalizeAppName(name string) string {
+	return r.normalizer.Normalize(name)
+}
+
+// AppIconName returns the icon the .desktop file of the app gives, if one
+// was loaded.
+func (r *Repository) AppIconName(name string) string {
+	return r.normalizer.IconName(name)
+}
+
+// NormalizeAppNames rewrites stored app_name values through the normalizer,
+// for every user's events as AppNameChanges counts them.
+func (r *Repository) NormalizeAppNames() (int64, error) {
+	var names []string
+	if err := r.db.Model(&models.FocusEvent{}).Distinct("app_name").Pluck("app_name", &names).Error; err != nil {
+		return 0, errors.Wrap(err, "failed to list app names")
+	}
+
+	var total int64
+	err := r.WithTx(func(tx *Repository) error {
+		for _, name := range names {
+			normalized := tx.normalizer.Normalize(name)
+			if normalized == name {
+				continue
+			}
+			result := tx.db.Model(&models.FocusEvent{}).Where("app_name = ?", name).Update("app_name", normalized)
+			if result.Error != nil {
+				return errors.Wrapf(result.Error, "failed to normalize app name %q", name)
+			}
+			total += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,536 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func newBenchRepository(b *testing.B) *Repository {
+	b.Helper()
+	db, err := Connect(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("Connect() error = %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		b.Fatalf("Initialize() error = %v", err)
+	}
+	return NewRepository(db)
+}
+
+func benchEvents(start time.Time, n int) []*models.FocusEvent {
+	events := make([]*models.FocusEvent, n)
+	for i := range events {
+		events[i] = &models.FocusEvent{
+			Timestamp:     start.Add(time.Duration(i) * 10 * time.Second),
+			AppName:       fmt.Sprintf("app-%d", i%7),
+			WindowTitle:   "Benchmark",
+			Duration:      10,
+			DisplayServer: "x11",
+		}
+	}
+	return events
+}
+
+func TestCreateBatch(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "batch.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := NewRepository(db)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := repo.Create(&models.FocusEvent{
+		Timestamp: start, AppName: "editor", WindowTitle: "stored", Duration: 60, DisplayServer: "x11",
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	events := benchEvents(start.Add(20*time.Second), 3)
+	events[1].Duration = 30
+	events = append(events, &models.FocusEvent{AppName: "broken", DisplayServer: "x11"})
+	if err := repo.CreateBatch(events); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	stored, err := repo.GetEvents(Query{})
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	want := []int64{20, 10, 10, 10}
+	if len(stored) != len(want) {
+		t.Fatalf("stored %d events, want %d", len(stored), len(want))
+	}
+	for i, event := range stored {
+		if event.Duration != want[i] {
+			t.Errorf("event %d duration = %d, want %d", i, event.Duration, want[i])
+		}
+	}
+}
+
+func TestGetDetectionSummary(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "detection.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := NewRepository(db, WithoutProcessGuesses())
+
+	events := benchEvents(time.Now().Add(-time.Hour).Truncate(time.Second), 4)
+	events[0].Detection = "window"
+	events[1].Detection = "window"
+	events[2].Detection = "window"
+	events[3].Detection = DetectionProcess
+	events[3].Duration = 90
+	if err := repo.CreateBatch(events); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	summaries, err := repo.GetDetectionSummary(Query{})
+	if err != nil {
+		t.Fatalf("GetDetectionSummary() error = %v", err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("got %d methods, want 2: %+v", len(summaries), summaries)
+	}
+	if summaries[0].Method != DetectionProcess || summaries[0].TotalSeconds != 90 || summaries[0].Percentage != 75 {
+		t.Errorf("first method = %+v, want %s with 90s and 75%%", summaries[0], DetectionProcess)
+	}
+	if summaries[1].Method != "window" || summaries[1].EventCount != 3 {
+		t.Errorf("second method = %+v, want window with 3 events", summaries[1])
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	repo := newBenchRepository(b)
+	start := time.Now().AddDate(-1, 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, event := range benchEvents(start.Add(time.Duration(i)*1000*time.Second), 100) {
+			if err := repo.Create(event); err != nil {
+				b.Fatalf("Create() error = %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateBatch(b *testing.B) {
+	repo := newBenchRepository(b)
+	start := time.Now().AddDate(-1, 0, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := repo.CreateBatch(benchEvents(start.Add(time.Duration(i)*1000*time.Second), 100)); err != nil {
+			b.Fatalf("CreateBatch() error = %v", err)
+		}
+	}
+}
+
+func TestForUser(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	owner := NewRepository(db)
+	alice := owner.ForUser("alice")
+
+	// Both users were active at the same time; neither event may shorten
+	// the other.
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := owner.Create(&models.FocusEvent{Timestamp: start, AppName: "editor", WindowTitle: "mine", Duration: 60, DisplayServer: "x11"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := alice.CreateBatch([]*models.FocusEvent{
+		{Timestamp: start.Add(10 * time.Second), AppName: "browser", WindowTitle: "hers", Duration: 60, DisplayServer: "api", UserID: "mallory"},
+	}); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	if err := alice.CreateNote(&models.Note{Text: "standup", Start: start, End: start.Add(time.Minute)}); err != nil {
+		t.Fatalf("CreateNote() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		repo *Repository
+		app  string
+		user string
+	}{
+		{owner, "editor", ""},
+		{alice, "browser", "alice"},
+	} {
+		events, err := tt.repo.GetEvents(Query{})
+		if err != nil {
+			t.Fatalf("GetEvents() error = %v", err)
+		}
+		if len(events) != 1 || events[0].AppName != tt.app || events[0].UserID != tt.user || events[0].Duration != 60 {
+			t.Errorf("GetEvents() for %q = %+v, want the untouched %s event", tt.user, events, tt.app)
+		}
+		if latest, _ := tt.repo.GetLatest(); latest == nil || latest.AppName != tt.app {
+			t.Errorf("GetLatest() for %q = %+v, want %s", tt.user, latest, tt.app)
+		}
+	}
+
+	if notes, _ := owner.GetNotes(start, start.Add(time.Hour)); len(notes) != 0 {
+		t.Errorf("owner sees %d of alice's notes", len(notes))
+	}
+	if notes, _ := alice.GetNotes(start, start.Add(time.Hour)); len(notes) != 1 {
+		t.Errorf("alice sees %d notes, want 1", len(notes))
+	}
+
+	// Writes by ID and bulk deletes leave the other user's rows alone.
+	mine, _ := owner.GetLatest()
+	hers, _ := alice.GetLatest()
+	if _, err := alice.GetByID(mine.ID); err == nil {
+		t.Errorf("alice can read the owner's event #%d", mine.ID)
+	}
+	if err := alice.UpdateDuration(mine.ID, 1); err != nil {
+		t.Fatalf("UpdateDuration() error = %v", err)
+	}
+	if deleted, _ := alice.DeleteEvents([]uint{mine.ID}); deleted != 0 {
+		t.Errorf("alice deleted %d of the owner's events", deleted)
+	}
+	if event, err := owner.GetByID(mine.ID); err != nil || event.Duration != 60 {
+		t.Errorf("owner's event after alice's writes = %+v, %v; want it untouched", event, err)
+	}
+	if deleted, _ := owner.DeleteOldEvents(time.Now()); deleted != 1 {
+		t.Errorf("DeleteOldEvents() as owner deleted %d events, want only its own", deleted)
+	}
+	if err := owner.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if event, err := alice.GetByID(hers.ID); err != nil || event.Duration != 60 {
+		t.Errorf("alice's event after the owner cleared = %+v, %v; want it untouched", event, err)
+	}
+	if notes, _ := alice.GetNotes(start, start.Add(time.Hour)); len(notes) != 1 {
+		t.Errorf("alice has %d notes after the owner cleared, want 1", len(notes))
+	}
+}
+
+func TestUniquePerUser(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "unique.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	// A database from before UUIDs and token names were unique per user.
+	if err := db.Exec("CREATE UNIQUE INDEX idx_focus_events_uuid ON focus_events(uuid)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX idx_api_tokens_name ON api_tokens(name)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() again error = %v", err)
+	}
+
+	owner := NewRepository(db)
+	alice := owner.ForUser("alice")
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	event := func(app string) *models.FocusEvent {
+		return &models.FocusEvent{UUID: "0b6f9d4e-1c1a-4c55-9d0e-2f7c9e1b8a10", Timestamp: start, AppName: app, WindowTitle: app, Duration: 60, DisplayServer: "api"}
+	}
+	if err := owner.Create(event("editor")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := alice.Create(event("browser")); err != nil {
+		t.Fatalf("Create() with another user's UUID error = %v", err)
+	}
+	dup := event("terminal")
+	dup.Timestamp = start.Add(time.Hour)
+	if err := owner.Create(dup); err == nil {
+		t.Error("Create() stored a UUID the user already has")
+	}
+
+	for _, tt := range []struct {
+		repo *Repository
+		app  string
+	}{{owner, "editor"}, {alice, "browser"}} {
+		found, err := tt.repo.GetEventsByUUID([]string{event("").UUID})
+		if err != nil {
+			t.Fatalf("GetEventsByUUID() error = %v", err)
+		}
+		if got := found[event("").UUID]; got == nil || got.AppName != tt.app {
+			t.Errorf("GetEventsByUUID() for %q = %+v, want %s", tt.repo.User(), got, tt.app)
+		}
+	}
+
+	for _, user := range []string{"", "alice"} {
+		if err := owner.CreateToken(&models.APIToken{Name: "laptop", Hash: "hash-" + user, Scopes: "read:events", UserID: user}); err != nil {
+			t.Fatalf("CreateToken() for %q error = %v", user, err)
+		}
+	}
+	if err := owner.CreateToken(&models.APIToken{Name: "laptop", Hash: "hash-again", Scopes: "read:events", UserID: "alice"}); err == nil {
+		t.Error("CreateToken() accepted a name the user already has")
+	}
+	if found, err := owner.RevokeToken("alice", "laptop"); err != nil || !found {
+		t.Fatalf("RevokeToken() = %v, %v", found, err)
+	}
+	if tokens, _ := owner.GetTokens(); len(tokens) != 1 || tokens[0].UserID != "" {
+		t.Errorf("tokens after revoking alice's = %+v, want the owner's", tokens)
+	}
+}
+
+func TestCheckEvent(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		duration  int64
+		wantErr   bool
+		want      int64
+	}{
+		{"valid", now.Add(-time.Hour), 60, false, 60},
+		{"zero timestamp", time.Time{}, 60, true, 0},
+		{"before the epoch", time.Unix(-60, 0), 60, true, 0},
+		{"far future", now.Add(time.Hour), 60, true, 0},
+		{"within clock skew", now.Add(5 * time.Minute), 60, false, 60},
+		{"negative duration", now.Add(-time.Hour), -1, true, 0},
+		{"zero duration", now.Add(-time.Hour), 0, false, 0},
+		{"over the maximum", now.Add(-time.Hour), 3600, false, 600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := Connect(filepath.Join(t.TempDir(), "check.db"))
+			if err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer db.Close()
+			if err := db.Initialize(); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			repo := NewRepository(db, WithMaxEventDuration(10*time.Minute))
+
+			event := &models.FocusEvent{Timestamp: tt.timestamp, AppName: "editor", Duration: tt.duration, DisplayServer: "x11"}
+			err = repo.checkEvent(event)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidEvent) {
+					t.Fatalf("checkEvent() error = %v, want ErrInvalidEvent", err)
+				}
+			} else if err != nil {
+				t.Fatalf("checkEvent() error = %v", err)
+			} else if event.Duration != tt.want {
+				t.Errorf("checkEvent() duration = %d, want %d", event.Duration, tt.want)
+			}
+
+			// Rejections and clamps are logged, valid events are not.
+			logs, err := repo.GetErrorLogsSince(now.Add(-time.Minute))
+			if err != nil {
+				t.Fatalf("GetErrorLogsSince() error = %v", err)
+			}
+			wantLogs := 0
+			if tt.wantErr || tt.want != tt.duration {
+				wantLogs = 1
+			}
+			if len(logs) != wantLogs {
+				t.Errorf("logged %d ingestion errors, want %d", len(logs), wantLogs)
+			}
+		})
+	}
+}
+
+func TestClampToNeighbours(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	tests := []struct {
+		name   string
+		stored []*models.FocusEvent
+		event  *models.FocusEvent
+		want   []int64 // durations of all events by timestamp
+	}{
+		{
+			name:  "no neighbours",
+			event: &models.FocusEvent{Timestamp: at(0), Duration: 60},
+			want:  []int64{60},
+		},
+		{
+			name:   "previous event runs past the new one",
+			stored: []*models.FocusEvent{{Timestamp: at(0), Duration: 120}},
+			event:  &models.FocusEvent{Timestamp: at(30), Duration: 60},
+			want:   []int64{30, 60},
+		},
+		{
+			name:   "new event runs past the next one",
+			stored: []*models.FocusEvent{{Timestamp: at(60), Duration: 60}},
+			event:  &models.FocusEvent{Timestamp: at(0), Duration: 120},
+			want:   []int64{60, 60},
+		},
+		{
+			name:   "inserted between overlapping neighbours",
+			stored: []*models.FocusEvent{{Timestamp: at(0), Duration: 60}, {Timestamp: at(60), Duration: 60}},
+			event:  &models.FocusEvent{Timestamp: at(40), Duration: 60},
+			want:   []int64{40, 20, 60},
+		},
+		{
+			name:   "same timestamp as a stored event",
+			stored: []*models.FocusEvent{{Timestamp: at(0), Duration: 60}},
+			event:  &models.FocusEvent{Timestamp: at(0), Duration: 60},
+			want:   []int64{0, 60},
+		},
+		{
+			name:   "overlap within tolerance",
+			stored: []*models.FocusEvent{{Timestamp: at(0), Duration: 61}, {Timestamp: at(121), Duration: 60}},
+			event:  &models.FocusEvent{Timestamp: at(60), Duration: 62},
+			want:   []int64{61, 62, 60},
+		},
+		{
+			name:   "another user's events",
+			stored: []*models.FocusEvent{{Timestamp: at(0), Duration: 120, UserID: "alice"}},
+			event:  &models.FocusEvent{Timestamp: at(30), Duration: 60},
+			want:   []int64{120, 60},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := Connect(filepath.Join(t.TempDir(), "clamp.db"))
+			if err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer db.Close()
+			if err := db.Initialize(); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			repo := NewRepository(db)
+
+			for i, event := range append(tt.stored, tt.event) {
+				event.AppName = fmt.Sprintf("app-%d", i)
+				event.DisplayServer = "x11"
+			}
+			if len(tt.stored) > 0 {
+				if err := db.Create(tt.stored).Error; err != nil {
+					t.Fatalf("inserting stored events: %v", err)
+				}
+			}
+			if err := repo.Create(tt.event); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			var stored []*models.FocusEvent
+			if err := db.Order("timestamp, id").Find(&stored).Error; err != nil {
+				t.Fatalf("reading events: %v", err)
+			}
+			var got []int64
+			for _, event := range stored {
+				got = append(got, event.Duration)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("durations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "tx.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := NewRepository(db)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	stored := &models.FocusEvent{Timestamp: start, AppName: "editor", Duration: 60, DisplayServer: "x11"}
+	if err := repo.Create(stored); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	failure := errors.New("failed")
+	at := func(seconds int) *models.FocusEvent {
+		return &models.FocusEvent{Timestamp: start.Add(time.Duration(seconds) * time.Second), AppName: "browser", Duration: 10, DisplayServer: "x11"}
+	}
+	// trimAndInsert makes the two writes of a focus change.
+	trimAndInsert := func(tx *Repository, seconds int) error {
+		if err := tx.UpdateDuration(stored.ID, int64(seconds)); err != nil {
+			return err
+		}
+		return tx.Create(at(seconds))
+	}
+
+	// The cases run in order against the same database.
+	tests := []struct {
+		name     string
+		fn       func(tx *Repository) error
+		wantErr  error
+		duration int64
+		count    int64
+	}{
+		{
+			name:     "error rolls back both writes",
+			fn:       func(tx *Repository) error { trimAndInsert(tx, 30); return failure },
+			wantErr:  failure,
+			duration: 60,
+			count:    1,
+		},
+		{
+			name: "failed savepoint rolls back only its writes",
+			fn: func(tx *Repository) error {
+				if err := trimAndInsert(tx, 40); err != nil {
+					return err
+				}
+				if err := tx.WithTx(func(nested *Repository) error { nested.Create(at(50)); return failure }); err != failure {
+					t.Errorf("nested WithTx() error = %v, want %v", err, failure)
+				}
+				return nil
+			},
+			duration: 40,
+			count:    2,
+		},
+		{
+			name: "failed savepoint fails the transaction it is returned from",
+			fn: func(tx *Repository) error {
+				if err := trimAndInsert(tx, 20); err != nil {
+					return err
+				}
+				return tx.WithTx(func(nested *Repository) error { return failure })
+			},
+			wantErr:  failure,
+			duration: 40,
+			count:    2,
+		},
+		{
+			name: "nested writes commit with the outer transaction",
+			fn: func(tx *Repository) error {
+				return tx.WithTx(func(nested *Repository) error { return trimAndInsert(nested, 45) })
+			},
+			duration: 45,
+			count:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.WithTx(tt.fn); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			event, err := repo.GetByID(stored.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			count, err := repo.CountEvents(Query{})
+			if err != nil {
+				t.Fatalf("CountEvents() error = %v", err)
+			}
+			if event.Duration != tt.duration || count != tt.count {
+				t.Errorf("after WithTx() duration = %d and %d events, want %d and %d", event.Duration, count, tt.duration, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion identifies the layout of the tables created by Initialize.
+// Bump it whenever a model gains, loses or changes a column or index.
+const SchemaVersion = 13
+
+// Schema describes the tables and indexes in the database file.
+type Schema struct {
+	Version       int             `json:"version"`
+	SQLiteVersion string          `json:"sqlite_version"`
+	Tables        []TableSchema   `json:"tables"`
+	Triggers      []TriggerSchema `json:"triggers"`
+}
+
+type TableSchema struct {
+	Name    string         `json:"name"`
+	Columns []ColumnSchema `json:"columns"`
+	Indexes []IndexSchema  `json:"indexes"`
+	SQL     string         `json:"sql"`
+}
+
+type TriggerSchema struct {
+	Name  string `json:"name"`
+	Table string `json:"table"`
+	SQL   string `json:"sql"`
+}
+
+type ColumnSchema struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	NotNull    bool    `json:"not_null"`
+	Default    *string `json:"default,omitempty"`
+	PrimaryKey bool    `json:"primary_key"`
+}
+
+type IndexSchema struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+	SQL     string   `json:"sql,omitempty"`
+}
+
+// Schema reads the effective schema from SQLite's catalog rather than from
+// the models, so it reflects what is actually in the file.
+func (db *DB) Schema() (*Schema, error) {
+	schema := &Schema{Version: SchemaVersion}
+	if err := db.Raw("SELECT sqlite_version()").Scan(&schema.SQLiteVersion).Error; err != nil {
+		return nil, fmt.Errorf("failed to read SQLite version: %w", err)
+	}
+
+	var tables []struct {
+		Name string
+		SQL  string
+	}
+	err := db.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name").
+		Scan(&tables).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	for _, t := range tables {
+		// The search index's own tables are managed by SQLite.
+		if isSearchTable(t.Name) && t.Name != searchTable {
+			continue
+		}
+		table := TableSchema{Name: t.Name, SQL: t.SQL}
+		if table.Columns, err = db.columns(t.Name); err != nil {
+			return nil, err
+		}
+		if table.Indexes, err = db.indexes(t.Name); err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	err = db.Raw("SELECT name, tbl_name AS \"table\", sql FROM sqlite_master WHERE type = 'trigger' ORDER BY name").
+		Scan(&schema.Triggers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list triggers: %w", err)
+	}
+	return schema, nil
+}
+
+func (db *DB) columns(table string) ([]ColumnSchema, error) {
+	var rows []struct {
+		Name      string
+		Type      string
+		NotNull   bool    `gorm:"column:notnull"`
+		DfltValue *string `gorm:"column:dflt_value"`
+		PK        int     `gorm:"column:pk"`
+	}
+	if err := db.Raw("SELECT name, type, \"notnull\", dflt_value, pk FROM pragma_table_info(?)", table).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	columns := make([]ColumnSchema, 0, len(rows))
+	for _, row := range rows {
+		columns = append(columns, ColumnSchema{
+			Name:       row.Name,
+			Type:       row.Type,
+			NotNull:    row.NotNull,
+			Default:    row.DfltValue,
+			PrimaryKey: row.PK > 0,
+		})
+	}
+	return columns, nil
+}
+
+func (db *DB) indexes(table string) ([]IndexSchema, error) {
+	var list []struct {
+		Name   string
+		Unique bool
+	}
+	if err := db.Raw("SELECT name, \"unique\" FROM pragma_index_list(?) ORDER BY name", table).Scan(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+
+	indexes := make([]IndexSchema, 0, len(list))
+	for _, entry := range list {
+		index := IndexSchema{Name: entry.Name, Unique: entry.Unique}
+		if err := db.Raw("SELECT name FROM pragma_index_info(?) ORDER BY seqno", entry.Name).Scan(&index.Columns).Error; err != nil {
+			return nil, fmt.Errorf("failed to read index %s: %w", entry.Name, err)
+		}
+		// Indexes SQLite creates for UNIQUE or PRIMARY KEY constraints
+		// have no SQL of their own.
+		var sql *string
+		if err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?", entry.Name).Scan(&sql).Error; err != nil {
+			return nil, fmt.Errorf("failed to read index %s: %w", entry.Name, err)
+		}
+		if sql != nil {
+			index.SQL = *sql
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// Schema describes the database the repository reads from.
+func (r *Repository) Schema() (*Schema, error) {
+	return r.db.Schema()
+}
+
+// SQL renders the schema as statements that recreate it in an empty
+// database.
+func (s *Schema) SQL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- actionsum schema version %d (SQLite %s)\n", s.Version, s.SQLiteVersion)
+	fmt.Fprintf(&b, "PRAGMA user_version = %d;\n", s.Version)
+	for _, table := range s.Tables {
+		fmt.Fprintf(&b, "\n%s;\n", table.SQL)
+		for _, index := range table.Indexes {
+			if index.SQL != "" {
+				fmt.Fprintf(&b, "%s;\n", index.SQL)
+			}
+		}
+	}
+	for _, trigger := range s.Triggers {
+		fmt.Fprintf(&b, "\n%s;\n", trigger.SQL)
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/actionsum/actionsum/internal/models"
+
+	"github.com/pkg/errors"
+)
+
+// searchTable is the full-text index of event app names and window titles.
+// It is an FTS4 table over focus_events' own rows, kept current by triggers.
+const searchTable = "focus_events_fts"
+
+var searchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS ` + searchTable + ` USING fts4(content="focus_events", app_name, window_title, tokenize=unicode61)`,
+	`CREATE TRIGGER IF NOT EXISTS focus_events_fts_bu BEFORE UPDATE OF app_name, window_title ON focus_events BEGIN
+		DELETE FROM ` + searchTable + ` WHERE docid = old.rowid;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS focus_events_fts_bd BEFORE DELETE ON focus_events BEGIN
+		DELETE FROM ` + searchTable + ` WHERE docid = old.rowid;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS focus_events_fts_au AFTER UPDATE OF app_name, window_title ON focus_events BEGIN
+		INSERT INTO ` + searchTable + `(docid, app_name, window_title) VALUES (new.rowid, new.app_name, new.window_title);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS focus_events_fts_ai AFTER INSERT ON focus_events BEGIN
+		INSERT INTO ` + searchTable + `(docid, app_name, window_title) VALUES (new.rowid, new.app_name, new.window_title);
+	END`,
+}
+
+// initSearch creates the search index and its triggers. When any of them was
+// missing, such as in a database from an older version or after a migration
+// rebuilt focus_events, the index is rebuilt from the events.
+func (db *DB) initSearch() error {
+	var existing int64
+	err := db.Raw(`SELECT COUNT(*) FROM sqlite_master WHERE (type = 'table' AND name = ?) OR (type = 'trigger' AND name LIKE ?)`,
+		searchTable, searchTable+"_%").Scan(&existing).Error
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %w", err)
+	}
+	if existing == int64(len(searchSchema)) {
+		return nil
+	}
+
+	for _, stmt := range searchSchema {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+	}
+	if err := db.Exec(`INSERT INTO ` + searchTable + `(` + searchTable + `) VALUES ('rebuild')`).Error; err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	return nil
+}
+
+// isSearchTable reports whether table is the search index or one of the
+// tables SQLite keeps it in, which are derived from focus_events rather than
+// holding data of their own.
+func isSearchTable(table string) bool {
+	return table == searchTable || strings.HasPrefix(table, searchTable+"_")
+}
+
+// SearchTerms splits text into the words a search matches, dropping
+// punctuation so it cannot be read as query syntax.
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Search returns the events in q whose app name or window title contains
+// words starting with each of the terms in text, newest first.
+func (r *Repository) Search(q Query, text string, limit int) ([]*models.FocusEvent, error) {
+	terms := SearchTerms(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `*"`
+	}
+
+	var events []*models.FocusEvent
+	result := r.db.Model(&models.FocusEvent{}).
+		Select("focus_events.*").
+		Joins("JOIN "+searchTable+" ON "+searchTable+".docid = focus_events.id").
+		Where(searchTable+" MATCH ?", strings.Join(match, " ")).
+		Scopes(r.scope(q)).
+		Order("timestamp DESC").
+		Limit(limit).
+		Find(&events)
+
+	if result.Error != nil {
+		return nil, errors.Wrap(result.Error, "failed to search focus events")
+	}
+
+	return events, nil
+}
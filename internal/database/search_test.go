@@ -0,0 +1,128 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestSearch(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := NewRepository(db)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	titles := []string{"Quarterly report.pdf - Évince", "main.go - actionsum", "Inbox (3) - Mail"}
+	events := make([]*models.FocusEvent, len(titles))
+	for i, title := range titles {
+		events[i] = &models.FocusEvent{
+			Timestamp: start.Add(time.Duration(i) * time.Minute), AppName: "app", WindowTitle: title, Duration: 60, DisplayServer: "x11",
+		}
+	}
+	if err := repo.CreateBatch(events); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	search := func(text string) []string {
+		t.Helper()
+		found, err := repo.Search(Query{}, text, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", text, err)
+		}
+		var got []string
+		for _, event := range found {
+			got = append(got, event.WindowTitle)
+		}
+		return got
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"rep", []string{titles[0]}},
+		{"QUARTERLY evince", []string{titles[0]}},
+		{"report mail", nil},
+		{`"inbox (`, []string{titles[2]}},
+		{"app", []string{titles[2], titles[1], titles[0]}},
+		{"   ", nil},
+	}
+	for _, tt := range tests {
+		if got := search(tt.text); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Search(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	// Changes to events are reflected in the index.
+	if err := db.Model(events[1]).Update("window_title", "notes.txt").Error; err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := search("main"); got != nil {
+		t.Errorf("Search(main) after rename = %q, want nothing", got)
+	}
+	if got := search("notes"); len(got) != 1 {
+		t.Errorf("Search(notes) after rename = %q, want the renamed event", got)
+	}
+	if err := db.Delete(events[0]).Error; err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := search("quarterly"); got != nil {
+		t.Errorf("Search(quarterly) after delete = %q, want nothing", got)
+	}
+
+	// A database without the index, as from an older version, gets it
+	// built from its events.
+	for _, stmt := range []string{"DROP TRIGGER focus_events_fts_ai", "DROP TABLE " + searchTable} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if got := search("inbox"); len(got) != 1 {
+		t.Errorf("Search(inbox) after rebuild = %q, want one event", got)
+	}
+}
+
+func TestSchemaSQLRecreatesSearch(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "source.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	schema, err := db.Schema()
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+
+	empty, err := Connect(filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer empty.Close()
+	sqlDB, _ := empty.DB.DB()
+	if _, err := sqlDB.Exec(schema.SQL()); err != nil {
+		t.Fatalf("schema SQL failed: %v", err)
+	}
+
+	repo := NewRepository(empty)
+	if err := repo.Create(&models.FocusEvent{Timestamp: time.Now(), AppName: "editor", WindowTitle: "draft", Duration: 10, DisplayServer: "x11"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if found, err := repo.Search(Query{}, "draft", 10); err != nil || len(found) != 1 {
+		t.Errorf("Search() = %d events, %v; want the event", len(found), err)
+	}
+}
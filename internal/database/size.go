@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// SizeInfo is the on-disk size of the database file and its write-ahead log.
+type SizeInfo struct {
+	DatabaseBytes int64 `json:"database_bytes"`
+	WALBytes      int64 `json:"wal_bytes"`
+}
+
+// FileSizes measures the database at path, or at the default location when
+// path is empty. A missing WAL file counts as empty.
+func FileSizes(path string) (SizeInfo, error) {
+	if path == "" {
+		var err error
+		if path, err = GetDefaultDBPath(); err != nil {
+			return SizeInfo{}, err
+		}
+	}
+
+	var info SizeInfo
+	stat, err := os.Stat(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to stat database: %w", err)
+	}
+	info.DatabaseBytes = stat.Size()
+
+	if stat, err := os.Stat(path + "-wal"); err == nil {
+		info.WALBytes = stat.Size()
+	}
+	return info, nil
+}
+
+// Warning returns advice once the database and WAL together exceed limit
+// bytes, or an empty string. A limit of zero disables the warning.
+func (s SizeInfo) Warning(limit int64) string {
+	total := s.DatabaseBytes + s.WALBytes
+	if limit <= 0 || total <= limit {
+		return ""
+	}
+	return fmt.Sprintf("database is %s, over the %s limit; consider removing old history or raising ACTIONSUM_DB_SIZE_WARN",
+		utils.FormatBytes(total), utils.FormatBytes(limit))
+}
+
+func (db *DB) Path() string {
+	return db.path
+}
+
+// Checkpoint copies the write-ahead log into the database and truncates it.
+func (db *DB) Checkpoint() error {
+	if err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Snapshot is a read-only copy of the database, refreshed periodically, that
+// report queries can run against without contending with the tracker's
+// writes.
+type Snapshot struct {
+	source *DB
+	repo   *Repository
+	path   string
+
+	mu      sync.RWMutex
+	current *Repository
+	takenAt time.Time
+
+	// previous stays open for one more refresh so queries that started on
+	// it can finish.
+	previous *DB
+	db       *DB
+}
+
+// NewSnapshot prepares a snapshot of source next to the database file. The
+// repository returned by Current shares repo's options.
+func NewSnapshot(source *DB, repo *Repository) *Snapshot {
+	return &Snapshot{
+		source: source,
+		repo:   repo,
+		path:   source.path + ".snapshot",
+	}
+}
+
+// Run refreshes the snapshot immediately and then every interval until ctx
+// is cancelled. Failed refreshes are logged and the last good copy is kept.
+func (s *Snapshot) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(); err != nil {
+			log.Printf("Failed to refresh report snapshot: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh copies the live database to the snapshot file and switches
+// queries over to it.
+func (s *Snapshot) Refresh() error {
+	tmp := s.path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale snapshot: %w", err)
+	}
+
+	takenAt := time.Now()
+	if err := s.source.Exec("VACUUM INTO ?", tmp).Error; err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	conn, err := gorm.Open(sqlite.Open("file:"+s.path+"?mode=ro"), &gorm.Config{
+		Logger: s.source.Config.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	db := &DB{DB: conn, path: s.path}
+
+	s.mu.Lock()
+	stale := s.previous
+	s.previous, s.db = s.db, db
+	s.current = s.repo.Using(db)
+	s.takenAt = takenAt
+	s.mu.Unlock()
+
+	if stale != nil {
+		stale.Close()
+	}
+	return nil
+}
+
+// Current returns a repository reading from the latest snapshot and when it
+// was taken, or nil before the first refresh succeeds.
+func (s *Snapshot) Current() (*Repository, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current, s.takenAt
+}
+
+// Close closes the snapshot connections and removes the snapshot file.
+func (s *Snapshot) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, db := range []*DB{s.previous, s.db} {
+		if db != nil {
+			db.Close()
+		}
+	}
+	s.previous, s.db, s.current = nil, nil, nil
+	return os.Remove(s.path)
+}
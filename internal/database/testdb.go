@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// TestingT is the part of testing.TB that OpenTest uses, so that the package
+// doesn't import testing.
+type TestingT interface {
+	Helper()
+	Name() string
+	Fatalf(format string, args ...any)
+	Cleanup(func())
+}
+
+var testDatabases atomic.Int64
+
+// OpenTest connects to a fresh, initialized in-memory database for a test
+// and closes it when the test ends. Each call opens a database of its own.
+func OpenTest(t TestingT) *DB {
+	t.Helper()
+	name := fmt.Sprintf("file:%s-%d?mode=memory&cache=shared", t.Name(), testDatabases.Add(1))
+	db, err := Connect(name)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return db
+}
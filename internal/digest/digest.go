@@ -0,0 +1,231 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/budget"
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/pkg/schedule"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+const (
+	topAppCount = 5
+
+	checkInterval = time.Hour
+	weekLayout    = "2006-01-02"
+)
+
+type Digest struct {
+	WeekStart       time.Time           `json:"week_start"`
+	WeekEnd         time.Time           `json:"week_end"`
+	TotalSeconds    int64               `json:"total_seconds"`
+	PreviousSeconds int64               `json:"previous_seconds"`
+	WorkDays        int                 `json:"work_days"`
+	DaysOff         int                 `json:"days_off,omitempty"`
+	PreviousDaysOff int                 `json:"previous_days_off,omitempty"`
+	TopApps         []models.AppSummary `json:"top_apps"`
+	Budgets         []budget.Outcome    `json:"budgets,omitempty"`
+}
+
+// WeekStart returns midnight on the Monday of t's week.
+func WeekStart(t time.Time) time.Time {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, -(weekday - 1))
+}
+
+// Build summarizes the week starting at weekStart and compares it with the
+// week before, along with how often each budget was exceeded. Only days
+// off on the working days of workHours count; a holiday on a weekend
+// doesn't change the comparison.
+func Build(repo *database.Repository, weekStart time.Time, budgets []budget.Budget, daysOff holiday.Days, workHours []schedule.Window) (*Digest, error) {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	apps, err := repo.GetAppSummary(database.Query{Since: weekStart, Until: weekEnd})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize week: %w", err)
+	}
+	previous, err := repo.GetAppSummary(database.Query{Since: weekStart.AddDate(0, 0, -7), Until: weekStart})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize previous week: %w", err)
+	}
+
+	workDays := schedule.WorkDays(workHours)
+	daysOff = daysOff.On(workDays)
+	d := &Digest{
+		WeekStart:       weekStart,
+		WeekEnd:         weekEnd,
+		DaysOff:         daysOff.Between(weekStart, weekEnd),
+		PreviousDaysOff: daysOff.Between(weekStart.AddDate(0, 0, -7), weekStart),
+	}
+	for _, ok := range workDays {
+		if ok {
+			d.WorkDays++
+		}
+	}
+	for _, app := range apps {
+		d.TotalSeconds += app.TotalSeconds
+	}
+	for _, app := range previous {
+		d.PreviousSeconds += app.TotalSeconds
+	}
+	for i := range apps {
+		if d.TotalSeconds > 0 {
+			apps[i].Percentage = float64(apps[i].TotalSeconds) / float64(d.TotalSeconds) * 100
+		}
+	}
+	if len(apps) > topAppCount {
+		apps = apps[:topAppCount]
+	}
+	d.TopApps = apps
+
+	if len(budgets) > 0 {
+		if d.Budgets, err = budget.WeekOutcomes(repo, budgets, weekStart); err != nil {
+			return nil, fmt.Errorf("failed to check budgets: %w", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *Digest) Title() string {
+	return fmt.Sprintf("actionsum weekly digest: %s – %s",
+		d.WeekStart.Format("Jan 2"), d.WeekEnd.AddDate(0, 0, -1).Format("Jan 2"))
+}
+
+// Change is the percent change in active time from the previous week. When
+// the weeks had different numbers of days off it compares the time per
+// working day, and perWorkDay is set. ok is false when there's no time in
+// the previous week to compare with.
+func (d *Digest) Change() (percent float64, perWorkDay, ok bool) {
+	if d.PreviousSeconds == 0 {
+		return 0, false, false
+	}
+	current, previous := float64(d.TotalSeconds), float64(d.PreviousSeconds)
+	if d.DaysOff != d.PreviousDaysOff && d.DaysOff < d.WorkDays && d.PreviousDaysOff < d.WorkDays {
+		current /= float64(d.WorkDays - d.DaysOff)
+		previous /= float64(d.WorkDays - d.PreviousDaysOff)
+		perWorkDay = true
+	}
+	return (current - previous) / previous * 100, perWorkDay, true
+}
+
+func (d *Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Active time: %s", utils.FormatHoursMinutes(d.TotalSeconds))
+	if change, perWorkDay, ok := d.Change(); ok {
+		if perWorkDay {
+			fmt.Fprintf(&b, " (%+.0f%% per working day vs. previous week)", change)
+		} else {
+			fmt.Fprintf(&b, " (%+.0f%% vs. previous week)", change)
+		}
+	}
+	b.WriteString("\n")
+	if d.DaysOff > 0 {
+		fmt.Fprintf(&b, "Days off: %d\n", d.DaysOff)
+	}
+
+	if len(d.TopApps) > 0 {
+		b.WriteString("\nTop apps:\n")
+		for i, app := range d.TopApps {
+			fmt.Fprintf(&b, "%d. %s  %s (%.0f%%)\n", i+1, app.AppName,
+				utils.FormatHoursMinutes(app.TotalSeconds), app.Percentage)
+		}
+	}
+
+	if len(d.Budgets) > 0 {
+		b.WriteString("\nBudgets:\n")
+		for _, outcome := range d.Budgets {
+			fmt.Fprintf(&b, "- %s (%v/day): exceeded on %d of 7 days\n", outcome.Name, outcome.Limit, outcome.DaysOver)
+		}
+	}
+	return b.String()
+}
+
+// Message is the digest as a notification, with the PDF layout attached
+// when withPDF is set.
+func (d *Digest) Message(withPDF bool) notify.Message {
+	msg := notify.Message{Title: d.Title(), Body: d.Text(), Data: d}
+	if withPDF {
+		msg.Attachments = []notify.Attachment{{
+			Name:        "actionsum-week-" + d.WeekStart.Format(weekLayout) + ".pdf",
+			ContentType: "application/pdf",
+			Data:        d.PDF(time.Now()),
+		}}
+	}
+	return msg
+}
+
+// Scheduler sends the previous week's digest once per week after it ends,
+// remembering delivered weeks in the state event log so restarts don't
+// resend them.
+type Scheduler struct {
+	repo      *database.Repository
+	notifier  notify.Notifier
+	budgets   []budget.Budget
+	holidays  config.HolidayConfig
+	workHours []schedule.Window
+	withPDF   bool
+}
+
+func NewScheduler(repo *database.Repository, notifier notify.Notifier, budgets []budget.Budget, holidays config.HolidayConfig, workHours []schedule.Window, withPDF bool) *Scheduler {
+	return &Scheduler{repo: repo, notifier: notifier, budgets: budgets, holidays: holidays, workHours: workHours, withPDF: withPDF}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sendDue(time.Now()); err != nil {
+			log.Printf("Weekly digest: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) sendDue(now time.Time) error {
+	weekStart := WeekStart(now).AddDate(0, 0, -7)
+	week := weekStart.Format(weekLayout)
+
+	last, err := s.repo.GetLastStateEventBefore(now.Add(time.Minute), models.StateDigestSent)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.Detail >= week {
+		return nil
+	}
+
+	d, err := Build(s.repo, weekStart, s.budgets, holiday.Load(s.holidays), s.workHours)
+	if err != nil {
+		return err
+	}
+	if d.TotalSeconds == 0 {
+		return nil
+	}
+	if err := s.notifier.Notify(d.Message(s.withPDF)); err != nil {
+		return fmt.Errorf("failed to deliver: %w", err)
+	}
+
+	log.Printf("Sent weekly digest for week of %s", week)
+	return s.repo.CreateStateEvent(&models.StateEvent{
+		Timestamp: now,
+		Type:      models.StateDigestSent,
+		Source:    "daemon",
+		Detail:    week,
+	})
+}
@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/schedule"
+)
+
+func TestBuild(t *testing.T) {
+	repo := database.NewRepository(database.OpenTest(t))
+	weekStart := time.Date(2025, 3, 3, 0, 0, 0, 0, time.Local) // Monday
+	minutes := map[string]int64{"code": 60, "firefox": 50, "slack": 40, "kitty": 30, "gimp": 20, "zoom": 10}
+	day := 0
+	for app, m := range minutes {
+		for _, at := range []time.Time{weekStart.AddDate(0, 0, day), weekStart.AddDate(0, 0, day-7)} {
+			event := &models.FocusEvent{Timestamp: at.Add(10 * time.Hour), AppName: app, Duration: m * 60, DisplayServer: "wayland"}
+			if err := repo.Create(event); err != nil {
+				t.Fatal(err)
+			}
+		}
+		day++
+	}
+	// Outside both weeks.
+	if err := repo.Create(&models.FocusEvent{Timestamp: weekStart.AddDate(0, 0, 7), AppName: "code", Duration: 600, DisplayServer: "wayland"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Build(repo, weekStart, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if d.TotalSeconds != 210*60 || d.PreviousSeconds != 210*60 {
+		t.Errorf("seconds %d vs %d, want %d each", d.TotalSeconds, d.PreviousSeconds, 210*60)
+	}
+	if !d.WeekEnd.Equal(weekStart.AddDate(0, 0, 7)) || d.WorkDays != 7 {
+		t.Errorf("WeekEnd %v, WorkDays %d", d.WeekEnd, d.WorkDays)
+	}
+	var names []string
+	for _, app := range d.TopApps {
+		names = append(names, app.AppName)
+	}
+	if fmt.Sprint(names) != "[code firefox slack kitty gimp]" {
+		t.Errorf("TopApps = %v, want the five longest", names)
+	}
+	if p := d.TopApps[0].Percentage; p < 28.5 || p > 28.6 {
+		t.Errorf("code percentage = %.2f, want 28.57", p)
+	}
+
+	text := d.Text()
+	for _, want := range []string{
+		"Active time: 3h30m (+0% vs. previous week)\n",
+		"\nTop apps:\n1. code  1h (29%)\n",
+		"5. gimp  20m (10%)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, missing %q", text, want)
+		}
+	}
+	if strings.Contains(text, "zoom") || strings.Contains(text, "Days off") || strings.Contains(text, "Budgets") {
+		t.Errorf("Text() = %q, want only the top apps and no days off or budgets", text)
+	}
+	if title := d.Title(); title != "actionsum weekly digest: Mar 3 – Mar 9" {
+		t.Errorf("Title() = %q", title)
+	}
+}
+
+func TestTextDaysOff(t *testing.T) {
+	d := &Digest{TotalSeconds: 4 * 3600, PreviousSeconds: 5 * 3600, WorkDays: 5, DaysOff: 1}
+	text := d.Text()
+	if !strings.HasPrefix(text, "Active time: 4h (+0% per working day vs. previous week)\nDays off: 1\n") {
+		t.Errorf("Text() = %q", text)
+	}
+	if text := (&Digest{TotalSeconds: 60}).Text(); text != "Active time: 1m\n" {
+		t.Errorf("Text() without a previous week = %q", text)
+	}
+}
+
+func TestBuildDaysOff(t *testing.T) {
+	repo := database.NewRepository(database.OpenTest(t))
+	weekStart := time.Date(2025, 12, 22, 0, 0, 0, 0, time.Local) // Monday
+	// An hour on each weekday of this week and the previous one, except
+	// for Christmas, a Thursday.
+	for day := weekStart.AddDate(0, 0, -7); day.Before(weekStart.AddDate(0, 0, 7)); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || day.Day() == 25 {
+			continue
+		}
+		event := &models.FocusEvent{Timestamp: day.Add(10 * time.Hour), AppName: "code", Duration: 3600, DisplayServer: "wayland"}
+		if err := repo.Create(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	weekdays, err := schedule.ParseList("mon-fri 09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		daysOff    holiday.Days
+		workHours  []schedule.Window
+		wantOff    int
+		perWorkDay bool
+		wantChange float64
+	}{
+		{"none", nil, weekdays, 0, false, -20},
+		{"on a weekend", holiday.Days{"2025-12-27": {Date: "2025-12-27", Kind: holiday.Holiday}}, weekdays, 0, false, -20},
+		{"on a work day", holiday.Days{"2025-12-25": {Date: "2025-12-25", Kind: holiday.Holiday}}, weekdays, 1, true, 0},
+		{"every day works", holiday.Days{"2025-12-25": {Date: "2025-12-25", Kind: holiday.Holiday}}, nil, 1, true, -7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Build(repo, weekStart, nil, tt.daysOff, tt.workHours)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if d.TotalSeconds != 4*3600 || d.PreviousSeconds != 5*3600 {
+				t.Errorf("seconds %d vs %d, want %d vs %d", d.TotalSeconds, d.PreviousSeconds, 4*3600, 5*3600)
+			}
+			if d.DaysOff != tt.wantOff {
+				t.Errorf("DaysOff = %d, want %d", d.DaysOff, tt.wantOff)
+			}
+			change, perWorkDay, ok := d.Change()
+			if !ok || perWorkDay != tt.perWorkDay || fmt.Sprintf("%.0f", change) != fmt.Sprintf("%.0f", tt.wantChange) {
+				t.Errorf("Change() = %.1f, %v, %v; want %.0f, %v, true", change, perWorkDay, ok, tt.wantChange, tt.perWorkDay)
+			}
+		})
+	}
+}
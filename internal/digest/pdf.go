@@ -0,0 +1,103 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/pdf"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// Page layout, in points.
+const (
+	margin    = 56.0
+	rowHeight = 26.0
+	barHeight = 8.0
+	nameWidth = 170.0
+)
+
+var (
+	textColor   = pdf.Gray(0.13)
+	mutedColor  = pdf.Gray(0.45)
+	trackColor  = pdf.Gray(0.92)
+	barColor    = pdf.Color{R: 0.23, G: 0.47, B: 0.85}
+	overColor   = pdf.Color{R: 0.86, G: 0.3, B: 0.26}
+	withinColor = pdf.Color{R: 0.3, G: 0.69, B: 0.42}
+)
+
+// PDF lays the digest out as a printable A4 page: the active time and its
+// change, a bar per top app, and how many days each budget was exceeded.
+func (d *Digest) PDF(now time.Time) []byte {
+	doc := &pdf.Document{Title: d.Title(), Created: now}
+	p := doc.AddPage()
+	right := pdf.PageWidth - margin
+	y := pdf.PageHeight - margin - 20
+
+	p.Text(margin, y, 20, true, textColor, "Weekly digest")
+	y -= 20
+	lastDay := d.WeekEnd.AddDate(0, 0, -1)
+	p.Text(margin, y, 11, false, mutedColor, d.WeekStart.Format("Monday, January 2")+" – "+lastDay.Format("Monday, January 2, 2006"))
+	y -= 14
+	p.Line(margin, y, right, y, 0.5, trackColor)
+
+	y -= 44
+	p.Text(margin, y, 28, true, textColor, utils.FormatHoursMinutes(d.TotalSeconds))
+	y -= 18
+	change := "active time"
+	if percent, perWorkDay, ok := d.Change(); ok {
+		vs := "vs."
+		if perWorkDay {
+			vs = "per working day vs."
+		}
+		change = fmt.Sprintf("active time, %+.0f%% %s the previous week (%s)", percent, vs, utils.FormatHoursMinutes(d.PreviousSeconds))
+	}
+	if d.DaysOff == 1 {
+		change += ", 1 day off"
+	} else if d.DaysOff > 1 {
+		change += fmt.Sprintf(", %d days off", d.DaysOff)
+	}
+	p.Text(margin, y, 11, false, mutedColor, change)
+
+	if len(d.TopApps) > 0 {
+		y -= 44
+		p.Text(margin, y, 13, true, textColor, "Top apps")
+		y -= 8
+		// Bars are relative to the top app, so the longest fills the track.
+		longest := d.TopApps[0].TotalSeconds
+		trackStart, trackEnd := margin+nameWidth, right-110
+		for i, app := range d.TopApps {
+			y -= rowHeight
+			name := pdf.Truncate(fmt.Sprintf("%d. %s", i+1, app.AppName), nameWidth-12, 11, false)
+			p.Text(margin, y, 11, false, textColor, name)
+			p.Rect(trackStart, y-1, trackEnd-trackStart, barHeight, trackColor)
+			if longest > 0 {
+				p.Rect(trackStart, y-1, (trackEnd-trackStart)*float64(app.TotalSeconds)/float64(longest), barHeight, barColor)
+			}
+			p.TextRight(right-44, y, 11, true, textColor, utils.FormatHoursMinutes(app.TotalSeconds))
+			p.TextRight(right, y, 11, false, mutedColor, fmt.Sprintf("%.0f%%", app.Percentage))
+		}
+	}
+
+	if len(d.Budgets) > 0 {
+		y -= 44
+		p.Text(margin, y, 13, true, textColor, "Budgets")
+		y -= 8
+		for _, outcome := range d.Budgets {
+			y -= rowHeight
+			p.Text(margin, y, 11, false, textColor, pdf.Truncate(fmt.Sprintf("%s (%v/day)", outcome.Name, outcome.Limit), nameWidth-12, 11, false))
+			// A square per day of the week, the days over budget first.
+			for day := 0; day < 7; day++ {
+				c := withinColor
+				if day < outcome.DaysOver {
+					c = overColor
+				}
+				p.Rect(margin+nameWidth+float64(day)*14, y-1, 10, 10, c)
+			}
+			p.Text(margin+nameWidth+7*14+8, y, 11, false, mutedColor, fmt.Sprintf("exceeded on %d of 7 days", outcome.DaysOver))
+		}
+	}
+
+	p.Line(margin, margin+14, right, margin+14, 0.5, trackColor)
+	p.Text(margin, margin, 9, false, mutedColor, "Generated by actionsum on "+now.Format("January 2, 2006 15:04"))
+	return doc.Bytes()
+}
@@ -0,0 +1,350 @@
+// Package doctor checks the setup actionsum depends on, the display session,
+// the daemon and the database, and how trustworthy the tracked data is.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/daemon"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+	"github.com/actionsum/actionsum/pkg/utils"
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const (
+	OK   = "ok"
+	Warn = "warn"
+	Fail = "fail"
+)
+
+const (
+	// detectionWindow is how far back the stored detection split looks.
+	detectionWindow = 7 * 24 * time.Hour
+
+	// maxGuessShare is the share of tracked time, in percent, process
+	// guesses may make up before the data is flagged as unreliable.
+	maxGuessShare = 25.0
+
+	// maxFailureRate is the share of lookups, in percent, a backend may fail
+	// before it is flagged, once it has made minAttempts.
+	maxFailureRate = 10.0
+	minAttempts    = 20
+)
+
+// Check is the outcome of one diagnostic.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Run performs every check. repo may be nil when the database could not be
+// opened.
+func Run(cfg *config.Config, repo *database.Repository) []Check {
+	checks := []Check{
+		checkSession(),
+	}
+	if sandbox.Flatpak() {
+		checks = append(checks, checkSandbox())
+	}
+	checks = append(checks,
+		checkDaemon(cfg),
+		checkDatabase(cfg, repo),
+		checkWeb(cfg),
+		checkBackends(cfg),
+	)
+	if repo != nil {
+		checks = append(checks, checkDetection(repo))
+	}
+	if check, ok := checkCoverage(cfg, repo); ok {
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// Failed reports whether any check failed outright.
+func Failed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSession() Check {
+	check := Check{Name: "session"}
+	wayland, x11 := os.Getenv("WAYLAND_DISPLAY"), os.Getenv("DISPLAY")
+	kind := os.Getenv("XDG_SESSION_TYPE")
+	if kind == "" {
+		kind = "unknown"
+	}
+	switch {
+	case wayland != "":
+		check.Status = OK
+		check.Detail = fmt.Sprintf("%s session on WAYLAND_DISPLAY=%s", kind, wayland)
+	case x11 != "":
+		check.Status = OK
+		check.Detail = fmt.Sprintf("%s session on DISPLAY=%s", kind, x11)
+	default:
+		check.Status = Warn
+		check.Detail = "no DISPLAY or WAYLAND_DISPLAY; only process-based detection can work from here"
+	}
+	return check
+}
+
+// checkSandbox says whether tools can be run on the host from inside
+// Flatpak; without that, only what the sandbox itself offers is available.
+func checkSandbox() Check {
+	check := Check{Name: "sandbox"}
+	if sandbox.HostAccess() {
+		check.Status = OK
+		check.Detail = fmt.Sprintf("Flatpak %s, running tools on the host", sandbox.AppID())
+		return check
+	}
+	check.Status = Warn
+	check.Detail = fmt.Sprintf("Flatpak %s without host access; grant --talk-name=org.freedesktop.Flatpak so xdotool, xprop and friends run on the host", sandbox.AppID())
+	return check
+}
+
+func checkDaemon(cfg *config.Config) Check {
+	check := Check{Name: "daemon"}
+	running, pid, err := daemon.New(cfg.Daemon.PIDFile).IsRunning()
+	switch {
+	case err != nil:
+		check.Status = Fail
+		check.Detail = err.Error()
+	case !running:
+		check.Status = Warn
+		check.Detail = "not running; nothing is being tracked"
+	default:
+		check.Status = OK
+		check.Detail = fmt.Sprintf("running (PID %d)", pid)
+	}
+	return check
+}
+
+func checkDatabase(cfg *config.Config, repo *database.Repository) Check {
+	check := Check{Name: "database"}
+	var corrupt *database.CorruptionError
+	if err := database.CheckIntegrity(cfg.Database.Path); errors.As(err, &corrupt) {
+		check.Status = Fail
+		check.Detail = corrupt.Error() + "; run actionsum recover, or restart the daemon to recover automatically"
+		return check
+	}
+	if repo == nil {
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("cannot open %s", cfg.Database.Path)
+		return check
+	}
+	size, err := database.FileSizes(cfg.Database.Path)
+	if err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		return check
+	}
+	check.Status = OK
+	check.Detail = fmt.Sprintf("%s, %s", cfg.Database.Path, utils.FormatBytes(size.DatabaseBytes))
+	if warning := size.Warning(cfg.Database.SizeWarning); warning != "" {
+		check.Status = Warn
+		check.Detail += "; " + warning
+	}
+	if copies := database.CorruptCopies(cfg.Database.Path); len(copies) > 0 {
+		check.Status = Warn
+		check.Detail += fmt.Sprintf("; recovered from corruption, damaged copy kept at %s", copies[len(copies)-1])
+	}
+	return check
+}
+
+// checkWeb says who can reach the web API and whether they need a token.
+func checkWeb(cfg *config.Config) Check {
+	check := Check{Name: "web"}
+	addr := fmt.Sprintf("%s:%d", cfg.Web.Host, cfg.Web.Port)
+	switch {
+	case !cfg.WebExposed():
+		check.Status = OK
+		check.Detail = fmt.Sprintf("%s, reachable from this machine only", addr)
+	case cfg.Web.RequireToken:
+		check.Status = OK
+		check.Detail = fmt.Sprintf("%s, tokens required; traffic is unencrypted, so use a TLS proxy beyond a trusted network", addr)
+	case cfg.Web.Insecure:
+		check.Status = Warn
+		check.Detail = fmt.Sprintf("%s serves window titles to anyone who can reach it; set ACTIONSUM_WEB_REQUIRE_TOKEN=true", addr)
+	default:
+		check.Status = Fail
+		check.Detail = fmt.Sprintf("%s is reachable from other machines without tokens, which serve refuses unless given --insecure; set ACTIONSUM_WEB_REQUIRE_TOKEN=true", addr)
+	}
+	return check
+}
+
+// checkBackends looks at the lookups the running daemon's backends made.
+func checkBackends(cfg *config.Config) Check {
+	check := Check{Name: "backends"}
+	stats, err := CurrentStats(cfg)
+	if err != nil {
+		check.Status = Warn
+		check.Detail = "no counts published by a running daemon"
+		return check
+	}
+	if len(stats.Backends) == 0 {
+		check.Status = OK
+		check.Detail = "no lookups yet"
+		return check
+	}
+
+	check.Status = OK
+	var failing []string
+	for _, name := range stats.BackendNames() {
+		counts := stats.Backends[name]
+		attempts := counts.Successes + counts.Failures
+		if attempts >= minAttempts && float64(counts.Failures)/float64(attempts)*100 > maxFailureRate {
+			failing = append(failing, name)
+		}
+	}
+	check.Detail = BackendSplit(stats)
+	if len(failing) > 0 {
+		check.Status = Warn
+		check.Detail += fmt.Sprintf("; %s failing often", strings.Join(failing, ", "))
+	}
+	return check
+}
+
+// checkDetection looks at how the last week's stored time was detected.
+func checkDetection(repo *database.Repository) Check {
+	check := Check{Name: "detection"}
+	summaries, err := repo.GetDetectionSummary(database.Query{Since: time.Now().Add(-detectionWindow)})
+	if err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		return check
+	}
+	if len(summaries) == 0 {
+		check.Status = Warn
+		check.Detail = "nothing tracked in the last 7 days"
+		return check
+	}
+
+	check.Status = OK
+	check.Detail = DetectionSplit(summaries) + " over the last 7 days"
+	for _, summary := range summaries {
+		if summary.Method == database.DetectionProcess && summary.Percentage > maxGuessShare {
+			check.Status = Warn
+			check.Detail += "; process-based guesses may misattribute time"
+		}
+	}
+	return check
+}
+
+// checkCoverage looks for native Wayland windows going unseen, either as
+// reported by the running daemon or because the last week's Wayland time
+// all came from XWayland windows. It is skipped when neither applies to
+// this machine.
+func checkCoverage(cfg *config.Config, repo *database.Repository) (Check, bool) {
+	check := Check{Name: "coverage"}
+	stats, err := CurrentStats(cfg)
+	if err == nil && len(stats.Limitations) > 0 {
+		check.Status = Warn
+		check.Detail = strings.Join(stats.Limitations, "; ")
+		return check, true
+	}
+	if repo == nil {
+		return check, false
+	}
+
+	native, xwayland, err := waylandClients(repo)
+	if err != nil {
+		check.Status = Fail
+		check.Detail = err.Error()
+		return check, true
+	}
+	switch {
+	case native == 0 && xwayland == 0:
+		return check, false
+	case native == 0:
+		check.Status = Warn
+		check.Detail = xwaylandOnlyWarning
+	default:
+		check.Status = OK
+		check.Detail = fmt.Sprintf("%.0f%% of Wayland time in native windows, %.0f%% in XWayland",
+			float64(native)/float64(native+xwayland)*100, float64(xwayland)/float64(native+xwayland)*100)
+	}
+	return check, true
+}
+
+const xwaylandOnlyWarning = "only XWayland windows were recorded on Wayland in the last 7 days; native Wayland apps may be missed"
+
+func waylandClients(repo *database.Repository) (native, xwayland int64, err error) {
+	totals, err := repo.GetWaylandClientTotals(database.Query{Since: time.Now().Add(-detectionWindow)})
+	if err != nil {
+		return 0, 0, err
+	}
+	return totals[window.ClientNative], totals[window.ClientXWayland], nil
+}
+
+// CoverageWarning returns a warning when the last week's Wayland time all
+// came from XWayland windows, or "" when native windows were seen or there
+// is nothing to judge by.
+func CoverageWarning(repo *database.Repository) string {
+	native, xwayland, err := waylandClients(repo)
+	if err != nil || native > 0 || xwayland == 0 {
+		return ""
+	}
+	return xwaylandOnlyWarning
+}
+
+// CurrentStats returns the counts published by the running daemon, ignoring
+// a file left behind by one that has since exited.
+func CurrentStats(cfg *config.Config) (*daemon.DetectorStats, error) {
+	running, pid, err := daemon.New(cfg.Daemon.PIDFile).IsRunning()
+	if err != nil {
+		return nil, err
+	}
+	if !running {
+		return nil, fmt.Errorf("daemon is not running")
+	}
+	stats, err := daemon.ReadStats(cfg.Daemon.StatsFile)
+	if err != nil {
+		return nil, err
+	}
+	if stats.PID != pid {
+		return nil, fmt.Errorf("detector stats are from another daemon")
+	}
+	return stats, nil
+}
+
+// DetectionSplit describes stored time by detection method, e.g.
+// "72% window, 28% process-based".
+func DetectionSplit(summaries []models.DetectionSummary) string {
+	parts := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		method := summary.Method
+		if method == "" {
+			method = "unrecorded"
+		}
+		parts = append(parts, fmt.Sprintf("%.0f%% %s", summary.Percentage, method))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BackendSplit describes the lookups each backend answered, e.g.
+// "x11 96% (1200 ok, 50 failed), process 4% (50 ok, 3 failed)".
+func BackendSplit(stats *daemon.DetectorStats) string {
+	total := stats.Successes()
+	parts := make([]string, 0, len(stats.Backends))
+	for _, name := range stats.BackendNames() {
+		counts := stats.Backends[name]
+		share := 0.0
+		if total > 0 {
+			share = float64(counts.Successes) / float64(total) * 100
+		}
+		parts = append(parts, fmt.Sprintf("%s %.0f%% (%d ok, %d failed)", name, share, counts.Successes, counts.Failures))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,103 @@
+// Package editor takes what editor plugins report about the file being
+// edited. A plugin posts the editor, project, file and language to the web
+// API on every change, which records them in a file; while that editor has
+// focus, the daemon puts the project, a hash of the file's path and the
+// language on its events. The payload is described in
+// docs/editor-plugins.md.
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/statefile"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+const (
+	// freshness is how long a report is believed. Plugins repeat theirs
+	// every 30 seconds, so an older one means the editor or the plugin
+	// has gone.
+	freshness = 2 * time.Minute
+
+	// maxField bounds each reported name.
+	maxField = 200
+)
+
+// Context is what an editor plugin last reported.
+type Context struct {
+	Editor   string `json:"editor"`            // e.g. "code", "idea" or "nvim"
+	Project  string `json:"project,omitempty"` // Workspace or project name
+	File     string `json:"file,omitempty"`    // Path of the file, only ever hashed
+	FileHash string `json:"file_hash,omitempty"`
+	Language string `json:"language,omitempty"` // e.g. "go" or "typescript"
+
+	// Focused is false once the editor window loses focus; plugins that
+	// can't tell leave it out.
+	Focused *bool `json:"focused,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Clean trims the context's names, replaces the file's path with its hash,
+// and checks that it names the editor.
+func (c *Context) Clean() error {
+	for _, field := range []*string{&c.Editor, &c.Project, &c.File, &c.FileHash, &c.Language} {
+		*field = strings.TrimSpace(*field)
+		*field = utils.TruncateBytes(*field, maxField)
+	}
+	if c.Editor == "" {
+		return fmt.Errorf("editor is required")
+	}
+	c.Editor = strings.ToLower(c.Editor)
+	c.Language = strings.ToLower(c.Language)
+	if c.File != "" {
+		c.FileHash = HashPath(c.File)
+		c.File = ""
+	}
+	return nil
+}
+
+// HashPath returns the first 16 hex digits of the SHA-256 of path, enough
+// to tell a project's files apart without storing their names.
+func HashPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Record saves c as the current context.
+func Record(path string, c *Context) error {
+	if path == "" {
+		return fmt.Errorf("editor context file is not configured")
+	}
+	return statefile.WriteJSON(path, c)
+}
+
+// Current returns the context recorded at path while the editor has focus
+// and its plugin reported recently.
+func Current(path string, now time.Time) (*Context, bool) {
+	if path == "" {
+		return nil, false
+	}
+	var c Context
+	if err := statefile.ReadJSON(path, &c); err != nil {
+		return nil, false
+	}
+	if c.Editor == "" || (c.Focused != nil && !*c.Focused) || now.Sub(c.UpdatedAt) > freshness {
+		return nil, false
+	}
+	return &c, true
+}
+
+// Matches reports whether the focused window is the reporting editor: the
+// app is named after it, or, as for an editor running in a terminal, the
+// window title names the project.
+func (c *Context) Matches(appName, windowTitle string) bool {
+	if strings.Contains(strings.ToLower(appName), c.Editor) {
+		return true
+	}
+	return c.Project != "" && strings.Contains(windowTitle, c.Project)
+}
@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClean(t *testing.T) {
+	c := &Context{Editor: " Code ", Project: "actionsum", File: "/home/ada/src/actionsum/main.go", Language: "Go"}
+	if err := c.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Editor != "code" || c.Language != "go" || c.File != "" || c.FileHash != HashPath("/home/ada/src/actionsum/main.go") {
+		t.Errorf("Clean() = %+v", c)
+	}
+	if len(c.FileHash) != 16 {
+		t.Errorf("FileHash %q, want 16 hex digits", c.FileHash)
+	}
+
+	hashed := &Context{Editor: "nvim", FileHash: "0123456789abcdef"}
+	if err := hashed.Clean(); err != nil || hashed.FileHash != "0123456789abcdef" {
+		t.Errorf("Clean() = %+v, %v; want the plugin's hash kept", hashed, err)
+	}
+
+	if err := (&Context{Project: "actionsum"}).Clean(); err == nil {
+		t.Error("Clean() accepted a context without an editor")
+	}
+}
+
+func TestRecordCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "editor.json")
+	now := time.Now()
+
+	if _, ok := Current(path, now); ok {
+		t.Fatal("Current() found a context before any was recorded")
+	}
+	if err := Record(path, &Context{Editor: "code", Project: "actionsum", UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := Current(path, now.Add(time.Minute)); !ok || c.Project != "actionsum" {
+		t.Errorf("Current() = %+v, %v", c, ok)
+	}
+	if _, ok := Current(path, now.Add(3*time.Minute)); ok {
+		t.Error("Current() believed a stale context")
+	}
+
+	unfocused := false
+	if err := Record(path, &Context{Editor: "code", Project: "actionsum", Focused: &unfocused, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Current(path, now); ok {
+		t.Error("Current() returned the context of an unfocused editor")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	c := &Context{Editor: "code", Project: "actionsum"}
+	tests := []struct {
+		app, title string
+		want       bool
+	}{
+		{"code", "main.go - actionsum - Visual Studio Code", true},
+		{"kitty", "nvim: actionsum/main.go", true},
+		{"firefox", "GitHub - Mozilla Firefox", false},
+	}
+	for _, tt := range tests {
+		if got := c.Matches(tt.app, tt.title); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.app, tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// BillingLine is the billable time of one project.
+type BillingLine struct {
+	Project        string
+	Sessions       int
+	TrackedSeconds int64
+	BilledSeconds  int64 // Each session rounded up to the increment
+
+	// Rate is the hourly rate, and Amount the billed hours at that rate;
+	// HasRate is false for projects without a configured rate.
+	Rate    float64
+	HasRate bool
+	Amount  float64
+}
+
+// Billing totals the time recorded in q per project, with each session, a
+// run of consecutive events of one project no further than maxGap apart,
+// rounded up to the billing increment and priced at the project's rate.
+// Events without a tag or an editor project aren't billed.
+func Billing(repo *database.Repository, q database.Query, billing config.BillingConfig, maxGap time.Duration) ([]BillingLine, error) {
+	events, err := repo.GetEvents(q)
+	if err != nil {
+		return nil, err
+	}
+	return billingLines(events, billing, maxGap), nil
+}
+
+// billingProject is what an event is billed to: the tag the user set, or
+// else the project the editor reported.
+func billingProject(event *models.FocusEvent) string {
+	if event.Tag != "" {
+		return event.Tag
+	}
+	return event.Project
+}
+
+func billingLines(events []*models.FocusEvent, billing config.BillingConfig, maxGap time.Duration) []BillingLine {
+	lines := make(map[string]*BillingLine)
+	var (
+		current string // Project of the open session, "" when none is
+		seconds int64
+		end     time.Time
+	)
+	closeSession := func() {
+		if current == "" {
+			return
+		}
+		line := lines[current]
+		line.Sessions++
+		line.TrackedSeconds += seconds
+		line.BilledSeconds += roundUp(seconds, billing.Increment)
+		current, seconds = "", 0
+	}
+
+	for _, event := range events {
+		project := billingProject(event)
+		if project != current || event.Timestamp.Sub(end) > maxGap {
+			closeSession()
+		}
+		if project == "" {
+			continue
+		}
+		if lines[project] == nil {
+			lines[project] = &BillingLine{Project: project}
+		}
+		current = project
+		seconds += event.Duration
+		end = event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+	}
+	closeSession()
+
+	result := make([]BillingLine, 0, len(lines))
+	for _, line := range lines {
+		if rate, ok := billing.Rates[strings.ToLower(line.Project)]; ok {
+			line.Rate, line.HasRate = rate, true
+			line.Amount = math.Round(float64(line.BilledSeconds)/3600*rate*100) / 100
+		}
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BilledSeconds != result[j].BilledSeconds {
+			return result[i].BilledSeconds > result[j].BilledSeconds
+		}
+		return result[i].Project < result[j].Project
+	})
+	return result
+}
+
+// roundUp rounds seconds up to a whole number of increments.
+func roundUp(seconds int64, increment time.Duration) int64 {
+	step := int64(increment / time.Second)
+	if step <= 0 || seconds%step == 0 {
+		return seconds
+	}
+	return (seconds/step + 1) * step
+}
+
+// WriteBillingCSV writes one row per project with its hours and amount,
+// followed by a total row. Projects without a rate have an empty rate and
+// amount, and don't count towards the total amount, which is empty too
+// when no project has a rate.
+func WriteBillingCSV(out io.Writer, lines []BillingLine, currency string) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"project", "sessions", "tracked_hours", "billed_hours", "rate", "amount", "currency"})
+
+	var total BillingLine
+	for _, line := range lines {
+		total.HasRate = total.HasRate || line.HasRate
+		rate, amount := "", ""
+		if line.HasRate {
+			rate = strconv.FormatFloat(line.Rate, 'f', -1, 64)
+			amount = fmt.Sprintf("%.2f", line.Amount)
+			total.Amount += line.Amount
+		}
+		total.Sessions += line.Sessions
+		total.TrackedSeconds += line.TrackedSeconds
+		total.BilledSeconds += line.BilledSeconds
+		w.Write([]string{line.Project, strconv.Itoa(line.Sessions), hours(line.TrackedSeconds), hours(line.BilledSeconds), rate, amount, currency})
+	}
+	totalAmount := ""
+	if total.HasRate {
+		totalAmount = fmt.Sprintf("%.2f", total.Amount)
+	}
+	w.Write([]string{"Total", strconv.Itoa(total.Sessions), hours(total.TrackedSeconds), hours(total.BilledSeconds), "", totalAmount, currency})
+
+	w.Flush()
+	return w.Error()
+}
+
+func hours(seconds int64) string {
+	return fmt.Sprintf("%.2f", float64(seconds)/3600)
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestBillingLines(t *testing.T) {
+	base := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	event := func(minute, seconds int64, tag, project string) *models.FocusEvent {
+		return &models.FocusEvent{Timestamp: base.Add(time.Duration(minute) * time.Minute), Duration: seconds, Tag: tag, Project: project}
+	}
+	// acme: a 10-minute session, the tag winning over the editor project,
+	// then a 1-minute one. website: 20 minutes from an editor project. The
+	// event with neither isn't billed.
+	events := []*models.FocusEvent{
+		event(0, 300, "acme", ""),
+		event(5, 300, "acme", "website"),
+		event(10, 600, "", "website"),
+		event(20, 600, "", "website"),
+		event(30, 60, "", ""),
+		event(120, 60, "acme", ""),
+	}
+
+	tests := []struct {
+		name      string
+		increment time.Duration
+		rates     map[string]float64
+		want      []BillingLine
+	}{
+		{
+			name: "exact",
+			want: []BillingLine{
+				{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1200},
+				{Project: "acme", Sessions: 2, TrackedSeconds: 660, BilledSeconds: 660},
+			},
+		},
+		{
+			name:      "6 minutes",
+			increment: 6 * time.Minute,
+			rates:     map[string]float64{"acme": 90},
+			want: []BillingLine{
+				{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1440},
+				{Project: "acme", Sessions: 2, TrackedSeconds: 660, BilledSeconds: 1080, Rate: 90, HasRate: true, Amount: 27},
+			},
+		},
+		{
+			name:      "15 minutes",
+			increment: 15 * time.Minute,
+			rates:     map[string]float64{"acme": 95, "website": 0},
+			want: []BillingLine{
+				{Project: "acme", Sessions: 2, TrackedSeconds: 660, BilledSeconds: 1800, Rate: 95, HasRate: true, Amount: 47.5},
+				{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1800, HasRate: true},
+			},
+		},
+		{
+			name:      "rate for a project without time",
+			increment: 15 * time.Minute,
+			rates:     map[string]float64{"other": 100},
+			want: []BillingLine{
+				{Project: "acme", Sessions: 2, TrackedSeconds: 660, BilledSeconds: 1800},
+				{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1800},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			billing := config.BillingConfig{Increment: tt.increment, Rates: tt.rates}
+			got := billingLines(events, billing, 5*time.Minute)
+			if len(got) != len(tt.want) {
+				t.Fatalf("billingLines() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteBillingCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []BillingLine
+		total string
+	}{
+		{
+			name: "with rates",
+			lines: []BillingLine{
+				{Project: "acme", Sessions: 2, TrackedSeconds: 660, BilledSeconds: 1800, Rate: 95, HasRate: true, Amount: 47.5},
+				{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1800},
+			},
+			total: "Total,3,0.52,1.00,,47.50,EUR",
+		},
+		{
+			name:  "zero rate",
+			lines: []BillingLine{{Project: "internal", Sessions: 1, TrackedSeconds: 3600, BilledSeconds: 3600, HasRate: true}},
+			total: "Total,1,1.00,1.00,,0.00,EUR",
+		},
+		{
+			name:  "no rates",
+			lines: []BillingLine{{Project: "website", Sessions: 1, TrackedSeconds: 1200, BilledSeconds: 1800}},
+			total: "Total,1,0.33,0.50,,,EUR",
+		},
+		{
+			name:  "no projects",
+			total: "Total,0,0.00,0.00,,,EUR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteBillingCSV(&out, tt.lines, "EUR"); err != nil {
+				t.Fatal(err)
+			}
+			rows := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(rows) != len(tt.lines)+2 {
+				t.Fatalf("%d rows, want %d: %q", len(rows), len(tt.lines)+2, out.String())
+			}
+			if got := rows[len(rows)-1]; got != tt.total {
+				t.Errorf("total row = %q, want %q", got, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/analytics"
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/reporter"
+)
+
+// bundleVersion identifies the bundle layout. A manifest with another
+// version is ignored and the bundle rebuilt.
+const bundleVersion = 1
+
+const monthLayout = "2006-01"
+
+// bundleTables are the per-month CSV tables in a bundle, each in a directory
+// of its own name holding one <YYYY-MM>.csv file per month.
+var bundleTables = []string{"sessions", "daily", "categories"}
+
+const viewsSQL = `-- Views over an actionsum analytics bundle. From the bundle directory run:
+--   duckdb -init views.sql
+-- Timestamps are UTC; days are the local days events were recorded on.
+
+CREATE OR REPLACE VIEW sessions AS
+SELECT * FROM read_csv('sessions/*.csv', header = true, columns = {
+    'day': 'DATE', 'start': 'TIMESTAMPTZ', 'end': 'TIMESTAMPTZ',
+    'app_name': 'VARCHAR', 'seconds': 'BIGINT'});
+
+CREATE OR REPLACE VIEW daily AS
+SELECT * FROM read_csv('daily/*.csv', header = true, columns = {
+    'day': 'DATE', 'app_name': 'VARCHAR', 'tag': 'VARCHAR', 'total_seconds': 'BIGINT'});
+
+CREATE OR REPLACE VIEW categories AS
+SELECT * FROM read_csv('categories/*.csv', header = true, columns = {
+    'day': 'DATE', 'category': 'VARCHAR', 'total_seconds': 'BIGINT'});
+`
+
+const bundleReadme = `# actionsum analytics bundle
+
+Generated by "actionsum export bundle". Re-running it rewrites only the
+months whose events changed since the previous run.
+
+    manifest.json              when each month was generated and its row counts
+    views.sql                  DuckDB views over the CSV files
+    sessions/YYYY-MM.csv       day, start, end, app_name, seconds
+    daily/YYYY-MM.csv          day, app_name, tag, total_seconds
+    categories/YYYY-MM.csv     day, category, total_seconds
+
+sessions joins consecutive events in one app into a focus session; sessions
+crossing a month boundary are split. daily is the focus time per local day,
+app and tag. categories groups daily by tag, or by the app's configured
+category, or "uncategorized".
+
+Open the views with "duckdb -init views.sql" from this directory, or read
+the files from any tool that understands CSV.
+`
+
+type manifest struct {
+	Version     int                   `json:"version"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	Months      map[string]*monthInfo `json:"months"`
+}
+
+type monthInfo struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Rows        map[string]int `json:"rows"`
+}
+
+// BundleResult lists the months a bundle run rewrote and removed.
+type BundleResult struct {
+	Updated []string
+	Removed []string
+}
+
+// Bundle writes CSV tables of sessions, daily totals and category totals to
+// dir, one file per table and month, with DuckDB views over them. Months are
+// only rewritten when their events changed since the last run recorded in
+// the manifest, unless full is set.
+func Bundle(dir string, cfg *config.Config, repo *database.Repository, full bool, progress Progress) (*BundleResult, error) {
+	for _, table := range bundleTables {
+		if err := os.MkdirAll(filepath.Join(dir, table), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create bundle directory: %w", err)
+		}
+	}
+
+	previous := readManifest(dir)
+	if previous == nil || previous.Version != bundleVersion {
+		full = true
+	}
+	current := &manifest{Version: bundleVersion, GeneratedAt: time.Now(), Months: map[string]*monthInfo{}}
+
+	months, err := repo.GetEventMonths()
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]bool{}
+	if !full {
+		changedMonths, err := repo.GetChangedMonths(previous.GeneratedAt)
+		if err != nil {
+			return nil, err
+		}
+		for _, month := range changedMonths {
+			changed[month] = true
+		}
+	}
+
+	result := &BundleResult{}
+	category := reporter.New(cfg, repo).Categorizer()
+	for i, month := range months {
+		progress.report(int64(i), int64(len(months)))
+		if info, ok := previous.month(month); ok && !full && !changed[month] && bundleFilesExist(dir, month) {
+			current.Months[month] = info
+			continue
+		}
+		info, err := writeMonth(dir, month, cfg, repo, category)
+		if err != nil {
+			return nil, err
+		}
+		current.Months[month] = info
+		result.Updated = append(result.Updated, month)
+	}
+	progress.report(int64(len(months)), int64(len(months)))
+
+	// Months left in the bundle after their events were deleted.
+	for _, table := range bundleTables {
+		files, _ := filepath.Glob(filepath.Join(dir, table, "*.csv"))
+		for _, file := range files {
+			month := strings.TrimSuffix(filepath.Base(file), ".csv")
+			if _, ok := current.Months[month]; ok {
+				continue
+			}
+			if err := os.Remove(file); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", file, err)
+			}
+			if table == bundleTables[0] {
+				result.Removed = append(result.Removed, month)
+			}
+		}
+	}
+
+	if err := writeFile(filepath.Join(dir, "views.sql"), []byte(viewsSQL)); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, "README.md"), []byte(bundleReadme)); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(filepath.Join(dir, "manifest.json"), append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func readManifest(dir string) *manifest {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return &m
+}
+
+func (m *manifest) month(month string) (*monthInfo, bool) {
+	if m == nil {
+		return nil, false
+	}
+	info, ok := m.Months[month]
+	return info, ok && info != nil
+}
+
+func bundleFilesExist(dir, month string) bool {
+	for _, table := range bundleTables {
+		if _, err := os.Stat(filepath.Join(dir, table, month+".csv")); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeMonth regenerates every table for one local month.
+func writeMonth(dir, month string, cfg *config.Config, repo *database.Repository, category func(appName, tag string) string) (*monthInfo, error) {
+	start, err := time.ParseInLocation(monthLayout, month, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month %q: %w", month, err)
+	}
+	end := start.AddDate(0, 1, 0)
+	info := &monthInfo{GeneratedAt: time.Now(), Rows: map[string]int{}}
+
+	events, err := repo.GetEvents(database.Query{Since: start, Until: end})
+	if err != nil {
+		return nil, err
+	}
+	var sessionRows [][]string
+	for _, s := range analytics.Sessions(repo, events, cfg.SessionGap()) {
+		sessionRows = append(sessionRows, []string{
+			s.Start.Local().Format("2006-01-02"),
+			s.Start.UTC().Format(time.RFC3339),
+			s.End.UTC().Format(time.RFC3339),
+			s.AppName,
+			strconv.FormatInt(s.Seconds, 10),
+		})
+	}
+
+	// Days follow the offset events were recorded under, so look a day
+	// beyond the month on each side and keep the month's own days.
+	totals, err := repo.GetDailyAppTotals(database.Query{Since: start.AddDate(0, 0, -1), Until: end.AddDate(0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	var dailyRows [][]string
+	byCategory := map[[2]string]int64{}
+	for _, t := range mergeDaily(totals) {
+		if !strings.HasPrefix(t.Day, month) {
+			continue
+		}
+		dailyRows = append(dailyRows, []string{t.Day, t.AppName, t.Tag, strconv.FormatInt(t.TotalSeconds, 10)})
+		byCategory[[2]string{t.Day, category(t.AppName, t.Tag)}] += t.TotalSeconds
+	}
+	categoryRows := categoryRows(byCategory)
+
+	tables := map[string]struct {
+		header []string
+		rows   [][]string
+	}{
+		"sessions":   {[]string{"day", "start", "end", "app_name", "seconds"}, sessionRows},
+		"daily":      {[]string{"day", "app_name", "tag", "total_seconds"}, dailyRows},
+		"categories": {[]string{"day", "category", "total_seconds"}, categoryRows},
+	}
+	for _, table := range bundleTables {
+		t := tables[table]
+		if err := writeCSV(filepath.Join(dir, table, month+".csv"), t.header, t.rows); err != nil {
+			return nil, err
+		}
+		info.Rows[table] = len(t.rows)
+	}
+	return info, nil
+}
+
+func categoryRows(totals map[[2]string]int64) [][]string {
+	keys := make([][2]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return totals[keys[i]] > totals[keys[j]]
+	})
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, []string{key[0], key[1], strconv.FormatInt(totals[key], 10)})
+	}
+	return rows
+}
+
+func writeCSV(path string, header []string, rows [][]string) error {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return writeFile(path, []byte(b.String()))
+}
+
+// writeFile replaces path atomically, so readers never see a partial file.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Package export writes tracked data in formats meant for analysis outside
+// actionsum.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/parquet"
+	"github.com/actionsum/actionsum/version"
+)
+
+// pageSize is how many events are read from the database at a time.
+const pageSize = 5000
+
+var eventColumns = []parquet.Column{
+	{Name: "uuid", Type: parquet.String},
+	{Name: "timestamp", Type: parquet.Timestamp},
+	{Name: "app_name", Type: parquet.String},
+	{Name: "window_title", Type: parquet.String},
+	{Name: "duration", Type: parquet.Int64},
+	{Name: "is_idle", Type: parquet.Boolean},
+	{Name: "is_locked", Type: parquet.Boolean},
+	{Name: "display_server", Type: parquet.String},
+	{Name: "is_fullscreen", Type: parquet.Boolean},
+	{Name: "is_maximized", Type: parquet.Boolean},
+	{Name: "window_x", Type: parquet.Int32},
+	{Name: "window_y", Type: parquet.Int32},
+	{Name: "window_width", Type: parquet.Int32},
+	{Name: "window_height", Type: parquet.Int32},
+	{Name: "clock_jump", Type: parquet.Int64},
+	{Name: "profile", Type: parquet.String},
+	{Name: "tag", Type: parquet.String},
+	{Name: "time_zone", Type: parquet.String, Optional: true},
+	{Name: "utc_offset", Type: parquet.Int32, Optional: true},
+	{Name: "label", Type: parquet.String},
+	{Name: "domain", Type: parquet.String},
+	{Name: "location", Type: parquet.String},
+	{Name: "sub_app", Type: parquet.String},
+	{Name: "remote_host", Type: parquet.String},
+	{Name: "shell_command", Type: parquet.String},
+	{Name: "shell_dir", Type: parquet.String},
+	{Name: "media_player", Type: parquet.String},
+	{Name: "media_track", Type: parquet.String},
+	{Name: "remote_client", Type: parquet.String},
+	{Name: "remote_session", Type: parquet.String},
+	{Name: "vpn", Type: parquet.Boolean},
+	{Name: "in_meeting", Type: parquet.Boolean},
+	{Name: "raw_app_name", Type: parquet.String},
+	{Name: "display_name", Type: parquet.String},
+	{Name: "project", Type: parquet.String},
+	{Name: "file_hash", Type: parquet.String},
+	{Name: "language", Type: parquet.String},
+	{Name: "output", Type: parquet.String},
+}
+
+var dailyColumns = []parquet.Column{
+	{Name: "day", Type: parquet.Date},
+	{Name: "app_name", Type: parquet.String},
+	{Name: "tag", Type: parquet.String},
+	{Name: "total_seconds", Type: parquet.Int64},
+}
+
+func createdBy() string {
+	return "actionsum " + version.Version
+}
+
+// EventsParquet writes the focus events matching q to out, one row per event
+// with app names normalized. Events are read a page at a time, so exports of
+// any size run in bounded memory. It returns the number of rows written.
+func EventsParquet(out io.Writer, repo *database.Repository, q database.Query, progress Progress) (int64, error) {
+	total, err := repo.CountEvents(q)
+	if err != nil {
+		return 0, err
+	}
+	w := parquet.NewWriter(out, createdBy(), eventColumns...)
+
+	var rows int64
+	after, afterID := q.Since, uint(0)
+	for {
+		events, err := repo.GetEventsAfter(after, afterID, pageSize)
+		if err != nil {
+			return rows, err
+		}
+		for _, event := range events {
+			if !q.Until.IsZero() && !event.Timestamp.Before(q.Until) {
+				return rows, closeWriter(w)
+			}
+			if q.Profile != "" && event.Profile != q.Profile {
+				continue
+			}
+			if err := w.Write(eventRow(repo, event)...); err != nil {
+				return rows, fmt.Errorf("failed to write event %d: %w", event.ID, err)
+			}
+			rows++
+		}
+		progress.report(rows, total)
+		if len(events) < pageSize {
+			return rows, closeWriter(w)
+		}
+		last := events[len(events)-1]
+		after, afterID = last.Timestamp, last.ID
+	}
+}
+
+func eventRow(repo *database.Repository, event *models.FocusEvent) []interface{} {
+	var zone, offset interface{}
+	if event.TimeZone != "" {
+		zone = event.TimeZone
+	}
+	if event.UTCOffset != nil {
+		offset = *event.UTCOffset
+	}
+	return []interface{}{
+		event.UUID,
+		event.Timestamp,
+		repo.NormalizeAppName(event.AppName),
+		event.WindowTitle,
+		event.Duration,
+		event.IsIdle,
+		event.IsLocked,
+		event.DisplayServer,
+		event.IsFullscreen,
+		event.IsMaximized,
+		event.WindowX,
+		event.WindowY,
+		event.WindowWidth,
+		event.WindowHeight,
+		event.ClockJump,
+		event.Profile,
+		event.Tag,
+		zone,
+		offset,
+		event.Label,
+		event.Domain,
+		event.Location,
+		event.SubApp,
+		event.RemoteHost,
+		event.ShellCommand,
+		event.ShellDir,
+		event.MediaPlayer,
+		event.MediaTrack,
+		event.RemoteClient,
+		event.RemoteSession,
+		event.VPN,
+		event.InMeeting,
+		event.RawAppName,
+		event.DisplayName,
+		event.Project,
+		event.FileHash,
+		event.Language,
+		event.Output,
+	}
+}
+
+// DailyParquet writes one row per local day, app and tag with the focus time
+// recorded, the rollup the reports are built from. It returns the number of
+// rows written.
+func DailyParquet(out io.Writer, repo *database.Repository, q database.Query, progress Progress) (int64, error) {
+	totals, err := repo.GetDailyAppTotals(q)
+	if err != nil {
+		return 0, err
+	}
+
+	w := parquet.NewWriter(out, createdBy(), dailyColumns...)
+	merged := mergeDaily(totals)
+	var rows int64
+	for _, total := range merged {
+		if err := w.Write(total.Day, total.AppName, total.Tag, total.TotalSeconds); err != nil {
+			return rows, fmt.Errorf("failed to write totals for %s: %w", total.Day, err)
+		}
+		rows++
+		progress.report(rows, int64(len(merged)))
+	}
+	return rows, closeWriter(w)
+}
+
+// Progress is told how many of the rows, or months of a bundle, have been
+// written out of the total. It may be nil.
+type Progress func(done, total int64)
+
+func (p Progress) report(done, total int64) {
+	if p != nil {
+		p(done, total)
+	}
+}
+
+// mergeDaily combines totals whose app names became equal once normalized.
+func mergeDaily(totals []models.DailyAppTotal) []models.DailyAppTotal {
+	type key struct{ day, app, tag string }
+	index := make(map[key]int)
+	var merged []models.DailyAppTotal
+	for _, total := range totals {
+		k := key{total.Day, total.AppName, total.Tag}
+		if i, ok := index[k]; ok {
+			merged[i].TotalSeconds += total.TotalSeconds
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, total)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Day != merged[j].Day {
+			return merged[i].Day < merged[j].Day
+		}
+		return merged[i].TotalSeconds > merged[j].TotalSeconds
+	})
+	return merged
+}
+
+func closeWriter(w *parquet.Writer) error {
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish parquet file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package gaps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// BootIntervals returns the periods the machine was running, taken from the
+// systemd journal's boot list with the current boot extended to now. Without
+// a readable journal only the current boot (from /proc/uptime) is known.
+func BootIntervals(now time.Time) ([]Interval, error) {
+	current, err := currentBoot(now)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := sandbox.Command("journalctl", "--list-boots", "--output=json", "--no-pager").Output()
+	if err != nil {
+		return []Interval{current}, nil
+	}
+	boots, err := parseBootList(output)
+	if err != nil || len(boots) == 0 {
+		return []Interval{current}, nil
+	}
+
+	// The journal's last entry for the running boot lags behind; the current
+	// boot lasts until now.
+	boots[len(boots)-1].End = now
+	if boots[len(boots)-1].Start.After(current.Start) {
+		boots[len(boots)-1].Start = current.Start
+	}
+	return boots, nil
+}
+
+type bootEntry struct {
+	Index      int   `json:"index"`
+	FirstEntry int64 `json:"first_entry"`
+	LastEntry  int64 `json:"last_entry"`
+}
+
+// parseBootList parses `journalctl --list-boots --output=json`, whose entry
+// timestamps are microseconds since the epoch.
+func parseBootList(data []byte) ([]Interval, error) {
+	var entries []bootEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse boot list: %w", err)
+	}
+
+	intervals := make([]Interval, 0, len(entries))
+	for _, entry := range entries {
+		if entry.FirstEntry <= 0 || entry.LastEntry < entry.FirstEntry {
+			continue
+		}
+		intervals = append(intervals, Interval{
+			Start: time.UnixMicro(entry.FirstEntry),
+			End:   time.UnixMicro(entry.LastEntry),
+		})
+	}
+	return intervals, nil
+}
+
+func currentBoot(now time.Time) (Interval, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return Interval{}, fmt.Errorf("failed to read uptime: %w", err)
+	}
+	uptime, err := parseUptime(string(data))
+	if err != nil {
+		return Interval{}, err
+	}
+	return Interval{Start: now.Add(-uptime), End: now}, nil
+}
+
+func parseUptime(data string) (time.Duration, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uptime %q: %w", fields[0], err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
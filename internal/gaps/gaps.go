@@ -0,0 +1,186 @@
+package gaps
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// DefaultMinGap is the shortest untracked period worth reporting.
+const DefaultMinGap = 10 * time.Minute
+
+const (
+	ReasonDaemonStopped = "daemon not running"
+	ReasonErrors        = "errors logged"
+)
+
+// Finder locates periods where the machine was running but nothing was
+// tracked. Time spent locked or suspended is not a gap, and neither is time
+// the daemon was running without errors, since idle polls store no events.
+type Finder struct {
+	repo   *database.Repository
+	minGap time.Duration
+	boots  func(now time.Time) ([]Interval, error)
+}
+
+func New(repo *database.Repository, minGap time.Duration) *Finder {
+	return &Finder{repo: repo, minGap: minGap, boots: BootIntervals}
+}
+
+func (f *Finder) Find(start, end time.Time) ([]models.Gap, error) {
+	now := time.Now()
+	if end.After(now) {
+		end = now
+	}
+
+	boots, err := f.boots(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine uptime: %w", err)
+	}
+	machineOn := clip(boots, start, end)
+
+	events, err := f.repo.GetEventsSince(start)
+	if err != nil {
+		return nil, err
+	}
+	stateEvents, err := f.repo.GetStateEventsSince(start)
+	if err != nil {
+		return nil, err
+	}
+	errorLogs, err := f.repo.GetErrorLogsSince(start)
+	if err != nil {
+		return nil, err
+	}
+	lastDaemon, err := f.repo.GetLastStateEventBefore(start, models.StateDaemonStart, models.StateDaemonStop)
+	if err != nil {
+		return nil, err
+	}
+
+	var covered []Interval
+	for _, event := range events {
+		covered = append(covered, Interval{
+			Start: event.Timestamp,
+			End:   event.Timestamp.Add(time.Duration(event.Duration) * time.Second),
+		})
+	}
+	covered = append(covered, lockedIntervals(stateEvents, end)...)
+	covered = append(covered, suspendedIntervals(stateEvents)...)
+
+	running := runningIntervals(lastDaemon, stateEvents, end)
+
+	var gaps []models.Gap
+	for _, untracked := range subtract(machineOn, covered) {
+		if untracked.Duration() < f.minGap {
+			continue
+		}
+		for _, stopped := range subtract([]Interval{untracked}, running) {
+			if stopped.Duration() >= f.minGap {
+				gaps = append(gaps, newGap(stopped, ReasonDaemonStopped))
+			}
+		}
+		for _, active := range intersect([]Interval{untracked}, running) {
+			if active.Duration() >= f.minGap && hasErrors(errorLogs, active) {
+				gaps = append(gaps, newGap(active, ReasonErrors))
+			}
+		}
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		return gaps[i].Start.Before(gaps[j].Start)
+	})
+	return gaps, nil
+}
+
+func newGap(in Interval, reason string) models.Gap {
+	return models.Gap{
+		Start:   in.Start,
+		End:     in.End,
+		Seconds: int64(in.Duration().Seconds()),
+		Reason:  reason,
+	}
+}
+
+func lockedIntervals(events []*models.StateEvent, end time.Time) []Interval {
+	var intervals []Interval
+	var lockedSince time.Time
+	locked := false
+	for _, event := range events {
+		switch event.Type {
+		case models.StateLock:
+			if !locked {
+				locked = true
+				lockedSince = event.Timestamp
+			}
+		case models.StateUnlock:
+			if locked {
+				intervals = append(intervals, Interval{Start: lockedSince, End: event.Timestamp})
+				locked = false
+			}
+		}
+	}
+	if locked {
+		intervals = append(intervals, Interval{Start: lockedSince, End: end})
+	}
+	return intervals
+}
+
+// suspendedIntervals treats a forward clock jump as the machine having been
+// asleep for the jumped duration.
+func suspendedIntervals(events []*models.StateEvent) []Interval {
+	var intervals []Interval
+	for _, event := range events {
+		if event.Type != models.StateClockJump {
+			continue
+		}
+		jump, err := time.ParseDuration(event.Detail)
+		if err != nil || jump <= 0 {
+			continue
+		}
+		intervals = append(intervals, Interval{Start: event.Timestamp.Add(-jump), End: event.Timestamp})
+	}
+	return intervals
+}
+
+// runningIntervals pairs daemon start and stop events. A start without a stop
+// (crash or still running) lasts until the next start or end.
+func runningIntervals(previous *models.StateEvent, events []*models.StateEvent, end time.Time) []Interval {
+	var intervals []Interval
+	var since time.Time
+	running := false
+	if previous != nil && previous.Type == models.StateDaemonStart {
+		running = true
+		since = previous.Timestamp
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case models.StateDaemonStart:
+			if running {
+				intervals = append(intervals, Interval{Start: since, End: event.Timestamp})
+			}
+			running = true
+			since = event.Timestamp
+		case models.StateDaemonStop:
+			if running {
+				intervals = append(intervals, Interval{Start: since, End: event.Timestamp})
+				running = false
+			}
+		}
+	}
+	if running {
+		intervals = append(intervals, Interval{Start: since, End: end})
+	}
+	return intervals
+}
+
+func hasErrors(logs []*models.ErrorLog, in Interval) bool {
+	for _, entry := range logs {
+		if !entry.Timestamp.Before(in.Start) && entry.Timestamp.Before(in.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package gaps
+
+import (
+	"sort"
+	"time"
+)
+
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// merge sorts intervals and joins those that overlap or touch.
+func merge(intervals []Interval) []Interval {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, in := range intervals {
+		if in.End.After(in.Start) {
+			sorted = append(sorted, in)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	var merged []Interval
+	for _, in := range sorted {
+		if n := len(merged); n > 0 && !in.Start.After(merged[n-1].End) {
+			if in.End.After(merged[n-1].End) {
+				merged[n-1].End = in.End
+			}
+			continue
+		}
+		merged = append(merged, in)
+	}
+	return merged
+}
+
+// subtract returns the parts of from not covered by any interval in cut.
+func subtract(from, cut []Interval) []Interval {
+	cut = merge(cut)
+	var result []Interval
+	for _, in := range merge(from) {
+		start := in.Start
+		for _, c := range cut {
+			if !c.End.After(start) || !c.Start.Before(in.End) {
+				continue
+			}
+			if c.Start.After(start) {
+				result = append(result, Interval{Start: start, End: c.Start})
+			}
+			start = c.End
+		}
+		if in.End.After(start) {
+			result = append(result, Interval{Start: start, End: in.End})
+		}
+	}
+	return result
+}
+
+// intersect returns the parts of from covered by an interval in with.
+func intersect(from, with []Interval) []Interval {
+	return subtract(from, subtract(from, with))
+}
+
+func clip(intervals []Interval, start, end time.Time) []Interval {
+	return intersect(intervals, []Interval{{Start: start, End: end}})
+}
@@ -0,0 +1,109 @@
+package gaps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// minutes builds intervals from pairs of minute offsets.
+func minutes(pairs ...int) []Interval {
+	var intervals []Interval
+	for i := 0; i+1 < len(pairs); i += 2 {
+		intervals = append(intervals, Interval{
+			Start: base.Add(time.Duration(pairs[i]) * time.Minute),
+			End:   base.Add(time.Duration(pairs[i+1]) * time.Minute),
+		})
+	}
+	return intervals
+}
+
+func format(intervals []Interval) string {
+	var parts []string
+	for _, in := range intervals {
+		parts = append(parts, fmt.Sprintf("%v-%v", in.Start.Sub(base).Minutes(), in.End.Sub(base).Minutes()))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []Interval
+		want string
+	}{
+		{"empty", nil, ""},
+		{"disjoint, unsorted", minutes(30, 40, 0, 10), "0-10 30-40"},
+		{"overlapping", minutes(0, 20, 10, 30), "0-30"},
+		{"touching", minutes(0, 10, 10, 20), "0-20"},
+		{"contained", minutes(0, 60, 10, 20), "0-60"},
+		{"empty and reversed intervals dropped", minutes(0, 0, 20, 10, 30, 40), "30-40"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(merge(tt.in)); got != tt.want {
+				t.Errorf("merge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, cut []Interval
+		want      string
+	}{
+		{"nothing to cut", minutes(0, 60), nil, "0-60"},
+		{"nothing to cut from", nil, minutes(0, 60), ""},
+		{"disjoint", minutes(0, 10), minutes(20, 30), "0-10"},
+		{"touching", minutes(0, 10), minutes(10, 20), "0-10"},
+		{"middle", minutes(0, 60), minutes(20, 30), "0-20 30-60"},
+		{"start", minutes(0, 60), minutes(-10, 20), "20-60"},
+		{"end", minutes(0, 60), minutes(50, 70), "0-50"},
+		{"everything", minutes(0, 60), minutes(-10, 70), ""},
+		{"several cuts", minutes(0, 60), minutes(40, 50, 10, 20, 15, 25), "0-10 25-40 50-60"},
+		{"one cut across two intervals", minutes(0, 20, 30, 60), minutes(10, 40), "0-10 40-60"},
+		{"overlapping from", minutes(0, 30, 20, 60), minutes(25, 35), "0-25 35-60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(subtract(tt.from, tt.cut)); got != tt.want {
+				t.Errorf("subtract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, with []Interval
+		want       string
+	}{
+		{"nothing in common", minutes(0, 10), minutes(20, 30), ""},
+		{"touching", minutes(0, 10), minutes(10, 20), ""},
+		{"overlap", minutes(0, 30), minutes(20, 40), "20-30"},
+		{"contained", minutes(0, 60), minutes(10, 20, 30, 40), "10-20 30-40"},
+		{"containing", minutes(10, 20), minutes(0, 60), "10-20"},
+		{"with nothing", minutes(0, 60), nil, ""},
+		{"several of each", minutes(0, 20, 40, 60), minutes(10, 50), "10-20 40-50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(intersect(tt.from, tt.with)); got != tt.want {
+				t.Errorf("intersect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClip(t *testing.T) {
+	got := clip(minutes(-30, 10, 20, 30, 50, 90), base, base.Add(time.Hour))
+	if want := "0-10 20-30 50-60"; format(got) != want {
+		t.Errorf("clip() = %q, want %q", format(got), want)
+	}
+}
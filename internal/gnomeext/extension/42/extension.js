@@ -0,0 +1,54 @@
+// Exports the focused window on the session bus for actionsum, as GNOME
+// Shell no longer lets other programs run org.gnome.Shell.Eval. Call
+// io.github.actionsum.FocusedWindow.Get on org.gnome.Shell at
+// /io/github/actionsum/FocusedWindow; it returns the window as JSON, or {}
+// when nothing is focused. This is the variant for GNOME 41 to 44, before
+// extensions became modules.
+
+const {Gio, Meta} = imports.gi;
+
+const OBJECT_PATH = '/io/github/actionsum/FocusedWindow';
+
+const INTERFACE = `
+<node>
+  <interface name="io.github.actionsum.FocusedWindow">
+    <method name="Get">
+      <arg type="s" direction="out" name="window"/>
+    </method>
+  </interface>
+</node>`;
+
+function focusedWindow() {
+    const win = global.display.get_focus_window();
+    if (!win)
+        return '{}';
+    const rect = win.get_frame_rect();
+    return JSON.stringify({
+        wmClass: win.get_wm_class() ?? '',
+        title: win.get_title() ?? '',
+        pid: win.get_pid(),
+        x: rect.x,
+        y: rect.y,
+        width: rect.width,
+        height: rect.height,
+        fullscreen: win.is_fullscreen(),
+        maximized: win.get_maximized() === Meta.MaximizeFlags.BOTH,
+        clientType: win.get_client_type(),
+    });
+}
+
+class Extension {
+    enable() {
+        this._object = Gio.DBusExportedObject.wrapJSObject(INTERFACE, {Get: focusedWindow});
+        this._object.export(Gio.DBus.session, OBJECT_PATH);
+    }
+
+    disable() {
+        this._object.unexport();
+        this._object = null;
+    }
+}
+
+function init() {
+    return new Extension();
+}
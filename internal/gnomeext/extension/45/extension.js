@@ -0,0 +1,58 @@
+// Exports the focused window on the session bus for actionsum, as GNOME
+// Shell no longer lets other programs run org.gnome.Shell.Eval. Call
+// io.github.actionsum.FocusedWindow.Get on org.gnome.Shell at
+// /io/github/actionsum/FocusedWindow; it returns the window as JSON, or {}
+// when nothing is focused.
+
+import Gio from 'gi://Gio';
+import Meta from 'gi://Meta';
+import {Extension} from 'resource:///org/gnome/shell/extensions/extension.js';
+
+const OBJECT_PATH = '/io/github/actionsum/FocusedWindow';
+
+const INTERFACE = `
+<node>
+  <interface name="io.github.actionsum.FocusedWindow">
+    <method name="Get">
+      <arg type="s" direction="out" name="window"/>
+    </method>
+  </interface>
+</node>`;
+
+function isMaximized(win) {
+    // GNOME 49 replaced get_maximized() with is_maximized().
+    if (typeof win.is_maximized === 'function')
+        return win.is_maximized();
+    return win.get_maximized() === Meta.MaximizeFlags.BOTH;
+}
+
+function focusedWindow() {
+    const win = global.display.get_focus_window();
+    if (!win)
+        return '{}';
+    const rect = win.get_frame_rect();
+    return JSON.stringify({
+        wmClass: win.get_wm_class() ?? '',
+        title: win.get_title() ?? '',
+        pid: win.get_pid(),
+        x: rect.x,
+        y: rect.y,
+        width: rect.width,
+        height: rect.height,
+        fullscreen: win.is_fullscreen(),
+        maximized: isMaximized(win),
+        clientType: win.get_client_type(),
+    });
+}
+
+export default class ActionsumExtension extends Extension {
+    enable() {
+        this._object = Gio.DBusExportedObject.wrapJSObject(INTERFACE, {Get: focusedWindow});
+        this._object.export(Gio.DBus.session, OBJECT_PATH);
+    }
+
+    disable() {
+        this._object.unexport();
+        this._object = null;
+    }
+}
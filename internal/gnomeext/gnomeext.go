@@ -0,0 +1,109 @@
+// Package gnomeext installs the GNOME Shell extension through which
+// actionsum sees the focused window on GNOME Wayland sessions, where GNOME
+// Shell blocks org.gnome.Shell.Eval for other programs.
+package gnomeext
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// UUID identifies the extension to GNOME Shell and names its directory.
+const UUID = "actionsum@actionsum.github.io"
+
+// files holds the extension in two variants: GNOME 45 made extensions ES
+// modules, which earlier releases cannot load.
+//
+//go:embed extension
+var files embed.FS
+
+var shellVersionPattern = regexp.MustCompile(`GNOME Shell (\d+)`)
+
+// Dir returns where the extension is installed for the user, honouring
+// XDG_DATA_HOME.
+func Dir() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(dataDir, "gnome-shell", "extensions", UUID), nil
+}
+
+// ShellVersion returns the major version of the installed GNOME Shell.
+func ShellVersion() (int, error) {
+	output, err := sandbox.Command("gnome-shell", "--version").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to run gnome-shell --version: %w", err)
+	}
+	return parseShellVersion(string(output))
+}
+
+// parseShellVersion reads output such as "GNOME Shell 46.2".
+func parseShellVersion(output string) (int, error) {
+	match := shellVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("unrecognised GNOME Shell version %q", output)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// variant is the directory holding the extension for a GNOME Shell major
+// version; unknown versions get the current one.
+func variant(major int) string {
+	if major > 0 && major < 45 {
+		return "42"
+	}
+	return "45"
+}
+
+// Install writes the extension for GNOME Shell major version shell, 0 if
+// unknown, replacing any earlier copy, and returns its directory.
+func Install(shell int) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create extension directory: %w", err)
+	}
+
+	src := path.Join("extension", variant(shell))
+	entries, err := fs.ReadDir(files, src)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(files, path.Join(src, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", entry.Name(), err)
+		}
+	}
+	return dir, nil
+}
+
+// Uninstall removes the extension. It is not an error if it isn't installed.
+func Uninstall() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to remove extension: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,57 @@
+package gnomeext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseShellVersion(t *testing.T) {
+	if got, err := parseShellVersion("GNOME Shell 46.2\n"); err != nil || got != 46 {
+		t.Errorf("parseShellVersion() = %d, %v; want 46", got, err)
+	}
+	if _, err := parseShellVersion("bash: gnome-shell: command not found"); err == nil {
+		t.Error("parseShellVersion() accepted output without a version")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		shell int
+		want  string
+	}{
+		{47, "export default class"},
+		{0, "export default class"},
+		{42, "function init()"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("XDG_DATA_HOME", t.TempDir())
+		dir, err := Install(tt.shell)
+		if err != nil {
+			t.Fatalf("Install(%d) error = %v", tt.shell, err)
+		}
+		if filepath.Base(dir) != UUID {
+			t.Errorf("Install() dir = %s, want one named %s", dir, UUID)
+		}
+		script, err := os.ReadFile(filepath.Join(dir, "extension.js"))
+		if err != nil {
+			t.Fatalf("extension.js: %v", err)
+		}
+		if !strings.Contains(string(script), tt.want) {
+			t.Errorf("Install(%d) wrote the wrong variant, missing %q", tt.shell, tt.want)
+		}
+		metadata, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+		if err != nil || !strings.Contains(string(metadata), UUID) {
+			t.Errorf("metadata.json = %s, %v", metadata, err)
+		}
+
+		if _, err := Uninstall(); err != nil {
+			t.Fatalf("Uninstall() error = %v", err)
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("Uninstall() left %s", dir)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package holiday keeps track of days off, public holidays and PTO, so that
+// goal streaks, averages and week-over-week comparisons can leave them out
+// instead of counting them as days nothing was done.
+package holiday
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+// Kinds of days off.
+const (
+	Holiday = "holiday"
+	PTO     = "pto"
+)
+
+const dayLayout = "2006-01-02"
+
+// Day is one day off.
+type Day struct {
+	Date string // YYYY-MM-DD
+	Kind string
+	Name string
+}
+
+// Days are days off keyed by date.
+type Days map[string]Day
+
+// Contains reports whether t falls on a day off.
+func (d Days) Contains(t time.Time) bool {
+	_, ok := d[t.Format(dayLayout)]
+	return ok
+}
+
+// Between counts the days off starting in [start, end).
+func (d Days) Between(start, end time.Time) int {
+	if len(d) == 0 {
+		return 0
+	}
+	count := 0
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !day.Before(start) && d.Contains(day) {
+			count++
+		}
+	}
+	return count
+}
+
+// On returns the days off that fall on the given weekdays, indexed by
+// time.Weekday.
+func (d Days) On(weekdays [7]bool) Days {
+	on := make(Days, len(d))
+	for date, day := range d {
+		t, err := time.Parse(dayLayout, date)
+		if err == nil && weekdays[t.Weekday()] {
+			on[date] = day
+		}
+	}
+	return on
+}
+
+// Sorted returns the days in date order.
+func (d Days) Sorted() []Day {
+	days := make([]Day, 0, len(d))
+	for _, day := range d {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// Load returns the days off marked in the holiday file and those in the
+// calendar, the file's taking precedence. A calendar that cannot be read is
+// logged and left out, so reports still work while a feed is unreachable.
+func Load(cfg config.HolidayConfig) Days {
+	days := make(Days)
+	if cfg.Calendar != "" {
+		calendar, err := readCalendar(cfg.Calendar)
+		if err != nil {
+			log.Printf("Holiday calendar: %v", err)
+		}
+		for date, day := range calendar {
+			days[date] = day
+		}
+	}
+	marked, err := ReadFile(cfg.File)
+	if err != nil {
+		log.Printf("Holiday file: %v", err)
+	}
+	for date, day := range marked {
+		days[date] = day
+	}
+	return days
+}
+
+// ParseDate parses a YYYY-MM-DD date in local time.
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(dayLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// ReadFile reads the days marked in file, one "YYYY-MM-DD kind [name]" per
+// line. A missing file has none.
+func ReadFile(file string) (Days, error) {
+	days := make(Days)
+	if file == "" {
+		return days, nil
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return days, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if _, err := ParseDate(fields[0]); err != nil {
+			continue
+		}
+		day := Day{Date: fields[0], Kind: Holiday}
+		if len(fields) > 1 && fields[1] == PTO {
+			day.Kind = PTO
+		}
+		if len(fields) > 2 {
+			day.Name = strings.TrimSpace(fields[2])
+		}
+		days[day.Date] = day
+	}
+	return days, scanner.Err()
+}
+
+// Add marks day in file as a day off, replacing what was marked on its date.
+func Add(file string, day Day) error {
+	if file == "" {
+		return fmt.Errorf("holiday file is not configured")
+	}
+	if _, err := ParseDate(day.Date); err != nil {
+		return err
+	}
+	if day.Kind != Holiday && day.Kind != PTO {
+		return fmt.Errorf("invalid kind %q: use %s or %s", day.Kind, Holiday, PTO)
+	}
+	days, err := ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read holidays: %w", err)
+	}
+	days[day.Date] = day
+	return writeFile(file, days)
+}
+
+// Remove unmarks date in file, returning whether it was marked.
+func Remove(file, date string) (bool, error) {
+	days, err := ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read holidays: %w", err)
+	}
+	if _, ok := days[date]; !ok {
+		return false, nil
+	}
+	delete(days, date)
+	return true, writeFile(file, days)
+}
+
+func writeFile(file string, days Days) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create holiday directory: %w", err)
+	}
+	var b strings.Builder
+	for _, day := range days.Sorted() {
+		fmt.Fprintf(&b, "%s %s", day.Date, day.Kind)
+		if day.Name != "" {
+			fmt.Fprintf(&b, " %s", day.Name)
+		}
+		b.WriteString("\n")
+	}
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save holidays: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package holiday
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+func TestParseICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20261225",
+		"DTEND;VALUE=DATE:20261227",
+		"SUMMARY:Christmas\\, Boxing",
+		"  Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260101",
+		"SUMMARY:New Year's Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20260310T090000Z",
+		"DTEND:20260310T100000Z",
+		"SUMMARY:Meeting",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260501",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	days, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Days{
+		"2026-12-25": {Date: "2026-12-25", Kind: Holiday, Name: "Christmas, Boxing Day"},
+		"2026-12-26": {Date: "2026-12-26", Kind: Holiday, Name: "Christmas, Boxing Day"},
+		"2026-01-01": {Date: "2026-01-01", Kind: Holiday, Name: "New Year's Day"},
+	}
+	if !reflect.DeepEqual(days, want) {
+		t.Errorf("ParseICS = %v, want %v", days, want)
+	}
+}
+
+func TestFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "holidays")
+	if err := Add(file, Day{Date: "2026-08-14", Kind: PTO, Name: "Long weekend"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(file, Day{Date: "2026-08-13", Kind: Holiday}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(file, Day{Date: "2026-08-12", Kind: "sick"}); err == nil {
+		t.Error("Add accepted an unknown kind")
+	}
+	if removed, err := Remove(file, "2026-08-13"); err != nil || !removed {
+		t.Errorf("Remove = %v, %v", removed, err)
+	}
+
+	days := Load(config.HolidayConfig{File: file})
+	want := Days{"2026-08-14": {Date: "2026-08-14", Kind: PTO, Name: "Long weekend"}}
+	if !reflect.DeepEqual(days, want) {
+		t.Errorf("Load = %v, want %v", days, want)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	days := Days{
+		"2026-12-25": {Date: "2026-12-25", Kind: Holiday},
+		"2026-12-28": {Date: "2026-12-28", Kind: PTO},
+	}
+	start := time.Date(2026, 12, 21, 0, 0, 0, 0, time.Local)
+	if got := days.Between(start, start.AddDate(0, 0, 7)); got != 1 {
+		t.Errorf("Between = %d, want 1", got)
+	}
+	if got := days.Between(start, start.AddDate(0, 0, 8)); got != 2 {
+		t.Errorf("Between = %d, want 2", got)
+	}
+	if !days.Contains(time.Date(2026, 12, 25, 15, 0, 0, 0, time.Local)) {
+		t.Error("Contains missed a day off")
+	}
+}
+
+// waitForFeed waits for the background download of src to finish.
+func waitForFeed(t *testing.T, src string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		feedsMu.Lock()
+		loading := feeds[src].loading
+		feedsMu.Unlock()
+		if !loading {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("calendar download did not finish")
+}
+
+func TestCalendarFeed(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var hits atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Write([]byte("BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261225\r\nSUMMARY:Christmas\r\nEND:VEVENT\r\n"))
+	}))
+	defer srv.Close()
+	cfg := config.HolidayConfig{Calendar: srv.URL + "/ok.ics"}
+
+	// The first load returns at once while the feed downloads.
+	if days := Load(cfg); len(days) != 0 {
+		t.Errorf("Load before the download = %v, want none", days)
+	}
+	Load(cfg)
+	close(release)
+	waitForFeed(t, cfg.Calendar)
+	if n := hits.Load(); n != 1 {
+		t.Errorf("feed fetched %d times, want 1", n)
+	}
+	want := Days{"2026-12-25": {Date: "2026-12-25", Kind: Holiday, Name: "Christmas"}}
+	if days := Load(cfg); !reflect.DeepEqual(days, want) {
+		t.Errorf("Load = %v, want %v", days, want)
+	}
+
+	// Another process starts from the cached download.
+	feedsMu.Lock()
+	delete(feeds, cfg.Calendar)
+	feedsMu.Unlock()
+	if days := Load(cfg); !reflect.DeepEqual(days, want) {
+		t.Errorf("Load from the cache = %v, want %v", days, want)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("feed fetched %d times after a fresh cache, want 1", n)
+	}
+}
+
+func TestCalendarFeedFailure(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	cfg := config.HolidayConfig{Calendar: srv.URL + "/down.ics"}
+
+	Load(cfg)
+	waitForFeed(t, cfg.Calendar)
+	for range 3 {
+		if days := Load(cfg); len(days) != 0 {
+			t.Errorf("Load = %v, want none", days)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("feed fetched %d times within the retry delay, want 1", n)
+	}
+
+	feedsMu.Lock()
+	retry := feeds[cfg.Calendar].retryAt.Sub(time.Now())
+	feedsMu.Unlock()
+	if retry <= 0 || retry > firstRetry {
+		t.Errorf("retry in %v, want within %v", retry, firstRetry)
+	}
+
+	if err := Refresh(cfg); err == nil {
+		t.Error("Refresh of an unavailable feed succeeded")
+	}
+	feedsMu.Lock()
+	retry = feeds[cfg.Calendar].retryAt.Sub(time.Now())
+	feedsMu.Unlock()
+	if retry <= firstRetry {
+		t.Errorf("retry after two failures in %v, want more than %v", retry, firstRetry)
+	}
+}
+
+func TestOn(t *testing.T) {
+	days := Days{
+		"2026-12-25": {Date: "2026-12-25", Kind: Holiday}, // Friday
+		"2026-12-26": {Date: "2026-12-26", Kind: Holiday}, // Saturday
+	}
+	weekdays := [7]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true}
+	want := Days{"2026-12-25": days["2026-12-25"]}
+	if got := days.On(weekdays); !reflect.DeepEqual(got, want) {
+		t.Errorf("On = %v, want %v", got, want)
+	}
+}
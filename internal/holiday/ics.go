@@ -0,0 +1,249 @@
+package holiday
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/statefile"
+)
+
+const (
+	// refreshInterval is how long a downloaded calendar is used before it
+	// is fetched again.
+	refreshInterval = 6 * time.Hour
+
+	// firstRetry is how long to wait before fetching a calendar again after
+	// a failed download. Each further failure doubles the wait, up to
+	// refreshInterval.
+	firstRetry = time.Minute
+
+	// maxEventDays bounds how many days one event may mark, against
+	// malformed end dates.
+	maxEventDays = 366
+
+	icsDateLayout = "20060102"
+)
+
+// feed is a calendar downloaded from a URL.
+type feed struct {
+	Days    Days      `json:"days"`
+	Fetched time.Time `json:"fetched"`
+
+	loading  bool
+	failures int
+	retryAt  time.Time
+}
+
+var (
+	feedsMu sync.Mutex
+	feeds   = make(map[string]*feed)
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// readCalendar reads the calendar at src, a file or an http(s) URL. A URL is
+// never fetched while the caller waits: the last download, kept in memory
+// and in the cache directory, is returned, and one older than
+// refreshInterval is fetched again in the background. After a failed
+// download the next attempt waits longer each time.
+func readCalendar(src string) (Days, error) {
+	if !isURL(src) {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ParseICS(f)
+	}
+
+	feedsMu.Lock()
+	defer feedsMu.Unlock()
+	cached, ok := feeds[src]
+	if !ok {
+		cached = &feed{}
+		statefile.ReadJSON(cachePath(src), cached)
+		feeds[src] = cached
+	}
+	now := time.Now()
+	if !cached.loading && now.Sub(cached.Fetched) >= refreshInterval && !now.Before(cached.retryAt) {
+		cached.loading = true
+		go func() {
+			if err := refresh(src, cached); err != nil {
+				log.Printf("Holiday calendar: %v", err)
+			}
+		}()
+	}
+	return cached.Days, nil
+}
+
+// Refresh downloads the calendar of cfg now, if it is a URL, for commands
+// that show the days off and can wait.
+func Refresh(cfg config.HolidayConfig) error {
+	if !isURL(cfg.Calendar) {
+		return nil
+	}
+	feedsMu.Lock()
+	cached, ok := feeds[cfg.Calendar]
+	if !ok {
+		cached = &feed{}
+		feeds[cfg.Calendar] = cached
+	}
+	cached.loading = true
+	feedsMu.Unlock()
+	return refresh(cfg.Calendar, cached)
+}
+
+// refresh downloads src into cached, whose loading flag the caller set.
+func refresh(src string, cached *feed) error {
+	days, err := fetchCalendar(src)
+
+	feedsMu.Lock()
+	defer feedsMu.Unlock()
+	cached.loading = false
+	if err != nil {
+		cached.failures++
+		wait := min(firstRetry<<(cached.failures-1), refreshInterval)
+		cached.retryAt = time.Now().Add(wait)
+		return fmt.Errorf("%w (retrying in %s)", err, wait)
+	}
+	cached.Days, cached.Fetched = days, time.Now()
+	cached.failures, cached.retryAt = 0, time.Time{}
+	if err := statefile.WriteJSON(cachePath(src), cached); err != nil {
+		log.Printf("Failed to cache holiday calendar: %v", err)
+	}
+	return nil
+}
+
+// cachePath is where the download of src is kept, so that commands which
+// exit before a download finishes still see the calendar.
+func cachePath(src string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(src))
+	return filepath.Join(dir, "actionsum", "holidays-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+func fetchCalendar(url string) (Days, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return ParseICS(resp.Body)
+}
+
+// ParseICS reads the all-day events of an iCalendar file as holidays. An
+// event covers its start date up to, but not including, its end date.
+// Timed, cancelled and recurring events are left out; holiday feeds
+// list each year's dates as events of their own.
+func ParseICS(r io.Reader) (Days, error) {
+	days := make(Days)
+	var (
+		inEvent    bool
+		start, end time.Time
+		name       string
+		skip       bool
+	)
+
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		property, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, _, _ := strings.Cut(property, ";")
+		switch strings.ToUpper(key) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, name, skip = true, time.Time{}, time.Time{}, "", false
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if skip || start.IsZero() {
+				continue
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day, n := start, 0; day.Before(end) && n < maxEventDays; day, n = day.AddDate(0, 0, 1), n+1 {
+				date := day.Format(dayLayout)
+				if _, ok := days[date]; !ok {
+					days[date] = Day{Date: date, Kind: Holiday, Name: name}
+				}
+			}
+		}
+		if !inEvent {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "DTSTART":
+			start, ok = allDay(value)
+			skip = skip || !ok
+		case "DTEND":
+			end, _ = allDay(value)
+		case "SUMMARY":
+			name = unescape(value)
+		case "STATUS":
+			skip = skip || strings.EqualFold(value, "CANCELLED")
+		case "RRULE":
+			skip = true
+		}
+	}
+	return days, nil
+}
+
+// unfold joins the continuation lines of an iCalendar file, which start
+// with a space or a tab, to the lines they continue.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// allDay parses a date value, as all-day events have, and reports false
+// for a date-time.
+func allDay(value string) (time.Time, bool) {
+	t, err := time.Parse(icsDateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(strings.TrimSpace(s))
+}
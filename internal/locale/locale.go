@@ -0,0 +1,131 @@
+// Package locale formats numbers and translates labels in reports and on the
+// dashboard.
+package locale
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+	"golang.org/x/text/number"
+)
+
+// periodLabels translates the report period names. English uses the names
+// as they are.
+var periodLabels = map[language.Tag]map[string]string{
+	language.German: {
+		"day": "Tag", "today": "Heute", "yesterday": "Gestern",
+		"week": "Woche", "lastweek": "Letzte Woche",
+		"month": "Monat", "lastmonth": "Letzter Monat",
+	},
+	language.French: {
+		"day": "jour", "today": "aujourd'hui", "yesterday": "hier",
+		"week": "semaine", "lastweek": "semaine dernière",
+		"month": "mois", "lastmonth": "mois dernier",
+	},
+	language.Spanish: {
+		"day": "día", "today": "hoy", "yesterday": "ayer",
+		"week": "semana", "lastweek": "semana pasada",
+		"month": "mes", "lastmonth": "mes pasado",
+	},
+}
+
+// messages holds every translation; supported records the base languages it
+// has any for. Both are only changed by LoadTranslations at startup.
+var (
+	messages  = catalog.NewBuilder(catalog.Fallback(language.English))
+	supported = map[language.Base]bool{baseOf(language.English): true}
+)
+
+func init() {
+	for _, set := range []map[language.Tag]map[string]string{periodLabels, dashboardMessages} {
+		for tag, translations := range set {
+			for key, msg := range translations {
+				messages.SetString(tag, key, msg)
+			}
+			supported[baseOf(tag)] = true
+		}
+	}
+}
+
+func baseOf(tag language.Tag) language.Base {
+	base, _ := tag.Base()
+	return base
+}
+
+type Locale struct {
+	tag     language.Tag
+	printer *message.Printer
+}
+
+// New returns the locale named by name, which may be a BCP 47 tag ("de-CH")
+// or a POSIX locale ("de_CH.UTF-8"). Empty or unknown names give English.
+func New(name string) *Locale {
+	tag := language.English
+	if name = normalize(name); name != "" {
+		if parsed, err := language.Parse(name); err == nil {
+			tag = parsed
+		}
+	}
+	return newLocale(tag)
+}
+
+func newLocale(tag language.Tag) *Locale {
+	return &Locale{tag: tag, printer: message.NewPrinter(tag, message.Catalog(messages))}
+}
+
+// Negotiate picks a locale from an Accept-Language header: the most preferred
+// language with translations, or else the most preferred one for number
+// formatting only. An empty or invalid header gives English.
+func Negotiate(acceptLanguage string) *Locale {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return New("")
+	}
+	for _, tag := range tags {
+		if supported[baseOf(tag)] {
+			return newLocale(tag)
+		}
+	}
+	return newLocale(tags[0])
+}
+
+// Tag returns the BCP 47 tag of the locale, for HTML lang attributes.
+func (l *Locale) Tag() string {
+	return l.tag.String()
+}
+
+// T translates msg, formatting any arguments into it like fmt.Sprintf.
+// Messages without a translation are used as they are.
+func (l *Locale) T(msg string, args ...interface{}) string {
+	return l.printer.Sprintf(msg, args...)
+}
+
+func normalize(name string) string {
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "C" || name == "POSIX" {
+		return ""
+	}
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// Decimal formats v with exactly digits fraction digits and the locale's
+// separators.
+func (l *Locale) Decimal(v float64, digits int) string {
+	return l.printer.Sprint(number.Decimal(v,
+		number.MinFractionDigits(digits), number.MaxFractionDigits(digits)))
+}
+
+// Percent formats a percentage between 0 and 100.
+func (l *Locale) Percent(v float64, digits int) string {
+	return l.printer.Sprint(number.Percent(v/100,
+		number.MinFractionDigits(digits), number.MaxFractionDigits(digits)))
+}
+
+// Period returns the translated label for a report period type.
+func (l *Locale) Period(periodType string) string {
+	return l.printer.Sprintf(periodType)
+}
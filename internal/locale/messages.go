@@ -0,0 +1,233 @@
+package locale
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// dashboardMessages translates the dashboard's text, keyed by the English
+// message. Keys containing verbs are formatted with Sprintf.
+var dashboardMessages = map[language.Tag]map[string]string{
+	language.German: {
+		"Actionsum Dashboard":           "Actionsum-Übersicht",
+		"Filter by profile":             "Nach Profil filtern",
+		"All profiles":                  "Alle Profile",
+		"Toggle bar chart":              "Balkendiagramm umschalten",
+		"Toggle theme":                  "Design umschalten",
+		"Today":                         "Heute",
+		"This Week":                     "Diese Woche",
+		"This Month":                    "Dieser Monat",
+		"Focus":                         "Fokus",
+		"Loading...":                    "Wird geladen …",
+		"No data available":             "Keine Daten vorhanden",
+		"Total: %s":                     "Gesamt: %s",
+		"day streak":                    "Tage in Folge",
+		"Longest streak":                "Längste Serie",
+		"%d days":                       "%d Tage",
+		"Daily goal (%s)":               "Tagesziel (%s)",
+		"met":                           "erreicht",
+		"not yet":                       "noch nicht",
+		"Longest session":               "Längste Sitzung",
+		"%s in %s":                      "%s in %s",
+		"Full dashboard":                "Vollständige Übersicht",
+		"Now":                           "Jetzt",
+		"Tracking paused":               "Erfassung pausiert",
+		"Paused until %s":               "Pausiert bis %s",
+		"Nothing tracked yet":           "Noch nichts erfasst",
+		"Top apps":                      "Top-Apps",
+		"Pause tracking":                "Erfassung pausieren",
+		"Resume tracking":               "Erfassung fortsetzen",
+		"Recent Activity":               "Letzte Aktivität",
+		"Not saved yet":                 "Noch nicht gespeichert",
+		"Idle":                          "Inaktiv",
+		"Screen locked":                 "Bildschirm gesperrt",
+		"Outside work hours":            "Außerhalb der Arbeitszeit",
+		"Not tracking":                  "Keine Erfassung",
+		"Daily Trend":                   "Tagesverlauf",
+		"Top Window Titles":             "Häufigste Fenstertitel",
+		"Longest Sessions":              "Längste Sitzungen",
+		"Hour of Day":                   "Tageszeit",
+		"Search apps and window titles": "Apps und Fenstertitel durchsuchen",
+		"Search Results":                "Suchergebnisse",
+		"Time per app":                  "Zeit pro App",
+		"Keyboard shortcuts":            "Tastenkürzel",
+		"Search":                        "Suchen",
+		"Close":                         "Schließen",
+		"Period":                        "Zeitraum",
+		"Share":                         "Teilen",
+		"Period to share":               "Zu teilender Zeitraum",
+		"Hide titles":                   "Titel ausblenden",
+		"Sign out":                      "Abmelden",
+		"Leave window titles and notes out of the shared report": "Fenstertitel und Notizen nicht in den geteilten Bericht aufnehmen",
+		"Download a report to share":                             "Bericht zum Teilen herunterladen",
+		"No matches":                                             "Keine Treffer",
+		"Timeline":                                               "Zeitleiste",
+	},
+	language.French: {
+		"Actionsum Dashboard":           "Tableau de bord Actionsum",
+		"Filter by profile":             "Filtrer par profil",
+		"All profiles":                  "Tous les profils",
+		"Toggle bar chart":              "Afficher ou masquer les barres",
+		"Toggle theme":                  "Changer de thème",
+		"Today":                         "Aujourd'hui",
+		"This Week":                     "Cette semaine",
+		"This Month":                    "Ce mois-ci",
+		"Focus":                         "Concentration",
+		"Loading...":                    "Chargement…",
+		"No data available":             "Aucune donnée disponible",
+		"Total: %s":                     "Total : %s",
+		"day streak":                    "jours d'affilée",
+		"Longest streak":                "Plus longue série",
+		"%d days":                       "%d jours",
+		"Daily goal (%s)":               "Objectif quotidien (%s)",
+		"met":                           "atteint",
+		"not yet":                       "pas encore",
+		"Longest session":               "Plus longue session",
+		"%s in %s":                      "%s dans %s",
+		"Full dashboard":                "Tableau de bord complet",
+		"Now":                           "Maintenant",
+		"Tracking paused":               "Suivi en pause",
+		"Paused until %s":               "En pause jusqu'à %s",
+		"Nothing tracked yet":           "Rien d'enregistré pour l'instant",
+		"Top apps":                      "Applications principales",
+		"Pause tracking":                "Mettre le suivi en pause",
+		"Resume tracking":               "Reprendre le suivi",
+		"Recent Activity":               "Activité récente",
+		"Not saved yet":                 "Pas encore enregistré",
+		"Idle":                          "Inactif",
+		"Screen locked":                 "Écran verrouillé",
+		"Outside work hours":            "Hors des heures de travail",
+		"Not tracking":                  "Aucun suivi",
+		"Daily Trend":                   "Tendance quotidienne",
+		"Top Window Titles":             "Titres de fenêtre principaux",
+		"Longest Sessions":              "Sessions les plus longues",
+		"Hour of Day":                   "Heure de la journée",
+		"Search apps and window titles": "Rechercher dans les applications et les titres de fenêtre",
+		"Search Results":                "Résultats de recherche",
+		"Time per app":                  "Temps par application",
+		"Keyboard shortcuts":            "Raccourcis clavier",
+		"Search":                        "Rechercher",
+		"Close":                         "Fermer",
+		"Period":                        "Période",
+		"Share":                         "Partager",
+		"Period to share":               "Période à partager",
+		"Hide titles":                   "Masquer les titres",
+		"Sign out":                      "Se déconnecter",
+		"Leave window titles and notes out of the shared report": "Exclure les titres de fenêtres et les notes du rapport partagé",
+		"Download a report to share":                             "Télécharger un rapport à partager",
+		"No matches":                                             "Aucun résultat",
+		"Timeline":                                               "Chronologie",
+	},
+	language.Spanish: {
+		"Actionsum Dashboard":           "Panel de Actionsum",
+		"Filter by profile":             "Filtrar por perfil",
+		"All profiles":                  "Todos los perfiles",
+		"Toggle bar chart":              "Mostrar u ocultar barras",
+		"Toggle theme":                  "Cambiar tema",
+		"Today":                         "Hoy",
+		"This Week":                     "Esta semana",
+		"This Month":                    "Este mes",
+		"Focus":                         "Concentración",
+		"Loading...":                    "Cargando…",
+		"No data available":             "No hay datos disponibles",
+		"Total: %s":                     "Total: %s",
+		"day streak":                    "días seguidos",
+		"Longest streak":                "Racha más larga",
+		"%d days":                       "%d días",
+		"Daily goal (%s)":               "Objetivo diario (%s)",
+		"met":                           "cumplido",
+		"not yet":                       "todavía no",
+		"Longest session":               "Sesión más larga",
+		"%s in %s":                      "%s en %s",
+		"Full dashboard":                "Panel completo",
+		"Now":                           "Ahora",
+		"Tracking paused":               "Seguimiento en pausa",
+		"Paused until %s":               "En pausa hasta las %s",
+		"Nothing tracked yet":           "Todavía no hay registros",
+		"Top apps":                      "Aplicaciones principales",
+		"Pause tracking":                "Pausar seguimiento",
+		"Resume tracking":               "Reanudar seguimiento",
+		"Recent Activity":               "Actividad reciente",
+		"Not saved yet":                 "Aún no guardado",
+		"Idle":                          "Inactivo",
+		"Screen locked":                 "Pantalla bloqueada",
+		"Outside work hours":            "Fuera del horario laboral",
+		"Not tracking":                  "Sin seguimiento",
+		"Daily Trend":                   "Tendencia diaria",
+		"Top Window Titles":             "Títulos de ventana principales",
+		"Longest Sessions":              "Sesiones más largas",
+		"Hour of Day":                   "Hora del día",
+		"Search apps and window titles": "Buscar en aplicaciones y títulos de ventana",
+		"Search Results":                "Resultados de búsqueda",
+		"Time per app":                  "Tiempo por aplicación",
+		"Keyboard shortcuts":            "Atajos de teclado",
+		"Search":                        "Buscar",
+		"Close":                         "Cerrar",
+		"Period":                        "Periodo",
+		"Share":                         "Compartir",
+		"Period to share":               "Periodo a compartir",
+		"Hide titles":                   "Ocultar títulos",
+		"Sign out":                      "Cerrar sesión",
+		"Leave window titles and notes out of the shared report": "No incluir títulos de ventanas ni notas en el informe compartido",
+		"Download a report to share":                             "Descargar un informe para compartir",
+		"No matches":                                             "Sin coincidencias",
+		"Timeline":                                               "Cronología",
+	},
+}
+
+// LoadTranslations adds the translations in dir to the catalog. Each file is
+// named after a language tag, such as "it.conf" or "pt-BR.conf", and holds
+// "English message = translation" lines; blank lines and lines starting with
+// # are ignored. Entries override the built-in ones. A missing directory is
+// not an error.
+func LoadTranslations(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return fmt.Errorf("failed to list translations: %w", err)
+	}
+
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), ".conf")
+		tag, err := language.Parse(normalize(name))
+		if err != nil {
+			return fmt.Errorf("translation file %s: unknown language %q", path, name)
+		}
+		if err := loadTranslationFile(path, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadTranslationFile(path string, tag language.Tag) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open translations: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, msg, ok := strings.Cut(text, " = ")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected \"message = translation\"", path, line)
+		}
+		if err := messages.SetString(tag, strings.TrimSpace(key), strings.TrimSpace(msg)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		supported[baseOf(tag)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read translations: %w", err)
+	}
+	return nil
+}
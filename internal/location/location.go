@@ -0,0 +1,130 @@
+// Package location labels where the user is working, such as "office" or
+// "home", from the Wi-Fi network the machine is connected to.
+package location
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// checkInterval is how long a network lookup is reused; it runs a tool, and
+// machines rarely change networks more often.
+const checkInterval = time.Minute
+
+// Resolver maps the current Wi-Fi network to a configured location.
+type Resolver struct {
+	networks map[string]string
+	fallback string
+	ssid     func() (string, error)
+
+	mu        sync.Mutex
+	location  string
+	checkedAt time.Time
+}
+
+// New returns nil when no locations are configured, so nothing is looked up.
+func New(cfg *config.Config) *Resolver {
+	if len(cfg.Locations.Networks) == 0 && cfg.Locations.Default == "" {
+		return nil
+	}
+	return &Resolver{networks: cfg.Locations.Networks, fallback: cfg.Locations.Default, ssid: CurrentSSID}
+}
+
+// Location returns where the user is as of now, or "" when r is nil or
+// the network is unknown and there is no default.
+func (r *Resolver) Location(now time.Time) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.checkedAt.IsZero() && now.Sub(r.checkedAt) < checkInterval && now.After(r.checkedAt) {
+		return r.location
+	}
+	r.checkedAt = now
+	r.location = r.fallback
+	// Without Wi-Fi, or where the network can't be read, the default applies.
+	if ssid, err := r.ssid(); err == nil {
+		if location, ok := r.networks[ssid]; ok {
+			r.location = location
+		}
+	}
+	return r.location
+}
+
+// CurrentSSID returns the name of the Wi-Fi network the machine is
+// connected to, from NetworkManager or iwgetid on Linux, networksetup on
+// macOS and netsh on Windows.
+func CurrentSSID() (string, error) {
+	var ssid string
+	switch runtime.GOOS {
+	case "darwin":
+		output, err := sandbox.Command("networksetup", "-getairportnetwork", "en0").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run networksetup: %w", err)
+		}
+		ssid = parseNetworksetup(string(output))
+	case "windows":
+		output, err := sandbox.Command("netsh", "wlan", "show", "interfaces").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run netsh: %w", err)
+		}
+		ssid = parseNetsh(string(output))
+	default:
+		if output, err := sandbox.Command("nmcli", "-t", "-f", "active,ssid", "dev", "wifi").Output(); err == nil {
+			ssid = parseNmcli(string(output))
+		} else if output, err := sandbox.Command("iwgetid", "-r").Output(); err == nil {
+			ssid = strings.TrimSpace(string(output))
+		} else {
+			return "", fmt.Errorf("neither nmcli nor iwgetid can tell the Wi-Fi network")
+		}
+	}
+	if ssid == "" {
+		return "", fmt.Errorf("not connected to Wi-Fi")
+	}
+	return ssid, nil
+}
+
+// parseNmcli finds the active network in "nmcli -t -f active,ssid dev wifi"
+// output, lines such as "yes:Office WiFi", where colons in the name are
+// escaped as "\:".
+func parseNmcli(output string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		active, ssid, ok := strings.Cut(scanner.Text(), ":")
+		if ok && active == "yes" {
+			return strings.ReplaceAll(strings.ReplaceAll(ssid, `\:`, ":"), `\\`, `\`)
+		}
+	}
+	return ""
+}
+
+// parseNetworksetup reads "Current Wi-Fi Network: Office WiFi".
+func parseNetworksetup(output string) string {
+	_, ssid, ok := strings.Cut(strings.TrimSpace(output), "Network: ")
+	if !ok {
+		return ""
+	}
+	return ssid
+}
+
+// parseNetsh finds the "SSID : name" line of "netsh wlan show interfaces",
+// which also has a BSSID line.
+func parseNetsh(output string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == "SSID" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package location
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+func TestNewWithoutLocations(t *testing.T) {
+	r := New(config.Default())
+	if r != nil {
+		t.Fatalf("New() = %v, want nil without configured locations", r)
+	}
+	if got := r.Location(time.Now()); got != "" {
+		t.Errorf("nil Resolver.Location() = %q, want empty", got)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	cfg := config.Default()
+	cfg.Locations.Networks = map[string]string{"Office WiFi": "office", "HomeNet": "home"}
+	cfg.Locations.Default = "travel"
+	r := New(cfg)
+
+	ssid, err := "Office WiFi", error(nil)
+	lookups := 0
+	r.ssid = func() (string, error) {
+		lookups++
+		return ssid, err
+	}
+
+	now := time.Date(2025, 5, 6, 9, 0, 0, 0, time.UTC)
+	if got := r.Location(now); got != "office" {
+		t.Errorf("Location() = %q, want office", got)
+	}
+
+	ssid = "HomeNet"
+	if got := r.Location(now.Add(30 * time.Second)); got != "office" || lookups != 1 {
+		t.Errorf("Location() = %q after %d lookups, want the cached office", got, lookups)
+	}
+	if got := r.Location(now.Add(checkInterval)); got != "home" {
+		t.Errorf("Location() = %q, want home", got)
+	}
+
+	ssid = "Airport Free WiFi"
+	if got := r.Location(now.Add(2 * checkInterval)); got != "travel" {
+		t.Errorf("Location() = %q on an unknown network, want travel", got)
+	}
+
+	ssid, err = "", errors.New("not connected to Wi-Fi")
+	if got := r.Location(now.Add(3 * checkInterval)); got != "travel" {
+		t.Errorf("Location() = %q without Wi-Fi, want travel", got)
+	}
+}
+
+func TestParseNmcli(t *testing.T) {
+	output := "no:Neighbours\nyes:Cafe\\: Guest\nno:\n"
+	if got := parseNmcli(output); got != "Cafe: Guest" {
+		t.Errorf("parseNmcli() = %q, want %q", got, "Cafe: Guest")
+	}
+	if got := parseNmcli("no:Neighbours\n"); got != "" {
+		t.Errorf("parseNmcli() = %q without an active network, want empty", got)
+	}
+}
+
+func TestParseNetworksetup(t *testing.T) {
+	if got := parseNetworksetup("Current Wi-Fi Network: Office WiFi\n"); got != "Office WiFi" {
+		t.Errorf("parseNetworksetup() = %q", got)
+	}
+	if got := parseNetworksetup("You are not associated with an AirPort network.\n"); got != "" {
+		t.Errorf("parseNetworksetup() = %q when not connected, want empty", got)
+	}
+}
+
+func TestParseNetsh(t *testing.T) {
+	output := `
+There is 1 interface on the system:
+
+    Name                   : Wi-Fi
+    State                  : connected
+    SSID                   : HomeNet
+    BSSID                  : 12:34:56:78:9a:bc
+`
+	if got := parseNetsh(output); got != "HomeNet" {
+		t.Errorf("parseNetsh() = %q, want HomeNet", got)
+	}
+}
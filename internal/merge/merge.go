@@ -0,0 +1,342 @@
+// Package merge copies the events and notes of another actionsum database
+// into the current one, for consolidating machines without running sync.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// pageSize is how many events are read from the other database at a time.
+const pageSize = 1000
+
+// overlapTolerance is how far two events may overlap before it counts as a
+// conflict, as much as the repository allows when storing events.
+const overlapTolerance = time.Second
+
+const (
+	// ConflictUUID marks an event whose UUID exists here with other content.
+	ConflictUUID = "uuid"
+
+	// ConflictTimestamp marks an event recorded at the same instant as an
+	// event here in a different app.
+	ConflictTimestamp = "timestamp"
+
+	// ConflictOverlap marks an event whose time overlaps an event here, or
+	// one merged before it.
+	ConflictOverlap = "overlap"
+)
+
+// Conflict is an event of the other database that was not merged because it
+// disagrees with an event here. The current event is always kept.
+type Conflict struct {
+	Kind    string
+	Current *models.FocusEvent
+	Other   *models.FocusEvent
+}
+
+func (c Conflict) String() string {
+	switch c.Kind {
+	case ConflictUUID:
+		return fmt.Sprintf("%s: event %s differs (here %s %q %ds, other %s %q %ds)",
+			c.Other.Timestamp.Local().Format("2006-01-02 15:04:05"), c.Other.UUID,
+			c.Current.Timestamp.Local().Format("15:04:05"), c.Current.AppName, c.Current.Duration,
+			c.Other.Timestamp.Local().Format("15:04:05"), c.Other.AppName, c.Other.Duration)
+	case ConflictOverlap:
+		return fmt.Sprintf("%s: overlaps %s %s-%s here (other %s %ds)",
+			c.Other.Timestamp.Local().Format("2006-01-02 15:04:05"), c.Current.AppName,
+			c.Current.Timestamp.Local().Format("15:04:05"), end(c.Current).Local().Format("15:04:05"),
+			c.Other.AppName, c.Other.Duration)
+	default:
+		return fmt.Sprintf("%s: here %s, other %s",
+			c.Other.Timestamp.Local().Format("2006-01-02 15:04:05"), c.Current.AppName, c.Other.AppName)
+	}
+}
+
+type Result struct {
+	DryRun bool
+
+	// Scanned is the number of events read from the other database.
+	Scanned int
+
+	// Added is the number of events copied, or that would be copied.
+	Added int
+
+	// Invalid are events that could not be stored, for a zero or future
+	// timestamp or a negative duration.
+	Invalid int
+
+	// Duplicates are events already present here, by UUID or by timestamp
+	// and app.
+	Duplicates int
+
+	// Deleted are events whose UUID belongs to an event deleted here; they
+	// stay deleted.
+	Deleted int
+
+	Conflicts []Conflict
+
+	NotesAdded      int
+	NotesDuplicates int
+}
+
+// Run merges the events and notes of other into repo. Events are matched by
+// UUID first, then by timestamp: an event with a known UUID and the same
+// content, or recorded at the same instant in the same app, is a duplicate.
+// Disagreeing events, and events whose time overlaps one here, are reported
+// as conflicts and left out, so events here are never changed. With dryRun
+// nothing is written; otherwise everything is merged in one transaction.
+// progress, when not nil, is told how many of the other database's events
+// have been scanned.
+func Run(repo, other *database.Repository, dryRun bool, progress func(done, total int64)) (*Result, error) {
+	if progress == nil {
+		progress = func(done, total int64) {}
+	}
+	if dryRun {
+		return run(repo, other, true, progress)
+	}
+
+	var result *Result
+	err := repo.WithTx(func(tx *database.Repository) error {
+		var err error
+		result, err = run(tx, other, false, progress)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func run(repo, other *database.Repository, dryRun bool, progress func(done, total int64)) (*Result, error) {
+	result := &Result{DryRun: dryRun}
+	total, err := other.CountEvents(database.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Events added by this merge, for duplicates within the other database
+	// and across page boundaries.
+	added := make(map[int64]*models.FocusEvent)
+	// The merged event ending last; events come in timestamp order, so only
+	// it can overlap the next one.
+	var lastAdded *models.FocusEvent
+
+	var after time.Time
+	var afterID uint
+	for {
+		events, err := other.GetEventsAfter(after, afterID, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			break
+		}
+		last := events[len(events)-1]
+		after, afterID = last.Timestamp, last.ID
+
+		uuids := make([]string, len(events))
+		for i, event := range events {
+			uuids[i] = event.UUID
+		}
+		byUUID, err := repo.GetEventsByUUID(uuids)
+		if err != nil {
+			return nil, err
+		}
+		// Stored events that could overlap the page, found from the end of
+		// the longest event in it.
+		until := last.Timestamp.Add(time.Nanosecond)
+		for _, event := range events {
+			if e := end(event); e.After(until) {
+				until = e
+			}
+		}
+		existing, err := repo.GetEventsOverlapping(events[0].Timestamp, until)
+		if err != nil {
+			return nil, err
+		}
+		byTime := make(map[int64]*models.FocusEvent, len(existing))
+		for _, event := range existing {
+			byTime[event.Timestamp.UnixNano()] = event
+		}
+		timeline := newTimeline(existing)
+
+		var batch []*models.FocusEvent
+		for _, event := range events {
+			result.Scanned++
+			if current, ok := byUUID[event.UUID]; ok {
+				switch {
+				case current.DeletedAt.Valid:
+					result.Deleted++
+				case sameEvent(repo, current, event):
+					result.Duplicates++
+				default:
+					result.Conflicts = append(result.Conflicts, Conflict{Kind: ConflictUUID, Current: current, Other: event})
+				}
+				continue
+			}
+
+			key := event.Timestamp.UnixNano()
+			current, ok := byTime[key]
+			if !ok {
+				current, ok = added[key]
+			}
+			if ok {
+				if repo.NormalizeAppName(current.AppName) == repo.NormalizeAppName(event.AppName) {
+					result.Duplicates++
+				} else {
+					result.Conflicts = append(result.Conflicts, Conflict{Kind: ConflictTimestamp, Current: current, Other: event})
+				}
+				continue
+			}
+
+			if err := repo.ValidateEvent(event); err != nil {
+				if !errors.Is(err, database.ErrInvalidEvent) {
+					return nil, err
+				}
+				result.Invalid++
+				continue
+			}
+			if current := timeline.overlap(event); current != nil {
+				result.Conflicts = append(result.Conflicts, Conflict{Kind: ConflictOverlap, Current: current, Other: event})
+				continue
+			}
+			if lastAdded != nil && overlaps(lastAdded, event) {
+				result.Conflicts = append(result.Conflicts, Conflict{Kind: ConflictOverlap, Current: lastAdded, Other: event})
+				continue
+			}
+
+			// Stored anew, so the copy counts as a change since the other
+			// database's events were created.
+			event.ID = 0
+			event.CreatedAt = time.Time{}
+			event.UpdatedAt = time.Time{}
+			added[key] = event
+			if lastAdded == nil || end(event).After(end(lastAdded)) {
+				lastAdded = event
+			}
+			batch = append(batch, event)
+		}
+		progress(int64(result.Scanned), total)
+
+		if dryRun {
+			result.Added += len(batch)
+		} else if len(batch) > 0 {
+			if err := repo.CreateBatch(batch); err != nil {
+				return nil, err
+			}
+			for _, event := range batch {
+				if event.ID != 0 {
+					result.Added++
+				}
+			}
+		}
+		if len(events) < pageSize {
+			break
+		}
+	}
+
+	if err := mergeNotes(repo, other, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func sameEvent(repo *database.Repository, a, b *models.FocusEvent) bool {
+	return a.Timestamp.Equal(b.Timestamp) &&
+		repo.NormalizeAppName(a.AppName) == repo.NormalizeAppName(b.AppName) &&
+		a.WindowTitle == b.WindowTitle &&
+		a.Duration == b.Duration
+}
+
+func end(event *models.FocusEvent) time.Time {
+	return event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+}
+
+// overlaps reports whether the earlier of a and b runs into the later one by
+// more than overlapTolerance.
+func overlaps(a, b *models.FocusEvent) bool {
+	if b.Timestamp.Before(a.Timestamp) {
+		a, b = b, a
+	}
+	return end(a).Sub(b.Timestamp) > overlapTolerance
+}
+
+// timeline finds the stored events an event overlaps.
+type timeline struct {
+	events []*models.FocusEvent // in timestamp order
+
+	// longest[i] is the event ending last among events[:i+1].
+	longest []*models.FocusEvent
+}
+
+func newTimeline(events []*models.FocusEvent) *timeline {
+	t := &timeline{events: events, longest: make([]*models.FocusEvent, len(events))}
+	for i, event := range events {
+		t.longest[i] = event
+		if i > 0 && end(t.longest[i-1]).After(end(event)) {
+			t.longest[i] = t.longest[i-1]
+		}
+	}
+	return t
+}
+
+// overlap returns a stored event that event overlaps, or nil.
+func (t *timeline) overlap(event *models.FocusEvent) *models.FocusEvent {
+	i := sort.Search(len(t.events), func(i int) bool {
+		return !t.events[i].Timestamp.Before(event.Timestamp)
+	})
+	if i > 0 && overlaps(t.longest[i-1], event) {
+		return t.longest[i-1]
+	}
+	for ; i < len(t.events) && t.events[i].Timestamp.Before(end(event)); i++ {
+		if overlaps(event, t.events[i]) {
+			return t.events[i]
+		}
+	}
+	return nil
+}
+
+// mergeNotes copies notes not already present with the same span and text.
+func mergeNotes(repo, other *database.Repository, result *Result) error {
+	all := [2]time.Time{{}, time.Now().AddDate(100, 0, 0)}
+	otherNotes, err := other.GetNotes(all[0], all[1])
+	if err != nil {
+		return err
+	}
+	currentNotes, err := repo.GetNotes(all[0], all[1])
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		start, end int64
+		text       string
+	}
+	seen := make(map[key]bool, len(currentNotes))
+	for _, note := range currentNotes {
+		seen[key{note.Start.Unix(), note.End.Unix(), note.Text}] = true
+	}
+	for _, note := range otherNotes {
+		k := key{note.Start.Unix(), note.End.Unix(), note.Text}
+		if seen[k] {
+			result.NotesDuplicates++
+			continue
+		}
+		seen[k] = true
+		result.NotesAdded++
+		if result.DryRun {
+			continue
+		}
+		copied := &models.Note{Start: note.Start, End: note.End, Text: note.Text}
+		if err := repo.CreateNote(copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package merge
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+var base = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func event(uuid string, offset, seconds int64, app string) *models.FocusEvent {
+	return &models.FocusEvent{
+		UUID:          uuid,
+		Timestamp:     base.Add(time.Duration(offset) * time.Second),
+		AppName:       app,
+		WindowTitle:   app + " window",
+		Duration:      seconds,
+		DisplayServer: "wayland",
+	}
+}
+
+func create(t *testing.T, repo *database.Repository, events ...*models.FocusEvent) {
+	t.Helper()
+	for _, e := range events {
+		if err := repo.Create(e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+}
+
+func durations(t *testing.T, repo *database.Repository) map[string]int64 {
+	t.Helper()
+	events, err := repo.GetEvents(database.Query{})
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	got := make(map[string]int64, len(events))
+	for _, e := range events {
+		got[e.UUID] = e.Duration
+	}
+	return got
+}
+
+func TestRun(t *testing.T) {
+	here := database.NewRepository(database.OpenTest(t))
+	otherDB := database.OpenTest(t)
+	other := database.NewRepository(otherDB)
+
+	create(t, here,
+		event("same", 0, 60, "code"),
+		event("differs", 100, 60, "code"),
+		event("deleted", 200, 60, "code"),
+		event("local-a", 300, 60, "code"),
+		event("local-b", 400, 60, "code"),
+		event("local-c", 500, 60, "code"),
+	)
+	deleted, err := here.GetByUUID("deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := here.DeleteEvents([]uint{deleted.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := here.CreateNote(&models.Note{Start: base, End: base.Add(time.Hour), Text: "standup"}); err != nil {
+		t.Fatal(err)
+	}
+
+	create(t, other,
+		event("same", 0, 60, "code"),         // duplicate by UUID
+		event("differs", 100, 30, "code"),    // UUID conflict
+		event("deleted", 200, 60, "code"),    // deleted here
+		event("other-a", 300, 60, "code"),    // duplicate by timestamp
+		event("other-b", 400, 60, "slack"),   // timestamp conflict
+		event("other-c", 530, 20, "slack"),   // runs inside local-c
+		event("other-d", 590, 30, "slack"),   // runs into local-e
+		event("other-e", 700, 60, "firefox"), // new
+		event("other-f", 1000, 60, "code"),   // new
+	)
+	// The other database may hold events that were stored before they were
+	// validated.
+	if err := otherDB.Create(event("invalid", 1200, -5, "code")).Error; err != nil {
+		t.Fatal(err)
+	}
+	create(t, here, event("local-e", 610, 30, "code"))
+
+	if err := other.CreateNote(&models.Note{Start: base, End: base.Add(time.Hour), Text: "standup"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.CreateNote(&models.Note{Start: base, End: base.Add(time.Hour), Text: "planning"}); err != nil {
+		t.Fatal(err)
+	}
+
+	before := durations(t, here)
+
+	dry, err := Run(here, other, true, nil)
+	if err != nil {
+		t.Fatalf("Run(dryRun) error = %v", err)
+	}
+	if got := durations(t, here); fmt.Sprint(got) != fmt.Sprint(before) {
+		t.Fatalf("dry run changed events: %v, want %v", got, before)
+	}
+	if notes, _ := here.GetNotes(time.Time{}, base.AddDate(1, 0, 0)); len(notes) != 1 {
+		t.Fatalf("dry run wrote notes: %d", len(notes))
+	}
+
+	result, err := Run(here, other, false, nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, r := range []*Result{dry, result} {
+		if r.Scanned != 10 || r.Added != 2 || r.Duplicates != 2 || r.Deleted != 1 || r.Invalid != 1 {
+			t.Errorf("DryRun=%v: scanned %d, added %d, duplicates %d, deleted %d, invalid %d; want 10, 2, 2, 1, 1",
+				r.DryRun, r.Scanned, r.Added, r.Duplicates, r.Deleted, r.Invalid)
+		}
+		if r.NotesAdded != 1 || r.NotesDuplicates != 1 {
+			t.Errorf("DryRun=%v: notes added %d, duplicates %d; want 1, 1", r.DryRun, r.NotesAdded, r.NotesDuplicates)
+		}
+		var kinds []string
+		for _, c := range r.Conflicts {
+			kinds = append(kinds, c.Kind+":"+c.Other.UUID+"/"+c.Current.UUID)
+		}
+		want := []string{"uuid:differs/differs", "timestamp:other-b/local-b", "overlap:other-c/local-c", "overlap:other-d/local-e"}
+		if fmt.Sprint(kinds) != fmt.Sprint(want) {
+			t.Errorf("DryRun=%v: conflicts %v, want %v", r.DryRun, kinds, want)
+		}
+	}
+
+	got := durations(t, here)
+	for uuid, d := range before {
+		if got[uuid] != d {
+			t.Errorf("event %s: duration %d, want %d unchanged", uuid, got[uuid], d)
+		}
+	}
+	for _, uuid := range []string{"other-e", "other-f"} {
+		if _, ok := got[uuid]; !ok {
+			t.Errorf("event %s was not merged", uuid)
+		}
+	}
+	if len(got) != len(before)+2 {
+		t.Errorf("%d events after merge, want %d", len(got), len(before)+2)
+	}
+	if notes, _ := here.GetNotes(time.Time{}, base.AddDate(1, 0, 0)); len(notes) != 2 {
+		t.Errorf("%d notes after merge, want 2", len(notes))
+	}
+
+	again, err := Run(here, other, false, nil)
+	if err != nil {
+		t.Fatalf("Run() again error = %v", err)
+	}
+	if again.Added != 0 || again.NotesAdded != 0 {
+		t.Errorf("second merge added %d events and %d notes, want none", again.Added, again.NotesAdded)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *models.FocusEvent
+		want bool
+	}{
+		{"apart", event("", 0, 60, "a"), event("", 120, 60, "b"), false},
+		{"adjacent", event("", 0, 60, "a"), event("", 60, 60, "b"), false},
+		{"within tolerance", event("", 0, 61, "a"), event("", 60, 60, "b"), false},
+		{"runs into", event("", 0, 90, "a"), event("", 60, 60, "b"), true},
+		{"either order", event("", 60, 60, "b"), event("", 0, 90, "a"), true},
+		{"contains", event("", 0, 300, "a"), event("", 60, 0, "b"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlaps(tt.a, tt.b); got != tt.want {
+				t.Errorf("overlaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AppDay is the focus time an app had on one local day.
+type AppDay struct {
+	Day          string `json:"day"`
+	TotalSeconds int64  `json:"total_seconds"`
+}
+
+// TitleSummary is the time spent in one window title of an app.
+type TitleSummary struct {
+	WindowTitle  string `json:"window_title"`
+	TotalSeconds int64  `json:"total_seconds"`
+	EventCount   int    `json:"event_count"`
+}
+
+// AppReport looks at a single app over a period: its daily trend, the
+// window titles it was used for, its longest sessions and the hours of the
+// day it was used in.
+type AppReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Period        ReportPeriod   `json:"period"`
+	Profile       string         `json:"profile,omitempty"`
+	AppName       string         `json:"app_name"`
+	TotalSeconds  int64          `json:"total_seconds"`
+	Days          []AppDay       `json:"days"`
+	Titles        []TitleSummary `json:"titles"`
+	Sessions      []FocusSession `json:"longest_sessions"`
+	Hours         [24]int64      `json:"hours"` // Seconds per local hour of the day
+	GeneratedAt   time.Time      `json:"generated_at"`
+}
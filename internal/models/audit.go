@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// AuditEntry records a change made through the CLI or web API, such as
+// clearing data or adding a note.
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Timestamp time.Time `gorm:"not null;index" json:"timestamp"`
+	Actor     string    `gorm:"not null;index" json:"actor"`  // "cli:<user>" or "api:<address>"
+	Action    string    `gorm:"not null;index" json:"action"` // e.g. "clear", "note.create"
+	Summary   string    `gorm:"not null" json:"summary"`
+}
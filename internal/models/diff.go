@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DiffEntry compares one app's or category's time between two periods.
+// ChangePercent is omitted when there was no time in the base period.
+type DiffEntry struct {
+	Name          string   `json:"name"`
+	BaseSeconds   int64    `json:"base_seconds"`
+	Seconds       int64    `json:"seconds"`
+	DeltaSeconds  int64    `json:"delta_seconds"`
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// Diff compares Period against Base, largest absolute changes first. When
+// the periods have different numbers of days off, ChangePercent compares the
+// time per working day rather than the totals.
+type Diff struct {
+	SchemaVersion int          `json:"schema_version"`
+	Period        ReportPeriod `json:"period"`
+	Base          ReportPeriod `json:"base"`
+	Profile       string       `json:"profile,omitempty"`
+	DaysOff       int          `json:"days_off,omitempty"`
+	BaseDaysOff   int          `json:"base_days_off,omitempty"`
+	Total         DiffEntry    `json:"total"`
+	Apps          []DiffEntry  `json:"apps"`
+	Categories    []DiffEntry  `json:"categories,omitempty"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+}
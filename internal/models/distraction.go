@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// FragmentedSegment is a stretch of rapid switching between apps, where no
+// session lasted long enough for focused work.
+type FragmentedSegment struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Seconds  int64     `json:"seconds"`
+	Switches int       `json:"switches"`
+	Apps     []string  `json:"apps"`
+	Pattern  string    `json:"pattern"`
+}
+
+// SwitchPattern counts back-and-forth switches between a pair of apps.
+type SwitchPattern struct {
+	Apps     [2]string `json:"apps"`
+	Switches int       `json:"switches"`
+}
+
+type DistractionReport struct {
+	SchemaVersion     int                 `json:"schema_version"`
+	Period            ReportPeriod        `json:"period"`
+	Segments          []FragmentedSegment `json:"segments"`
+	Patterns          []SwitchPattern     `json:"patterns"`
+	FragmentedSeconds int64               `json:"fragmented_seconds"`
+	TotalSeconds      int64               `json:"total_seconds"`
+	FragmentedPercent float64             `json:"fragmented_percent"`
+	GeneratedAt       time.Time           `json:"generated_at"`
+}
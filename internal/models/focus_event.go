@@ -1,45 +1,230 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/actionsum/actionsum/pkg/utils"
+
 	"gorm.io/gorm"
 )
 
 type FocusEvent struct {
 	ID            uint           `gorm:"primaryKey" json:"id"`
+	UUID          string         `gorm:"column:uuid;size:36" json:"uuid"` // Stable reference for external systems, unique per user
 	Timestamp     time.Time      `gorm:"not null;index" json:"timestamp"`
 	AppName       string         `gorm:"not null;index" json:"app_name"`
+	RawAppName    string         `gorm:"not null;default:''" json:"raw_app_name,omitempty"` // As detected, e.g. "org.mozilla.firefox", when normalizing changed it
+	DisplayName   string         `gorm:"not null;default:''" json:"display_name,omitempty"` // From the app's .desktop file, e.g. "Firefox"
 	WindowTitle   string         `gorm:"not null" json:"window_title"`
 	Duration      int64          `gorm:"not null;default:0" json:"duration"` // Duration in seconds
 	IsIdle        bool           `gorm:"not null;default:false" json:"is_idle"`
 	IsLocked      bool           `gorm:"not null;default:false" json:"is_locked"`
 	DisplayServer string         `gorm:"not null" json:"display_server"` // "x11" or "wayland"
+	IsFullscreen  bool           `gorm:"not null;default:false" json:"is_fullscreen"`
+	IsMaximized   bool           `gorm:"not null;default:false" json:"is_maximized"`
+	WindowX       int            `gorm:"not null;default:0" json:"window_x"`
+	WindowY       int            `gorm:"not null;default:0" json:"window_y"`
+	WindowWidth   int            `gorm:"not null;default:0" json:"window_width"`
+	WindowHeight  int            `gorm:"not null;default:0" json:"window_height"`
+	ClockJump     int64          `gorm:"not null;default:0" json:"clock_jump,omitempty"` // Wall-clock jump in seconds detected before this sample
+	Profile       string         `gorm:"not null;default:'default';index" json:"profile"`
+	Tag           string         `gorm:"not null;default:'';index" json:"tag,omitempty"`
+	TimeZone      string         `gorm:"column:time_zone;size:64" json:"time_zone,omitempty"`     // IANA zone in effect when recorded
+	UTCOffset     *int           `gorm:"column:utc_offset" json:"utc_offset,omitempty"`           // Seconds east of UTC when recorded; nil for older events
+	Source        string         `gorm:"not null;default:'';index" json:"source,omitempty"`       // "backfill:<importer>" for time estimated from history; empty when tracked
+	Detection     string         `gorm:"not null;default:'';index" json:"detection,omitempty"`    // "window", "hybrid" or "process-based"; empty for events stored before it was recorded
+	Label         string         `gorm:"not null;default:'';index" json:"label,omitempty"`        // Set by the configured classifier hook, e.g. "reading docs"
+	Client        string         `gorm:"not null;default:''" json:"client,omitempty"`             // "native" or "xwayland" on Wayland when the backend can tell
+	Output        string         `gorm:"not null;default:'';index" json:"output,omitempty"`       // Monitor the window was on, e.g. "DP-1", on sway, Hyprland and X11
+	URL           string         `gorm:"column:url;not null;default:''" json:"url,omitempty"`     // Active browser tab without query or fragment, from the browser extension
+	Domain        string         `gorm:"not null;default:'';index" json:"domain,omitempty"`       // Host of URL without "www."
+	SubApp        string         `gorm:"not null;default:'';index" json:"sub_app,omitempty"`      // Foreground command of a terminal, e.g. "vim", or of the remote one it is connected to
+	RemoteHost    string         `gorm:"not null;default:''" json:"remote_host,omitempty"`        // Machine a terminal was connected to, from its remote agent
+	ShellCommand  string         `gorm:"not null;default:''" json:"shell_command,omitempty"`      // Command line running in a terminal's shell, from the shell hooks, secrets redacted
+	ShellDir      string         `gorm:"not null;default:'';index" json:"shell_dir,omitempty"`    // Working directory of that shell, "~/..." under the home directory
+	Project       string         `gorm:"not null;default:'';index" json:"project,omitempty"`      // Project open in an editor, from its plugin
+	FileHash      string         `gorm:"not null;default:''" json:"file_hash,omitempty"`          // Hash of the edited file's path
+	Language      string         `gorm:"not null;default:'';index" json:"language,omitempty"`     // Language of the edited file, e.g. "go"
+	MediaPlayer   string         `gorm:"not null;default:'';index" json:"media_player,omitempty"` // Media player playing meanwhile, e.g. "Spotify"
+	MediaTrack    string         `gorm:"not null;default:''" json:"media_track,omitempty"`        // What it played, "artist – title"
+	RemoteClient  string         `gorm:"not null;default:''" json:"remote_client,omitempty"`      // Protocol of the remote-desktop client in focus, e.g. "rdp", "vnc" or "citrix"
+	RemoteSession string         `gorm:"not null;default:''" json:"remote_session,omitempty"`     // Protocol this session was used over from elsewhere, "rdp" or "vnc"
+	VPN           bool           `gorm:"not null;default:false" json:"vpn,omitempty"`             // A VPN interface was up
+	InMeeting     bool           `gorm:"not null;default:false" json:"in_meeting,omitempty"`      // A microphone or camera was in use
+	Location      string         `gorm:"not null;default:'';index" json:"location,omitempty"`     // Where the user worked, e.g. "office", from the Wi-Fi network
+	UserID        string         `gorm:"not null;default:'';index" json:"user_id,omitempty"`      // Whose activity this is on a multi-user server; empty for this machine's user
 	CreatedAt     time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// BeforeCreate assigns a UUID so the event can be referenced across devices
+// and exports, where its numeric ID is not stable.
+func (e *FocusEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.UUID == "" {
+		e.UUID = utils.NewUUID()
+	}
+	return nil
+}
+
 type AppSummary struct {
+	AppName           string  `json:"app_name"`
+	DisplayName       string  `json:"display_name,omitempty" gorm:"-"`
+	TotalSeconds      int64   `json:"total_seconds"`
+	TotalMinutes      float64 `json:"total_minutes"`
+	TotalHours        float64 `json:"total_hours"`
+	FullscreenSeconds int64   `json:"fullscreen_seconds"`
+	WorkSeconds       int64   `json:"work_seconds,omitempty"`
+	EventCount        int     `json:"event_count"`
+	Percentage        float64 `json:"percentage,omitempty"`
+
+	// SessionCount and LastSeen, the number of focus sessions in the app
+	// and when the latest one ended, are only filled in where the events
+	// were looked at.
+	SessionCount int        `json:"session_count,omitempty"`
+	LastSeen     *time.Time `json:"last_seen,omitempty"`
+}
+
+// DomainSummary is the time spent on one website in a browser.
+type DomainSummary struct {
+	Domain       string  `json:"domain"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// SubAppSummary is the time one command had in the foreground of a
+// terminal app, on RemoteHost when it ran on another machine.
+type SubAppSummary struct {
+	AppName      string  `json:"app_name"`
+	SubApp       string  `json:"sub_app"`
+	RemoteHost   string  `json:"remote_host,omitempty"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// MediaSummary is the time a media player was playing, and how much of it
+// another app had focus, the music playing in the background.
+type MediaSummary struct {
+	Player            string  `json:"player"`
+	TotalSeconds      int64   `json:"total_seconds"`
+	BackgroundSeconds int64   `json:"background_seconds"`
+	Percentage        float64 `json:"percentage"`
+}
+
+// PlayerIsApp reports whether a media player's name, e.g. "Mozilla
+// Firefox", names the app appName, e.g. "firefox".
+func PlayerIsApp(player, appName string) bool {
+	player, appName = strings.ToLower(player), strings.ToLower(appName)
+	if player == "" || appName == "" {
+		return false
+	}
+	return strings.Contains(player, appName) || strings.Contains(appName, player)
+}
+
+// RemoteSummary is the time spent on remote desktops of one protocol: in a
+// client showing a remote machine, and in this session used from
+// elsewhere.
+type RemoteSummary struct {
+	Protocol       string `json:"protocol"`
+	ClientSeconds  int64  `json:"client_seconds"`
+	SessionSeconds int64  `json:"session_seconds"`
+}
+
+// ShellDirSummary is the time spent in terminals whose shell worked in one
+// directory.
+type ShellDirSummary struct {
+	Dir          string  `json:"dir"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// ProjectSummary is the time spent editing one project, as editor plugins
+// report it, with the number of its files edited and their languages,
+// most used first.
+type ProjectSummary struct {
+	Project      string   `json:"project"`
+	TotalSeconds int64    `json:"total_seconds"`
+	Percentage   float64  `json:"percentage"`
+	Files        int      `json:"files"`
+	Languages    []string `json:"languages,omitempty"`
+}
+
+// MeetingSummary is the time spent in meetings, with a microphone or
+// camera in use, while one app had focus.
+type MeetingSummary struct {
 	AppName      string  `json:"app_name"`
 	TotalSeconds int64   `json:"total_seconds"`
-	TotalMinutes float64 `json:"total_minutes"`
-	TotalHours   float64 `json:"total_hours"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// OutputSummary is the time spent in windows on one monitor.
+type OutputSummary struct {
+	Output       string  `json:"output"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// LocationSummary is the time spent working from one location.
+type LocationSummary struct {
+	Location     string  `json:"location"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// LabelSummary is the time spent on events a classifier gave one label.
+type LabelSummary struct {
+	Label        string  `json:"label"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// DetectionSummary is the time recorded by one detection method. Method is
+// empty for events stored before methods were recorded or added by hand.
+type DetectionSummary struct {
+	Method       string  `json:"method"`
+	TotalSeconds int64   `json:"total_seconds"`
 	EventCount   int     `json:"event_count"`
-	Percentage   float64 `json:"percentage,omitempty"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// WorkSplit divides tracked time into working and off hours.
+type WorkSplit struct {
+	InsideSeconds  int64 `json:"inside_seconds"`
+	OutsideSeconds int64 `json:"outside_seconds"`
 }
 
 type ReportPeriod struct {
 	Start time.Time `json:"start"`
 	End   time.Time `json:"end"`
-	Type  string    `json:"type"` // "day", "week", "month"
+	Type  string    `json:"type"` // "day", "yesterday", "week", "lastweek", "month" or "lastmonth"
 }
 
 type Report struct {
-	Period       ReportPeriod `json:"period"`
-	Apps         []AppSummary `json:"apps"`
-	TotalSeconds int64        `json:"total_seconds"`
-	TotalMinutes float64      `json:"total_minutes"`
-	TotalHours   float64      `json:"total_hours"`
-	GeneratedAt  time.Time    `json:"generated_at"`
+	SchemaVersion int               `json:"schema_version"`
+	Period        ReportPeriod      `json:"period"`
+	Profile       string            `json:"profile,omitempty"`
+	Apps          []AppSummary      `json:"apps"`
+	TotalSeconds  int64             `json:"total_seconds"`
+	TotalMinutes  float64           `json:"total_minutes"`
+	TotalHours    float64           `json:"total_hours"`
+	Fullscreen    int64             `json:"fullscreen_seconds"`
+	Locks         LockSummary       `json:"locks"`
+	WorkHours     *WorkSplit        `json:"work_hours,omitempty"`
+	Metrics       *SessionMetrics   `json:"metrics,omitempty"`
+	Focus         *FocusStats       `json:"focus,omitempty"`
+	Notes         []*Note           `json:"notes,omitempty"`
+	Backfill      int64             `json:"backfill_seconds,omitempty"` // Part of TotalSeconds estimated from history
+	Labels        []LabelSummary    `json:"labels,omitempty"`
+	Domains       []DomainSummary   `json:"domains,omitempty"`
+	Locations     []LocationSummary `json:"locations,omitempty"`
+	SubApps       []SubAppSummary   `json:"sub_apps,omitempty"`
+	ShellDirs     []ShellDirSummary `json:"shell_dirs,omitempty"`
+	Projects      []ProjectSummary  `json:"projects,omitempty"`
+	Media         []MediaSummary    `json:"media,omitempty"`
+	Remote        []RemoteSummary   `json:"remote,omitempty"`
+	VPNSeconds    int64             `json:"vpn_seconds,omitempty"`
+	Meetings      []MeetingSummary  `json:"meetings,omitempty"`
+	Outputs       []OutputSummary   `json:"outputs,omitempty"`
+	GeneratedAt   time.Time         `json:"generated_at"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Gap is a period where the machine was running but no activity was recorded.
+type Gap struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Seconds int64     `json:"seconds"`
+	Reason  string    `json:"reason"`
+}
+
+type GapReport struct {
+	SchemaVersion int          `json:"schema_version"`
+	Period        ReportPeriod `json:"period"`
+	Gaps          []Gap        `json:"gaps"`
+	TotalSeconds  int64        `json:"total_seconds"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+}
+
+// TimelineSegment is a stretch of continuous activity in one app or a tracking
+// gap, for drawing a timeline. Fragmented stretches and notes overlay the
+// activity they span.
+type TimelineSegment struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Kind    string    `json:"kind"` // "activity", "gap", "fragmented" or "note"
+	AppName string    `json:"app_name,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Text    string    `json:"text,omitempty"`
+}
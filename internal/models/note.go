@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Note is a free-text journal entry attached to a time range. A note for a
+// whole day spans midnight to midnight.
+type Note struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Start     time.Time      `gorm:"not null;index" json:"start"`
+	End       time.Time      `gorm:"not null;index" json:"end"`
+	Text      string         `gorm:"not null" json:"text"`
+	UserID    string         `gorm:"not null;default:'';index" json:"user_id,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
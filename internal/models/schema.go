@@ -0,0 +1,9 @@
+package models
+
+// ReportSchemaVersion is the version of the JSON form of reports, stored in
+// their schema_version field: the period, gap, diff, distraction, team,
+// distribution and app reports, and the API summary. Fields may be added
+// within a version; renaming or removing a field, or changing what it
+// means, takes a new version. The fields of each version are listed in
+// docs/report-schema.md.
+const ReportSchemaVersion = 1
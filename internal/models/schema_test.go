@@ -0,0 +1,98 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaReports are the reports documented in docs/report-schema.md, by
+// their section there.
+var schemaReports = map[string]any{
+	"report":       Report{},
+	"gaps":         GapReport{},
+	"diff":         Diff{},
+	"distractions": DistractionReport{},
+	"team":         TeamReport{},
+	"distribution": DistributionReport{},
+	"app":          AppReport{},
+}
+
+// schemaFields lists the JSON fields of t in order, nested ones as dotted
+// paths with [] marking lists.
+func schemaFields(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if t.Kind() != reflect.Pointer {
+			prefix += "[]"
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		fields = append(fields, path)
+		fields = append(fields, schemaFields(field.Type, path)...)
+	}
+	return fields
+}
+
+// documentedSchema reads the field lists of one version from the schema
+// document, by section.
+func documentedSchema(t *testing.T, version int) map[string][]string {
+	t.Helper()
+	file, err := os.Open("../../docs/report-schema.md")
+	if err != nil {
+		t.Fatalf("failed to open schema document: %v", err)
+	}
+	defer file.Close()
+
+	sections := map[string][]string{}
+	var inVersion, inBlock bool
+	var section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "## "):
+			inVersion = line == fmt.Sprintf("## Version %d", version)
+		case !inVersion:
+		case strings.HasPrefix(line, "### "):
+			section, _, _ = strings.Cut(strings.TrimPrefix(line, "### "), " ")
+		case strings.HasPrefix(line, "```"):
+			inBlock = !inBlock
+		case inBlock && line != "":
+			sections[section] = append(sections[section], line)
+		}
+	}
+	return sections
+}
+
+// TestReportSchema keeps the JSON reports in line with the fields documented
+// for the current schema version. A new field goes in the document too; a
+// renamed or removed one needs a new ReportSchemaVersion.
+func TestReportSchema(t *testing.T) {
+	documented := documentedSchema(t, ReportSchemaVersion)
+	for section, report := range schemaReports {
+		got := schemaFields(reflect.TypeOf(report), "")
+		want := documented[section]
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%T fields differ from section %q of version %d:\ngot:\n%s\ndocumented:\n%s",
+				report, section, ReportSchemaVersion, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
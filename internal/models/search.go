@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SearchResult is a stretch of consecutive matching events in one window.
+type SearchResult struct {
+	UUID        string    `json:"uuid"` // The stretch's first event
+	AppName     string    `json:"app_name"`
+	WindowTitle string    `json:"window_title"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Seconds     int64     `json:"seconds"`
+	Events      int       `json:"events"`
+}
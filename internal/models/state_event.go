@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StateLock      = "lock"
+	StateUnlock    = "unlock"
+	StateClockJump = "clock_jump"
+
+	StateDaemonStart = "daemon_start"
+	StateDaemonStop  = "daemon_stop"
+
+	// StateDigestSent marks a delivered weekly digest; Detail is the week start.
+	StateDigestSent = "digest_sent"
+)
+
+type StateEvent struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Timestamp time.Time      `gorm:"not null;index" json:"timestamp"`
+	Type      string         `gorm:"not null;index" json:"type"` // see State* constants
+	Source    string         `gorm:"not null" json:"source"`     // "dbus", "windows", "macos", "poll" or "daemon"
+	Detail    string         `gorm:"not null;default:''" json:"detail,omitempty"`
+	UserID    string         `gorm:"not null;default:'';index" json:"user_id,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type LockSummary struct {
+	LockCount   int     `json:"lock_count"`
+	AwaySeconds int64   `json:"away_seconds"`
+	AwayHours   float64 `json:"away_hours"`
+}
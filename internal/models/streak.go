@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// DailyAppTotal is the focus time recorded for one app and tag on one local
+// day.
+type DailyAppTotal struct {
+	Day          string `json:"day"`
+	AppName      string `json:"app_name"`
+	Tag          string `json:"tag,omitempty"`
+	TotalSeconds int64  `json:"total_seconds"`
+}
+
+// FocusSession is a stretch of consecutive events in the same app.
+type FocusSession struct {
+	AppName string    `json:"app_name"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Seconds int64     `json:"seconds"`
+}
+
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unlocked    bool   `json:"unlocked"`
+}
+
+type FocusStats struct {
+	GoalSeconds    int64         `json:"goal_seconds"`
+	TodaySeconds   int64         `json:"today_seconds"`
+	GoalMetToday   bool          `json:"goal_met_today"`
+	CurrentStreak  int           `json:"current_streak_days"`
+	LongestStreak  int           `json:"longest_streak_days"`
+	LongestSession *FocusSession `json:"longest_session,omitempty"`
+	Achievements   []Achievement `json:"achievements"`
+}
+
+// SessionMetrics describes how fragmented focus time was over a period.
+type SessionMetrics struct {
+	Sessions              int           `json:"sessions"`
+	Switches              int           `json:"switches"`
+	SwitchesPerHour       float64       `json:"switches_per_hour"`
+	AverageSessionSeconds int64         `json:"average_session_seconds"`
+	LongestSession        *FocusSession `json:"longest_session,omitempty"`
+}
+
+// AppDistribution summarizes the lengths of an app's focus sessions.
+type AppDistribution struct {
+	AppName        string  `json:"app_name"`
+	Sessions       int     `json:"sessions"`
+	TotalSeconds   int64   `json:"total_seconds"`
+	MedianSeconds  int64   `json:"median_seconds"`
+	P90Seconds     int64   `json:"p90_seconds"`
+	LongestSeconds int64   `json:"longest_seconds"`
+	SessionsPerDay float64 `json:"sessions_per_day"`
+}
+
+type DistributionReport struct {
+	SchemaVersion int               `json:"schema_version"`
+	Period        ReportPeriod      `json:"period"`
+	Profile       string            `json:"profile,omitempty"`
+	Days          int               `json:"days"`
+	Apps          []AppDistribution `json:"apps"`
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
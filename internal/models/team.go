@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Team sharing levels, from least to most shared.
+const (
+	// ShareNone leaves a user out of team reports. It is every user's
+	// level until they choose another.
+	ShareNone = "none"
+
+	// ShareCategories counts a user's time in the team's category totals
+	// without naming them.
+	ShareCategories = "categories"
+
+	// ShareHours also lists the user with their total active time.
+	ShareHours = "hours"
+)
+
+// ParseShareLevel validates a team sharing level.
+func ParseShareLevel(level string) (string, error) {
+	switch level {
+	case ShareNone, ShareCategories, ShareHours:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown sharing level %q (valid: %s, %s, %s)", level, ShareNone, ShareCategories, ShareHours)
+}
+
+// TeamSharing is a user's choice of what team reports may include of their
+// time. Window titles and app names are never included.
+type TeamSharing struct {
+	UserID    string    `gorm:"primaryKey" json:"user_id"`
+	Level     string    `gorm:"not null;default:'none'" json:"level"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TeamMember is the total active time of a user sharing their hours.
+type TeamMember struct {
+	UserID       string  `json:"user_id"`
+	TotalSeconds int64   `json:"total_seconds"`
+	TotalHours   float64 `json:"total_hours"`
+}
+
+// TeamCategory is the time the sharing users spent in a category together.
+type TeamCategory struct {
+	Category     string  `json:"category"`
+	TotalSeconds int64   `json:"total_seconds"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// TeamReport aggregates the time of the users who share it. Categories and
+// TotalSeconds are omitted while fewer than the configured minimum of users
+// share their time.
+type TeamReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Period        ReportPeriod   `json:"period"`
+	Contributors  int            `json:"contributors"`
+	Members       []TeamMember   `json:"members"`
+	Categories    []TeamCategory `json:"categories,omitempty"`
+	TotalSeconds  int64          `json:"total_seconds,omitempty"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIToken grants a client access to the web API. Only a hash of the token
+// is stored; the token itself is shown once when created.
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"not null" json:"name"` // Unique per user
+	Hash       string     `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"not null" json:"scopes"`                       // Comma-separated, e.g. "read:events,write:events"
+	UserID     string     `gorm:"not null;default:''" json:"user_id,omitempty"` // Whose data the token reads and writes in multi-user mode
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Set for the sessions of OIDC sign-ins
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (t *APIToken) ScopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
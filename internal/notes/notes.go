@@ -0,0 +1,55 @@
+package notes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// ParseRange resolves a note's range on date (YYYY-MM-DD, default today) from
+// clock times such as "9:00" and "11:30". Without from and to the note covers
+// the whole day; without to it runs until now.
+func ParseRange(date, from, to string, now time.Time) (time.Time, time.Time, error) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if date != "" {
+		d, err := time.ParseInLocation(dateLayout, date, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+		day = d
+	}
+
+	if from == "" && to == "" {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if from == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to requires --from")
+	}
+
+	start, err := clock(day, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end := now
+	if to != "" {
+		if end, err = clock(day, to); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end %s is not after start %s",
+			end.Format("2006-01-02 15:04"), start.Format("2006-01-02 15:04"))
+	}
+	return start, end, nil
+}
+
+func clock(day time.Time, s string) (time.Time, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d:%d", &hour, &minute); err != nil ||
+		hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute), nil
+}
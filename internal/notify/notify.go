@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+type Message struct {
+	Title string
+	Body  string
+
+	// Urgency is passed to desktop notifications: "low", "normal" or "critical".
+	Urgency string
+
+	// Data is attached to webhook payloads as structured JSON.
+	Data any
+
+	// Attachments are sent along with email; other channels leave them
+	// out.
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type Notifier interface {
+	Notify(msg Message) error
+}
+
+// FromConfig returns a notifier delivering to every configured channel, or
+// nil when none is configured.
+func FromConfig(cfg *config.Config) Notifier {
+	var channels Multi
+	if cfg.Notify.Desktop {
+		channels = append(channels, Desktop{})
+	}
+	if cfg.Notify.WebhookURL != "" {
+		channels = append(channels, NewWebhook(cfg.Notify.WebhookURL))
+	}
+	if cfg.Notify.SMTP.Host != "" && len(cfg.Notify.SMTP.To) > 0 {
+		channels = append(channels, Email{cfg: cfg.Notify.SMTP})
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	return channels
+}
+
+// Multi delivers to each notifier. Failures of individual channels are
+// logged; an error is returned only when no channel succeeded, so callers
+// don't redeliver to channels that already received the message.
+type Multi []Notifier
+
+func (m Multi) Notify(msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(msg); err != nil {
+			log.Printf("Notification channel failed: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(m) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// Desktop shows a notification through notify-send.
+type Desktop struct{}
+
+func (Desktop) Notify(msg Message) error {
+	args := []string{"--app-name=actionsum"}
+	if msg.Urgency != "" {
+		args = append(args, "--urgency="+msg.Urgency)
+	}
+	args = append(args, msg.Title, msg.Body)
+	if output, err := sandbox.Command("notify-send", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send failed: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Ask shows a desktop notification with a button per choice and waits until
+// one is clicked, returning it, or "" if the notification is dismissed or
+// expires. It needs a notify-send recent enough to support --action.
+func Ask(ctx context.Context, msg Message, choices []string) (string, error) {
+	args := []string{"--app-name=actionsum", "--wait"}
+	if msg.Urgency != "" {
+		args = append(args, "--urgency="+msg.Urgency)
+	}
+	for i, choice := range choices {
+		args = append(args, fmt.Sprintf("--action=%d=%s", i, choice))
+	}
+	args = append(args, msg.Title, msg.Body)
+
+	output, err := sandbox.CommandContext(ctx, "notify-send", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("notify-send failed: %w", err)
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil || i < 0 || i >= len(choices) {
+		return "", nil
+	}
+	return choices[i], nil
+}
+
+// Webhook POSTs messages as JSON.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *Webhook) Notify(msg Message) error {
+	payload, err := json.Marshal(map[string]any{
+		"title": msg.Title,
+		"text":  msg.Body,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Email sends plain-text mail over SMTP, as multipart/mixed when the message
+// has attachments.
+type Email struct {
+	cfg config.SMTPConfig
+}
+
+func (e Email) Notify(msg Message) error {
+	addr := e.cfg.Host + ":" + strconv.Itoa(e.cfg.Port)
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+
+	from := e.cfg.From
+	if from == "" {
+		from = e.cfg.Username
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	if err := writeContent(&body, msg); err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(addr, auth, from, e.cfg.To, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// writeContent writes the Content-Type header and the body of an email.
+func writeContent(w *strings.Builder, msg Message) error {
+	text := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if len(msg.Attachments) == 0 {
+		w.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		w.WriteString(text)
+		return nil
+	}
+
+	var random [12]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return fmt.Errorf("failed to create MIME boundary: %w", err)
+	}
+	boundary := "actionsum-" + hex.EncodeToString(random[:])
+	fmt.Fprintf(w, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(w, "--%s\r\n", boundary)
+	w.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	w.WriteString(text)
+	w.WriteString("\r\n")
+	for _, a := range msg.Attachments {
+		name := mime.QEncoding.Encode("utf-8", a.Name)
+		fmt.Fprintf(w, "--%s\r\n", boundary)
+		fmt.Fprintf(w, "Content-Type: %s; name=%q\r\n", a.ContentType, name)
+		fmt.Fprintf(w, "Content-Disposition: attachment; filename=%q\r\n", name)
+		w.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		// Lines of base64 are limited to 76 characters.
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			w.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		w.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(w, "--%s--\r\n", boundary)
+	return nil
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+// fakeSMTP accepts one message without authentication and returns the
+// address it listens on and a channel receiving the message's data, with
+// its lines ending in "\n".
+func fakeSMTP(t *testing.T) (host string, port int, data <-chan []byte) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := textproto.NewReader(bufio.NewReader(conn))
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(strings.ToUpper(line), " "); verb {
+			case "DATA":
+				reply("354 go ahead")
+				b, err := r.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- b
+				reply("250 OK")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestEmailNotify(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachments []Attachment
+	}{
+		{"plain", nil},
+		{"attachment", []Attachment{{Name: "wöchentlich.pdf", ContentType: "application/pdf", Data: []byte(strings.Repeat("%PDF", 40))}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, data := fakeSMTP(t)
+			email := Email{cfg: config.SMTPConfig{Host: host, Port: port, From: "actionsum@example.com", To: []string{"me@example.com", "you@example.com"}}}
+			msg := Message{Title: "actionsum weekly digest: Mar 2 – Mar 8", Body: "Active time: 3h\nTop apps:", Attachments: tt.attachments}
+			if err := email.Notify(msg); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+
+			m, err := mail.ReadMessage(strings.NewReader(string(<-data)))
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			raw := m.Header.Get("Subject")
+			if strings.ContainsFunc(raw, func(r rune) bool { return r > 127 }) {
+				t.Errorf("Subject %q is not ASCII", raw)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(raw)
+			if err != nil || subject != msg.Title {
+				t.Errorf("Subject decodes to %q, %v; want %q", subject, err, msg.Title)
+			}
+			if got := m.Header.Get("To"); got != "me@example.com, you@example.com" {
+				t.Errorf("To = %q", got)
+			}
+
+			mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("Content-Type: %v", err)
+			}
+			if len(tt.attachments) == 0 {
+				body, _ := io.ReadAll(m.Body)
+				if mediaType != "text/plain" || strings.TrimSpace(string(body)) != "Active time: 3h\nTop apps:" {
+					t.Errorf("body %s %q", mediaType, body)
+				}
+				return
+			}
+
+			if mediaType != "multipart/mixed" {
+				t.Fatalf("Content-Type = %s, want multipart/mixed", mediaType)
+			}
+			parts := multipart.NewReader(m.Body, params["boundary"])
+			text, err := parts.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, _ := io.ReadAll(text); strings.TrimSpace(string(body)) != "Active time: 3h\nTop apps:" {
+				t.Errorf("text part %q", body)
+			}
+			attachment, err := parts.NextRawPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, dispositionParams, _ := mime.ParseMediaType(attachment.Header.Get("Content-Disposition"))
+			if name, err := new(mime.WordDecoder).DecodeHeader(dispositionParams["filename"]); err != nil || name != tt.attachments[0].Name {
+				t.Errorf("filename decodes to %q, %v", name, err)
+			}
+			encoded, _ := io.ReadAll(attachment)
+			for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\n") {
+				if len(line) > 76 {
+					t.Errorf("base64 line of %d characters", len(line))
+				}
+			}
+			decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\n", ""))
+			if err != nil || string(decoded) != string(tt.attachments[0].Data) {
+				t.Errorf("attachment decodes to %d bytes, %v", len(decoded), err)
+			}
+		})
+	}
+}
+
+func TestMulti(t *testing.T) {
+	failing := Email{cfg: config.SMTPConfig{Host: "127.0.0.1", Port: 1, To: []string{"me@example.com"}}}
+	if err := (Multi{failing}).Notify(Message{Title: "x"}); err == nil {
+		t.Error("Multi with only failing channels returned nil")
+	}
+	host, port, _ := fakeSMTP(t)
+	working := Email{cfg: config.SMTPConfig{Host: host, Port: port, From: "a@example.com", To: []string{"me@example.com"}}}
+	if err := (Multi{failing, working}).Notify(Message{Title: "x"}); err != nil {
+		t.Errorf("Multi with a working channel: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Package oidc signs users in with an OpenID Connect provider, using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+// requestTimeout bounds each request to the provider.
+const requestTimeout = 15 * time.Second
+
+// Provider talks to the OpenID Connect provider configured in
+// ACTIONSUM_OIDC_ISSUER. Its endpoints are discovered on first use, so a
+// provider that is down when the server starts doesn't keep it from serving.
+type Provider struct {
+	config config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+}
+
+// endpoints are the parts of the provider's discovery document used here.
+type endpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+func New(cfg config.OIDCConfig) *Provider {
+	return &Provider{
+		config: cfg,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// discover fetches the provider's discovery document once it is first
+// needed, and again after a failure.
+func (p *Provider) discover(ctx context.Context) (*endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer %s: %w", p.config.Issuer, err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch provider configuration: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch provider configuration: %s", resp.Status)
+	}
+
+	var found endpoints
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, fmt.Errorf("invalid provider configuration: %w", err)
+	}
+	// Providers differ in whether their issuer ends in a slash.
+	if strings.TrimSuffix(found.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("provider calls itself %s, not %s", found.Issuer, p.config.Issuer)
+	}
+	if found.AuthorizationEndpoint == "" || found.TokenEndpoint == "" {
+		return nil, fmt.Errorf("provider configuration lacks its authorization or token endpoint")
+	}
+	p.endpoints = &found
+	return p.endpoints, nil
+}
+
+// Flow holds the secrets of one sign-in between sending the browser to the
+// provider and its return: the state tying the return to this browser, the
+// nonce tying the ID token to this sign-in, and the PKCE verifier.
+type Flow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func NewFlow() (*Flow, error) {
+	var values [3]string
+	for i := range values {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to start sign-in: %w", err)
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return &Flow{State: values[0], Nonce: values[1], Verifier: values[2]}, nil
+}
+
+// Encode packs the flow into a cookie value.
+func (f *Flow) Encode() string {
+	return f.State + "." + f.Nonce + "." + f.Verifier
+}
+
+// DecodeFlow unpacks a cookie value written by Encode.
+func DecodeFlow(s string) (*Flow, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid sign-in state")
+	}
+	return &Flow{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, nil
+}
+
+// AuthURL returns where to send the browser to sign in.
+func (p *Provider) AuthURL(ctx context.Context, flow *Flow) (string, error) {
+	found, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(found.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return found.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange trades the code the provider returned for an ID token and returns
+// its claims once they check out against the flow.
+//
+// The ID token comes straight from the provider's token endpoint over the
+// connection this server opened, so, as OpenID Connect Core 3.1.3.7 allows,
+// the connection vouches for it instead of its signature.
+func (p *Provider) Exchange(ctx context.Context, code string, flow *Flow) (map[string]interface{}, error) {
+	found, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {flow.Verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, found.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem sign-in code: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider refused the sign-in code: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no ID token")
+	}
+	return p.checkIDToken(tokens.IDToken, found.Issuer, flow, time.Now())
+}
+
+// checkIDToken decodes an ID token and checks it was issued by issuer for
+// this client and this sign-in, and hasn't expired.
+func (p *Provider) checkIDToken(idToken, issuer string, flow *Flow, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != issuer {
+		return nil, fmt.Errorf("ID token was issued by %q, not %s", iss, issuer)
+	}
+	var audience []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+	}
+	if !slices.Contains(audience, p.config.ClientID) {
+		return nil, fmt.Errorf("ID token is not meant for client %s", p.config.ClientID)
+	}
+	exp, _ := claims["exp"].(float64)
+	if now.After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("ID token has expired")
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != flow.Nonce {
+		return nil, fmt.Errorf("ID token belongs to another sign-in")
+	}
+	return claims, nil
+}
+
+// UserID returns the user a sign-in is for: the configured claim of its ID
+// token. An email address is only accepted when the provider says it has
+// verified it, as anyone could have entered it.
+func (p *Provider) UserID(claims map[string]interface{}) (string, error) {
+	user, _ := claims[p.config.UserClaim].(string)
+	if user == "" {
+		return "", fmt.Errorf("ID token has no %s claim", p.config.UserClaim)
+	}
+	if p.config.UserClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return "", fmt.Errorf("email address %s is not verified", user)
+		}
+	}
+	return user, nil
+}
+
+// Allowed reports whether user may sign in: anyone when no users are
+// configured, otherwise those listed and, for "@domain" entries, those whose
+// user ID ends in it.
+func (p *Provider) Allowed(user string) bool {
+	if len(p.config.AllowedUsers) == 0 {
+		return true
+	}
+	for _, allowed := range p.config.AllowedUsers {
+		if strings.HasPrefix(allowed, "@") {
+			if strings.HasSuffix(strings.ToLower(user), strings.ToLower(allowed)) {
+				return true
+			}
+		} else if user == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionLength is how long a sign-in lasts.
+func (p *Provider) SessionLength() time.Duration {
+	return p.config.SessionLength
+}
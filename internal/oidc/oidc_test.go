@@ -0,0 +1,123 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+func idToken(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestCheckIDToken(t *testing.T) {
+	const issuer = "https://id.example.com"
+	p := New(config.OIDCConfig{Issuer: issuer, ClientID: "actionsum", UserClaim: "email"})
+	flow := &Flow{State: "s", Nonce: "n", Verifier: "v"}
+	now := time.Now()
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": issuer, "aud": "actionsum", "exp": float64(now.Add(time.Hour).Unix()), "nonce": "n",
+			"email": "alice@example.com", "email_verified": true,
+		}
+	}
+
+	claims, err := p.checkIDToken(idToken(t, valid()), issuer, flow, now)
+	if err != nil {
+		t.Fatalf("checkIDToken() error = %v", err)
+	}
+	if user, err := p.UserID(claims); err != nil || user != "alice@example.com" {
+		t.Errorf("UserID() = %q, %v; want alice@example.com", user, err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(map[string]interface{})
+		want   string
+	}{
+		{"other issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, "issued by"},
+		{"other client", func(c map[string]interface{}) { c["aud"] = []interface{}{"other"} }, "not meant for"},
+		{"expired", func(c map[string]interface{}) { c["exp"] = float64(now.Add(-time.Minute).Unix()) }, "expired"},
+		{"replayed", func(c map[string]interface{}) { c["nonce"] = "old" }, "another sign-in"},
+	}
+	for _, tt := range tests {
+		claims := valid()
+		tt.change(claims)
+		if _, err := p.checkIDToken(idToken(t, claims), issuer, flow, now); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: checkIDToken() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+}
+
+func TestUserID(t *testing.T) {
+	tests := []struct {
+		name    string
+		claim   string
+		claims  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{"verified email", "email", map[string]interface{}{"email": "alice@example.com", "email_verified": true}, "alice@example.com", false},
+		{"unverified email", "email", map[string]interface{}{"email": "alice@example.com", "email_verified": false}, "", true},
+		{"email without email_verified", "email", map[string]interface{}{"email": "alice@example.com"}, "", true},
+		{"email_verified as a string", "email", map[string]interface{}{"email": "alice@example.com", "email_verified": "true"}, "", true},
+		{"no email", "email", map[string]interface{}{"sub": "1234", "email_verified": true}, "", true},
+		{"username", "preferred_username", map[string]interface{}{"preferred_username": "alice"}, "alice", false},
+		{"username with an unverified email", "preferred_username", map[string]interface{}{"preferred_username": "alice", "email": "a@example.com", "email_verified": false}, "alice", false},
+		{"empty username", "preferred_username", map[string]interface{}{"preferred_username": ""}, "", true},
+		{"subject", "sub", map[string]interface{}{"sub": "1234"}, "1234", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(config.OIDCConfig{UserClaim: tt.claim})
+			user, err := p.UserID(tt.claims)
+			if (err != nil) != tt.wantErr || user != tt.want {
+				t.Errorf("UserID() = %q, %v; want %q, error %v", user, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	p := New(config.OIDCConfig{AllowedUsers: []string{"bob", "@example.com"}})
+	for user, want := range map[string]bool{
+		"bob":                       true,
+		"alice@example.com":         true,
+		"alice@EXAMPLE.com":         true,
+		"alice@evil-example.com":    false,
+		"alice@example.com.evil.io": false,
+		"carol":                     false,
+	} {
+		if got := p.Allowed(user); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", user, got, want)
+		}
+	}
+	if !New(config.OIDCConfig{}).Allowed("anyone") {
+		t.Error("Allowed() without a list refused a user")
+	}
+}
+
+func TestFlowRoundTrip(t *testing.T) {
+	flow, err := NewFlow()
+	if err != nil {
+		t.Fatalf("NewFlow() error = %v", err)
+	}
+	decoded, err := DecodeFlow(flow.Encode())
+	if err != nil || *decoded != *flow {
+		t.Errorf("DecodeFlow(Encode()) = %+v, %v; want %+v", decoded, err, flow)
+	}
+	if _, err := DecodeFlow("only.two"); err == nil {
+		t.Error("DecodeFlow() accepted a malformed value")
+	}
+}
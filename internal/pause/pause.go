@@ -0,0 +1,63 @@
+package pause
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indefinite is written in place of an expiry for a pause with no end.
+const indefinite = "indefinite"
+
+// Set stops tracking until the given time, or until Clear is called when
+// until is zero.
+func Set(stateFile string, until time.Time) error {
+	if stateFile == "" {
+		return fmt.Errorf("pause state file is not configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create pause directory: %w", err)
+	}
+	data := indefinite
+	if !until.IsZero() {
+		data = until.Format(time.RFC3339)
+	}
+	if err := os.WriteFile(stateFile, []byte(data+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save pause: %w", err)
+	}
+	return nil
+}
+
+// Clear resumes tracking.
+func Clear(stateFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear pause: %w", err)
+	}
+	return nil
+}
+
+// Active reports whether tracking is paused at now and until when; the time
+// is zero for a pause with no end.
+func Active(stateFile string, now time.Time) (bool, time.Time) {
+	if stateFile == "" {
+		return false, time.Time{}
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return false, time.Time{}
+	}
+	value := strings.TrimSpace(string(data))
+	if value == indefinite {
+		return true, time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil || !now.Before(until) {
+		return false, time.Time{}
+	}
+	return true, until
+}
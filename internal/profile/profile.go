@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+)
+
+const (
+	// Default is used when no profile is selected and no rule matches.
+	Default = "default"
+
+	// Auto clears a manual selection so time-window rules apply again.
+	Auto = "auto"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Validate checks that name is usable as a profile name.
+func Validate(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Manual returns the profile selected with "actionsum profile use", or an
+// empty string when none is set.
+func Manual(stateFile string) string {
+	if stateFile == "" {
+		return ""
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(data))
+	if Validate(name) != nil {
+		return ""
+	}
+	return name
+}
+
+// Use selects name as the active profile. Auto removes the selection.
+func Use(stateFile, name string) error {
+	if stateFile == "" {
+		return fmt.Errorf("profile state file is not configured")
+	}
+
+	if name == Auto {
+		if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear profile: %w", err)
+		}
+		return nil
+	}
+
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if err := os.WriteFile(stateFile, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the profile active at now and where it came from: a manual
+// selection, the first matching time-window rule, or the default.
+func Resolve(cfg *config.Config, now time.Time) (name, source string) {
+	if name := Manual(cfg.Profiles.StateFile); name != "" {
+		return name, "manual"
+	}
+	for _, rule := range cfg.Profiles.Rules {
+		if rule.Window.Contains(now) {
+			return rule.Profile, "rule"
+		}
+	}
+	return Default, "default"
+}
@@ -0,0 +1,136 @@
+package remoteterm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgentInterval is how often the agent reports by default.
+const AgentInterval = 15 * time.Second
+
+// Agent reports the active tmux window on this machine to an actionsum web
+// API, typically reached through an SSH reverse tunnel.
+type Agent struct {
+	URL      string // Base URL of the API, e.g. "http://localhost:10000"
+	Token    string // Sent as a bearer token when set
+	Host     string // Reported host name; the machine's by default
+	Interval time.Duration
+
+	client *http.Client
+}
+
+// Run reports every Interval until ctx is done. Nothing is sent while no
+// tmux client is attached. Failures are logged through logf and retried at
+// the next report.
+func (a *Agent) Run(ctx context.Context, logf func(format string, args ...any)) error {
+	if a.Host == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get host name: %w", err)
+		}
+		a.Host, _, _ = strings.Cut(host, ".")
+	}
+	if a.Interval <= 0 {
+		a.Interval = AgentInterval
+	}
+	a.client = &http.Client{Timeout: 10 * time.Second}
+
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+	for {
+		activity, err := tmuxActivity()
+		if err != nil {
+			logf("Failed to read tmux: %v", err)
+		} else if activity != nil {
+			activity.Host = a.Host
+			if err := a.send(ctx, activity); err != nil {
+				logf("Failed to report to %s: %v", a.URL, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) send(ctx context.Context, activity *Activity) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(a.URL, "/")+"/api/terminal", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// tmuxFormat asks tmux for each attached client's last activity and the
+// session, window and foreground command it shows.
+const tmuxFormat = "#{client_activity}\t#{session_name}\t#{window_name}\t#{pane_current_command}"
+
+// tmuxActivity returns what the most recently used tmux client shows, or
+// nil when no client is attached or no tmux server runs.
+func tmuxActivity() (*Activity, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("tmux", "list-clients", "-F", tmuxFormat)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(stderr.String(), "no server running") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseClients(string(output)), nil
+}
+
+// parseClients picks the most recently active client from tmux
+// list-clients output in tmuxFormat.
+func parseClients(output string) *Activity {
+	type client struct {
+		activity int64
+		fields   []string
+	}
+	var clients []client
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 || fields[3] == "" {
+			continue
+		}
+		activity, _ := strconv.ParseInt(fields[0], 10, 64)
+		clients = append(clients, client{activity, fields})
+	}
+	if len(clients) == 0 {
+		return nil
+	}
+	sort.SliceStable(clients, func(i, j int) bool { return clients[i].activity > clients[j].activity })
+	fields := clients[0].fields
+	return &Activity{Session: fields[1], Window: fields[2], Command: fields[3]}
+}
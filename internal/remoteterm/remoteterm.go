@@ -0,0 +1,93 @@
+// Package remoteterm learns what runs in terminals on other machines. A
+// small agent on a server, or a shell hook, reports the active tmux window
+// or command to the local web API, which records it in a file; while a
+// local terminal runs ssh, the daemon puts that command on its events
+// instead of "ssh".
+package remoteterm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/statefile"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+const (
+	// freshness is how long a report is believed. The agent reports every
+	// 15 seconds, so an older one means the connection or the agent has
+	// gone.
+	freshness = time.Minute
+
+	// maxField bounds each reported name.
+	maxField = 200
+)
+
+// remoteShells are the commands that put a local terminal on another
+// machine.
+var remoteShells = map[string]bool{
+	"et":          true,
+	"mosh":        true,
+	"mosh-client": true,
+	"ssh":         true,
+}
+
+// Activity is what a remote terminal was last reported doing.
+type Activity struct {
+	Host    string `json:"host"`
+	Session string `json:"session,omitempty"` // tmux session
+	Window  string `json:"window,omitempty"`  // tmux window
+	Command string `json:"command"`           // Foreground command, e.g. "vim"
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Clean trims the activity's names and checks that it names a host and a
+// command.
+func (a *Activity) Clean() error {
+	for _, field := range []*string{&a.Host, &a.Session, &a.Window, &a.Command} {
+		*field = strings.TrimSpace(*field)
+		*field = utils.TruncateBytes(*field, maxField)
+	}
+	if a.Host == "" || a.Command == "" {
+		return fmt.Errorf("host and command are required")
+	}
+	a.Host = strings.ToLower(a.Host)
+	return nil
+}
+
+// Record saves a as the current activity.
+func Record(path string, a *Activity) error {
+	if path == "" {
+		return fmt.Errorf("remote terminal file is not configured")
+	}
+	return statefile.WriteJSON(path, a)
+}
+
+// Current returns the activity recorded at path if it was reported
+// recently.
+func Current(path string, now time.Time) (*Activity, bool) {
+	if path == "" {
+		return nil, false
+	}
+	var a Activity
+	if err := statefile.ReadJSON(path, &a); err != nil {
+		return nil, false
+	}
+	if a.Command == "" || now.Sub(a.UpdatedAt) > freshness {
+		return nil, false
+	}
+	return &a, true
+}
+
+// Matches reports whether a local terminal shows this activity: its
+// foreground command connects to another machine, or, where that command
+// can't be found, its window title names the host, as "user@host: ~"
+// does.
+func (a *Activity) Matches(localCommand, windowTitle string) bool {
+	if remoteShells[localCommand] {
+		return true
+	}
+	return localCommand == "" && strings.Contains(strings.ToLower(windowTitle), a.Host)
+}
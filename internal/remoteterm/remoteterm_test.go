@@ -0,0 +1,82 @@
+package remoteterm
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRecordAndCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terminal.json")
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, ok := Current(path, now); ok {
+		t.Fatal("Current() found activity before any was recorded")
+	}
+	activity := &Activity{Host: " Build01 ", Session: "main", Window: "editor", Command: "vim", UpdatedAt: now}
+	if err := activity.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if err := Record(path, activity); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	got, ok := Current(path, now.Add(30*time.Second))
+	if !ok || got.Host != "build01" || got.Command != "vim" || got.Window != "editor" {
+		t.Errorf("Current() = %+v, %v; want vim on build01", got, ok)
+	}
+	if _, ok := Current(path, now.Add(2*time.Minute)); ok {
+		t.Error("Current() returned a stale report")
+	}
+}
+
+func TestClean(t *testing.T) {
+	if err := (&Activity{Host: "build01"}).Clean(); err == nil {
+		t.Error("Clean() accepted an activity without a command")
+	}
+	if err := (&Activity{Command: "vim"}).Clean(); err == nil {
+		t.Error("Clean() accepted an activity without a host")
+	}
+
+	long := &Activity{Host: "build01", Command: "vim", Window: "x" + strings.Repeat("é", maxField)}
+	if err := long.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if len(long.Window) > maxField || !utf8.ValidString(long.Window) {
+		t.Errorf("Clean() left window of %d bytes, valid UTF-8 %v", len(long.Window), utf8.ValidString(long.Window))
+	}
+}
+
+func TestMatches(t *testing.T) {
+	activity := &Activity{Host: "build01", Command: "vim"}
+	tests := []struct {
+		command, title string
+		want           bool
+	}{
+		{"ssh", "anything", true},
+		{"mosh-client", "", true},
+		{"vim", "me@build01: ~", false},
+		{"", "me@build01: ~/src", true},
+		{"", "me@laptop: ~", false},
+	}
+	for _, tt := range tests {
+		if got := activity.Matches(tt.command, tt.title); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.command, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestParseClients(t *testing.T) {
+	output := "1700000000\twork\tlogs\ttail\n" +
+		"1700000300\tmain\teditor\tvim\n" +
+		"1700000100\tmain\tshell\tbash\n"
+	got := parseClients(output)
+	if got == nil || got.Session != "main" || got.Window != "editor" || got.Command != "vim" {
+		t.Errorf("parseClients() = %+v, want the most recently active client's vim", got)
+	}
+	if got := parseClients(""); got != nil {
+		t.Errorf("parseClients(\"\") = %+v, want nil without clients", got)
+	}
+}
@@ -0,0 +1,198 @@
+package repair
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+const (
+	batchSize = 1000
+
+	// tolerance absorbs scheduling jitter between a sample's recorded duration
+	// and the actual gap to the next poll.
+	tolerance = time.Second
+)
+
+const (
+	KindRename = "rename"
+	KindMerge  = "merge"
+	KindClamp  = "clamp"
+)
+
+type Change struct {
+	Kind        string
+	EventID     uint
+	Description string
+}
+
+type Result struct {
+	DryRun  bool
+	Renamed int64
+	Merged  int
+	Clamped int
+	Changes []Change
+}
+
+type Repairer struct {
+	repo        *database.Repository
+	maxDuration time.Duration
+}
+
+// New returns a Repairer that clamps event durations to maxDuration, which
+// should be the largest interval the tracker can legitimately record.
+func New(repo *database.Repository, maxDuration time.Duration) *Repairer {
+	return &Repairer{repo: repo, maxDuration: maxDuration}
+}
+
+// Run re-normalizes app names, merges overlapping duplicate samples of the same
+// window and clamps durations that exceed the gap to the next event or the
+// maximum poll interval. With dryRun nothing is written; otherwise all changes
+// are applied in one transaction.
+func (r *Repairer) Run(dryRun bool) (*Result, error) {
+	if dryRun {
+		return r.run(true)
+	}
+
+	var result *Result
+	err := r.repo.WithTx(func(tx *database.Repository) error {
+		inTx := *r
+		inTx.repo = tx
+		var err error
+		result, err = inTx.run(false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Repairer) run(dryRun bool) (*Result, error) {
+	result := &Result{DryRun: dryRun}
+
+	renames, err := r.repo.AppNameChanges()
+	if err != nil {
+		return nil, err
+	}
+	for _, rename := range renames {
+		result.Renamed += rename.Count
+		result.Changes = append(result.Changes, Change{
+			Kind:        KindRename,
+			Description: fmt.Sprintf("%q -> %q (%d events)", rename.From, rename.To, rename.Count),
+		})
+	}
+	if !dryRun && len(renames) > 0 {
+		if _, err := r.repo.NormalizeAppNames(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.scan(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Repairer) scan(result *Result) error {
+	var prev *models.FocusEvent
+	prevDirty := false
+	var toDelete []uint
+
+	var cursor time.Time
+	var cursorID uint
+	for {
+		events, err := r.repo.GetEventsAfter(cursor, cursorID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+		cursor = events[len(events)-1].Timestamp
+		cursorID = events[len(events)-1].ID
+
+		for _, event := range events {
+			if prev != nil && r.isDuplicate(prev, event) {
+				prevEnd := end(prev)
+				if eventEnd := end(event); eventEnd.After(prevEnd) {
+					prev.Duration = int64(eventEnd.Sub(prev.Timestamp).Seconds())
+					prevDirty = true
+				}
+				toDelete = append(toDelete, event.ID)
+				result.Merged++
+				result.Changes = append(result.Changes, Change{
+					Kind:        KindMerge,
+					EventID:     event.ID,
+					Description: fmt.Sprintf("merged #%d into #%d (%s)", event.ID, prev.ID, prev.AppName),
+				})
+				continue
+			}
+
+			if prev != nil {
+				if err := r.finish(prev, prevDirty, event.Timestamp.Sub(prev.Timestamp), result); err != nil {
+					return err
+				}
+			}
+			prev = event
+			prevDirty = false
+		}
+
+		if !result.DryRun && len(toDelete) > 0 {
+			if _, err := r.repo.DeleteEvents(toDelete); err != nil {
+				return err
+			}
+		}
+		toDelete = toDelete[:0]
+	}
+
+	if prev != nil {
+		return r.finish(prev, prevDirty, -1, result)
+	}
+	return nil
+}
+
+// isDuplicate reports whether next is a second sample of the same window that
+// starts before prev's recorded interval has ended.
+func (r *Repairer) isDuplicate(prev, next *models.FocusEvent) bool {
+	if r.repo.NormalizeAppName(prev.AppName) != r.repo.NormalizeAppName(next.AppName) ||
+		prev.WindowTitle != next.WindowTitle ||
+		prev.IsIdle != next.IsIdle ||
+		prev.IsLocked != next.IsLocked {
+		return false
+	}
+	return next.Timestamp.Before(end(prev).Add(-tolerance))
+}
+
+// finish clamps the duration of a fully merged event against gap (the time to
+// the next event, negative when unknown) and writes it back if it changed.
+func (r *Repairer) finish(event *models.FocusEvent, dirty bool, gap time.Duration, result *Result) error {
+	original := event.Duration
+	limit := int64(r.maxDuration.Seconds())
+	if gap >= 0 && time.Duration(event.Duration)*time.Second > gap+tolerance {
+		limit = min(limit, int64(gap.Seconds()))
+	}
+
+	if event.Duration > limit {
+		event.Duration = limit
+		dirty = true
+		result.Clamped++
+		result.Changes = append(result.Changes, Change{
+			Kind:        KindClamp,
+			EventID:     event.ID,
+			Description: fmt.Sprintf("clamped #%d (%s) from %ds to %ds", event.ID, event.AppName, original, limit),
+		})
+	}
+
+	if !dirty || result.DryRun {
+		return nil
+	}
+	return r.repo.UpdateDuration(event.ID, event.Duration)
+}
+
+func end(event *models.FocusEvent) time.Time {
+	return event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+}
@@ -0,0 +1,120 @@
+package repair
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestRun(t *testing.T) {
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	event := func(seconds int, app, title string, duration int64) models.FocusEvent {
+		return models.FocusEvent{Timestamp: at(seconds), AppName: app, WindowTitle: title, Duration: duration, DisplayServer: "x11"}
+	}
+
+	tests := []struct {
+		name    string
+		events  []models.FocusEvent
+		renamed int64
+		merged  int
+		clamped int
+		want    []string // app:duration of the events left
+	}{
+		{
+			name:   "clean",
+			events: []models.FocusEvent{event(0, "code", "a", 60), event(60, "firefox", "b", 60)},
+			want:   []string{"code:60", "firefox:60"},
+		},
+		{
+			name:    "rename",
+			events:  []models.FocusEvent{event(0, "Firefox", "b", 60), event(60, "soffice.bin", "c", 60), event(120, "soffice", "c", 60)},
+			renamed: 3,
+			want:    []string{"firefox:60", "libreoffice:60", "libreoffice:60"},
+		},
+		{
+			name:   "overlapping samples of one window are merged",
+			events: []models.FocusEvent{event(0, "code", "a", 60), event(30, "code", "a", 60), event(45, "code", "a", 30), event(120, "firefox", "b", 60)},
+			merged: 2,
+			want:   []string{"code:90", "firefox:60"},
+		},
+		{
+			name:   "merging keeps the longer interval",
+			events: []models.FocusEvent{event(0, "code", "a", 120), event(30, "code", "a", 30), event(120, "firefox", "b", 60)},
+			merged: 1,
+			want:   []string{"code:120", "firefox:60"},
+		},
+		{
+			name:    "different windows are clamped, not merged",
+			events:  []models.FocusEvent{event(0, "code", "a", 120), event(60, "code", "b", 60)},
+			clamped: 1,
+			want:    []string{"code:60", "code:60"},
+		},
+		{
+			name:    "idle samples are not merged with active ones",
+			events:  []models.FocusEvent{event(0, "code", "a", 60), {Timestamp: at(30), AppName: "code", WindowTitle: "a", Duration: 60, IsIdle: true, DisplayServer: "x11"}},
+			clamped: 1,
+			want:    []string{"code:30", "code:60"},
+		},
+		{
+			name:   "jitter within tolerance is kept",
+			events: []models.FocusEvent{event(0, "code", "a", 61), event(60, "firefox", "b", 60)},
+			want:   []string{"code:61", "firefox:60"},
+		},
+		{
+			name:    "longer than the poll interval",
+			events:  []models.FocusEvent{event(0, "code", "a", 60), event(3600, "firefox", "b", 3600)},
+			clamped: 1,
+			want:    []string{"code:60", "firefox:600"},
+		},
+	}
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/dry-run=%v", tt.name, dryRun), func(t *testing.T) {
+				db := database.OpenTest(t)
+				// Insert through gorm directly: Create would normalize and
+				// clamp the very problems the repair is meant to find.
+				events := make([]models.FocusEvent, len(tt.events))
+				copy(events, tt.events)
+				if err := db.Create(&events).Error; err != nil {
+					t.Fatalf("inserting events: %v", err)
+				}
+				repo := database.NewRepository(db)
+
+				result, err := New(repo, 10*time.Minute).Run(dryRun)
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+				if result.Renamed != tt.renamed || result.Merged != tt.merged || result.Clamped != tt.clamped {
+					t.Errorf("Run() renamed %d, merged %d, clamped %d; want %d, %d, %d",
+						result.Renamed, result.Merged, result.Clamped, tt.renamed, tt.merged, tt.clamped)
+				}
+				if changes := tt.merged + tt.clamped; len(result.Changes) < changes {
+					t.Errorf("Run() listed %d changes, want at least %d", len(result.Changes), changes)
+				}
+
+				stored, err := repo.GetEvents(database.Query{})
+				if err != nil {
+					t.Fatalf("GetEvents() error = %v", err)
+				}
+				var got []string
+				for _, event := range stored {
+					got = append(got, fmt.Sprintf("%s:%d", event.AppName, event.Duration))
+				}
+				want := tt.want
+				if dryRun {
+					want = nil
+					for _, event := range tt.events {
+						want = append(want, fmt.Sprintf("%s:%d", event.AppName, event.Duration))
+					}
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("stored %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
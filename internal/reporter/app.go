@@ -0,0 +1,109 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/analytics"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+const (
+	// appReportTitles is how many window titles an app report lists.
+	appReportTitles = 10
+	// appReportSessions is how many of the longest sessions it lists.
+	appReportSessions = 5
+)
+
+// GenerateAppReport describes one app's use in the period. appName is
+// matched after normalization, so it covers every name mapped to it.
+func (r *Reporter) GenerateAppReport(periodType, profile, appName string) (*models.AppReport, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.repo.GetEvents(database.Query{Since: period.Start, Until: period.End, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	appName = r.repo.NormalizeAppName(appName)
+	report := &models.AppReport{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Profile:       profile,
+		AppName:       appName,
+		Titles:        []models.TitleSummary{},
+		Sessions:      []models.FocusSession{},
+		GeneratedAt:   time.Now(),
+	}
+
+	daily := make(map[string]int64)
+	titles := make(map[string]*models.TitleSummary)
+	for _, event := range events {
+		if r.repo.NormalizeAppName(event.AppName) != appName {
+			continue
+		}
+		local := recordedLocal(event)
+		report.TotalSeconds += event.Duration
+		daily[local.Format("2006-01-02")] += event.Duration
+		report.Hours[local.Hour()] += event.Duration
+
+		title, ok := titles[event.WindowTitle]
+		if !ok {
+			title = &models.TitleSummary{WindowTitle: event.WindowTitle}
+			titles[event.WindowTitle] = title
+		}
+		title.TotalSeconds += event.Duration
+		title.EventCount++
+	}
+
+	end := period.End
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+	for day := period.Start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		report.Days = append(report.Days, models.AppDay{Day: key, TotalSeconds: daily[key]})
+	}
+
+	for _, title := range titles {
+		report.Titles = append(report.Titles, *title)
+	}
+	sort.Slice(report.Titles, func(i, j int) bool {
+		if report.Titles[i].TotalSeconds != report.Titles[j].TotalSeconds {
+			return report.Titles[i].TotalSeconds > report.Titles[j].TotalSeconds
+		}
+		return report.Titles[i].WindowTitle < report.Titles[j].WindowTitle
+	})
+	if len(report.Titles) > appReportTitles {
+		report.Titles = report.Titles[:appReportTitles]
+	}
+
+	// Sessions are found among all events, so time in other apps ends them.
+	for _, session := range analytics.Sessions(r.repo, events, r.config.SessionGap()) {
+		if session.AppName == appName {
+			report.Sessions = append(report.Sessions, session)
+		}
+	}
+	sort.SliceStable(report.Sessions, func(i, j int) bool {
+		return report.Sessions[i].Seconds > report.Sessions[j].Seconds
+	})
+	if len(report.Sessions) > appReportSessions {
+		report.Sessions = report.Sessions[:appReportSessions]
+	}
+
+	return report, nil
+}
+
+// recordedLocal returns the local time an event was recorded at, in the UTC
+// offset it was recorded under when known.
+func recordedLocal(event *models.FocusEvent) time.Time {
+	if event.UTCOffset != nil {
+		return event.Timestamp.In(time.FixedZone("", *event.UTCOffset))
+	}
+	return event.Timestamp.Local()
+}
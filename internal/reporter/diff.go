@@ -0,0 +1,301 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/schedule"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// Uncategorized groups apps not listed in any configured category.
+const Uncategorized = "uncategorized"
+
+// previousPeriods pairs each period with the one before it, the default
+// base for a comparison.
+var previousPeriods = map[string]string{
+	"day":   "yesterday",
+	"today": "yesterday",
+	"week":  "lastweek",
+	"month": "lastmonth",
+}
+
+// PreviousPeriod returns the period preceding periodType, or "" if there is
+// no named one.
+func PreviousPeriod(periodType string) string {
+	return previousPeriods[periodType]
+}
+
+// GenerateDiff compares the time per app, and per category when categories
+// are configured, in periodType against basePeriodType.
+func (r *Reporter) GenerateDiff(periodType, basePeriodType, profile string) (*models.Diff, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+	base, err := r.getPeriod(basePeriodType)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.repo.GetDailyAppTotals(database.Query{Since: period.Start, Until: period.End, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totals: %w", err)
+	}
+	previous, err := r.repo.GetDailyAppTotals(database.Query{Since: base.Start, Until: base.End, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base totals: %w", err)
+	}
+
+	diff := &models.Diff{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Base:          *base,
+		Profile:       profile,
+		GeneratedAt:   time.Now(),
+	}
+
+	// As in the weekly digest, only days off on working days count.
+	workDays := schedule.WorkDays(r.config.WorkHours.Windows)
+	daysOff := holiday.Load(r.config.Holidays).On(workDays)
+	diff.DaysOff = daysOff.Between(period.Start, period.End)
+	diff.BaseDaysOff = daysOff.Between(base.Start, base.End)
+	scale := workDayScale(diff, workDays)
+
+	byApp := func(t models.DailyAppTotal) string { return t.AppName }
+	diff.Apps = diffEntries(current, previous, byApp, scale)
+
+	var baseSeconds, seconds int64
+	for _, entry := range diff.Apps {
+		baseSeconds += entry.BaseSeconds
+		seconds += entry.Seconds
+	}
+	diff.Total = newDiffEntry("total", baseSeconds, seconds, scale)
+
+	if len(r.config.Budgets.Categories) > 0 {
+		category := r.Categorizer()
+		byCategory := func(t models.DailyAppTotal) string {
+			return category(t.AppName, t.Tag)
+		}
+		diff.Categories = diffEntries(current, previous, byCategory, scale)
+	}
+
+	return diff, nil
+}
+
+// workDayScale is the ratio of working days in the period to those in the
+// base, which base times are scaled by to compare time per working day.
+// Working days are the days on weekdays less the days off. It is 1 when both
+// periods have as many days off, or either has no working days left.
+func workDayScale(diff *models.Diff, weekdays [7]bool) float64 {
+	if diff.DaysOff == diff.BaseDaysOff {
+		return 1
+	}
+	days := func(p models.ReportPeriod) int {
+		n := 0
+		for day := p.Start; day.Before(p.End); day = day.AddDate(0, 0, 1) {
+			if weekdays[day.Weekday()] {
+				n++
+			}
+		}
+		return n
+	}
+	workDays := days(diff.Period) - diff.DaysOff
+	baseWorkDays := days(diff.Base) - diff.BaseDaysOff
+	if workDays <= 0 || baseWorkDays <= 0 {
+		return 1
+	}
+	return float64(workDays) / float64(baseWorkDays)
+}
+
+// Categorizer returns a function naming the category time in a normalized app
+// counts towards: the tag when there is one, else the app's configured
+// category, else Uncategorized.
+func (r *Reporter) Categorizer() func(appName, tag string) string {
+	categories := r.categoryIndex()
+	return func(appName, tag string) string {
+		if tag != "" {
+			return tag
+		}
+		if category, ok := categories[appName]; ok {
+			return category
+		}
+		return Uncategorized
+	}
+}
+
+// categoryIndex maps normalized app names to their category. An app listed in
+// several categories belongs to the first by name.
+func (r *Reporter) categoryIndex() map[string]string {
+	names := make([]string, 0, len(r.config.Budgets.Categories))
+	for name := range r.config.Budgets.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	index := make(map[string]string)
+	for _, name := range names {
+		for _, app := range r.config.Budgets.Categories[name] {
+			app = r.repo.NormalizeAppName(app)
+			if _, ok := index[app]; !ok {
+				index[app] = name
+			}
+		}
+	}
+	return index
+}
+
+func diffEntries(current, previous []models.DailyAppTotal, key func(models.DailyAppTotal) string, scale float64) []models.DiffEntry {
+	seconds := make(map[string]int64)
+	baseSeconds := make(map[string]int64)
+	for _, t := range current {
+		seconds[key(t)] += t.TotalSeconds
+	}
+	for _, t := range previous {
+		baseSeconds[key(t)] += t.TotalSeconds
+	}
+
+	entries := make([]models.DiffEntry, 0, len(seconds))
+	for name := range seconds {
+		entries = append(entries, newDiffEntry(name, baseSeconds[name], seconds[name], scale))
+	}
+	for name := range baseSeconds {
+		if _, ok := seconds[name]; !ok {
+			entries = append(entries, newDiffEntry(name, baseSeconds[name], 0, scale))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := abs(entries[i].DeltaSeconds), abs(entries[j].DeltaSeconds)
+		if a != b {
+			return a > b
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// newDiffEntry compares seconds with baseSeconds, scaled by scale for the
+// change percentage.
+func newDiffEntry(name string, baseSeconds, seconds int64, scale float64) models.DiffEntry {
+	entry := models.DiffEntry{
+		Name:         name,
+		BaseSeconds:  baseSeconds,
+		Seconds:      seconds,
+		DeltaSeconds: seconds - baseSeconds,
+	}
+	if baseSeconds > 0 {
+		scaled := float64(baseSeconds) * scale
+		change := (float64(seconds) - scaled) / scaled * 100.0
+		entry.ChangePercent = &change
+	}
+	return entry
+}
+
+func (r *Reporter) FormatDiffText(diff *models.Diff) string {
+	output := fmt.Sprintf("Comparison - %s vs %s\n", diff.Period.Type, diff.Base.Type)
+	output += fmt.Sprintf("Period: %s to %s\n",
+		diff.Period.Start.Format("2006-01-02 15:04"), diff.Period.End.Format("2006-01-02 15:04"))
+	output += fmt.Sprintf("Base:   %s to %s\n",
+		diff.Base.Start.Format("2006-01-02 15:04"), diff.Base.End.Format("2006-01-02 15:04"))
+	if diff.Profile != "" {
+		output += fmt.Sprintf("Profile: %s\n", diff.Profile)
+	}
+	if diff.DaysOff != diff.BaseDaysOff {
+		output += fmt.Sprintf("Days off: %d vs %d, percentages compare time per working day\n", diff.DaysOff, diff.BaseDaysOff)
+	}
+	output += fmt.Sprintf("Total Time: %s\n\n", formatDiffLine(diff.Total))
+
+	output += formatDiffTable("Application", diff.Apps)
+	if len(diff.Categories) > 0 {
+		output += "\n" + formatDiffTable("Category", diff.Categories)
+	}
+	return output
+}
+
+func formatDiffTable(heading string, entries []models.DiffEntry) string {
+	if len(entries) == 0 {
+		return "No activity recorded in either period.\n"
+	}
+
+	output := fmt.Sprintf("%-30s %10s %10s %10s %9s\n", heading, "Before", "After", "Change", "Percent")
+	output += fmt.Sprintf("%s\n", "--------------------------------------------------------------------------------")
+	for _, entry := range entries {
+		output += fmt.Sprintf("%-30s %10s %10s %10s %9s\n",
+			truncate(entry.Name, 30),
+			utils.FormatRoundedUnit(entry.BaseSeconds),
+			utils.FormatRoundedUnit(entry.Seconds),
+			formatDelta(entry.DeltaSeconds),
+			formatChangePercent(entry.ChangePercent))
+	}
+	return output
+}
+
+func formatDiffLine(entry models.DiffEntry) string {
+	return fmt.Sprintf("%s -> %s (%s, %s)",
+		utils.FormatHoursMinutes(entry.BaseSeconds),
+		utils.FormatHoursMinutes(entry.Seconds),
+		formatDelta(entry.DeltaSeconds),
+		formatChangePercent(entry.ChangePercent))
+}
+
+func formatDelta(seconds int64) string {
+	if seconds < 0 {
+		return "-" + utils.FormatRoundedUnit(-seconds)
+	}
+	return "+" + utils.FormatRoundedUnit(seconds)
+}
+
+func formatChangePercent(change *float64) string {
+	if change == nil {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", *change)
+}
+
+// FormatDiffCSV writes one row per app and category with raw seconds.
+func (r *Reporter) FormatDiffCSV(diff *models.Diff) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"kind", "name", "base_seconds", "seconds", "delta_seconds", "change_percent"})
+
+	write := func(kind string, entries []models.DiffEntry) {
+		for _, entry := range entries {
+			change := ""
+			if entry.ChangePercent != nil {
+				change = strconv.FormatFloat(*entry.ChangePercent, 'f', 1, 64)
+			}
+			w.Write([]string{
+				kind,
+				entry.Name,
+				strconv.FormatInt(entry.BaseSeconds, 10),
+				strconv.FormatInt(entry.Seconds, 10),
+				strconv.FormatInt(entry.DeltaSeconds, 10),
+				change,
+			})
+		}
+	}
+	write("total", []models.DiffEntry{diff.Total})
+	write("app", diff.Apps)
+	write("category", diff.Categories)
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,221 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestWorkDayScale(t *testing.T) {
+	monday := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	week := func(start time.Time) models.ReportPeriod {
+		return models.ReportPeriod{Start: start, End: start.AddDate(0, 0, 7)}
+	}
+	day := func(start time.Time) models.ReportPeriod {
+		return models.ReportPeriod{Start: start, End: start.AddDate(0, 0, 1)}
+	}
+	weekdays := [7]bool{false, true, true, true, true, true, false}
+	everyDay := [7]bool{true, true, true, true, true, true, true}
+
+	tests := []struct {
+		name             string
+		period, base     models.ReportPeriod
+		daysOff, baseOff int
+		workDays         [7]bool
+		want             float64
+	}{
+		{"as many days off", week(monday), week(monday.AddDate(0, 0, -7)), 1, 1, weekdays, 1},
+		{"holiday this week", week(monday), week(monday.AddDate(0, 0, -7)), 1, 0, weekdays, 4.0 / 5},
+		{"holiday last week", week(monday), week(monday.AddDate(0, 0, -7)), 0, 2, weekdays, 5.0 / 3},
+		{"every day a work day", week(monday), week(monday.AddDate(0, 0, -7)), 1, 0, everyDay, 6.0 / 7},
+		{"whole week off", week(monday), week(monday.AddDate(0, 0, -7)), 5, 0, weekdays, 1},
+		{"day off today", day(monday), day(monday.AddDate(0, 0, -1)), 1, 0, everyDay, 1},
+		{"21 working days less one against 20", models.ReportPeriod{Start: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), End: time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local)},
+			models.ReportPeriod{Start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), End: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}, 1, 0, weekdays, 20.0 / 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := &models.Diff{Period: tt.period, Base: tt.base, DaysOff: tt.daysOff, BaseDaysOff: tt.baseOff}
+			if got := workDayScale(diff, tt.workDays); got != tt.want {
+				t.Errorf("workDayScale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDiff(t *testing.T) {
+	db := database.OpenTest(t)
+	repo := database.NewRepository(db)
+	cfg := config.Default()
+	cfg.Holidays.File = filepath.Join(t.TempDir(), "holidays")
+	cfg.Budgets.Categories = map[string][]string{"work": {"code"}}
+
+	// Today's events start at midnight so that none lies in the future; they
+	// are inserted directly so that their overlap is not clamped.
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	yesterday := today.AddDate(0, 0, -1).Add(9 * time.Hour)
+	events := []models.FocusEvent{
+		{Timestamp: yesterday, AppName: "code", Duration: 3600},
+		{Timestamp: yesterday.Add(time.Hour), AppName: "firefox", Duration: 1800},
+		{Timestamp: yesterday.Add(90 * time.Minute), AppName: "slack", Duration: 600},
+		{Timestamp: today, AppName: "code", Duration: 1800},
+		{Timestamp: today, AppName: "firefox", Duration: 2400},
+		{Timestamp: today, AppName: "gimp", Duration: 1200},
+	}
+	if err := db.Create(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(cfg, repo)
+	diff, err := r.GenerateDiff("today", "yesterday", "")
+	if err != nil {
+		t.Fatalf("GenerateDiff() error = %v", err)
+	}
+
+	csv, err := r.FormatDiffCSV(diff)
+	if err != nil {
+		t.Fatalf("FormatDiffCSV() error = %v", err)
+	}
+	wantCSV := `kind,name,base_seconds,seconds,delta_seconds,change_percent
+total,total,6000,5400,-600,-10.0
+app,code,3600,1800,-1800,-50.0
+app,gimp,0,1200,1200,
+app,firefox,1800,2400,600,33.3
+app,slack,600,0,-600,-100.0
+category,work,3600,1800,-1800,-50.0
+category,uncategorized,2400,3600,1200,50.0
+`
+	if csv != wantCSV {
+		t.Errorf("FormatDiffCSV() =\n%s\nwant\n%s", csv, wantCSV)
+	}
+
+	out, err := r.FormatReportJSON(diff)
+	if err != nil {
+		t.Fatalf("FormatReportJSON() error = %v", err)
+	}
+	var decoded struct {
+		Period models.ReportPeriod `json:"period"`
+		Base   models.ReportPeriod `json:"base"`
+		Total  map[string]any      `json:"total"`
+		Apps   []map[string]any    `json:"apps"`
+	}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("diff JSON does not decode: %v\n%s", err, out)
+	}
+	if decoded.Period.Type != "today" || decoded.Base.Type != "yesterday" {
+		t.Errorf("JSON periods = %q vs %q, want today vs yesterday", decoded.Period.Type, decoded.Base.Type)
+	}
+	if decoded.Total["delta_seconds"] != float64(-600) || decoded.Total["change_percent"] != float64(-10) {
+		t.Errorf("JSON total = %v, want a delta of -600 and -10%%", decoded.Total)
+	}
+	if len(decoded.Apps) != 4 {
+		t.Fatalf("JSON apps = %v, want 4", decoded.Apps)
+	}
+	if gimp := decoded.Apps[1]; gimp["name"] != "gimp" || gimp["base_seconds"] != float64(0) {
+		t.Errorf("JSON apps[1] = %v, want gimp, new", gimp)
+	} else if _, ok := gimp["change_percent"]; ok {
+		t.Errorf("JSON new app has change_percent %v, want it left out", gimp["change_percent"])
+	}
+	if slack := decoded.Apps[3]; slack["name"] != "slack" || slack["seconds"] != float64(0) || slack["delta_seconds"] != float64(-600) {
+		t.Errorf("JSON apps[3] = %v, want slack, gone", slack)
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	byApp := func(t models.DailyAppTotal) string { return t.AppName }
+	total := func(day, app string, seconds int64) models.DailyAppTotal {
+		return models.DailyAppTotal{Day: day, AppName: app, TotalSeconds: seconds}
+	}
+	percent := func(entry models.DiffEntry) string {
+		if entry.ChangePercent == nil {
+			return "new"
+		}
+		return fmt.Sprintf("%.1f", *entry.ChangePercent)
+	}
+
+	tests := []struct {
+		name              string
+		current, previous []models.DailyAppTotal
+		scale             float64
+		want              []string
+	}{
+		{
+			name: "no activity",
+		},
+		{
+			name:     "days are summed per app",
+			current:  []models.DailyAppTotal{total("2025-03-11", "code", 600), total("2025-03-12", "code", 600)},
+			previous: []models.DailyAppTotal{total("2025-03-04", "code", 300), total("2025-03-05", "code", 300)},
+			scale:    1,
+			want:     []string{"code 600 1200 600 100.0"},
+		},
+		{
+			name:     "largest absolute change first",
+			current:  []models.DailyAppTotal{total("2025-03-11", "code", 600), total("2025-03-11", "firefox", 3600), total("2025-03-11", "slack", 900)},
+			previous: []models.DailyAppTotal{total("2025-03-04", "code", 2400), total("2025-03-04", "firefox", 3000), total("2025-03-04", "slack", 900)},
+			scale:    1,
+			want:     []string{"code 2400 600 -1800 -75.0", "firefox 3000 3600 600 20.0", "slack 900 900 0 0.0"},
+		},
+		{
+			name:     "ties by name",
+			current:  []models.DailyAppTotal{total("2025-03-11", "zoom", 600), total("2025-03-11", "anki", 1200)},
+			previous: []models.DailyAppTotal{total("2025-03-04", "zoom", 1200), total("2025-03-04", "anki", 600)},
+			scale:    1,
+			want:     []string{"anki 600 1200 600 100.0", "zoom 1200 600 -600 -50.0"},
+		},
+		{
+			name:     "apps in only one period",
+			current:  []models.DailyAppTotal{total("2025-03-11", "gimp", 300)},
+			previous: []models.DailyAppTotal{total("2025-03-04", "slack", 600)},
+			scale:    1,
+			want:     []string{"slack 600 0 -600 -100.0", "gimp 0 300 300 new"},
+		},
+		{
+			name:     "scale applies to the percentage only",
+			current:  []models.DailyAppTotal{total("2025-03-11", "code", 3600)},
+			previous: []models.DailyAppTotal{total("2025-03-04", "code", 3600)},
+			scale:    0.5,
+			want:     []string{"code 3600 3600 0 100.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range diffEntries(tt.current, tt.previous, byApp, tt.scale) {
+				got = append(got, fmt.Sprintf("%s %d %d %d %s", entry.Name, entry.BaseSeconds, entry.Seconds, entry.DeltaSeconds, percent(entry)))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("diffEntries() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	tests := []struct {
+		seconds int64
+		want    string
+	}{
+		{0, "+0s"},
+		{45, "+45s"},
+		{-45, "-45s"},
+		{600, "+10m"},
+		{-600, "-10m"},
+		{7200 + 1800, "+2h"},
+		{-(7200 + 1800), "-2h"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDelta(tt.seconds); got != tt.want {
+			t.Errorf("formatDelta(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -1,44 +1,64 @@
 package reporter
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/actionsum/actionsum/internal/analytics"
+	"github.com/actionsum/actionsum/internal/backfill"
 	"github.com/actionsum/actionsum/internal/config"
 	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/gaps"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/locale"
 	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/schedule"
 	"github.com/actionsum/actionsum/pkg/utils"
 )
 
 type Reporter struct {
 	config *config.Config
 	repo   *database.Repository
+	locale *locale.Locale
 }
 
 func New(cfg *config.Config, repo *database.Repository) *Reporter {
 	return &Reporter{
 		config: cfg,
 		repo:   repo,
+		locale: locale.New(cfg.Report.Locale),
 	}
 }
 
 func (r *Reporter) GenerateReport(periodType string) (*models.Report, error) {
+	return r.GenerateProfileReport(periodType, "")
+}
+
+// GenerateProfileReport reports on events recorded under profile, or on all
+// profiles when profile is empty.
+func (r *Reporter) GenerateProfileReport(periodType, profile string) (*models.Report, error) {
 	period, err := r.getPeriod(periodType)
 	if err != nil {
 		return nil, err
 	}
 
-	summaries, err := r.repo.GetAppSummarySince(period.Start)
+	summaries, err := r.repo.GetAppSummary(database.Query{Since: period.Start, Until: period.End, Profile: profile})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get app summary: %w", err)
 	}
 
-	var totalSeconds int64
+	var totalSeconds, fullscreenSeconds int64
 	for i := range summaries {
 		summaries[i].TotalMinutes = float64(summaries[i].TotalSeconds) / 60.0
 		summaries[i].TotalHours = float64(summaries[i].TotalSeconds) / 3600.0
 		totalSeconds += summaries[i].TotalSeconds
+		fullscreenSeconds += summaries[i].FullscreenSeconds
 	}
 
 	if totalSeconds > 0 {
@@ -47,18 +67,349 @@ func (r *Reporter) GenerateReport(periodType string) (*models.Report, error) {
 		}
 	}
 
+	events, err := r.repo.GetEvents(database.Query{Since: period.Start, Until: period.End, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	var backfilled int64
+	for _, event := range events {
+		if strings.HasPrefix(event.Source, backfill.SourcePrefix) {
+			backfilled += event.Duration
+		}
+	}
+
+	var workSplit *models.WorkSplit
+	if len(r.config.WorkHours.Windows) > 0 {
+		workSplit = r.splitWorkHours(events, summaries)
+	}
+
+	stateEvents, err := r.repo.GetStateEventsSince(period.Start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state events: %w", err)
+	}
+
+	focus, err := analytics.New(r.config, r.repo).FocusStats(period.Start, period.End, time.Now(), profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute focus stats: %w", err)
+	}
+
+	notes, err := r.repo.GetNotes(period.Start, period.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+
+	sessions := analytics.Sessions(r.repo, events, r.config.SessionGap())
+	annotateApps(summaries, sessions)
+
 	report := &models.Report{
-		Period:       *period,
-		Apps:         summaries,
-		TotalSeconds: totalSeconds,
-		TotalMinutes: float64(totalSeconds) / 60.0,
-		TotalHours:   float64(totalSeconds) / 3600.0,
-		GeneratedAt:  time.Now(),
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Profile:       profile,
+		Apps:          summaries,
+		TotalSeconds:  totalSeconds,
+		TotalMinutes:  float64(totalSeconds) / 60.0,
+		TotalHours:    float64(totalSeconds) / 3600.0,
+		Fullscreen:    fullscreenSeconds,
+		Locks:         summarizeLocks(stateEvents, period.Start, period.End, time.Now()),
+		WorkHours:     workSplit,
+		Metrics:       analytics.Metrics(sessions),
+		Focus:         focus,
+		Notes:         notes,
+		Backfill:      backfilled,
+		Labels:        summarizeLabels(events, totalSeconds),
+		Domains:       summarizeDomains(events, totalSeconds),
+		Locations:     summarizeLocations(events, totalSeconds),
+		SubApps:       summarizeSubApps(events, totalSeconds),
+		ShellDirs:     summarizeShellDirs(events, totalSeconds),
+		Projects:      summarizeProjects(events, totalSeconds),
+		Media:         summarizeMedia(events, totalSeconds),
+		Remote:        summarizeRemote(events),
+		VPNSeconds:    vpnSeconds(events),
+		Meetings:      summarizeMeetings(events, totalSeconds),
+		Outputs:       summarizeOutputs(events, totalSeconds),
+		GeneratedAt:   time.Now(),
 	}
 
 	return report, nil
 }
 
+// group is the events sharing a summary key, with their total time and
+// its share of all tracked time.
+type group[K comparable] struct {
+	key     K
+	events  []*models.FocusEvent
+	seconds int64
+	percent float64
+}
+
+// summarizeBy groups events by key, leaving out those it reports false for,
+// and summarizes each group, the most time first and then in key order.
+func summarizeBy[K comparable, S any](events []*models.FocusEvent, totalSeconds int64,
+	key func(*models.FocusEvent) (K, bool), compare func(a, b K) int, summarize func(group[K]) S) []S {
+	groups := make(map[K]*group[K])
+	for _, event := range events {
+		k, ok := key(event)
+		if !ok {
+			continue
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &group[K]{key: k}
+			groups[k] = g
+		}
+		g.events = append(g.events, event)
+		g.seconds += event.Duration
+	}
+
+	sorted := make([]*group[K], 0, len(groups))
+	for _, g := range groups {
+		if totalSeconds > 0 {
+			g.percent = float64(g.seconds) / float64(totalSeconds) * 100.0
+		}
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].seconds != sorted[j].seconds {
+			return sorted[i].seconds > sorted[j].seconds
+		}
+		return compare(sorted[i].key, sorted[j].key) < 0
+	})
+
+	summaries := make([]S, len(sorted))
+	for i, g := range sorted {
+		summaries[i] = summarize(*g)
+	}
+	return summaries
+}
+
+// field keys events by a string field, leaving out those where it's empty.
+func field(get func(*models.FocusEvent) string) func(*models.FocusEvent) (string, bool) {
+	return func(event *models.FocusEvent) (string, bool) {
+		value := get(event)
+		return value, value != ""
+	}
+}
+
+// summarizeLabels totals the time of labelled events per label.
+func summarizeLabels(events []*models.FocusEvent, totalSeconds int64) []models.LabelSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.Label }), strings.Compare,
+		func(g group[string]) models.LabelSummary {
+			return models.LabelSummary{Label: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+		})
+}
+
+// summarizeDomains totals the time of browser events per website.
+func summarizeDomains(events []*models.FocusEvent, totalSeconds int64) []models.DomainSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.Domain }), strings.Compare,
+		func(g group[string]) models.DomainSummary {
+			return models.DomainSummary{Domain: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+		})
+}
+
+// summarizeLocations totals the time of events per location.
+func summarizeLocations(events []*models.FocusEvent, totalSeconds int64) []models.LocationSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.Location }), strings.Compare,
+		func(g group[string]) models.LocationSummary {
+			return models.LocationSummary{Location: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+		})
+}
+
+// summarizeSubApps totals the time of terminal events per app, foreground
+// command and remote host.
+func summarizeSubApps(events []*models.FocusEvent, totalSeconds int64) []models.SubAppSummary {
+	type subApp struct{ app, subApp, host string }
+	key := func(e *models.FocusEvent) (subApp, bool) {
+		return subApp{e.AppName, e.SubApp, e.RemoteHost}, e.SubApp != ""
+	}
+	compare := func(a, b subApp) int {
+		return cmp.Or(strings.Compare(a.app, b.app), strings.Compare(a.subApp, b.subApp), strings.Compare(a.host, b.host))
+	}
+	return summarizeBy(events, totalSeconds, key, compare, func(g group[subApp]) models.SubAppSummary {
+		return models.SubAppSummary{AppName: g.key.app, SubApp: g.key.subApp, RemoteHost: g.key.host,
+			TotalSeconds: g.seconds, Percentage: g.percent}
+	})
+}
+
+// summarizeMedia totals the time each media player was playing, and how
+// much of it was behind another app.
+func summarizeMedia(events []*models.FocusEvent, totalSeconds int64) []models.MediaSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.MediaPlayer }), strings.Compare,
+		func(g group[string]) models.MediaSummary {
+			summary := models.MediaSummary{Player: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+			for _, event := range g.events {
+				if !models.PlayerIsApp(event.MediaPlayer, event.AppName) {
+					summary.BackgroundSeconds += event.Duration
+				}
+			}
+			return summary
+		})
+}
+
+// summarizeRemote totals the time in remote-desktop clients and in remote
+// sessions per protocol, by protocol name.
+func summarizeRemote(events []*models.FocusEvent) []models.RemoteSummary {
+	totals := make(map[string]*models.RemoteSummary)
+	summary := func(protocol string) *models.RemoteSummary {
+		if totals[protocol] == nil {
+			totals[protocol] = &models.RemoteSummary{Protocol: protocol}
+		}
+		return totals[protocol]
+	}
+	for _, event := range events {
+		if event.RemoteClient != "" {
+			summary(event.RemoteClient).ClientSeconds += event.Duration
+		}
+		if event.RemoteSession != "" {
+			summary(event.RemoteSession).SessionSeconds += event.Duration
+		}
+	}
+
+	remote := make([]models.RemoteSummary, 0, len(totals))
+	for _, summary := range totals {
+		remote = append(remote, *summary)
+	}
+	sort.Slice(remote, func(i, j int) bool { return remote[i].Protocol < remote[j].Protocol })
+	return remote
+}
+
+// vpnSeconds totals the time of events recorded while a VPN was up.
+func vpnSeconds(events []*models.FocusEvent) int64 {
+	var seconds int64
+	for _, event := range events {
+		if event.VPN {
+			seconds += event.Duration
+		}
+	}
+	return seconds
+}
+
+// summarizeShellDirs totals the time of terminal events per working
+// directory of their shell.
+func summarizeShellDirs(events []*models.FocusEvent, totalSeconds int64) []models.ShellDirSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.ShellDir }), strings.Compare,
+		func(g group[string]) models.ShellDirSummary {
+			return models.ShellDirSummary{Dir: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+		})
+}
+
+// summarizeProjects totals the time of events editor plugins put in a
+// project, counting the files edited and listing the languages by time.
+func summarizeProjects(events []*models.FocusEvent, totalSeconds int64) []models.ProjectSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.Project }), strings.Compare,
+		func(g group[string]) models.ProjectSummary {
+			files := make(map[string]bool)
+			languages := make(map[string]int64)
+			for _, event := range g.events {
+				if event.FileHash != "" {
+					files[event.FileHash] = true
+				}
+				if event.Language != "" {
+					languages[event.Language] += event.Duration
+				}
+			}
+			summary := models.ProjectSummary{Project: g.key, TotalSeconds: g.seconds, Percentage: g.percent, Files: len(files)}
+			for language := range languages {
+				summary.Languages = append(summary.Languages, language)
+			}
+			sort.Slice(summary.Languages, func(i, j int) bool {
+				a, b := summary.Languages[i], summary.Languages[j]
+				if languages[a] != languages[b] {
+					return languages[a] > languages[b]
+				}
+				return a < b
+			})
+			return summary
+		})
+}
+
+// summarizeOutputs totals the time of events per monitor. Events whose
+// monitor is unknown are left out.
+func summarizeOutputs(events []*models.FocusEvent, totalSeconds int64) []models.OutputSummary {
+	return summarizeBy(events, totalSeconds, field(func(e *models.FocusEvent) string { return e.Output }), strings.Compare,
+		func(g group[string]) models.OutputSummary {
+			return models.OutputSummary{Output: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+		})
+}
+
+// summarizeMeetings totals the time in meetings per app in focus.
+func summarizeMeetings(events []*models.FocusEvent, totalSeconds int64) []models.MeetingSummary {
+	key := func(e *models.FocusEvent) (string, bool) { return e.AppName, e.InMeeting }
+	return summarizeBy(events, totalSeconds, key, strings.Compare, func(g group[string]) models.MeetingSummary {
+		return models.MeetingSummary{AppName: g.key, TotalSeconds: g.seconds, Percentage: g.percent}
+	})
+}
+
+// splitWorkHours divides event time into working and off hours, filling in
+// each summary's WorkSeconds. Events straddling a boundary are split.
+func (r *Reporter) splitWorkHours(events []*models.FocusEvent, summaries []models.AppSummary) *models.WorkSplit {
+	index := make(map[string]int, len(summaries))
+	for i := range summaries {
+		index[summaries[i].AppName] = i
+	}
+
+	var split models.WorkSplit
+	for _, event := range events {
+		start := event.Timestamp.In(time.Local)
+		inside := int64(schedule.Overlap(r.config.WorkHours.Windows, start,
+			start.Add(time.Duration(event.Duration)*time.Second)).Seconds())
+
+		split.InsideSeconds += inside
+		split.OutsideSeconds += event.Duration - inside
+		if i, ok := index[r.repo.NormalizeAppName(event.AppName)]; ok {
+			summaries[i].WorkSeconds += inside
+		}
+	}
+	return &split
+}
+
+// summarizeLocks counts lock transitions inside [start, end) and sums the time
+// spent locked, clipping intervals that straddle the period boundaries.
+func summarizeLocks(events []*models.StateEvent, start, end, now time.Time) models.LockSummary {
+	var summary models.LockSummary
+	var lockedSince time.Time
+	locked := false
+
+	if now.Before(end) {
+		end = now
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case models.StateLock:
+			if locked {
+				continue
+			}
+			locked = true
+			lockedSince = event.Timestamp
+			if lockedSince.Before(start) {
+				lockedSince = start
+			} else {
+				summary.LockCount++
+			}
+		case models.StateUnlock:
+			if !locked {
+				continue
+			}
+			locked = false
+			until := event.Timestamp
+			if until.After(end) {
+				until = end
+			}
+			if until.After(lockedSince) {
+				summary.AwaySeconds += int64(until.Sub(lockedSince).Seconds())
+			}
+		}
+	}
+
+	if locked && end.After(lockedSince) {
+		summary.AwaySeconds += int64(end.Sub(lockedSince).Seconds())
+	}
+
+	summary.AwayHours = float64(summary.AwaySeconds) / 3600.0
+	return summary
+}
+
 func (r *Reporter) getPeriod(periodType string) (*models.ReportPeriod, error) {
 	now := time.Now()
 	var start, end time.Time
@@ -68,20 +419,30 @@ func (r *Reporter) getPeriod(periodType string) (*models.ReportPeriod, error) {
 		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 		end = start.Add(24 * time.Hour)
 
-	case "week":
+	case "yesterday":
+		end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		start = end.AddDate(0, 0, -1)
+
+	case "week", "lastweek":
 		weekday := int(now.Weekday())
 		if weekday == 0 {
 			weekday = 7 // Sunday = 7
 		}
 		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(weekday - 1))
+		if periodType == "lastweek" {
+			start = start.AddDate(0, 0, -7)
+		}
 		end = start.AddDate(0, 0, 7)
 
-	case "month":
+	case "month", "lastmonth":
 		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if periodType == "lastmonth" {
+			start = start.AddDate(0, -1, 0)
+		}
 		end = start.AddDate(0, 1, 0)
 
 	default:
-		return nil, fmt.Errorf("invalid period type: %s (valid: day, week, month)", periodType)
+		return nil, fmt.Errorf("invalid period type: %s (valid: day, yesterday, week, lastweek, month, lastmonth)", periodType)
 	}
 
 	return &models.ReportPeriod{
@@ -92,11 +453,50 @@ func (r *Reporter) getPeriod(periodType string) (*models.ReportPeriod, error) {
 }
 
 func (r *Reporter) FormatReportText(report *models.Report) string {
-	output := fmt.Sprintf("Activity Report - %s\n", report.Period.Type)
+	output := fmt.Sprintf("Activity Report - %s\n", r.locale.Period(report.Period.Type))
 	output += fmt.Sprintf("Period: %s to %s\n",
 		report.Period.Start.Format("2006-01-02 15:04"),
 		report.Period.End.Format("2006-01-02 15:04"))
-	output += fmt.Sprintf("Total Time: %s\n\n", utils.FormatRoundedUnit(report.TotalSeconds))
+	if report.Profile != "" {
+		output += fmt.Sprintf("Profile: %s\n", report.Profile)
+	}
+	output += fmt.Sprintf("Total Time: %s\n", utils.FormatRoundedUnit(report.TotalSeconds))
+	if report.Backfill > 0 {
+		output += fmt.Sprintf("Backfilled: %s (estimated from history, low confidence)\n", utils.FormatHoursMinutes(report.Backfill))
+	}
+	if report.Fullscreen > 0 {
+		output += fmt.Sprintf("Fullscreen: %s\n", utils.FormatHoursMinutes(report.Fullscreen))
+	}
+	if report.WorkHours != nil {
+		output += fmt.Sprintf("Work Hours: %s inside, %s outside\n",
+			utils.FormatHoursMinutes(report.WorkHours.InsideSeconds),
+			utils.FormatHoursMinutes(report.WorkHours.OutsideSeconds))
+	}
+	if report.Locks.LockCount > 0 || report.Locks.AwaySeconds > 0 {
+		output += fmt.Sprintf("Screen Locked: %d times, %s away\n",
+			report.Locks.LockCount, utils.FormatHoursMinutes(report.Locks.AwaySeconds))
+	}
+	if metrics := report.Metrics; metrics != nil && metrics.LongestSession != nil {
+		output += fmt.Sprintf("Sessions: %d (average %s, longest %s in %s)\n",
+			metrics.Sessions, utils.FormatHoursMinutes(metrics.AverageSessionSeconds),
+			utils.FormatHoursMinutes(metrics.LongestSession.Seconds), metrics.LongestSession.AppName)
+		output += fmt.Sprintf("App Switches: %d (%s per hour)\n", metrics.Switches, r.locale.Decimal(metrics.SwitchesPerHour, 1))
+	}
+	if focus := report.Focus; focus != nil {
+		output += fmt.Sprintf("Goal Streak: %s (longest %s, daily goal %s)\n",
+			pluralDays(focus.CurrentStreak), pluralDays(focus.LongestStreak),
+			utils.FormatHoursMinutes(focus.GoalSeconds))
+		var unlocked []string
+		for _, a := range focus.Achievements {
+			if a.Unlocked {
+				unlocked = append(unlocked, a.Name)
+			}
+		}
+		if len(unlocked) > 0 {
+			output += fmt.Sprintf("Achievements: %s\n", strings.Join(unlocked, ", "))
+		}
+	}
+	output += "\n"
 
 	if len(report.Apps) == 0 {
 		output += "No activity recorded for this period.\n"
@@ -108,18 +508,381 @@ func (r *Reporter) FormatReportText(report *models.Report) string {
 
 	for _, app := range report.Apps {
 		timeStr := utils.FormatRoundedUnit(app.TotalSeconds)
+		name := app.AppName
+		if app.DisplayName != "" {
+			name = app.DisplayName
+		}
 
-		output += fmt.Sprintf("%-30s %10.2f %10s %9.1f%%\n",
-			truncate(app.AppName, 30),
-			app.TotalHours,
+		output += fmt.Sprintf("%-30s %s %10s %s\n",
+			truncate(name, 30),
+			padLeft(r.locale.Decimal(app.TotalHours, 2), 10),
 			timeStr,
-			app.Percentage)
+			padLeft(r.locale.Percent(app.Percentage, 1), 10))
+	}
+
+	if len(report.Labels) > 0 {
+		output += "\nLabels:\n"
+		for _, label := range report.Labels {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(label.Label, 30),
+				utils.FormatRoundedUnit(label.TotalSeconds),
+				padLeft(r.locale.Percent(label.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.SubApps) > 0 {
+		output += "\nTerminal commands:\n"
+		for _, subApp := range report.SubApps {
+			name := subApp.AppName + ": " + subApp.SubApp
+			if subApp.RemoteHost != "" {
+				name += " on " + subApp.RemoteHost
+			}
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(name, 30),
+				utils.FormatRoundedUnit(subApp.TotalSeconds),
+				padLeft(r.locale.Percent(subApp.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.ShellDirs) > 0 {
+		output += "\nTerminal directories:\n"
+		for _, dir := range report.ShellDirs {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(dir.Dir, 30),
+				utils.FormatRoundedUnit(dir.TotalSeconds),
+				padLeft(r.locale.Percent(dir.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.Projects) > 0 {
+		output += "\nProjects:\n"
+		for _, project := range report.Projects {
+			output += fmt.Sprintf("%-30s %21s %s  %d files %s\n",
+				truncate(project.Project, 30),
+				utils.FormatRoundedUnit(project.TotalSeconds),
+				padLeft(r.locale.Percent(project.Percentage, 1), 10),
+				project.Files,
+				strings.Join(project.Languages, ", "))
+		}
+	}
+
+	if len(report.Media) > 0 {
+		output += "\nMedia playing:\n"
+		for _, media := range report.Media {
+			output += fmt.Sprintf("%-30s %21s %s  %s in background\n",
+				truncate(media.Player, 30),
+				utils.FormatRoundedUnit(media.TotalSeconds),
+				padLeft(r.locale.Percent(media.Percentage, 1), 10),
+				utils.FormatRoundedUnit(media.BackgroundSeconds))
+		}
+	}
+
+	if len(report.Remote) > 0 {
+		output += "\nRemote desktop:\n"
+		for _, remote := range report.Remote {
+			output += fmt.Sprintf("%-30s %10s in a client, %s in a remote session\n",
+				remote.Protocol,
+				utils.FormatRoundedUnit(remote.ClientSeconds),
+				utils.FormatRoundedUnit(remote.SessionSeconds))
+		}
+	}
+	if report.VPNSeconds > 0 {
+		output += fmt.Sprintf("\nOver a VPN: %s\n", utils.FormatRoundedUnit(report.VPNSeconds))
+	}
+
+	if len(report.Meetings) > 0 {
+		var meetingSeconds int64
+		for _, meeting := range report.Meetings {
+			meetingSeconds += meeting.TotalSeconds
+		}
+		output += fmt.Sprintf("\nIn meetings: %s\n", utils.FormatRoundedUnit(meetingSeconds))
+		for _, meeting := range report.Meetings {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(meeting.AppName, 30),
+				utils.FormatRoundedUnit(meeting.TotalSeconds),
+				padLeft(r.locale.Percent(meeting.Percentage, 1), 10))
+		}
+	}
+
+	// A single monitor says nothing the total doesn't.
+	if len(report.Outputs) > 1 {
+		output += "\nMonitors:\n"
+		for _, monitor := range report.Outputs {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(monitor.Output, 30),
+				utils.FormatRoundedUnit(monitor.TotalSeconds),
+				padLeft(r.locale.Percent(monitor.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.Locations) > 0 {
+		output += "\nLocations:\n"
+		for _, location := range report.Locations {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(location.Location, 30),
+				utils.FormatRoundedUnit(location.TotalSeconds),
+				padLeft(r.locale.Percent(location.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.Domains) > 0 {
+		output += "\nWebsites:\n"
+		for _, domain := range report.Domains {
+			output += fmt.Sprintf("%-30s %21s %s\n",
+				truncate(domain.Domain, 30),
+				utils.FormatRoundedUnit(domain.TotalSeconds),
+				padLeft(r.locale.Percent(domain.Percentage, 1), 10))
+		}
+	}
+
+	if len(report.Notes) > 0 {
+		output += "\nNotes:\n"
+		for _, note := range report.Notes {
+			output += fmt.Sprintf("  %s  %s\n", formatNoteRange(note), note.Text)
+		}
 	}
 
 	return output
 }
 
-func (r *Reporter) FormatReportJSON(report *models.Report) (string, error) {
+// formatNoteRange shows whole-day notes by date and others by clock time.
+func formatNoteRange(note *models.Note) string {
+	start, end := note.Start.Local(), note.End.Local()
+	if start.Hour() == 0 && start.Minute() == 0 && end.Equal(start.AddDate(0, 0, 1)) {
+		return start.Format("2006-01-02")
+	}
+	if start.YearDay() == end.YearDay() {
+		return start.Format("2006-01-02 15:04") + "-" + end.Format("15:04")
+	}
+	return start.Format("2006-01-02 15:04") + " - " + end.Format("2006-01-02 15:04")
+}
+
+// GenerateGapReport lists periods in the report period where the machine was
+// running but nothing was tracked.
+func (r *Reporter) GenerateGapReport(periodType string, minGap time.Duration) (*models.GapReport, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := gaps.New(r.repo, minGap).Find(period.Start, period.End)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tracking gaps: %w", err)
+	}
+
+	report := &models.GapReport{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Gaps:          found,
+		GeneratedAt:   time.Now(),
+	}
+	for _, gap := range found {
+		report.TotalSeconds += gap.Seconds
+	}
+	return report, nil
+}
+
+// GenerateTimeline returns activity segments for the period, merging
+// back-to-back samples of the same app, interleaved with tracking gaps.
+func (r *Reporter) GenerateTimeline(periodType, profile string) ([]models.TimelineSegment, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+	return r.timeline(period.Start, period.End, profile)
+}
+
+// GenerateDayTimeline returns the timeline of the local day containing day.
+func (r *Reporter) GenerateDayTimeline(day time.Time, profile string) ([]models.TimelineSegment, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return r.timeline(start, start.AddDate(0, 0, 1), profile)
+}
+
+func (r *Reporter) timeline(start, end time.Time, profile string) ([]models.TimelineSegment, error) {
+	events, err := r.repo.GetEvents(database.Query{Since: start, Until: end, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	var segments []models.TimelineSegment
+	sessions := analytics.Sessions(r.repo, events, r.config.SessionGap())
+	for _, session := range sessions {
+		segments = append(segments, models.TimelineSegment{
+			Start:   session.Start,
+			End:     session.End,
+			Kind:    "activity",
+			AppName: session.AppName,
+		})
+	}
+
+	fragments, _ := analytics.Fragments(sessions)
+	for _, fragment := range fragments {
+		segments = append(segments, models.TimelineSegment{
+			Start:  fragment.Start,
+			End:    fragment.End,
+			Kind:   "fragmented",
+			Reason: fmt.Sprintf("%s, %d switches", fragment.Pattern, fragment.Switches),
+		})
+	}
+
+	notes, err := r.repo.GetNotes(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	for _, note := range notes {
+		segments = append(segments, models.TimelineSegment{
+			Start: note.Start,
+			End:   note.End,
+			Kind:  "note",
+			Text:  note.Text,
+		})
+	}
+
+	found, err := gaps.New(r.repo, gaps.DefaultMinGap).Find(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tracking gaps: %w", err)
+	}
+	for _, gap := range found {
+		segments = append(segments, models.TimelineSegment{
+			Start:  gap.Start,
+			End:    gap.End,
+			Kind:   "gap",
+			Reason: gap.Reason,
+		})
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start.Before(segments[j].Start)
+	})
+	return segments, nil
+}
+
+// GenerateDistributionReport describes per-app session lengths in the period.
+// Sessions per day are averaged over the days elapsed so far, less days off.
+func (r *Reporter) GenerateDistributionReport(periodType, profile string) (*models.DistributionReport, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.repo.GetEvents(database.Query{Since: period.Start, Until: period.End, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	end := period.End
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+	days := int(math.Ceil(end.Sub(period.Start).Hours() / 24))
+	if daysOff := holiday.Load(r.config.Holidays).Between(period.Start, end); daysOff > 0 {
+		days = max(days-daysOff, 1)
+	}
+
+	return &models.DistributionReport{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Profile:       profile,
+		Days:          days,
+		Apps:          analytics.Distributions(analytics.Sessions(r.repo, events, r.config.SessionGap()), days),
+		GeneratedAt:   time.Now(),
+	}, nil
+}
+
+// GenerateDistractionReport finds stretches of rapid app switching in the
+// period and how much of the tracked time they account for.
+func (r *Reporter) GenerateDistractionReport(periodType, profile string) (*models.DistractionReport, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := r.repo.GetEvents(database.Query{Since: period.Start, Profile: profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	sessions := analytics.Sessions(r.repo, events, r.config.SessionGap())
+	segments, patterns := analytics.Fragments(sessions)
+
+	report := &models.DistractionReport{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Segments:      segments,
+		Patterns:      patterns,
+		GeneratedAt:   time.Now(),
+	}
+	for _, event := range events {
+		report.TotalSeconds += event.Duration
+	}
+	for _, segment := range segments {
+		report.FragmentedSeconds += segment.Seconds
+	}
+	if report.TotalSeconds > 0 {
+		report.FragmentedPercent = float64(report.FragmentedSeconds) / float64(report.TotalSeconds) * 100.0
+	}
+	return report, nil
+}
+
+func (r *Reporter) FormatDistractionsText(report *models.DistractionReport) string {
+	output := fmt.Sprintf("Distraction Report - %s\n", report.Period.Type)
+	output += fmt.Sprintf("Period: %s to %s\n",
+		report.Period.Start.Format("2006-01-02 15:04"),
+		report.Period.End.Format("2006-01-02 15:04"))
+	output += fmt.Sprintf("Fragmented Time: %s of %s (%.1f%%)\n\n",
+		utils.FormatHoursMinutes(report.FragmentedSeconds),
+		utils.FormatHoursMinutes(report.TotalSeconds),
+		report.FragmentedPercent)
+
+	if len(report.Segments) == 0 {
+		output += "No fragmented time found for this period.\n"
+		return output
+	}
+
+	output += "Switching Patterns:\n"
+	for _, pattern := range report.Patterns {
+		output += fmt.Sprintf("  %-40s %5d switches\n", pattern.Apps[0]+" ↔ "+pattern.Apps[1], pattern.Switches)
+	}
+	output += "\n"
+
+	output += fmt.Sprintf("%-17s %-6s %10s %9s  %s\n", "From", "To", "Duration", "Switches", "Pattern")
+	output += fmt.Sprintf("%s\n", "--------------------------------------------------------------------------------")
+	for _, segment := range report.Segments {
+		output += fmt.Sprintf("%-17s %-6s %10s %9d  %s\n",
+			segment.Start.Local().Format("2006-01-02 15:04"),
+			segment.End.Local().Format("15:04"),
+			utils.FormatHoursMinutes(segment.Seconds),
+			segment.Switches,
+			segment.Pattern)
+	}
+	return output
+}
+
+func (r *Reporter) FormatGapsText(report *models.GapReport) string {
+	output := fmt.Sprintf("Tracking Gaps - %s\n", report.Period.Type)
+	output += fmt.Sprintf("Period: %s to %s\n",
+		report.Period.Start.Format("2006-01-02 15:04"),
+		report.Period.End.Format("2006-01-02 15:04"))
+	output += fmt.Sprintf("Untracked Time: %s\n\n", utils.FormatHoursMinutes(report.TotalSeconds))
+
+	if len(report.Gaps) == 0 {
+		output += "No tracking gaps found for this period.\n"
+		return output
+	}
+
+	output += fmt.Sprintf("%-17s %-17s %10s  %s\n", "From", "To", "Duration", "Reason")
+	output += fmt.Sprintf("%s\n", "--------------------------------------------------------------------------------")
+	for _, gap := range report.Gaps {
+		output += fmt.Sprintf("%-17s %-17s %10s  %s\n",
+			gap.Start.Local().Format("2006-01-02 15:04"),
+			gap.End.Local().Format("2006-01-02 15:04"),
+			utils.FormatHoursMinutes(gap.Seconds),
+			gap.Reason)
+	}
+	return output
+}
+
+func (r *Reporter) FormatReportJSON(report any) (string, error) {
 	data, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
@@ -127,6 +890,22 @@ func (r *Reporter) FormatReportJSON(report *models.Report) (string, error) {
 	return string(data), nil
 }
 
+func pluralDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// padLeft right-aligns s in width columns. Unlike %*s it counts runes, since
+// localized numbers may contain multi-byte separators.
+func padLeft(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
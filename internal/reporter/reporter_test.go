@@ -0,0 +1,49 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestSummarizeBy(t *testing.T) {
+	events := []*models.FocusEvent{
+		{AppName: "kitty", SubApp: "vim", Duration: 60, Project: "api", Language: "go", FileHash: "a"},
+		{AppName: "kitty", SubApp: "ssh", RemoteHost: "db", Duration: 60, Project: "api", Language: "sql", FileHash: "b"},
+		{AppName: "kitty", SubApp: "ssh", RemoteHost: "web", Duration: 60, Project: "api", Language: "go", FileHash: "a"},
+		{AppName: "spotify", MediaPlayer: "spotify", Duration: 30},
+		{AppName: "code", MediaPlayer: "spotify", Duration: 90, Project: "web", Language: "ts"},
+		{AppName: "firefox", Duration: 300},
+	}
+	const total = 600
+
+	subApps := summarizeSubApps(events, total)
+	want := []models.SubAppSummary{
+		{AppName: "kitty", SubApp: "ssh", RemoteHost: "db", TotalSeconds: 60, Percentage: 10},
+		{AppName: "kitty", SubApp: "ssh", RemoteHost: "web", TotalSeconds: 60, Percentage: 10},
+		{AppName: "kitty", SubApp: "vim", TotalSeconds: 60, Percentage: 10},
+	}
+	if !reflect.DeepEqual(subApps, want) {
+		t.Errorf("summarizeSubApps() = %+v, want %+v", subApps, want)
+	}
+
+	media := summarizeMedia(events, total)
+	wantMedia := []models.MediaSummary{{Player: "spotify", TotalSeconds: 120, BackgroundSeconds: 90, Percentage: 20}}
+	if !reflect.DeepEqual(media, wantMedia) {
+		t.Errorf("summarizeMedia() = %+v, want %+v", media, wantMedia)
+	}
+
+	projects := summarizeProjects(events, total)
+	wantProjects := []models.ProjectSummary{
+		{Project: "api", TotalSeconds: 180, Percentage: 30, Files: 2, Languages: []string{"go", "sql"}},
+		{Project: "web", TotalSeconds: 90, Percentage: 15, Languages: []string{"ts"}},
+	}
+	if !reflect.DeepEqual(projects, wantProjects) {
+		t.Errorf("summarizeProjects() = %+v, want %+v", projects, wantProjects)
+	}
+
+	if labels := summarizeLabels(events, 0); len(labels) != 0 {
+		t.Errorf("summarizeLabels() = %+v, want none", labels)
+	}
+}
@@ -0,0 +1,54 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// searchEventLimit bounds the matching events a search reads, newest first,
+// before merging them into results.
+const searchEventLimit = 5000
+
+// Search finds where text appears in app names and window titles, newest
+// first, merging back-to-back matching events in the same window into one
+// result.
+func (r *Reporter) Search(text, profile string, limit int) ([]models.SearchResult, error) {
+	events, err := r.repo.Search(database.Query{Profile: profile}, text, searchEventLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+
+	results := []models.SearchResult{}
+	for _, event := range events {
+		appName := r.repo.NormalizeAppName(event.AppName)
+		end := event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+		if n := len(results); n > 0 {
+			last := &results[n-1]
+			if last.AppName == appName && last.WindowTitle == event.WindowTitle && last.Start.Sub(end) <= r.config.SessionGap() {
+				if event.Timestamp.Before(last.Start) {
+					last.Start = event.Timestamp
+					last.UUID = event.UUID
+				}
+				last.Seconds += event.Duration
+				last.Events++
+				continue
+			}
+		}
+		if len(results) == limit {
+			break
+		}
+		results = append(results, models.SearchResult{
+			UUID:        event.UUID,
+			AppName:     appName,
+			WindowTitle: event.WindowTitle,
+			Start:       event.Timestamp,
+			End:         end,
+			Seconds:     event.Duration,
+			Events:      1,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,189 @@
+package reporter
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/utils"
+	"github.com/actionsum/actionsum/version"
+)
+
+const (
+	// shareTitleApps is how many of the top apps a shared report lists
+	// window titles for.
+	shareTitleApps = 5
+	// shareTitles is how many titles it lists per app.
+	shareTitles = 3
+)
+
+// shareApp is an app row of a shared report.
+type shareApp struct {
+	models.AppSummary
+	Titles []models.TitleSummary
+}
+
+// shareData is what the shared report template renders.
+type shareData struct {
+	Report   *models.Report
+	Apps     []shareApp
+	Redacted bool
+	Version  string
+}
+
+// FormatShareHTML renders the report for periodType as a single HTML file
+// that needs nothing else to display, for sending to someone without access
+// to the dashboard. With redact, window titles, websites and notes are left
+// out, so only app names and times are shared.
+func (r *Reporter) FormatShareHTML(periodType, profile string, redact bool) (string, error) {
+	report, err := r.GenerateProfileReport(periodType, profile)
+	if err != nil {
+		return "", err
+	}
+
+	data := shareData{Report: report, Redacted: redact, Version: version.Version}
+	for i, app := range report.Apps {
+		row := shareApp{AppSummary: app}
+		if !redact && i < shareTitleApps {
+			appReport, err := r.GenerateAppReport(periodType, profile, app.AppName)
+			if err != nil {
+				return "", fmt.Errorf("failed to get window titles of %s: %w", app.AppName, err)
+			}
+			row.Titles = appReport.Titles
+			if len(row.Titles) > shareTitles {
+				row.Titles = row.Titles[:shareTitles]
+			}
+		}
+		data.Apps = append(data.Apps, row)
+	}
+	if redact {
+		report.Notes = nil
+		report.Domains = nil
+	}
+
+	var b strings.Builder
+	if err := r.shareTemplate().Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.String(), nil
+}
+
+func (r *Reporter) shareTemplate() *template.Template {
+	return template.Must(template.New("share").Funcs(template.FuncMap{
+		"duration": utils.FormatHoursMinutes,
+		"percent":  func(v float64) string { return r.locale.Percent(v, 1) },
+		"decimal":  func(v float64) string { return r.locale.Decimal(v, 1) },
+		"date":     func(t time.Time) string { return t.Local().Format("2006-01-02") },
+		"lastDay":  func(t time.Time) string { return t.Local().Add(-time.Second).Format("2006-01-02") },
+		"stamp":    func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+		"note":     formatNoteRange,
+		"bar":      func(v float64) template.CSS { return template.CSS(fmt.Sprintf("width: %.1f%%", v)) },
+	}).Parse(shareTemplate))
+}
+
+const shareTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Activity report {{date .Report.Period.Start}} to {{lastDay .Report.Period.End}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; color: #333; background: #f5f5f5; margin: 0; padding: 24px; }
+main { max-width: 760px; margin: 0 auto; background: white; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); padding: 24px 32px; }
+h1 { font-size: 1.6rem; margin: 0 0 4px; color: #1a1a1a; }
+h2 { font-size: 1.2rem; color: #2c3e50; border-bottom: 2px solid #3498db; padding-bottom: 6px; margin-top: 28px; }
+.meta { color: #7f8c8d; margin: 0 0 20px; }
+.stats { display: flex; flex-wrap: wrap; gap: 24px; }
+.stat strong { display: block; font-size: 1.5rem; color: #3498db; }
+.stat span { color: #7f8c8d; font-size: 0.9rem; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: 8px 6px; border-bottom: 1px solid #eee; vertical-align: top; }
+th { color: #7f8c8d; font-weight: 600; font-size: 0.85rem; }
+td.num, th.num { text-align: right; white-space: nowrap; }
+.bar { height: 6px; background: #3498db; border-radius: 3px; margin-top: 4px; opacity: 0.5; }
+.titles { margin: 6px 0 0; padding-left: 18px; color: #7f8c8d; font-size: 0.85rem; }
+.redacted { color: #7f8c8d; font-style: italic; }
+footer { color: #7f8c8d; font-size: 0.8rem; margin-top: 28px; }
+@media (prefers-color-scheme: dark) {
+  body { background: #1a1a1a; color: #e0e0e0; }
+  main { background: #2d2d2d; }
+  h1 { color: #fff; }
+  h2 { color: #5dade2; }
+  th, td { border-color: #404040; }
+}
+</style>
+</head>
+<body>
+<main>
+<h1>Activity report</h1>
+<p class="meta">{{date .Report.Period.Start}} to {{lastDay .Report.Period.End}}{{with .Report.Profile}} · profile {{.}}{{end}}</p>
+
+<div class="stats">
+  <div class="stat"><strong>{{duration .Report.TotalSeconds}}</strong><span>tracked</span></div>
+  {{- with .Report.Metrics}}{{if .LongestSession}}
+  <div class="stat"><strong>{{.Sessions}}</strong><span>sessions, average {{duration .AverageSessionSeconds}}</span></div>
+  <div class="stat"><strong>{{duration .LongestSession.Seconds}}</strong><span>longest session, in {{.LongestSession.AppName}}</span></div>
+  <div class="stat"><strong>{{decimal .SwitchesPerHour}}</strong><span>app switches per hour</span></div>
+  {{- end}}{{end}}
+  {{- with .Report.Focus}}{{if .GoalSeconds}}
+  <div class="stat"><strong>{{.CurrentStreak}}</strong><span>day goal streak ({{duration .GoalSeconds}} a day)</span></div>
+  {{- end}}{{end}}
+</div>
+
+<h2>Applications</h2>
+{{- if .Apps}}
+<table>
+  <tr><th>Application</th><th class="num">Time</th><th class="num">Share</th></tr>
+  {{- range .Apps}}
+  <tr>
+    <td>{{.AppName}}<div class="bar" style="{{bar .Percentage}}"></div>
+      {{- if .Titles}}
+      <ul class="titles">{{range .Titles}}<li>{{if .WindowTitle}}{{.WindowTitle}}{{else}}—{{end}} ({{duration .TotalSeconds}})</li>{{end}}</ul>
+      {{- end}}
+    </td>
+    <td class="num">{{duration .TotalSeconds}}</td>
+    <td class="num">{{percent .Percentage}}</td>
+  </tr>
+  {{- end}}
+</table>
+{{- if .Redacted}}
+<p class="redacted">Window titles, websites and notes are not included in this report.</p>
+{{- end}}
+{{- else}}
+<p>No activity recorded for this period.</p>
+{{- end}}
+
+{{- if .Report.Labels}}
+<h2>Labels</h2>
+<table>
+  {{- range .Report.Labels}}
+  <tr><td>{{.Label}}</td><td class="num">{{duration .TotalSeconds}}</td><td class="num">{{percent .Percentage}}</td></tr>
+  {{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.Domains}}
+<h2>Websites</h2>
+<table>
+  {{- range .Report.Domains}}
+  <tr><td>{{.Domain}}</td><td class="num">{{duration .TotalSeconds}}</td><td class="num">{{percent .Percentage}}</td></tr>
+  {{- end}}
+</table>
+{{- end}}
+
+{{- if .Report.Notes}}
+<h2>Notes</h2>
+<table>
+  {{- range .Report.Notes}}
+  <tr><td class="num">{{note .}}</td><td>{{.Text}}</td></tr>
+  {{- end}}
+</table>
+{{- end}}
+
+<footer>Generated by actionsum {{.Version}} on {{stamp .Report.GeneratedAt}}.</footer>
+</main>
+</body>
+</html>
+`
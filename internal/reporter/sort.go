@@ -0,0 +1,101 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/actionsum/actionsum/internal/analytics"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// Orders for the apps of a report or summary.
+const (
+	SortTime     = "time"
+	SortEvents   = "events"
+	SortSessions = "sessions"
+	SortRecent   = "recent"
+)
+
+// SortOrders lists the orders SortApps accepts, the default first.
+var SortOrders = []string{SortTime, SortEvents, SortSessions, SortRecent}
+
+// SortApps orders apps by most time (the default for an empty order), most
+// events, most focus sessions or most recently used first. Ties go to the
+// app with more time, then by name. Sessions and recency need the apps
+// annotated with AnnotateApps, as reports are.
+func SortApps(apps []models.AppSummary, order string) error {
+	// compare is negative when a goes first.
+	var compare func(a, b *models.AppSummary) int
+	switch order {
+	case "", SortTime:
+		compare = func(a, b *models.AppSummary) int { return 0 }
+	case SortEvents:
+		compare = func(a, b *models.AppSummary) int { return b.EventCount - a.EventCount }
+	case SortSessions:
+		compare = func(a, b *models.AppSummary) int { return b.SessionCount - a.SessionCount }
+	case SortRecent:
+		compare = func(a, b *models.AppSummary) int {
+			if a.LastSeen == nil || b.LastSeen == nil {
+				return boolOrder(a.LastSeen != nil, b.LastSeen != nil)
+			}
+			return b.LastSeen.Compare(*a.LastSeen)
+		}
+	default:
+		return fmt.Errorf("unknown sort order %q (use %s)", order, strings.Join(SortOrders, ", "))
+	}
+
+	sort.SliceStable(apps, func(i, j int) bool {
+		a, b := &apps[i], &apps[j]
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		if a.TotalSeconds != b.TotalSeconds {
+			return a.TotalSeconds > b.TotalSeconds
+		}
+		return a.AppName < b.AppName
+	})
+	return nil
+}
+
+// boolOrder compares by whether a and b hold, true first.
+func boolOrder(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	}
+	return 1
+}
+
+// AnnotateApps fills in the session count and last use of each app from the
+// events matching q.
+func (r *Reporter) AnnotateApps(apps []models.AppSummary, q database.Query) error {
+	events, err := r.repo.GetEvents(q)
+	if err != nil {
+		return fmt.Errorf("failed to get events: %w", err)
+	}
+	annotateApps(apps, analytics.Sessions(r.repo, events, r.config.SessionGap()))
+	return nil
+}
+
+func annotateApps(apps []models.AppSummary, sessions []models.FocusSession) {
+	index := make(map[string]int, len(apps))
+	for i := range apps {
+		index[apps[i].AppName] = i
+	}
+	for _, session := range sessions {
+		i, ok := index[session.AppName]
+		if !ok {
+			continue
+		}
+		app := &apps[i]
+		app.SessionCount++
+		if app.LastSeen == nil || session.End.After(*app.LastSeen) {
+			end := session.End
+			app.LastSeen = &end
+		}
+	}
+}
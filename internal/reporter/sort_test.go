@@ -0,0 +1,57 @@
+package reporter
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func appNames(apps []models.AppSummary) []string {
+	names := make([]string, len(apps))
+	for i, app := range apps {
+		names[i] = app.AppName
+	}
+	return names
+}
+
+func TestSortApps(t *testing.T) {
+	base := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	apps := []models.AppSummary{
+		{AppName: "editor", TotalSeconds: 3600, EventCount: 10},
+		{AppName: "browser", TotalSeconds: 1800, EventCount: 40},
+		{AppName: "chat", TotalSeconds: 600, EventCount: 40},
+		{AppName: "terminal", TotalSeconds: 300, EventCount: 5},
+	}
+	annotateApps(apps, []models.FocusSession{
+		{AppName: "editor", Start: base, End: base.Add(time.Hour)},
+		{AppName: "chat", Start: base.Add(time.Hour), End: base.Add(70 * time.Minute)},
+		{AppName: "browser", Start: base.Add(70 * time.Minute), End: base.Add(80 * time.Minute)},
+		{AppName: "chat", Start: base.Add(80 * time.Minute), End: base.Add(90 * time.Minute)},
+		{AppName: "other", Start: base.Add(90 * time.Minute), End: base.Add(2 * time.Hour)},
+	})
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{"", []string{"editor", "browser", "chat", "terminal"}},
+		{SortEvents, []string{"browser", "chat", "editor", "terminal"}},
+		{SortSessions, []string{"chat", "editor", "browser", "terminal"}},
+		{SortRecent, []string{"chat", "browser", "editor", "terminal"}},
+		{SortTime, []string{"editor", "browser", "chat", "terminal"}},
+	}
+	for _, tt := range tests {
+		if err := SortApps(apps, tt.order); err != nil {
+			t.Fatalf("SortApps(%q) error = %v", tt.order, err)
+		}
+		if got := appNames(apps); !slices.Equal(got, tt.want) {
+			t.Errorf("SortApps(%q) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+
+	if err := SortApps(apps, "alphabetical"); err == nil {
+		t.Error("SortApps() accepted an unknown order")
+	}
+}
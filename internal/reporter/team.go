@@ -0,0 +1,122 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// GenerateTeamReport aggregates the time in periodType of the users of a
+// multi-user server who share it: the total of each user sharing their hours
+// and, once enough users contribute, the team's time per category. What is
+// included of each user follows their own sharing level; window titles and
+// app names never are.
+func (r *Reporter) GenerateTeamReport(periodType string) (*models.TeamReport, error) {
+	period, err := r.getPeriod(periodType)
+	if err != nil {
+		return nil, err
+	}
+
+	sharing, err := r.repo.GetSharingUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sharing settings: %w", err)
+	}
+
+	report := &models.TeamReport{
+		SchemaVersion: models.ReportSchemaVersion,
+		Period:        *period,
+		Members:       []models.TeamMember{},
+		GeneratedAt:   time.Now(),
+	}
+	category := r.Categorizer()
+	byCategory := make(map[string]int64)
+	var totalSeconds int64
+	for _, user := range sharing {
+		if user.UserID == "" {
+			continue
+		}
+		totals, err := r.repo.ForUser(user.UserID).GetDailyAppTotals(database.Query{Since: period.Start, Until: period.End})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get totals of %s: %w", user.UserID, err)
+		}
+
+		var seconds int64
+		for _, total := range totals {
+			seconds += total.TotalSeconds
+			// A tag names a team category only when it is a configured
+			// one; free-text tags and labels stay private.
+			name := category(total.AppName, total.Tag)
+			if _, ok := r.config.Budgets.Categories[name]; !ok {
+				name = Uncategorized
+			}
+			byCategory[name] += total.TotalSeconds
+		}
+		if seconds == 0 {
+			continue
+		}
+		report.Contributors++
+		totalSeconds += seconds
+		if user.Level == models.ShareHours {
+			report.Members = append(report.Members, models.TeamMember{
+				UserID:       user.UserID,
+				TotalSeconds: seconds,
+				TotalHours:   float64(seconds) / 3600.0,
+			})
+		}
+	}
+
+	// With few contributors, team totals would tell each one's time.
+	if report.Contributors < r.config.Web.TeamMinMembers {
+		return report, nil
+	}
+	report.TotalSeconds = totalSeconds
+	for name, seconds := range byCategory {
+		if seconds == 0 {
+			continue
+		}
+		report.Categories = append(report.Categories, models.TeamCategory{
+			Category:     name,
+			TotalSeconds: seconds,
+			Percentage:   float64(seconds) / float64(totalSeconds) * 100.0,
+		})
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].TotalSeconds != report.Categories[j].TotalSeconds {
+			return report.Categories[i].TotalSeconds > report.Categories[j].TotalSeconds
+		}
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+	return report, nil
+}
+
+// FormatTeamText renders a team report for the terminal.
+func (r *Reporter) FormatTeamText(report *models.TeamReport) string {
+	output := fmt.Sprintf("Team Report - %s\n", report.Period.Type)
+	output += fmt.Sprintf("Period: %s to %s\n",
+		report.Period.Start.Format("2006-01-02 15:04"), report.Period.End.Format("2006-01-02 15:04"))
+	output += fmt.Sprintf("Sharing users with activity: %d\n\n", report.Contributors)
+
+	if len(report.Members) > 0 {
+		output += "Active Time per Person:\n"
+		for _, member := range report.Members {
+			output += fmt.Sprintf("  %-30s %s\n", member.UserID, utils.FormatHoursMinutes(member.TotalSeconds))
+		}
+		output += "\n"
+	}
+
+	if report.Categories == nil {
+		output += fmt.Sprintf("Category totals are shown once %d users share their time.\n", r.config.Web.TeamMinMembers)
+		return output
+	}
+	output += fmt.Sprintf("Team Total: %s\n", utils.FormatHoursMinutes(report.TotalSeconds))
+	output += "Time per Category:\n"
+	for _, category := range report.Categories {
+		output += fmt.Sprintf("  %-30s %10s  %s\n", category.Category,
+			utils.FormatHoursMinutes(category.TotalSeconds), r.locale.Percent(category.Percentage, 1))
+	}
+	return output
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bashHook reports each command from the DEBUG trap, the first time it
+// fires after a prompt, as bash has no preexec hook; $BASH_COMMAND is then
+// the first simple command of the line. The prompt is reported last in
+// PROMPT_COMMAND so that the commands before it aren't taken for the
+// user's. Reports run in a subshell's background, keeping job control
+// quiet.
+const bashHook = `__actionsum_report() { ( %[1]s shell-event --pid "$$" --dir "$PWD" "$@" >/dev/null 2>&1 & ) }
+__actionsum_preexec() {
+  [ -n "$__actionsum_prompt" ] && [ -z "$COMP_LINE" ] || return 0
+  __actionsum_prompt=
+  __actionsum_report --command "$BASH_COMMAND"
+}
+__actionsum_precmd() { __actionsum_prompt=1; __actionsum_report; }
+trap '__actionsum_preexec' DEBUG
+trap '__actionsum_report --exit' EXIT
+PROMPT_COMMAND="${PROMPT_COMMAND:+$PROMPT_COMMAND
+}__actionsum_precmd"
+`
+
+const zshHook = `__actionsum_report() { %[1]s shell-event --pid "$$" --dir "$PWD" "$@" >/dev/null 2>&1 &! }
+__actionsum_preexec() { __actionsum_report --command "$1" }
+__actionsum_precmd() { __actionsum_report }
+__actionsum_exit() { __actionsum_report --exit }
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __actionsum_preexec
+add-zsh-hook precmd __actionsum_precmd
+add-zsh-hook zshexit __actionsum_exit
+`
+
+// Hook returns the script that makes shell, "bash" or "zsh", report to
+// executable, for the shell to evaluate at startup.
+func Hook(shell, executable string) (string, error) {
+	quoted := "'" + strings.ReplaceAll(executable, "'", `'\''`) + "'"
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashHook, quoted), nil
+	case "zsh":
+		return fmt.Sprintf(zshHook, quoted), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q: use bash or zsh", shell)
+}
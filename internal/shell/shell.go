@@ -0,0 +1,121 @@
+// Package shell receives the command line and working directory of
+// interactive shells. Hooks "actionsum shell-hook" prints for bash and zsh
+// run "actionsum shell-event" before each command and at each prompt, which
+// records the shell's state in a file of its own; the daemon reads the one
+// of the shell in the foreground of a focused terminal.
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/statefile"
+)
+
+// State is what a shell last reported.
+type State struct {
+	PID int `json:"pid"`
+
+	// Command is the command line running, without secrets, or empty at the
+	// prompt.
+	Command string `json:"command,omitempty"`
+
+	// Dir is the working directory, under the home directory as "~/...".
+	Dir string `json:"dir,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func statePath(dir string, pid int) string {
+	return filepath.Join(dir, strconv.Itoa(pid)+".json")
+}
+
+// Record saves state as the current one of its shell.
+func Record(dir string, state *State) error {
+	if dir == "" {
+		return fmt.Errorf("shell state directory is not configured")
+	}
+	return statefile.WriteJSON(statePath(dir, state.PID), state)
+}
+
+// Remove forgets the shell pid, as it exits. It is not an error if nothing
+// was recorded.
+func Remove(dir string, pid int) error {
+	if err := os.Remove(statePath(dir, pid)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove shell state: %w", err)
+	}
+	return nil
+}
+
+// Current returns what the shell pid last reported. A command may run for
+// hours without the shell reporting again, so any state is believed for as
+// long as the shell is in a focused terminal.
+func Current(dir string, pid int) (*State, bool) {
+	if dir == "" || pid <= 0 {
+		return nil, false
+	}
+	var state State
+	if err := statefile.ReadJSON(statePath(dir, pid), &state); err != nil || state.PID != pid {
+		return nil, false
+	}
+	return &state, true
+}
+
+var (
+	// secretAssignment matches variables set for a command whose names
+	// suggest secrets, as in "GITHUB_TOKEN=abc make release".
+	secretAssignment = regexp.MustCompile(`(?i)\b(\w*(?:token|secret|password|passwd|key|auth)\w*)=("[^"]*"|'[^']*'|\S+)`)
+
+	// secretFlag matches the values of options that usually carry
+	// secrets, as in "--password abc" or "--token=abc".
+	secretFlag = regexp.MustCompile(`(?i)(--?(?:token|secret|password|passwd|pass|api-key|auth)[= ])("[^"]*"|'[^']*'|\S+)`)
+
+	// credentials matches the user and password of a URL.
+	credentials = regexp.MustCompile(`(\w+://)[^/\s@]+@`)
+)
+
+// Redact replaces what looks like a secret in a command line with "***":
+// values of variables and options named like tokens or passwords, URL
+// credentials, and whatever the extra pattern matches.
+func Redact(command string, extra *regexp.Regexp) string {
+	command = secretAssignment.ReplaceAllString(command, "$1=***")
+	command = secretFlag.ReplaceAllString(command, "$1***")
+	command = credentials.ReplaceAllString(command, "$1***@")
+	if extra != nil {
+		command = extra.ReplaceAllString(command, "***")
+	}
+	return strings.TrimSpace(command)
+}
+
+// Pattern compiles the extra pattern for Redact, or returns nil for none or
+// an invalid one.
+func Pattern(expr string) *regexp.Regexp {
+	if expr == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// HomeRelative writes dir under home as "~/...", so that the user name in
+// the path isn't stored.
+func HomeRelative(dir, home string) string {
+	if home == "" || home == "/" {
+		return dir
+	}
+	if dir == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(dir, strings.TrimSuffix(home, "/")+"/"); ok {
+		return "~/" + rest
+	}
+	return dir
+}
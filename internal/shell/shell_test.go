@@ -0,0 +1,93 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordCurrentRemove(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := Current(dir, 42); ok {
+		t.Fatal("Current() found a state before any was recorded")
+	}
+
+	state := &State{PID: 42, Command: "make test", Dir: "~/src/actionsum", UpdatedAt: time.Now().UTC()}
+	if err := Record(dir, state); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := Current(dir, 42)
+	if !ok || got.Command != "make test" || got.Dir != "~/src/actionsum" {
+		t.Errorf("Current() = %+v, %v", got, ok)
+	}
+	if _, ok := Current(dir, 43); ok {
+		t.Error("Current() returned another shell's state")
+	}
+
+	if err := Remove(dir, 42); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Current(dir, 42); ok {
+		t.Error("Current() found a removed state")
+	}
+	if err := Remove(dir, 42); err != nil {
+		t.Errorf("Remove() of a missing state: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		command string
+		extra   string
+		want    string
+	}{
+		{"go test ./...", "", "go test ./..."},
+		{"GITHUB_TOKEN=ghp_abc make release", "", "GITHUB_TOKEN=*** make release"},
+		{`API_KEY="a b" ./run`, "", "API_KEY=*** ./run"},
+		{"mysql -u root --password hunter2 db", "", "mysql -u root --password *** db"},
+		{"curl --token=abc https://example.com", "", "curl --token=*** https://example.com"},
+		{"git clone https://me:pw@example.com/repo.git", "", "git clone https://***@example.com/repo.git"},
+		{"ssh build@10.0.0.5", "", "ssh build@10.0.0.5"},
+		{"cd ~/clients/acme", `acme`, "cd ~/clients/***"},
+	}
+	for _, tt := range tests {
+		var extra *regexp.Regexp
+		if tt.extra != "" {
+			extra = regexp.MustCompile(tt.extra)
+		}
+		if got := Redact(tt.command, extra); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestHomeRelative(t *testing.T) {
+	tests := []struct{ dir, home, want string }{
+		{"/home/ada", "/home/ada", "~"},
+		{"/home/ada/src", "/home/ada", "~/src"},
+		{"/home/adam", "/home/ada", "/home/adam"},
+		{"/etc", "/home/ada", "/etc"},
+		{"/etc", "", "/etc"},
+	}
+	for _, tt := range tests {
+		if got := HomeRelative(tt.dir, tt.home); got != tt.want {
+			t.Errorf("HomeRelative(%q, %q) = %q, want %q", tt.dir, tt.home, got, tt.want)
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	for _, name := range []string{"bash", "zsh"} {
+		script, err := Hook(name, "/opt/it's/actionsum")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(script, `'/opt/it'\''s/actionsum' shell-event`) {
+			t.Errorf("%s hook doesn't quote the executable:\n%s", name, script)
+		}
+	}
+	if _, err := Hook("fish", "actionsum"); err == nil {
+		t.Error("Hook() accepted fish")
+	}
+}
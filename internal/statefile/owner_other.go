@@ -0,0 +1,9 @@
+//go:build !unix
+
+package statefile
+
+import "os"
+
+func ownerOf(info os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,88 @@
+// Package statefile keeps the small JSON files that browser, editor and
+// shell integrations write for the daemon to read, in directories other
+// users cannot write to.
+package statefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteJSON saves v as JSON at path. The file is written under a random name
+// next to path and renamed over it, so the daemon never reads a partial
+// file and a link planted at path is replaced rather than followed. The
+// directory is created private to the user, and one that other users can
+// write to is refused.
+func WriteJSON(path string, v any) (err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := checkDir(dir); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadJSON decodes the JSON file at path into v. A file in a directory that
+// other users can write to is not read.
+func ReadJSON(path string, v any) error {
+	if err := checkDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkDir refuses a directory that belongs to another user, other than
+// root, or that others can write to without the sticky bit that stops them
+// replacing the user's files. Without Unix owners only the type is checked.
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	owner, ok := ownerOf(info)
+	if !ok {
+		return nil
+	}
+	if owner != os.Getuid() && owner != 0 {
+		return fmt.Errorf("%s belongs to another user", dir)
+	}
+	if info.Mode().Perm()&0022 != 0 && info.Mode()&os.ModeSticky == 0 {
+		return fmt.Errorf("%s is writable by other users", dir)
+	}
+	return nil
+}
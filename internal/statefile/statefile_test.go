@@ -0,0 +1,72 @@
+//go:build unix
+
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	path := filepath.Join(dir, "tab.json")
+	if err := WriteJSON(path, map[string]string{"url": "https://example.com/"}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("directory mode = %v, %v; want 0700", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	var got map[string]string
+	if err := ReadJSON(path, &got); err != nil || got["url"] != "https://example.com/" {
+		t.Errorf("ReadJSON() = %v, %v", got, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the state file", len(entries))
+	}
+}
+
+func TestWriteJSONReplacesLink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tab.json")
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteJSON(path, map[string]string{}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Errorf("WriteJSON() wrote through the link: target = %q", data)
+	}
+	if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("%s is still a link", path)
+	}
+}
+
+func TestWriteJSONRefusesSharedDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tab.json")
+	if err := WriteJSON(path, map[string]string{}); err == nil {
+		t.Error("WriteJSON() wrote to a directory anyone can write to")
+	}
+	if err := ReadJSON(path, &map[string]string{}); err == nil {
+		t.Error("ReadJSON() read from a directory anyone can write to")
+	}
+}
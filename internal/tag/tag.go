@@ -0,0 +1,72 @@
+package tag
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Validate checks that name is usable as a tag, typically a category or
+// budget name.
+func Validate(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid tag %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Set tags everything tracked until the given time as name, overriding the
+// category the focused apps would otherwise count towards.
+func Set(stateFile, name string, until time.Time) error {
+	if stateFile == "" {
+		return fmt.Errorf("tag state file is not configured")
+	}
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create tag directory: %w", err)
+	}
+	data := fmt.Sprintf("%s %s\n", name, until.Format(time.RFC3339))
+	if err := os.WriteFile(stateFile, []byte(data), 0644); err != nil {
+		return fmt.Errorf("failed to save tag: %w", err)
+	}
+	return nil
+}
+
+// Clear ends the current tag early.
+func Clear(stateFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear tag: %w", err)
+	}
+	return nil
+}
+
+// Active returns the tag in effect at now and when it expires, or an empty
+// name when there is none.
+func Active(stateFile string, now time.Time) (string, time.Time) {
+	if stateFile == "" {
+		return "", time.Time{}
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return "", time.Time{}
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || Validate(fields[0]) != nil {
+		return "", time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil || !now.Before(until) {
+		return "", time.Time{}
+	}
+	return fields[0], until
+}
@@ -0,0 +1,57 @@
+package tracker
+
+import "github.com/actionsum/actionsum/internal/models"
+
+// writeBufferSize is how many events are held in memory while the database
+// refuses writes: a day of polling at the default interval.
+const writeBufferSize = 8640
+
+// eventBuffer is a fixed-size ring of events waiting to be written. Once
+// full, each new event replaces the oldest.
+type eventBuffer struct {
+	events  []*models.FocusEvent
+	head    int
+	count   int
+	dropped int
+}
+
+func newEventBuffer(size int) *eventBuffer {
+	return &eventBuffer{events: make([]*models.FocusEvent, size)}
+}
+
+func (b *eventBuffer) Push(events ...*models.FocusEvent) {
+	for _, event := range events {
+		if b.count == len(b.events) {
+			b.events[b.head] = event
+			b.head = (b.head + 1) % len(b.events)
+			b.dropped++
+			continue
+		}
+		b.events[(b.head+b.count)%len(b.events)] = event
+		b.count++
+	}
+}
+
+// Events returns the buffered events, oldest first.
+func (b *eventBuffer) Events() []*models.FocusEvent {
+	events := make([]*models.FocusEvent, b.count)
+	for i := range events {
+		events[i] = b.events[(b.head+i)%len(b.events)]
+	}
+	return events
+}
+
+func (b *eventBuffer) Len() int {
+	return b.count
+}
+
+// Dropped is how many events were pushed out by newer ones since the last
+// Reset.
+func (b *eventBuffer) Dropped() int {
+	return b.dropped
+}
+
+func (b *eventBuffer) Reset() {
+	clear(b.events)
+	b.head, b.count, b.dropped = 0, 0, 0
+}
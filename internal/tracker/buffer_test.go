@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestEventBuffer(t *testing.T) {
+	b := newEventBuffer(3)
+	for i := 1; i <= 5; i++ {
+		b.Push(&models.FocusEvent{Duration: int64(i)})
+	}
+	if b.Len() != 3 || b.Dropped() != 2 {
+		t.Fatalf("Len() = %d Dropped() = %d, want 3 and 2", b.Len(), b.Dropped())
+	}
+	for i, event := range b.Events() {
+		if want := int64(i + 3); event.Duration != want {
+			t.Errorf("Events()[%d].Duration = %d, want %d", i, event.Duration, want)
+		}
+	}
+
+	b.Reset()
+	b.Push(&models.FocusEvent{Duration: 9})
+	if events := b.Events(); len(events) != 1 || events[0].Duration != 9 || b.Dropped() != 0 {
+		t.Errorf("after Reset() Events() = %v Dropped() = %d, want one event", events, b.Dropped())
+	}
+}
+
+func TestStoreBuffersFailedWrites(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "actionsum.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	sqlDB, _ := db.DB.DB()
+	sqlDB.SetMaxOpenConns(1)
+	repo := database.NewRepository(db)
+
+	cfg := config.Default()
+	cfg.Notify.Desktop = false
+	s := NewService(cfg, repo, nil)
+
+	start := time.Now().Add(-time.Hour)
+	event := func(i int) *models.FocusEvent {
+		return &models.FocusEvent{Timestamp: start.Add(time.Duration(i) * 10 * time.Second), AppName: "code", Duration: 10}
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.FocusEvent{}).Count(&n)
+		return n
+	}
+
+	// Writes fail as they would on a full disk.
+	db.Exec("PRAGMA query_only = ON")
+	for i := 0; i < 3; i++ {
+		if err := s.store(nil, event(i)); err != nil {
+			t.Fatalf("store() while writes fail error = %v, want nil", err)
+		}
+	}
+	if s.buffer.Len() != 3 || count() != 0 {
+		t.Fatalf("buffered %d, stored %d, want 3 buffered", s.buffer.Len(), count())
+	}
+
+	db.Exec("PRAGMA query_only = OFF")
+	if err := s.store(nil, event(3)); err != nil {
+		t.Fatalf("store() error = %v", err)
+	}
+	if s.buffer.Len() != 0 || count() != 4 {
+		t.Errorf("buffered %d, stored %d after writes resumed, want 0 and 4", s.buffer.Len(), count())
+	}
+}
+
+func TestStoreTrimsWithInsert(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "actionsum.db"))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := database.NewRepository(db)
+
+	cfg := config.Default()
+	cfg.Notify.Desktop = false
+	s := NewService(cfg, repo, nil)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	duration := func(id uint) int64 {
+		event, err := repo.GetByID(id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		return event.Duration
+	}
+
+	last := &models.FocusEvent{Timestamp: start, AppName: "code", Duration: 60}
+	if err := s.store(nil, last); err != nil {
+		t.Fatalf("store() error = %v", err)
+	}
+	s.lastEvent = last
+
+	// The insert fails on the duplicate UUID, so the trim is rolled back
+	// with it.
+	duplicate := &models.FocusEvent{Timestamp: start.Add(20 * time.Second), AppName: "firefox", Duration: 10, UUID: last.UUID}
+	if err := s.store(s.endLastEvent(start.Add(20*time.Second)), duplicate); err != nil {
+		t.Fatalf("store() error = %v", err)
+	}
+	if got := duration(last.ID); got != 60 || s.buffer.Len() != 1 {
+		t.Errorf("after a failed insert the previous event lasts %ds with %d buffered, want 60s and 1", got, s.buffer.Len())
+	}
+	s.buffer.Reset()
+
+	// The trim is already in memory; inserting the next event clamps the
+	// stored one to match.
+	next := &models.FocusEvent{Timestamp: start.Add(30 * time.Second), AppName: "firefox", Duration: 10}
+	if err := s.store(s.endLastEvent(start.Add(30*time.Second)), next); err != nil {
+		t.Fatalf("store() error = %v", err)
+	}
+	if got := duration(last.ID); got != 30 || next.ID == 0 {
+		t.Errorf("previous event lasts %ds, next stored as #%d; want 30s and stored", got, next.ID)
+	}
+
+	// A rejected event is not stored, but the previous one still ends.
+	s.lastEvent = next
+	rejected := &models.FocusEvent{Timestamp: start.Add(35 * time.Second), AppName: "code", Duration: -1}
+	if err := s.store(s.endLastEvent(start.Add(35*time.Second)), rejected); !errors.Is(err, database.ErrInvalidEvent) {
+		t.Fatalf("store() error = %v, want ErrInvalidEvent", err)
+	}
+	if got := duration(next.ID); got != 5 {
+		t.Errorf("event before a rejected one lasts %ds, want 5s", got)
+	}
+	if logs, _ := repo.GetErrorLogsSince(start); len(logs) == 0 {
+		t.Error("rejection not in the error log")
+	}
+}
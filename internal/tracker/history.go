@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// historySize is how many focus changes History keeps.
+const historySize = 100
+
+// FocusChange is a stretch of time in one window, as polled.
+type FocusChange struct {
+	AppName     string    `json:"app_name"`
+	WindowTitle string    `json:"window_title"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+
+	// Pending marks a change with events still waiting in the write buffer.
+	Pending bool `json:"pending"`
+}
+
+// History keeps the most recent focus changes in memory so they can be
+// served without querying the database, including events not yet written.
+// It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	changes []FocusChange
+	head    int
+	count   int
+}
+
+func NewHistory(size int) *History {
+	return &History{changes: make([]FocusChange, size)}
+}
+
+// Record adds an event, extending the latest change when the event continues
+// it in the same window.
+func (h *History) Record(event *models.FocusEvent, pending bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	end := event.Timestamp.Add(time.Duration(event.Duration) * time.Second)
+	if h.count > 0 {
+		last := &h.changes[(h.head+h.count-1)%len(h.changes)]
+		if last.AppName == event.AppName && last.WindowTitle == event.WindowTitle && !event.Timestamp.After(last.End.Add(time.Second)) {
+			if end.After(last.End) {
+				last.End = end
+			}
+			last.Pending = last.Pending || pending
+			return
+		}
+	}
+
+	change := FocusChange{
+		AppName:     event.AppName,
+		WindowTitle: event.WindowTitle,
+		Start:       event.Timestamp,
+		End:         end,
+		Pending:     pending,
+	}
+	if h.count == len(h.changes) {
+		h.changes[h.head] = change
+		h.head = (h.head + 1) % len(h.changes)
+		return
+	}
+	h.changes[(h.head+h.count)%len(h.changes)] = change
+	h.count++
+}
+
+// EndLast cuts the latest change short at end, when the event it was
+// extended by turned out shorter.
+func (h *History) EndLast(end time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count == 0 {
+		return
+	}
+	last := &h.changes[(h.head+h.count-1)%len(h.changes)]
+	if last.End.After(end) && !end.Before(last.Start) {
+		last.End = end
+	}
+}
+
+// MarkSaved clears Pending once the write buffer has been flushed.
+func (h *History) MarkSaved() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.changes {
+		h.changes[i].Pending = false
+	}
+}
+
+// Recent returns up to limit changes, newest first. A limit of zero or less
+// returns all of them.
+func (h *History) Recent(limit int) []FocusChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if limit <= 0 || limit > h.count {
+		limit = h.count
+	}
+	changes := make([]FocusChange, limit)
+	for i := range changes {
+		changes[i] = h.changes[(h.head+h.count-1-i)%len(h.changes)]
+	}
+	return changes
+}
@@ -0,0 +1,63 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(2)
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	poll := func(i int, app string) *models.FocusEvent {
+		return &models.FocusEvent{Timestamp: start.Add(time.Duration(i) * 10 * time.Second), AppName: app, WindowTitle: app, Duration: 10}
+	}
+
+	h.Record(poll(0, "code"), false)
+	h.Record(poll(1, "code"), false)
+	h.Record(poll(2, "firefox"), true)
+	h.Record(poll(3, "firefox"), false)
+
+	changes := h.Recent(0)
+	if len(changes) != 2 || changes[0].AppName != "firefox" || changes[1].AppName != "code" {
+		t.Fatalf("Recent() = %+v, want firefox then code", changes)
+	}
+	if got := changes[0].End.Sub(changes[0].Start); got != 20*time.Second {
+		t.Errorf("firefox lasted %v, want 20s", got)
+	}
+	if !changes[0].Pending || changes[1].Pending {
+		t.Errorf("Pending = %v %v, want only firefox pending", changes[0].Pending, changes[1].Pending)
+	}
+
+	// A gap starts a new change even in the same window.
+	h.Record(poll(10, "firefox"), false)
+	if changes := h.Recent(1); len(changes) != 1 || !changes[0].Start.Equal(poll(10, "").Timestamp) {
+		t.Errorf("Recent(1) = %+v, want the change after the gap", changes)
+	}
+	if changes := h.Recent(5); len(changes) != 2 || changes[1].AppName != "firefox" {
+		t.Errorf("Recent(5) = %+v, want the two newest changes", changes)
+	}
+
+	h.MarkSaved()
+	for _, change := range h.Recent(0) {
+		if change.Pending {
+			t.Errorf("%+v still pending after MarkSaved()", change)
+		}
+	}
+}
+
+func TestHistoryEndLast(t *testing.T) {
+	h := NewHistory(4)
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	h.Record(&models.FocusEvent{Timestamp: start, AppName: "code", WindowTitle: "code", Duration: 10}, false)
+
+	h.EndLast(start.Add(4 * time.Second))
+	if changes := h.Recent(0); changes[0].End.Sub(changes[0].Start) != 4*time.Second {
+		t.Errorf("change lasted %v after EndLast(), want 4s", changes[0].End.Sub(changes[0].Start))
+	}
+	h.EndLast(start.Add(8 * time.Second))
+	if changes := h.Recent(0); changes[0].End.Sub(changes[0].Start) != 4*time.Second {
+		t.Errorf("EndLast() past the end extended the change to %v", changes[0].End.Sub(changes[0].Start))
+	}
+}
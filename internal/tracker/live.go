@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+)
+
+// Presence states.
+const (
+	PresenceActive   = "active"
+	PresenceIdle     = "idle"
+	PresenceLocked   = "locked"
+	PresencePaused   = "paused"
+	PresenceOffHours = "off_hours"
+	PresenceUnknown  = "unknown"
+)
+
+// Presence is what the user is doing as of the latest poll.
+type Presence struct {
+	State       string `json:"state"`
+	AppName     string `json:"app_name,omitempty"`
+	WindowTitle string `json:"window_title,omitempty"`
+
+	// Since is when the user entered this state, or focused this window
+	// while active.
+	Since     time.Time `json:"since"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// sameAs reports whether p continues other: the same state and, while
+// active, the same window.
+func (p Presence) sameAs(other Presence) bool {
+	return p.State == other.State && p.AppName == other.AppName && p.WindowTitle == other.WindowTitle
+}
+
+// Live holds the current Presence and passes changes to subscribers, such
+// as the dashboard's stream. It is safe for concurrent use.
+type Live struct {
+	mu          sync.Mutex
+	current     Presence
+	subscribers map[chan Presence]struct{}
+}
+
+func NewLive() *Live {
+	return &Live{
+		current:     Presence{State: PresenceUnknown},
+		subscribers: make(map[chan Presence]struct{}),
+	}
+}
+
+// Set records the presence seen at a poll, keeping the start of the current
+// stretch when nothing changed. Subscribers are only told about changes.
+func (l *Live) Set(p Presence) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if p.sameAs(l.current) && !l.current.Since.IsZero() {
+		l.current.UpdatedAt = p.UpdatedAt
+		return
+	}
+	p.Since = p.UpdatedAt
+	l.current = p
+
+	for ch := range l.subscribers {
+		// A subscriber that hasn't taken the previous change only needs
+		// the latest one.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- p
+	}
+}
+
+// Current returns the latest presence.
+func (l *Live) Current() Presence {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// Subscribe returns a channel receiving each change, and a function to stop
+// receiving them.
+func (l *Live) Subscribe() (<-chan Presence, func()) {
+	ch := make(chan Presence, 1)
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subscribers, ch)
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,44 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLive(t *testing.T) {
+	l := NewLive()
+	changes, unsubscribe := l.Subscribe()
+	defer unsubscribe()
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	l.Set(Presence{State: PresenceActive, AppName: "code", WindowTitle: "main.go", UpdatedAt: start})
+	l.Set(Presence{State: PresenceActive, AppName: "code", WindowTitle: "main.go", UpdatedAt: start.Add(10 * time.Second)})
+
+	got := l.Current()
+	if !got.Since.Equal(start) || !got.UpdatedAt.Equal(start.Add(10*time.Second)) {
+		t.Errorf("Current() = %+v, want since %v, updated 10s later", got, start)
+	}
+	if p := <-changes; p.AppName != "code" {
+		t.Errorf("first change = %+v, want code", p)
+	}
+	select {
+	case p := <-changes:
+		t.Errorf("unchanged poll was sent: %+v", p)
+	default:
+	}
+
+	// An unread change is replaced by the next rather than blocking.
+	l.Set(Presence{State: PresenceIdle, UpdatedAt: start.Add(20 * time.Second)})
+	l.Set(Presence{State: PresenceLocked, UpdatedAt: start.Add(30 * time.Second)})
+	if p := <-changes; p.State != PresenceLocked || !p.Since.Equal(start.Add(30*time.Second)) {
+		t.Errorf("change = %+v, want locked since 30s", p)
+	}
+
+	unsubscribe()
+	l.Set(Presence{State: PresenceActive, AppName: "firefox", UpdatedAt: start.Add(40 * time.Second)})
+	select {
+	case p := <-changes:
+		t.Errorf("change sent after unsubscribing: %+v", p)
+	default:
+	}
+}
@@ -0,0 +1,68 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+)
+
+// PollStats sums up the polls made since the tracker started.
+type PollStats struct {
+	Polls   int64         `json:"polls"`
+	Skipped int64         `json:"skipped"`
+	Total   time.Duration `json:"total"`
+	Max     time.Duration `json:"max"`
+}
+
+// Average returns the mean time a poll took.
+func (s PollStats) Average() time.Duration {
+	if s.Polls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Polls)
+}
+
+// Polls times the tracker's polls and holds back the poll after one that
+// took longer than the budget, so a stalled detector doesn't keep the
+// daemon busy. It is safe for concurrent use.
+type Polls struct {
+	budget time.Duration
+
+	mu      sync.Mutex
+	stats   PollStats
+	overran bool
+}
+
+// NewPolls returns Polls that never skips when budget is zero.
+func NewPolls(budget time.Duration) *Polls {
+	return &Polls{budget: budget}
+}
+
+// Record counts a poll that took d.
+func (p *Polls) Record(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Polls++
+	p.stats.Total += d
+	p.stats.Max = max(p.stats.Max, d)
+	p.overran = p.budget > 0 && d > p.budget
+}
+
+// SkipNext reports whether the poll due now should be skipped because the
+// one before overran the budget, and counts it if so.
+func (p *Polls) SkipNext() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.overran {
+		return false
+	}
+	p.overran = false
+	p.stats.Skipped++
+	return true
+}
+
+// Stats returns the polls counted so far.
+func (p *Polls) Stats() PollStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
@@ -0,0 +1,33 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolls(t *testing.T) {
+	p := NewPolls(time.Second)
+	p.Record(200 * time.Millisecond)
+	if p.SkipNext() {
+		t.Error("SkipNext() after a poll within budget = true")
+	}
+
+	p.Record(3 * time.Second)
+	if !p.SkipNext() {
+		t.Error("SkipNext() after a poll over budget = false")
+	}
+	if p.SkipNext() {
+		t.Error("SkipNext() skipped a second poll for the same overrun")
+	}
+
+	stats := p.Stats()
+	if stats.Polls != 2 || stats.Skipped != 1 || stats.Max != 3*time.Second || stats.Average() != 1600*time.Millisecond {
+		t.Errorf("Stats() = %+v, average %v", stats, stats.Average())
+	}
+
+	unlimited := NewPolls(0)
+	unlimited.Record(time.Hour)
+	if unlimited.SkipNext() {
+		t.Error("SkipNext() without a budget = true")
+	}
+}
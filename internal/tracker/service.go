@@ -2,32 +2,104 @@ package tracker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/actionsum/actionsum/internal/away"
+	"github.com/actionsum/actionsum/internal/browser"
+	"github.com/actionsum/actionsum/internal/classify"
 	"github.com/actionsum/actionsum/internal/config"
 	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/editor"
+	"github.com/actionsum/actionsum/internal/location"
 	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/internal/pause"
+	"github.com/actionsum/actionsum/internal/profile"
+	"github.com/actionsum/actionsum/internal/remoteterm"
+	"github.com/actionsum/actionsum/internal/shell"
+	"github.com/actionsum/actionsum/internal/tag"
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/integrations/audio"
+	"github.com/actionsum/actionsum/pkg/integrations/camera"
+	"github.com/actionsum/actionsum/pkg/integrations/remote"
+	"github.com/actionsum/actionsum/pkg/integrations/terminal"
+	"github.com/actionsum/actionsum/pkg/utils"
 	"github.com/actionsum/actionsum/pkg/window"
 )
 
+// awayAnswerTimeout is how long the away notification waits for an answer.
+const awayAnswerTimeout = 30 * time.Minute
+
+// focusSettle is how long after a focus change the window is looked up, so
+// a quick run of switches, as with Alt+Tab, is only looked up once.
+const focusSettle = 300 * time.Millisecond
+
 type Service struct {
 	config   *config.Config
 	repo     *database.Repository
 	detector window.Detector
 	stopChan chan struct{}
 	running  bool
+
+	lockKnown bool
+	locked    bool
+	lockedAt  time.Time
+
+	lastPoll    time.Time
+	pendingJump time.Duration
+
+	offHours bool
+	paused   bool
+
+	// lastEvent is the event stored by the previous active poll, and
+	// idleSince when it ended if the user has since gone idle; together they
+	// let a short idle stretch be credited to that window.
+	lastEvent *models.FocusEvent
+	idleSince time.Time
+
+	// awaySince is when the user went idle or locked the screen, for asking
+	// what they were doing when they return.
+	awaySince time.Time
+
+	classifier *classify.Cached
+	location   *location.Resolver
+
+	// buffer holds events while the database refuses writes, such as when
+	// the disk is full, until they can be flushed.
+	buffer   *eventBuffer
+	notifier notify.Notifier
+
+	history *History
+	live    *Live
+	polls   *Polls
 }
 
 func NewService(cfg *config.Config, repo *database.Repository, detector window.Detector) *Service {
-	return &Service{
+	s := &Service{
 		config:   cfg,
 		repo:     repo,
 		detector: detector,
 		stopChan: make(chan struct{}),
 		running:  false,
+		buffer:   newEventBuffer(writeBufferSize),
+		notifier: notify.FromConfig(cfg),
+		history:  NewHistory(historySize),
+		live:     NewLive(),
+		polls:    NewPolls(cfg.Daemon.PollBudget),
+		location: location.New(cfg),
+	}
+	if cfg.Tracker.Classifier != "" {
+		classifier, err := classify.New(cfg.Tracker.Classifier)
+		if err != nil {
+			log.Printf("Classifier disabled: %v", err)
+		} else {
+			s.classifier = classifier
+		}
 	}
+	return s
 }
 
 func (s *Service) Start(ctx context.Context) error {
@@ -37,17 +109,38 @@ func (s *Service) Start(ctx context.Context) error {
 
 	s.running = true
 	log.Printf("Starting tracker with %v poll interval", s.config.Tracker.PollInterval)
+	if len(s.config.Tracker.AppPollIntervals) > 0 {
+		log.Printf("Per-app poll intervals: %s", s.config.AppPollIntervalsString())
+	}
+
+	s.recordDaemonState(models.StateDaemonStart)
+	defer s.recordDaemonState(models.StateDaemonStop)
+	defer s.flushOnStop()
+	if s.classifier != nil {
+		defer s.classifier.Close()
+	}
 
-	ticker := time.NewTicker(s.config.Tracker.PollInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(s.config.Tracker.PollInterval)
+	defer timer.Stop()
 
-	appName, isIdle, isLocked, err := s.trackOnce()
+	var lockChanges <-chan bool
+	if notifier, ok := s.detector.(window.LockNotifier); ok {
+		lockChanges = notifier.LockChanges()
+	}
+	var focusChanges <-chan struct{}
+	if notifier, ok := s.detector.(window.FocusNotifier); ok {
+		focusChanges = notifier.FocusChanges()
+	}
+
+	appName, isIdle, isLocked, err := s.timedTrack()
 	if err != nil {
 		s.storeError(err)
 	}
+	s.publish(appName, isIdle, isLocked)
 	if appName != "" {
 		log.Printf("Initial track: %s (idle: %v, locked: %v)", appName, isIdle, isLocked)
 	}
+	timer.Reset(s.config.PollIntervalFor(appName))
 
 	for {
 		select {
@@ -61,14 +154,34 @@ func (s *Service) Start(ctx context.Context) error {
 			s.running = false
 			return nil
 
-		case <-ticker.C:
-			appName, isIdle, isLocked, err := s.trackOnce()
+		case locked := <-lockChanges:
+			s.recordLockState(locked, s.lockSource())
+			if locked {
+				s.publish("", false, true)
+			}
+
+		case _, ok := <-focusChanges:
+			if !ok {
+				focusChanges = nil
+				continue
+			}
+			timer.Reset(focusSettle)
+
+		case <-timer.C:
+			if s.polls.SkipNext() {
+				log.Printf("Skipping poll: the previous one took longer than the %v budget", s.config.Daemon.PollBudget)
+				timer.Reset(s.config.Tracker.PollInterval)
+				continue
+			}
+			appName, isIdle, isLocked, err := s.timedTrack()
 			if err != nil {
 				s.storeError(err)
 			}
+			s.publish(appName, isIdle, isLocked)
 			if appName != "" {
 				log.Printf("Tracked: %s (idle: %v, locked: %v)", appName, isIdle, isLocked)
 			}
+			timer.Reset(s.config.PollIntervalFor(appName))
 		}
 	}
 }
@@ -79,22 +192,112 @@ func (s *Service) Stop() {
 	}
 }
 
+// History returns the tracker's recent focus changes.
+func (s *Service) History() *History {
+	return s.history
+}
+
+// Live returns what the user is doing as of the latest poll.
+func (s *Service) Live() *Live {
+	return s.live
+}
+
+// Polls returns how many polls the tracker made and how long they took.
+func (s *Service) Polls() *Polls {
+	return s.polls
+}
+
+// publish updates the live presence from a poll's outcome.
+func (s *Service) publish(appName string, idle, locked bool) {
+	p := Presence{State: PresenceUnknown, UpdatedAt: time.Now()}
+	switch {
+	case appName != "" && s.lastEvent != nil:
+		p.State = PresenceActive
+		p.AppName = appName
+		p.WindowTitle = s.lastEvent.WindowTitle
+	case s.offHours:
+		p.State = PresenceOffHours
+	case s.paused:
+		p.State = PresencePaused
+	case locked:
+		p.State = PresenceLocked
+	case idle:
+		p.State = PresenceIdle
+	}
+	s.live.Set(p)
+}
+
 func (s *Service) IsRunning() bool {
 	return s.running
 }
 
+// timedTrack polls once, counting the time it takes against the budget.
+func (s *Service) timedTrack() (string, bool, bool, error) {
+	start := time.Now()
+	defer func() { s.polls.Record(time.Since(start)) }()
+	return s.trackOnce()
+}
+
 func (s *Service) trackOnce() (string, bool, bool, error) {
+	s.checkClock(time.Now())
 
 	idleInfo, err := s.detector.GetIdleInfo()
 	if err != nil {
 		return "", false, false, fmt.Errorf("failed to get idle info: %w", err)
 	}
 
-	if idleInfo.IsIdle || idleInfo.IsLocked {
-		log.Printf("Skipping tracking: idle=%v, locked=%v", idleInfo.IsIdle, idleInfo.IsLocked)
+	s.recordLockState(idleInfo.IsLocked, "poll")
+
+	if s.config.WorkHours.AutoPause {
+		offHours := !s.config.InWorkHours(time.Now())
+		if offHours != s.offHours {
+			s.offHours = offHours
+			if offHours {
+				log.Println("Outside work hours, pausing tracking")
+			} else {
+				log.Println("Work hours started, resuming tracking")
+			}
+		}
+		if offHours {
+			s.forgetIdle()
+			return "", idleInfo.IsIdle, idleInfo.IsLocked, nil
+		}
+	}
+
+	paused, _ := pause.Active(s.config.Tracker.PauseFile, time.Now())
+	if paused != s.paused {
+		s.paused = paused
+		if paused {
+			log.Println("Tracking paused")
+		} else {
+			log.Println("Tracking resumed")
+		}
+	}
+	if paused {
+		s.forgetIdle()
 		return "", idleInfo.IsIdle, idleInfo.IsLocked, nil
 	}
 
+	idle := s.isIdle(idleInfo)
+	if idle || idleInfo.IsLocked {
+		log.Printf("Skipping tracking: idle=%v, locked=%v", idle, idleInfo.IsLocked)
+		if s.awaySince.IsZero() && s.lastEvent != nil {
+			s.awaySince = s.lastEventEnd()
+		}
+		switch {
+		case idleInfo.IsLocked:
+			// A locked screen means the user left; it is never credited.
+			s.lastEvent, s.idleSince = nil, time.Time{}
+		case s.lastEvent != nil && s.idleSince.IsZero():
+			s.idleSince = s.lastEventEnd()
+		}
+		return "", idle, idleInfo.IsLocked, nil
+	}
+
+	back := time.Now()
+	credited := s.creditIdle(back)
+	s.checkAway(back, credited)
+
 	windowInfo, err := s.detector.GetFocusedWindow()
 	if err != nil {
 		return "", idleInfo.IsIdle, idleInfo.IsLocked, fmt.Errorf("failed to get focused window: %w", err)
@@ -104,25 +307,498 @@ func (s *Service) trackOnce() (string, bool, bool, error) {
 		return "", idleInfo.IsIdle, idleInfo.IsLocked, fmt.Errorf("no valid window information available")
 	}
 
+	if windowInfo.DetectionMethod == database.DetectionProcess && windowInfo.Confidence < s.config.Tracker.MinConfidence {
+		log.Printf("Skipping tracking: process-based guess %s has confidence %.2f, below %.2f",
+			windowInfo.AppName, windowInfo.Confidence, s.config.Tracker.MinConfidence)
+		return "", idleInfo.IsIdle, idleInfo.IsLocked, nil
+	}
+
+	activeProfile, _ := profile.Resolve(s.config, time.Now())
+	activeTag, _ := tag.Active(s.config.Tags.StateFile, time.Now())
+	now := time.Now()
+	zone, offset := localZone(now)
+
 	event := &models.FocusEvent{
-		Timestamp:     time.Now(),
+		Timestamp:     now,
 		AppName:       windowInfo.AppName,
 		WindowTitle:   windowInfo.WindowTitle,
-		Duration:      s.config.GetPollIntervalSeconds(),
+		Duration:      int64(s.config.PollIntervalFor(windowInfo.AppName).Seconds()),
 		IsIdle:        idleInfo.IsIdle,
 		IsLocked:      idleInfo.IsLocked,
 		DisplayServer: windowInfo.DisplayServer,
+		IsFullscreen:  windowInfo.IsFullscreen,
+		IsMaximized:   windowInfo.IsMaximized,
+		WindowX:       windowInfo.Geometry.X,
+		WindowY:       windowInfo.Geometry.Y,
+		WindowWidth:   windowInfo.Geometry.Width,
+		WindowHeight:  windowInfo.Geometry.Height,
+		ClockJump:     int64(s.pendingJump.Seconds()),
+		Profile:       activeProfile,
+		Tag:           activeTag,
+		Detection:     windowInfo.DetectionMethod,
+		Client:        windowInfo.Client,
+		Output:        windowInfo.Output,
+		Label:         s.label(windowInfo),
+		Location:      s.location.Location(now),
+		TimeZone:      zone,
+		UTCOffset:     &offset,
 		CreatedAt:     time.Now(),
 	}
+	if tab, ok := browser.Current(s.config.Tracker.BrowserTabFile, now); ok && tab.Matches(windowInfo.AppName, windowInfo.WindowTitle) {
+		event.URL, event.Domain = tab.URL, tab.Domain
+	}
+	s.addTerminal(event, windowInfo, now)
+	if c, ok := editor.Current(s.config.Tracker.EditorFile, now); ok && c.Matches(windowInfo.AppName, windowInfo.WindowTitle) {
+		event.Project, event.FileHash, event.Language = c.Project, c.FileHash, c.Language
+	}
+	event.RemoteClient = remote.ClientProtocol(windowInfo.AppName, windowInfo.ProcessName)
+	event.RemoteSession = remote.SessionProtocol()
+	event.VPN = remote.VPNUp(s.config.Tracker.VPNInterfaces)
+	event.InMeeting = s.inMeeting()
+	if player := s.mediaPlayer(windowInfo.AppName); player != nil {
+		event.MediaPlayer, event.MediaTrack = player.Name, player.Track()
+	}
 
-	if err := s.repo.Create(event); err != nil {
+	if err := s.store(s.endLastEvent(now), event); err != nil {
+		if errors.Is(err, database.ErrInvalidEvent) {
+			// Already recorded in the error log by the repository.
+			log.Printf("Event rejected: %v", err)
+			return "", idleInfo.IsIdle, idleInfo.IsLocked, nil
+		}
 		return "", idleInfo.IsIdle, idleInfo.IsLocked, fmt.Errorf("failed to save event: %w", err)
 	}
+	s.pendingJump = 0
+	s.lastEvent = event
 
 	return event.AppName, idleInfo.IsIdle, idleInfo.IsLocked, nil
 }
 
+// isIdle applies the configured idle threshold to the time since the last
+// input, falling back to the detector's own judgement when it cannot tell.
+// With AudioActive, playing sound keeps the user active.
+func (s *Service) isIdle(idleInfo *window.IdleInfo) bool {
+	if s.config.Tracker.AudioActive && idleInfo.HasAudioPlaying {
+		return false
+	}
+	if idleInfo.IdleTime > 0 && s.config.Tracker.IdleThreshold > 0 {
+		return idleInfo.IdleTime >= int64(s.config.Tracker.IdleThreshold.Seconds())
+	}
+	return idleInfo.IsIdle
+}
+
+// creditIdle records an idle stretch that ended at now as time in the window
+// focused before it, provided it lasted no longer than the idle grace period
+// and was not a suspend. The stretch is stored as events no longer than a
+// poll interval, as if tracking had continued.
+func (s *Service) creditIdle(now time.Time) bool {
+	since, last := s.idleSince, s.lastEvent
+	s.idleSince = time.Time{}
+	grace := s.config.Tracker.IdleGrace
+	if since.IsZero() || last == nil || grace <= 0 || s.pendingJump != 0 {
+		return false
+	}
+	away := now.Sub(since)
+	if away < time.Second || away > grace {
+		return false
+	}
+
+	interval := s.config.PollIntervalFor(last.AppName)
+	var events []*models.FocusEvent
+	for start := since; now.Sub(start) >= time.Second; start = start.Add(interval) {
+		length := now.Sub(start)
+		if length > interval {
+			length = interval
+		}
+		event := *last
+		event.ID = 0
+		event.UUID = ""
+		event.Timestamp = start
+		event.Duration = int64(length.Seconds())
+		event.ClockJump = 0
+		event.CreatedAt = now
+		event.UpdatedAt = time.Time{}
+		events = append(events, &event)
+	}
+	if err := s.store(nil, events...); err != nil {
+		log.Printf("Failed to credit idle time: %v", err)
+		return false
+	}
+	log.Printf("Counted %v idle in %s as active (within %v grace)", away.Round(time.Second), last.AppName, grace)
+	return true
+}
+
+// forgetIdle stops an idle stretch from being credited or asked about, when
+// tracking stops for reasons other than the user being away.
+func (s *Service) forgetIdle() {
+	s.lastEvent = nil
+	s.idleSince = time.Time{}
+	s.awaySince = time.Time{}
+}
+
+// endLastEvent trims the previous event to end at now when this poll came
+// before it was over, as after a focus change, so the two don't overlap. It
+// returns the event if its stored duration needs updating, for store to
+// write together with the next event.
+func (s *Service) endLastEvent(now time.Time) *models.FocusEvent {
+	last := s.lastEvent
+	if last == nil || !s.lastEventEnd().After(now) || now.Before(last.Timestamp) {
+		return nil
+	}
+	last.Duration = int64(now.Sub(last.Timestamp).Seconds())
+	s.history.EndLast(now)
+	// Events still in the write buffer have no ID yet and are saved trimmed.
+	if last.ID == 0 {
+		return nil
+	}
+	return last
+}
+
+func (s *Service) lastEventEnd() time.Time {
+	return s.lastEvent.Timestamp.Add(time.Duration(s.lastEvent.Duration) * time.Second)
+}
+
+// checkAway keeps an away stretch that ended at now, unless it was credited
+// as idle grace, for a reason to be given, and asks for one in the
+// background when it lasted at least the away prompt threshold.
+func (s *Service) checkAway(now time.Time, credited bool) {
+	since := s.awaySince
+	s.awaySince = time.Time{}
+	threshold := s.config.Tracker.AwayPrompt
+	if since.IsZero() || credited || threshold <= 0 || now.Sub(since) < threshold {
+		return
+	}
+
+	interval := away.Interval{Start: since, End: now}
+	if err := away.Record(s.config.Tracker.AwayFile, interval); err != nil {
+		log.Printf("Failed to record away interval: %v", err)
+		return
+	}
+	log.Printf("Back after %v away", interval.Duration().Round(time.Minute))
+	go s.askAwayReason(interval)
+}
+
+// askAwayReason asks what the user did while away and fills the interval
+// with the answer. Unanswered, the interval stays pending for "actionsum
+// away".
+func (s *Service) askAwayReason(interval away.Interval) {
+	ctx, cancel := context.WithTimeout(context.Background(), awayAnswerTimeout)
+	defer cancel()
+
+	msg := notify.Message{
+		Title: "Welcome back",
+		Body: fmt.Sprintf("You were away for %s from %s. What were you doing?",
+			utils.FormatHoursMinutes(int64(interval.Duration().Seconds())), interval.Start.Local().Format("15:04")),
+		Urgency: "normal",
+	}
+	reason, err := notify.Ask(ctx, msg, s.config.Tracker.AwayReasons)
+	if err != nil {
+		log.Printf("Failed to ask for away reason: %v", err)
+		return
+	}
+	if reason == "" {
+		return
+	}
+
+	// A reason may have been given with "actionsum away" meanwhile, or a
+	// newer interval recorded.
+	if pending, ok := away.Pending(s.config.Tracker.AwayFile); !ok || !pending.Start.Equal(interval.Start) {
+		return
+	}
+	activeProfile, _ := profile.Resolve(s.config, interval.Start)
+	if _, err := away.Fill(s.repo, interval, reason, activeProfile, s.config.Tracker.MaxPollInterval); err != nil {
+		log.Printf("Failed to fill away interval: %v", err)
+		return
+	}
+	if err := away.Clear(s.config.Tracker.AwayFile); err != nil {
+		log.Printf("Failed to clear away interval: %v", err)
+	}
+	log.Printf("Recorded %v away as %s", interval.Duration().Round(time.Minute), reason)
+}
+
+// label asks the classifier for the window's label. A failing classifier
+// leaves events unlabelled rather than holding up tracking.
+func (s *Service) label(windowInfo *window.WindowInfo) string {
+	if s.classifier == nil {
+		return ""
+	}
+	label, err := s.classifier.Classify(classify.Input{AppName: windowInfo.AppName, WindowTitle: windowInfo.WindowTitle})
+	if err != nil {
+		log.Printf("Failed to classify window: %v", err)
+		return ""
+	}
+	return label
+}
+
+// addTerminal records on event what a focused terminal runs, as enabled:
+// the command in its foreground, and while it is connected to another
+// machine whose remote agent reports, the command there and that machine;
+// and the command line and directory its shell's hooks last reported.
+func (s *Service) addTerminal(event *models.FocusEvent, windowInfo *window.WindowInfo, now time.Time) {
+	tracker := &s.config.Tracker
+	if (!tracker.TerminalCommands && !tracker.ShellCommands) || !terminal.IsTerminal(windowInfo.AppName, windowInfo.ProcessName) {
+		return
+	}
+	// Terminals whose processes can't be seen, as from inside a Flatpak,
+	// have none.
+	var command string
+	var session int
+	if windowInfo.PID > 0 {
+		command, session, _ = terminal.ForegroundCommand(windowInfo.PID, windowInfo.WindowTitle)
+	}
+	if tracker.ShellCommands {
+		if state, ok := shell.Current(tracker.ShellStateDir, session); ok {
+			redact := shell.Pattern(tracker.ShellRedact)
+			event.ShellCommand = shell.Redact(state.Command, redact)
+			event.ShellDir = shell.Redact(state.Dir, redact)
+		}
+	}
+	if !tracker.TerminalCommands {
+		return
+	}
+	if activity, ok := remoteterm.Current(tracker.RemoteTerminalFile, now); ok && activity.Matches(command, windowInfo.WindowTitle) {
+		event.SubApp, event.RemoteHost = activity.Command, activity.Host
+		return
+	}
+	event.SubApp = command
+}
+
+// mediaPlayer returns the media player playing, when enabled, preferring
+// the focused app's own so that it isn't mistaken for one in the
+// background.
+func (s *Service) mediaPlayer(appName string) *dbus.MediaPlayer {
+	if !s.config.Tracker.MediaPlayers {
+		return nil
+	}
+	// Without a session bus, as on macOS and Windows, nothing is playing.
+	players, err := dbus.SharedSession().PlayingMedia()
+	if err != nil || len(players) == 0 {
+		return nil
+	}
+	for i := range players {
+		if models.PlayerIsApp(players[i].Name, appName) {
+			return &players[i]
+		}
+	}
+	return &players[0]
+}
+
+// inMeeting reports, when enabled, whether a microphone or camera is in
+// use. What can't be checked counts as not in use.
+func (s *Service) inMeeting() bool {
+	if !s.config.Tracker.Meetings {
+		return false
+	}
+	if recording, _ := audio.Recording(); recording {
+		return true
+	}
+	inUse, _ := camera.InUse()
+	return inUse
+}
+
+// checkClock compares the wall-clock and monotonic time elapsed since the
+// previous poll. Suspend/resume and NTP steps move the wall clock without
+// advancing the monotonic one, so a large difference marks a discontinuity.
+// The jump is recorded as a state event and carried onto the next stored
+// sample so that no interval is treated as spanning it. DST changes do not
+// move Unix time and are not reported.
+func (s *Service) checkClock(now time.Time) {
+	last := s.lastPoll
+	s.lastPoll = now
+	if last.IsZero() {
+		return
+	}
+
+	jump := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+	if jump.Abs() < s.config.Tracker.ClockJumpThreshold {
+		return
+	}
+
+	s.pendingJump += jump
+	log.Printf("Clock jump detected: wall clock moved %v relative to monotonic time", jump.Round(time.Second))
+
+	event := &models.StateEvent{
+		Timestamp: now,
+		Type:      models.StateClockJump,
+		Source:    "poll",
+		Detail:    jump.Round(time.Second).String(),
+	}
+	if err := s.repo.CreateStateEvent(event); err != nil {
+		s.storeError(fmt.Errorf("failed to save clock jump event: %w", err))
+	}
+}
+
+// recordDaemonState marks when tracking starts and stops so gap analysis can
+// tell a stopped daemon apart from idle time.
+func (s *Service) recordDaemonState(eventType string) {
+	event := &models.StateEvent{
+		Timestamp: time.Now(),
+		Type:      eventType,
+		Source:    "daemon",
+	}
+	if err := s.repo.CreateStateEvent(event); err != nil {
+		log.Printf("Failed to save %s event: %v", eventType, err)
+	}
+}
+
+// recordLockState stores a lock or unlock event when the screen lock state
+// differs from the last one seen. The baseline comes from the last stored
+// event so a restart while locked still closes the away interval.
+// lockSource names where pushed lock changes come from: the operating
+// system's own notifications on Windows and macOS, DBus elsewhere.
+func (s *Service) lockSource() string {
+	switch server := s.detector.GetDisplayServer(); server {
+	case "windows", "macos":
+		return server
+	}
+	return "dbus"
+}
+
+func (s *Service) recordLockState(locked bool, source string) {
+	if !s.lockKnown {
+		s.lockKnown = true
+		if latest, err := s.repo.GetLatestStateEvent(); err == nil && latest != nil {
+			s.locked = latest.Type == models.StateLock
+			if s.locked {
+				s.lockedAt = latest.Timestamp
+			}
+		}
+	}
+	if s.locked == locked {
+		return
+	}
+	s.locked = locked
+	now := time.Now()
+
+	eventType := models.StateUnlock
+	if locked {
+		eventType = models.StateLock
+	}
+
+	event := &models.StateEvent{
+		Timestamp: now,
+		Type:      eventType,
+		Source:    source,
+	}
+	if err := s.repo.CreateStateEvent(event); err != nil {
+		s.storeError(fmt.Errorf("failed to save %s event: %w", eventType, err))
+	} else {
+		log.Printf("Screen %sed (source: %s)", eventType, source)
+	}
+
+	if locked {
+		s.lockedAt = now
+		return
+	}
+	lockedAt := s.lockedAt
+	s.lockedAt = time.Time{}
+	s.checkUnlockSummary(lockedAt, now)
+}
+
+// store saves events, in one transaction with the new duration of trimmed
+// when it is set, or buffers them in memory while writes fail so a full disk
+// or a permissions problem doesn't lose tracking, writing the buffer out
+// first once the database accepts writes again. A buffered write drops the
+// trim; the stored event is clamped when the buffer is written instead. It
+// only returns the repository's rejection of an invalid event.
+func (s *Service) store(trimmed *models.FocusEvent, events ...*models.FocusEvent) error {
+	if s.buffer.Len() > 0 && !s.flush() {
+		s.buffer.Push(events...)
+		s.record(events, true)
+		return nil
+	}
+
+	var rejected error
+	err := s.repo.WithTx(func(tx *database.Repository) error {
+		if trimmed != nil {
+			if err := tx.UpdateDuration(trimmed.ID, trimmed.Duration); err != nil {
+				return err
+			}
+		}
+		var err error
+		if len(events) == 1 {
+			err = tx.Create(events[0])
+		} else {
+			err = tx.CreateBatch(events)
+		}
+		// A rejected event is not stored, but the trim and the logged
+		// rejection are kept.
+		if errors.Is(err, database.ErrInvalidEvent) {
+			rejected = err
+			return nil
+		}
+		return err
+	})
+	if rejected != nil {
+		return rejected
+	}
+	if err == nil {
+		s.record(events, false)
+		return nil
+	}
+
+	s.buffer.Push(events...)
+	s.record(events, true)
+	log.Printf("Database writes failing, buffering up to %d events in memory: %v", writeBufferSize, err)
+	s.notify(notify.Message{
+		Title:   "actionsum cannot save activity",
+		Body:    fmt.Sprintf("Writing to the database failed (%v). Activity is kept in memory and saved once writes succeed again.", err),
+		Urgency: "critical",
+	})
+	return nil
+}
+
+func (s *Service) record(events []*models.FocusEvent, pending bool) {
+	for _, event := range events {
+		s.history.Record(event, pending)
+	}
+}
+
+// flush writes the buffered events, reporting whether the buffer is empty.
+func (s *Service) flush() bool {
+	events := s.buffer.Events()
+	if err := s.repo.CreateBatch(events); err != nil {
+		return false
+	}
+
+	summary := fmt.Sprintf("%d buffered events saved", len(events))
+	if dropped := s.buffer.Dropped(); dropped > 0 {
+		summary += fmt.Sprintf(", %d older ones lost when the buffer filled", dropped)
+	}
+	s.buffer.Reset()
+	s.history.MarkSaved()
+	log.Printf("Database writes succeeding again: %s", summary)
+	s.notify(notify.Message{
+		Title:   "actionsum is saving activity again",
+		Body:    fmt.Sprintf("Database writes are working again: %s.", summary),
+		Urgency: "normal",
+	})
+	return true
+}
+
+// flushOnStop makes a last attempt to save buffered events when tracking
+// stops.
+func (s *Service) flushOnStop() {
+	if s.buffer.Len() > 0 && !s.flush() {
+		log.Printf("Database writes still failing, %d buffered events lost", s.buffer.Len())
+	}
+}
+
+func (s *Service) notify(msg notify.Message) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(msg); err != nil {
+		log.Printf("Failed to send notification: %v", err)
+	}
+}
+
 func (s *Service) storeError(err error) {
+	// The error log is in the database too.
+	if s.buffer.Len() > 0 {
+		log.Printf("Error: %v", err)
+		return
+	}
+
 	errorLog := &models.ErrorLog{
 		Timestamp: time.Now(),
 		ErrorMsg:  err.Error(),
@@ -0,0 +1,104 @@
+package tracker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestCreditIdle(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	idleSince := start.Add(time.Minute)
+
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		idleSince   time.Time
+		away        time.Duration
+		pendingJump time.Duration
+		want        []string
+	}{
+		{
+			name:      "within grace",
+			grace:     2 * time.Minute,
+			idleSince: idleSince,
+			away:      25 * time.Second,
+			want:      []string{"code 1m0s 10s", "code 1m10s 10s", "code 1m20s 5s"},
+		},
+		{
+			name:      "exactly the grace",
+			grace:     20 * time.Second,
+			idleSince: idleSince,
+			away:      20 * time.Second,
+			want:      []string{"code 1m0s 10s", "code 1m10s 10s"},
+		},
+		{
+			name:      "beyond grace",
+			grace:     2 * time.Minute,
+			idleSince: idleSince,
+			away:      3 * time.Minute,
+		},
+		{
+			name:      "grace disabled",
+			idleSince: idleSince,
+			away:      25 * time.Second,
+		},
+		{
+			name:  "not idle",
+			grace: 2 * time.Minute,
+			away:  25 * time.Second,
+		},
+		{
+			name:        "suspended",
+			grace:       2 * time.Minute,
+			idleSince:   idleSince,
+			away:        25 * time.Second,
+			pendingJump: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := database.NewRepository(database.OpenTest(t))
+			cfg := config.Default()
+			cfg.Notify.Desktop = false
+			cfg.Tracker.IdleGrace = tt.grace
+			s := NewService(cfg, repo, nil)
+
+			last := &models.FocusEvent{Timestamp: start, AppName: "code", WindowTitle: "main.go", Duration: 60}
+			if err := s.store(nil, last); err != nil {
+				t.Fatalf("store() error = %v", err)
+			}
+			s.lastEvent = last
+			s.idleSince = tt.idleSince
+			s.pendingJump = tt.pendingJump
+
+			credited := s.creditIdle(idleSince.Add(tt.away))
+			if credited != (tt.want != nil) {
+				t.Errorf("creditIdle() = %v, want %v", credited, tt.want != nil)
+			}
+			if !s.idleSince.IsZero() {
+				t.Errorf("idleSince = %v after creditIdle(), want it cleared", s.idleSince)
+			}
+
+			events, err := repo.GetEvents(database.Query{Since: start.Add(time.Second)})
+			if err != nil {
+				t.Fatalf("GetEvents() error = %v", err)
+			}
+			var got []string
+			for _, event := range events {
+				if event.WindowTitle != last.WindowTitle {
+					t.Errorf("credited event has title %q, want %q", event.WindowTitle, last.WindowTitle)
+				}
+				got = append(got, fmt.Sprintf("%s %v %v", event.AppName, event.Timestamp.Sub(start), time.Duration(event.Duration)*time.Second))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("credited events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
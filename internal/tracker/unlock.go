@@ -0,0 +1,63 @@
+package tracker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// checkUnlockSummary shows today's totals on unlocking the screen at now,
+// when it was locked since lockedAt for at least the unlock summary
+// threshold.
+func (s *Service) checkUnlockSummary(lockedAt, now time.Time) {
+	threshold := s.config.Tracker.UnlockSummary
+	if lockedAt.IsZero() || threshold <= 0 || now.Sub(lockedAt) < threshold {
+		return
+	}
+
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	totals, err := s.repo.GetDailyAppTotals(database.Query{Since: dayStart, Until: now.Add(time.Minute)})
+	if err != nil {
+		log.Printf("Failed to get today's totals for the unlock summary: %v", err)
+		return
+	}
+	s.notify(unlockSummary(totals, now.Sub(lockedAt)))
+}
+
+// unlockSummary sums today's totals, which come per app and tag, into the
+// time worked and the app used most.
+func unlockSummary(totals []models.DailyAppTotal, lockedFor time.Duration) notify.Message {
+	perApp := make(map[string]int64)
+	var worked int64
+	for _, total := range totals {
+		perApp[total.AppName] += total.TotalSeconds
+		worked += total.TotalSeconds
+	}
+	var topApp string
+	for app, seconds := range perApp {
+		if seconds > perApp[topApp] || (seconds == perApp[topApp] && app < topApp) {
+			topApp = app
+		}
+	}
+
+	body := "Nothing recorded yet today."
+	if worked > 0 {
+		body = fmt.Sprintf("%s worked today, most of it in %s (%s).",
+			utils.FormatHoursMinutes(worked), topApp, utils.FormatHoursMinutes(perApp[topApp]))
+	}
+	return notify.Message{
+		Title:   fmt.Sprintf("Back after %s", utils.FormatHoursMinutes(int64(lockedFor.Seconds()))),
+		Body:    body,
+		Urgency: "low",
+		Data: map[string]any{
+			"locked_seconds": int64(lockedFor.Seconds()),
+			"worked_seconds": worked,
+			"top_app":        topApp,
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+func TestUnlockSummary(t *testing.T) {
+	totals := []models.DailyAppTotal{
+		{AppName: "code", TotalSeconds: 3600},
+		{AppName: "firefox", TotalSeconds: 2700},
+		{AppName: "firefox", Tag: "research", TotalSeconds: 1800},
+	}
+	msg := unlockSummary(totals, 45*time.Minute)
+	if msg.Title != "Back after 45m" {
+		t.Errorf("Title = %q", msg.Title)
+	}
+	if want := "2h15m worked today, most of it in firefox (1h15m)."; msg.Body != want {
+		t.Errorf("Body = %q, want %q", msg.Body, want)
+	}
+	if data := msg.Data.(map[string]any); data["top_app"] != "firefox" || data["worked_seconds"] != int64(8100) {
+		t.Errorf("Data = %v", msg.Data)
+	}
+
+	if msg := unlockSummary(nil, time.Hour); msg.Body != "Nothing recorded yet today." {
+		t.Errorf("Body without totals = %q", msg.Body)
+	}
+}
@@ -0,0 +1,28 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localZone returns the IANA name of the local time zone, as far as it can
+// be determined, and its UTC offset in seconds at t. Go's time.Local does not
+// expose the IANA name, so it is read from TZ or the /etc/localtime link.
+func localZone(t time.Time) (string, int) {
+	_, offset := t.Zone()
+
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !filepath.IsAbs(tz) {
+		return tz, offset
+	}
+
+	if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+			return target[i+len("zoneinfo/"):], offset
+		}
+	}
+
+	name, _ := t.Zone()
+	return name, offset
+}
@@ -0,0 +1,1063 @@
+package web
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/auth"
+	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/tracker"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden API responses in testdata/api")
+
+// golden is what a golden file records about a response: values change with
+// the clock and the seeded UUIDs, so JSON bodies are reduced to their shape,
+// the keys and value types consumers rely on.
+type golden struct {
+	Status      int         `json:"status"`
+	ContentType string      `json:"content_type"`
+	Schema      interface{} `json:"schema,omitempty"`
+}
+
+// newTestServer serves the API from an in-memory database seeded with a
+// few minutes of activity that ended a minute ago, with every state file in
+// a temporary directory. configure, when not nil, adjusts the configuration.
+func newTestServer(t *testing.T, configure func(*config.Config)) (*httptest.Server, *models.FocusEvent) {
+	t.Helper()
+
+	now := time.Now()
+	start := now.Add(-11 * time.Minute)
+	if start.Day() != now.Day() {
+		t.Skip("too close to midnight for today's reports to hold the seeded events")
+	}
+
+	db, err := database.Connect(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	repo := database.NewRepository(db)
+
+	var first *models.FocusEvent
+	for i, app := range []string{"code", "firefox", "code", "slack", "code"} {
+		event := &models.FocusEvent{
+			Timestamp:     start.Add(time.Duration(i) * 2 * time.Minute),
+			AppName:       app,
+			WindowTitle:   app + " window",
+			Duration:      120,
+			DisplayServer: "wayland",
+			Detection:     "window",
+			Client:        "native",
+		}
+		if err := repo.Create(event); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if first == nil {
+			first = event
+		}
+	}
+	if err := repo.CreateNote(&models.Note{Text: "planning", Start: start, End: start.Add(4 * time.Minute)}); err != nil {
+		t.Fatalf("CreateNote() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(dir, "actionsum.db")
+	cfg.Tracker.PauseFile = filepath.Join(dir, "pause")
+	cfg.Tracker.AwayFile = filepath.Join(dir, "away")
+	cfg.Tracker.RemoteTerminalFile = filepath.Join(dir, "terminal.json")
+	cfg.Tracker.EditorFile = filepath.Join(dir, "editor.json")
+	cfg.Profiles.StateFile = filepath.Join(dir, "profile")
+	cfg.Tags.StateFile = filepath.Join(dir, "tag")
+	cfg.AppNames.MappingFile = filepath.Join(dir, "app-names.conf")
+	cfg.Daemon.PIDFile = filepath.Join(dir, "actionsum.pid")
+	cfg.Daemon.StatsFile = filepath.Join(dir, "stats.json")
+	if configure != nil {
+		configure(cfg)
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(cfg, repo).SetupRoutes(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, first
+}
+
+// newToken stores a token with scopes for user in the database of the test
+// server newTestServer started for t, and returns it.
+func newToken(t *testing.T, name, scopes, user string) string {
+	t.Helper()
+
+	// The test server's in-memory database is shared by name.
+	db, err := database.Connect(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	plain, hash, err := auth.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	token := &models.APIToken{Name: name, Hash: hash, Scopes: scopes, UserID: user}
+	if err := database.NewRepository(db).CreateToken(token); err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	return plain
+}
+
+func TestAPIGolden(t *testing.T) {
+	server, event := newTestServer(t, nil)
+	token := newToken(t, "golden", auth.ScopeRead+","+auth.ScopeWrite, "")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"health", "GET", "/health", ""},
+		{"events", "GET", "/api/events", ""},
+		{"events_week", "GET", "/api/events?period=week", ""},
+		{"events_bad_period", "GET", "/api/events?period=decade", ""},
+		{"events_latest", "GET", "/api/events/latest", ""},
+		{"recent", "GET", "/api/recent", ""},
+		{"recent_bad_limit", "GET", "/api/recent?limit=none", ""},
+		{"stream_unavailable", "GET", "/api/stream", ""},
+		{"app", "GET", "/api/apps/code", ""},
+		{"app_html", "GET", "/api/apps/code?period=day&hx=1", ""},
+		{"app_bad_period", "GET", "/api/apps/code?period=decade", ""},
+		{"app_icon_missing", "GET", "/api/apps/code/icon", ""},
+		{"app_page", "GET", "/apps/code", ""},
+		{"search", "GET", "/api/search?q=window", ""},
+		{"search_html", "GET", "/api/search?q=code&hx=1", ""},
+		{"search_empty", "GET", "/api/search?q=%22", ""},
+		{"search_bad_limit", "GET", "/api/search?q=code&limit=0", ""},
+		{"share", "GET", "/api/share?period=day", ""},
+		{"share_bad_period", "GET", "/api/share?period=decade", ""},
+		{"share_bad_redact", "GET", "/api/share?redact=maybe", ""},
+		{"event", "GET", "/api/events/" + event.UUID, ""},
+		{"event_missing", "GET", "/api/events/00000000-0000-4000-8000-000000000000", ""},
+		{"events_create", "POST", "/api/events", `{"app_name":"browser-extension","window_title":"docs","duration":30,"timestamp":"2024-01-01T09:00:00Z"}`},
+		{"events_create_invalid", "POST", "/api/events", `{"app_name":`},
+		{"events_create_no_token", "POST", "/api/events", `{"app_name":"page","duration":30,"timestamp":"2024-01-01T09:00:00Z"}`},
+		{"events_create_form", "POST", "/api/events", `app_name=page`},
+		{"report", "GET", "/api/report?period=day", ""},
+		{"report_sort", "GET", "/api/report?period=day&sort=events", ""},
+		{"summary", "GET", "/api/summary", ""},
+		{"summary_week", "GET", "/api/summary?period=week", ""},
+		{"summary_html", "GET", "/api/summary?hx=1", ""},
+		{"summary_recent", "GET", "/api/summary?sort=recent", ""},
+		{"summary_bad_sort", "GET", "/api/summary?sort=alphabetical", ""},
+		{"status", "GET", "/api/status", ""},
+		{"profiles", "GET", "/api/profiles", ""},
+		{"gaps", "GET", "/api/gaps?period=day", ""},
+		{"gaps_bad_min", "GET", "/api/gaps?min=soon", ""},
+		{"timeline", "GET", "/api/timeline", ""},
+		{"timeline_date", "GET", "/api/timeline?date=2024-01-01", ""},
+		{"timeline_bad_date", "GET", "/api/timeline?date=yesterday", ""},
+		{"timeline_page", "GET", "/timeline?at=2024-01-01T09:00:00Z", ""},
+		{"timeline_page_bad_at", "GET", "/timeline?at=9am", ""},
+		{"focus", "GET", "/api/focus", ""},
+		{"distractions", "GET", "/api/distractions", ""},
+		{"distribution", "GET", "/api/distribution", ""},
+		{"diff", "GET", "/api/diff", ""},
+		{"diff_csv", "GET", "/api/diff?format=csv", ""},
+		{"notes", "GET", "/api/notes", ""},
+		{"notes_create", "POST", "/api/notes", `{"text":"review","start":"2024-01-01T10:00:00Z","end":"2024-01-01T11:00:00Z"}`},
+		{"tag", "GET", "/api/tag", ""},
+		{"tag_set", "POST", "/api/tag", `{"name":"work","duration":"30m"}`},
+		{"tag_clear", "DELETE", "/api/tag", ""},
+		{"pause", "GET", "/api/pause", ""},
+		{"pause_set", "POST", "/api/pause", `{"duration":"15m"}`},
+		{"status_paused", "GET", "/api/status", ""},
+		{"pause_clear", "DELETE", "/api/pause", ""},
+		{"terminal", "GET", "/api/terminal", ""},
+		{"terminal_report", "POST", "/api/terminal", `{"host":"build01","session":"main","window":"editor","command":"vim"}`},
+		{"terminal_invalid", "POST", "/api/terminal", `{"host":"build01"}`},
+		{"editor", "GET", "/api/editor", ""},
+		{"editor_report", "POST", "/api/editor", `{"editor":"code","project":"actionsum","file":"/home/ada/src/actionsum/main.go","language":"go"}`},
+		{"editor_invalid", "POST", "/api/editor", `{"project":"actionsum"}`},
+		{"schema", "GET", "/api/schema", ""},
+		{"schema_sql", "GET", "/api/schema?format=sql", ""},
+		{"metrics", "GET", "/metrics", ""},
+		{"mobile", "GET", "/m", ""},
+		{"index", "GET", "/", ""},
+		{"static", "GET", "/static/hx.js", ""},
+		{"static_missing", "GET", "/static/htmx.min.js", ""},
+		{"not_found", "GET", "/nope", ""},
+		{"method_not_allowed", "PUT", "/api/summary", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			if strings.Contains(tt.path, "hx=1") {
+				req.Header.Set("HX-Request", "true")
+			}
+			// Changes need a token and JSON, unlike what cross-site pages
+			// can send.
+			if tt.method != "GET" && !strings.HasSuffix(tt.name, "_no_token") {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			if tt.body != "" {
+				contentType := "application/json"
+				if strings.HasSuffix(tt.name, "_form") {
+					contentType = "application/x-www-form-urlencoded"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+
+			got := golden{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")}
+			if strings.HasPrefix(got.ContentType, "application/json") {
+				var value interface{}
+				if err := json.Unmarshal(body, &value); err != nil {
+					t.Fatalf("invalid JSON response: %v\n%s", err, body)
+				}
+				got.Schema = schemaOf(value)
+			}
+			checkGolden(t, tt.name, got)
+		})
+	}
+}
+
+func TestAppReport(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+
+	resp, err := http.Get(server.URL + "/api/apps/code?period=day")
+	if err != nil {
+		t.Fatalf("GET /api/apps/code: %v", err)
+	}
+	defer resp.Body.Close()
+	var report models.AppReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+
+	if report.TotalSeconds != 360 {
+		t.Errorf("TotalSeconds = %d, want 360", report.TotalSeconds)
+	}
+	if len(report.Days) != 1 || report.Days[0].TotalSeconds != 360 {
+		t.Errorf("Days = %+v, want one day of 360s", report.Days)
+	}
+	if len(report.Titles) != 1 || report.Titles[0].WindowTitle != "code window" || report.Titles[0].EventCount != 3 {
+		t.Errorf("Titles = %+v, want code window with 3 events", report.Titles)
+	}
+	// Time in firefox and slack separates the three sessions.
+	if len(report.Sessions) != 3 || report.Sessions[0].Seconds != 120 {
+		t.Errorf("Sessions = %+v, want three of 120s", report.Sessions)
+	}
+	var hours int64
+	for _, seconds := range report.Hours {
+		hours += seconds
+	}
+	if hours != 360 {
+		t.Errorf("Hours add up to %d, want 360", hours)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	server, event := newTestServer(t, nil)
+
+	resp, err := http.Get(server.URL + "/api/search?q=cod")
+	if err != nil {
+		t.Fatalf("GET /api/search: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Results []models.SearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	// The three code events are separated by other apps, so they stay
+	// separate results, newest first.
+	if len(body.Results) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(body.Results), body.Results)
+	}
+	if last := body.Results[2]; last.UUID != event.UUID || last.Seconds != 120 {
+		t.Errorf("oldest result = %+v, want the first event", last)
+	}
+	if !body.Results[0].Start.After(body.Results[1].Start) {
+		t.Errorf("results not newest first: %+v", body.Results)
+	}
+}
+
+func TestShare(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+
+	share := func(query string) string {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/share?period=day" + query)
+		if err != nil {
+			t.Fatalf("GET /api/share: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/share%s: status %d", query, resp.StatusCode)
+		}
+		if disposition := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="actionsum-day-`) {
+			t.Errorf("Content-Disposition = %q, want a download", disposition)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	page := share("")
+	for _, want := range []string{"code", "code window", "planning"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("shared report is missing %q", want)
+		}
+	}
+	if strings.Contains(page, "<script") || strings.Contains(page, "/static/") {
+		t.Errorf("shared report depends on scripts or assets of the server")
+	}
+
+	page = share("&redact=true")
+	if !strings.Contains(page, "slack") {
+		t.Errorf("redacted report is missing the app names")
+	}
+	for _, hidden := range []string{"window", "planning"} {
+		if strings.Contains(page, hidden) {
+			t.Errorf("redacted report contains %q", hidden)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Quarterly report.pdf", []string{"rep"}, "Quarterly <mark>report</mark>.pdf"},
+		{"Évince <viewer>", []string{"EVI"}, "<mark>Évince</mark> &lt;viewer&gt;"},
+		{"prepare report", []string{"rep"}, "prepare <mark>report</mark>"},
+		{"a & b", []string{"c"}, "a &amp; b"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.text, tt.terms); got != tt.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	live := tracker.NewLive()
+	live.Set(tracker.Presence{State: tracker.PresenceActive, AppName: "code", UpdatedAt: time.Now()})
+
+	handler := NewHandler(config.Default(), nil)
+	handler.live = live
+	mux := http.NewServeMux()
+	handler.SetupRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/stream")
+	if err != nil {
+		t.Fatalf("GET /api/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() tracker.Presence {
+		t.Helper()
+		var event string
+		for events.Scan() {
+			line := events.Text()
+			if data, ok := strings.CutPrefix(line, "data: "); ok && event == "presence" {
+				var p tracker.Presence
+				if err := json.Unmarshal([]byte(data), &p); err != nil {
+					t.Fatalf("invalid presence %q: %v", data, err)
+				}
+				return p
+			}
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			}
+		}
+		t.Fatalf("stream ended: %v", events.Err())
+		return tracker.Presence{}
+	}
+
+	if p := next(); p.State != tracker.PresenceActive || p.AppName != "code" {
+		t.Errorf("first event = %+v, want the current presence", p)
+	}
+	live.Set(tracker.Presence{State: tracker.PresenceLocked, UpdatedAt: time.Now()})
+	if p := next(); p.State != tracker.PresenceLocked {
+		t.Errorf("next event = %+v, want locked", p)
+	}
+}
+
+func TestAPIRequireToken(t *testing.T) {
+	server, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.Web.RequireToken = true
+	})
+
+	for _, path := range []string{"/api/summary", "/api/status", "/metrics", "/"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token = %d, want 401", path, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /health without a token = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestWriteAuth(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	writer := newToken(t, "writer", auth.ScopeRead+","+auth.ScopeWrite, "")
+	reader := newToken(t, "reader", auth.ScopeRead, "")
+
+	note := `{"text":"review","start":"2024-01-01T10:00:00Z","end":"2024-01-01T11:00:00Z"}`
+	tests := []struct {
+		name        string
+		token       string
+		contentType string
+		want        int
+	}{
+		{"no token", "", "application/json", http.StatusUnauthorized},
+		{"invalid token", "as_0000", "application/json", http.StatusUnauthorized},
+		{"read scope only", reader, "application/json", http.StatusForbidden},
+		{"plain text", writer, "text/plain", http.StatusUnsupportedMediaType},
+		{"form", writer, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"write scope", writer, "application/json; charset=utf-8", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", server.URL+"/api/notes", strings.NewReader(note))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Origin", "https://example.com")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST /api/notes: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("POST /api/notes = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != "" {
+				t.Errorf("POST /api/notes allows origin %q", origin)
+			}
+		})
+	}
+
+	// Pages elsewhere may read, but a preflight for changes is not granted.
+	req, _ := http.NewRequest("OPTIONS", server.URL+"/api/events", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("OPTIONS /api/events: %v", err)
+	}
+	resp.Body.Close()
+	if methods := resp.Header.Get("Access-Control-Allow-Methods"); strings.Contains(methods, "POST") || strings.Contains(methods, "DELETE") {
+		t.Errorf("preflight allows %q", methods)
+	}
+	resp, err = http.Get(server.URL + "/api/summary")
+	if err != nil {
+		t.Fatalf("GET /api/summary: %v", err)
+	}
+	resp.Body.Close()
+	if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != "*" {
+		t.Errorf("GET /api/summary allows origin %q, want *", origin)
+	}
+
+	// A token given to a page is kept in a cookie for its requests, and
+	// only then does the mobile page offer to pause.
+	mobile := func(query string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL+"/m"+query, nil)
+		req.Header.Set("HX-Request", "true")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /m: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+	if _, body := mobile(""); strings.Contains(body, "/api/pause") {
+		t.Error("mobile page without a token offers to pause")
+	}
+	resp, body := mobile("?access_token=" + writer)
+	if !strings.Contains(body, "/api/pause") {
+		t.Error("mobile page with a write token doesn't offer to pause")
+	}
+	var session *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "access_token" {
+			session = cookie
+		}
+	}
+	if session == nil || !session.HttpOnly || session.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("access_token cookie = %+v, want a strict HTTP-only one", session)
+	}
+	req, _ = http.NewRequest("POST", server.URL+"/api/pause", nil)
+	req.AddCookie(session)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/pause: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("POST /api/pause with the cookie = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestIngestDuplicates(t *testing.T) {
+	server, event := newTestServer(t, nil)
+	token := newToken(t, "extension", auth.ScopeWrite, "")
+
+	post := func(body string) map[string]interface{} {
+		t.Helper()
+		req, _ := http.NewRequest("POST", server.URL+"/api/events", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /api/events: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST /api/events = %d, want 201", resp.StatusCode)
+		}
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return result
+	}
+
+	// A retry repeats a stored event and one already in the batch.
+	batch := fmt.Sprintf(`[
+		{"uuid": %q, "app_name": "code", "window_title": "again", "duration": 30, "timestamp": "2024-01-01T09:00:00Z"},
+		{"uuid": "6c9b7a0e-2f7a-4d2b-9d57-0a4a1f3c2b10", "app_name": "docs", "window_title": "new", "duration": 30, "timestamp": "2024-01-01T09:01:00Z"},
+		{"uuid": "6c9b7a0e-2f7a-4d2b-9d57-0a4a1f3c2b10", "app_name": "docs", "window_title": "new", "duration": 30, "timestamp": "2024-01-01T09:01:00Z"}
+	]`, event.UUID)
+	result := post(batch)
+	if result["created"] != 1.0 || result["duplicates"] != 2.0 || result["rejected"] != 0.0 {
+		t.Errorf("first post = %v, want 1 created and 2 duplicates", result)
+	}
+	result = post(batch)
+	if result["created"] != 0.0 || result["duplicates"] != 3.0 {
+		t.Errorf("retry = %v, want only duplicates", result)
+	}
+}
+
+func TestMultiUser(t *testing.T) {
+	server, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.Web.MultiUser = true
+	})
+	plain := newToken(t, "alice-laptop", auth.ScopeRead+","+auth.ScopeWrite, "alice")
+
+	do := func(method, path, body string, asAlice bool) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if asAlice {
+			req.Header.Set("Authorization", "Bearer "+plain)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	apps := func(asAlice bool) []string {
+		t.Helper()
+		var report models.Report
+		if err := json.NewDecoder(do("GET", "/api/report", "", asAlice).Body).Decode(&report); err != nil {
+			t.Fatalf("invalid report: %v", err)
+		}
+		var names []string
+		for _, app := range report.Apps {
+			names = append(names, app.AppName)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	event := fmt.Sprintf(`{"timestamp": %q, "app_name": "krita", "window_title": "drawing", "duration": 60, "user_id": "bob"}`,
+		time.Now().Add(-5*time.Minute).Format(time.RFC3339))
+	if resp := do("POST", "/api/events", event, true); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/events as alice = %d, want 201", resp.StatusCode)
+	}
+
+	if got := apps(true); strings.Join(got, ",") != "krita" {
+		t.Errorf("alice's report has %q, want only her event", got)
+	}
+	if got := apps(false); strings.Join(got, ",") != "code,firefox,slack" {
+		t.Errorf("this machine's report has %q, want only its own events", got)
+	}
+
+	var distractions models.DistractionReport
+	if err := json.NewDecoder(do("GET", "/api/distractions", "", true).Body).Decode(&distractions); err != nil {
+		t.Fatalf("invalid distraction report: %v", err)
+	}
+	if distractions.TotalSeconds != 60 {
+		t.Errorf("alice's distraction report covers %ds, want only her 60s", distractions.TotalSeconds)
+	}
+
+	var status map[string]any
+	if err := json.NewDecoder(do("GET", "/api/status", "", true).Body).Decode(&status); err != nil {
+		t.Fatalf("invalid status: %v", err)
+	}
+	if latest, _ := status["latest_event"].(map[string]any); latest["app_name"] != "krita" {
+		t.Errorf("alice's status has latest event %v, want hers", status["latest_event"])
+	}
+	for _, field := range []string{"database_path", "database_size", "profile", "poll_interval"} {
+		if _, ok := status[field]; ok {
+			t.Errorf("alice's status shows this machine's %s", field)
+		}
+	}
+
+	// Alice neither sees nor controls this machine's tracker.
+	for _, path := range []string{"/api/pause", "/api/tag", "/api/stream", "/api/terminal", "/api/editor"} {
+		if resp := do("GET", path, "", true); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("GET %s as alice = %d, want 403", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestTeamReports(t *testing.T) {
+	server, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.Web.MultiUser = true
+		cfg.Web.TeamReports = true
+		cfg.Web.TeamMinMembers = 2
+	})
+
+	tokens := make(map[string]string)
+	for _, user := range []string{"alice", "bob", "carol"} {
+		tokens[user] = newToken(t, user, auth.ScopeRead+","+auth.ScopeWrite, user)
+	}
+	// This machine's user, who has no team sharing setting.
+	tokens["owner"] = newToken(t, "owner", auth.ScopeRead+","+auth.ScopeWrite, "")
+
+	do := func(method, path, body, user string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("Authorization", "Bearer "+tokens[user])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	team := func() (models.TeamReport, string) {
+		t.Helper()
+		body, err := io.ReadAll(do("GET", "/api/team", "", "carol").Body)
+		if err != nil {
+			t.Fatalf("GET /api/team: %v", err)
+		}
+		var report models.TeamReport
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Fatalf("invalid team report: %v", err)
+		}
+		return report, string(body)
+	}
+
+	for i, user := range []string{"alice", "bob", "carol"} {
+		event := fmt.Sprintf(`{"timestamp": %q, "app_name": "krita", "window_title": "secret drawing", "duration": %d, "tag": %q}`,
+			time.Now().Add(-5*time.Minute).Format(time.RFC3339), 60*(i+1), user+" job interview")
+		if resp := do("POST", "/api/events", event, user); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST /api/events as %s = %d, want 201", user, resp.StatusCode)
+		}
+	}
+
+	// Nobody is included until they opt in.
+	if report, _ := team(); report.Contributors != 0 || len(report.Members) != 0 || report.Categories != nil {
+		t.Errorf("team report before opting in = %+v, want nobody", report)
+	}
+
+	if resp := do("PUT", "/api/team/sharing", `{"level": "hours"}`, "alice"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /api/team/sharing as alice = %d, want 200", resp.StatusCode)
+	}
+	// One contributor's category totals would be their own time.
+	if report, _ := team(); report.Contributors != 1 || report.Categories != nil || report.TotalSeconds != 0 {
+		t.Errorf("team report with one member = %+v, want no totals", report)
+	}
+
+	if resp := do("PUT", "/api/team/sharing", `{"level": "categories"}`, "bob"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /api/team/sharing as bob = %d, want 200", resp.StatusCode)
+	}
+	report, body := team()
+	if len(report.Members) != 1 || report.Members[0].UserID != "alice" || report.Members[0].TotalSeconds != 60 {
+		t.Errorf("team members = %+v, want only alice with 60s", report.Members)
+	}
+	if report.Contributors != 2 || report.TotalSeconds != 180 || len(report.Categories) != 1 {
+		t.Errorf("team report = %+v, want alice's and bob's 180s in one category", report)
+	}
+	if strings.Contains(body, "bob") || strings.Contains(body, "krita") || strings.Contains(body, "secret") || strings.Contains(body, "interview") {
+		t.Errorf("team report reveals more than shared: %s", body)
+	}
+
+	if resp := do("PUT", "/api/team/sharing", `{"level": "everything"}`, "carol"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT an unknown level = %d, want 400", resp.StatusCode)
+	}
+	// This machine's user has no sharing setting to change.
+	if resp := do("PUT", "/api/team/sharing", `{"level": "hours"}`, "owner"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("PUT /api/team/sharing without a user = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	// The provider signs in whoever the test names in the code, as the
+	// user it names, for the nonce it carries: "alice:<nonce>".
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			respondJSON(w, map[string]string{
+				"issuer":                 provider.URL,
+				"authorization_endpoint": provider.URL + "/authorize",
+				"token_endpoint":         provider.URL + "/token",
+			})
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("code_verifier") == "" {
+				http.Error(w, "PKCE verifier missing", http.StatusBadRequest)
+				return
+			}
+			user, nonce, _ := strings.Cut(r.Form.Get("code"), ":")
+			claims, _ := json.Marshal(map[string]interface{}{
+				"iss": provider.URL, "aud": "actionsum", "exp": time.Now().Add(time.Hour).Unix(),
+				"nonce": nonce, "preferred_username": user,
+			})
+			respondJSON(w, map[string]string{"id_token": "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".c2ln"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	server, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.Web.MultiUser = true
+		cfg.Web.RequireToken = true
+		cfg.Web.OIDC.Issuer = provider.URL
+		cfg.Web.OIDC.ClientID = "actionsum"
+		cfg.Web.OIDC.RedirectURL = "http://localhost/auth/callback"
+		cfg.Web.OIDC.AllowedUsers = []string{"alice"}
+	})
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(path string, cookies ...*http.Cookie) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("Accept", "text/html")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	cookie := func(resp *http.Response, name string) *http.Cookie {
+		for _, c := range resp.Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("response sets no %s cookie", name)
+		return nil
+	}
+	signIn := func(user string) *http.Response {
+		t.Helper()
+		login := get("/auth/login")
+		target, err := url.Parse(login.Header.Get("Location"))
+		if err != nil || !strings.HasPrefix(target.String(), provider.URL+"/authorize") {
+			t.Fatalf("GET /auth/login redirects to %q, want the provider", login.Header.Get("Location"))
+		}
+		query := target.Query()
+		if query.Get("code_challenge_method") != "S256" {
+			t.Errorf("sign-in doesn't use PKCE: %s", target)
+		}
+		code := url.QueryEscape(user + ":" + query.Get("nonce"))
+		return get("/auth/callback?code="+code+"&state="+query.Get("state"), cookie(login, "oidc_flow"))
+	}
+
+	if resp := get("/"); resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/auth/login" {
+		t.Errorf("GET / without a session = %d to %q, want a redirect to sign in", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp := signIn("alice")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/" {
+		t.Fatalf("sign-in callback = %d to %q, want a redirect to the dashboard", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	session := cookie(resp, "access_token")
+	if resp := get("/api/status", session); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /api/status with the session = %d, want 200", resp.StatusCode)
+	}
+	if body, _ := io.ReadAll(get("/", session).Body); !strings.Contains(string(body), "/auth/logout") {
+		t.Error("dashboard of a signed-in user has no sign-out link")
+	}
+
+	// Signed-in users issue their own tokens, for themselves only.
+	req, _ := http.NewRequest("POST", server.URL+"/api/tokens", strings.NewReader(`{"name": "alice-laptop", "scopes": "write:events"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(session)
+	created, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/tokens: %v", err)
+	}
+	defer created.Body.Close()
+	if created.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/tokens = %d, want 201", created.StatusCode)
+	}
+	req, _ = http.NewRequest("POST", server.URL+"/api/tokens", strings.NewReader(`{"name": "alice-admin", "scopes": "admin"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(session)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST /api/tokens for admin = %v, %v; want 403", resp.StatusCode, err)
+	}
+
+	if resp := signIn("mallory"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("sign-in of a user not allowed = %d, want 403", resp.StatusCode)
+	}
+	login := get("/auth/login")
+	if resp := get("/auth/callback?code=alice:x&state=forged", cookie(login, "oidc_flow")); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("callback with another state = %d, want 400", resp.StatusCode)
+	}
+
+	get("/auth/logout", session)
+	if resp := get("/api/status", session); resp.StatusCode != http.StatusFound {
+		t.Errorf("GET /api/status after signing out = %d, want a redirect to sign in again", resp.StatusCode)
+	}
+}
+
+func TestPagesUseEmbeddedAssets(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	link := regexp.MustCompile(`(?:src|href)="([^"]+)"`)
+
+	for _, page := range []string{"/", "/m"} {
+		resp, err := http.Get(server.URL + page)
+		if err != nil {
+			t.Fatalf("GET %s: %v", page, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		for _, match := range link.FindAllStringSubmatch(string(body), -1) {
+			url := match[1]
+			if !strings.HasPrefix(url, "/") {
+				t.Errorf("%s links %s, want only local URLs", page, url)
+				continue
+			}
+			if !strings.HasPrefix(url, "/static/") {
+				continue
+			}
+			asset, err := http.Get(server.URL + url)
+			if err != nil {
+				t.Fatalf("GET %s: %v", url, err)
+			}
+			asset.Body.Close()
+			if asset.StatusCode != http.StatusOK || !strings.Contains(asset.Header.Get("Cache-Control"), "immutable") {
+				t.Errorf("GET %s = %d, Cache-Control %q, want a cacheable 200", url, asset.StatusCode, asset.Header.Get("Cache-Control"))
+			}
+		}
+	}
+}
+
+func TestDashboardAccessibility(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	get := func(path string, htmx bool) string {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// Buttons showing only an icon need a name screen readers can announce.
+	button := regexp.MustCompile(`<button[^>]*>`)
+	for _, page := range []string{"/", "/apps/code"} {
+		body := get(page, false)
+		for _, tag := range button.FindAllString(body, -1) {
+			if strings.Contains(tag, `class="header-btn" onclick=`) && !strings.Contains(tag, "aria-label=") {
+				t.Errorf("%s: %s has no aria-label", page, tag)
+			}
+		}
+		if !strings.Contains(body, `<dialog id="shortcuts"`) {
+			t.Errorf("%s has no keyboard shortcuts dialog", page)
+		}
+	}
+	for _, period := range []string{"day", "week", "month"} {
+		if body := get("/", false); !strings.Contains(body, `id="summary-`+period+`"`) {
+			t.Errorf("dashboard has no section for %s to move to", period)
+		}
+	}
+
+	for _, path := range []string{"/api/summary?period=today", "/api/recent"} {
+		body := get(path, true)
+		if !strings.Contains(body, `role="list" aria-label=`) || !strings.Contains(body, `role="listitem"`) {
+			t.Errorf("%s is not marked up as a labelled list", path)
+		}
+	}
+}
+
+// checkGolden compares got with testdata/api/<name>.json, or rewrites the
+// file with -update.
+func checkGolden(t *testing.T, name string, got golden) {
+	t.Helper()
+	path := filepath.Join("testdata", "api", name+".json")
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent() error = %v", err)
+	}
+	data = append(data, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file, run go test ./internal/web -update: %v", err)
+	}
+	if string(want) != string(data) {
+		t.Errorf("response differs from %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", path, data, want)
+	}
+}
+
+// schemaOf reduces a decoded JSON value to its shape: objects keep their keys,
+// arrays hold the merged shape of their elements, and other values become
+// their type name.
+func schemaOf(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schema := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			schema[key] = schemaOf(field)
+		}
+		return schema
+	case []interface{}:
+		if len(v) == 0 {
+			return []interface{}{}
+		}
+		merged := schemaOf(v[0])
+		for _, element := range v[1:] {
+			merged = mergeSchemas(merged, schemaOf(element))
+		}
+		return []interface{}{merged}
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// mergeSchemas combines the shapes of two array elements: objects get the
+// union of their keys, and differing types are joined as "number|null".
+func mergeSchemas(a, b interface{}) interface{} {
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if okA && okB {
+		for key, field := range objB {
+			if existing, ok := objA[key]; ok {
+				objA[key] = mergeSchemas(existing, field)
+			} else {
+				objA[key] = field
+			}
+		}
+		return objA
+	}
+	arrA, okA := a.([]interface{})
+	arrB, okB := b.([]interface{})
+	if okA && okB {
+		switch {
+		case len(arrA) == 0:
+			return arrB
+		case len(arrB) == 0:
+			return arrA
+		}
+		return []interface{}{mergeSchemas(arrA[0], arrB[0])}
+	}
+
+	nameA, nameB := fmt.Sprint(a), fmt.Sprint(b)
+	if nameA == nameB {
+		return a
+	}
+	types := map[string]bool{}
+	for _, name := range append(strings.Split(nameA, "|"), strings.Split(nameB, "|")...) {
+		types[name] = true
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
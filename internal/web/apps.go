@@ -0,0 +1,193 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+
+	"github.com/actionsum/actionsum/internal/locale"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/icontheme"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// appURL links an app's drill-down page.
+func appURL(appName string) string {
+	return "/apps/" + url.PathEscape(appName)
+}
+
+// appIconURL is where the icon of an app is served.
+func appIconURL(appName string) string {
+	return "/api" + appURL(appName) + "/icon"
+}
+
+// handleAppIcon serves the icon of an app from its .desktop file, found in
+// the hicolor icon theme, for the dashboard to show next to its name.
+// Icons rarely change, so browsers may keep them for a day.
+func (h *Handler) handleAppIcon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path, ok := icontheme.Find(h.repo.AppIconName(r.PathValue("name")))
+	if !ok {
+		http.Error(w, "No icon for this app", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	// An SVG opened on its own must not run scripts with the dashboard's
+	// origin.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	http.ServeFile(w, r, path)
+}
+
+// handleApp reports on one app: its daily trend, top window titles, longest
+// sessions and hour-of-day distribution. htmx requests get the dashboard's
+// HTML for it.
+func (h *Handler) handleApp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+
+	report, err := h.readReporter(w, r).GenerateAppReport(periodType, r.URL.Query().Get("profile"), r.PathValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		h.respondAppHTML(w, h.localeFor(w, r), report)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handler) respondAppHTML(w http.ResponseWriter, loc *locale.Locale, report *models.AppReport) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := func(msg string) string {
+		return html.EscapeString(loc.T(msg))
+	}
+
+	if report.TotalSeconds == 0 {
+		w.Write([]byte(`<div class="report-box"><div class="loading">` + t("No data available") + `</div></div>`))
+		return
+	}
+
+	out := `<div class="report-box"><h2>` + t("Daily Trend") + `</h2><div class="listing bars">`
+	var maxDay int64
+	for _, day := range report.Days {
+		maxDay = max(maxDay, day.TotalSeconds)
+	}
+	for _, day := range report.Days {
+		out += fmt.Sprintf(`
+		<div class="app-item" style="--bar-width: %.1f%%">
+			<span class="app-name">%s</span>
+			<span class="app-time">%s</span>
+		</div>`, percentOf(day.TotalSeconds, maxDay), day.Day, utils.FormatHoursMinutes(day.TotalSeconds))
+	}
+	out += `</div><div class="total">` + html.EscapeString(loc.T("Total: %s", utils.FormatHoursMinutes(report.TotalSeconds))) + `</div></div>`
+
+	out += `<div class="report-box"><h2>` + t("Top Window Titles") + `</h2><div class="listing bars">`
+	for _, title := range report.Titles {
+		name := title.WindowTitle
+		if name == "" {
+			name = "—"
+		}
+		out += fmt.Sprintf(`
+		<div class="app-item" style="--bar-width: %.1f%%" title="%s">
+			<span class="app-name app-title">%s</span>
+			<span class="app-time">%s</span>
+		</div>`, percentOf(title.TotalSeconds, report.TotalSeconds), html.EscapeString(title.WindowTitle),
+			html.EscapeString(name), utils.FormatHoursMinutes(title.TotalSeconds))
+	}
+	out += `</div></div>`
+
+	out += `<div class="report-box"><h2>` + t("Longest Sessions") + `</h2><div class="listing">`
+	for _, session := range report.Sessions {
+		start := session.Start.Local()
+		out += fmt.Sprintf(`
+		<div class="app-item">
+			<span class="app-name">%s–%s</span>
+			<span class="app-time">%s</span>
+		</div>`, start.Format("2006-01-02 15:04"), session.End.Local().Format("15:04"), utils.FormatHoursMinutes(session.Seconds))
+	}
+	out += `</div></div>`
+
+	out += `<div class="report-box"><h2>` + t("Hour of Day") + `</h2><div class="hours">`
+	var maxHour int64
+	for _, seconds := range report.Hours {
+		maxHour = max(maxHour, seconds)
+	}
+	for hour, seconds := range report.Hours {
+		out += fmt.Sprintf(`<div class="hour" style="--bar-height: %.1f%%" title="%02d:00 · %s"></div>`,
+			percentOf(seconds, maxHour), hour, utils.FormatHoursMinutes(seconds))
+	}
+	out += `</div><div class="hour-labels"><span>00</span><span>06</span><span>12</span><span>18</span><span>24</span></div></div>`
+
+	w.Write([]byte(out))
+}
+
+func percentOf(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100.0
+}
+
+// handleAppPage serves the drill-down page for one app, which loads its
+// report for the chosen period.
+func (h *Handler) handleAppPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loc := h.localeFor(w, r)
+	t := func(msg string) string {
+		return html.EscapeString(loc.T(msg))
+	}
+	appName := h.repo.NormalizeAppName(r.PathValue("name"))
+	api := "/api" + appURL(appName)
+
+	page := `<!DOCTYPE html>
+<html lang="` + loc.Tag() + `">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>` + html.EscapeString(appName) + ` - ` + t("Actionsum Dashboard") + `</title>
+    <script src="` + assetURL("hx.js") + `"></script>
+    <link rel="stylesheet" href="` + assetURL("dashboard.css") + `">
+</head>
+<body>
+    <div class="header">
+        <h1><a class="back-link" href="/" title="` + t("Full dashboard") + `">←</a> ` + html.EscapeString(appName) + `</h1>
+        <div class="header-controls">
+            <select id="period" class="header-select" name="period" aria-label="` + t("Period") + `">
+                <option value="day">` + t("Today") + `</option>
+                <option value="week" selected>` + t("This Week") + `</option>
+                <option value="month">` + t("This Month") + `</option>
+            </select>
+            <button class="header-btn" onclick="toggleTheme()" title="` + t("Toggle theme") + `" aria-label="` + t("Toggle theme") + `">
+                <span id="theme-icon" aria-hidden="true">🌙</span>
+            </button>
+        </div>
+    </div>
+    <div class="dashboard" hx-get="` + html.EscapeString(api) + `" hx-include="#period" hx-trigger="load, every 60s, change from:#period" hx-swap="innerHTML">
+        <div class="report-box"><div class="loading">` + t("Loading...") + `</div></div>
+    </div>
+    ` + shortcutsDialog(loc) + `
+    <script src="` + assetURL("dashboard.js") + `"></script>
+</body>
+</html>`
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// staticFiles holds the dashboard's scripts and stylesheets, so the pages
+// work offline and load nothing from third parties.
+//
+//go:embed static
+var staticFiles embed.FS
+
+// assetTypes are the content types of the kinds of asset embedded, fixed
+// rather than taken from the system's MIME database.
+var assetTypes = map[string]string{
+	".css": "text/css; charset=utf-8",
+	".js":  "text/javascript; charset=utf-8",
+}
+
+// assetHashes maps each asset's name to a hash of its contents. Pages link
+// assets with it as a version, so browsers may cache them for good and pick
+// up a new build's files straight away.
+var assetHashes = hashAssets()
+
+func hashAssets() map[string]string {
+	hashes := make(map[string]string)
+	entries, _ := fs.ReadDir(staticFiles, "static")
+	for _, entry := range entries {
+		data, err := staticFiles.ReadFile("static/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hashes[entry.Name()] = hex.EncodeToString(sum[:])[:12]
+	}
+	return hashes
+}
+
+// assetURL returns the versioned URL of an embedded asset.
+func assetURL(name string) string {
+	return "/static/" + name + "?v=" + assetHashes[name]
+}
+
+// handleStatic serves embedded assets. Requests for the current version are
+// cacheable indefinitely; others must be revalidated.
+func (h *Handler) handleStatic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	hash, ok := assetHashes[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := staticFiles.ReadFile("static/" + name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := `"` + hash + `"`
+	w.Header().Set("ETag", etag)
+	if r.URL.Query().Get("v") == hash {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", assetTypes[path.Ext(name)])
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hxAttribute matches an hx-* attribute and its value in page markup, whether
+// written in a raw string or an escaped one.
+var hxAttribute = regexp.MustCompile(`\bhx-([a-z-]+)=\\?"([^"\\]*)`)
+
+// hxTriggerEvent matches one event of an hx-trigger spec in the forms hx.js
+// understands: load, every <n>s or <n>ms, or an event name with from: and
+// delay: modifiers.
+var hxTriggerEvent = regexp.MustCompile(`^(load|every \d+m?s|[a-zA-Z][\w:.-]*( from:\S+)?( delay:\d+m?s)?)$`)
+
+// checkHx says why hx.js cannot handle an attribute, or is empty if it can.
+func checkHx(name, value string) string {
+	switch name {
+	case "get", "post", "delete", "include", "target":
+		return ""
+	case "swap":
+		if value != "innerHTML" && value != "none" {
+			return "only innerHTML and none swaps are supported"
+		}
+		return ""
+	case "trigger":
+		for _, part := range strings.Split(value, ",") {
+			if !hxTriggerEvent.MatchString(strings.TrimSpace(part)) {
+				return fmt.Sprintf("trigger %q is not supported", strings.TrimSpace(part))
+			}
+		}
+		return ""
+	}
+	return "attribute is not supported"
+}
+
+func TestCheckHx(t *testing.T) {
+	tests := []struct {
+		name, value string
+		ok          bool
+	}{
+		{"get", "/api/stats", true},
+		{"swap", "innerHTML", true},
+		{"swap", "none", true},
+		{"swap", "outerHTML", false},
+		{"trigger", "load, every 30s, change from:#profile-filter", true},
+		{"trigger", "submit, input delay:300ms", true},
+		{"trigger", "every 500ms", true},
+		{"trigger", "keyup changed delay:500ms", false},
+		{"trigger", "revealed once", false},
+		{"boost", "true", false},
+		{"confirm", "Delete?", false},
+	}
+	for _, tt := range tests {
+		if got := checkHx(tt.name, tt.value); (got == "") != tt.ok {
+			t.Errorf("checkHx(%q, %q) = %q, want supported %v", tt.name, tt.value, got, tt.ok)
+		}
+	}
+}
+
+// TestHxAttributesSupported keeps pages to the part of htmx that hx.js
+// implements: an attribute it doesn't know would be silently ignored.
+func TestHxAttributesSupported(t *testing.T) {
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scripts, err := fs.Glob(staticFiles, "static/*.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := 0
+	check := func(file string, data []byte) {
+		for _, match := range hxAttribute.FindAllSubmatch(data, -1) {
+			found++
+			if reason := checkHx(string(match[1]), string(match[2])); reason != "" {
+				t.Errorf("%s: hx-%s=%q: %s by static/hx.js", file, match[1], match[2], reason)
+			}
+		}
+	}
+	for _, file := range sources {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(file, data)
+	}
+	for _, file := range scripts {
+		if file == "static/hx.js" {
+			continue
+		}
+		data, err := staticFiles.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(file, data)
+	}
+	if found == 0 {
+		t.Error("found no hx-* attributes; is the pattern out of date?")
+	}
+}
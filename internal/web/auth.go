@@ -0,0 +1,178 @@
+package web
+
+import (
+	"context"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/auth"
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+type tokenKey struct{}
+
+// touchInterval limits how often a token's last-used time is written.
+const touchInterval = time.Minute
+
+// requireScope wraps next so it only runs for requests allowed scope. A
+// request presenting a token must present a valid one that grants scope.
+// Reads without a token are let through unless ACTIONSUM_WEB_REQUIRE_TOKEN
+// is set, so the local dashboard works without setup. Changes always need
+// a token, and a JSON body when they have one: any web page the user visits
+// can post to localhost, but it can neither read nor send the token, nor
+// send JSON cross-origin without a preflight the server doesn't grant.
+func (h *Handler) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		read := isRead(r)
+		if r.Method == http.MethodOptions {
+			setCORSHeaders(w)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if read {
+			setCORSHeaders(w)
+		} else if !jsonBody(r) {
+			http.Error(w, "Request body must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		presented := requestToken(w, r)
+		if presented == "" {
+			if h.config.Web.RequireToken && h.wantsSignIn(r) {
+				http.Redirect(w, r, "/auth/login", http.StatusFound)
+				return
+			}
+			if h.config.Web.RequireToken || !read {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="actionsum"`)
+				http.Error(w, "API token required", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		token, err := h.repo.GetTokenByHash(auth.Hash(presented))
+		if err != nil {
+			http.Error(w, "Failed to check API token", http.StatusInternalServerError)
+			return
+		}
+		if token == nil {
+			if h.wantsSignIn(r) {
+				// Most likely a session that has expired.
+				http.Redirect(w, r, "/auth/login", http.StatusFound)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="actionsum", error="invalid_token"`)
+			http.Error(w, "Invalid API token", http.StatusUnauthorized)
+			return
+		}
+		if !auth.Allows(token.ScopeList(), scope) {
+			http.Error(w, "API token lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+
+		now := time.Now()
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > touchInterval {
+			if err := h.repo.TouchToken(token.ID, now); err != nil {
+				log.Printf("Failed to update token %s: %v", token.Name, err)
+			}
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)))
+	}
+}
+
+// readWrite requires the read scope for GET requests and the write scope for
+// anything else.
+func (h *Handler) readWrite(next http.HandlerFunc) http.HandlerFunc {
+	read := h.requireScope(auth.ScopeRead, next)
+	write := h.requireScope(auth.ScopeWrite, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isRead(r) || r.Method == http.MethodOptions {
+			read(w, r)
+			return
+		}
+		write(w, r)
+	}
+}
+
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// jsonBody reports whether r has no body or a JSON one. Forms and plain
+// text are what pages on other sites can send without asking first.
+func jsonBody(r *http.Request) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// canWrite reports whether r presented a token allowed to make changes.
+func canWrite(r *http.Request) bool {
+	token, ok := r.Context().Value(tokenKey{}).(*models.APIToken)
+	return ok && auth.Allows(token.ScopeList(), auth.ScopeWrite)
+}
+
+// requestUser returns whose data a request reads and writes: the user of
+// the token it presents in multi-user mode, otherwise this machine's own
+// user, "".
+func (h *Handler) requestUser(r *http.Request) string {
+	if !h.config.Web.MultiUser {
+		return ""
+	}
+	if token, ok := r.Context().Value(tokenKey{}).(*models.APIToken); ok {
+		return token.UserID
+	}
+	return ""
+}
+
+// ownerOnly keeps requests acting for other users of a multi-user server
+// away from next, which reports on or controls this machine's tracker.
+func (h *Handler) ownerOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.requestUser(r) != "" {
+			http.Error(w, "Only available for the activity of this machine", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requestToken returns the token from the Authorization header, or from an
+// access_token query parameter or cookie so the dashboard can be opened with
+// one. A token given as a parameter is kept in a cookie for the requests the
+// page makes afterwards.
+func requestToken(w http.ResponseWriter, r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	if token := r.URL.Query().Get("access_token"); token != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     "access_token",
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		return token
+	}
+	if cookie, err := r.Cookie("access_token"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// requestActor names who made a request for the audit log: the token used,
+// or the client address.
+func requestActor(r *http.Request) string {
+	if token, ok := r.Context().Value(tokenKey{}).(*models.APIToken); ok {
+		return "token:" + token.Name
+	}
+	return "api:" + r.RemoteAddr
+}
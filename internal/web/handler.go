@@ -1,47 +1,185 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/actionsum/actionsum/internal/analytics"
+	"github.com/actionsum/actionsum/internal/auth"
 	"github.com/actionsum/actionsum/internal/config"
 	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/doctor"
+	"github.com/actionsum/actionsum/internal/editor"
+	"github.com/actionsum/actionsum/internal/gaps"
+	"github.com/actionsum/actionsum/internal/locale"
 	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notes"
+	"github.com/actionsum/actionsum/internal/oidc"
+	"github.com/actionsum/actionsum/internal/pause"
+	"github.com/actionsum/actionsum/internal/profile"
+	"github.com/actionsum/actionsum/internal/remoteterm"
 	"github.com/actionsum/actionsum/internal/reporter"
+	"github.com/actionsum/actionsum/internal/tag"
+	"github.com/actionsum/actionsum/internal/tracker"
 	"github.com/actionsum/actionsum/pkg/utils"
 )
 
+// maxIngestBytes bounds the body of an event submission.
+const maxIngestBytes = 4 << 20
+
 type Handler struct {
 	config   *config.Config
 	repo     *database.Repository
 	reporter *reporter.Reporter
+	snapshot *database.Snapshot
+	locale   *locale.Locale
+	history  *tracker.History
+	live     *tracker.Live
+
+	// oidc signs users in when ACTIONSUM_OIDC_ISSUER is set.
+	oidc *oidc.Provider
+
+	// done is closed when the server shuts down, ending open streams.
+	done chan struct{}
 }
 
 func NewHandler(cfg *config.Config, repo *database.Repository) *Handler {
-	return &Handler{
+	h := &Handler{
 		config:   cfg,
 		repo:     repo,
 		reporter: reporter.New(cfg, repo),
+		locale:   locale.New(cfg.Report.Locale),
+		done:     make(chan struct{}),
+	}
+	if cfg.Web.OIDC.Issuer != "" {
+		h.oidc = oidc.New(cfg.Web.OIDC)
+	}
+	return h
+}
+
+// localeFor picks the dashboard language: a lang query parameter (remembered
+// in a cookie for the fragments the page loads), then ACTIONSUM_LOCALE, then
+// the browser's Accept-Language header.
+func (h *Handler) localeFor(w http.ResponseWriter, r *http.Request) *locale.Locale {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		http.SetCookie(w, &http.Cookie{Name: "lang", Value: lang, Path: "/", SameSite: http.SameSiteLaxMode})
+		return locale.New(lang)
+	}
+	if cookie, err := r.Cookie("lang"); err == nil && cookie.Value != "" {
+		return locale.New(cookie.Value)
+	}
+	if h.config.Report.Locale != "" {
+		return h.locale
+	}
+	return locale.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// readRepo returns the repository report queries run against: the latest
+// snapshot when one is in use, otherwise the live database. Responses served
+// from a snapshot say how old it is in X-Snapshot-Taken-At and
+// X-Snapshot-Age (seconds). Either way it holds the requesting user's data.
+func (h *Handler) readRepo(w http.ResponseWriter, r *http.Request) *database.Repository {
+	repo := h.repo
+	if h.snapshot != nil {
+		if snapshot, takenAt := h.snapshot.Current(); snapshot != nil {
+			w.Header().Set("X-Snapshot-Taken-At", takenAt.Format(time.RFC3339))
+			w.Header().Set("X-Snapshot-Age", strconv.Itoa(int(time.Since(takenAt).Seconds())))
+			repo = snapshot
+		}
+	}
+	if user := h.requestUser(r); user != "" {
+		return repo.ForUser(user)
+	}
+	return repo
+}
+
+func (h *Handler) readReporter(w http.ResponseWriter, r *http.Request) *reporter.Reporter {
+	repo := h.readRepo(w, r)
+	if repo == h.repo {
+		return h.reporter
+	}
+	return reporter.New(h.config, repo)
+}
+
+// userRepo returns the live repository for the requesting user's data, for
+// writes and for reads that must not come from a snapshot.
+func (h *Handler) userRepo(r *http.Request) *database.Repository {
+	if user := h.requestUser(r); user != "" {
+		return h.repo.ForUser(user)
+	}
+	return h.repo
+}
+
+// audit records a change made through the API, attributed to the token used
+// or the client address.
+func (h *Handler) audit(r *http.Request, action, summary string) {
+	entry := &models.AuditEntry{Actor: requestActor(r), Action: action, Summary: summary}
+	if err := h.repo.RecordAudit(entry); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
 	}
 }
 
 func (h *Handler) SetupRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/events", h.handleEvents)
-	mux.HandleFunc("/api/events/latest", h.handleLatestEvent)
-	mux.HandleFunc("/api/report", h.handleReport)
-	mux.HandleFunc("/api/summary", h.handleSummary)
-	mux.HandleFunc("/api/status", h.handleStatus)
+	read := func(next http.HandlerFunc) http.HandlerFunc {
+		return h.requireScope(auth.ScopeRead, next)
+	}
+
+	mux.HandleFunc("/api/events", h.readWrite(h.handleEvents))
+	mux.HandleFunc("/api/events/latest", read(h.handleLatestEvent))
+	mux.HandleFunc("/api/recent", read(h.handleRecent))
+	mux.HandleFunc("/api/apps/{name}", read(h.handleApp))
+	mux.HandleFunc("/api/apps/{name}/icon", read(h.handleAppIcon))
+	mux.HandleFunc("/api/search", read(h.handleSearch))
+	mux.HandleFunc("/api/stream", read(h.ownerOnly(h.handleStream)))
+	mux.HandleFunc("/api/events/{uuid}", read(h.handleEvent))
+	mux.HandleFunc("/api/report", read(h.handleReport))
+	mux.HandleFunc("/api/share", read(h.handleShare))
+	mux.HandleFunc("/api/summary", read(h.handleSummary))
+	mux.HandleFunc("/api/status", read(h.handleStatus))
+	mux.HandleFunc("/api/profiles", read(h.handleProfiles))
+	mux.HandleFunc("/api/gaps", read(h.handleGaps))
+	mux.HandleFunc("/api/timeline", read(h.handleTimeline))
+	mux.HandleFunc("/api/focus", read(h.handleFocus))
+	mux.HandleFunc("/api/distractions", read(h.handleDistractions))
+	mux.HandleFunc("/api/notes", h.readWrite(h.handleNotes))
+	mux.HandleFunc("/api/tag", h.readWrite(h.ownerOnly(h.handleTag)))
+	mux.HandleFunc("/api/pause", h.readWrite(h.ownerOnly(h.handlePause)))
+	mux.HandleFunc("/api/terminal", h.readWrite(h.ownerOnly(h.handleTerminal)))
+	mux.HandleFunc("/api/editor", h.readWrite(h.ownerOnly(h.handleEditor)))
+	mux.HandleFunc("/api/diff", read(h.handleDiff))
+	mux.HandleFunc("/api/distribution", read(h.handleDistribution))
+	mux.HandleFunc("/api/team", read(h.handleTeam))
+	mux.HandleFunc("/api/team/sharing", h.readWrite(h.handleTeamSharing))
+	mux.HandleFunc("/api/schema", h.requireScope(auth.ScopeAdmin, h.handleSchema))
+	mux.HandleFunc("/api/tokens", h.readWrite(h.handleTokens))
+
+	mux.HandleFunc("/auth/login", h.handleLogin)
+	mux.HandleFunc("/auth/callback", h.handleCallback)
+	mux.HandleFunc("/auth/logout", h.handleLogout)
 
 	mux.HandleFunc("/health", h.handleHealth)
+	mux.HandleFunc("/static/", h.handleStatic)
+	mux.HandleFunc("/metrics", read(h.handleMetrics))
 
-	mux.HandleFunc("/", h.handleIndex)
+	mux.HandleFunc("/m", read(h.handleMobile))
+	mux.HandleFunc("/apps/{name}", read(h.handleAppPage))
+	mux.HandleFunc("/timeline", read(h.handleTimelinePage))
+	mux.HandleFunc("/", read(h.handleIndex))
 }
 
 func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.ingestEvents(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -50,6 +188,7 @@ func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	limitStr := query.Get("limit")
 	periodType := query.Get("period") // day, week, month
+	profileName := query.Get("profile")
 
 	var events []*models.FocusEvent
 
@@ -59,14 +198,14 @@ func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		events, err = h.repo.GetEventsSince(period.Start)
+		events, err = h.readRepo(w, r).GetEvents(database.Query{Since: period.Start, Profile: profileName})
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to fetch events: %v", err), http.StatusInternalServerError)
 			return
 		}
 	} else {
 		start := time.Now().Add(-24 * time.Hour)
-		allEvents, err := h.repo.GetEventsSince(start)
+		allEvents, err := h.readRepo(w, r).GetEvents(database.Query{Since: start, Profile: profileName})
 		if err == nil {
 			limit := 100 // default
 			if limitStr != "" {
@@ -92,7 +231,7 @@ func (h *Handler) handleLatestEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := h.repo.GetLatest()
+	event, err := h.userRepo(r).GetLatest()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch latest event: %v", err), http.StatusInternalServerError)
 		return
@@ -106,6 +245,99 @@ func (h *Handler) handleLatestEvent(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, event)
 }
 
+// ingestEvents stores events submitted by other trackers, such as a browser
+// extension, as a single JSON event or an array of them. Invalid events are
+// logged to the error log and skipped, and events whose UUID is already
+// stored, typically sent again after a timeout, are counted as duplicates.
+func (h *Handler) ingestEvents(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxIngestBytes))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var events []*models.FocusEvent
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &events)
+	} else {
+		var event models.FocusEvent
+		err = json.Unmarshal(trimmed, &event)
+		events = append(events, &event)
+	}
+	if err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	repo := h.userRepo(r)
+	var uuids []string
+	for _, event := range events {
+		if event.UUID != "" {
+			uuids = append(uuids, event.UUID)
+		}
+	}
+	stored, err := repo.GetEventsByUUID(uuids)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	fresh := make([]*models.FocusEvent, 0, len(events))
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.UUID != "" {
+			if stored[event.UUID] != nil || seen[event.UUID] {
+				continue
+			}
+			seen[event.UUID] = true
+		}
+		event.ID = 0
+		if event.DisplayServer == "" {
+			event.DisplayServer = "api"
+		}
+		fresh = append(fresh, event)
+	}
+	if err := repo.CreateBatch(fresh); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to store events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var created []string
+	for _, event := range fresh {
+		if event.ID != 0 {
+			created = append(created, event.UUID)
+		}
+	}
+	h.audit(r, "events.create", fmt.Sprintf("ingested %d of %d events", len(created), len(events)))
+	respondJSONStatus(w, http.StatusCreated, map[string]interface{}{
+		"created":    len(created),
+		"duplicates": len(events) - len(fresh),
+		"rejected":   len(fresh) - len(created),
+		"uuids":      created,
+	})
+}
+
+// handleEvent looks an event up by the UUID external systems refer to it by.
+func (h *Handler) handleEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	event, err := h.userRepo(r).GetByUUID(r.PathValue("uuid"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch event: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if event == nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, event)
+}
+
 func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -117,15 +349,400 @@ func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
 		periodType = "day"
 	}
 
-	report, err := h.reporter.GenerateReport(periodType)
+	report, err := h.readReporter(w, r).GenerateProfileReport(periodType, r.URL.Query().Get("profile"))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to generate report: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if err := reporter.SortApps(report.Apps, r.URL.Query().Get("sort")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handler) handleGaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+
+	minGap := gaps.DefaultMinGap
+	if minStr := r.URL.Query().Get("min"); minStr != "" {
+		d, err := time.ParseDuration(minStr)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid min duration", http.StatusBadRequest)
+			return
+		}
+		minGap = d
+	}
+
+	report, err := h.readReporter(w, r).GenerateGapReport(periodType, minGap)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to find gaps: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handler) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	periodType := query.Get("period")
+	if periodType == "" {
+		periodType = "day"
+	}
+
+	var segments []models.TimelineSegment
+	var err error
+	if dateStr := query.Get("date"); dateStr != "" {
+		day, parseErr := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if parseErr != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		segments, err = h.readReporter(w, r).GenerateDayTimeline(day, query.Get("profile"))
+	} else {
+		segments, err = h.readReporter(w, r).GenerateTimeline(periodType, query.Get("profile"))
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build timeline: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		at, _ := time.Parse(time.RFC3339, query.Get("at"))
+		h.respondTimelineHTML(w, h.localeFor(w, r), segments, at)
+		return
+	}
+
+	respondJSON(w, segments)
+}
+
+func (h *Handler) handleDistribution(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+
+	report, err := h.readReporter(w, r).GenerateDistributionReport(periodType, r.URL.Query().Get("profile"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute distributions: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	respondJSON(w, report)
 }
 
+func (h *Handler) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+	baseType := r.URL.Query().Get("base")
+	if baseType == "" {
+		baseType = reporter.PreviousPeriod(periodType)
+	}
+
+	diff, err := h.readReporter(w, r).GenerateDiff(periodType, baseType, r.URL.Query().Get("profile"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		csvStr, err := h.reporter.FormatDiffCSV(diff)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Write([]byte(csvStr))
+		return
+	}
+
+	respondJSON(w, diff)
+}
+
+type tagRequest struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// handleTag reports, sets (POST) or clears (DELETE) the tag applied to
+// activity tracked over the next few minutes.
+func (h *Handler) handleTag(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req tagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		duration := h.config.Tags.DefaultDuration
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				http.Error(w, "Invalid duration", http.StatusBadRequest)
+				return
+			}
+			duration = d
+		}
+
+		until := time.Now().Add(duration)
+		if err := tag.Set(h.config.Tags.StateFile, req.Name, until); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.audit(r, "tag.set", fmt.Sprintf("tagged activity as %s until %s", req.Name, until.Format(time.RFC3339)))
+	case http.MethodDelete:
+		if err := tag.Clear(h.config.Tags.StateFile); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, "tag.clear", "cleared tag")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, until := tag.Active(h.config.Tags.StateFile, time.Now())
+	response := map[string]interface{}{"tag": name}
+	if name != "" {
+		response["until"] = until
+	}
+	respondJSON(w, response)
+}
+
+// handleTerminal takes what a terminal on another machine runs, from its
+// remote agent or shell hook, for the tracker to put on events of the local
+// terminal connected to it. Reports arrive every few seconds, so they are
+// not audited.
+func (h *Handler) handleTerminal(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var activity remoteterm.Activity
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxIngestBytes)).Decode(&activity); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := activity.Clean(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The remote clock may be off; what counts is when it arrived.
+		activity.UpdatedAt = time.Now()
+		if err := remoteterm.Record(h.config.Tracker.RemoteTerminalFile, &activity); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	activity, _ := remoteterm.Current(h.config.Tracker.RemoteTerminalFile, time.Now())
+	respondJSON(w, map[string]interface{}{"activity": activity})
+}
+
+// handleEditor takes the project and file an editor plugin reports, for
+// the tracker to put on the editor's events. Like terminal reports, they
+// arrive too often to be audited.
+func (h *Handler) handleEditor(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var report editor.Context
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxIngestBytes)).Decode(&report); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := report.Clean(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report.UpdatedAt = time.Now()
+		if err := editor.Record(h.config.Tracker.EditorFile, &report); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current, _ := editor.Current(h.config.Tracker.EditorFile, time.Now())
+	respondJSON(w, map[string]interface{}{"context": current})
+}
+
+// noteRequest accepts either explicit start/end timestamps or a date with
+// clock times, as on the command line.
+type noteRequest struct {
+	Text  string    `json:"text"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Date  string    `json:"date"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+}
+
+func (h *Handler) handleNotes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		periodType := r.URL.Query().Get("period")
+		if periodType == "" {
+			periodType = "day"
+		}
+
+		period, err := h.getPeriod(periodType)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		found, err := h.readRepo(w, r).GetNotes(period.Start, period.End)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get notes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, found)
+	case http.MethodPost:
+		var req noteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		note := &models.Note{Text: req.Text, Start: req.Start, End: req.End}
+		if req.Start.IsZero() {
+			start, end, err := notes.ParseRange(req.Date, req.From, req.To, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			note.Start, note.End = start, end
+		}
+
+		if err := h.userRepo(r).CreateNote(note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.audit(r, "note.create", fmt.Sprintf("note #%d: %s", note.ID, note.Text))
+		respondJSONStatus(w, http.StatusCreated, note)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleDistractions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "day"
+	}
+
+	report, err := h.readReporter(w, r).GenerateDistractionReport(periodType, r.URL.Query().Get("profile"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to analyze distractions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handler) handleFocus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "day"
+	}
+
+	period, err := h.getPeriod(periodType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := analytics.New(h.config, h.readRepo(w, r)).FocusStats(period.Start, period.End, time.Now(), r.URL.Query().Get("profile"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute focus stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		h.respondFocusHTML(w, h.localeFor(w, r), stats)
+		return
+	}
+
+	respondJSON(w, stats)
+}
+
+func (h *Handler) respondFocusHTML(w http.ResponseWriter, loc *locale.Locale, stats *models.FocusStats) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	goal := loc.T("not yet")
+	if stats.GoalMetToday {
+		goal = loc.T("met")
+	}
+
+	out := `<div class="focus-stats">`
+	out += fmt.Sprintf(`<div class="streak"><span class="streak-days">%d</span> %s</div>`,
+		stats.CurrentStreak, html.EscapeString(loc.T("day streak")))
+	out += fmt.Sprintf(`<div class="app-item"><span class="app-name">%s</span><span class="app-time">%s</span></div>`,
+		html.EscapeString(loc.T("Longest streak")), html.EscapeString(loc.T("%d days", stats.LongestStreak)))
+	out += fmt.Sprintf(`<div class="app-item"><span class="app-name">%s</span><span class="app-time">%s, %s</span></div>`,
+		html.EscapeString(loc.T("Daily goal (%s)", utils.FormatHoursMinutes(stats.GoalSeconds))),
+		utils.FormatHoursMinutes(stats.TodaySeconds), html.EscapeString(goal))
+	if stats.LongestSession != nil {
+		out += fmt.Sprintf(`<div class="app-item"><span class="app-name">%s</span><span class="app-time">%s</span></div>`,
+			html.EscapeString(loc.T("Longest session")),
+			html.EscapeString(loc.T("%s in %s", utils.FormatHoursMinutes(stats.LongestSession.Seconds), stats.LongestSession.AppName)))
+	}
+	out += `</div><div class="achievements">`
+	for _, a := range stats.Achievements {
+		class := "achievement"
+		if a.Unlocked {
+			class += " unlocked"
+		}
+		out += fmt.Sprintf(`<span class="%s" title="%s">%s</span>`, class, html.EscapeString(a.Description), html.EscapeString(a.Name))
+	}
+	out += `</div>`
+
+	w.Write([]byte(out))
+}
+
 func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -143,11 +760,28 @@ func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summaries, err := h.repo.GetAppSummarySince(period.Start)
+	order := r.URL.Query().Get("sort")
+	if order != "" && !slices.Contains(reporter.SortOrders, order) {
+		http.Error(w, fmt.Sprintf("Unknown sort order %q", order), http.StatusBadRequest)
+		return
+	}
+
+	q := database.Query{
+		Since:   period.Start,
+		Profile: r.URL.Query().Get("profile"),
+	}
+	summaries, err := h.readRepo(w, r).GetAppSummary(q)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get summary: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if order == reporter.SortSessions || order == reporter.SortRecent {
+		if err := h.readReporter(w, r).AnnotateApps(summaries, q); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get summary: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	reporter.SortApps(summaries, order)
 
 	var totalSeconds int64
 	for i := range summaries {
@@ -163,56 +797,56 @@ func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Header.Get("HX-Request") == "true" {
-		h.respondSummaryHTML(w, summaries, totalSeconds)
+		h.respondSummaryHTML(w, h.localeFor(w, r), summaries, totalSeconds)
 		return
 	}
 
 	response := map[string]interface{}{
-		"period":        period,
-		"apps":          summaries,
-		"total_seconds": totalSeconds,
-		"total_minutes": float64(totalSeconds) / 60.0,
-		"total_hours":   float64(totalSeconds) / 3600.0,
+		"schema_version": models.ReportSchemaVersion,
+		"period":         period,
+		"apps":           summaries,
+		"total_seconds":  totalSeconds,
+		"total_minutes":  float64(totalSeconds) / 60.0,
+		"total_hours":    float64(totalSeconds) / 3600.0,
 	}
 
 	respondJSON(w, response)
 }
 
-func (h *Handler) respondSummaryHTML(w http.ResponseWriter, summaries []models.AppSummary, totalSeconds int64) {
+func (h *Handler) respondSummaryHTML(w http.ResponseWriter, loc *locale.Locale, summaries []models.AppSummary, totalSeconds int64) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	if len(summaries) == 0 {
-		w.Write([]byte(`<div class="loading">No data available</div>`))
+		w.Write([]byte(`<div class="loading">` + html.EscapeString(loc.T("No data available")) + `</div>`))
 		return
 	}
+	total := html.EscapeString(loc.T("Total: %s", utils.FormatRoundedUnit(totalSeconds)))
 
-	html := `<div class="listing">`
+	out := `<div class="listing" role="list" aria-label="` + html.EscapeString(loc.T("Time per app")) + `">`
 	for _, app := range summaries {
 		timeStr := utils.FormatRoundedUnit(app.TotalSeconds)
 
-		percentStr := fmt.Sprintf("%.1f%%", app.Percentage)
+		percentStr := loc.Percent(app.Percentage, 1)
 		if app.Percentage < 10 {
 			percentStr = "&nbsp;&nbsp;" + percentStr
 		} else if app.Percentage < 100 {
 			percentStr = "&nbsp;" + percentStr
 		}
 
-		html += fmt.Sprintf(`
-		<div class="app-item" style="--bar-width: %.1f%%">
-			<span class="app-name">%s</span>
+		out += fmt.Sprintf(`
+		<div class="app-item" role="listitem" style="--bar-width: %.1f%%">
+			<a class="app-name" href="%s"><img class="app-icon" src="%s" alt="" loading="lazy" onerror="this.remove()">%s</a>
 			<div>
 				<span class="app-time">%s</span>
 				<span class="app-percentage">%s</span>
 			</div>
-		</div>`, app.Percentage, app.AppName, timeStr, percentStr)
+		</div>`, app.Percentage, html.EscapeString(appURL(app.AppName)), html.EscapeString(appIconURL(app.AppName)), html.EscapeString(app.AppName), timeStr, percentStr)
 	}
-	html += `</div>`
-
-	totalStr := utils.FormatRoundedUnit(totalSeconds)
+	out += `</div>`
 
-	html += fmt.Sprintf(`<div class="total">Total: %s</div>`, totalStr)
+	out += `<div class="total">` + total + `</div>`
 
-	w.Write([]byte(html))
+	w.Write([]byte(out))
 }
 
 func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
@@ -221,13 +855,18 @@ func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	latestEvent, _ := h.repo.GetLatest()
+	latestEvent, _ := h.userRepo(r).GetLatest()
 
 	status := map[string]interface{}{
-		"running":       true,
-		"poll_interval": h.config.Tracker.PollInterval.String(),
-		"database_path": h.config.Database.Path,
-		"exclude_idle":  h.config.Report.ExcludeIdle,
+		"running": true,
+	}
+	if summaries, err := h.userRepo(r).GetDetectionSummary(database.Query{Since: time.Now().AddDate(0, 0, -7)}); err == nil {
+		status["detection"] = summaries
+	}
+	// The database, tracker and backends are this machine's; other users
+	// of a multi-user server only see their own events.
+	if h.requestUser(r) == "" {
+		h.addHostStatus(status)
 	}
 
 	if latestEvent != nil {
@@ -242,7 +881,98 @@ func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, status)
 }
 
+// addHostStatus adds the state of this machine's tracker and database to
+// status.
+func (h *Handler) addHostStatus(status map[string]interface{}) {
+	status["poll_interval"] = h.config.Tracker.PollInterval.String()
+	status["database_path"] = h.config.Database.Path
+	status["exclude_idle"] = h.config.Report.ExcludeIdle
+
+	if size, err := database.FileSizes(h.config.Database.Path); err == nil {
+		status["database_size"] = size
+		if warning := size.Warning(h.config.Database.SizeWarning); warning != "" {
+			status["database_warning"] = warning
+		}
+	}
+
+	if h.snapshot != nil {
+		if repo, takenAt := h.snapshot.Current(); repo != nil {
+			status["snapshot_taken_at"] = takenAt
+		}
+	}
+
+	activeProfile, source := profile.Resolve(h.config, time.Now())
+	status["profile"] = activeProfile
+	status["profile_source"] = source
+
+	if paused, until := pause.Active(h.config.Tracker.PauseFile, time.Now()); paused {
+		status["paused"] = true
+		if !until.IsZero() {
+			status["paused_until"] = until
+		}
+	}
+
+	if stats, err := doctor.CurrentStats(h.config); err == nil {
+		status["backends"] = stats.Backends
+		if len(stats.Limitations) > 0 {
+			status["limitations"] = stats.Limitations
+		}
+		if stats.Overhead != nil {
+			status["overhead"] = stats.Overhead
+		}
+	}
+}
+
+func (h *Handler) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profiles, err := h.readRepo(w, r).GetProfiles()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get profiles: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		options := `<option value="">` + html.EscapeString(h.localeFor(w, r).T("All profiles")) + `</option>`
+		for _, name := range profiles {
+			escaped := html.EscapeString(name)
+			options += fmt.Sprintf(`<option value="%s">%s</option>`, escaped, escaped)
+		}
+		w.Write([]byte(options))
+		return
+	}
+
+	respondJSON(w, profiles)
+}
+
+// handleSchema describes the database tables and indexes as JSON, or as SQL
+// with format=sql.
+func (h *Handler) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	schema, err := h.repo.Schema()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read schema: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "sql" {
+		w.Header().Set("Content-Type", "application/sql; charset=utf-8")
+		w.Write([]byte(schema.SQL()))
+		return
+	}
+	respondJSON(w, schema)
+}
+
 func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
 	respondJSON(w, map[string]string{
 		"status": "healthy",
 		"time":   time.Now().Format(time.RFC3339),
@@ -255,314 +985,104 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	loc := h.localeFor(w, r)
+	t := func(msg string) string {
+		return html.EscapeString(loc.T(msg))
+	}
+	signOut := ""
+	if h.oidc != nil && h.requestUser(r) != "" {
+		signOut = `
+            <a class="header-btn" href="/auth/logout" title="` + html.EscapeString(h.requestUser(r)) + `">` + t("Sign out") + `</a>`
+	}
+
 	html := `<!DOCTYPE html>
-<html lang="en">
+<html lang="` + loc.Tag() + `">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Actionsum Dashboard</title>
-    <script src="https://unpkg.com/htmx.org@1.9.10"></script>
-    <style>
-        * {
-            margin: 0;
-            padding: 0;
-            box-sizing: border-box;
-        }
-        
-        :root {
-            --bg-primary: #f5f5f5;
-            --bg-secondary: white;
-            --text-primary: #333;
-            --text-secondary: #1a1a1a;
-            --text-muted: #7f8c8d;
-            --border-color: #eee;
-            --border-strong: #ecf0f1;
-            --accent-color: #3498db;
-            --heading-color: #2c3e50;
-            --shadow: rgba(0,0,0,0.1);
-        }
-        
-        [data-theme="dark"] {
-            --bg-primary: #1a1a1a;
-            --bg-secondary: #2d2d2d;
-            --text-primary: #e0e0e0;
-            --text-secondary: #ffffff;
-            --text-muted: #a0a0a0;
-            --border-color: #404040;
-            --border-strong: #4a4a4a;
-            --accent-color: #5dade2;
-            --heading-color: #5dade2;
-            --shadow: rgba(0,0,0,0.3);
-        }
-        
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif;
-            background: var(--bg-primary);
-            padding: 20px;
-            color: var(--text-primary);
-            transition: background-color 0.3s ease, color 0.3s ease;
-        }
-        
-        .header {
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
-            margin-bottom: 30px;
-        }
-        
-        h1 {
-            color: var(--text-secondary);
-            font-size: 2rem;
-            margin: 0;
-        }
-        
-        .header-controls {
-            display: flex;
-            gap: 10px;
-        }
-
-        .header-btn {
-            background: var(--bg-secondary);
-            border: 2px solid var(--border-color);
-            border-radius: 50px;
-            padding: 8px 16px;
-            cursor: pointer;
-            font-size: 1.2rem;
-            transition: all 0.3s ease;
-            display: flex;
-            align-items: center;
-            gap: 8px;
-        }
-
-        .header-btn:hover {
-            border-color: var(--accent-color);
-            transform: scale(1.05);
-        }
-
-        .header-btn.active {
-            border-color: var(--accent-color);
-            background: var(--accent-color);
-        }
-        
-        .dashboard {
-            display: flex;
-            gap: 20px;
-            flex-wrap: wrap;
-        }
-        
-        .report-box {
-            flex: 1;
-            min-width: 300px;
-            background: var(--bg-secondary);
-            border-radius: 8px;
-            box-shadow: 0 2px 4px var(--shadow);
-            padding: 24px;
-            transition: background-color 0.3s ease, box-shadow 0.3s ease;
-        }
-        
-        .report-box h2 {
-            font-size: 1.5rem;
-            margin-bottom: 20px;
-            color: var(--heading-color);
-            border-bottom: 2px solid var(--accent-color);
-            padding-bottom: 10px;
-        }
-        
-        .app-item {
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
-            padding: 12px 8px;
-            border-bottom: 1px solid var(--border-color);
-            position: relative;
-            border-radius: 4px;
-            transition: background 0.3s ease;
-        }
-
-        .app-item::before {
-            content: '';
-            position: absolute;
-            left: 0;
-            top: 0;
-            height: 100%;
-            width: var(--bar-width, 0%);
-            background: var(--accent-color);
-            opacity: 0;
-            transition: opacity 0.3s ease;
-            border-radius: 4px;
-            z-index: 0;
-        }
-
-        [data-bars="true"] .app-item::before {
-            opacity: 0.2;
-        }
-
-        .app-item > * {
-            position: relative;
-            z-index: 1;
-        }
-
-        .app-item:last-child {
-            border-bottom: none;
-        }
-        
-        .app-name {
-            font-weight: 500;
-            color: var(--text-primary);
-        }
-        
-        .app-time {
-            color: var(--text-muted);
-            font-size: 0.9rem;
-        }
-        
-        .app-percentage {
-            color: var(--accent-color);
-            font-weight: 600;
-            margin-left: 10px;
-            display: inline-block;
-            min-width: 5em;
-            text-align: right;
-			margin: 1px
-        }
-        
-        .loading {
-            color: var(--text-muted);
-            font-style: italic;
-        }
-        
-        .total {
-            margin-top: 20px;
-            padding-top: 15px;
-            border-top: 2px solid var(--border-strong);
-            font-weight: 600;
-            font-size: 1.1rem;
-            color: var(--heading-color);
-        }
-
-        .listing {
-            overflow-y: auto;
-            overflow-x: hidden;
-            max-height: calc(100vh - 320px);
-            scrollbar-width: thin;
-            scrollbar-color: var(--accent-color) var(--bg-secondary);
-        }
-
-        .listing::-webkit-scrollbar {
-            width: 10px;
-        }
-
-        .listing::-webkit-scrollbar-track {
-            background: var(--border-color);
-            border-radius: 10px;
-        }
-
-        .listing::-webkit-scrollbar-thumb {
-            background-color: var(--accent-color);
-            border-radius: 10px;
-            border: 2px solid var(--border-color);
-        }
-
-        .listing::-webkit-scrollbar-thumb:hover {
-            background-color: var(--heading-color);
-        }
-
-        @media (max-width: 768px) {
-            .listing {
-                max-height: 450px;
-            }
-        }
-        
-        @media (max-width: 1024px) {
-            .dashboard {
-                flex-direction: column;
-            }
-            
-            .report-box {
-                min-width: 100%;
-            }
-        }
-    </style>
+    <title>` + t("Actionsum Dashboard") + `</title>
+    <script src="` + assetURL("hx.js") + `"></script>
+    <link rel="stylesheet" href="` + assetURL("dashboard.css") + `">
 </head>
 <body>
     <div class="header">
-        <h1>Actionsum Dashboard</h1>
+        <h1>` + t("Actionsum Dashboard") + `</h1>
+        <div id="now" class="now-widget" hidden
+             data-idle="` + t("Idle") + `" data-locked="` + t("Screen locked") + `" data-paused="` + t("Tracking paused") + `"
+             data-off-hours="` + t("Outside work hours") + `" data-unknown="` + t("Not tracking") + `">
+            <span class="now-dot"></span>
+            <span class="now-app"></span>
+            <span class="now-time"></span>
+        </div>
         <div class="header-controls">
-            <button class="header-btn" onclick="toggleBars()" title="Toggle bar chart">
-                <span id="bars-icon">📊</span>
+            <form id="share-form" class="share-form" action="/api/share" method="get">
+                <select class="header-select" name="period" title="` + t("Period to share") + `" aria-label="` + t("Period to share") + `">
+                    <option value="day">` + t("Today") + `</option>
+                    <option value="week" selected>` + t("This Week") + `</option>
+                    <option value="month">` + t("This Month") + `</option>
+                </select>
+                <label class="share-redact" title="` + t("Leave window titles and notes out of the shared report") + `">
+                    <input type="checkbox" name="redact" value="true" checked> ` + t("Hide titles") + `
+                </label>
+                <button type="submit" class="header-btn" title="` + t("Download a report to share") + `">` + t("Share") + `</button>
+            </form>
+            <select id="profile-filter" class="header-select" name="profile" form="share-form" title="` + t("Filter by profile") + `" aria-label="` + t("Filter by profile") + `"
+                    hx-get="/api/profiles" hx-trigger="load" hx-swap="innerHTML">
+                <option value="">` + t("All profiles") + `</option>
+            </select>
+            <button class="header-btn" onclick="toggleBars()" title="` + t("Toggle bar chart") + `" aria-label="` + t("Toggle bar chart") + `" aria-pressed="false">
+                <span id="bars-icon" aria-hidden="true">📊</span>
             </button>
-            <button class="header-btn" onclick="toggleTheme()" title="Toggle theme">
-                <span id="theme-icon">🌙</span>
+            <button class="header-btn" onclick="toggleShortcuts()" title="` + t("Keyboard shortcuts") + `" aria-label="` + t("Keyboard shortcuts") + `">
+                <span aria-hidden="true">⌨️</span>
             </button>
+            <button class="header-btn" onclick="toggleTheme()" title="` + t("Toggle theme") + `" aria-label="` + t("Toggle theme") + `">
+                <span id="theme-icon" aria-hidden="true">🌙</span>
+            </button>` + signOut + `
         </div>
     </div>
+    <form class="search" role="search" hx-get="/api/search" hx-target="#search-results" hx-trigger="submit, input delay:300ms">
+        <input type="search" name="q" class="search-input" placeholder="` + t("Search apps and window titles") + `" aria-label="` + t("Search apps and window titles") + `">
+    </form>
+    <div id="search-results" aria-live="polite"></div>
     <div class="dashboard">
-        <div class="report-box">
-            <h2>Today</h2>
-            <div hx-get="/api/summary?period=today" hx-trigger="load, every 30s" hx-swap="innerHTML">
-                <div class="loading">Loading...</div>
+        <section class="report-box" id="summary-day" tabindex="-1" aria-labelledby="summary-day-title">
+            <h2 id="summary-day-title">` + t("Today") + `</h2>
+            <div hx-get="/api/summary?period=today" hx-include="#profile-filter" hx-trigger="load, every 30s, change from:#profile-filter" hx-swap="innerHTML">
+                <div class="loading">` + t("Loading...") + `</div>
             </div>
-        </div>
+        </section>
+        
+        <section class="report-box" id="summary-week" tabindex="-1" aria-labelledby="summary-week-title">
+            <h2 id="summary-week-title">` + t("This Week") + `</h2>
+            <div hx-get="/api/summary?period=week" hx-include="#profile-filter" hx-trigger="load, every 30s, change from:#profile-filter" hx-swap="innerHTML">
+                <div class="loading">` + t("Loading...") + `</div>
+            </div>
+        </section>
         
+        <section class="report-box" id="summary-month" tabindex="-1" aria-labelledby="summary-month-title">
+            <h2 id="summary-month-title">` + t("This Month") + `</h2>
+            <div hx-get="/api/summary?period=month" hx-include="#profile-filter" hx-trigger="load, every 30s, change from:#profile-filter" hx-swap="innerHTML">
+                <div class="loading">` + t("Loading...") + `</div>
+            </div>
+        </section>
+
         <div class="report-box">
-            <h2>This Week</h2>
-            <div hx-get="/api/summary?period=week" hx-trigger="load, every 30s" hx-swap="innerHTML">
-                <div class="loading">Loading...</div>
+            <h2>` + t("Focus") + `</h2>
+            <div hx-get="/api/focus?period=week" hx-include="#profile-filter" hx-trigger="load, every 60s, change from:#profile-filter" hx-swap="innerHTML">
+                <div class="loading">` + t("Loading...") + `</div>
             </div>
         </div>
-        
+
         <div class="report-box">
-            <h2>This Month</h2>
-            <div hx-get="/api/summary?period=month" hx-trigger="load, every 30s" hx-swap="innerHTML">
-                <div class="loading">Loading...</div>
+            <h2>` + t("Recent Activity") + `</h2>
+            <div hx-get="/api/recent?limit=10" hx-trigger="load, every 10s" hx-swap="innerHTML">
+                <div class="loading">` + t("Loading...") + `</div>
             </div>
         </div>
     </div>
-    <script>
-        function initTheme() {
-            const savedTheme = localStorage.getItem('theme');
-            const prefersDark = window.matchMedia('(prefers-color-scheme: dark)').matches;
-            const theme = savedTheme || (prefersDark ? 'dark' : 'light');
-            setTheme(theme);
-        }
-        
-        function setTheme(theme) {
-            document.documentElement.setAttribute('data-theme', theme);
-            document.getElementById('theme-icon').textContent = theme === 'dark' ? '☀️' : '🌙';
-            localStorage.setItem('theme', theme);
-        }
-        
-        function toggleTheme() {
-            const currentTheme = document.documentElement.getAttribute('data-theme');
-            const newTheme = currentTheme === 'dark' ? 'light' : 'dark';
-            setTheme(newTheme);
-        }
-        
-        function initBars() {
-            const savedBars = localStorage.getItem('bars');
-            const showBars = savedBars === 'true';
-            setBars(showBars);
-        }
-
-        function setBars(show) {
-            document.documentElement.setAttribute('data-bars', show);
-            const btn = document.querySelector('button[onclick="toggleBars()"]');
-            if (show) {
-                btn.classList.add('active');
-            } else {
-                btn.classList.remove('active');
-            }
-            localStorage.setItem('bars', show);
-        }
-
-        function toggleBars() {
-            const current = document.documentElement.getAttribute('data-bars') === 'true';
-            setBars(!current);
-        }
-
-        initTheme();
-        initBars();
-    </script>
+    ` + shortcutsDialog(loc) + `
+    <script src="` + assetURL("dashboard.js") + `"></script>
 </body>
 </html>`
 
@@ -570,6 +1090,31 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
+// shortcutsDialog lists the dashboard's keyboard shortcuts, shown with "?".
+func shortcutsDialog(loc *locale.Locale) string {
+	shortcuts := []struct{ key, action string }{
+		{"/", "Search"},
+		{"d", "Today"},
+		{"w", "This Week"},
+		{"m", "This Month"},
+		{"b", "Toggle bar chart"},
+		{"t", "Toggle theme"},
+		{"?", "Keyboard shortcuts"},
+		{"Esc", "Close"},
+	}
+
+	out := `<dialog id="shortcuts" class="shortcuts" aria-labelledby="shortcuts-title">
+        <h2 id="shortcuts-title">` + html.EscapeString(loc.T("Keyboard shortcuts")) + `</h2>
+        <dl>`
+	for _, shortcut := range shortcuts {
+		out += `<dt><kbd>` + html.EscapeString(shortcut.key) + `</kbd></dt><dd>` + html.EscapeString(loc.T(shortcut.action)) + `</dd>`
+	}
+	out += `</dl>
+        <form method="dialog"><button class="header-btn">` + html.EscapeString(loc.T("Close")) + `</button></form>
+    </dialog>`
+	return out
+}
+
 func (h *Handler) getPeriod(periodType string) (*models.ReportPeriod, error) {
 	now := time.Now()
 	var start, end time.Time
@@ -599,11 +1144,21 @@ func (h *Handler) getPeriod(periodType string) (*models.ReportPeriod, error) {
 	}, nil
 }
 
-func respondJSON(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+// setCORSHeaders lets pages elsewhere read the API, as dashboards and
+// widgets do. Changes aren't offered cross-origin.
+func setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+}
+
+func respondJSON(w http.ResponseWriter, data interface{}) {
+	respondJSONStatus(w, http.StatusOK, data)
+}
+
+func respondJSONStatus(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		log.Printf("Error encoding JSON: %v", err)
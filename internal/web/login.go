@@ -0,0 +1,269 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/auth"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/oidc"
+)
+
+// flowCookie carries a sign-in's state, nonce and PKCE verifier while the
+// browser is at the provider.
+const flowCookie = "oidc_flow"
+
+// flowTimeout is how long a sign-in may take at the provider.
+const flowTimeout = 10 * time.Minute
+
+// secureCookies reports whether cookies should be limited to HTTPS: when the
+// provider returns browsers to an https URL, the server is behind TLS.
+func (h *Handler) secureCookies() bool {
+	return strings.HasPrefix(h.config.Web.OIDC.RedirectURL, "https://")
+}
+
+// wantsSignIn reports whether a request without a usable token is a browser
+// opening a page, which is better sent to sign in than refused. API clients
+// presenting a token in the Authorization header are refused as before.
+func (h *Handler) wantsSignIn(r *http.Request) bool {
+	return h.oidc != nil && r.Method == http.MethodGet && r.Header.Get("Authorization") == "" &&
+		r.Header.Get("HX-Request") != "true" && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// handleLogin sends the browser to the OIDC provider to sign in.
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		http.Error(w, "Sign-in is not configured", http.StatusNotFound)
+		return
+	}
+
+	flow, err := oidc.NewFlow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	target, err := h.oidc.AuthURL(r.Context(), flow)
+	if err != nil {
+		log.Printf("OIDC sign-in failed: %v", err)
+		http.Error(w, "The sign-in provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	// Lax, not Strict: the provider's redirect back is a cross-site
+	// navigation.
+	http.SetCookie(w, &http.Cookie{
+		Name:     flowCookie,
+		Value:    flow.Encode(),
+		Path:     "/auth/",
+		MaxAge:   int(flowTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleCallback completes a sign-in: it redeems the provider's code and
+// starts a session, a token for the user that expires, kept in the cookie
+// requireScope reads.
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		http.Error(w, "Sign-in is not configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		http.Error(w, fmt.Sprintf("Sign-in failed: %s %s", errCode, query.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	cookie, err := r.Cookie(flowCookie)
+	if err != nil {
+		http.Error(w, "Sign-in expired; start again at /auth/login", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: flowCookie, Path: "/auth/", MaxAge: -1})
+	flow, err := oidc.DecodeFlow(cookie.Value)
+	if err != nil || query.Get("state") != flow.State {
+		http.Error(w, "Sign-in state does not match; start again at /auth/login", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.oidc.Exchange(r.Context(), query.Get("code"), flow)
+	if err != nil {
+		log.Printf("OIDC sign-in failed: %v", err)
+		http.Error(w, "Sign-in failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	user, err := h.oidc.UserID(claims)
+	if err == nil {
+		err = auth.CheckUserID(user)
+	}
+	if err != nil {
+		http.Error(w, "Sign-in failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	if !h.oidc.Allowed(user) {
+		http.Error(w, user+" may not sign in to this server", http.StatusForbidden)
+		return
+	}
+
+	if _, err := h.repo.DeleteExpiredTokens(time.Now()); err != nil {
+		log.Printf("Failed to delete expired sessions: %v", err)
+	}
+	plain, hash, err := auth.Generate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	expires := time.Now().Add(h.oidc.SessionLength())
+	session := &models.APIToken{
+		Name:      "session:" + user + ":" + hash[:8],
+		Hash:      hash,
+		Scopes:    auth.ScopeRead + "," + auth.ScopeWrite,
+		UserID:    user,
+		ExpiresAt: &expires,
+	}
+	if err := h.repo.CreateToken(session); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to start session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, "auth.login", user+" signed in")
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "access_token",
+		Value:    plain,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   h.secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// handleLogout ends the session in the request's cookie.
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("access_token"); err == nil {
+		token, err := h.repo.GetTokenByHash(auth.Hash(cookie.Value))
+		if err != nil {
+			http.Error(w, "Failed to check session", http.StatusInternalServerError)
+			return
+		}
+		// Only sessions end here; a long-lived token in the cookie is
+		// just forgotten by the browser.
+		if token != nil && token.ExpiresAt != nil {
+			if _, err := h.repo.RevokeToken(token.UserID, token.Name); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to end session: %v", err), http.StatusInternalServerError)
+				return
+			}
+			h.audit(r, "auth.logout", token.UserID+" signed out")
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: "access_token", Path: "/", MaxAge: -1})
+	if h.oidc != nil {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+type tokenRequest struct {
+	Name   string `json:"name"`
+	Scopes string `json:"scopes"`
+}
+
+// handleTokens lets the users of a multi-user server manage their own API
+// tokens, such as one for the agent on each of their machines: GET lists
+// them, POST creates one and DELETE ?name= revokes one. New tokens act for
+// the same user and grant no more than the token or session creating them.
+func (h *Handler) handleTokens(w http.ResponseWriter, r *http.Request) {
+	user := h.requestUser(r)
+	caller, _ := r.Context().Value(tokenKey{}).(*models.APIToken)
+	if user == "" || caller == nil {
+		http.Error(w, "Tokens of this machine's user are managed with \"actionsum token\"", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.repo.GetTokens()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list tokens: %v", err), http.StatusInternalServerError)
+			return
+		}
+		own := []*models.APIToken{}
+		for _, token := range tokens {
+			if token.UserID == user && (token.ExpiresAt == nil || token.ExpiresAt.After(time.Now())) {
+				own = append(own, token)
+			}
+		}
+		respondJSON(w, own)
+	case http.MethodPost:
+		var req tokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		if req.Scopes == "" {
+			req.Scopes = auth.ScopeRead
+		}
+		scopes, err := auth.ParseScopes(req.Scopes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, scope := range strings.Split(scopes, ",") {
+			if !auth.Allows(caller.ScopeList(), scope) {
+				http.Error(w, "Cannot grant the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+		}
+
+		plain, hash, err := auth.Generate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		token := &models.APIToken{Name: req.Name, Hash: hash, Scopes: scopes, UserID: user}
+		if err := h.repo.CreateToken(token); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.audit(r, "token.create", fmt.Sprintf("created token %s with scopes %s for user %s", token.Name, scopes, user))
+		respondJSONStatus(w, http.StatusCreated, map[string]interface{}{
+			"token":  plain,
+			"name":   token.Name,
+			"scopes": scopes,
+		})
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		tokens, err := h.repo.GetTokens()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list tokens: %v", err), http.StatusInternalServerError)
+			return
+		}
+		idx := slices.IndexFunc(tokens, func(t *models.APIToken) bool { return t.Name == name && t.UserID == user })
+		if idx < 0 {
+			http.Error(w, "No token of yours named "+name, http.StatusNotFound)
+			return
+		}
+		if _, err := h.repo.RevokeToken(user, name); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to revoke token: %v", err), http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, "token.revoke", "revoked token "+name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/actionsum/actionsum/pkg/mdns"
+	"github.com/actionsum/actionsum/version"
+)
+
+// serviceType is the DNS-SD type companion apps browse for.
+const serviceType = "_actionsum._tcp"
+
+// Advertise announces the web API on the local network until ctx is
+// cancelled. It does nothing when the server only listens on loopback, since
+// no other device could reach it.
+func (s *Server) Advertise(ctx context.Context) {
+	host, portStr, err := net.SplitHostPort(s.server.Addr)
+	if err != nil {
+		log.Printf("mDNS: invalid listen address %s: %v", s.server.Addr, err)
+		return
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	ips, err := advertisedIPs(host)
+	if err != nil {
+		log.Printf("mDNS: %v", err)
+		return
+	}
+	if len(ips) == 0 {
+		log.Printf("mDNS: not advertising, web server only listens on %s", host)
+		return
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Printf("mDNS: failed to read host name: %v", err)
+		return
+	}
+	hostname, _, _ = strings.Cut(hostname, ".")
+
+	txt := []string{"path=/", "version=" + version.Version}
+	if s.config.Web.RequireToken {
+		txt = append(txt, "auth=token")
+	}
+
+	service := &mdns.Service{
+		Instance: "actionsum on " + hostname,
+		Type:     serviceType,
+		Host:     hostname,
+		Port:     port,
+		IPs:      ips,
+		TXT:      txt,
+	}
+	log.Printf("mDNS: advertising %s.local on port %d", serviceType, port)
+	if err := service.Run(ctx); err != nil {
+		log.Printf("mDNS: %v", err)
+	}
+}
+
+// advertisedIPs returns the non-loopback IPv4 addresses the server is
+// reachable on when bound to host. A wildcard host means every interface.
+func advertisedIPs(host string) ([]net.IP, error) {
+	var candidates []net.IP
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				candidates = append(candidates, ipNet.IP)
+			}
+		}
+	} else {
+		resolved, err := net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		candidates = resolved
+	}
+
+	var ips []net.IP
+	for _, ip := range candidates {
+		if ip.IsLoopback() || ip.To4() == nil {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/doctor"
+)
+
+// handleMetrics serves the detector lookup counts and the last week's
+// tracked time per detection method in the Prometheus text format.
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	summaries, err := h.readRepo(w, r).GetDetectionSummary(database.Query{Since: time.Now().AddDate(0, 0, -7)})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get detection summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var b strings.Builder
+	if stats, err := doctor.CurrentStats(h.config); err == nil {
+		b.WriteString("# HELP actionsum_detector_lookups_total Focused window lookups per detection backend since the daemon started.\n")
+		b.WriteString("# TYPE actionsum_detector_lookups_total counter\n")
+		for _, name := range stats.BackendNames() {
+			counts := stats.Backends[name]
+			fmt.Fprintf(&b, "actionsum_detector_lookups_total{backend=%q,result=\"success\"} %d\n", name, counts.Successes)
+			fmt.Fprintf(&b, "actionsum_detector_lookups_total{backend=%q,result=\"failure\"} %d\n", name, counts.Failures)
+		}
+		if o := stats.Overhead; o != nil {
+			b.WriteString("# HELP actionsum_daemon_cpu_seconds_total Processor time the daemon has used since it started.\n")
+			b.WriteString("# TYPE actionsum_daemon_cpu_seconds_total counter\n")
+			fmt.Fprintf(&b, "actionsum_daemon_cpu_seconds_total %g\n", o.CPUSeconds)
+			b.WriteString("# HELP actionsum_polls_skipped_total Polls skipped because the previous one overran the poll budget.\n")
+			b.WriteString("# TYPE actionsum_polls_skipped_total counter\n")
+			fmt.Fprintf(&b, "actionsum_polls_skipped_total %d\n", o.SkippedPolls)
+		}
+	}
+	b.WriteString("# HELP actionsum_tracked_seconds Time tracked over the last 7 days per detection method.\n")
+	b.WriteString("# TYPE actionsum_tracked_seconds gauge\n")
+	for _, summary := range summaries {
+		method := summary.Method
+		if method == "" {
+			method = "unrecorded"
+		}
+		fmt.Fprintf(&b, "actionsum_tracked_seconds{method=%q} %d\n", method, summary.TotalSeconds)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
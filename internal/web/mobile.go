@@ -0,0 +1,185 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/locale"
+	"github.com/actionsum/actionsum/internal/pause"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// mobileTopApps is how many apps the mobile page lists.
+const mobileTopApps = 5
+
+type pauseRequest struct {
+	Duration string `json:"duration"`
+}
+
+// handlePause reports, starts (POST) or ends (DELETE) a pause in tracking. A
+// POST without a duration pauses until tracking is resumed.
+func (h *Handler) handlePause(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req pauseRequest
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var until time.Time
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				http.Error(w, "Invalid duration", http.StatusBadRequest)
+				return
+			}
+			until = time.Now().Add(d)
+		}
+
+		if err := pause.Set(h.config.Tracker.PauseFile, until); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		summary := "paused tracking"
+		if !until.IsZero() {
+			summary += " until " + until.Format(time.RFC3339)
+		}
+		h.audit(r, "tracking.pause", summary)
+	case http.MethodDelete:
+		if err := pause.Clear(h.config.Tracker.PauseFile); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, "tracking.resume", "resumed tracking")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	paused, until := pause.Active(h.config.Tracker.PauseFile, time.Now())
+	response := map[string]interface{}{"paused": paused}
+	if !until.IsZero() {
+		response["until"] = until
+	}
+	respondJSON(w, response)
+}
+
+// handleMobile serves a small page for phones: today's total, the current
+// app, the top apps and a pause button. htmx requests get just the content,
+// which the page reloads periodically and after pausing or resuming.
+func (h *Handler) handleMobile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loc := h.localeFor(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if r.Header.Get("HX-Request") == "true" {
+		h.respondMobileContent(w, r, loc)
+		return
+	}
+
+	t := func(msg string) string {
+		return html.EscapeString(loc.T(msg))
+	}
+
+	page := `<!DOCTYPE html>
+<html lang="` + loc.Tag() + `">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta name="color-scheme" content="light dark">
+    <title>Actionsum</title>
+    <script src="` + assetURL("hx.js") + `"></script>
+    <link rel="stylesheet" href="` + assetURL("mobile.css") + `">
+</head>
+<body>
+    <div id="mobile" hx-get="/m" hx-trigger="load, every 30s, refresh" hx-swap="innerHTML">
+        <div class="card">` + t("Loading...") + `</div>
+    </div>
+    <a href="/">` + t("Full dashboard") + `</a>
+    <script src="` + assetURL("mobile.js") + `"></script>
+</body>
+</html>`
+
+	w.Write([]byte(page))
+}
+
+func (h *Handler) respondMobileContent(w http.ResponseWriter, r *http.Request, loc *locale.Locale) {
+	t := func(msg string, args ...interface{}) string {
+		return html.EscapeString(loc.T(msg, args...))
+	}
+
+	period, _ := h.getPeriod("today")
+	summaries, err := h.readRepo(w, r).GetAppSummary(database.Query{Since: period.Start})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var totalSeconds int64
+	for _, app := range summaries {
+		totalSeconds += app.TotalSeconds
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div class="card"><div class="label">%s</div><div class="total">%s</div></div>`,
+		t("Today"), utils.FormatRoundedUnit(totalSeconds))
+
+	// Pausing applies to this machine's tracker, which other users of a
+	// multi-user server neither see nor control.
+	owner := h.requestUser(r) == ""
+	paused, until := false, time.Time{}
+	if owner {
+		paused, until = pause.Active(h.config.Tracker.PauseFile, time.Now())
+	}
+	b.WriteString(`<div class="card"><div class="label">` + t("Now") + `</div>`)
+	switch {
+	case paused && until.IsZero():
+		b.WriteString(`<div class="current paused">` + t("Tracking paused") + `</div>`)
+	case paused:
+		b.WriteString(`<div class="current paused">` + t("Paused until %s", until.Format("15:04")) + `</div>`)
+	default:
+		current := t("Nothing tracked yet")
+		if latest, _ := h.userRepo(r).GetLatest(); latest != nil {
+			current = html.EscapeString(latest.AppName)
+		}
+		b.WriteString(`<div class="current">` + current + `</div>`)
+	}
+	b.WriteString(`</div>`)
+
+	b.WriteString(`<div class="card"><div class="label">` + t("Top apps") + `</div>`)
+	if len(summaries) == 0 {
+		b.WriteString(`<div class="app-item">` + t("No data available") + `</div>`)
+	}
+	for i, app := range summaries {
+		if i == mobileTopApps {
+			break
+		}
+		fmt.Fprintf(&b, `<div class="app-item"><span>%s</span><span>%s</span></div>`,
+			html.EscapeString(app.AppName), utils.FormatRoundedUnit(app.TotalSeconds))
+	}
+	b.WriteString(`</div>`)
+
+	// The pause endpoint answers with JSON, so the button swaps nothing and
+	// mobile.js has the page reload its content instead.
+	switch {
+	case !owner || !canWrite(r):
+	case paused:
+		b.WriteString(`<button class="resume" hx-delete="/api/pause" hx-swap="none" data-refresh="#mobile">` + t("Resume tracking") + `</button>`)
+	default:
+		b.WriteString(`<button hx-post="/api/pause" hx-swap="none" data-refresh="#mobile">` + t("Pause tracking") + `</button>`)
+	}
+
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/locale"
+	"github.com/actionsum/actionsum/internal/tracker"
+	"github.com/actionsum/actionsum/pkg/utils"
+)
+
+// defaultRecentLimit is how many focus changes /api/recent returns unless
+// asked for more.
+const defaultRecentLimit = 20
+
+// handleRecent lists the latest focus changes. From actionsum serve they
+// come from the tracker's memory, so they are instant and include events the
+// write buffer hasn't saved yet; otherwise they are rebuilt from the last
+// day of events.
+func (h *Handler) handleRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultRecentLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	history, source := h.history, "memory"
+	if history == nil || h.requestUser(r) != "" {
+		events, err := h.userRepo(r).GetEvents(database.Query{Since: time.Now().Add(-24 * time.Hour)})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch events: %v", err), http.StatusInternalServerError)
+			return
+		}
+		history, source = tracker.NewHistory(limit), "database"
+		for _, event := range events {
+			history.Record(event, false)
+		}
+	}
+	changes := history.Recent(limit)
+
+	if r.Header.Get("HX-Request") == "true" {
+		h.respondRecentHTML(w, h.localeFor(w, r), changes)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"changes": changes,
+		"source":  source,
+	})
+}
+
+func (h *Handler) respondRecentHTML(w http.ResponseWriter, loc *locale.Locale, changes []tracker.FocusChange) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if len(changes) == 0 {
+		w.Write([]byte(`<div class="loading">` + html.EscapeString(loc.T("No data available")) + `</div>`))
+		return
+	}
+
+	out := `<div class="listing" role="list" aria-label="` + html.EscapeString(loc.T("Recent Activity")) + `">`
+	for _, change := range changes {
+		pending := ""
+		if change.Pending {
+			pending = ` <span class="app-percentage" title="` + html.EscapeString(loc.T("Not saved yet")) + `">…</span>`
+		}
+		out += fmt.Sprintf(`
+		<div class="app-item" role="listitem" title="%s">
+			<span class="app-name">%s</span>
+			<div>
+				<span class="app-time">%s</span>
+				<span class="app-percentage">%s</span>%s
+			</div>
+		</div>`,
+			html.EscapeString(change.WindowTitle),
+			html.EscapeString(change.AppName),
+			change.Start.Local().Format("15:04"),
+			utils.FormatRoundedUnit(int64(change.End.Sub(change.Start).Seconds())),
+			pending)
+	}
+	out += `</div>`
+
+	w.Write([]byte(out))
+}
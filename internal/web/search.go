@@ -0,0 +1,251 @@
+package web
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/locale"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/pkg/utils"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// defaultSearchLimit is how many results /api/search returns unless asked
+// for more.
+const defaultSearchLimit = 20
+
+// handleSearch finds text in app names and window titles. htmx requests get
+// the results with the matching words marked, each linking to its place on
+// the timeline.
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	text := query.Get("q")
+	terms := database.SearchTerms(text)
+	htmx := r.Header.Get("HX-Request") == "true"
+	if len(terms) == 0 {
+		if htmx {
+			// Clearing the search box clears the results.
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			return
+		}
+		http.Error(w, "q must contain a word to search for", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	results, err := h.readReporter(w, r).Search(text, query.Get("profile"), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if htmx {
+		h.respondSearchHTML(w, h.localeFor(w, r), terms, results)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"query":   text,
+		"terms":   terms,
+		"results": results,
+	})
+}
+
+func (h *Handler) respondSearchHTML(w http.ResponseWriter, loc *locale.Locale, terms []string, results []models.SearchResult) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	out := `<div class="report-box search-box"><h2>` + html.EscapeString(loc.T("Search Results")) + `</h2>`
+	if len(results) == 0 {
+		out += `<div class="loading">` + html.EscapeString(loc.T("No matches")) + `</div></div>`
+		w.Write([]byte(out))
+		return
+	}
+
+	out += `<div class="listing" role="list">`
+	for _, result := range results {
+		start := result.Start.Local()
+		out += fmt.Sprintf(`
+		<a class="app-item search-result" role="listitem" href="%s">
+			<span class="app-name app-title"><strong>%s</strong> %s</span>
+			<span class="app-time">%s · %s</span>
+		</a>`,
+			html.EscapeString(timelineURL(result.Start)),
+			highlight(result.AppName, terms),
+			highlight(result.WindowTitle, terms),
+			start.Format("2006-01-02 15:04"),
+			utils.FormatHoursMinutes(result.Seconds))
+	}
+	out += `</div></div>`
+
+	w.Write([]byte(out))
+}
+
+// timelineURL links the timeline page at the moment t.
+func timelineURL(t time.Time) string {
+	return "/timeline?at=" + url.QueryEscape(t.Format(time.RFC3339Nano))
+}
+
+// foldAccents compares words the way the search index does: ignoring case
+// and diacritics.
+var foldAccents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+func fold(s string) string {
+	folded, _, err := transform.String(foldAccents, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// highlight escapes text for HTML, marking the words that start with one of
+// terms.
+func highlight(text string, terms []string) string {
+	folded := make([]string, len(terms))
+	for i, term := range terms {
+		folded[i] = fold(term)
+	}
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+
+	var b strings.Builder
+	for text != "" {
+		// Split off the leading run of word or non-word characters.
+		word := isWord([]rune(text)[0])
+		end := strings.IndexFunc(text, func(r rune) bool { return isWord(r) != word })
+		if end < 0 {
+			end = len(text)
+		}
+		chunk := text[:end]
+		text = text[end:]
+
+		matched := false
+		if word {
+			for _, term := range folded {
+				if strings.HasPrefix(fold(chunk), term) {
+					matched = true
+					break
+				}
+			}
+		}
+		if matched {
+			b.WriteString("<mark>" + html.EscapeString(chunk) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(chunk))
+		}
+	}
+	return b.String()
+}
+
+// handleTimelinePage shows the timeline of the day containing the at query
+// parameter, or of today, scrolled to and marking the segment at that time.
+func (h *Handler) handleTimelinePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	at := time.Now()
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		t, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			http.Error(w, "at must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+	at = at.Local()
+
+	loc := h.localeFor(w, r)
+	t := func(msg string) string {
+		return html.EscapeString(loc.T(msg))
+	}
+	api := "/api/timeline?date=" + at.Format("2006-01-02") + "&at=" + url.QueryEscape(at.Format(time.RFC3339Nano))
+
+	page := `<!DOCTYPE html>
+<html lang="` + loc.Tag() + `">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>` + t("Timeline") + ` - ` + t("Actionsum Dashboard") + `</title>
+    <script src="` + assetURL("hx.js") + `"></script>
+    <link rel="stylesheet" href="` + assetURL("dashboard.css") + `">
+</head>
+<body>
+    <div class="header">
+        <h1><a class="back-link" href="/" title="` + t("Full dashboard") + `">←</a> ` + t("Timeline") + ` ` + at.Format("2006-01-02") + `</h1>
+        <div class="header-controls">
+            <button class="header-btn" onclick="toggleTheme()" title="` + t("Toggle theme") + `" aria-label="` + t("Toggle theme") + `">
+                <span id="theme-icon" aria-hidden="true">🌙</span>
+            </button>
+        </div>
+    </div>
+    <div class="report-box" hx-get="` + html.EscapeString(api) + `" hx-trigger="load" hx-swap="innerHTML" data-scroll-to="timeline-target">
+        <div class="loading">` + t("Loading...") + `</div>
+    </div>
+    <script src="` + assetURL("dashboard.js") + `"></script>
+</body>
+</html>`
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+func (h *Handler) respondTimelineHTML(w http.ResponseWriter, loc *locale.Locale, segments []models.TimelineSegment, at time.Time) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if len(segments) == 0 {
+		w.Write([]byte(`<div class="loading">` + html.EscapeString(loc.T("No data available")) + `</div>`))
+		return
+	}
+
+	out := `<div class="listing timeline">`
+	for _, segment := range segments {
+		id := ""
+		if !at.IsZero() && !at.Before(segment.Start) && at.Before(segment.End) && segment.Kind == "activity" {
+			id = ` id="timeline-target"`
+			at = time.Time{}
+		}
+		label := segment.AppName
+		switch segment.Kind {
+		case "note":
+			label = segment.Text
+		case "gap", "fragmented":
+			label = segment.Reason
+		}
+		out += fmt.Sprintf(`
+		<div class="app-item timeline-%s"%s>
+			<span class="app-name">%s</span>
+			<span class="app-time">%s–%s</span>
+		</div>`,
+			segment.Kind, id,
+			html.EscapeString(label),
+			segment.Start.Local().Format("15:04"),
+			segment.End.Local().Format("15:04"))
+	}
+	out += `</div>`
+
+	w.Write([]byte(out))
+}
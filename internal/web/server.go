@@ -9,6 +9,8 @@ import (
 
 	"github.com/actionsum/actionsum/internal/config"
 	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/locale"
+	"github.com/actionsum/actionsum/internal/tracker"
 )
 
 type Server struct {
@@ -18,6 +20,10 @@ type Server struct {
 }
 
 func NewServer(cfg *config.Config, repo *database.Repository, customPort int) *Server {
+	if err := locale.LoadTranslations(cfg.Web.TranslationsDir); err != nil {
+		log.Printf("Failed to load dashboard translations: %v", err)
+	}
+
 	handler := NewHandler(cfg, repo)
 	mux := http.NewServeMux()
 	handler.SetupRoutes(mux)
@@ -35,6 +41,7 @@ func NewServer(cfg *config.Config, repo *database.Repository, customPort int) *S
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	httpServer.RegisterOnShutdown(func() { close(handler.done) })
 
 	return &Server{
 		config:  cfg,
@@ -43,6 +50,23 @@ func NewServer(cfg *config.Config, repo *database.Repository, customPort int) *S
 	}
 }
 
+// UseSnapshot answers report queries from snapshot instead of the live
+// database once it has been taken.
+func (s *Server) UseSnapshot(snapshot *database.Snapshot) {
+	s.handler.snapshot = snapshot
+}
+
+// UseHistory serves /api/recent from the tracker's memory instead of the
+// database.
+func (s *Server) UseHistory(history *tracker.History) {
+	s.handler.history = history
+}
+
+// UseLive streams the tracker's presence at /api/stream.
+func (s *Server) UseLive(live *tracker.Live) {
+	s.handler.live = live
+}
+
 func (s *Server) Start() error {
 	log.Printf("Starting web server on http://%s", s.server.Addr)
 	return s.server.ListenAndServe()
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleShare serves the report for a period as a standalone HTML file to
+// save and pass on. Window titles and notes are left out when redact is set.
+func (h *Handler) handleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	periodType := query.Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+	redact := false
+	if redactStr := query.Get("redact"); redactStr != "" {
+		b, err := strconv.ParseBool(redactStr)
+		if err != nil {
+			http.Error(w, "redact must be true or false", http.StatusBadRequest)
+			return
+		}
+		redact = b
+	}
+
+	page, err := h.readReporter(w, r).FormatShareHTML(periodType, query.Get("profile"), redact)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("actionsum-%s-%s.html", periodType, time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Write([]byte(page))
+}
@@ -0,0 +1,188 @@
+function initTheme() {
+    const savedTheme = localStorage.getItem('theme');
+    const prefersDark = window.matchMedia('(prefers-color-scheme: dark)').matches;
+    const theme = savedTheme || (prefersDark ? 'dark' : 'light');
+    setTheme(theme);
+}
+
+function setTheme(theme) {
+    document.documentElement.setAttribute('data-theme', theme);
+    document.getElementById('theme-icon').textContent = theme === 'dark' ? '☀️' : '🌙';
+    localStorage.setItem('theme', theme);
+}
+
+function toggleTheme() {
+    const currentTheme = document.documentElement.getAttribute('data-theme');
+    const newTheme = currentTheme === 'dark' ? 'light' : 'dark';
+    setTheme(newTheme);
+}
+
+function initBars() {
+    const savedBars = localStorage.getItem('bars');
+    const showBars = savedBars === 'true';
+    setBars(showBars);
+}
+
+function setBars(show) {
+    document.documentElement.setAttribute('data-bars', show);
+    const btn = document.querySelector('button[onclick="toggleBars()"]');
+    if (!btn) {
+        return;
+    }
+    if (show) {
+        btn.classList.add('active');
+    } else {
+        btn.classList.remove('active');
+    }
+    btn.setAttribute('aria-pressed', show);
+    localStorage.setItem('bars', show);
+}
+
+function toggleBars() {
+    const current = document.documentElement.getAttribute('data-bars') === 'true';
+    setBars(!current);
+}
+
+function formatElapsed(seconds) {
+    if (seconds < 60) {
+        return seconds + 's';
+    }
+    const hours = Math.floor(seconds / 3600);
+    const minutes = Math.floor((seconds % 3600) / 60);
+    if (hours === 0) {
+        return minutes + 'm';
+    }
+    return minutes === 0 ? hours + 'h' : hours + 'h ' + minutes + 'm';
+}
+
+// initNow shows what is focused right now, from the server's presence
+// stream, counting up the time since it changed.
+function initNow() {
+    const widget = document.getElementById('now');
+    if (!widget || !window.EventSource) {
+        return;
+    }
+    const labels = {
+        idle: widget.dataset.idle,
+        locked: widget.dataset.locked,
+        paused: widget.dataset.paused,
+        off_hours: widget.dataset.offHours,
+        unknown: widget.dataset.unknown,
+    };
+    let since = null;
+
+    function tick() {
+        if (since) {
+            const elapsed = Math.max(0, Math.floor((Date.now() - since) / 1000));
+            widget.querySelector('.now-time').textContent = formatElapsed(elapsed);
+        }
+    }
+
+    const stream = new EventSource('/api/stream');
+    stream.addEventListener('presence', (event) => {
+        const presence = JSON.parse(event.data);
+        widget.dataset.state = presence.state;
+        widget.title = presence.window_title || '';
+        widget.querySelector('.now-app').textContent =
+            presence.state === 'active' ? presence.app_name : (labels[presence.state] || presence.state);
+        since = Date.parse(presence.since);
+        widget.hidden = false;
+        tick();
+    });
+    stream.addEventListener('error', () => {
+        if (stream.readyState === EventSource.CLOSED) {
+            widget.hidden = true;
+        }
+    });
+    setInterval(tick, 1000);
+}
+
+function toggleShortcuts() {
+    const dialog = document.getElementById('shortcuts');
+    if (!dialog) {
+        return;
+    }
+    if (dialog.open) {
+        dialog.close();
+    } else {
+        dialog.showModal();
+    }
+}
+
+// showPeriod switches the app page's period, or moves to that period's
+// summary on the dashboard.
+function showPeriod(period) {
+    const select = document.getElementById('period');
+    if (select) {
+        select.value = period;
+        select.dispatchEvent(new Event('change'));
+        return;
+    }
+    const section = document.getElementById('summary-' + period);
+    if (section) {
+        section.scrollIntoView({block: 'nearest'});
+        section.focus();
+    }
+}
+
+// initShortcuts binds the keys listed in the shortcuts dialog. Keys typed
+// into fields are left alone, except Escape, which leaves the search box.
+function initShortcuts() {
+    const actions = {
+        '/': () => {
+            const search = document.querySelector('.search-input');
+            if (search) {
+                search.focus();
+            }
+        },
+        'd': () => showPeriod('day'),
+        'w': () => showPeriod('week'),
+        'm': () => showPeriod('month'),
+        'b': toggleBars,
+        't': toggleTheme,
+        '?': toggleShortcuts,
+    };
+
+    document.addEventListener('keydown', (event) => {
+        if (event.ctrlKey || event.metaKey || event.altKey) {
+            return;
+        }
+        const target = event.target;
+        if (target.matches('input, select, textarea')) {
+            if (event.key === 'Escape' && target.classList.contains('search-input')) {
+                target.value = '';
+                target.dispatchEvent(new Event('input'));
+                target.blur();
+            }
+            return;
+        }
+        const dialog = document.getElementById('shortcuts');
+        if (dialog && dialog.open && event.key !== '?') {
+            return;
+        }
+        const action = actions[event.key];
+        if (action) {
+            event.preventDefault();
+            action();
+        }
+    });
+}
+
+// initScrollTo scrolls to the element named by a data-scroll-to attribute
+// once content has been swapped into it, such as the searched-for moment
+// of a timeline.
+function initScrollTo() {
+    document.body.addEventListener('htmx:afterSwap', (event) => {
+        const id = event.target.dataset && event.target.dataset.scrollTo;
+        const target = id && document.getElementById(id);
+        if (target) {
+            target.scrollIntoView({block: 'center'});
+        }
+    });
+}
+
+initTheme();
+initBars();
+initNow();
+initShortcuts();
+initScrollTo();
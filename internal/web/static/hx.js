@@ -0,0 +1,179 @@
+// hx.js implements the part of htmx the dashboard and mobile page use, so
+// they work without loading anything from the internet:
+//
+//   hx-get, hx-post, hx-delete  request the URL, sending HX-Request: true
+//   hx-trigger                  load, every <n>s, <event> [from:<selector>] [delay:<n>ms]
+//   hx-include                  add the named fields of matching elements
+//   hx-target                   swap into the element matching a selector
+//   hx-swap                     innerHTML (the default) or none
+//
+// As in htmx, htmx:afterSwap is dispatched on the target after a swap and
+// htmx:afterRequest on the element once its request completes; pages
+// listen for them rather than running code from attributes, so no script
+// is ever built from a string. htmx.trigger(target, event) dispatches an
+// event on an element or selector.
+(function () {
+    'use strict';
+
+    const verbs = ['get', 'post', 'delete'];
+
+    function request(el) {
+        for (const verb of verbs) {
+            const url = el.getAttribute('hx-' + verb);
+            if (url !== null) {
+                return { method: verb.toUpperCase(), url: url };
+            }
+        }
+        return null;
+    }
+
+    function defaultTrigger(el) {
+        if (el.matches('form')) {
+            return 'submit';
+        }
+        if (el.matches('input, select, textarea')) {
+            return 'change';
+        }
+        return 'click';
+    }
+
+    function parameters(el) {
+        const params = new URLSearchParams();
+        const sources = [];
+        if (el.matches('form') || (el.name && 'value' in el)) {
+            sources.push(el);
+        }
+        const include = el.getAttribute('hx-include');
+        if (include) {
+            sources.push(...document.querySelectorAll(include));
+        }
+        for (const source of sources) {
+            if (source.matches('form')) {
+                new FormData(source).forEach((value, key) => params.append(key, value));
+            } else if (source.name) {
+                params.append(source.name, source.value);
+            }
+        }
+        return params;
+    }
+
+    async function issue(el) {
+        const req = request(el);
+        let url = req.url;
+        let body = null;
+        const params = parameters(el);
+        if (req.method === 'POST') {
+            if ([...params].length > 0) {
+                body = params;
+            }
+        } else if ([...params].length > 0) {
+            url += (url.includes('?') ? '&' : '?') + params.toString();
+        }
+
+        let ok = false;
+        try {
+            const response = await fetch(url, {
+                method: req.method,
+                body: body,
+                headers: { 'HX-Request': 'true' },
+                credentials: 'same-origin',
+            });
+            ok = response.ok;
+            const target = el.hasAttribute('hx-target') ? document.querySelector(el.getAttribute('hx-target')) : el;
+            if (ok && target && (el.getAttribute('hx-swap') || 'innerHTML') === 'innerHTML') {
+                target.innerHTML = await response.text();
+                process(target);
+                target.dispatchEvent(new CustomEvent('htmx:afterSwap', { bubbles: true, detail: { elt: el, target: target } }));
+            }
+        } catch (err) {
+            console.error('hx: ' + req.method + ' ' + url + ' failed', err);
+        }
+
+        el.dispatchEvent(new CustomEvent('htmx:afterRequest', { bubbles: true, detail: { elt: el, successful: ok } }));
+    }
+
+    function bind(el) {
+        const spec = el.getAttribute('hx-trigger') || defaultTrigger(el);
+        for (const part of spec.split(',')) {
+            const words = part.trim().split(/\s+/);
+            const name = words[0];
+            if (name === 'load') {
+                issue(el);
+                continue;
+            }
+            if (name === 'every') {
+                const match = /^(\d+)(ms|s)$/.exec(words[1] || '');
+                if (!match) {
+                    continue;
+                }
+                const interval = Number(match[1]) * (match[2] === 's' ? 1000 : 1);
+                const timer = setInterval(() => {
+                    if (!document.contains(el)) {
+                        clearInterval(timer);
+                        return;
+                    }
+                    issue(el);
+                }, interval);
+                continue;
+            }
+
+            let source = el;
+            const from = words.find((word) => word.startsWith('from:'));
+            if (from) {
+                source = document.querySelector(from.slice('from:'.length));
+            }
+            let delay = 0;
+            const delayed = words.find((word) => word.startsWith('delay:'));
+            if (delayed) {
+                const match = /^(\d+)(ms|s)$/.exec(delayed.slice('delay:'.length));
+                if (match) {
+                    delay = Number(match[1]) * (match[2] === 's' ? 1000 : 1);
+                }
+            }
+            let pending = null;
+            if (source) {
+                source.addEventListener(name, (event) => {
+                    if (name === 'submit') {
+                        event.preventDefault();
+                    }
+                    clearTimeout(pending);
+                    if (delay > 0) {
+                        pending = setTimeout(() => issue(el), delay);
+                    } else {
+                        issue(el);
+                    }
+                });
+            }
+        }
+    }
+
+    function process(root) {
+        const selector = verbs.map((verb) => '[hx-' + verb + ']').join(', ');
+        const elements = [...root.querySelectorAll(selector)];
+        if (root.matches && root.matches(selector)) {
+            elements.unshift(root);
+        }
+        for (const el of elements) {
+            if (!el.hxBound) {
+                el.hxBound = true;
+                bind(el);
+            }
+        }
+    }
+
+    window.htmx = {
+        process: process,
+        trigger: function (target, name) {
+            const el = typeof target === 'string' ? document.querySelector(target) : target;
+            if (el) {
+                el.dispatchEvent(new CustomEvent(name));
+            }
+        },
+    };
+
+    if (document.readyState === 'loading') {
+        document.addEventListener('DOMContentLoaded', () => process(document.body));
+    } else {
+        process(document.body);
+    }
+})();
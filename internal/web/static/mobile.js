@@ -0,0 +1,8 @@
+// Buttons with a data-refresh selector, such as pausing, swap nothing
+// themselves; once their request completes the element they name reloads.
+document.body.addEventListener('htmx:afterRequest', (event) => {
+    const selector = event.target.dataset && event.target.dataset.refresh;
+    if (selector) {
+        htmx.trigger(selector, 'refresh');
+    }
+});
@@ -0,0 +1,77 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/tracker"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment, so proxies
+// and browsers don't drop the connection.
+const streamKeepAlive = 30 * time.Second
+
+// handleStream sends what the user is doing as server-sent events: a
+// "presence" event with the current state on connecting, then one for each
+// change. It needs the tracker, so it is only available from actionsum serve.
+func (h *Handler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.live == nil {
+		http.Error(w, "Live updates need the tracker running in actionsum serve", http.StatusServiceUnavailable)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's write timeout would otherwise end the stream.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	changes, unsubscribe := h.live.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	if err := writePresence(w, h.live.Current()); err != nil {
+		return
+	}
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case p := <-changes:
+			err = writePresence(w, p)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil {
+			return
+		}
+		rc.Flush()
+	}
+}
+
+func writePresence(w http.ResponseWriter, p tracker.Presence) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: presence\ndata: %s\n\n", data)
+	return err
+}
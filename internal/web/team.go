@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/actionsum/actionsum/internal/models"
+)
+
+// handleTeam serves the aggregate report over the users sharing their time,
+// when team reports are enabled.
+func (h *Handler) handleTeam(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.config.Web.TeamReports {
+		http.Error(w, "Team reports are not enabled", http.StatusNotFound)
+		return
+	}
+
+	periodType := r.URL.Query().Get("period")
+	if periodType == "" {
+		periodType = "week"
+	}
+
+	// Each member's totals are read under their own user, whoever asks.
+	report, err := h.readReporter(w, r).GenerateTeamReport(periodType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+type sharingRequest struct {
+	Level string `json:"level"`
+}
+
+// handleTeamSharing reports or sets (PUT) what team reports include of the
+// requesting user's time. Only the user can change it.
+func (h *Handler) handleTeamSharing(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Web.TeamReports {
+		http.Error(w, "Team reports are not enabled", http.StatusNotFound)
+		return
+	}
+	user := h.requestUser(r)
+	if user == "" {
+		http.Error(w, "Sharing settings belong to the users of a multi-user server; use a token with a user", http.StatusForbidden)
+		return
+	}
+	repo := h.userRepo(r)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req sharingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		level, err := models.ParseShareLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := repo.SetShareLevel(level); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save sharing level: %v", err), http.StatusInternalServerError)
+			return
+		}
+		h.audit(r, "team.share", fmt.Sprintf("%s shares %s with team reports", user, level))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	level, err := repo.GetShareLevel()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get sharing level: %v", err), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, models.TeamSharing{UserID: user, Level: level})
+}
@@ -2,36 +2,73 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/actionsum/actionsum/internal/alert"
+	"github.com/actionsum/actionsum/internal/auth"
+	"github.com/actionsum/actionsum/internal/autostart"
+	"github.com/actionsum/actionsum/internal/away"
+	"github.com/actionsum/actionsum/internal/backfill"
+	"github.com/actionsum/actionsum/internal/browser"
+	"github.com/actionsum/actionsum/internal/budget"
 	"github.com/actionsum/actionsum/internal/config"
+	"github.com/actionsum/actionsum/internal/console"
 	"github.com/actionsum/actionsum/internal/daemon"
 	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/digest"
+	"github.com/actionsum/actionsum/internal/doctor"
+	"github.com/actionsum/actionsum/internal/export"
+	"github.com/actionsum/actionsum/internal/gaps"
+	"github.com/actionsum/actionsum/internal/gnomeext"
+	"github.com/actionsum/actionsum/internal/holiday"
+	"github.com/actionsum/actionsum/internal/merge"
+	"github.com/actionsum/actionsum/internal/models"
+	"github.com/actionsum/actionsum/internal/notes"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/internal/pause"
+	"github.com/actionsum/actionsum/internal/profile"
+	"github.com/actionsum/actionsum/internal/remoteterm"
+	"github.com/actionsum/actionsum/internal/repair"
 	"github.com/actionsum/actionsum/internal/reporter"
+	"github.com/actionsum/actionsum/internal/shell"
+	"github.com/actionsum/actionsum/internal/tag"
 	"github.com/actionsum/actionsum/internal/tracker"
-	"github.com/actionsum/actionsum/internal/web"
-	"github.com/actionsum/actionsum/pkg/detector"
+	"github.com/actionsum/actionsum/pkg/integrations/fake"
+	"github.com/actionsum/actionsum/pkg/integrations/hybrid"
+	"github.com/actionsum/actionsum/pkg/normalize"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+	"github.com/actionsum/actionsum/pkg/utils"
+	"github.com/actionsum/actionsum/pkg/window"
 	"github.com/actionsum/actionsum/version"
 )
 
 type CommandHandler struct {
 	cfg *config.Config
+	out *console.Console
 }
 
-func NewCommandHandler() *CommandHandler {
+func NewCommandHandler(plain bool) *CommandHandler {
 	return &CommandHandler{
 		cfg: config.New(),
+		out: console.New(plain),
 	}
 }
 
 func main() {
+	plain := takePlainFlag()
 	customPort := flag.Int("p", 0, "Custom port to run the server on")
 	flag.Parse()
 
@@ -41,7 +78,7 @@ func main() {
 	}
 
 	command := os.Args[1]
-	handler := NewCommandHandler()
+	handler := NewCommandHandler(plain)
 
 	switch command {
 	case "start":
@@ -52,12 +89,68 @@ func main() {
 		handler.stopDaemon()
 	case "status":
 		handler.showStatus()
+	case "config":
+		handler.manageConfig()
+	case "doctor":
+		handler.runDoctor()
 	case "report":
 		handler.generateReport()
+	case "diff":
+		handler.showDiff()
+	case "share":
+		handler.shareReport()
+	case "team":
+		handler.teamReport()
+	case "schema":
+		handler.showSchema()
+	case "export":
+		handler.exportData()
+	case "backfill":
+		handler.backfillHistory()
+	case "merge":
+		handler.mergeDatabase()
 	case "clear":
 		handler.clearDatabase()
+	case "audit":
+		handler.showAudit()
+	case "token":
+		handler.manageTokens()
 	case "normalize":
 		handler.normalizeDatabase()
+	case "repair":
+		handler.repairDatabase()
+	case "recover":
+		handler.recoverDatabase()
+	case "profile":
+		handler.manageProfile()
+	case "note":
+		handler.addNote()
+	case "tag":
+		handler.manageTag()
+	case "away":
+		handler.manageAway()
+	case "holiday":
+		handler.manageHoliday()
+	case "digest":
+		handler.showDigest()
+	case "budget":
+		handler.showBudgets()
+	case "enable-autostart":
+		handler.enableAutostart()
+	case "disable-autostart":
+		handler.disableAutostart()
+	case "gnome-extension":
+		handler.manageGnomeExtension()
+	case "browser":
+		handler.manageBrowserExtension()
+	case "native-host":
+		handler.runNativeHost()
+	case "remote-agent":
+		handler.runRemoteAgent()
+	case "shell-hook":
+		handler.printShellHook()
+	case "shell-event":
+		handler.recordShellEvent()
 	case "version":
 		showVersion()
 	case "help", "--help", "-h":
@@ -69,6 +162,22 @@ func main() {
 	}
 }
 
+// takePlainFlag removes --plain, accepted anywhere on the command line, from
+// os.Args and reports whether it was given.
+func takePlainFlag() bool {
+	plain := false
+	args := os.Args[:1]
+	for _, arg := range os.Args[1:] {
+		if arg == "--plain" || arg == "-plain" {
+			plain = true
+			continue
+		}
+		args = append(args, arg)
+	}
+	os.Args = args
+	return plain
+}
+
 func printUsage() {
 	fmt.Printf(`actionsum - Application focus time tracker
 
@@ -78,34 +187,187 @@ Usage:
 Commands:
   start              Start the tracking daemon
   serve              Start daemon with web API server
+                     --detector fake --scenario <file>  play back a scripted scenario
+                     --insecure  serve beyond localhost without requiring tokens
   stop               Stop the tracking daemon
   status             Show daemon status and current focused app
-  report [period]    Generate time report (period: day, week, month)
+  config validate    Check the configuration and report every problem at once
+  doctor             Check the session, daemon, database and detection quality (--json)
+  report [period]    Generate time report (period: day, yesterday, week, lastweek, month, lastmonth)
+                     --json, --profile <name>, --user <id> on a multi-user server
+  report gaps [period]  List periods the machine was on but nothing was tracked (--min 10m)
+  report distractions [period]  Find rapid back-and-forth app switching (--json, --profile)
+  diff [period] [base]  Compare two periods per app and category (default: week lastweek)
+                     --json, --csv, --profile <name>
+  share [period]     Write the report as a standalone HTML file to send to someone (default: week)
+                     --output <file>, --profile <name>, --redact to leave out window titles, websites and notes
+  team [period]      Aggregate the time multi-user server users share with the team (--json)
+  profile            Show the active profile
+  profile use <name> Track under a profile (use "auto" for time-window rules)
+  profile list       List recorded profiles and rules
+  schema             Print the database schema as JSON (--sql for CREATE statements)
+  export [events|daily]  Export events or daily per-app totals as Parquet
+                     --output <file>, --since/--until YYYY-MM-DD, --profile <name>
+  export bundle --output <dir>  Write or update CSV tables and DuckDB views (--full to rebuild)
+  export billing     Billable hours and amounts per project as CSV (--increment 6m)
+  backfill <zsh|bash|vscode>  Estimate activity from before tracking began from shell history or VS Code logs
+                     --path <file or dir>, --app <name>, --dry-run
+  merge <other.db>   Merge another machine's database into this one (--dry-run)
   clear              Clear all tracking data from database
+  token create <name>  Create an API token (--scopes read:events,write:events,admin, --user <id> for multi-user mode)
+  token list         List API tokens and their scopes
+  token revoke <name>  Revoke an API token (--user for another user's)
+  audit              Show recent changes made via the CLI or web API (--limit 50, --json)
   normalize          Normalize all app names to lowercase
+  repair             Fix overlapping or oversized events (--dry-run)
+  recover            Rebuild a corrupt database, keeping the damaged file aside (--yes)
+  tag <name>         Count current activity towards a category for a while (--for 30m)
+  tag clear          End the current tag early
+  away [reason]      Show or fill the last time you were away, e.g. "away Dentist" (away clear to skip it)
+  holiday [list]     List this year's days off, public holidays and PTO
+  holiday add YYYY-MM-DD  Mark a day off (--pto, --until YYYY-MM-DD for several, then an optional name)
+  holiday remove YYYY-MM-DD  Unmark a day off
+  note "text"        Attach a note to today or a time range (--from 9:00 --to 11:30 --date YYYY-MM-DD)
+  budget             Show today's usage against daily app/category budgets
+  digest             Show last week's digest (--send to deliver, --current for this week, --pdf FILE)
+  enable-autostart   Start tracking on graphical login (--serve to include the web server)
+  disable-autostart  Remove the login autostart entry
+  gnome-extension install  Install the GNOME Shell extension that shows actionsum the focused window on Wayland
+  gnome-extension uninstall  Remove it again
+  browser install    Install the browser extension and native messaging host that report the active tab
+                     --chrome-id <id,...> for Chrome, Chromium, Brave and Edge
+  browser uninstall  Remove them again
+  remote-agent       On a server, report the active tmux window to actionsum on your machine
+                     --url <base URL> (default http://localhost:10000), --token <token>, --host <name>, --interval 15s
+  shell-hook <bash|zsh>  Print the hook that reports each command and directory, for eval in .bashrc or .zshrc
   version            Show version information
   help               Show this help message
 
+Every command accepts --plain to print without colors or progress bars, as
+does setting NO_COLOR for colors. Neither is drawn when output is piped.
+
 Examples:
   actionsum start
   actionsum serve
   actionsum status
   actionsum report day
   actionsum report week
+  actionsum report week --profile work
+  actionsum profile use work
   actionsum stop
 
 Environment Variables:
   ACTIONSUM_DB_PATH          Database file path
+  ACTIONSUM_DB_SIZE_WARN     Warn when the database exceeds this many MB (default 500, 0 disables)
+  ACTIONSUM_WAL_CHECKPOINT   Checkpoint the write-ahead log above this many MB (default 64)
+  ACTIONSUM_DB_LOG_LEVEL     Database logging: silent, error, warn or info (default warn)
+  ACTIONSUM_DB_SLOW_QUERY    Log queries slower than this with their SQL (default 500ms, 0 disables)
+  ACTIONSUM_DB_AUTO_RECOVER  Rebuild a corrupt database at startup, keeping the damaged file (default true)
   ACTIONSUM_POLL_INTERVAL    Poll interval in seconds (10-300)
+  ACTIONSUM_APP_POLL_INTERVALS  Per-app poll intervals, e.g. firefox=5,slack=60
   ACTIONSUM_IDLE_THRESHOLD   Idle threshold in seconds
+  ACTIONSUM_IDLE_GRACE       Count idle stretches up to this long as active when you return (e.g. 2m)
+  ACTIONSUM_CLOCK_JUMP_THRESHOLD  Clock change treated as suspend/NTP step, in seconds (default 30)
   ACTIONSUM_PID_FILE         PID file path
+  ACTIONSUM_STATS_FILE       Where the daemon publishes detection backend counts
+  ACTIONSUM_STARTUP_TIMEOUT  Seconds to wait and retry for the display server at startup (default 60)
   ACTIONSUM_EXCLUDE_IDLE     Exclude idle time from reports (true/false)
+  ACTIONSUM_EXCLUDE_PROCESS_GUESSES  Leave process-based guesses out of reports (true/false)
+  ACTIONSUM_LOCALE           Number format and period labels in reports and the dashboard, e.g. de-DE
+  ACTIONSUM_WEB_REQUIRE_TOKEN  Reject API requests without a token (true/false)
+  ACTIONSUM_WEB_DISABLED     Refuse to run the web server, for CLI-only use (true/false)
+  ACTIONSUM_WEB_INSECURE     Serve beyond localhost without requiring tokens (true/false, or serve --insecure)
+  ACTIONSUM_WEB_MULTI_USER   Keep each token user's events apart and scope every query to them (true/false)
+  ACTIONSUM_WEB_TEAM_REPORTS Serve aggregate team reports of users who opt in (true/false)
+  ACTIONSUM_WEB_TEAM_MIN_MEMBERS  Users needed before team reports show category totals (default: 3)
+  ACTIONSUM_OIDC_ISSUER      OpenID Connect provider for dashboard sign-in on a multi-user server
+  ACTIONSUM_OIDC_CLIENT_ID, ACTIONSUM_OIDC_CLIENT_SECRET  Client registered with the provider
+  ACTIONSUM_OIDC_REDIRECT_URL  This server's /auth/callback as browsers reach it
+  ACTIONSUM_OIDC_USER_CLAIM  ID token claim used as the user ID (default preferred_username)
+  ACTIONSUM_OIDC_ALLOWED_USERS  Who may sign in, e.g. "alice,@example.com" (required with OIDC)
+  ACTIONSUM_OIDC_SESSION     How long a sign-in lasts (default 168h)
+  ACTIONSUM_MDNS             Advertise the web API on the LAN via mDNS when not bound to localhost (true/false)
+  ACTIONSUM_TRANSLATIONS_DIR  Extra dashboard translations (default ~/.config/actionsum/translations)
+  ACTIONSUM_MICRO_BREAK      Breaks shorter than this don't split focus sessions (e.g. 2m)
+  ACTIONSUM_REPORT_SNAPSHOT  Serve web reports from a copy refreshed this often (e.g. 5m, default off)
+  ACTIONSUM_APP_NAMES_FILE   App name alias file (default ~/.config/actionsum/app-names.conf)
+  ACTIONSUM_PROFILE_RULES    Time-window profiles, e.g. "work=mon-fri 09:00-17:00;personal=sat,sun 00:00-24:00"
+  ACTIONSUM_TAG_DURATION     Default duration of "actionsum tag" (default 30m)
+  ACTIONSUM_TAG_FILE         Active tag state file (default ~/.config/actionsum/tag)
+  ACTIONSUM_PAUSE_FILE       Pause state set from the mobile page (default ~/.config/actionsum/pause)
+  ACTIONSUM_AWAY_PROMPT      Ask what you were doing after being away this long (e.g. 15m, default off)
+  ACTIONSUM_AWAY_REASONS     Choices offered when asking, e.g. "Meeting,Break,Lunch"
+  ACTIONSUM_AWAY_FILE        Away interval waiting for a reason (default ~/.config/actionsum/away)
+  ACTIONSUM_UNLOCK_SUMMARY   Show today's totals on unlocking after a lock this long (e.g. 30m, default off)
+  ACTIONSUM_FOCUS_EVENTS     Poll as soon as X11 reports a focus change (true/false, default false)
+  ACTIONSUM_PROCESS_FALLBACK Guess the focused app from processes without a window detector (default true)
+  ACTIONSUM_MIN_CONFIDENCE   Only store process-based guesses at least this confident (0-1)
+  ACTIONSUM_DETECTOR         Window detector: auto, or fake to play back a scenario (default auto)
+  ACTIONSUM_SCENARIO         Scenario file played back by the fake detector
+  ACTIONSUM_CLASSIFIER       Command or plugin:<name> labelling events from their window titles
+  ACTIONSUM_PROFILE_FILE     Manual profile selection file (default ~/.config/actionsum/profile)
+  ACTIONSUM_WORK_HOURS       Working hours, e.g. "mon-fri 09:00-12:30;mon-fri 13:30-18:00"
+  ACTIONSUM_WORK_HOURS_AUTOPAUSE  Stop tracking outside working hours (true/false)
+  ACTIONSUM_WORK_HOURS_BUDGETS_ONLY  Only count and enforce budgets during working hours (true/false)
+  ACTIONSUM_BUDGETS          Daily limits per app or category, e.g. youtube=30m,social=1h
+  ACTIONSUM_CATEGORIES       App categories, e.g. "social=slack,discord;video=mpv,vlc"
+  ACTIONSUM_BUDGET_STRICT    Keep warning while an over-budget app is focused (true/false)
+  ACTIONSUM_BUDGET_REPEAT    Strict mode warning interval (default 5m)
+  ACTIONSUM_BUDGET_HOOK      Command run in strict mode when an over-budget app is focused
+  ACTIONSUM_DAILY_GOAL       Focus time a day needs to extend the goal streak (default 1h)
+  ACTIONSUM_HOLIDAYS_FILE    Days marked off with "actionsum holiday" (default ~/.config/actionsum/holidays)
+  ACTIONSUM_HOLIDAYS_ICS     iCalendar file or URL of public holidays, left out of streaks and comparisons
+  ACTIONSUM_BILLING_INCREMENT  Round billed sessions up to this, e.g. 6m or 15m
+  ACTIONSUM_BILLING_RATES    Hourly rates per project, e.g. acme=95,internal=62.5
+  ACTIONSUM_BILLING_CURRENCY Currency of billed amounts, e.g. EUR
+  ACTIONSUM_WEEKLY_DIGEST    Send a digest when each week ends (true/false)
+  ACTIONSUM_DIGEST_PDF       Attach the digest as a PDF to emails (true/false)
+  ACTIONSUM_ALERTS           Alert rules, e.g. "active>10h,outage>30m"
+  ACTIONSUM_NOTIFY_DESKTOP   Desktop notifications via notify-send (default true)
+  ACTIONSUM_NOTIFY_WEBHOOK   URL receiving notifications as JSON POSTs
+  ACTIONSUM_SMTP_HOST, ACTIONSUM_SMTP_PORT, ACTIONSUM_SMTP_USER, ACTIONSUM_SMTP_PASSWORD,
+  ACTIONSUM_SMTP_FROM, ACTIONSUM_SMTP_TO  Email notifications
 
 Version: %s
 `, version.Version)
 }
 
+// parseDaemonFlags applies the options start and serve accept. They stay in
+// os.Args, so the daemonized child parses them again.
+func (h *CommandHandler) parseDaemonFlags(command string) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	detector := fs.String("detector", h.cfg.Tracker.Detector, "Window detector: auto, or fake to play back a scenario")
+	scenario := fs.String("scenario", h.cfg.Tracker.Scenario, "Scenario file played back by the fake detector")
+	insecure := fs.Bool("insecure", h.cfg.Web.Insecure, "Serve beyond localhost without requiring tokens")
+	fs.Parse(os.Args[2:])
+	h.cfg.Tracker.Detector = *detector
+	h.cfg.Tracker.Scenario = *scenario
+	h.cfg.Web.Insecure = *insecure
+}
+
+// newDetector creates the configured window detector. A fake detector's
+// scenario is read up front so a broken file fails the daemon at startup.
+func (h *CommandHandler) newDetector() (window.Detector, error) {
+	if h.cfg.Tracker.Detector == "fake" {
+		return fake.Load(h.cfg.Tracker.Scenario)
+	}
+	return daemon.StartDetector(h.cfg.Daemon.StartupTimeout,
+		hybrid.WithProcessFallback(h.cfg.Tracker.ProcessFallback),
+		hybrid.WithFocusEvents(h.cfg.Tracker.FocusEvents),
+		hybrid.WithAudio(h.cfg.Tracker.AudioActive))
+}
+
+// scenarioDone is closed when a fake detector has played its scenario out,
+// and never for real detectors.
+func scenarioDone(det window.Detector) <-chan struct{} {
+	if f, ok := det.(*fake.Detector); ok {
+		return f.Done()
+	}
+	return nil
+}
+
 func (h *CommandHandler) startDaemon() {
+	h.parseDaemonFlags("start")
 	if err := h.cfg.Validate(); err != nil {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
@@ -128,24 +390,26 @@ func (h *CommandHandler) startDaemon() {
 }
 
 func (h *CommandHandler) runStartDaemon(dm *daemon.Daemon) {
-	logPath := fmt.Sprintf("/tmp/actionsum-%d.log", os.Getuid())
+	logPath := daemonLogPath()
 	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err == nil {
 		log.SetOutput(logFile)
 		defer logFile.Close()
 	}
 
-	db, err := database.Connect(h.cfg.Database.Path)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if err := daemon.ApplyResourceLimits(h.cfg); err != nil {
+		log.Printf("Warning: %v", err)
 	}
+
+	h.recoverCorruptDatabase()
+	db, repo := h.openDatabase()
 	defer db.Close()
 
 	if err := db.Initialize(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	det, err := detector.New()
+	det, err := h.newDetector()
 	if err != nil {
 		log.Fatalf("Failed to initialize window detector: %v", err)
 	}
@@ -158,17 +422,36 @@ func (h *CommandHandler) runStartDaemon(dm *daemon.Daemon) {
 	}
 	defer dm.RemovePID()
 
-	repo := database.NewRepository(db)
 	trackerSvc := tracker.NewService(h.cfg, repo, det)
+	notifier := notify.FromConfig(h.cfg)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	if h.cfg.Notify.WeeklyDigest && notifier != nil {
+		go digest.NewScheduler(repo, notifier, budget.FromConfig(h.cfg, repo), h.cfg.Holidays, h.cfg.WorkHours.Windows, h.cfg.Notify.DigestPDF).Run(ctx)
+	}
+	if enforcer := budget.NewEnforcer(h.cfg, repo, notifier); enforcer != nil {
+		go enforcer.Run(ctx)
+	}
+	if evaluator := alert.NewEvaluator(h.cfg, repo, trackerSvc.Live(), notifier); evaluator != nil {
+		go evaluator.Run(ctx)
+	}
+	go daemon.NewSizeMonitor(db, h.cfg, notifier).Run(ctx)
+	if publisher := daemon.NewStatsPublisher(h.cfg.Daemon.StatsFile, det); publisher != nil {
+		publisher.UsePolls(trackerSvc.Polls())
+		go publisher.Run(ctx)
+	}
+
 	go func() {
-		<-sigChan
-		log.Println("Received shutdown signal")
+		select {
+		case <-sigChan:
+			log.Println("Received shutdown signal")
+		case <-scenarioDone(det):
+			log.Println("Scenario finished")
+		}
 		cancel()
 		trackerSvc.Stop()
 	}()
@@ -208,189 +491,1652 @@ func (h *CommandHandler) showStatus() {
 	}
 
 	if !running {
-		fmt.Println("Not running")
+		fmt.Println(h.out.Yellow("Not running"))
 	} else {
-		fmt.Printf("Running (PID: %d)\n", pid)
+		fmt.Println(h.out.Green(fmt.Sprintf("Running (PID: %d)", pid)))
 		fmt.Printf("http://localhost:%d\n", h.cfg.Web.Port)
 	}
+
+	if paused, until := pause.Active(h.cfg.Tracker.PauseFile, time.Now()); paused {
+		if until.IsZero() {
+			fmt.Println("Tracking paused")
+		} else {
+			fmt.Printf("Tracking paused until %s\n", until.Format("15:04"))
+		}
+	}
+
+	if size, err := database.FileSizes(h.cfg.Database.Path); err == nil {
+		fmt.Printf("Database: %s (WAL %s)\n", utils.FormatBytes(size.DatabaseBytes), utils.FormatBytes(size.WALBytes))
+		if warning := size.Warning(h.cfg.Database.SizeWarning); warning != "" {
+			h.out.Warn("%s", warning)
+		}
+
+		db, repo := h.openDatabase()
+		defer db.Close()
+		summaries, err := repo.GetDetectionSummary(database.Query{Since: time.Now().AddDate(0, 0, -7)})
+		if err == nil && len(summaries) > 0 {
+			fmt.Printf("Detection (7 days): %s\n", doctor.DetectionSplit(summaries))
+		}
+		if warning := doctor.CoverageWarning(repo); warning != "" {
+			h.out.Warn("%s", warning)
+		}
+	}
+
+	if running {
+		if stats, err := doctor.CurrentStats(h.cfg); err == nil {
+			if len(stats.Backends) > 0 {
+				fmt.Printf("Backends: %s\n", doctor.BackendSplit(stats))
+			}
+			if stats.Overhead != nil {
+				fmt.Printf("Overhead: %s\n", stats.Overhead)
+			}
+			for _, limitation := range stats.Limitations {
+				h.out.Warn("%s", limitation)
+			}
+		}
+	}
 }
 
-func (h *CommandHandler) generateReport() {
-	periodType := "day"
-	if len(os.Args) > 2 {
-		periodType = os.Args[2]
+func (h *CommandHandler) manageConfig() {
+	if len(os.Args) < 3 || os.Args[2] != "validate" {
+		log.Fatal("Usage: actionsum config validate")
 	}
-	db, err := database.Connect(h.cfg.Database.Path)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+
+	cfg := *h.cfg
+	if cfg.Database.Path == "" {
+		if path, err := database.GetDefaultDBPath(); err == nil {
+			cfg.Database.Path = path
+		}
 	}
-	defer db.Close()
-	repo := database.NewRepository(db)
-	rep := reporter.New(h.cfg, repo)
 
-	jsonOutput := false
-	if len(os.Args) > 3 && os.Args[3] == "--json" {
-		jsonOutput = true
+	// A running daemon may be the one holding the web port.
+	running, _, _ := daemon.New(cfg.Daemon.PIDFile).IsRunning()
+	problems := cfg.ValidateAll(!running)
+	if len(problems) == 0 {
+		h.out.Success("Configuration OK")
+		if running {
+			fmt.Println("(web port not checked while the daemon is running)")
+		}
+		return
 	}
-	report, err := rep.GenerateReport(periodType)
-	if err != nil {
-		log.Fatalf("Failed to generate report: %v", err)
+
+	fmt.Println(h.out.Red(fmt.Sprintf("Found %d configuration problem(s):", len(problems))))
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
 	}
-	if jsonOutput {
-		jsonStr, err := rep.FormatReportJSON(report)
+	os.Exit(1)
+}
+
+func (h *CommandHandler) runDoctor() {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	fs.Parse(os.Args[2:])
+
+	var repo *database.Repository
+	var corrupt *database.CorruptionError
+	if _, err := os.Stat(h.cfg.Database.Path); err == nil && !errors.As(database.CheckIntegrity(h.cfg.Database.Path), &corrupt) {
+		db, opened := h.openDatabase()
+		defer db.Close()
+		repo = opened
+	}
+
+	checks := doctor.Run(h.cfg, repo)
+	if *jsonOutput {
+		data, err := json.MarshalIndent(checks, "", "  ")
 		if err != nil {
 			log.Fatalf("Failed to format JSON: %v", err)
 		}
-		fmt.Println(jsonStr)
+		fmt.Println(string(data))
 	} else {
-		fmt.Println(rep.FormatReportText(report))
+		table := console.NewTable()
+		for _, check := range checks {
+			table.Row(h.statusLabel(check.Status), check.Name, check.Detail)
+		}
+		table.Write(h.out)
+	}
+	if doctor.Failed(checks) {
+		os.Exit(1)
 	}
 }
 
-func (h *CommandHandler) clearDatabase() {
-	fmt.Print("This will delete all tracking data. Are you sure? (yes/no): ")
-	var response string
-	fmt.Scanln(&response)
-	if response != "yes" && response != "y" {
-		fmt.Println("Operation cancelled")
+// statusLabel shows a doctor check status in brackets, colored by outcome.
+func (h *CommandHandler) statusLabel(status string) string {
+	label := fmt.Sprintf("[%-4s]", strings.ToUpper(status))
+	switch status {
+	case doctor.OK:
+		return h.out.Green(label)
+	case doctor.Warn:
+		return h.out.Yellow(label)
+	case doctor.Fail:
+		return h.out.Red(label)
+	}
+	return label
+}
+
+func (h *CommandHandler) showSchema() {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	sqlOutput := fs.Bool("sql", false, "Print CREATE statements instead of JSON")
+	fs.Parse(os.Args[2:])
+
+	db, _ := h.openDatabase()
+	defer db.Close()
+
+	schema, err := db.Schema()
+	if err != nil {
+		log.Fatalf("Failed to read schema: %v", err)
+	}
+	if *sqlOutput {
+		fmt.Print(schema.SQL())
 		return
 	}
-	db, err := database.Connect(h.cfg.Database.Path)
+
+	data, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to format JSON: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+func (h *CommandHandler) exportData() {
+	kind := "events"
+	args := os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		kind = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "", "Output format: parquet for events and daily, csv for billing")
+	output := fs.String("output", "", "File to write, default standard output; the directory for a bundle")
+	full := fs.Bool("full", false, "Rewrite every month of a bundle, not just changed ones")
+	since := fs.String("since", "", "First day to export (YYYY-MM-DD)")
+	until := fs.String("until", "", "Last day to export (YYYY-MM-DD)")
+	profileName := fs.String("profile", "", "Only export events recorded under this profile")
+	increment := fs.Duration("increment", h.cfg.Billing.Increment, "Round each billed session up to this, e.g. 6m or 15m")
+	fs.Parse(args)
+
+	if kind == "bundle" {
+		h.exportBundle(*output, *full)
+		return
+	}
+	formats := map[string]string{"events": "parquet", "daily": "parquet", "billing": "csv"}
+	kindFormat, ok := formats[kind]
+	if !ok {
+		log.Fatalf("Unknown export: %s (use events, daily, billing or bundle)", kind)
+	}
+	if *format != "" && *format != kindFormat {
+		log.Fatalf("Unknown export format for %s: %s (use %s)", kind, *format, kindFormat)
+	}
+	q := database.Query{Profile: *profileName}
+	if *since != "" {
+		day, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			log.Fatalf("Invalid --since date: %v", err)
+		}
+		q.Since = day
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			log.Fatalf("Invalid --until date: %v", err)
+		}
+		q.Until = day.AddDate(0, 0, 1)
+	}
+
+	if kind == "billing" {
+		if *increment < 0 {
+			log.Fatalf("Billing increment must not be negative")
+		}
+		h.exportBilling(*output, q, *increment)
+		return
+	}
+
+	var write func(io.Writer, *database.Repository, database.Query, export.Progress) (int64, error)
+	switch kind {
+	case "events":
+		write = export.EventsParquet
+	case "daily":
+		write = export.DailyParquet
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer file.Close()
+		out = file
+	} else if info, err := out.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		log.Fatalf("Refusing to write Parquet to a terminal; use --output or redirect the output")
 	}
+
+	db, repo := h.openDatabase()
 	defer db.Close()
-	repo := database.NewRepository(db)
-	if err := repo.Clear(); err != nil {
-		log.Fatalf("Failed to clear database: %v", err)
+
+	progress := h.out.Progress("Exporting "+kind, 0)
+	rows, err := write(out, repo, q, progress.Set)
+	progress.Done()
+	if err != nil {
+		log.Fatalf("Failed to export %s: %v", kind, err)
+	}
+	if *output != "" {
+		if err := out.Close(); err != nil {
+			log.Fatalf("Failed to write %s: %v", *output, err)
+		}
+		h.out.Success("Exported %d rows to %s", rows, *output)
 	}
-	fmt.Println("Database cleared successfully")
 }
 
-func (h *CommandHandler) normalizeDatabase() {
-	db, err := database.Connect(h.cfg.Database.Path)
+// exportBilling writes the billable hours and amounts per project as CSV.
+func (h *CommandHandler) exportBilling(output string, q database.Query, increment time.Duration) {
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	billing := h.cfg.Billing
+	billing.Increment = increment
+	lines, err := export.Billing(repo, q, billing, h.cfg.SessionGap())
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to export billing: %v", err)
+	}
+
+	out := os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", output, err)
+		}
+		out = file
+	}
+	if err := export.WriteBillingCSV(out, lines, billing.Currency); err != nil {
+		log.Fatalf("Failed to write billing: %v", err)
+	}
+	if output != "" {
+		if err := out.Close(); err != nil {
+			log.Fatalf("Failed to write %s: %v", output, err)
+		}
+		h.out.Success("Exported %d projects to %s", len(lines), output)
+	}
+}
+
+func (h *CommandHandler) exportBundle(dir string, full bool) {
+	if dir == "" {
+		log.Fatalf("Usage: actionsum export bundle --output <directory> [--full]")
 	}
+
+	db, repo := h.openDatabase()
 	defer db.Close()
-	repo := database.NewRepository(db)
-	count, err := repo.NormalizeAppNames()
+
+	progress := h.out.Progress("Exporting months", 0)
+	result, err := export.Bundle(dir, h.cfg, repo, full, progress.Set)
+	progress.Done()
 	if err != nil {
-		log.Fatalf("Failed to normalize app names: %v", err)
+		log.Fatalf("Failed to export bundle: %v", err)
 	}
-	fmt.Printf("Normalized %d records\n", count)
+	switch {
+	case len(result.Updated) == 0 && len(result.Removed) == 0:
+		fmt.Printf("%s is up to date\n", dir)
+	default:
+		if len(result.Updated) > 0 {
+			h.out.Success("Updated %s", strings.Join(result.Updated, ", "))
+		}
+		if len(result.Removed) > 0 {
+			fmt.Printf("Removed %s\n", strings.Join(result.Removed, ", "))
+		}
+	}
+	fmt.Printf("Query with: cd %s && duckdb -init views.sql\n", dir)
 }
 
-func (h *CommandHandler) serveDaemon(customPort int) {
-	if err := h.cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+func (h *CommandHandler) backfillHistory() {
+	if len(os.Args) < 3 {
+		log.Fatalf("Usage: actionsum backfill <zsh|bash|vscode> [--path <file or dir>] [--app <name>] [--dry-run]")
+	}
+	importer := os.Args[2]
+
+	home, _ := os.UserHomeDir()
+	var defaultPath, defaultApp string
+	switch importer {
+	case "zsh":
+		defaultPath, defaultApp = os.Getenv("HISTFILE"), "terminal"
+		if defaultPath == "" {
+			defaultPath = filepath.Join(home, ".zsh_history")
+		}
+	case "bash":
+		defaultPath, defaultApp = filepath.Join(home, ".bash_history"), "terminal"
+	case "vscode":
+		defaultPath, defaultApp = filepath.Join(home, ".config", "Code", "logs"), "code"
+	default:
+		log.Fatalf("Unknown backfill source: %s (use zsh, bash or vscode)", importer)
+	}
+
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	path := fs.String("path", defaultPath, "History file, or VS Code log directory")
+	appName := fs.String("app", defaultApp, "App name to record the activity under")
+	dryRun := fs.Bool("dry-run", false, "Show what would be imported without storing it")
+	fs.Parse(os.Args[3:])
+
+	var activities []backfill.Activity
+	var err error
+	if importer == "vscode" {
+		activities, err = backfill.ReadVSCodeLogs(*path)
+	} else {
+		var file *os.File
+		if file, err = os.Open(*path); err != nil {
+			log.Fatalf("Failed to open history: %v", err)
+		}
+		if importer == "zsh" {
+			activities, err = backfill.ReadZshHistory(file)
+		} else {
+			activities, err = backfill.ReadBashHistory(file)
+		}
+		file.Close()
 	}
-	dm := daemon.New(h.cfg.Daemon.PIDFile)
-	running, pid, err := dm.IsRunning()
 	if err != nil {
-		log.Fatalf("Failed to check daemon status: %v", err)
+		log.Fatalf("Failed to read %s: %v", *path, err)
 	}
-	if running {
-		log.Fatalf("Daemon is already running (PID: %d)", pid)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	// Only fill in the time before the tracker's first event.
+	cutoff := time.Now()
+	earliest, err := repo.GetEarliestTracked()
+	if err != nil {
+		log.Fatalf("Failed to find the first tracked event: %v", err)
 	}
-	if os.Getenv("ACTIONSUM_DAEMON_CHILD") != "1" {
-		h.daemonize(true)
+	if earliest != nil {
+		cutoff = earliest.Timestamp
+	}
+
+	events := backfill.Events(activities, *appName, cutoff)
+	var seconds int64
+	for _, event := range events {
+		seconds += event.Duration
+	}
+	if len(events) == 0 {
+		fmt.Printf("Nothing to backfill: no activity in %s before %s\n", *path, cutoff.Format("2006-01-02 15:04"))
 		return
 	}
-	h.runServeDaemon(dm, customPort)
+	fmt.Printf("%d activities from %s to %s become %d events, %s as %s\n",
+		len(activities), events[0].Timestamp.Format("2006-01-02"), cutoff.Format("2006-01-02"),
+		len(events), utils.FormatHoursMinutes(seconds), *appName)
+	if *dryRun {
+		return
+	}
+
+	source := backfill.SourcePrefix + importer
+	replaced, err := repo.ReplaceSource(source, events)
+	if err != nil {
+		log.Fatalf("Failed to store backfill: %v", err)
+	}
+	if replaced > 0 {
+		fmt.Printf("Replaced %d events from an earlier %s backfill\n", replaced, importer)
+	}
+	h.audit(repo, "backfill", fmt.Sprintf("imported %d events (%s) from %s", len(events), utils.FormatHoursMinutes(seconds), *path))
 }
 
-func (h *CommandHandler) runServeDaemon(dm *daemon.Daemon, customPort int) {
-	logPath := fmt.Sprintf("/tmp/actionsum-%d.log", os.Getuid())
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err == nil {
-		log.SetOutput(logFile)
-		defer logFile.Close()
+func (h *CommandHandler) generateReport() {
+	periodType := "day"
+	args := os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		periodType = args[0]
+		args = args[1:]
 	}
-	db, err := database.Connect(h.cfg.Database.Path)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	switch periodType {
+	case "gaps":
+		h.reportGaps(args)
+		return
+	case "distractions":
+		h.reportDistractions(args)
+		return
 	}
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	profileName := fs.String("profile", "", "Only include events recorded under this profile")
+	user := fs.String("user", "", "Report on a user of a multi-user server instead of this machine's activity")
+	order := fs.String("sort", reporter.SortTime, "Order apps by "+strings.Join(reporter.SortOrders, ", "))
+	fs.Parse(args)
+
+	db, repo := h.openDatabase()
 	defer db.Close()
-	if err := db.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	det, err := detector.New()
+	rep := reporter.New(h.cfg, repo.ForUser(*user))
+
+	report, err := rep.GenerateProfileReport(periodType, *profileName)
 	if err != nil {
-		log.Fatalf("Failed to initialize window detector: %v", err)
+		log.Fatalf("Failed to generate report: %v", err)
 	}
-	defer det.Close()
-	log.Printf("Window detector initialized: %s", det.GetDisplayServer())
-	if err := dm.WritePID(); err != nil {
-		log.Fatalf("Failed to write PID file: %v", err)
+	if err := reporter.SortApps(report.Apps, *order); err != nil {
+		log.Fatalf("Failed to sort report: %v", err)
 	}
-	defer dm.RemovePID()
-	repo := database.NewRepository(db)
-	trackerSvc := tracker.NewService(h.cfg, repo, det)
-	webServer := web.NewServer(h.cfg, repo, customPort)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		if err := webServer.Start(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Web server error: %v", err)
-		}
-	}()
-	go func() {
-		if err := trackerSvc.Start(ctx); err != nil && err != context.Canceled {
-			log.Printf("Tracker error: %v", err)
-			cancel()
+	if *jsonOutput {
+		jsonStr, err := rep.FormatReportJSON(report)
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
 		}
-	}()
-	log.Println("Starting actionsum daemon with web API...")
-	log.Printf("Web API available at: http://%s", webServer.GetAddress())
-	log.Printf("Configuration:\n%s", h.cfg.String())
-	<-sigChan
-	log.Println("Received shutdown signal")
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-	cancel()
-	trackerSvc.Stop()
-	if err := webServer.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Error shutting down web server: %v", err)
+		fmt.Println(jsonStr)
+	} else {
+		fmt.Println(rep.FormatReportText(report))
 	}
-	log.Println("Daemon stopped successfully")
 }
 
-func (h *CommandHandler) daemonize(withWeb bool) {
-	env := os.Environ()
-	env = append(env, "ACTIONSUM_DAEMON_CHILD=1")
+func (h *CommandHandler) shareReport() {
+	periodType := "week"
+	args := os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		periodType = args[0]
+		args = args[1:]
+	}
 
-	executable, err := os.Executable()
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	output := fs.String("output", "", "File to write (default actionsum-<period>-<date>.html)")
+	profileName := fs.String("profile", "", "Only include events recorded under this profile")
+	redact := fs.Bool("redact", false, "Leave window titles and notes out of the report")
+	fs.Parse(args)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	rep := reporter.New(h.cfg, repo)
+
+	page, err := rep.FormatShareHTML(periodType, *profileName, *redact)
 	if err != nil {
-		log.Fatalf("Failed to get executable path: %v", err)
+		log.Fatalf("Failed to generate report: %v", err)
 	}
 
-	args := os.Args
-	args[0] = executable // Use absolute path instead of argv[0]
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("actionsum-%s-%s.html", periodType, time.Now().Format("2006-01-02"))
+	}
+	if err := os.WriteFile(path, []byte(page), 0o600); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+	fmt.Printf("Wrote %s report to %s\n", periodType, path)
+	if !*redact {
+		fmt.Println("It includes window titles and notes; use --redact to leave them out.")
+	}
+}
 
-	procAttr := &os.ProcAttr{
-		Env:   env,
-		Files: []*os.File{nil, nil, nil},
-		Sys:   &syscall.SysProcAttr{Setsid: true},
+func (h *CommandHandler) reportGaps(args []string) {
+	periodType := "week"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		periodType = args[0]
+		args = args[1:]
 	}
-	process, err := os.StartProcess(executable, args, procAttr)
+
+	fs := flag.NewFlagSet("report gaps", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	minGap := fs.Duration("min", gaps.DefaultMinGap, "Shortest untracked period to report")
+	fs.Parse(args)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	rep := reporter.New(h.cfg, repo)
+
+	report, err := rep.GenerateGapReport(periodType, *minGap)
 	if err != nil {
-		log.Fatalf("Failed to start daemon process: %v", err)
+		log.Fatalf("Failed to generate gap report: %v", err)
 	}
-	logPath := fmt.Sprintf("/tmp/actionsum-%d.log", os.Getuid())
-	if withWeb {
-		fmt.Printf("Daemon started successfully (PID: %d)\n", process.Pid)
-		fmt.Printf("Web API available at: http://localhost:%d\n", h.cfg.Web.Port)
-		fmt.Printf("Logs: %s\n", logPath)
+	if *jsonOutput {
+		jsonStr, err := rep.FormatReportJSON(report)
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
+		}
+		fmt.Println(jsonStr)
 	} else {
-		fmt.Printf("Daemon started successfully (PID: %d)\n", process.Pid)
-		fmt.Printf("Logs: %s\n", logPath)
+		fmt.Println(rep.FormatGapsText(report))
 	}
 }
 
-func showVersion() {
-	fmt.Printf("version -- %s\n", version.Version)
-	fmt.Printf("built ---- %s\n", version.Date)
+func (h *CommandHandler) reportDistractions(args []string) {
+	periodType := "day"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		periodType = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("report distractions", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	profileName := fs.String("profile", "", "Only include events recorded under this profile")
+	fs.Parse(args)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	rep := reporter.New(h.cfg, repo)
+
+	report, err := rep.GenerateDistractionReport(periodType, *profileName)
+	if err != nil {
+		log.Fatalf("Failed to generate distraction report: %v", err)
+	}
+	if *jsonOutput {
+		jsonStr, err := rep.FormatReportJSON(report)
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
+		}
+		fmt.Println(jsonStr)
+	} else {
+		fmt.Println(rep.FormatDistractionsText(report))
+	}
+}
+
+func (h *CommandHandler) showDiff() {
+	var periods []string
+	args := os.Args[2:]
+	for len(args) > 0 && len(periods) < 2 && !strings.HasPrefix(args[0], "-") {
+		periods = append(periods, args[0])
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the comparison as JSON")
+	csvOutput := fs.Bool("csv", false, "Print the comparison as CSV")
+	profileName := fs.String("profile", "", "Only include events recorded under this profile")
+	fs.Parse(args)
+
+	periodType, baseType := "week", ""
+	if len(periods) > 0 {
+		periodType = periods[0]
+	}
+	if len(periods) > 1 {
+		baseType = periods[1]
+	} else if baseType = reporter.PreviousPeriod(periodType); baseType == "" {
+		log.Fatalf("Usage: actionsum diff <period> <base period>")
+	}
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	rep := reporter.New(h.cfg, repo)
+
+	diff, err := rep.GenerateDiff(periodType, baseType, *profileName)
+	if err != nil {
+		log.Fatalf("Failed to compare periods: %v", err)
+	}
+	switch {
+	case *jsonOutput:
+		jsonStr, err := rep.FormatReportJSON(diff)
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
+		}
+		fmt.Println(jsonStr)
+	case *csvOutput:
+		csvStr, err := rep.FormatDiffCSV(diff)
+		if err != nil {
+			log.Fatalf("Failed to format CSV: %v", err)
+		}
+		fmt.Print(csvStr)
+	default:
+		fmt.Println(rep.FormatDiffText(diff))
+	}
+}
+
+func (h *CommandHandler) teamReport() {
+	periodType := "week"
+	args := os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		periodType = args[0]
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("team", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	rep := reporter.New(h.cfg, repo)
+
+	report, err := rep.GenerateTeamReport(periodType)
+	if err != nil {
+		log.Fatalf("Failed to generate team report: %v", err)
+	}
+	if *jsonOutput {
+		jsonStr, err := rep.FormatReportJSON(report)
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
+		}
+		fmt.Println(jsonStr)
+		return
+	}
+	fmt.Print(rep.FormatTeamText(report))
+}
+
+func (h *CommandHandler) clearDatabase() {
+	prompt := "This will delete all tracking data."
+	if h.cfg.Web.MultiUser {
+		prompt = "This will delete this machine's tracking data; other users' data is kept."
+	}
+	fmt.Print(prompt + " Are you sure? (yes/no): ")
+	var response string
+	fmt.Scanln(&response)
+	if response != "yes" && response != "y" {
+		fmt.Println("Operation cancelled")
+		return
+	}
+	db, repo := h.openDatabase()
+	defer db.Close()
+	if err := repo.Clear(); err != nil {
+		log.Fatalf("Failed to clear database: %v", err)
+	}
+	h.audit(repo, "clear", "cleared all tracking data")
+	fmt.Println("Database cleared successfully")
+}
+
+// audit records a change made from the command line, attributed to the
+// local user.
+func (h *CommandHandler) audit(repo *database.Repository, action, summary string) {
+	actor := "cli"
+	if u, err := user.Current(); err == nil {
+		actor += ":" + u.Username
+	}
+	entry := &models.AuditEntry{Actor: actor, Action: action, Summary: summary}
+	if err := repo.RecordAudit(entry); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+	}
+}
+
+// auditOnly records a change that does not otherwise touch the database.
+func (h *CommandHandler) auditOnly(action, summary string) {
+	db, repo := h.openDatabase()
+	defer db.Close()
+	h.audit(repo, action, summary)
+}
+
+func (h *CommandHandler) manageTokens() {
+	usage := "Usage: actionsum token create <name> [--scopes read:events,write:events,admin] [--user <id>] | token list | token revoke <name> [--user <id>]"
+	if len(os.Args) < 3 {
+		log.Fatal(usage)
+	}
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	switch os.Args[2] {
+	case "create":
+		if len(os.Args) < 4 || strings.HasPrefix(os.Args[3], "-") {
+			log.Fatal(usage)
+		}
+		name := os.Args[3]
+		fs := flag.NewFlagSet("token create", flag.ExitOnError)
+		scopeList := fs.String("scopes", auth.ScopeRead, "Comma-separated scopes: read:events, write:events, admin")
+		user := fs.String("user", "", "User whose data the token reads and writes in multi-user mode")
+		fs.Parse(os.Args[4:])
+
+		scopes, err := auth.ParseScopes(*scopeList)
+		if err != nil {
+			log.Fatalf("Invalid scopes: %v", err)
+		}
+		if *user != "" {
+			if err := auth.CheckUserID(*user); err != nil {
+				log.Fatalf("Invalid user: %v", err)
+			}
+			if !h.cfg.Web.MultiUser {
+				fmt.Println("Note: the user only takes effect with ACTIONSUM_WEB_MULTI_USER=true")
+			}
+		}
+		plain, hash, err := auth.Generate()
+		if err != nil {
+			log.Fatalf("Failed to create token: %v", err)
+		}
+		if err := repo.CreateToken(&models.APIToken{Name: name, Hash: hash, Scopes: scopes, UserID: *user}); err != nil {
+			log.Fatalf("Failed to create token: %v", err)
+		}
+		summary := fmt.Sprintf("created token %s with scopes %s", name, scopes)
+		if *user != "" {
+			summary += " for user " + *user
+		}
+		h.audit(repo, "token.create", summary)
+
+		fmt.Printf("Token %s (%s):\n%s\n", name, scopes, h.out.Bold(plain))
+		fmt.Println(h.out.Yellow("Store it now; it cannot be shown again."))
+	case "list":
+		tokens, err := repo.GetTokens()
+		if err != nil {
+			log.Fatalf("Failed to list tokens: %v", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No API tokens")
+			return
+		}
+		table := console.NewTable("NAME", "SCOPES", "USER", "USE")
+		for _, token := range tokens {
+			lastUsed := "never used"
+			if token.LastUsedAt != nil {
+				lastUsed = "last used " + token.LastUsedAt.Local().Format("2006-01-02 15:04")
+			}
+			if token.ExpiresAt != nil {
+				lastUsed += ", expires " + token.ExpiresAt.Local().Format("2006-01-02 15:04")
+			}
+			user := token.UserID
+			if user == "" {
+				user = "-"
+			}
+			table.Row(token.Name, token.Scopes, user, lastUsed)
+		}
+		table.Write(h.out)
+	case "revoke":
+		if len(os.Args) < 4 {
+			log.Fatal(usage)
+		}
+		name := os.Args[3]
+		fs := flag.NewFlagSet("token revoke", flag.ExitOnError)
+		user := fs.String("user", "", "User the token belongs to in multi-user mode")
+		fs.Parse(os.Args[4:])
+
+		found, err := repo.RevokeToken(*user, name)
+		if err != nil {
+			log.Fatalf("Failed to revoke token: %v", err)
+		}
+		if !found {
+			log.Fatalf("No token named %s", name)
+		}
+		summary := "revoked token " + name
+		if *user != "" {
+			summary += " of user " + *user
+		}
+		h.audit(repo, "token.revoke", summary)
+		h.out.Success("Token %s revoked", name)
+	default:
+		log.Fatal(usage)
+	}
+}
+
+func (h *CommandHandler) showAudit() {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "Number of entries to show")
+	jsonOutput := fs.Bool("json", false, "Print entries as JSON")
+	fs.Parse(os.Args[2:])
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	entries, err := repo.GetAuditEntries(*limit)
+	if err != nil {
+		log.Fatalf("Failed to read audit log: %v", err)
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to format JSON: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No changes recorded")
+		return
+	}
+	table := console.NewTable("TIME", "ACTOR", "ACTION", "SUMMARY")
+	for _, entry := range entries {
+		table.Row(h.out.Dim(entry.Timestamp.Local().Format("2006-01-02 15:04:05")), entry.Actor, entry.Action, entry.Summary)
+	}
+	table.Write(h.out)
+}
+
+func (h *CommandHandler) normalizeDatabase() {
+	db, repo := h.openDatabase()
+	defer db.Close()
+	count, err := repo.NormalizeAppNames()
+	if err != nil {
+		log.Fatalf("Failed to normalize app names: %v", err)
+	}
+	h.audit(repo, "normalize", fmt.Sprintf("normalized %d records", count))
+	fmt.Printf("Normalized %d records\n", count)
+}
+
+func (h *CommandHandler) repairDatabase() {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Report what would change without writing")
+	fs.Parse(os.Args[2:])
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	result, err := repair.New(repo, h.cfg.Tracker.MaxPollInterval).Run(*dryRun)
+	if err != nil {
+		log.Fatalf("Failed to repair database: %v", err)
+	}
+
+	const maxPerKind = 20
+	shown := make(map[string]int)
+	table := console.NewTable().Indent("  ")
+	for _, change := range result.Changes {
+		shown[change.Kind]++
+		if shown[change.Kind] <= maxPerKind {
+			table.Row(change.Kind, change.Description)
+		}
+	}
+	for kind, count := range shown {
+		if count > maxPerKind {
+			table.Row(kind, fmt.Sprintf("... and %d more", count-maxPerKind))
+		}
+	}
+	table.Write(h.out)
+
+	verb := "Repaired"
+	if result.DryRun {
+		verb = "Would repair"
+	} else {
+		h.audit(repo, "repair", fmt.Sprintf("%d renamed, %d merged, %d clamped", result.Renamed, result.Merged, result.Clamped))
+	}
+	summary := fmt.Sprintf("%s: %d renamed, %d merged, %d clamped", verb, result.Renamed, result.Merged, result.Clamped)
+	if result.DryRun {
+		fmt.Println(summary)
+	} else {
+		h.out.Success("%s", summary)
+	}
+}
+
+// recoverCorruptDatabase checks the database before the daemon opens it.
+// A corrupt one is rebuilt, when enabled, so tracking carries on into a
+// fresh file rather than the daemon failing on every restart.
+func (h *CommandHandler) recoverCorruptDatabase() {
+	var corrupt *database.CorruptionError
+	err := database.CheckIntegrity(h.cfg.Database.Path)
+	if !errors.As(err, &corrupt) {
+		if err != nil {
+			log.Printf("Failed to check database integrity: %v", err)
+		}
+		return
+	}
+	log.Printf("Database corruption detected: %v", corrupt)
+	if !h.cfg.Database.AutoRecover {
+		log.Fatalf("Refusing to start on a corrupt database; run actionsum recover or set ACTIONSUM_DB_AUTO_RECOVER=true")
+	}
+
+	result, err := database.Recover(h.cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to recover database: %v", err)
+	}
+	summary := recoverySummary(result)
+	log.Printf("Database recovered: %s", summary)
+
+	if notifier := notify.FromConfig(h.cfg); notifier != nil {
+		msg := notify.Message{
+			Title:   "actionsum database recovered",
+			Body:    fmt.Sprintf("The database was corrupt and has been rebuilt: %s. Tracking continues.", summary),
+			Urgency: "critical",
+			Data: map[string]any{
+				"corrupt_path": result.CorruptPath,
+				"rows":         result.Rows(),
+				"lost":         result.Lost,
+			},
+		}
+		if err := notifier.Notify(msg); err != nil {
+			log.Printf("Failed to send recovery notification: %v", err)
+		}
+	}
+}
+
+func recoverySummary(result *database.RecoveryResult) string {
+	summary := fmt.Sprintf("%d rows recovered", result.Rows())
+	if result.Lost > 0 {
+		summary += fmt.Sprintf(", %d lost", result.Lost)
+	}
+	if len(result.Unreadable) > 0 {
+		summary += fmt.Sprintf(", unreadable: %s", strings.Join(result.Unreadable, ", "))
+	}
+	return summary + fmt.Sprintf("; damaged file kept at %s", result.CorruptPath)
+}
+
+func (h *CommandHandler) recoverDatabase() {
+	fs := flag.NewFlagSet("recover", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Recover without asking")
+	fs.Parse(os.Args[2:])
+
+	var corrupt *database.CorruptionError
+	err := database.CheckIntegrity(h.cfg.Database.Path)
+	if !errors.As(err, &corrupt) {
+		if err != nil {
+			log.Fatalf("Failed to check database integrity: %v", err)
+		}
+		fmt.Println("Database integrity check passed; nothing to recover")
+		return
+	}
+
+	fmt.Println(h.out.Red(fmt.Sprintf("Database %s is corrupt:", corrupt.Path)))
+	for _, problem := range corrupt.Problems {
+		fmt.Printf("  %s\n", problem)
+	}
+
+	running, pid, err := daemon.New(h.cfg.Daemon.PIDFile).IsRunning()
+	if err != nil {
+		log.Fatalf("Failed to check daemon status: %v", err)
+	}
+	if running {
+		log.Fatalf("Daemon is running (PID: %d); stop it before recovering", pid)
+	}
+
+	if !*yes {
+		fmt.Print("Rebuild it from the rows that can still be read? (yes/no): ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "yes" && response != "y" {
+			fmt.Println("Operation cancelled")
+			return
+		}
+	}
+
+	result, err := database.Recover(h.cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to recover database: %v", err)
+	}
+	tables := make([]string, 0, len(result.Recovered))
+	for table := range result.Recovered {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	rows := console.NewTable().Indent("  ").AlignRight(1)
+	for _, table := range tables {
+		rows.Row(table, fmt.Sprintf("%d rows", result.Recovered[table]))
+	}
+	rows.Write(h.out)
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+	h.audit(repo, "recover", recoverySummary(result))
+	h.out.Success("Database recovered: %s", recoverySummary(result))
+}
+
+func (h *CommandHandler) mergeDatabase() {
+	if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+		log.Fatalf("Usage: actionsum merge <other.db> [--dry-run]")
+	}
+	path := os.Args[2]
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Report what would be merged without writing")
+	fs.Parse(os.Args[3:])
+
+	if a, err := filepath.Abs(path); err == nil {
+		if b, err := filepath.Abs(h.cfg.Database.Path); err == nil && a == b {
+			log.Fatalf("%s is the current database", path)
+		}
+	}
+
+	// The other database is read from a copy, which is migrated to the
+	// current schema without touching the original.
+	otherDB, err := database.ConnectCopy(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer otherDB.Discard()
+	if err := otherDB.Initialize(); err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	progress := h.out.Progress("Merging events", 0)
+	result, err := merge.Run(repo, database.NewRepository(otherDB), *dryRun, progress.Set)
+	progress.Done()
+	if err != nil {
+		log.Fatalf("Failed to merge %s: %v", path, err)
+	}
+
+	const maxConflicts = 20
+	if len(result.Conflicts) > 0 {
+		fmt.Println(h.out.Yellow("Conflicts (kept the event in this database):"))
+		table := console.NewTable().Indent("  ")
+		for i, conflict := range result.Conflicts {
+			if i == maxConflicts {
+				table.Row("...", fmt.Sprintf("and %d more", len(result.Conflicts)-maxConflicts))
+				break
+			}
+			table.Row(conflict.Kind, conflict.String())
+		}
+		table.Write(h.out)
+	}
+
+	verb := "Merged"
+	if result.DryRun {
+		verb = "Would merge"
+	}
+	summary := fmt.Sprintf("%d of %d events (%d duplicates, %d deleted here, %d conflicts, %d invalid) and %d notes",
+		result.Added, result.Scanned, result.Duplicates, result.Deleted, len(result.Conflicts), result.Invalid, result.NotesAdded)
+	if result.DryRun {
+		fmt.Printf("%s %s from %s\n", verb, summary, path)
+	} else {
+		h.out.Success("%s %s from %s", verb, summary, path)
+	}
+	if !result.DryRun {
+		h.audit(repo, "merge", fmt.Sprintf("merged %s from %s", summary, path))
+	}
+}
+
+func (h *CommandHandler) manageProfile() {
+	if len(os.Args) < 3 {
+		name, source := profile.Resolve(h.cfg, time.Now())
+		fmt.Printf("%s (%s)\n", name, source)
+		return
+	}
+
+	switch os.Args[2] {
+	case "use":
+		if len(os.Args) < 4 {
+			log.Fatalf("Usage: actionsum profile use <name|%s>", profile.Auto)
+		}
+		name := strings.ToLower(os.Args[3])
+		if err := profile.Use(h.cfg.Profiles.StateFile, name); err != nil {
+			log.Fatalf("Failed to switch profile: %v", err)
+		}
+		h.auditOnly("profile.use", "switched profile to "+name)
+		if name == profile.Auto {
+			name, source := profile.Resolve(h.cfg, time.Now())
+			fmt.Printf("Profile selection cleared, now using %s (%s)\n", name, source)
+			return
+		}
+		fmt.Printf("Now tracking under profile %s\n", name)
+	case "list":
+		db, repo := h.openDatabase()
+		defer db.Close()
+		profiles, err := repo.GetProfiles()
+		if err != nil {
+			log.Fatalf("Failed to list profiles: %v", err)
+		}
+		current, _ := profile.Resolve(h.cfg, time.Now())
+		for _, name := range profiles {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		if len(h.cfg.Profiles.Rules) > 0 {
+			fmt.Println("\nRules:")
+			for _, rule := range h.cfg.Profiles.Rules {
+				fmt.Printf("  %s: %s\n", rule.Profile, rule.Window)
+			}
+		}
+	default:
+		log.Fatalf("Unknown profile command: %s", os.Args[2])
+	}
+}
+
+func (h *CommandHandler) showDigest() {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	send := fs.Bool("send", false, "Deliver the digest through the configured channels")
+	current := fs.Bool("current", false, "Summarize the current week instead of the previous one")
+	pdfPath := fs.String("pdf", "", "Also write the digest as a PDF file")
+	fs.Parse(os.Args[2:])
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	weekStart := digest.WeekStart(time.Now())
+	if !*current {
+		weekStart = weekStart.AddDate(0, 0, -7)
+	}
+
+	d, err := digest.Build(repo, weekStart, budget.FromConfig(h.cfg, repo), holiday.Load(h.cfg.Holidays), h.cfg.WorkHours.Windows)
+	if err != nil {
+		log.Fatalf("Failed to build digest: %v", err)
+	}
+	fmt.Println(d.Title())
+	fmt.Println()
+	fmt.Print(d.Text())
+
+	if *pdfPath != "" {
+		if err := os.WriteFile(*pdfPath, d.PDF(time.Now()), 0644); err != nil {
+			log.Fatalf("Failed to write PDF: %v", err)
+		}
+		fmt.Printf("\nPDF written to %s\n", *pdfPath)
+	}
+
+	if *send {
+		notifier := notify.FromConfig(h.cfg)
+		if notifier == nil {
+			log.Fatalf("No notification channel configured")
+		}
+		if err := notifier.Notify(d.Message(h.cfg.Notify.DigestPDF)); err != nil {
+			log.Fatalf("Failed to send digest: %v", err)
+		}
+		fmt.Println("\nDigest sent")
+	}
+}
+
+func (h *CommandHandler) manageTag() {
+	if len(os.Args) < 3 {
+		name, until := tag.Active(h.cfg.Tags.StateFile, time.Now())
+		if name == "" {
+			fmt.Println("No active tag")
+			return
+		}
+		fmt.Printf("%s (until %s)\n", name, until.Local().Format("15:04"))
+		return
+	}
+
+	name := strings.ToLower(os.Args[2])
+	if name == "clear" {
+		if err := tag.Clear(h.cfg.Tags.StateFile); err != nil {
+			log.Fatalf("Failed to clear tag: %v", err)
+		}
+		h.auditOnly("tag.clear", "cleared tag")
+		fmt.Println("Tag cleared")
+		return
+	}
+
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	duration := fs.Duration("for", h.cfg.Tags.DefaultDuration, "How long the tag lasts")
+	fs.Parse(os.Args[3:])
+	if *duration <= 0 {
+		log.Fatalf("Tag duration must be positive")
+	}
+
+	until := time.Now().Add(*duration)
+	if err := tag.Set(h.cfg.Tags.StateFile, name, until); err != nil {
+		log.Fatalf("Failed to set tag: %v", err)
+	}
+	h.auditOnly("tag.set", fmt.Sprintf("tagged activity as %s until %s", name, until.Format(time.RFC3339)))
+	fmt.Printf("Tagging activity as %s until %s\n", name, until.Format("15:04"))
+}
+
+func (h *CommandHandler) manageAway() {
+	interval, ok := away.Pending(h.cfg.Tracker.AwayFile)
+	if !ok {
+		fmt.Println("No away time waiting for a reason")
+		return
+	}
+	span := fmt.Sprintf("%s to %s (%s)", interval.Start.Local().Format("2006-01-02 15:04"),
+		interval.End.Local().Format("15:04"), utils.FormatHoursMinutes(int64(interval.Duration().Seconds())))
+	if len(os.Args) < 3 {
+		fmt.Printf("Away %s\n", span)
+		fmt.Println(`Record it with "actionsum away <reason>", or skip it with "actionsum away clear"`)
+		return
+	}
+
+	if len(os.Args) == 3 && os.Args[2] == "clear" {
+		if err := away.Clear(h.cfg.Tracker.AwayFile); err != nil {
+			log.Fatalf("Failed to clear away time: %v", err)
+		}
+		fmt.Println("Away time left unrecorded")
+		return
+	}
+
+	reason := strings.Join(os.Args[2:], " ")
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	activeProfile, _ := profile.Resolve(h.cfg, interval.Start)
+	if _, err := away.Fill(repo, interval, reason, activeProfile, h.cfg.Tracker.MaxPollInterval); err != nil {
+		log.Fatalf("Failed to record away time: %v", err)
+	}
+	if err := away.Clear(h.cfg.Tracker.AwayFile); err != nil {
+		log.Printf("Failed to clear away time: %v", err)
+	}
+	h.audit(repo, "away", fmt.Sprintf("recorded %s as %s", span, reason))
+	fmt.Printf("Recorded %s as %s\n", span, reason)
+}
+
+func (h *CommandHandler) manageHoliday() {
+	command := "list"
+	if len(os.Args) >= 3 {
+		command = os.Args[2]
+	}
+
+	switch command {
+	case "list":
+		today := time.Now().Format("2006-01-02")
+		thisYear := today[:4]
+		if err := holiday.Refresh(h.cfg.Holidays); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: holiday calendar: %v\n", err)
+		}
+		days := holiday.Load(h.cfg.Holidays).Sorted()
+		shown := 0
+		for _, day := range days {
+			if day.Date < thisYear {
+				continue
+			}
+			marker := " "
+			if day.Date == today {
+				marker = "*"
+			}
+			fmt.Printf("%s %s  %-7s  %s\n", marker, day.Date, day.Kind, day.Name)
+			shown++
+		}
+		if shown == 0 {
+			fmt.Println("No days off this year")
+		}
+	case "add":
+		if len(os.Args) < 4 {
+			log.Fatalf("Usage: actionsum holiday add YYYY-MM-DD [--until YYYY-MM-DD] [--pto] [name]")
+		}
+		start, err := holiday.ParseDate(os.Args[3])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fs := flag.NewFlagSet("holiday add", flag.ExitOnError)
+		untilDate := fs.String("until", "", "Last day off, for several days in a row")
+		pto := fs.Bool("pto", false, "Mark the days as PTO rather than a public holiday")
+		fs.Parse(os.Args[4:])
+
+		end := start
+		if *untilDate != "" {
+			if end, err = holiday.ParseDate(*untilDate); err != nil {
+				log.Fatalf("%v", err)
+			}
+			if end.Before(start) {
+				log.Fatalf("--until is before the first day off")
+			}
+		}
+		kind := holiday.Holiday
+		if *pto {
+			kind = holiday.PTO
+		}
+		name := strings.Join(fs.Args(), " ")
+
+		count := 0
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if err := holiday.Add(h.cfg.Holidays.File, holiday.Day{Date: day.Format("2006-01-02"), Kind: kind, Name: name}); err != nil {
+				log.Fatalf("Failed to add day off: %v", err)
+			}
+			count++
+		}
+		span := start.Format("2006-01-02")
+		if count > 1 {
+			span += " to " + end.Format("2006-01-02")
+		}
+		h.auditOnly("holiday.add", fmt.Sprintf("marked %s as %s", span, kind))
+		fmt.Printf("Marked %s as %s\n", span, kind)
+	case "remove":
+		if len(os.Args) < 4 {
+			log.Fatalf("Usage: actionsum holiday remove YYYY-MM-DD")
+		}
+		date := os.Args[3]
+		if _, err := holiday.ParseDate(date); err != nil {
+			log.Fatalf("%v", err)
+		}
+		removed, err := holiday.Remove(h.cfg.Holidays.File, date)
+		if err != nil {
+			log.Fatalf("Failed to remove day off: %v", err)
+		}
+		if !removed {
+			fmt.Printf("%s is not marked as a day off\n", date)
+			return
+		}
+		h.auditOnly("holiday.remove", "unmarked "+date)
+		fmt.Printf("%s is no longer a day off\n", date)
+	default:
+		log.Fatalf("Unknown holiday command: %s", command)
+	}
+}
+
+func (h *CommandHandler) addNote() {
+	fs := flag.NewFlagSet("note", flag.ExitOnError)
+	from := fs.String("from", "", "Start time (HH:MM); omit for a note on the whole day")
+	to := fs.String("to", "", "End time (HH:MM), default now")
+	date := fs.String("date", "", "Day of the note (YYYY-MM-DD), default today")
+
+	// Allow the text before or after the flags.
+	args := os.Args[2:]
+	var text []string
+	for len(args) > 0 {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) > 0 {
+			text = append(text, args[0])
+			args = args[1:]
+		}
+	}
+	if len(text) == 0 {
+		log.Fatalf("Usage: actionsum note \"text\" [--from HH:MM] [--to HH:MM] [--date YYYY-MM-DD]")
+	}
+
+	start, end, err := notes.ParseRange(*date, *from, *to, time.Now())
+	if err != nil {
+		log.Fatalf("Invalid note range: %v", err)
+	}
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	note := &models.Note{Start: start, End: end, Text: strings.Join(text, " ")}
+	if err := repo.CreateNote(note); err != nil {
+		log.Fatalf("Failed to save note: %v", err)
+	}
+	h.audit(repo, "note.create", fmt.Sprintf("note #%d: %s", note.ID, note.Text))
+	fmt.Printf("Note added for %s to %s\n", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))
+}
+
+func (h *CommandHandler) showBudgets() {
+	if len(h.cfg.Budgets.Limits) == 0 {
+		fmt.Println("No budgets configured (set ACTIONSUM_BUDGETS, e.g. youtube=30m,social=1h)")
+		return
+	}
+
+	db, repo := h.openDatabase()
+	defer db.Close()
+
+	usage, err := budget.Today(repo, budget.FromConfig(h.cfg, repo), time.Now())
+	if err != nil {
+		log.Fatalf("Failed to compute budget usage: %v", err)
+	}
+
+	mode := "notify"
+	if h.cfg.Budgets.Strict {
+		mode = "strict"
+	}
+	fmt.Printf("Daily budgets (%s mode)\n\n", mode)
+	table := console.NewTable().Indent("  ").AlignRight(1)
+	for _, u := range usage {
+		status := h.out.Green("ok")
+		if u.Exceeded() {
+			status = h.out.Red("EXCEEDED")
+		}
+		table.Row(u.Budget.Name, utils.FormatHoursMinutes(int64(u.Used.Seconds())), "/ "+u.Budget.Limit.String(), status)
+	}
+	table.Write(h.out)
+}
+
+func (h *CommandHandler) enableAutostart() {
+	fs := flag.NewFlagSet("enable-autostart", flag.ExitOnError)
+	withWeb := fs.Bool("serve", false, "Start the web server along with the tracker")
+	fs.Parse(os.Args[2:])
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to get executable path: %v", err)
+	}
+
+	command := "start"
+	if *withWeb {
+		if reason := h.webUnavailable(); reason != "" {
+			log.Fatalf("Cannot start the web server at login: %s", reason)
+		}
+		command = "serve"
+	}
+
+	path, err := autostart.Enable(executable, command)
+	if err != nil {
+		log.Fatalf("Failed to enable autostart: %v", err)
+	}
+	fmt.Printf("Autostart enabled: %s\n", path)
+}
+
+func (h *CommandHandler) disableAutostart() {
+	path, err := autostart.Disable()
+	if err != nil {
+		log.Fatalf("Failed to disable autostart: %v", err)
+	}
+	fmt.Printf("Autostart disabled: %s\n", path)
+}
+
+// manageGnomeExtension installs or removes the GNOME Shell extension the
+// Wayland detector prefers over Shell.Eval, and enables it.
+func (h *CommandHandler) manageGnomeExtension() {
+	if len(os.Args) < 3 {
+		log.Fatalf("Usage: actionsum gnome-extension <install|uninstall>")
+	}
+	if sandbox.Flatpak() {
+		log.Fatalf("GNOME Shell extensions cannot be installed from inside Flatpak; copy the extension from the actionsum sources to ~/.local/share/gnome-shell/extensions/%s instead", gnomeext.UUID)
+	}
+
+	switch os.Args[2] {
+	case "install":
+		shell, err := gnomeext.ShellVersion()
+		if err != nil {
+			log.Printf("Warning: %v; installing the extension for GNOME 45 and later", err)
+		}
+		dir, err := gnomeext.Install(shell)
+		if err != nil {
+			log.Fatalf("Failed to install GNOME Shell extension: %v", err)
+		}
+		fmt.Printf("GNOME Shell extension installed: %s\n", dir)
+		// GNOME Shell on Wayland only finds new extensions at login.
+		if err := sandbox.Command("gnome-extensions", "enable", gnomeext.UUID).Run(); err != nil {
+			fmt.Printf("Log out and back in, then run: gnome-extensions enable %s\n", gnomeext.UUID)
+			return
+		}
+		fmt.Println("Extension enabled")
+	case "uninstall":
+		sandbox.Command("gnome-extensions", "disable", gnomeext.UUID).Run()
+		dir, err := gnomeext.Uninstall()
+		if err != nil {
+			log.Fatalf("Failed to remove GNOME Shell extension: %v", err)
+		}
+		fmt.Printf("GNOME Shell extension removed: %s\n", dir)
+	default:
+		log.Fatalf("Unknown gnome-extension command %q: use install or uninstall", os.Args[2])
+	}
+}
+
+// manageBrowserExtension installs or removes the browser extension and the
+// native messaging host through which it reports the active tab.
+func (h *CommandHandler) manageBrowserExtension() {
+	if len(os.Args) < 3 {
+		log.Fatalf("Usage: actionsum browser <install|uninstall>")
+	}
+
+	switch os.Args[2] {
+	case "install":
+		fs := flag.NewFlagSet("browser install", flag.ExitOnError)
+		chromeID := fs.String("chrome-id", "", "IDs chrome://extensions shows for the loaded extension, comma-separated")
+		fs.Parse(os.Args[3:])
+		var chromeIDs []string
+		for _, id := range strings.Split(*chromeID, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				chromeIDs = append(chromeIDs, id)
+			}
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatalf("Failed to get executable path: %v", err)
+		}
+		inst, err := browser.Install(executable, chromeIDs)
+		if err != nil {
+			log.Fatalf("Failed to install browser integration: %v", err)
+		}
+		for _, manifest := range inst.Manifests {
+			fmt.Printf("Native messaging host registered: %s\n", manifest)
+		}
+		fmt.Printf("Extension written to %s\n", inst.Extension)
+		fmt.Println("Firefox: load manifest.json from there in about:debugging, This Firefox, Load Temporary Add-on.")
+		if len(chromeIDs) == 0 {
+			fmt.Println("Chrome and others: load the directory in chrome://extensions with developer mode on, then run:")
+			fmt.Println("  actionsum browser install --chrome-id <the extension's ID>")
+		}
+	case "uninstall":
+		removed, err := browser.Uninstall()
+		if err != nil {
+			log.Fatalf("Failed to remove browser integration: %v", err)
+		}
+		for _, manifest := range removed {
+			fmt.Printf("Native messaging host removed: %s\n", manifest)
+		}
+		fmt.Println("Remove the extension from the browser as well.")
+	default:
+		log.Fatalf("Unknown browser command %q: use install or uninstall", os.Args[2])
+	}
+}
+
+// runNativeHost serves the browser extension, which starts it through
+// native messaging, until the browser disconnects. Nothing but messages
+// may be written to stdout.
+func (h *CommandHandler) runNativeHost() {
+	log.SetOutput(os.Stderr)
+	if err := browser.RunHost(os.Stdin, os.Stdout, h.cfg.Tracker.BrowserTabFile); err != nil {
+		log.Fatalf("Native messaging host: %v", err)
+	}
+}
+
+// runRemoteAgent reports the active tmux window on this machine, usually a
+// server reached over SSH, to the actionsum web API on the user's own
+// machine until interrupted.
+func (h *CommandHandler) runRemoteAgent() {
+	fs := flag.NewFlagSet("remote-agent", flag.ExitOnError)
+	url := fs.String("url", fmt.Sprintf("http://localhost:%d", h.cfg.Web.Port), "Base URL of the web API, e.g. through ssh -R")
+	token := fs.String("token", os.Getenv("ACTIONSUM_TOKEN"), "API token with the write:events scope (default $ACTIONSUM_TOKEN)")
+	host := fs.String("host", "", "Host name to report (default this machine's)")
+	interval := fs.Duration("interval", remoteterm.AgentInterval, "How often to report")
+	fs.Parse(os.Args[2:])
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	agent := &remoteterm.Agent{URL: *url, Token: *token, Host: *host, Interval: *interval}
+	log.Printf("Reporting tmux activity to %s every %v", *url, agent.Interval)
+	if err := agent.Run(ctx, log.Printf); err != nil {
+		log.Fatalf("Remote agent: %v", err)
+	}
+}
+
+// printShellHook prints the hook a shell evaluates at startup to report
+// its commands through shell-event.
+func (h *CommandHandler) printShellHook() {
+	if len(os.Args) < 3 {
+		log.Fatalf("Usage: actionsum shell-hook <bash|zsh>")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to get executable path: %v", err)
+	}
+	script, err := shell.Hook(os.Args[2], executable)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fmt.Print(script)
+}
+
+// recordShellEvent records what a shell hook reports: the command starting
+// with --command, the prompt without it, or with --exit, the shell exiting.
+// It runs on every prompt, so it stays quiet.
+func (h *CommandHandler) recordShellEvent() {
+	fs := flag.NewFlagSet("shell-event", flag.ExitOnError)
+	pid := fs.Int("pid", 0, "PID of the shell")
+	dir := fs.String("dir", "", "Working directory")
+	command := fs.String("command", "", "Command line starting, or none at the prompt")
+	exit := fs.Bool("exit", false, "The shell is exiting")
+	fs.Parse(os.Args[2:])
+	if *pid <= 0 {
+		log.Fatalf("Usage: actionsum shell-event --pid <pid> [--dir <dir>] [--command <line>] [--exit]")
+	}
+
+	stateDir := h.cfg.Tracker.ShellStateDir
+	if *exit {
+		if err := shell.Remove(stateDir, *pid); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	home, _ := os.UserHomeDir()
+	redact := shell.Pattern(h.cfg.Tracker.ShellRedact)
+	state := &shell.State{
+		PID:       *pid,
+		Command:   shell.Redact(*command, redact),
+		Dir:       shell.Redact(shell.HomeRelative(*dir, home), redact),
+		UpdatedAt: time.Now(),
+	}
+	if err := shell.Record(stateDir, state); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// daemonLogPath is where a background daemon writes its log.
+func daemonLogPath() string {
+	return filepath.Join(sandbox.RuntimeDir(), fmt.Sprintf("actionsum-%d.log", os.Getuid()))
+}
+
+func (h *CommandHandler) openDatabase() (*database.DB, *database.Repository) {
+	db, err := database.Connect(h.cfg.Database.Path,
+		database.WithLogging(h.cfg.Database.LogLevel, h.cfg.Database.SlowQueryThreshold),
+	)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.Initialize(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	normalizer, err := normalize.Load(h.cfg.AppNames.MappingFile)
+	if err != nil {
+		log.Printf("Failed to load app name mapping: %v", err)
+	}
+	if h.cfg.AppNames.DesktopEntries {
+		normalizer.LoadDesktopEntries(normalize.DesktopDirs())
+	}
+
+	opts := []database.Option{
+		database.WithNormalizer(normalizer),
+		database.WithMaxEventDuration(h.cfg.Tracker.MaxPollInterval),
+	}
+	if h.cfg.Report.ExcludeProcessGuesses {
+		opts = append(opts, database.WithoutProcessGuesses())
+	}
+	return db, database.NewRepository(db, opts...)
+}
+
+// webUnavailable explains why this actionsum can't serve the web API, or
+// returns "" when it can.
+func (h *CommandHandler) webUnavailable() string {
+	switch {
+	case !webIncluded:
+		return "this actionsum was built without the web server (-tags noweb). " +
+			"Use \"actionsum start\" to track and \"actionsum report\" for reports, " +
+			"or install the full build with: go install github.com/actionsum/actionsum@latest"
+	case h.cfg.Web.Disabled:
+		return "the web server is disabled by ACTIONSUM_WEB_DISABLED=true. " +
+			"Use \"actionsum start\" to track without it, or unset the variable to serve"
+	}
+	return ""
+}
+
+func (h *CommandHandler) serveDaemon(customPort int) {
+	if reason := h.webUnavailable(); reason != "" {
+		log.Fatalf("Cannot serve: %s", reason)
+	}
+	h.parseDaemonFlags("serve")
+	if err := h.cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	dm := daemon.New(h.cfg.Daemon.PIDFile)
+	running, pid, err := dm.IsRunning()
+	if err != nil {
+		log.Fatalf("Failed to check daemon status: %v", err)
+	}
+	if running {
+		log.Fatalf("Daemon is already running (PID: %d)", pid)
+	}
+	if os.Getenv("ACTIONSUM_DAEMON_CHILD") != "1" {
+		h.daemonize(true)
+		return
+	}
+	h.runServeDaemon(dm, customPort)
+}
+
+func (h *CommandHandler) daemonize(withWeb bool) {
+	env := os.Environ()
+	env = append(env, "ACTIONSUM_DAEMON_CHILD=1")
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to get executable path: %v", err)
+	}
+
+	args := os.Args
+	args[0] = executable // Use absolute path instead of argv[0]
+
+	procAttr := &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{nil, nil, nil},
+		Sys:   detachedProcAttr(),
+	}
+	process, err := os.StartProcess(executable, args, procAttr)
+	if err != nil {
+		log.Fatalf("Failed to start daemon process: %v", err)
+	}
+	logPath := daemonLogPath()
+	if withWeb {
+		fmt.Printf("Daemon started successfully (PID: %d)\n", process.Pid)
+		fmt.Printf("Web API available at: http://localhost:%d\n", h.cfg.Web.Port)
+		fmt.Printf("Logs: %s\n", logPath)
+	} else {
+		fmt.Printf("Daemon started successfully (PID: %d)\n", process.Pid)
+		fmt.Printf("Logs: %s\n", logPath)
+	}
+}
+
+func showVersion() {
+	fmt.Printf("version -- %s\n", version.Version)
+	fmt.Printf("built ---- %s\n", version.Date)
+	if !webIncluded {
+		fmt.Println("web ------ not included (built with -tags noweb)")
+	}
 }
@@ -0,0 +1,80 @@
+// Package dbus connects to the session and system buses through godbus, and
+// asks logind, the screensaver and media players about the desktop session,
+// so that actionsum needs neither gdbus nor loginctl.
+package dbus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// Conn is a connection to a bus.
+type Conn = godbus.Conn
+
+// SessionBusAddress returns the address of the session bus, from
+// DBUS_SESSION_BUS_ADDRESS or the socket at $XDG_RUNTIME_DIR/bus.
+func SessionBusAddress() (string, error) {
+	if address := os.Getenv("DBUS_SESSION_BUS_ADDRESS"); address != "" {
+		return address, nil
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		path := filepath.Join(runtimeDir, "bus")
+		if _, err := os.Stat(path); err == nil {
+			return "unix:path=" + path, nil
+		}
+	}
+	return "", fmt.Errorf("no session bus address")
+}
+
+// SystemBusAddress returns the address of the system bus, from
+// DBUS_SYSTEM_BUS_ADDRESS or the socket at its well-known path.
+func SystemBusAddress() (string, error) {
+	if address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"); address != "" {
+		return address, nil
+	}
+	const path = "/run/dbus/system_bus_socket"
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no system bus address")
+	}
+	return "unix:path=" + path, nil
+}
+
+// Dial opens a private connection to the bus at address, authenticates and
+// says Hello, giving up after timeout.
+func Dial(address string, timeout time.Duration) (*Conn, error) {
+	conn, err := godbus.Dial(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the bus: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if err := conn.Auth(nil); err != nil {
+			done <- fmt.Errorf("failed to authenticate to the bus: %w", err)
+			return
+		}
+		done <- conn.Hello()
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		err = fmt.Errorf("timed out connecting to the bus")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// IsErrorReply reports whether err is an error reply to a method call, such
+// as for a missing service or object, rather than a failed connection.
+func IsErrorReply(err error) bool {
+	var reply godbus.Error
+	return errors.As(err, &reply)
+}
@@ -0,0 +1,115 @@
+package dbus_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/dbus/dbustest"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+func TestSessionBusAddress(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/run/user/1000/bus")
+	if got, err := dbus.SessionBusAddress(); err != nil || got != "unix:path=/run/user/1000/bus" {
+		t.Errorf("SessionBusAddress() = %q, %v; want the environment's address", got, err)
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	if _, err := dbus.SessionBusAddress(); err == nil {
+		t.Error("SessionBusAddress() found a bus without an address or socket")
+	}
+	if err := os.WriteFile(filepath.Join(runtimeDir, "bus"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dbus.SessionBusAddress(); err != nil || got != "unix:path="+filepath.Join(runtimeDir, "bus") {
+		t.Errorf("SessionBusAddress() = %q, %v; want the runtime directory's socket", got, err)
+	}
+}
+
+func TestSession(t *testing.T) {
+	idleSince := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	var calls atomic.Int32
+	address := dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		calls.Add(1)
+		switch msg.Member() {
+		case "GetActive":
+			if msg.Destination() == "org.gnome.ScreenSaver" {
+				send(dbustest.ErrorReply(msg, "org.freedesktop.DBus.Error.ServiceUnknown", "not running"))
+				return
+			}
+			send(dbustest.Reply(msg, false))
+		case "Get":
+			switch msg.StringArg(1) {
+			case "LockedHint", "IdleHint":
+				send(dbustest.Reply(msg, godbus.MakeVariant(true)))
+			case "IdleSinceHint":
+				send(dbustest.Reply(msg, godbus.MakeVariant(uint64(idleSince.UnixMicro()))))
+			}
+		}
+	})
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", address)
+
+	var s dbus.Session
+	defer s.Close()
+
+	if active, err := s.ScreenSaverActive(); err != nil || active {
+		t.Errorf("ScreenSaverActive() = %v, %v; want the freedesktop screensaver's false", active, err)
+	}
+	if !s.Locked() {
+		t.Error("Locked() = false, want logind's LockedHint")
+	}
+	idle, since, err := s.IdleHint()
+	if err != nil || !idle || !since.Equal(idleSince) {
+		t.Errorf("IdleHint() = %v, %v, %v; want true since %v", idle, since, err, idleSince)
+	}
+	// Both screensavers are asked twice, and logind once for the lock and
+	// twice for idleness.
+	if got := calls.Load(); got != 7 {
+		t.Errorf("calls = %d, want 7", got)
+	}
+}
+
+func TestPlayingMedia(t *testing.T) {
+	address := dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		switch msg.Member() {
+		case "ListNames":
+			send(dbustest.Reply(msg, []string{"org.freedesktop.DBus", "org.mpris.MediaPlayer2.vlc", "org.mpris.MediaPlayer2.spotify.instance42"}))
+		case "Get":
+			property := msg.StringArg(1)
+			playing := msg.Destination() == "org.mpris.MediaPlayer2.spotify.instance42"
+			switch {
+			case property == "PlaybackStatus" && playing:
+				send(dbustest.Reply(msg, godbus.MakeVariant("Playing")))
+			case property == "PlaybackStatus":
+				send(dbustest.Reply(msg, godbus.MakeVariant("Paused")))
+			case property == "Identity":
+				send(dbustest.ErrorReply(msg, "org.freedesktop.DBus.Error.UnknownProperty", "no identity"))
+			case property == "Metadata":
+				send(dbustest.Reply(msg, godbus.MakeVariant(map[string]godbus.Variant{
+					"xesam:title":  godbus.MakeVariant("Hey Jude"),
+					"xesam:artist": godbus.MakeVariant([]string{"The Beatles"}),
+				})))
+			}
+		}
+	})
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+
+	var s dbus.Session
+	defer s.Close()
+
+	players, err := s.PlayingMedia()
+	if err != nil {
+		t.Fatalf("PlayingMedia() error = %v", err)
+	}
+	if len(players) != 1 || players[0].Name != "spotify" || players[0].Track() != "The Beatles – Hey Jude" {
+		t.Errorf("PlayingMedia() = %+v, want spotify playing Hey Jude", players)
+	}
+}
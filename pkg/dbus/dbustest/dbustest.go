@@ -0,0 +1,207 @@
+// Package dbustest provides a fake bus for testing D-Bus clients.
+package dbustest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// ClientName is the unique name the fake bus gives every client.
+const ClientName = ":1.42"
+
+// Message is a message a client sent to the bus.
+type Message struct {
+	*godbus.Message
+}
+
+func (m Message) header(field godbus.HeaderField) string {
+	v, ok := m.Headers[field]
+	if !ok {
+		return ""
+	}
+	switch value := v.Value().(type) {
+	case string:
+		return value
+	case godbus.ObjectPath:
+		return string(value)
+	}
+	return ""
+}
+
+// Destination returns the bus name the message is sent to.
+func (m Message) Destination() string { return m.header(godbus.FieldDestination) }
+
+// Path returns the object path of a method call.
+func (m Message) Path() string { return m.header(godbus.FieldPath) }
+
+// Member returns the method called.
+func (m Message) Member() string { return m.header(godbus.FieldMember) }
+
+// StringArg returns argument i if it is a string.
+func (m Message) StringArg(i int) string {
+	if i >= len(m.Body) {
+		return ""
+	}
+	s, _ := m.Body[i].(string)
+	return s
+}
+
+// Reply returns the reply to call with body.
+func Reply(call Message, body ...any) *godbus.Message {
+	msg := &godbus.Message{
+		Type:    godbus.TypeMethodReply,
+		Headers: map[godbus.HeaderField]godbus.Variant{godbus.FieldReplySerial: godbus.MakeVariant(call.Serial())},
+		Body:    body,
+	}
+	return withSignature(msg)
+}
+
+// ErrorReply returns an error reply to call.
+func ErrorReply(call Message, name, text string) *godbus.Message {
+	msg := &godbus.Message{
+		Type: godbus.TypeError,
+		Headers: map[godbus.HeaderField]godbus.Variant{
+			godbus.FieldReplySerial: godbus.MakeVariant(call.Serial()),
+			godbus.FieldErrorName:   godbus.MakeVariant(name),
+		},
+		Body: []any{text},
+	}
+	return withSignature(msg)
+}
+
+// MethodCall returns a call of iface.member on the object at path.
+func MethodCall(path, iface, member string, body ...any) *godbus.Message {
+	msg := &godbus.Message{
+		Type: godbus.TypeMethodCall,
+		Headers: map[godbus.HeaderField]godbus.Variant{
+			godbus.FieldPath:      godbus.MakeVariant(godbus.ObjectPath(path)),
+			godbus.FieldInterface: godbus.MakeVariant(iface),
+			godbus.FieldMember:    godbus.MakeVariant(member),
+		},
+		Body: body,
+	}
+	return withSignature(msg)
+}
+
+// Signal returns the signal iface.member emitted by the object at path.
+func Signal(path, iface, member string, body ...any) *godbus.Message {
+	msg := MethodCall(path, iface, member, body...)
+	msg.Type = godbus.TypeSignal
+	return msg
+}
+
+func withSignature(msg *godbus.Message) *godbus.Message {
+	if len(msg.Body) > 0 {
+		msg.Headers[godbus.FieldSignature] = godbus.MakeVariant(godbus.SignatureOf(msg.Body...))
+	}
+	return msg
+}
+
+// Handler is passed every message a client sends other than Hello, along
+// with a function to send messages to that client.
+type Handler func(msg Message, send func(m *godbus.Message))
+
+// NewBus plays a bus until the test ends: it authenticates clients and
+// answers Hello, and passes every other message to handle. It returns the
+// bus's address.
+func NewBus(t *testing.T, handle Handler) (address string) {
+	t.Helper()
+	// Unix socket paths are short, so t.TempDir() is often too deep.
+	dir, err := os.MkdirTemp("", "actionsum-dbus")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "bus")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, handle)
+		}
+	}()
+	return "unix:path=" + path
+}
+
+// authenticate accepts the EXTERNAL mechanism, without passing file
+// descriptors.
+func authenticate(conn net.Conn, r *bufio.Reader) bool {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		line = strings.TrimPrefix(strings.TrimSpace(line), "\x00")
+		switch {
+		case line == "AUTH":
+			conn.Write([]byte("REJECTED EXTERNAL\r\n"))
+		case strings.HasPrefix(line, "AUTH EXTERNAL"):
+			conn.Write([]byte("OK 0123456789abcdef0123456789abcdef\r\n"))
+		case line == "NEGOTIATE_UNIX_FD":
+			conn.Write([]byte("ERROR\r\n"))
+		case line == "BEGIN":
+			return true
+		default:
+			conn.Write([]byte("ERROR\r\n"))
+		}
+	}
+}
+
+func serve(conn net.Conn, handle Handler) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if !authenticate(conn, r) {
+		return
+	}
+
+	var mu sync.Mutex
+	var serial uint32
+	send := func(m *godbus.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := m.Headers[godbus.FieldDestination]; !ok {
+			m.Headers[godbus.FieldDestination] = godbus.MakeVariant(ClientName)
+		}
+		var buf bytes.Buffer
+		if err := m.EncodeTo(&buf, binary.LittleEndian); err != nil {
+			return
+		}
+		// The serial is not exported, but sits at the same offset in
+		// every message.
+		serial++
+		b := buf.Bytes()
+		binary.LittleEndian.PutUint32(b[8:12], serial)
+		conn.Write(b)
+	}
+	for {
+		msg, err := godbus.DecodeMessage(r)
+		if err != nil {
+			return
+		}
+		m := Message{msg}
+		if m.Member() == "Hello" {
+			send(Reply(m, ClientName))
+			send(Signal("/org/freedesktop/DBus", "org.freedesktop.DBus", "NameAcquired", ClientName))
+			continue
+		}
+		handle(m, send)
+	}
+}
@@ -0,0 +1,101 @@
+package dbus
+
+import (
+	"sort"
+	"strings"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	mprisPrefix = "org.mpris.MediaPlayer2."
+	mprisPath   = "/org/mpris/MediaPlayer2"
+)
+
+// MediaPlayer is a player playing through MPRIS, the interface media players
+// such as Spotify, VLC and browsers implement on the session bus.
+type MediaPlayer struct {
+	// Name is how the player calls itself, e.g. "Spotify" or "Mozilla
+	// Firefox", or else its bus name, e.g. "spotify".
+	Name string
+
+	Title  string
+	Artist string
+}
+
+// Track returns "artist – title", or whichever of the two is known.
+func (p *MediaPlayer) Track() string {
+	switch {
+	case p.Artist != "" && p.Title != "":
+		return p.Artist + " – " + p.Title
+	case p.Title != "":
+		return p.Title
+	}
+	return p.Artist
+}
+
+// PlayingMedia returns the MPRIS players currently playing, by bus name.
+// Paused and stopped players are left out.
+func (s *Session) PlayingMedia() ([]MediaPlayer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	if err := call(&s.session, SessionBusAddress, "org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus.ListNames", &names); err != nil {
+		return nil, err
+	}
+	var buses []string
+	for _, name := range names {
+		if strings.HasPrefix(name, mprisPrefix) {
+			buses = append(buses, name)
+		}
+	}
+	sort.Strings(buses)
+
+	var players []MediaPlayer
+	for _, bus := range buses {
+		// A player that quits meanwhile, or doesn't answer, is skipped.
+		status, err := getProperty(&s.session, SessionBusAddress, bus, mprisPath, "org.mpris.MediaPlayer2.Player", "PlaybackStatus")
+		if err != nil || status != "Playing" {
+			continue
+		}
+		player := MediaPlayer{Name: playerName(bus)}
+		if identity, err := getProperty(&s.session, SessionBusAddress, bus, mprisPath, "org.mpris.MediaPlayer2", "Identity"); err == nil {
+			if identity, ok := identity.(string); ok && identity != "" {
+				player.Name = identity
+			}
+		}
+		if metadata, err := getProperty(&s.session, SessionBusAddress, bus, mprisPath, "org.mpris.MediaPlayer2.Player", "Metadata"); err == nil {
+			if metadata, ok := metadata.(map[string]godbus.Variant); ok {
+				player.Title, player.Artist = parseMetadata(metadata)
+			}
+		}
+		players = append(players, player)
+	}
+	return players, nil
+}
+
+// playerName is the player part of an MPRIS bus name, without the
+// ".instance1234" suffix players running more than once add.
+func playerName(bus string) string {
+	name := strings.TrimPrefix(bus, mprisPrefix)
+	if i := strings.Index(name, ".instance"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// parseMetadata reads the title and artists from MPRIS track metadata.
+func parseMetadata(metadata map[string]godbus.Variant) (title, artist string) {
+	title, _ = metadata["xesam:title"].Value().(string)
+	if artists, ok := metadata["xesam:artist"].Value().([]string); ok {
+		var names []string
+		for _, a := range artists {
+			if a != "" {
+				names = append(names, a)
+			}
+		}
+		artist = strings.Join(names, ", ")
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(artist)
+}
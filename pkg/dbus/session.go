@@ -0,0 +1,189 @@
+package dbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	// callTimeout bounds each query of the session state.
+	callTimeout = 2 * time.Second
+
+	// loginSessionPath is logind's alias for the caller's session or, when
+	// it runs outside one as a user service does, the user's display
+	// session.
+	loginSessionPath = "/org/freedesktop/login1/session/auto"
+)
+
+// screenSavers lists the screensaver services that report whether the
+// screen is locked, and emit ActiveChanged(bool) when that changes.
+var screenSavers = []struct {
+	Dest string
+	Path string
+}{
+	{"org.gnome.ScreenSaver", "/org/gnome/ScreenSaver"},
+	{"org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver"},
+}
+
+// Session reads the idle and lock state of the desktop session from logind
+// and the screensaver. Its connections to the session and system buses are
+// kept between queries and dialed again after one fails. It is safe for
+// concurrent use.
+type Session struct {
+	mu      sync.Mutex
+	session *Conn
+	system  *Conn
+}
+
+var (
+	sharedSession     *Session
+	sharedSessionOnce sync.Once
+)
+
+// SharedSession returns the Session that detectors share, so that they
+// don't each hold their own connections.
+func SharedSession() *Session {
+	sharedSessionOnce.Do(func() {
+		sharedSession = &Session{}
+	})
+	return sharedSession
+}
+
+// Locked reports whether the screensaver is active or logind's LockedHint
+// is set. It is false when neither can be asked.
+func (s *Session) Locked() bool {
+	if active, err := s.ScreenSaverActive(); err == nil && active {
+		return true
+	}
+	locked, err := s.LockedHint()
+	return err == nil && locked
+}
+
+// ScreenSaverActive asks GNOME's screensaver, then the freedesktop one,
+// whether it is active.
+func (s *Session) ScreenSaverActive() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lastErr error
+	for _, svc := range screenSavers {
+		var active bool
+		err := call(&s.session, SessionBusAddress, svc.Dest, svc.Path, svc.Dest+".GetActive", &active)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return active, nil
+	}
+	return false, lastErr
+}
+
+// LockedHint returns logind's LockedHint for the session.
+func (s *Session) LockedHint() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := getProperty(&s.system, SystemBusAddress, "org.freedesktop.login1", loginSessionPath, "org.freedesktop.login1.Session", "LockedHint")
+	if err != nil {
+		return false, err
+	}
+	locked, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("LockedHint is not a boolean")
+	}
+	return locked, nil
+}
+
+// IdleHint returns logind's IdleHint for the session and, while it is
+// set, when the session went idle. Desktops such as GNOME set it after
+// their own idle delay.
+func (s *Session) IdleHint() (bool, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := getProperty(&s.system, SystemBusAddress, "org.freedesktop.login1", loginSessionPath, "org.freedesktop.login1.Session", "IdleHint")
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	idle, ok := v.(bool)
+	if !ok {
+		return false, time.Time{}, fmt.Errorf("IdleHint is not a boolean")
+	}
+	if !idle {
+		return false, time.Time{}, nil
+	}
+
+	v, err = getProperty(&s.system, SystemBusAddress, "org.freedesktop.login1", loginSessionPath, "org.freedesktop.login1.Session", "IdleSinceHint")
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	usec, ok := v.(uint64)
+	if !ok || usec == 0 {
+		return true, time.Time{}, nil
+	}
+	return true, time.UnixMicro(int64(usec)), nil
+}
+
+// Close closes the connections; later queries dial again.
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range []**Conn{&s.session, &s.system} {
+		if *conn != nil {
+			(*conn).Close()
+			*conn = nil
+		}
+	}
+}
+
+// call makes a method call without arguments on the connection held in
+// *conn, dialing the bus found by address first if there is none, and stores
+// the reply in retvalues. A failure other than an error reply closes the
+// connection.
+func call(conn **Conn, address func() (string, error), dest, path, method string, retvalues ...any) error {
+	return withConn(conn, address, func(c *Conn) error {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+		return c.Object(dest, godbus.ObjectPath(path)).CallWithContext(ctx, method, 0).Store(retvalues...)
+	})
+}
+
+func getProperty(conn **Conn, address func() (string, error), dest, path, iface, property string) (any, error) {
+	var v godbus.Variant
+	err := withConn(conn, address, func(c *Conn) error {
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+		return c.Object(dest, godbus.ObjectPath(path)).
+			CallWithContext(ctx, "org.freedesktop.DBus.Properties.Get", 0, iface, property).
+			Store(&v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
+func withConn(conn **Conn, address func() (string, error), fn func(*Conn) error) error {
+	if *conn == nil {
+		addr, err := address()
+		if err != nil {
+			return err
+		}
+		c, err := Dial(addr, callTimeout)
+		if err != nil {
+			return err
+		}
+		*conn = c
+	}
+
+	err := fn(*conn)
+	if err != nil && !IsErrorReply(err) {
+		(*conn).Close()
+		*conn = nil
+	}
+	return err
+}
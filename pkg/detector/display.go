@@ -0,0 +1,53 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DisplayConfigured reports whether the environment names a display at all.
+func DisplayConfigured() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") != ""
+}
+
+// DisplayReady reports whether the socket of the configured display exists.
+// Sessions started from an autostart entry can run before the compositor or
+// X server has created it.
+func DisplayReady() bool {
+	if display := os.Getenv("WAYLAND_DISPLAY"); display != "" {
+		path := display
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), display)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	if display := os.Getenv("DISPLAY"); display != "" {
+		path := x11SocketPath(display)
+		if path == "" {
+			return true // Remote display, nothing to check locally
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// x11SocketPath maps a local DISPLAY such as ":0" or ":1.0" to its Unix
+// socket. It returns "" for remote displays.
+func x11SocketPath(display string) string {
+	host, number, ok := strings.Cut(display, ":")
+	if !ok || (host != "" && host != "unix") {
+		return ""
+	}
+	number, _, _ = strings.Cut(number, ".")
+	if number == "" {
+		return ""
+	}
+	return "/tmp/.X11-unix/X" + number
+}
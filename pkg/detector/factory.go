@@ -1,17 +1,44 @@
 package detector
 
 import (
+	"fmt"
 	"os"
+	"runtime"
 
 	"github.com/actionsum/actionsum/pkg/integrations/hybrid"
+	"github.com/actionsum/actionsum/pkg/integrations/macos"
+	"github.com/actionsum/actionsum/pkg/integrations/windows"
 	"github.com/actionsum/actionsum/pkg/window"
 )
 
-func New() (window.Detector, error) {
-	return hybrid.NewDetector()
+func New(opts ...hybrid.Option) (window.Detector, error) {
+	if runtime.GOOS == "darwin" {
+		det := macos.NewDetector()
+		if !det.IsAvailable() {
+			det.Close()
+			return nil, fmt.Errorf("macOS window detection unavailable: actionsum must be built with cgo and run in a logged-in session")
+		}
+		return det, nil
+	}
+	if runtime.GOOS == "windows" {
+		det := windows.NewDetector()
+		if !det.IsAvailable() {
+			det.Close()
+			return nil, fmt.Errorf("Windows window detection unavailable: user32.dll could not be loaded")
+		}
+		return det, nil
+	}
+	return hybrid.NewDetector(opts...)
 }
 
 func DetectDisplayServer() string {
+	if runtime.GOOS == "darwin" {
+		return macos.DisplayServer
+	}
+	if runtime.GOOS == "windows" {
+		return windows.DisplayServer
+	}
+
 	sessionType := os.Getenv("XDG_SESSION_TYPE")
 	waylandDisplay := os.Getenv("WAYLAND_DISPLAY")
 	x11Display := os.Getenv("DISPLAY")
@@ -158,3 +158,47 @@ func TestMultipleDetectorInstances(t *testing.T) {
 
 	t.Logf("Successfully created multiple detector instances")
 }
+
+func TestX11SocketPath(t *testing.T) {
+	tests := []struct {
+		display  string
+		expected string
+	}{
+		{":0", "/tmp/.X11-unix/X0"},
+		{":1.0", "/tmp/.X11-unix/X1"},
+		{"unix:2", "/tmp/.X11-unix/X2"},
+		{"remote:0", ""},
+		{"localhost:10.0", ""},
+		{"garbage", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.display, func(t *testing.T) {
+			if got := x11SocketPath(tt.display); got != tt.expected {
+				t.Errorf("x11SocketPath(%q) = %q, want %q", tt.display, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDisplayReady(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if DisplayReady() {
+		t.Error("DisplayReady() = true with no display configured")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-test")
+	if DisplayReady() {
+		t.Error("DisplayReady() = true before the socket exists")
+	}
+
+	if err := os.WriteFile(dir+"/wayland-test", nil, 0600); err != nil {
+		t.Fatalf("failed to create socket placeholder: %v", err)
+	}
+	if !DisplayReady() {
+		t.Error("DisplayReady() = false after the socket exists")
+	}
+}
@@ -0,0 +1,142 @@
+// Package icontheme finds the files of freedesktop icons by name, as named
+// by the Icon key of .desktop files. It looks in the hicolor theme, the one
+// every app installs its icon into and every theme falls back on, rather
+// than the user's theme, and then among the unthemed icons in pixmaps.
+package icontheme
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// extensions are the formats browsers show; XPM is left out.
+var extensions = []string{".svg", ".png"}
+
+// Dirs lists the directories icons are looked up in, the user's first.
+func Dirs() []string {
+	home, _ := os.UserHomeDir()
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	if dataHome != "" {
+		add(filepath.Join(dataHome, "icons"))
+		add(filepath.Join(dataHome, "flatpak", "exports", "share", "icons"))
+	}
+	if home != "" {
+		add(filepath.Join(home, ".icons"))
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		add(filepath.Join(dir, "icons"))
+	}
+	add("/var/lib/flatpak/exports/share/icons")
+	add("/var/lib/snapd/desktop/icons")
+	for _, dir := range filepath.SplitList(dataDirs) {
+		add(filepath.Join(dir, "pixmaps"))
+	}
+	return dirs
+}
+
+// Find returns the file of the icon called name, or false when there is
+// none in a format browsers show. A name that is a path, as Snap apps
+// give, is the file itself.
+func Find(name string) (string, bool) {
+	return find(name, Dirs())
+}
+
+func find(name string, dirs []string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if filepath.IsAbs(name) {
+		if hasExtension(name) && isFile(name) {
+			return name, true
+		}
+		return "", false
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	// Some entries name the icon with its extension.
+	base := name
+	if hasExtension(name) {
+		base = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	for _, dir := range dirs {
+		if path, ok := findThemed(filepath.Join(dir, "hicolor"), base); ok {
+			return path, true
+		}
+		for _, ext := range extensions {
+			if path := filepath.Join(dir, base+ext); isFile(path) {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// findThemed returns the best size of the icon in theme: scalable, then
+// the largest.
+func findThemed(theme, name string) (string, bool) {
+	var best string
+	bestSize := -1
+	for _, ext := range extensions {
+		matches, _ := filepath.Glob(filepath.Join(theme, "*", "apps", name+ext))
+		for _, path := range matches {
+			if size := iconSize(filepath.Base(filepath.Dir(filepath.Dir(path)))); size > bestSize {
+				best, bestSize = path, size
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// iconSize reads a theme's size directory, e.g. "48x48" or "256x256@2",
+// counting scalable icons as the largest.
+func iconSize(dir string) int {
+	if dir == "scalable" {
+		return 1 << 20
+	}
+	width, _, _ := strings.Cut(dir, "x")
+	size, err := strconv.Atoi(width)
+	if err != nil {
+		return 0
+	}
+	if _, scale, ok := strings.Cut(dir, "@"); ok {
+		if n, err := strconv.Atoi(scale); err == nil {
+			size *= n
+		}
+	}
+	return size
+}
+
+func hasExtension(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,56 @@
+package icontheme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func touch(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("icon"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	user, system, pixmaps := t.TempDir(), t.TempDir(), t.TempDir()
+	touch(t, filepath.Join(system, "hicolor", "48x48", "apps", "firefox.png"))
+	largest := touch(t, filepath.Join(system, "hicolor", "128x128", "apps", "firefox.png"))
+	touch(t, filepath.Join(system, "hicolor", "32x32@2", "apps", "gimp.png"))
+	touch(t, filepath.Join(system, "hicolor", "48x48", "apps", "gimp.png"))
+	scalable := touch(t, filepath.Join(system, "hicolor", "scalable", "apps", "org.gnome.Maps.svg"))
+	touch(t, filepath.Join(system, "hicolor", "256x256", "apps", "org.gnome.Maps.png"))
+	userIcon := touch(t, filepath.Join(user, "hicolor", "16x16", "apps", "code.png"))
+	touch(t, filepath.Join(system, "hicolor", "512x512", "apps", "code.png"))
+	pixmap := touch(t, filepath.Join(pixmaps, "xterm.png"))
+	touch(t, filepath.Join(pixmaps, "old.xpm"))
+	snap := touch(t, filepath.Join(t.TempDir(), "meta", "gui", "icon.png"))
+	dirs := []string{user, system, pixmaps}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"firefox", largest},
+		{"gimp", filepath.Join(system, "hicolor", "32x32@2", "apps", "gimp.png")},
+		{"org.gnome.Maps", scalable},
+		{"code", userIcon}, // The user's directories come first
+		{"xterm", pixmap},
+		{"xterm.png", pixmap},
+		{"old", ""},
+		{snap, snap},
+		{"../hicolor/48x48/apps/firefox", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, ok := find(tt.name, dirs)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("find(%q) = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package audio tells whether sound is playing, so that watching a video or
+// listening to a talk without touching the keyboard or mouse isn't taken for
+// being away, and whether a microphone is recording, as in a call.
+package audio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// Playing reports whether any output sink is running, that is, some stream
+// is playing through it. It asks with pactl, which talks to PulseAudio and
+// to PipeWire through pipewire-pulse alike. Paused streams leave their sink
+// idle, so a paused video doesn't count.
+func Playing() (bool, error) {
+	output, err := sandbox.Command("pactl", "list", "short", "sinks").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to run pactl: %w", err)
+	}
+	return parseSinks(string(output)), nil
+}
+
+// parseSinks reads pactl's short sink list, one sink per line as "id name
+// module format state", for one in the RUNNING state.
+func parseSinks(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[len(fields)-1] == "RUNNING" {
+			return true
+		}
+	}
+	return false
+}
+
+// Recording reports whether any microphone is recording. It asks pactl for
+// a running source, leaving out the monitors that mirror each output, and
+// without pactl reads the state of ALSA's capture devices, which misses
+// Bluetooth headsets.
+func Recording() (bool, error) {
+	if output, err := sandbox.Command("pactl", "list", "short", "sources").Output(); err == nil {
+		return parseSources(string(output)), nil
+	}
+	return alsaCapturing("/proc/asound")
+}
+
+// parseSources reads pactl's short source list, in the format of its sink
+// list, for a running source that isn't a monitor.
+func parseSources(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[len(fields)-1] == "RUNNING" && !strings.HasSuffix(fields[1], ".monitor") {
+			return true
+		}
+	}
+	return false
+}
+
+// alsaCapturing looks under asound, normally /proc/asound, for a capture
+// substream whose status says it is running.
+func alsaCapturing(asound string) (bool, error) {
+	statuses, err := filepath.Glob(filepath.Join(asound, "card*", "pcm*c", "sub*", "status"))
+	if err != nil || len(statuses) == 0 {
+		return false, fmt.Errorf("no ALSA capture devices under %s", asound)
+	}
+	for _, status := range statuses {
+		data, err := os.ReadFile(status)
+		if err == nil && strings.Contains(string(data), "state: RUNNING") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
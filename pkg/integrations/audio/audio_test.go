@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{
+			name:   "playing",
+			output: "47\talsa_output.pci-0000_00_1f.3.analog-stereo\tPipeWire\ts32le 2ch 48000Hz\tRUNNING\n",
+			want:   true,
+		},
+		{
+			name: "suspended and idle",
+			output: "47\talsa_output.pci-0000_00_1f.3.analog-stereo\tPipeWire\ts32le 2ch 48000Hz\tSUSPENDED\n" +
+				"52\tbluez_output.00_11_22_33_44_55.1\tPipeWire\ts16le 2ch 48000Hz\tIDLE\n",
+			want: false,
+		},
+		{
+			name: "second sink playing",
+			output: "1\talsa_output.hdmi-stereo\tmodule-alsa-card.c\ts16le 2ch 44100Hz\tSUSPENDED\n" +
+				"2\talsa_output.analog-stereo\tmodule-alsa-card.c\ts16le 2ch 44100Hz\tRUNNING\n",
+			want: true,
+		},
+		{name: "no sinks", output: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSinks(tt.output); got != tt.want {
+				t.Errorf("parseSinks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	monitor := "47\talsa_output.pci-0000_00_1f.3.analog-stereo.monitor\tPipeWire\ts32le 2ch 48000Hz\tRUNNING\n"
+	if parseSources(monitor) {
+		t.Error("parseSources() took a running monitor for a microphone")
+	}
+	mic := monitor + "48\talsa_input.pci-0000_00_1f.3.analog-stereo\tPipeWire\ts32le 2ch 48000Hz\tRUNNING\n"
+	if !parseSources(mic) {
+		t.Error("parseSources() missed a running microphone")
+	}
+}
+
+func TestALSACapturing(t *testing.T) {
+	asound := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(asound, path)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := alsaCapturing(asound); err == nil {
+		t.Error("alsaCapturing() found devices in an empty directory")
+	}
+	write("card0/pcm0p/sub0/status", "state: RUNNING\n")
+	write("card0/pcm0c/sub0/status", "closed\n")
+	if capturing, err := alsaCapturing(asound); err != nil || capturing {
+		t.Errorf("alsaCapturing() = %v, %v; want false while only playback runs", capturing, err)
+	}
+	write("card1/pcm0c/sub0/status", "state: RUNNING\nowner_pid   : 4242\n")
+	if capturing, err := alsaCapturing(asound); err != nil || !capturing {
+		t.Errorf("alsaCapturing() = %v, %v; want true", capturing, err)
+	}
+}
@@ -0,0 +1,2 @@
+// Package camera tells whether a webcam is in use, as in a video call.
+package camera
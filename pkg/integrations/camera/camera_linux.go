@@ -0,0 +1,28 @@
+package camera
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InUse reports whether a process has a video device such as /dev/video0
+// open. Only this user's processes can be looked into, which covers the
+// browsers and meeting apps of the session.
+func InUse() (bool, error) {
+	return videoOpen("/proc")
+}
+
+func videoOpen(proc string) (bool, error) {
+	fds, err := filepath.Glob(filepath.Join(proc, "[0-9]*", "fd", "*"))
+	if err != nil {
+		return false, err
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(fd)
+		if err == nil && strings.HasPrefix(target, "/dev/video") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,30 @@
+package camera
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVideoOpen(t *testing.T) {
+	proc := t.TempDir()
+	link := func(pid, fd, target string) {
+		dir := filepath.Join(proc, pid, "fd")
+		os.MkdirAll(dir, 0755)
+		if err := os.Symlink(target, filepath.Join(dir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link("100", "0", "/dev/pts/1")
+	link("100", "3", "socket:[12345]")
+	link("200", "5", "/dev/snd/pcmC0D0p")
+	if open, err := videoOpen(proc); err != nil || open {
+		t.Errorf("videoOpen() = %v, %v; want false without a video device", open, err)
+	}
+
+	link("300", "27", "/dev/video0")
+	if open, err := videoOpen(proc); err != nil || !open {
+		t.Errorf("videoOpen() = %v, %v; want true", open, err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package camera
+
+import "errors"
+
+// InUse needs /proc, which only Linux has.
+func InUse() (bool, error) {
+	return false, errors.New("camera use is only detected on Linux")
+}
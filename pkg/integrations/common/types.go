@@ -1,6 +1,10 @@
 package common
 
-import "time"
+import (
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
 
 type AppInfo struct {
 	AppName string
@@ -16,6 +20,9 @@ type AppInfo struct {
 	Confidence float64
 
 	DetectionMethod string
+
+	// Window holds the full window details when a window detector resolved the app.
+	Window *window.WindowInfo
 }
 
 type Detector interface {
@@ -0,0 +1,161 @@
+// Package fake provides a window detector that plays back a scripted
+// scenario instead of asking a display server, so the tracker, storage,
+// reports and API can be exercised end to end in CI or containers without a
+// desktop.
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+// Detector reports what the current step of its scenario describes. Steps
+// follow each other in real time from when the detector was created. Once a
+// scenario that doesn't repeat has played out, the session reads as locked
+// so nothing more is tracked, and Done is closed.
+type Detector struct {
+	scenario *Scenario
+	start    time.Time
+	now      func() time.Time
+
+	mu       sync.Mutex
+	stats    map[string]window.BackendStats
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewDetector plays back scenario starting now.
+func NewDetector(scenario *Scenario) *Detector {
+	return newDetector(scenario, time.Now)
+}
+
+func newDetector(scenario *Scenario, now func() time.Time) *Detector {
+	return &Detector{
+		scenario: scenario,
+		start:    now(),
+		now:      now,
+		stats:    make(map[string]window.BackendStats),
+		done:     make(chan struct{}),
+	}
+}
+
+// Load reads the scenario at path and plays it back starting now.
+func Load(path string) (*Detector, error) {
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewDetector(scenario), nil
+}
+
+// current returns the active step and how long it has been active, or nil
+// once the scenario has ended.
+func (d *Detector) current() (*Step, time.Duration) {
+	elapsed := d.now().Sub(d.start)
+	total := d.scenario.Duration()
+	if elapsed >= total {
+		if !d.scenario.Repeat {
+			d.doneOnce.Do(func() { close(d.done) })
+			return nil, 0
+		}
+		elapsed %= total
+	}
+	for i := range d.scenario.Steps {
+		step := &d.scenario.Steps[i]
+		if elapsed < step.For {
+			return step, elapsed
+		}
+		elapsed -= step.For
+	}
+	return nil, 0
+}
+
+func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
+	step, _ := d.current()
+	if step == nil || step.App == "" || step.Fail != "" {
+		d.record(false)
+		if step != nil && step.Fail != "" {
+			return nil, fmt.Errorf("%s", step.Fail)
+		}
+		return nil, fmt.Errorf("no focused window in scenario")
+	}
+	d.record(true)
+
+	process := step.Process
+	if process == "" {
+		process = step.App
+	}
+	return &window.WindowInfo{
+		AppName:         step.App,
+		WindowTitle:     step.Title,
+		ProcessName:     process,
+		DisplayServer:   d.GetDisplayServer(),
+		IsFullscreen:    step.Fullscreen,
+		IsMaximized:     step.Maximized,
+		DetectionMethod: "window",
+		Confidence:      1,
+		Client:          step.Client,
+		Output:          step.Output,
+	}, nil
+}
+
+// GetIdleInfo reports idle steps as idle. A step with idle_time reports an
+// idle time that starts there and grows through the step, so it is held
+// against the idle threshold like a real one; without it the step counts as
+// idle straight away. Steps with audio report sound playing.
+func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
+	step, inStep := d.current()
+	if step == nil {
+		return &window.IdleInfo{IsLocked: true}, nil
+	}
+	info := &window.IdleInfo{IsIdle: step.Idle, IsLocked: step.Locked, HasAudioPlaying: step.Audio}
+	if step.Idle && step.IdleTime > 0 {
+		info.IdleTime = int64((step.IdleTime + inStep).Seconds())
+	}
+	return info, nil
+}
+
+func (d *Detector) IsAvailable() bool {
+	return true
+}
+
+func (d *Detector) GetDisplayServer() string {
+	return d.scenario.DisplayServer
+}
+
+func (d *Detector) Close() error {
+	return nil
+}
+
+// Done is closed once a scenario that doesn't repeat has played out. It is
+// noticed on the first poll after the end.
+func (d *Detector) Done() <-chan struct{} {
+	return d.done
+}
+
+// BackendStats counts the lookups answered, keyed by the scenario's display
+// server, like the real detectors do.
+func (d *Detector) BackendStats() map[string]window.BackendStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := make(map[string]window.BackendStats, len(d.stats))
+	for name, counts := range d.stats {
+		stats[name] = counts
+	}
+	return stats
+}
+
+func (d *Detector) record(ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts := d.stats[d.GetDisplayServer()]
+	if ok {
+		counts.Successes++
+	} else {
+		counts.Failures++
+	}
+	d.stats[d.GetDisplayServer()] = counts
+}
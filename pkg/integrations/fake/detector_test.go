@@ -0,0 +1,150 @@
+package fake
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testScenario = `# a short working session
+display_server: wayland
+steps:
+  - app: firefox
+    title: "Issue #12 - GitHub"  # quoted, with a hash
+    for: 30s
+  - app: code
+    process: code-oss
+    client: native
+    output: DP-1
+    for: 1m
+  - idle: true
+    idle_time: 5m
+    audio: true
+    for: 2m
+  - locked: true
+    for: 1m
+  - fail: window lookup failed
+    for: 10s
+`
+
+func TestParseScenario(t *testing.T) {
+	s, err := ParseScenario(strings.NewReader(testScenario))
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	if s.DisplayServer != "wayland" || s.Repeat {
+		t.Errorf("settings = %q repeat=%v, want wayland repeat=false", s.DisplayServer, s.Repeat)
+	}
+	if len(s.Steps) != 5 {
+		t.Fatalf("got %d steps, want 5", len(s.Steps))
+	}
+	if got := s.Steps[0].Title; got != "Issue #12 - GitHub" {
+		t.Errorf("title = %q", got)
+	}
+	if got := s.Steps[1]; got.Process != "code-oss" || got.Client != "native" || got.Output != "DP-1" || got.For != time.Minute {
+		t.Errorf("step 2 = %+v", got)
+	}
+	if got := s.Steps[2]; !got.Idle || got.IdleTime != 5*time.Minute {
+		t.Errorf("step 3 = %+v", got)
+	}
+	if got := s.Duration(); got != 4*time.Minute+40*time.Second {
+		t.Errorf("Duration() = %v", got)
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	tests := map[string]string{
+		"no steps":      "display_server: x11\n",
+		"no duration":   "steps:\n  - app: firefox\n",
+		"empty step":    "steps:\n  - for: 1m\n",
+		"unknown field": "steps:\n  - app: firefox\n    colour: red\n    for: 1m\n",
+		"bad duration":  "steps:\n  - app: firefox\n    for: soon\n",
+		"unknown key":   "speed: 2\n",
+		"stray indent":  "  app: firefox\n",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseScenario(strings.NewReader(input)); err == nil {
+				t.Error("ParseScenario() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestDetectorPlayback(t *testing.T) {
+	s, err := ParseScenario(strings.NewReader(testScenario))
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	d := newDetector(s, func() time.Time { return now })
+	at := func(offset time.Duration) {
+		now = d.start.Add(offset)
+	}
+
+	at(10 * time.Second)
+	info, err := d.GetFocusedWindow()
+	if err != nil || info.AppName != "firefox" || info.ProcessName != "firefox" || info.DisplayServer != "wayland" {
+		t.Errorf("at 10s: %+v, %v", info, err)
+	}
+
+	at(45 * time.Second)
+	info, err = d.GetFocusedWindow()
+	if err != nil || info.AppName != "code" || info.ProcessName != "code-oss" || info.Client != "native" {
+		t.Errorf("at 45s: %+v, %v", info, err)
+	}
+
+	at(2 * time.Minute)
+	idle, _ := d.GetIdleInfo()
+	if !idle.IsIdle || !idle.HasAudioPlaying || idle.IdleTime != int64((5*time.Minute+30*time.Second).Seconds()) {
+		t.Errorf("at 2m: %+v", idle)
+	}
+
+	at(3*time.Minute + 40*time.Second)
+	idle, _ = d.GetIdleInfo()
+	if !idle.IsLocked || idle.IsIdle {
+		t.Errorf("at 3m40s: %+v", idle)
+	}
+
+	at(4*time.Minute + 35*time.Second)
+	if _, err := d.GetFocusedWindow(); err == nil || err.Error() != "window lookup failed" {
+		t.Errorf("at 4m35s: error = %v", err)
+	}
+
+	select {
+	case <-d.Done():
+		t.Fatal("Done() closed before the scenario ended")
+	default:
+	}
+
+	at(5 * time.Minute)
+	idle, _ = d.GetIdleInfo()
+	if !idle.IsLocked {
+		t.Errorf("after the end: %+v, want locked", idle)
+	}
+	select {
+	case <-d.Done():
+	default:
+		t.Error("Done() not closed after the scenario ended")
+	}
+
+	stats := d.BackendStats()["wayland"]
+	if stats.Successes != 2 || stats.Failures != 1 {
+		t.Errorf("BackendStats() = %+v, want 2 ok, 1 failed", stats)
+	}
+}
+
+func TestDetectorRepeat(t *testing.T) {
+	s, err := ParseScenario(strings.NewReader("repeat: true\nsteps:\n  - app: a\n    for: 1m\n  - app: b\n    for: 1m\n"))
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	now := time.Now()
+	d := newDetector(s, func() time.Time { return now })
+
+	now = d.start.Add(2*time.Minute + 30*time.Second)
+	info, err := d.GetFocusedWindow()
+	if err != nil || info.AppName != "a" {
+		t.Errorf("second pass: %+v, %v, want a", info, err)
+	}
+}
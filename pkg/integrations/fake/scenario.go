@@ -0,0 +1,224 @@
+package fake
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario is a script of what the fake detector reports over time. It is
+// read from a small subset of YAML:
+//
+//	display_server: x11
+//	repeat: false
+//	steps:
+//	  - app: firefox
+//	    title: "Issue #12 - GitHub"
+//	    for: 30s
+//	  - idle: true
+//	    for: 2m
+//	  - locked: true
+//	    for: 1m
+//	  - fail: "window lookup failed"
+//	    for: 10s
+type Scenario struct {
+	DisplayServer string
+	Repeat        bool
+	Steps         []Step
+}
+
+// Step is what the detector reports for a stretch of time.
+type Step struct {
+	App        string
+	Title      string
+	Process    string
+	For        time.Duration
+	Idle       bool
+	IdleTime   time.Duration
+	Locked     bool
+	Audio      bool
+	Fullscreen bool
+	Maximized  bool
+	Client     string
+	Output     string
+	Fail       string
+}
+
+// Duration is the length of one pass through the scenario.
+func (s *Scenario) Duration() time.Duration {
+	var total time.Duration
+	for _, step := range s.Steps {
+		total += step.For
+	}
+	return total
+}
+
+// LoadScenario reads a scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scenario: %w", err)
+	}
+	defer f.Close()
+	return ParseScenario(f)
+}
+
+// ParseScenario reads a scenario in the format documented on Scenario.
+func ParseScenario(r io.Reader) (*Scenario, error) {
+	s := &Scenario{DisplayServer: "x11"}
+	var step *Step
+	inSteps := false
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		raw := stripComment(scanner.Text())
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		line := strings.TrimSpace(raw)
+
+		if !indented {
+			inSteps, step = false, nil
+			key, value, err := splitKey(line)
+			if err != nil {
+				return nil, fmt.Errorf("scenario line %d: %w", n, err)
+			}
+			switch key {
+			case "steps":
+				if value != "" {
+					return nil, fmt.Errorf("scenario line %d: steps must be a list", n)
+				}
+				inSteps = true
+			case "display_server":
+				s.DisplayServer = value
+			case "repeat":
+				if s.Repeat, err = strconv.ParseBool(value); err != nil {
+					return nil, fmt.Errorf("scenario line %d: invalid repeat %q", n, value)
+				}
+			default:
+				return nil, fmt.Errorf("scenario line %d: unknown setting %q", n, key)
+			}
+			continue
+		}
+
+		if !inSteps {
+			return nil, fmt.Errorf("scenario line %d: unexpected indentation", n)
+		}
+		if item, ok := strings.CutPrefix(line, "-"); ok {
+			s.Steps = append(s.Steps, Step{})
+			step = &s.Steps[len(s.Steps)-1]
+			line = strings.TrimSpace(item)
+			if line == "" {
+				continue
+			}
+		}
+		if step == nil {
+			return nil, fmt.Errorf("scenario line %d: expected a list item", n)
+		}
+		key, value, err := splitKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("scenario line %d: %w", n, err)
+		}
+		if err := step.set(key, value); err != nil {
+			return nil, fmt.Errorf("scenario line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("scenario has no steps")
+	}
+	for i, step := range s.Steps {
+		if step.For <= 0 {
+			return nil, fmt.Errorf("scenario step %d needs a positive \"for\" duration", i+1)
+		}
+		if step.App == "" && !step.Idle && !step.Locked && step.Fail == "" {
+			return nil, fmt.Errorf("scenario step %d needs an app, idle, locked or fail", i+1)
+		}
+	}
+	return s, nil
+}
+
+func (st *Step) set(key, value string) error {
+	var err error
+	switch key {
+	case "app":
+		st.App = value
+	case "title":
+		st.Title = value
+	case "process":
+		st.Process = value
+	case "client":
+		st.Client = value
+	case "output":
+		st.Output = value
+	case "fail":
+		st.Fail = value
+	case "for":
+		st.For, err = time.ParseDuration(value)
+	case "idle_time":
+		st.IdleTime, err = time.ParseDuration(value)
+	case "idle":
+		st.Idle, err = strconv.ParseBool(value)
+	case "locked":
+		st.Locked, err = strconv.ParseBool(value)
+	case "audio":
+		st.Audio, err = strconv.ParseBool(value)
+	case "fullscreen":
+		st.Fullscreen, err = strconv.ParseBool(value)
+	case "maximized":
+		st.Maximized, err = strconv.ParseBool(value)
+	default:
+		return fmt.Errorf("unknown step field %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	return nil
+}
+
+// splitKey splits "key: value", unquoting the value.
+func splitKey(line string) (string, string, error) {
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", fmt.Errorf("expected \"key: value\", got %q", line)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", value)
+			}
+			value = unquoted
+		} else {
+			value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return strings.TrimSpace(key), value, nil
+}
+
+// stripComment drops a "#" comment that isn't inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
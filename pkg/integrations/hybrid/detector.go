@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"sort"
-	"strings"
 	"time"
 
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/integrations/audio"
 	"github.com/actionsum/actionsum/pkg/integrations/common"
 	"github.com/actionsum/actionsum/pkg/integrations/process"
 	"github.com/actionsum/actionsum/pkg/integrations/wayland"
@@ -25,27 +25,93 @@ type Detector struct {
 
 	windowCache map[int]string // PID -> window title
 
+	lockMonitor *lockMonitor
+
+	// bus asks logind and the screensaver for the idle and lock state.
+	bus *dbus.Session
+
+	// session is the graphical session windowDetector was chosen for.
+	session        session
+	sessionChecked time.Time
+
 	initialized bool
+
+	processFallback bool
+
+	// audio fills IdleInfo.HasAudioPlaying.
+	audio bool
+
+	// focusChanges carries the window detector's focus changes when focus
+	// events are enabled, across switches of window detector.
+	focusChanges chan struct{}
+
+	stats backendStats
 }
 
-func NewDetector() (*Detector, error) {
+// Option configures a Detector.
+type Option func(*Detector)
+
+// WithProcessFallback enables or disables guessing the focused app from
+// running processes when no display-server detector can tell. It is enabled
+// by default.
+func WithProcessFallback(enabled bool) Option {
+	return func(d *Detector) {
+		d.processFallback = enabled
+	}
+}
+
+// WithFocusEvents enables listening for focus changes where the window
+// detector supports it, currently on X11. It is disabled by default.
+func WithFocusEvents(enabled bool) Option {
+	return func(d *Detector) {
+		if enabled {
+			d.focusChanges = make(chan struct{}, 1)
+		} else {
+			d.focusChanges = nil
+		}
+	}
+}
+
+// WithAudio enables checking whether sound is playing with the idle state.
+// It is disabled by default, as it runs pactl on every poll.
+func WithAudio(enabled bool) Option {
+	return func(d *Detector) {
+		d.audio = enabled
+	}
+}
+
+func NewDetector(opts ...Option) (*Detector, error) {
 	d := &Detector{
-		windowCache: make(map[int]string),
+		windowCache:     make(map[int]string),
+		processFallback: true,
+		bus:             dbus.SharedSession(),
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
 
+	d.session = currentSession()
+	d.session.apply()
+	d.sessionChecked = time.Now()
+
 	windowDet := detectWindowDetector()
 	if windowDet != nil {
 		d.windowDetector = windowDet
 		log.Printf("Window detector initialized: %s", windowDet.GetDisplayServer())
+		d.watchFocus()
 	} else {
 		log.Printf("Window detector unavailable, using process-based detection only")
 	}
 
-	d.processDetector = process.NewDetector()
-	if err := d.processDetector.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize process detector: %w", err)
+	if d.processFallback {
+		d.processDetector = process.NewDetector()
+		if err := d.processDetector.Initialize(); err != nil {
+			return nil, fmt.Errorf("failed to initialize process detector: %w", err)
+		}
 	}
 
+	d.lockMonitor = newLockMonitor()
+
 	d.initialized = true
 	return d, nil
 }
@@ -76,19 +142,36 @@ func (d *Detector) GetActiveApp() (*common.AppInfo, error) {
 	if !d.initialized {
 		return nil, fmt.Errorf("detector not initialized")
 	}
+	d.refreshSession()
 
 	var windowErr error
 
 	if d.windowDetector != nil && d.windowDetector.IsAvailable() {
-		if appInfo, err := d.getActiveAppFromWindow(); err == nil {
+		appInfo, err := d.getActiveAppFromWindow()
+		if reporter, ok := d.windowDetector.(window.CoverageReporter); ok {
+			d.stats.setLimitations(reporter.Limitations())
+		} else {
+			d.stats.setLimitations(nil)
+		}
+		if err == nil {
+			d.stats.record(d.windowDetector.GetDisplayServer(), true)
 			d.lastSuccessfulMethod = "window"
 			return appInfo, nil
 		} else {
+			d.stats.record(d.windowDetector.GetDisplayServer(), false)
 			windowErr = err
 		}
 	}
 
+	if d.processDetector == nil {
+		if windowErr != nil {
+			return nil, fmt.Errorf("window detection failed and process fallback is disabled: %w", windowErr)
+		}
+		return nil, fmt.Errorf("no window detector available and process fallback is disabled")
+	}
+
 	if appInfo, err := d.processDetector.GetActiveApp(); err == nil {
+		d.stats.record(processBackend, true)
 		d.lastSuccessfulMethod = "process"
 
 		if d.windowDetector != nil {
@@ -97,12 +180,14 @@ func (d *Detector) GetActiveApp() (*common.AppInfo, error) {
 					appInfo.WindowTitle = windowInfo.WindowTitle
 					appInfo.Confidence = 0.9
 					appInfo.DetectionMethod = "hybrid"
+					appInfo.Window = windowInfo
 				}
 			}
 		}
 
 		return appInfo, nil
 	} else {
+		d.stats.record(processBackend, false)
 		if windowErr != nil {
 			log.Printf("All detection methods failed - Window: %v, Process: %v", windowErr, err)
 		} else {
@@ -130,6 +215,7 @@ func (d *Detector) getActiveAppFromWindow() (*common.AppInfo, error) {
 		LastActivity:    time.Now(),
 		Confidence:      1.0, // Window detection is most accurate
 		DetectionMethod: "window",
+		Window:          windowInfo,
 	}, nil
 }
 
@@ -152,6 +238,9 @@ func (d *Detector) Initialize() error {
 }
 
 func (d *Detector) Close() error {
+	if d.lockMonitor != nil {
+		d.lockMonitor.Close()
+	}
 	if d.windowDetector != nil {
 		if err := d.windowDetector.Close(); err != nil {
 			log.Printf("Error closing window detector: %v", err)
@@ -166,35 +255,67 @@ func (d *Detector) Close() error {
 }
 
 func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
+	d.refreshSession()
+	info := d.idleInfo()
+	if d.audio {
+		// Without pactl, sound is taken not to be playing.
+		info.HasAudioPlaying, _ = audio.Playing()
+	}
+	return info, nil
+}
+
+func (d *Detector) idleInfo() *window.IdleInfo {
 	if d.windowDetector != nil && d.windowDetector.IsAvailable() {
 		if info, err := d.windowDetector.GetIdleInfo(); err == nil {
-			return info, nil
+			return info
 		}
 	}
 
-	return &window.IdleInfo{
-		IsIdle:   false,
-		IsLocked: d.isScreenLocked(),
-		IdleTime: 0,
-	}, nil
-}
-
-func (d *Detector) isScreenLocked() bool {
-	cmd := exec.Command("gdbus", "call", "--session", "--dest", "org.gnome.ScreenSaver", "--object-path", "/org/gnome/ScreenSaver", "--method", "org.gnome.ScreenSaver.GetActive")
-	if output, err := cmd.Output(); err == nil {
-		if strings.Contains(string(output), "true") {
-			return true
+	info := &window.IdleInfo{IsLocked: d.bus.Locked()}
+	if idle, since, err := d.bus.IdleHint(); err == nil && idle {
+		info.IsIdle = true
+		if !since.IsZero() {
+			info.IdleTime = int64(time.Since(since).Seconds())
 		}
 	}
+	return info
+}
 
-	cmd = exec.Command("loginctl", "show-session", "-p", "LockedHint")
-	if output, err := cmd.Output(); err == nil {
-		if strings.Contains(string(output), "LockedHint=yes") {
-			return true
-		}
+// FocusChanges returns the window detector's focus changes, or nil when
+// focus events are disabled.
+func (d *Detector) FocusChanges() <-chan struct{} {
+	return d.focusChanges
+}
+
+// watchFocus forwards focus changes from the current window detector, if it
+// can listen for them.
+func (d *Detector) watchFocus() {
+	x11Det, ok := d.windowDetector.(*x11.Detector)
+	if d.focusChanges == nil || !ok {
+		return
 	}
+	if !x11Det.WatchFocus() {
+		log.Printf("Focus events unavailable: xprop is missing or failed to start")
+		return
+	}
+	log.Printf("Listening for X11 focus changes")
+	go func(changes <-chan struct{}) {
+		for range changes {
+			select {
+			case d.focusChanges <- struct{}{}:
+			default:
+			}
+		}
+	}(x11Det.FocusChanges())
+}
 
-	return false
+// LockChanges returns screen lock transitions reported over DBus, or nil when
+// no screensaver service could be monitored.
+func (d *Detector) LockChanges() <-chan bool {
+	if d.lockMonitor == nil {
+		return nil
+	}
+	return d.lockMonitor.changes
 }
 
 func (d *Detector) GetAllDetectors() []DetectorInfo {
@@ -257,6 +378,12 @@ func (d *Detector) GetStatus() string {
 	return status
 }
 
+// HasWindowDetector reports whether a display-server detector is in use
+// rather than the process-based fallback alone.
+func (d *Detector) HasWindowDetector() bool {
+	return d.windowDetector != nil
+}
+
 func (d *Detector) GetDisplayServer() string {
 	if d.windowDetector != nil {
 		return d.windowDetector.GetDisplayServer()
@@ -270,10 +397,25 @@ func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
 		return nil, err
 	}
 
-	return &window.WindowInfo{
-		AppName:       appInfo.AppName,
-		WindowTitle:   appInfo.WindowTitle,
-		ProcessName:   appInfo.ProcessName,
-		DisplayServer: d.GetDisplayServer(),
-	}, nil
+	info := &window.WindowInfo{
+		AppName:         appInfo.AppName,
+		WindowTitle:     appInfo.WindowTitle,
+		ProcessName:     appInfo.ProcessName,
+		PID:             appInfo.PID,
+		DisplayServer:   d.GetDisplayServer(),
+		DetectionMethod: appInfo.DetectionMethod,
+		Confidence:      appInfo.Confidence,
+	}
+	if appInfo.Window != nil {
+		info.Geometry = appInfo.Window.Geometry
+		info.IsFullscreen = appInfo.Window.IsFullscreen
+		info.IsMaximized = appInfo.Window.IsMaximized
+		info.Client = appInfo.Window.Client
+		info.Output = appInfo.Window.Output
+		if appInfo.Window.PID > 0 {
+			info.PID = appInfo.Window.PID
+		}
+	}
+
+	return info, nil
 }
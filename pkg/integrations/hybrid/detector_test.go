@@ -1,7 +1,16 @@
 package hybrid
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/dbus/dbustest"
+	"github.com/actionsum/actionsum/pkg/window"
+
+	godbus "github.com/godbus/dbus/v5"
 )
 
 func TestIsScreenLocked(t *testing.T) {
@@ -10,6 +19,128 @@ func TestIsScreenLocked(t *testing.T) {
 		t.Fatalf("Failed to create detector: %v", err)
 	}
 
-	locked := detector.isScreenLocked()
+	locked := detector.bus.Locked()
 	t.Logf("Screen is locked: %v", locked)
 }
+
+func TestLockMonitor(t *testing.T) {
+	address := dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		if msg.Member() != "AddMatch" {
+			return
+		}
+		send(dbustest.Reply(msg))
+		if !strings.Contains(msg.StringArg(0), "org.freedesktop.ScreenSaver") {
+			return
+		}
+		for _, signal := range []struct {
+			iface, member string
+			active        bool
+		}{
+			{"org.gnome.ScreenSaver", "WakeUpScreen", false},
+			{"org.gnome.ScreenSaver", "ActiveChanged", true},
+			{"org.freedesktop.ScreenSaver", "ActiveChanged", true},
+			{"org.gnome.ScreenSaver", "ActiveChanged", false},
+		} {
+			send(dbustest.Signal("/org/gnome/ScreenSaver", signal.iface, signal.member, signal.active))
+		}
+	})
+
+	m, err := watchLocks(address)
+	if err != nil {
+		t.Fatalf("watchLocks() error = %v", err)
+	}
+	defer m.Close()
+
+	for _, want := range []bool{true, false} {
+		select {
+		case got := <-m.changes:
+			if got != want {
+				t.Errorf("lock change = %v, want %v", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no lock change, want %v", want)
+		}
+	}
+	select {
+	case got := <-m.changes:
+		t.Errorf("extra lock change %v; the duplicate from the second interface should be dropped", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLockNotifierInterface(t *testing.T) {
+	var _ window.LockNotifier = (*Detector)(nil)
+	var _ window.FocusNotifier = (*Detector)(nil)
+}
+
+func TestBackendStats(t *testing.T) {
+	var _ window.StatsReporter = (*Detector)(nil)
+
+	d := &Detector{}
+	d.stats.record("x11", true)
+	d.stats.record("x11", true)
+	d.stats.record("x11", false)
+	d.stats.record(processBackend, true)
+
+	stats := d.BackendStats()
+	if got := stats["x11"]; got.Successes != 2 || got.Failures != 1 {
+		t.Errorf("x11 stats = %+v, want 2 successes and 1 failure", got)
+	}
+	if got := stats[processBackend]; got.Successes != 1 || got.Failures != 0 {
+		t.Errorf("process stats = %+v, want 1 success", got)
+	}
+
+	stats["x11"] = window.BackendStats{}
+	if d.BackendStats()["x11"].Successes != 2 {
+		t.Error("BackendStats() returned a map sharing state with the detector")
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	output := "HOME=/home/user\nWAYLAND_DISPLAY=wayland-0\nXDG_SESSION_TYPE=wayland\nDISPLAY=\nLANG=C.UTF-8\n"
+	s := parseEnvironment(output)
+	if len(s) != 2 || s["WAYLAND_DISPLAY"] != "wayland-0" || s["XDG_SESSION_TYPE"] != "wayland" {
+		t.Errorf("parseEnvironment() = %v, want WAYLAND_DISPLAY and XDG_SESSION_TYPE only", s)
+	}
+}
+
+func TestSessionAlive(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	if err := os.WriteFile(filepath.Join(runtimeDir, "wayland-1"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		session session
+		want    string
+	}{
+		{"live wayland", session{"XDG_SESSION_TYPE": "wayland", "WAYLAND_DISPLAY": "wayland-1"}, "wayland|wayland-1|"},
+		{"stale wayland", session{"XDG_SESSION_TYPE": "wayland", "WAYLAND_DISPLAY": "wayland-9"}, "wayland||"},
+		{"stale x11", session{"XDG_SESSION_TYPE": "x11", "DISPLAY": ":97"}, "x11||"},
+		{"remote x11", session{"DISPLAY": "host:0"}, "||host:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.alive().key(); got != tt.want {
+				t.Errorf("alive().key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestX11SocketPath(t *testing.T) {
+	tests := map[string]string{
+		":0":        "/tmp/.X11-unix/X0",
+		":1.0":      "/tmp/.X11-unix/X1",
+		"unix:2":    "/tmp/.X11-unix/X2",
+		"remote:0":  "",
+		"localhost": "",
+	}
+	for display, want := range tests {
+		if got := x11SocketPath(display); got != want {
+			t.Errorf("x11SocketPath(%q) = %q, want %q", display, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package hybrid
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/dbus"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// lockMonitorTimeout bounds connecting to the session bus and subscribing.
+const lockMonitorTimeout = 2 * time.Second
+
+// screensaverInterfaces lists the DBus screensaver interfaces that emit an
+// ActiveChanged(bool) signal when the session is locked or unlocked.
+var screensaverInterfaces = []string{
+	"org.gnome.ScreenSaver",
+	"org.freedesktop.ScreenSaver",
+}
+
+type lockMonitor struct {
+	changes chan bool
+	bus     *dbus.Conn
+	signals chan *godbus.Signal
+	mu      sync.Mutex
+	last    *bool
+}
+
+func newLockMonitor() *lockMonitor {
+	address, err := dbus.SessionBusAddress()
+	if err != nil {
+		return nil
+	}
+	m, err := watchLocks(address)
+	if err != nil {
+		log.Printf("Screen lock changes unavailable: %v", err)
+		return nil
+	}
+	return m
+}
+
+// watchLocks subscribes to the screensavers' ActiveChanged signals on its
+// own connection to the bus at address.
+func watchLocks(address string) (*lockMonitor, error) {
+	bus, err := dbus.Dial(address, lockMonitorTimeout)
+	if err != nil {
+		return nil, err
+	}
+	m := &lockMonitor{changes: make(chan bool, 8), bus: bus, signals: make(chan *godbus.Signal, 8)}
+	bus.Signal(m.signals)
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockMonitorTimeout)
+	defer cancel()
+	for _, iface := range screensaverInterfaces {
+		err := bus.AddMatchSignalContext(ctx, godbus.WithMatchInterface(iface), godbus.WithMatchMember("ActiveChanged"))
+		if err != nil {
+			bus.Close()
+			return nil, err
+		}
+	}
+
+	go m.read()
+	return m, nil
+}
+
+func (m *lockMonitor) read() {
+	// The channel is closed along with the connection.
+	for signal := range m.signals {
+		locked, ok := parseActiveChanged(signal)
+		if !ok {
+			continue
+		}
+
+		// GNOME exports both interfaces, so the same transition arrives twice.
+		m.mu.Lock()
+		duplicate := m.last != nil && *m.last == locked
+		m.last = &locked
+		m.mu.Unlock()
+		if duplicate {
+			continue
+		}
+
+		select {
+		case m.changes <- locked:
+		default:
+			log.Printf("Dropping screen lock change: listener is not keeping up")
+		}
+	}
+}
+
+// parseActiveChanged extracts the lock state from a screensaver's
+// ActiveChanged signal.
+func parseActiveChanged(signal *godbus.Signal) (bool, bool) {
+	for _, iface := range screensaverInterfaces {
+		if signal.Name == iface+".ActiveChanged" && len(signal.Body) > 0 {
+			active, ok := signal.Body[0].(bool)
+			return active, ok
+		}
+	}
+	return false, false
+}
+
+func (m *lockMonitor) Close() {
+	m.bus.Close()
+}
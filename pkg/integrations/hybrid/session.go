@@ -0,0 +1,195 @@
+package hybrid
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// sessionCheckInterval is how often the graphical session is looked up again
+// to notice a switch between X11 and Wayland.
+const sessionCheckInterval = 30 * time.Second
+
+// sessionVars are the variables the window backends, and the tools they run,
+// read to find the display server.
+var sessionVars = []string{
+	"XDG_SESSION_TYPE",
+	"WAYLAND_DISPLAY",
+	"DISPLAY",
+	"XAUTHORITY",
+	"SWAYSOCK",
+	"I3SOCK",
+	"HYPRLAND_INSTANCE_SIGNATURE",
+	"XDG_CURRENT_DESKTOP",
+}
+
+// session is the environment of a graphical session, limited to sessionVars.
+type session map[string]string
+
+// currentSession returns the graphical session the user is logged into.
+// The daemon's own environment is fixed at start, so the systemd user
+// manager's environment, which desktops update at login, is preferred.
+// Displays whose sockets are gone are dropped, so a logged-out session has
+// none.
+func currentSession() session {
+	if s, err := managerSession(); err == nil {
+		if s = s.alive(); s.hasDisplay() {
+			return s
+		}
+	}
+	return processSession().alive()
+}
+
+func processSession() session {
+	s := session{}
+	for _, name := range sessionVars {
+		if value := os.Getenv(name); value != "" {
+			s[name] = value
+		}
+	}
+	return s
+}
+
+func managerSession() (session, error) {
+	output, err := sandbox.Command("systemctl", "--user", "show-environment").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user manager environment: %w", err)
+	}
+	return parseEnvironment(string(output)), nil
+}
+
+// parseEnvironment reads the sessionVars out of NAME=value lines.
+func parseEnvironment(output string) session {
+	s := session{}
+	for _, line := range strings.Split(output, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || value == "" {
+			continue
+		}
+		for _, known := range sessionVars {
+			if name == known {
+				s[name] = value
+				break
+			}
+		}
+	}
+	return s
+}
+
+// alive returns s without the displays whose local sockets don't exist.
+func (s session) alive() session {
+	live := session{}
+	for name, value := range s {
+		live[name] = value
+	}
+	if display := live["WAYLAND_DISPLAY"]; display != "" {
+		path := display
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), display)
+		}
+		if _, err := os.Stat(path); err != nil {
+			delete(live, "WAYLAND_DISPLAY")
+		}
+	}
+	if display := live["DISPLAY"]; display != "" {
+		if path := x11SocketPath(display); path != "" {
+			if _, err := os.Stat(path); err != nil {
+				delete(live, "DISPLAY")
+			}
+		}
+	}
+	return live
+}
+
+func (s session) hasDisplay() bool {
+	return s["WAYLAND_DISPLAY"] != "" || s["DISPLAY"] != ""
+}
+
+// key identifies the display server a session uses; sessions with equal
+// keys can share a window detector.
+func (s session) key() string {
+	return s["XDG_SESSION_TYPE"] + "|" + s["WAYLAND_DISPLAY"] + "|" + s["DISPLAY"]
+}
+
+func (s session) String() string {
+	var displays []string
+	if display := s["WAYLAND_DISPLAY"]; display != "" {
+		displays = append(displays, "WAYLAND_DISPLAY="+display)
+	}
+	if display := s["DISPLAY"]; display != "" {
+		displays = append(displays, "DISPLAY="+display)
+	}
+	if len(displays) == 0 {
+		return "no display"
+	}
+	kind := s["XDG_SESSION_TYPE"]
+	if kind == "" {
+		kind = "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", kind, strings.Join(displays, ", "))
+}
+
+// apply exports s to the process environment, where the backends and the
+// commands they run look for it. Displays s doesn't have are unset.
+func (s session) apply() {
+	for _, name := range sessionVars {
+		if value, ok := s[name]; ok {
+			os.Setenv(name, value)
+		} else if name == "WAYLAND_DISPLAY" || name == "DISPLAY" {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// x11SocketPath maps a local DISPLAY such as ":0" or ":1.0" to its Unix
+// socket. It returns "" for remote displays.
+func x11SocketPath(display string) string {
+	host, number, ok := strings.Cut(display, ":")
+	if !ok || (host != "" && host != "unix") {
+		return ""
+	}
+	number, _, _ = strings.Cut(number, ".")
+	if number == "" {
+		return ""
+	}
+	return "/tmp/.X11-unix/X" + number
+}
+
+// refreshSession switches window backends when the graphical session has
+// changed since it was last looked at, e.g. after logging out of X11 and
+// into Wayland.
+func (d *Detector) refreshSession() {
+	if time.Since(d.sessionChecked) < sessionCheckInterval {
+		return
+	}
+	d.sessionChecked = time.Now()
+
+	s := currentSession()
+	if s.key() == d.session.key() {
+		return
+	}
+	log.Printf("Session changed from %s to %s", d.session, s)
+	s.apply()
+	d.session = s
+	// The buses may belong to the old session.
+	d.bus.Close()
+
+	previous := d.windowDetector
+	d.windowDetector = detectWindowDetector()
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			log.Printf("Error closing window detector: %v", err)
+		}
+	}
+	if d.windowDetector != nil {
+		log.Printf("Window detector switched to %s", d.windowDetector.GetDisplayServer())
+		d.watchFocus()
+	} else {
+		log.Printf("Window detector unavailable, using process-based detection only")
+	}
+}
@@ -0,0 +1,64 @@
+package hybrid
+
+import (
+	"sync"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+// processBackend is the name process-based lookups are counted under.
+const processBackend = "process"
+
+// backendStats counts lookups per backend since the detector was created,
+// and keeps the coverage gaps the window backend last reported.
+type backendStats struct {
+	mu          sync.Mutex
+	counts      map[string]window.BackendStats
+	limitations []string
+}
+
+func (s *backendStats) record(backend string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[string]window.BackendStats)
+	}
+	stats := s.counts[backend]
+	if ok {
+		stats.Successes++
+	} else {
+		stats.Failures++
+	}
+	s.counts[backend] = stats
+}
+
+func (s *backendStats) setLimitations(limitations []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limitations = limitations
+}
+
+func (s *backendStats) snapshot() map[string]window.BackendStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]window.BackendStats, len(s.counts))
+	for backend, stats := range s.counts {
+		counts[backend] = stats
+	}
+	return counts
+}
+
+// BackendStats returns how many lookups each backend answered or failed
+// since the detector was created. Window backends are keyed by display
+// server, process-based guesses by "process".
+func (d *Detector) BackendStats() map[string]window.BackendStats {
+	return d.stats.snapshot()
+}
+
+// Limitations returns the windows the current window backend cannot see, as
+// of its last lookup.
+func (d *Detector) Limitations() []string {
+	d.stats.mu.Lock()
+	defer d.stats.mu.Unlock()
+	return append([]string(nil), d.stats.limitations...)
+}
@@ -0,0 +1,94 @@
+// Package macos detects the focused window and idle time on macOS through
+// Quartz window services, Quartz event sources, AppKit and IOKit.
+package macos
+
+import "github.com/actionsum/actionsum/pkg/window"
+
+// DisplayServer is what events recorded on macOS give as their display
+// server.
+const DisplayServer = "macos"
+
+// idleThreshold is how many seconds without input count as idle, as on the
+// other backends.
+const idleThreshold = 300
+
+// frontWindow is the frontmost window as reported by the platform layer.
+type frontWindow struct {
+	appName     string
+	processName string
+	title       string
+	geometry    window.Geometry
+	fullscreen  bool
+}
+
+type Detector struct {
+	// locks follows the screen lock notifications, or is nil.
+	locks *lockWatcher
+}
+
+func NewDetector() *Detector {
+	return &Detector{locks: watchLocks()}
+}
+
+func (d *Detector) GetDisplayServer() string {
+	return DisplayServer
+}
+
+func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
+	front, err := focusedWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	appName := front.appName
+	if appName == "" {
+		appName = front.processName
+	}
+	if appName == "" {
+		appName = "Unknown"
+	}
+
+	return &window.WindowInfo{
+		AppName:         appName,
+		WindowTitle:     front.title,
+		ProcessName:     front.processName,
+		DisplayServer:   DisplayServer,
+		Geometry:        front.geometry,
+		IsFullscreen:    front.fullscreen,
+		DetectionMethod: "window",
+		Confidence:      1.0,
+	}, nil
+}
+
+func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
+	idleTime, err := idleSeconds()
+	if err != nil {
+		return nil, err
+	}
+
+	return &window.IdleInfo{
+		IsIdle:   idleTime > idleThreshold,
+		IsLocked: screenLocked(),
+		IdleTime: idleTime,
+	}, nil
+}
+
+// LockChanges returns screen lock transitions as macOS announces them, or
+// nil when they can't be observed.
+func (d *Detector) LockChanges() <-chan bool {
+	if d.locks == nil {
+		return nil
+	}
+	return d.locks.changes
+}
+
+func (d *Detector) IsAvailable() bool {
+	return available()
+}
+
+func (d *Detector) Close() error {
+	if d.locks != nil {
+		d.locks.Close()
+	}
+	return nil
+}
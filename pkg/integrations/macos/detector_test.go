@@ -0,0 +1,31 @@
+package macos
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+func TestDetector(t *testing.T) {
+	var _ window.Detector = (*Detector)(nil)
+
+	detector := NewDetector()
+	if got := detector.GetDisplayServer(); got != DisplayServer {
+		t.Errorf("GetDisplayServer() = %s, want %s", got, DisplayServer)
+	}
+
+	if runtime.GOOS == "darwin" {
+		t.Logf("macOS detector available: %v", detector.IsAvailable())
+		return
+	}
+	if detector.IsAvailable() {
+		t.Error("IsAvailable() = true on " + runtime.GOOS)
+	}
+	if _, err := detector.GetFocusedWindow(); err == nil {
+		t.Error("GetFocusedWindow() succeeded on " + runtime.GOOS)
+	}
+	if _, err := detector.GetIdleInfo(); err == nil {
+		t.Error("GetIdleInfo() succeeded on " + runtime.GOOS)
+	}
+}
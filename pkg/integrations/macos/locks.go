@@ -0,0 +1,45 @@
+package macos
+
+import (
+	"log"
+	"sync"
+)
+
+// lockWatcher passes on the screen lock and unlock notifications macOS
+// distributes, so locking is recorded when it happens rather than at the next
+// poll. The state is still read from the session on each poll.
+type lockWatcher struct {
+	changes chan bool
+	stop    func()
+
+	// The last state announced, as both notifications may repeat.
+	mu     sync.Mutex
+	known  bool
+	locked bool
+}
+
+func newLockWatcher() *lockWatcher {
+	return &lockWatcher{changes: make(chan bool, 8)}
+}
+
+func (w *lockWatcher) announce(locked bool) {
+	w.mu.Lock()
+	duplicate := w.known && w.locked == locked
+	w.known, w.locked = true, locked
+	w.mu.Unlock()
+	if duplicate {
+		return
+	}
+
+	select {
+	case w.changes <- locked:
+	default:
+		log.Printf("Dropping screen lock change: listener is not keeping up")
+	}
+}
+
+func (w *lockWatcher) Close() {
+	if w.stop != nil {
+		w.stop()
+	}
+}
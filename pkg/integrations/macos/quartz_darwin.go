@@ -0,0 +1,285 @@
+//go:build darwin && cgo
+
+package macos
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework AppKit -framework CoreGraphics -framework IOKit -framework CoreFoundation
+
+#import <AppKit/AppKit.h>
+#import <CoreGraphics/CoreGraphics.h>
+#import <IOKit/IOKitLib.h>
+#include <pthread.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+
+typedef struct {
+	int found;
+	char *appName;
+	char *processName;
+	char *title;
+	double x, y, width, height;
+	int fullscreen;
+} frontWindow;
+
+static char *copyString(NSString *s) {
+	if (s == nil || ![s isKindOfClass:[NSString class]]) {
+		return NULL;
+	}
+	return strdup([s UTF8String]);
+}
+
+// getFrontWindow finds the frontmost normal window. The on-screen window
+// list is ordered front to back, so its first window on layer 0 belongs to
+// the focused app. Unlike NSWorkspace's frontmostApplication, this needs no
+// running event loop to stay current.
+static frontWindow getFrontWindow(void) {
+	frontWindow w = {0};
+	@autoreleasepool {
+		CFArrayRef list = CGWindowListCopyWindowInfo(
+			kCGWindowListOptionOnScreenOnly | kCGWindowListExcludeDesktopElements, kCGNullWindowID);
+		if (list == NULL) {
+			return w;
+		}
+		NSArray *windows = CFBridgingRelease(list);
+		for (NSDictionary *info in windows) {
+			if ([info[(id)kCGWindowLayer] intValue] != 0) {
+				continue;
+			}
+			pid_t pid = [info[(id)kCGWindowOwnerPID] intValue];
+			w.found = 1;
+
+			NSRunningApplication *app = [NSRunningApplication runningApplicationWithProcessIdentifier:pid];
+			if (app != nil) {
+				w.appName = copyString(app.localizedName);
+				w.processName = copyString(app.executableURL.lastPathComponent);
+			}
+			if (w.appName == NULL) {
+				w.appName = copyString(info[(id)kCGWindowOwnerName]);
+			}
+			// Titles of other apps' windows need the Screen Recording
+			// permission; without it kCGWindowName is missing.
+			w.title = copyString(info[(id)kCGWindowName]);
+
+			CGRect bounds;
+			NSDictionary *boundsInfo = info[(id)kCGWindowBounds];
+			if (boundsInfo != nil && CGRectMakeWithDictionaryRepresentation((__bridge CFDictionaryRef)boundsInfo, &bounds)) {
+				w.x = bounds.origin.x;
+				w.y = bounds.origin.y;
+				w.width = bounds.size.width;
+				w.height = bounds.size.height;
+
+				CGDirectDisplayID display;
+				uint32_t count = 0;
+				if (CGGetDisplaysWithRect(bounds, 1, &display, &count) == kCGErrorSuccess && count > 0) {
+					w.fullscreen = CGRectEqualToRect(bounds, CGDisplayBounds(display));
+				}
+			}
+			break;
+		}
+	}
+	return w;
+}
+
+// secondsSinceInput returns the time since the last input event of any kind
+// in the login session, or a negative value if unknown.
+static double secondsSinceInput(void) {
+	return CGEventSourceSecondsSinceLastEventType(kCGEventSourceStateCombinedSessionState, kCGAnyInputEventType);
+}
+
+// idleNanoseconds reads the time since the last keyboard or mouse input
+// from the HID system, or returns -1.
+static int64_t idleNanoseconds(void) {
+	io_iterator_t iter;
+	if (IOServiceGetMatchingServices(MACH_PORT_NULL, IOServiceMatching("IOHIDSystem"), &iter) != KERN_SUCCESS) {
+		return -1;
+	}
+	io_registry_entry_t entry = IOIteratorNext(iter);
+	IOObjectRelease(iter);
+	if (entry == 0) {
+		return -1;
+	}
+
+	int64_t ns = -1;
+	CFTypeRef value = IORegistryEntryCreateCFProperty(entry, CFSTR("HIDIdleTime"), kCFAllocatorDefault, 0);
+	if (value != NULL) {
+		if (CFGetTypeID(value) == CFNumberGetTypeID()) {
+			CFNumberGetValue((CFNumberRef)value, kCFNumberSInt64Type, &ns);
+		} else if (CFGetTypeID(value) == CFDataGetTypeID() && CFDataGetLength((CFDataRef)value) >= (CFIndex)sizeof(ns)) {
+			CFDataGetBytes((CFDataRef)value, CFRangeMake(0, sizeof(ns)), (UInt8 *)&ns);
+		}
+		CFRelease(value);
+	}
+	IOObjectRelease(entry);
+	return ns;
+}
+
+static int screenLocked(void) {
+	CFDictionaryRef session = CGSessionCopyCurrentDictionary();
+	if (session == NULL) {
+		return 0;
+	}
+	int locked = 0;
+	CFTypeRef value = CFDictionaryGetValue(session, CFSTR("CGSSessionScreenIsLocked"));
+	if (value != NULL && CFGetTypeID(value) == CFBooleanGetTypeID()) {
+		locked = CFBooleanGetValue((CFBooleanRef)value);
+	}
+	CFRelease(session);
+	return locked;
+}
+
+// The lock observer runs a CFRunLoop on a thread of its own, as the Go
+// runtime runs none, and writes '1' on lock and '0' on unlock to lockFD.
+static int lockFD = -1;
+static CFRunLoopRef lockLoop = NULL;
+static pthread_mutex_t lockMutex = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t lockStarted = PTHREAD_COND_INITIALIZER;
+
+static void lockChanged(CFNotificationCenterRef center, void *observer, CFNotificationName name, const void *object, CFDictionaryRef info) {
+	char c = CFEqual(name, CFSTR("com.apple.screenIsLocked")) ? '1' : '0';
+	write(lockFD, &c, 1);
+}
+
+static void *observeLocks(void *arg) {
+	CFNotificationCenterRef center = CFNotificationCenterGetDistributedCenter();
+	CFNotificationCenterAddObserver(center, &lockFD, lockChanged, CFSTR("com.apple.screenIsLocked"), NULL, CFNotificationSuspensionBehaviorDeliverImmediately);
+	CFNotificationCenterAddObserver(center, &lockFD, lockChanged, CFSTR("com.apple.screenIsUnlocked"), NULL, CFNotificationSuspensionBehaviorDeliverImmediately);
+
+	pthread_mutex_lock(&lockMutex);
+	lockLoop = CFRunLoopGetCurrent();
+	pthread_cond_signal(&lockStarted);
+	pthread_mutex_unlock(&lockMutex);
+
+	CFRunLoopRun();
+	CFNotificationCenterRemoveEveryObserver(center, &lockFD);
+	return NULL;
+}
+
+// startLockObserver starts following screen lock notifications, writing
+// them to fd. It returns 0 when the thread can't be started.
+static int startLockObserver(int fd) {
+	pthread_mutex_lock(&lockMutex);
+	if (lockLoop != NULL) {
+		pthread_mutex_unlock(&lockMutex);
+		return 0;
+	}
+	lockFD = fd;
+	pthread_t thread;
+	if (pthread_create(&thread, NULL, observeLocks, NULL) != 0) {
+		pthread_mutex_unlock(&lockMutex);
+		return 0;
+	}
+	pthread_detach(thread);
+	while (lockLoop == NULL) {
+		pthread_cond_wait(&lockStarted, &lockMutex);
+	}
+	pthread_mutex_unlock(&lockMutex);
+	return 1;
+}
+
+static void stopLockObserver(void) {
+	pthread_mutex_lock(&lockMutex);
+	if (lockLoop != NULL) {
+		CFRunLoopStop(lockLoop);
+		lockLoop = NULL;
+	}
+	pthread_mutex_unlock(&lockMutex);
+}
+
+// hasSession reports whether the process runs in a logged-in GUI session,
+// which the window list and HID system need.
+static int hasSession(void) {
+	CFDictionaryRef session = CGSessionCopyCurrentDictionary();
+	if (session == NULL) {
+		return 0;
+	}
+	CFRelease(session);
+	return 1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"unsafe"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+func focusedWindow() (*frontWindow, error) {
+	w := C.getFrontWindow()
+	defer C.free(unsafe.Pointer(w.appName))
+	defer C.free(unsafe.Pointer(w.processName))
+	defer C.free(unsafe.Pointer(w.title))
+
+	if w.found == 0 {
+		return nil, fmt.Errorf("no focused window found in the macOS window list")
+	}
+	return &frontWindow{
+		appName:     C.GoString(w.appName),
+		processName: C.GoString(w.processName),
+		title:       C.GoString(w.title),
+		geometry: window.Geometry{
+			X:      int(w.x),
+			Y:      int(w.y),
+			Width:  int(w.width),
+			Height: int(w.height),
+		},
+		fullscreen: w.fullscreen != 0,
+	}, nil
+}
+
+// idleSeconds prefers the session's event source, which also counts input
+// the HID system doesn't see, such as remote control sessions.
+func idleSeconds() (int64, error) {
+	if seconds := float64(C.secondsSinceInput()); seconds >= 0 && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		return int64(seconds), nil
+	}
+	ns := int64(C.idleNanoseconds())
+	if ns < 0 {
+		return 0, fmt.Errorf("failed to read idle time from IOHIDSystem")
+	}
+	return ns / 1e9, nil
+}
+
+func screenLocked() bool {
+	return C.screenLocked() != 0
+}
+
+// watchLocks follows the com.apple.screenIsLocked and screenIsUnlocked
+// distributed notifications, or returns nil when they can't be observed.
+func watchLocks() *lockWatcher {
+	r, fd, err := os.Pipe()
+	if err != nil {
+		return nil
+	}
+	if C.startLockObserver(C.int(fd.Fd())) == 0 {
+		r.Close()
+		fd.Close()
+		return nil
+	}
+
+	w := newLockWatcher()
+	w.stop = func() {
+		C.stopLockObserver()
+		r.Close()
+	}
+	go func() {
+		defer fd.Close()
+		buf := make([]byte, 1)
+		for {
+			if _, err := r.Read(buf); err != nil {
+				return
+			}
+			w.announce(buf[0] == '1')
+		}
+	}()
+	return w
+}
+
+func available() bool {
+	return C.hasSession() != 0
+}
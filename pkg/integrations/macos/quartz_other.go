@@ -0,0 +1,29 @@
+//go:build !darwin || !cgo
+
+package macos
+
+import "fmt"
+
+// errUnsupported is returned on other systems, and on macOS builds made
+// without cgo, which the window services need.
+var errUnsupported = fmt.Errorf("macOS window detection needs a macOS build with cgo enabled")
+
+func focusedWindow() (*frontWindow, error) {
+	return nil, errUnsupported
+}
+
+func idleSeconds() (int64, error) {
+	return 0, errUnsupported
+}
+
+func screenLocked() bool {
+	return false
+}
+
+func watchLocks() *lockWatcher {
+	return nil
+}
+
+func available() bool {
+	return false
+}
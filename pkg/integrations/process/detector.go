@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -12,10 +11,11 @@ import (
 	"time"
 
 	"github.com/actionsum/actionsum/pkg/integrations/common"
+	"github.com/actionsum/actionsum/pkg/normalize"
+	"github.com/actionsum/actionsum/pkg/sandbox"
 )
 
 type Detector struct {
-	sessionID      string
 	lastScan       time.Time
 	knownProcesses map[int]*processInfo
 	guiApps        []string
@@ -44,11 +44,6 @@ func (d *Detector) Initialize() error {
 		return nil
 	}
 
-	cmd := exec.Command("gdbus", "call", "--session", "--dest", "org.gnome.ScreenSaver", "--object-path", "/org/gnome/ScreenSaver", "--method", "org.gnome.ScreenSaver.GetActive")
-	if output, err := cmd.Output(); err == nil {
-		d.sessionID = strings.TrimSpace(string(output))
-	}
-
 	d.inputMonitor = NewInputMonitor()
 	if err := d.inputMonitor.Initialize(); err != nil {
 		fmt.Printf("Warning: input monitoring unavailable: %v\n", err)
@@ -85,7 +80,7 @@ func (d *Detector) GetActiveApp() (*common.AppInfo, error) {
 		ProcessName:     proc.name,
 		PID:             best.pid,
 		LastActivity:    proc.lastSeen,
-		Confidence:      best.score,
+		Confidence:      best.confidence,
 		DetectionMethod: "process-based",
 	}, nil
 }
@@ -164,6 +159,12 @@ func (d *Detector) readProcessInfo(pid int) (*processInfo, error) {
 	cmdlinePath := filepath.Join("/proc", strconv.Itoa(pid), "cmdline")
 	if cmdData, err := os.ReadFile(cmdlinePath); err == nil {
 		info.cmdline = strings.ReplaceAll(string(cmdData), "\x00", " ")
+		// A shared Electron runtime is reported as the app it runs.
+		if args := strings.Split(strings.TrimRight(string(cmdData), "\x00"), "\x00"); normalize.IsElectron(info.name) && len(args) > 1 {
+			if product := normalize.ElectronProduct(args[1:]); product != "" {
+				info.name = product
+			}
+		}
 	}
 
 	return info, nil
@@ -203,6 +204,10 @@ func (d *Detector) isGUIApp(info *processInfo) bool {
 type scoredProcess struct {
 	pid   int
 	score float64
+
+	// confidence counts only evidence that the process has focus, not the
+	// ancestry boosts that rank the daemon's own terminal first.
+	confidence float64
 }
 
 func (d *Detector) scoreProcesses(activePIDs map[int]time.Time) []scoredProcess {
@@ -212,15 +217,16 @@ func (d *Detector) scoreProcesses(activePIDs map[int]time.Time) []scoredProcess
 
 	for pid, proc := range d.knownProcesses {
 		score := 0.0
+		boost := 0.0
 
 		score += 0.3
 
 		if pid == myTerminalPID {
-			score += 10.0 // Very high score ensures this wins
+			boost += 10.0 // Very high score ensures this wins
 		}
 
 		if d.isAncestorProcess(pid) {
-			score += 5.0 // High score for ancestor processes (e.g., VSCode, terminal)
+			boost += 5.0 // High score for ancestor processes (e.g., VSCode, terminal)
 		}
 
 		if lastActive, ok := activePIDs[pid]; ok {
@@ -239,7 +245,7 @@ func (d *Detector) scoreProcesses(activePIDs map[int]time.Time) []scoredProcess
 			score += 0.2
 		}
 
-		scored = append(scored, scoredProcess{pid: pid, score: score})
+		scored = append(scored, scoredProcess{pid: pid, score: score + boost, confidence: min(score, 1.0)})
 	}
 
 	sort.Slice(scored, func(i, j int) bool {
@@ -319,7 +325,7 @@ func (d *Detector) isAncestorProcess(checkPID int) bool {
 }
 
 func getWindowTitleForPID(pid int) string {
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("xprop -root _NET_CLIENT_LIST | tr ',' '\\n' | while read w; do xprop -id $w _NET_WM_PID | grep -q %d && xprop -id $w WM_NAME; done | head -1", pid))
+	cmd := sandbox.Command("sh", "-c", fmt.Sprintf("xprop -root _NET_CLIENT_LIST | tr ',' '\\n' | while read w; do xprop -id $w _NET_WM_PID | grep -q %d && xprop -id $w WM_NAME; done | head -1", pid))
 	if output, err := cmd.Output(); err == nil {
 		title := string(output)
 		if strings.Contains(title, "=") {
@@ -385,7 +391,7 @@ func (im *InputMonitor) monitor() {
 }
 
 func (im *InputMonitor) updateActivityFromCPU() {
-	cmd := exec.Command("ps", "aux", "--sort=-pcpu")
+	cmd := sandbox.Command("ps", "aux", "--sort=-pcpu")
 	output, err := cmd.Output()
 	if err != nil {
 		return
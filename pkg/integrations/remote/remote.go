@@ -0,0 +1,114 @@
+// Package remote tells when the time tracked isn't really spent in the
+// focused app: when that app is a remote-desktop client, whatever runs on
+// the remote machine has the user's attention, and when this session is
+// itself used over VNC or RDP, or a VPN is up, the work is done from
+// elsewhere.
+package remote
+
+import (
+	"net"
+	"strings"
+)
+
+// Protocols of remote desktops.
+const (
+	RDP    = "rdp"
+	VNC    = "vnc"
+	Citrix = "citrix"
+	Other  = "other" // Proprietary ones such as AnyDesk and TeamViewer
+)
+
+// clients maps remote-desktop clients, by process name, WM_CLASS, Wayland
+// app ID or macOS app name, lowercased, to the protocol they usually speak.
+// Clients speaking several, such as Remmina, are put under their most
+// common one.
+var clients = map[string]string{
+	"anydesk":                  Other,
+	"citrix viewer":            Citrix,
+	"citrix workspace":         Citrix,
+	"com.freerdp.freerdp":      RDP,
+	"com.microsoft.rdc.macos":  RDP,
+	"com.realvnc.vncviewer":    VNC,
+	"com.rustdesk.rustdesk":    Other,
+	"gnome-connections":        VNC,
+	"jump desktop":             Other,
+	"krdc":                     RDP,
+	"microsoft remote desktop": RDP,
+	"msrdc":                    RDP,
+	"mstsc":                    RDP,
+	"nxplayer":                 Other,
+	"org.gnome.connections":    VNC,
+	"org.kde.krdc":             RDP,
+	"org.remmina.remmina":      RDP,
+	"parsecd":                  Other,
+	"rdesktop":                 RDP,
+	"realvnc-vncviewer":        VNC,
+	"remmina":                  RDP,
+	"rustdesk":                 Other,
+	"screens 5":                VNC,
+	"sdl-freerdp":              RDP,
+	"selfservice":              Citrix,
+	"teamviewer":               Other,
+	"tigervnc":                 VNC,
+	"vinagre":                  VNC,
+	"vmware-view":              Other,
+	"vncviewer":                VNC,
+	"wfica":                    Citrix,
+	"wfica32":                  Citrix,
+	"windows app":              RDP,
+	"wlfreerdp":                RDP,
+	"xfreerdp":                 RDP,
+	"xfreerdp3":                RDP,
+	"xtightvncviewer":          VNC,
+}
+
+// ClientProtocol returns the protocol of the remote-desktop client a
+// window's app or process name is, or "" for other apps.
+func ClientProtocol(appName, processName string) string {
+	if protocol, ok := clients[strings.ToLower(appName)]; ok {
+		return protocol
+	}
+	return clients[strings.ToLower(processName)]
+}
+
+// portProtocol returns the protocol served on a well-known remote-desktop
+// port: 3389 for RDP and 5900 to 5999, one per display, for VNC.
+func portProtocol(port int) string {
+	switch {
+	case port == 3389:
+		return RDP
+	case port >= 5900 && port <= 5999:
+		return VNC
+	}
+	return ""
+}
+
+// VPNUp reports whether a network interface whose name starts with one of
+// prefixes, such as "tun" or "wg", is up and has an address.
+func VPNUp(prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || !hasPrefix(iface.Name, prefixes) {
+			continue
+		}
+		if addrs, err := iface.Addrs(); err == nil && len(addrs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
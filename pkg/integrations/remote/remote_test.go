@@ -0,0 +1,23 @@
+package remote
+
+import "testing"
+
+func TestClientProtocol(t *testing.T) {
+	tests := []struct {
+		appName, processName string
+		want                 string
+	}{
+		{"org.remmina.Remmina", "remmina", RDP},
+		{"Vncviewer", "vncviewer", VNC},
+		{"Citrix Viewer", "Citrix Viewer", Citrix},
+		{"Wfica", "wfica", Citrix},
+		{"AnyDesk", "anydesk", Other},
+		{"unknown", "xfreerdp", RDP},
+		{"firefox", "firefox", ""},
+	}
+	for _, tt := range tests {
+		if got := ClientProtocol(tt.appName, tt.processName); got != tt.want {
+			t.Errorf("ClientProtocol(%q, %q) = %q, want %q", tt.appName, tt.processName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package remote
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tcpEstablished is the state of a connected socket in /proc/net/tcp.
+const tcpEstablished = "01"
+
+// SessionProtocol returns the protocol this session is being used over
+// remotely, or "" when it isn't: xrdp marks its sessions in the
+// environment, and otherwise a connection to the local RDP port or a VNC
+// port, as gnome-remote-desktop, krfb, wayvnc and x11vnc serve, means
+// someone is watching the screen.
+func SessionProtocol() string {
+	if os.Getenv("XRDP_SESSION") != "" {
+		return RDP
+	}
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if protocol := parseConnections(string(data)); protocol != "" {
+			return protocol
+		}
+	}
+	return ""
+}
+
+// parseConnections finds an established connection to a remote-desktop
+// port in the format of /proc/net/tcp.
+func parseConnections(data string) string {
+	lines := strings.Split(data, "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		// The local address is hex IP:port.
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			continue
+		}
+		if protocol := portProtocol(int(port)); protocol != "" {
+			return protocol
+		}
+	}
+	return ""
+}
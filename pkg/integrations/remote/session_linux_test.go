@@ -0,0 +1,42 @@
+package remote
+
+import "testing"
+
+func TestParseConnections(t *testing.T) {
+	const header = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "listening only",
+			data: header + "   0: 00000000:170C 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1 1 0000000000000000 100 0 0 10 0\n",
+			want: "",
+		},
+		{
+			name: "vnc client connected",
+			data: header + "   0: 00000000:170C 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1 1 0000000000000000 100 0 0 10 0\n" +
+				"   1: 0100A8C0:170C 0200A8C0:D431 01 00000000:00000000 00:00000000 00000000  1000        0 2 1 0000000000000000 20 4 30 10 -1\n",
+			want: VNC,
+		},
+		{
+			name: "rdp client connected",
+			data: header + "   0: 0100A8C0:0D3D 0200A8C0:D431 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1\n",
+			want: RDP,
+		},
+		{
+			// Connecting out to a VNC server is the client side.
+			name: "outgoing connection",
+			data: header + "   0: 0100A8C0:D431 0200A8C0:170C 01 00000000:00000000 00:00000000 00000000  1000        0 2 1 0000000000000000 20 4 30 10 -1\n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseConnections(tt.data); got != tt.want {
+				t.Errorf("parseConnections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
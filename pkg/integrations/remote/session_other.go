@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package remote
+
+// SessionProtocol can't tell whether the session is used remotely here yet.
+func SessionProtocol() string {
+	return ""
+}
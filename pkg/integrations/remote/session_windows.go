@@ -0,0 +1,15 @@
+package remote
+
+import (
+	"os"
+	"strings"
+)
+
+// SessionProtocol returns RDP when the daemon runs in a Remote Desktop
+// session, which Windows names "RDP-Tcp#N" in SESSIONNAME.
+func SessionProtocol() string {
+	if strings.HasPrefix(strings.ToUpper(os.Getenv("SESSIONNAME")), "RDP-") {
+		return RDP
+	}
+	return ""
+}
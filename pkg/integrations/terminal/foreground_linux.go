@@ -0,0 +1,127 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// process is what /proc/<pid>/stat says about a process.
+type process struct {
+	pid, ppid   int
+	pgrp, tpgid int
+	session     int
+	tty         int
+	start       uint64 // Clock ticks after boot
+	comm        string
+}
+
+// ForegroundCommand returns the name of the command in the foreground of
+// the terminal whose window belongs to pid: among the terminal's
+// descendants, the leader of the process group its tty runs in the
+// foreground, which is the shell itself at a prompt. With several tabs, the
+// one whose command the window title names wins, then the most recently
+// started. It also returns the command's session, whose leader is the shell
+// the terminal started.
+func ForegroundCommand(pid int, windowTitle string) (string, int, error) {
+	return foregroundCommand("/proc", pid, windowTitle)
+}
+
+func foregroundCommand(proc string, pid int, windowTitle string) (string, int, error) {
+	processes, err := readProcesses(proc)
+	if err != nil {
+		return "", 0, err
+	}
+	children := make(map[int][]int)
+	for _, p := range processes {
+		children[p.ppid] = append(children[p.ppid], p.pid)
+	}
+
+	var best *process
+	bestNamed := false
+	queue := append([]int(nil), children[pid]...)
+	for len(queue) > 0 {
+		p := processes[queue[0]]
+		queue = append(queue[1:], children[queue[0]]...)
+		if p == nil || p.tty == 0 || p.pgrp != p.tpgid || p.pid != p.pgrp {
+			continue
+		}
+		named := mentions(windowTitle, p.comm)
+		if best == nil || (named && !bestNamed) || (named == bestNamed && p.start > best.start) {
+			best, bestNamed = p, named
+		}
+	}
+	if best == nil {
+		return "", 0, fmt.Errorf("no foreground command under process %d", pid)
+	}
+	return best.comm, best.session, nil
+}
+
+// mentions reports whether title has command as a word of its own, as in
+// "vim main.go" or "make: ~/src".
+func mentions(title, command string) bool {
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.'
+	})
+	for _, word := range words {
+		if word == command {
+			return true
+		}
+	}
+	return false
+}
+
+func readProcesses(proc string) (map[int]*process, error) {
+	entries, err := os.ReadDir(proc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	processes := make(map[int]*process, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Processes may exit while being read.
+		data, err := os.ReadFile(filepath.Join(proc, entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		if p, ok := parseStat(string(data)); ok && p.pid == pid {
+			processes[pid] = p
+		}
+	}
+	return processes, nil
+}
+
+// parseStat reads /proc/<pid>/stat, "pid (comm) state ppid pgrp session
+// tty_nr tpgid ...", where comm may itself hold spaces and parentheses.
+func parseStat(stat string) (*process, bool) {
+	open, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open == -1 || end < open {
+		return nil, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:open]))
+	if err != nil {
+		return nil, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	// starttime is field 22 of the line, the 20th after comm.
+	if len(fields) < 20 {
+		return nil, false
+	}
+	p := &process{pid: pid, comm: stat[open+1 : end]}
+	ints := []*int{&p.ppid, &p.pgrp, &p.session, &p.tty, &p.tpgid}
+	for i, dst := range ints {
+		if *dst, err = strconv.Atoi(fields[1+i]); err != nil {
+			return nil, false
+		}
+	}
+	if p.start, err = strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return nil, false
+	}
+	return p, true
+}
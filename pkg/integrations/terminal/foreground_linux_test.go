@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeProc lays out a fake /proc with a stat file per process.
+func writeProc(t *testing.T, stats ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, stat := range stats {
+		var pid int
+		fmt.Sscanf(stat, "%d", &pid)
+		path := filepath.Join(dir, fmt.Sprint(pid))
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "stat"), []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// stat renders a stat line with the fields foregroundCommand reads.
+func stat(pid int, comm string, ppid, pgrp, session, tty, tpgid int, start uint64) string {
+	return fmt.Sprintf("%d (%s) S %d %d %d %d %d 4194304 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0\n",
+		pid, comm, ppid, pgrp, session, tty, tpgid, start)
+}
+
+func TestForegroundCommand(t *testing.T) {
+	proc := writeProc(t,
+		stat(1000, "kitty", 1, 1000, 1000, 0, -1, 100),
+		// First tab: vim started from zsh.
+		stat(1001, "zsh", 1000, 1001, 1001, 34816, 1005, 110),
+		stat(1005, "vim", 1001, 1005, 1001, 34816, 1005, 500),
+		// Second tab: make running a compiler, started later.
+		stat(1002, "zsh", 1000, 1002, 1002, 34817, 1010, 120),
+		stat(1010, "make", 1002, 1010, 1002, 34817, 1010, 900),
+		stat(1011, "cc1 (child)", 1010, 1010, 1002, 34817, 1010, 901),
+		// Not the terminal's.
+		stat(2000, "htop", 1, 2000, 2000, 34818, 2000, 1000),
+	)
+
+	tests := []struct {
+		title   string
+		want    string
+		session int
+	}{
+		{"~/src/actionsum", "make", 1002},
+		{"vim main.go", "vim", 1001},
+		{"make: ~/src", "make", 1002},
+	}
+	for _, tt := range tests {
+		got, session, err := foregroundCommand(proc, 1000, tt.title)
+		if err != nil || got != tt.want || session != tt.session {
+			t.Errorf("foregroundCommand(%q) = %q, %d, %v; want %q, %d", tt.title, got, session, err, tt.want, tt.session)
+		}
+	}
+
+	if _, _, err := foregroundCommand(proc, 2000, ""); err == nil {
+		t.Error("foregroundCommand() found a command in a process without children")
+	}
+}
+
+func TestForegroundCommandAtPrompt(t *testing.T) {
+	proc := writeProc(t,
+		stat(1000, "foot", 1, 1000, 1000, 0, -1, 100),
+		stat(1001, "bash", 1000, 1001, 1001, 34816, 1001, 110),
+	)
+	if got, session, err := foregroundCommand(proc, 1000, "foot"); err != nil || got != "bash" || session != 1001 {
+		t.Errorf("foregroundCommand() = %q, %d, %v; want the shell", got, session, err)
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	p, ok := parseStat(stat(42, "tmux: server (1)", 1, 42, 42, 0, -1, 77))
+	if !ok {
+		t.Fatal("parseStat() failed")
+	}
+	if p.pid != 42 || p.comm != "tmux: server (1)" || p.ppid != 1 || p.pgrp != 42 || p.tpgid != -1 || p.start != 77 {
+		t.Errorf("parseStat() = %+v", p)
+	}
+	if _, ok := parseStat("42 (short) S 1"); ok {
+		t.Error("parseStat() accepted a truncated line")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package terminal
+
+import "errors"
+
+// ForegroundCommand needs /proc, which only Linux has.
+func ForegroundCommand(pid int, windowTitle string) (string, int, error) {
+	return "", 0, errors.New("foreground commands are only found on Linux")
+}
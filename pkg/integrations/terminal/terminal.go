@@ -0,0 +1,54 @@
+// Package terminal finds the command running in the foreground of a
+// terminal window, such as vim, ssh or make, so time in terminals can be
+// told apart by what ran in them.
+package terminal
+
+import "strings"
+
+// terminals names terminal emulators by process name, WM_CLASS or Wayland
+// app ID, lowercased.
+var terminals = map[string]bool{
+	"alacritty":              true,
+	"blackbox":               true,
+	"com.gexperts.tilix":     true,
+	"com.mitchellh.ghostty":  true,
+	"contour":                true,
+	"cool-retro-term":        true,
+	"deepin-terminal":        true,
+	"foot":                   true,
+	"footclient":             true,
+	"ghostty":                true,
+	"gnome-terminal":         true,
+	"gnome-terminal-server":  true,
+	"kgx":                    true,
+	"kitty":                  true,
+	"konsole":                true,
+	"lxterminal":             true,
+	"mate-terminal":          true,
+	"org.gnome.console":      true,
+	"org.gnome.ptyxis":       true,
+	"org.gnome.terminal":     true,
+	"org.kde.konsole":        true,
+	"org.wezfurlong.wezterm": true,
+	"ptyxis":                 true,
+	"qterminal":              true,
+	"rxvt":                   true,
+	"sakura":                 true,
+	"st":                     true,
+	"st-256color":            true,
+	"terminator":             true,
+	"terminology":            true,
+	"tilix":                  true,
+	"urxvt":                  true,
+	"uxterm":                 true,
+	"wezterm":                true,
+	"wezterm-gui":            true,
+	"xfce4-terminal":         true,
+	"xterm":                  true,
+}
+
+// IsTerminal reports whether a window's app or process name is a known
+// terminal emulator.
+func IsTerminal(appName, processName string) bool {
+	return terminals[strings.ToLower(appName)] || terminals[strings.ToLower(processName)]
+}
@@ -3,47 +3,90 @@ package wayland
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/sandbox"
 	"github.com/actionsum/actionsum/pkg/window"
 )
 
 type Detector struct {
 	compositor string
-	hasSwaymsg bool
-	hasGdbus   bool
+
+	// xwaylandOnly is set while GNOME answers through the xprop fallback,
+	// which cannot see native Wayland windows.
+	xwaylandOnly atomic.Bool
+
+	// sway and hyprland are the IPC clients on those compositors, when
+	// their sockets were found.
+	sway     *swayIPC
+	hyprland *hyprlandIPC
+
+	// kwin queries KWin through its scripting API over the session bus.
+	kwin *kwinScripting
+
+	// gnome asks GNOME Shell, through actionsum's extension when
+	// installed, else Shell.Eval.
+	gnome *gnomeShell
+
+	// idle follows the compositor's idle notifications.
+	idle *idleMonitor
+
+	// session asks logind and the screensaver for the idle and lock state.
+	session *dbus.Session
 }
 
 func NewDetector() *Detector {
-	d := &Detector{}
-	d.hasSwaymsg = d.commandExists("swaymsg")
-	d.hasGdbus = d.commandExists("gdbus")
+	d := &Detector{session: dbus.SharedSession()}
 	d.detectCompositor()
+	if path, err := socketPath(); err == nil {
+		d.idle = newIdleMonitor(path)
+	}
+	switch d.compositor {
+	case "sway":
+		if path, err := swaySocketPath(); err == nil {
+			d.sway = newSwayIPC(path)
+		}
+	case "hyprland":
+		if dir, err := hyprlandSocketDir(); err == nil {
+			d.hyprland = newHyprlandIPC(dir)
+		}
+	case "kde":
+		if address, err := dbus.SessionBusAddress(); err == nil {
+			d.kwin = newKWinScripting(address)
+		}
+	case "gnome":
+		if address, err := dbus.SessionBusAddress(); err == nil {
+			d.gnome = newGnomeShell(address)
+		}
+	}
 	return d
 }
 
 func (d *Detector) commandExists(cmd string) bool {
-	_, err := exec.LookPath(cmd)
+	_, err := sandbox.LookPath(cmd)
 	return err == nil
 }
 
+// detectCompositor asks the compositor which protocols it offers, which
+// works even where its process is hidden or renamed, as in containers and
+// flatpak, and falls back to the session's environment.
 func (d *Detector) detectCompositor() {
-	compositors := map[string]string{
-		"sway":         "sway",
-		"Hyprland":     "hyprland",
-		"wayfire":      "wayfire",
-		"river":        "river",
-		"gnome-shell":  "gnome",
-		"kwin_wayland": "kde",
+	if path, err := socketPath(); err == nil {
+		if globals, err := queryGlobals(path); err == nil {
+			if name := compositorFromGlobals(globals); name != "" {
+				d.compositor = name
+				return
+			}
+		}
 	}
 
-	for process, name := range compositors {
-		cmd := exec.Command("pgrep", "-x", process)
-		if err := cmd.Run(); err == nil {
-			d.compositor = name
-			return
-		}
+	if name := compositorFromEnvironment(); name != "" {
+		d.compositor = name
+		return
 	}
 
 	d.compositor = "unknown"
@@ -51,12 +94,14 @@ func (d *Detector) detectCompositor() {
 
 func (d *Detector) IsAvailable() bool {
 	switch d.compositor {
-	case "sway", "hyprland":
-		return d.hasSwaymsg
+	case "sway":
+		return d.sway != nil
+	case "hyprland":
+		return d.hyprland != nil
 	case "gnome":
-		return d.hasGdbus
+		return d.gnome != nil
 	case "kde":
-		return true
+		return d.kwin != nil
 	default:
 		return false
 	}
@@ -81,210 +126,31 @@ func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
 	}
 }
 
-func (d *Detector) getFocusedWindowSway() (*window.WindowInfo, error) {
-	cmd := exec.Command("swaymsg", "-t", "get_tree")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute swaymsg: %w", err)
-	}
-
-	info, err := parseSwayTree(string(output))
-	if err != nil {
-		return nil, err
-	}
-
-	info.DisplayServer = "wayland"
-	return info, nil
-}
-
-func parseSwayTree(jsonOutput string) (*window.WindowInfo, error) {
-	lines := strings.Split(jsonOutput, "\n")
-
-	var appName, windowTitle, pid string
-	inFocusedNode := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.Contains(line, `"focused": true`) {
-			inFocusedNode = true
-		}
-
-		if inFocusedNode {
-			if strings.HasPrefix(line, `"app_id":`) || strings.HasPrefix(line, `"class":`) {
-				parts := strings.SplitN(line, ":", 2)
-				if len(parts) == 2 {
-					appName = strings.Trim(strings.TrimRight(parts[1], ","), `" `)
-				}
-			}
-
-			if strings.HasPrefix(line, `"name":`) {
-				parts := strings.SplitN(line, ":", 2)
-				if len(parts) == 2 {
-					windowTitle = strings.Trim(strings.TrimRight(parts[1], ","), `" `)
-				}
-			}
-
-			if strings.HasPrefix(line, `"pid":`) {
-				parts := strings.SplitN(line, ":", 2)
-				if len(parts) == 2 {
-					pid = strings.Trim(strings.TrimRight(parts[1], ","), " ")
-				}
-			}
-
-			if appName != "" && windowTitle != "" && pid != "" {
-				break
-			}
-		}
-	}
-
-	if appName == "" {
-		appName = "Unknown"
-	}
-	if windowTitle == "" {
-		windowTitle = "Unknown"
-	}
-
-	processName := appName
-	if pid != "" {
-		if name := getProcessName(pid); name != "" {
-			processName = name
-		}
-	}
-
-	return &window.WindowInfo{
-		AppName:     appName,
-		WindowTitle: windowTitle,
-		ProcessName: processName,
-	}, nil
-}
-
-func (d *Detector) getFocusedWindowHyprland() (*window.WindowInfo, error) {
-	cmd := exec.Command("hyprctl", "activewindow", "-j")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute hyprctl: %w", err)
-	}
-
-	info := parseHyprlandWindow(string(output))
-	info.DisplayServer = "wayland"
-	return info, nil
-}
-
-func parseHyprlandWindow(jsonOutput string) *window.WindowInfo {
-	lines := strings.Split(jsonOutput, "\n")
-
-	var appName, windowTitle, pid string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, `"class":`) {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				appName = strings.Trim(strings.TrimRight(parts[1], ","), `" `)
-			}
-		}
-
-		if strings.HasPrefix(line, `"title":`) {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				windowTitle = strings.Trim(strings.TrimRight(parts[1], ","), `" `)
-			}
-		}
-
-		if strings.HasPrefix(line, `"pid":`) {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				pid = strings.Trim(strings.TrimRight(parts[1], ","), " ")
-			}
-		}
-	}
-
-	if appName == "" {
-		appName = "Unknown"
-	}
-	if windowTitle == "" {
-		windowTitle = "Unknown"
-	}
-
-	processName := appName
-	if pid != "" {
-		if name := getProcessName(pid); name != "" {
-			processName = name
-		}
-	}
-
-	return &window.WindowInfo{
-		AppName:     appName,
-		WindowTitle: windowTitle,
-		ProcessName: processName,
-	}
-}
-
 func (d *Detector) getFocusedWindowGnome() (*window.WindowInfo, error) {
-	script := `
-	try {
-		let win = global.get_window_actors().find(w => w.meta_window && w.meta_window.has_focus());
-		if (win && win.meta_window) {
-			let wm_class = win.meta_window.get_wm_class() || 'Unknown';
-			let title = win.meta_window.get_title() || 'Unknown';
-			wm_class + '|||' + title;
-		} else {
-			'Unknown|||Unknown';
+	if d.gnome != nil {
+		if info, err := d.gnome.extensionWindow(); err == nil {
+			d.xwaylandOnly.Store(false)
+			return info, nil
 		}
-	} catch(e) {
-		'Unknown|||Unknown';
 	}
-	`
-
-	cmd := exec.Command("gdbus", "call", "--session",
-		"--dest", "org.gnome.Shell",
-		"--object-path", "/org/gnome/Shell",
-		"--method", "org.gnome.Shell.Eval",
-		script)
-
-	output, err := cmd.Output()
 
-	if err == nil {
-		result := strings.TrimSpace(string(output))
-
-		if strings.HasPrefix(result, "(true,") {
-			result = strings.TrimPrefix(result, "(true, '")
-			result = strings.TrimSuffix(result, "')")
-			result = strings.Trim(result, "'\"")
-
-			parts := strings.Split(result, "|||")
-			appName := "Unknown"
-			windowTitle := "Unknown"
-
-			if len(parts) >= 1 && parts[0] != "" && parts[0] != "Unknown" {
-				appName = parts[0]
-			}
-			if len(parts) >= 2 && parts[1] != "" && parts[1] != "Unknown" {
-				windowTitle = parts[1]
-			}
-
-			if appName != "Unknown" {
-				return &window.WindowInfo{
-					AppName:       appName,
-					WindowTitle:   windowTitle,
-					ProcessName:   appName,
-					DisplayServer: "wayland",
-				}, nil
-			}
+	if d.gnome != nil {
+		if info, err := d.gnome.evalWindow(); err == nil {
+			d.xwaylandOnly.Store(false)
+			return info, nil
 		}
 	}
 
 	if d.commandExists("xprop") {
+		d.xwaylandOnly.Store(true)
 		info, xErr := d.getFocusedWindowXWayland()
 		if xErr == nil {
 			return info, nil
 		}
-		return nil, fmt.Errorf("GNOME window detection failed: gdbus Shell.Eval blocked, xprop failed: %v", xErr)
+		return nil, fmt.Errorf("GNOME window detection failed: Shell.Eval blocked, xprop failed: %v", xErr)
 	}
 
-	return nil, fmt.Errorf("GNOME window detection failed: gdbus Shell.Eval blocked and xprop unavailable")
+	return nil, fmt.Errorf("GNOME window detection failed: Shell.Eval blocked and xprop unavailable")
 }
 
 func (d *Detector) getFocusedWindowXWayland() (*window.WindowInfo, error) {
@@ -293,7 +159,7 @@ func (d *Detector) getFocusedWindowXWayland() (*window.WindowInfo, error) {
 		return nil, fmt.Errorf("DISPLAY environment variable not set (XWayland not available)")
 	}
 
-	rootCmd := exec.Command("xprop", "-root", "_NET_ACTIVE_WINDOW")
+	rootCmd := sandbox.Command("xprop", "-root", "_NET_ACTIVE_WINDOW")
 	rootOutput, err := rootCmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active window from root: %w (output: %s)", err, string(rootOutput))
@@ -312,26 +178,84 @@ func (d *Detector) getFocusedWindowXWayland() (*window.WindowInfo, error) {
 		return nil, fmt.Errorf("no active window found (focused window may be native Wayland)")
 	}
 
-	nameCmd := exec.Command("xprop", "-id", windowID, "WM_NAME")
+	nameCmd := sandbox.Command("xprop", "-id", windowID, "WM_NAME")
 	nameOutput, _ := nameCmd.Output()
 	windowTitle := parseXPropString(string(nameOutput))
 	if windowTitle == "" {
 		windowTitle = "Unknown"
 	}
 
-	classCmd := exec.Command("xprop", "-id", windowID, "WM_CLASS")
+	classCmd := sandbox.Command("xprop", "-id", windowID, "WM_CLASS")
 	classOutput, _ := classCmd.Output()
 	appName := parseWMClass(string(classOutput))
 	if appName == "" {
 		appName = "Unknown"
 	}
 
-	return &window.WindowInfo{
+	info := &window.WindowInfo{
 		AppName:       appName,
 		WindowTitle:   windowTitle,
 		ProcessName:   appName,
 		DisplayServer: "wayland",
-	}, nil
+		Client:        window.ClientXWayland,
+	}
+
+	stateCmd := sandbox.Command("xprop", "-id", windowID, "_NET_WM_STATE")
+	if stateOutput, err := stateCmd.Output(); err == nil {
+		info.IsFullscreen, info.IsMaximized = parseNetWMState(string(stateOutput))
+	}
+
+	return info, nil
+}
+
+// gnomeClient maps Meta.WindowClientType, 0 for Wayland and 1 for X11.
+func gnomeClient(value string) string {
+	switch strings.TrimSpace(value) {
+	case "0":
+		return window.ClientNative
+	case "1":
+		return window.ClientXWayland
+	default:
+		return ""
+	}
+}
+
+// parseGeometryList parses "x,y,width,height" as produced by the GNOME script.
+func parseGeometryList(value string) window.Geometry {
+	fields := strings.Split(value, ",")
+	if len(fields) != 4 {
+		return window.Geometry{}
+	}
+	var n [4]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return window.Geometry{}
+		}
+		n[i] = v
+	}
+	return window.Geometry{X: n[0], Y: n[1], Width: n[2], Height: n[3]}
+}
+
+func parseNetWMState(output string) (fullscreen bool, maximized bool) {
+	parts := strings.SplitN(output, "=", 2)
+	if len(parts) < 2 {
+		return false, false
+	}
+
+	var vert, horz bool
+	for _, atom := range strings.Split(parts[1], ",") {
+		switch strings.TrimSpace(atom) {
+		case "_NET_WM_STATE_FULLSCREEN":
+			fullscreen = true
+		case "_NET_WM_STATE_MAXIMIZED_VERT":
+			vert = true
+		case "_NET_WM_STATE_MAXIMIZED_HORZ":
+			horz = true
+		}
+	}
+
+	return fullscreen, vert && horz
 }
 
 func parseXPropString(output string) string {
@@ -348,7 +272,7 @@ func parseXPropString(output string) string {
 
 func parseWMClass(output string) string {
 	if strings.Contains(output, "=") {
-		parts := strings.Split(output, "=")
+		parts := strings.SplitN(output, "=", 2)
 		if len(parts) >= 2 {
 			classInfo := strings.TrimSpace(parts[1])
 			classInfo = strings.Trim(classInfo, "\"")
@@ -362,42 +286,17 @@ func parseWMClass(output string) string {
 }
 
 func (d *Detector) getFocusedWindowKDE() (*window.WindowInfo, error) {
-	script := `
-	var clients = workspace.clientList();
-	for (var i = 0; i < clients.length; i++) {
-		if (clients[i].active) {
-			print(clients[i].resourceClass + "|" + clients[i].caption);
-		}
+	if d.kwin == nil {
+		return nil, fmt.Errorf("session bus not found for KWin scripting")
 	}
-	`
-
-	cmd := exec.Command("qdbus", "org.kde.KWin", "/Scripting", "org.kde.kwin.Scripting.loadScript", script)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to query KDE window: %w", err)
-	}
-
-	parts := strings.Split(strings.TrimSpace(string(output)), "|")
-	appName := "Unknown"
-	windowTitle := "Unknown"
-
-	if len(parts) >= 1 && parts[0] != "" {
-		appName = parts[0]
-	}
-	if len(parts) >= 2 && parts[1] != "" {
-		windowTitle = parts[1]
-	}
-
-	return &window.WindowInfo{
-		AppName:       appName,
-		WindowTitle:   windowTitle,
-		ProcessName:   appName,
-		DisplayServer: "wayland",
-	}, nil
+	return d.kwin.activeWindow()
 }
 
 func getProcessName(pid string) string {
-	cmd := exec.Command("ps", "-p", pid, "-o", "comm=")
+	if _, err := strconv.Atoi(pid); err != nil {
+		return ""
+	}
+	cmd := sandbox.Command("ps", "-p", pid, "-o", "comm=")
 	output, err := cmd.Output()
 	if err != nil {
 		return ""
@@ -419,19 +318,27 @@ func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
 	}, nil
 }
 
+// getIdleTime returns how long the user has been idle according to the
+// compositor's idle notifications or, where it offers none, logind's idle
+// hint. It is 0 when neither knows.
 func (d *Detector) getIdleTime() int64 {
-	switch d.compositor {
-	case "sway", "hyprland":
-		cmd := exec.Command("swaymsg", "-t", "get_idle_inhibitors")
-		if err := cmd.Run(); err == nil {
-			return 0
+	now := time.Now()
+	if d.idle != nil {
+		if seconds, ok := d.idle.idleSeconds(now); ok {
+			return seconds
 		}
 	}
-
+	if idle, since, err := d.session.IdleHint(); err == nil && idle && !since.IsZero() {
+		return int64(now.Sub(since).Seconds())
+	}
 	return 0
 }
 
 func (d *Detector) isScreenLocked() bool {
+	if d.session.Locked() {
+		return true
+	}
+
 	lockers := []string{
 		"swaylock",
 		"waylock",
@@ -441,22 +348,39 @@ func (d *Detector) isScreenLocked() bool {
 	}
 
 	for _, locker := range lockers {
-		cmd := exec.Command("pgrep", "-x", locker)
+		cmd := sandbox.Command("pgrep", "-x", locker)
 		if err := cmd.Run(); err == nil {
 			return true
 		}
 	}
 
-	cmd := exec.Command("gdbus", "call", "--session", "--dest", "org.gnome.ScreenSaver", "--object-path", "/org/gnome/ScreenSaver", "--method", "org.gnome.ScreenSaver.GetActive")
-	if output, err := cmd.Output(); err == nil {
-		if strings.Contains(string(output), "true") {
-			return true
-		}
-	}
-
 	return false
 }
 
+// Limitations reports that native Wayland windows go unseen while GNOME can
+// only be queried through XWayland.
+func (d *Detector) Limitations() []string {
+	if d.xwaylandOnly.Load() {
+		return []string{"GNOME Shell.Eval is blocked, so only XWayland windows are seen (via xprop) and native Wayland apps are missed; run \"actionsum gnome-extension install\" to fix this"}
+	}
+	return nil
+}
+
 func (d *Detector) Close() error {
+	if d.idle != nil {
+		d.idle.Close()
+	}
+	if d.sway != nil {
+		d.sway.Close()
+	}
+	if d.hyprland != nil {
+		d.hyprland.Close()
+	}
+	if d.kwin != nil {
+		d.kwin.Close()
+	}
+	if d.gnome != nil {
+		d.gnome.Close()
+	}
 	return nil
 }
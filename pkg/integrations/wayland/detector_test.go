@@ -41,8 +41,8 @@ func TestDetectCompositor(t *testing.T) {
 	}
 
 	t.Logf("Compositor: %s", detector.compositor)
-	t.Logf("Has swaymsg: %v", detector.hasSwaymsg)
-	t.Logf("Has gdbus: %v", detector.hasGdbus)
+	t.Logf("Has sway IPC: %v", detector.sway != nil)
+	t.Logf("Has GNOME Shell client: %v", detector.gnome != nil)
 }
 
 func TestIsAvailable(t *testing.T) {
@@ -53,12 +53,14 @@ func TestIsAvailable(t *testing.T) {
 	t.Logf("Compositor: %s", detector.compositor)
 
 	switch detector.compositor {
-	case "sway", "hyprland":
-		t.Logf("Sway/Hyprland requires swaymsg: %v", detector.hasSwaymsg)
+	case "sway":
+		t.Logf("Sway requires its IPC socket: %v", detector.sway != nil)
+	case "hyprland":
+		t.Logf("Hyprland requires its IPC socket: %v", detector.hyprland != nil)
 	case "gnome":
-		t.Logf("GNOME requires gdbus: %v", detector.hasGdbus)
+		t.Logf("GNOME requires the session bus: %v", detector.gnome != nil)
 	case "kde":
-		t.Log("KDE should be available")
+		t.Logf("KDE requires the session bus: %v", detector.kwin != nil)
 	default:
 		t.Logf("Unknown compositor: %s", detector.compositor)
 	}
@@ -160,6 +162,28 @@ func TestParseSwayTree(t *testing.T) {
 	}
 }
 
+func TestParseSwayTreeXWayland(t *testing.T) {
+	sampleJSON := `{
+		"focused": true,
+		"name": "Steam",
+		"pid": 1234,
+		"app_id": null,
+		"shell": "xwayland",
+		"window_properties": {
+			"class": "Steam"
+		}
+	}`
+
+	windowInfo, err := parseSwayTree(sampleJSON)
+	if err != nil {
+		t.Fatalf("parseSwayTree() error: %v", err)
+	}
+
+	if windowInfo.AppName != "Steam" {
+		t.Errorf("AppName = %s, want Steam", windowInfo.AppName)
+	}
+}
+
 func TestParseHyprlandWindow(t *testing.T) {
 	sampleJSON := `{
 		"class": "kitty",
@@ -178,6 +202,195 @@ func TestParseHyprlandWindow(t *testing.T) {
 	}
 }
 
+func TestParseSwayTreeGeometry(t *testing.T) {
+	sampleJSON := `{
+		"id": 42,
+		"type": "con",
+		"focused": true,
+		"rect": {
+			"x": 0,
+			"y": 30,
+			"width": 2560,
+			"height": 1410
+		},
+		"window_rect": {
+			"x": 2,
+			"y": 2,
+			"width": 2556,
+			"height": 1406
+		},
+		"name": "Video - mpv",
+		"fullscreen_mode": 1,
+		"pid": 4321,
+		"app_id": "mpv"
+	}`
+
+	windowInfo, err := parseSwayTree(sampleJSON)
+	if err != nil {
+		t.Fatalf("parseSwayTree() error: %v", err)
+	}
+
+	want := window.Geometry{X: 0, Y: 30, Width: 2560, Height: 1410}
+	if windowInfo.Geometry != want {
+		t.Errorf("Geometry = %+v, want %+v", windowInfo.Geometry, want)
+	}
+	if !windowInfo.IsFullscreen {
+		t.Error("IsFullscreen = false, want true")
+	}
+}
+
+func TestParseSwayShell(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "native",
+			json: `{
+				"focused": true,
+				"pid": 1234,
+				"app_id": "foot",
+				"shell": "xdg_shell"
+			}`,
+			want: window.ClientNative,
+		},
+		{
+			name: "xwayland",
+			json: `{
+				"focused": true,
+				"pid": 1234,
+				"app_id": null,
+				"shell": "xwayland",
+				"window_properties": {
+					"class": "Steam"
+				}
+			}`,
+			want: window.ClientXWayland,
+		},
+		{
+			name: "shell of a later node",
+			json: `{
+				"focused": true,
+				"app_id": "foot"
+			},
+			{
+				"focused": false,
+				"shell": "xwayland"
+			}`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := swayClient(parseSwayShell(tt.json)); got != tt.want {
+				t.Errorf("client = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHyprlandClient(t *testing.T) {
+	xwayland := parseHyprlandWindow(`{
+		"class": "steam",
+		"xwayland": true,
+		"pid": 5678
+	}`)
+	if xwayland.Client != window.ClientXWayland {
+		t.Errorf("Client = %q, want %q", xwayland.Client, window.ClientXWayland)
+	}
+
+	native := parseHyprlandWindow(`{
+		"class": "kitty",
+		"xwayland": false,
+		"pid": 5678
+	}`)
+	if native.Client != window.ClientNative {
+		t.Errorf("Client = %q, want %q", native.Client, window.ClientNative)
+	}
+}
+
+func TestGnomeClient(t *testing.T) {
+	for value, want := range map[string]string{"0": window.ClientNative, "1": window.ClientXWayland, "": ""} {
+		if got := gnomeClient(value); got != want {
+			t.Errorf("gnomeClient(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestLimitations(t *testing.T) {
+	var _ window.CoverageReporter = (*Detector)(nil)
+
+	d := &Detector{compositor: "gnome"}
+	if got := d.Limitations(); len(got) != 0 {
+		t.Errorf("Limitations() = %v, want none", got)
+	}
+	d.xwaylandOnly.Store(true)
+	if got := d.Limitations(); len(got) != 1 {
+		t.Errorf("Limitations() = %v, want the XWayland-only warning", got)
+	}
+}
+
+func TestParseHyprlandFullscreen(t *testing.T) {
+	tests := []struct {
+		name           string
+		json           string
+		wantFullscreen bool
+		wantMaximized  bool
+	}{
+		{
+			name:           "Numeric fullscreen",
+			json:           "{\n\"class\": \"mpv\",\n\"fullscreen\": 2,\n}",
+			wantFullscreen: true,
+		},
+		{
+			name:          "Numeric maximized",
+			json:          "{\n\"class\": \"kitty\",\n\"fullscreen\": 1,\n}",
+			wantMaximized: true,
+		},
+		{
+			name:           "Legacy boolean fullscreen",
+			json:           "{\n\"class\": \"mpv\",\n\"fullscreen\": true,\n\"fullscreenMode\": 0,\n}",
+			wantFullscreen: true,
+		},
+		{
+			name:          "Legacy boolean maximized",
+			json:          "{\n\"class\": \"kitty\",\n\"fullscreen\": true,\n\"fullscreenMode\": 1,\n}",
+			wantMaximized: true,
+		},
+		{
+			name: "Windowed",
+			json: "{\n\"class\": \"kitty\",\n\"fullscreen\": false,\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windowInfo := parseHyprlandWindow(tt.json)
+			if windowInfo.IsFullscreen != tt.wantFullscreen || windowInfo.IsMaximized != tt.wantMaximized {
+				t.Errorf("fullscreen/maximized = (%v, %v), want (%v, %v)",
+					windowInfo.IsFullscreen, windowInfo.IsMaximized, tt.wantFullscreen, tt.wantMaximized)
+			}
+		})
+	}
+}
+
+func TestParseHyprlandGeometry(t *testing.T) {
+	sampleJSON := `{
+		"class": "kitty",
+		"at": [1920, 45],
+		"size": [1280, 720],
+		"pid": 5678
+	}`
+
+	windowInfo := parseHyprlandWindow(sampleJSON)
+	want := window.Geometry{X: 1920, Y: 45, Width: 1280, Height: 720}
+	if windowInfo.Geometry != want {
+		t.Errorf("Geometry = %+v, want %+v", windowInfo.Geometry, want)
+	}
+}
+
 func TestParseWMClass(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,144 @@
+package wayland
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// jsonString encodes s the way sway and Hyprland print strings, and returns
+// what decoding it gives back: invalid UTF-8 is replaced on the way.
+func jsonString(t *testing.T, s string) (encoded, decoded string) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal(%q) error = %v", s, err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", data, err)
+	}
+	return string(data), decoded
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+var fuzzTitles = []string{
+	"Mozilla Firefox",
+	`He said "hi" - Gmail`,
+	`C:\Users\me\notes.txt`,
+	"first line\nsecond line",
+	"Ünïcödé — 日本語 🚀",
+	`"focused": true,`,
+	"trailing comma,",
+	"",
+}
+
+func FuzzParseSwayTree(f *testing.F) {
+	for _, title := range fuzzTitles {
+		f.Add("firefox", title)
+	}
+	f.Add("", "xwayland window")
+	f.Fuzz(func(t *testing.T, app, title string) {
+		appJSON, wantApp := jsonString(t, app)
+		titleJSON, wantTitle := jsonString(t, title)
+		tree := fmt.Sprintf("{\n  \"focused\": true,\n  \"name\": %s,\n  \"app_id\": %s,\n  \"shell\": \"xdg_shell\"\n}", titleJSON, appJSON)
+
+		info, err := parseSwayTree(tree)
+		if err != nil {
+			t.Fatalf("parseSwayTree() error = %v", err)
+		}
+		if info.AppName != orUnknown(wantApp) {
+			t.Errorf("AppName = %q, want %q", info.AppName, orUnknown(wantApp))
+		}
+		if info.WindowTitle != orUnknown(wantTitle) {
+			t.Errorf("WindowTitle = %q, want %q", info.WindowTitle, orUnknown(wantTitle))
+		}
+
+		// Arbitrary output must not panic.
+		parseSwayTree(title)
+		parseSwayShell(title)
+	})
+}
+
+func FuzzParseHyprlandWindow(f *testing.F) {
+	for _, title := range fuzzTitles {
+		f.Add("kitty", title)
+	}
+	f.Fuzz(func(t *testing.T, class, title string) {
+		classJSON, wantClass := jsonString(t, class)
+		titleJSON, wantTitle := jsonString(t, title)
+		output := fmt.Sprintf("{\n    \"class\": %s,\n    \"title\": %s,\n    \"xwayland\": false\n}", classJSON, titleJSON)
+
+		info := parseHyprlandWindow(output)
+		if info.AppName != orUnknown(wantClass) {
+			t.Errorf("AppName = %q, want %q", info.AppName, orUnknown(wantClass))
+		}
+		if info.WindowTitle != orUnknown(wantTitle) {
+			t.Errorf("WindowTitle = %q, want %q", info.WindowTitle, orUnknown(wantTitle))
+		}
+
+		parseHyprlandWindow(title)
+	})
+}
+
+func FuzzParseGnomeEval(f *testing.F) {
+	for _, title := range fuzzTitles {
+		f.Add("firefox", title)
+	}
+	f.Add("it's", "Bob's notes")
+	f.Fuzz(func(t *testing.T, class, title string) {
+		// Eval returns JSON.stringify of the script's result.
+		result, _ := json.Marshal(class + "|||" + title + "|||false|||true|||0,0,1920,1080|||0")
+		info := parseGnomeEval(string(result))
+
+		_, wantClass := jsonString(t, class)
+		_, wantTitle := jsonString(t, title)
+		if strings.Contains(class+title, "|") || wantClass == "" || wantClass == "Unknown" {
+			parseGnomeEval(title)
+			return
+		}
+		if info == nil {
+			t.Fatalf("parseGnomeEval() = nil for class %q", class)
+		}
+		if info.AppName != wantClass || info.WindowTitle != orUnknown(wantTitle) {
+			t.Errorf("window = %q %q, want %q %q", info.AppName, info.WindowTitle, wantClass, orUnknown(wantTitle))
+		}
+		if !info.IsMaximized || info.Geometry.Width != 1920 {
+			t.Errorf("state = %+v, want maximized 1920 wide", info)
+		}
+
+		parseGnomeEval(title)
+	})
+}
+
+func FuzzParseWMClass(f *testing.F) {
+	f.Add("navigator", "Firefox")
+	f.Add("a=b", "Key=Value")
+	f.Add("", "")
+	f.Fuzz(func(t *testing.T, instance, class string) {
+		got := parseWMClass(fmt.Sprintf("WM_CLASS(STRING) = %q, %q", instance, class))
+
+		// xprop quotes like %q; values it would escape, or that contain the
+		// separator, aren't expected back verbatim.
+		if !strings.Contains(class, ",") && class == strings.Trim(class, " ") && isPrintable(class) {
+			if got != class {
+				t.Errorf("parseWMClass() = %q, want %q", got, class)
+			}
+		}
+
+		// Arbitrary output must not panic.
+		parseWMClass(class)
+		parseXPropString(class)
+		parseNetWMState(class)
+	})
+}
+
+// isPrintable reports whether %q leaves s unescaped.
+func isPrintable(s string) bool {
+	return fmt.Sprintf("%q", s) == `"`+s+`"`
+}
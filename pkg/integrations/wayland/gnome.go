@@ -0,0 +1,230 @@
+package wayland
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/window"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	// gnomeShellTimeout bounds each call to GNOME Shell.
+	gnomeShellTimeout = 2 * time.Second
+
+	// gnomeExtensionRetry is how long to wait before asking again after
+	// finding the extension missing.
+	gnomeExtensionRetry = time.Minute
+)
+
+// gnomeShell asks GNOME Shell for the focused window over the session bus:
+// actionsum's GNOME Shell extension, which exports it from inside the shell,
+// or else Shell.Eval, which GNOME 41 and later only allow in unsafe mode.
+type gnomeShell struct {
+	address string
+
+	mu  sync.Mutex
+	bus *dbus.Conn
+
+	// retryAfter is when to ask again after finding the extension missing.
+	retryAfter time.Time
+}
+
+func newGnomeShell(address string) *gnomeShell {
+	return &gnomeShell{address: address}
+}
+
+// call calls method on the shell's object at path, connecting first if
+// needed, and stores the reply in results. A failed exchange other than an
+// error reply closes the connection. g.mu must be held.
+func (g *gnomeShell) call(path, method string, args []any, results ...any) error {
+	if g.bus == nil {
+		bus, err := dbus.Dial(g.address, gnomeShellTimeout)
+		if err != nil {
+			return err
+		}
+		g.bus = bus
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gnomeShellTimeout)
+	defer cancel()
+	err := g.bus.Object("org.gnome.Shell", godbus.ObjectPath(path)).
+		CallWithContext(ctx, method, 0, args...).
+		Store(results...)
+	if err != nil && !dbus.IsErrorReply(err) {
+		g.bus.Close()
+		g.bus = nil
+	}
+	return err
+}
+
+// extensionWindow returns the focused window, or an error when the
+// extension isn't installed and enabled.
+func (g *gnomeShell) extensionWindow() (*window.WindowInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if time.Now().Before(g.retryAfter) {
+		return nil, fmt.Errorf("actionsum GNOME Shell extension not running")
+	}
+	var answer string
+	err := g.call("/io/github/actionsum/FocusedWindow", "io.github.actionsum.FocusedWindow.Get", nil, &answer)
+	if err != nil {
+		// The extension may be installed or enabled meanwhile; until then,
+		// neither a missing object nor a missing bus is worth asking again
+		// about on every poll.
+		if dbus.IsErrorReply(err) || g.bus == nil {
+			g.retryAfter = time.Now().Add(gnomeExtensionRetry)
+		}
+		return nil, err
+	}
+	return parseGnomeExtensionWindow(answer)
+}
+
+// evalWindow returns the focused window found by running gnomeEvalScript in
+// the shell.
+func (g *gnomeShell) evalWindow() (*window.WindowInfo, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var ok bool
+	var result string
+	if err := g.call("/org/gnome/Shell", "org.gnome.Shell.Eval", []any{gnomeEvalScript}, &ok, &result); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("Shell.Eval refused: %s", result)
+	}
+	info := parseGnomeEval(result)
+	if info == nil {
+		return nil, fmt.Errorf("no focused window")
+	}
+	return info, nil
+}
+
+func (g *gnomeShell) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bus != nil {
+		g.bus.Close()
+		g.bus = nil
+	}
+}
+
+// gnomeExtensionWindow is what the extension reports about the focused
+// window. clientType is Meta.WindowClientType: 0 Wayland, 1 X11.
+type gnomeExtensionWindow struct {
+	WMClass    string `json:"wmClass"`
+	Title      string `json:"title"`
+	PID        int    `json:"pid"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Fullscreen bool   `json:"fullscreen"`
+	Maximized  bool   `json:"maximized"`
+	ClientType *int   `json:"clientType"`
+}
+
+// parseGnomeExtensionWindow decodes the extension's answer. With nothing
+// focused it gives an Unknown window.
+func parseGnomeExtensionWindow(answer string) (*window.WindowInfo, error) {
+	var w gnomeExtensionWindow
+	if err := json.Unmarshal([]byte(answer), &w); err != nil {
+		return nil, fmt.Errorf("failed to parse GNOME extension window: %w", err)
+	}
+
+	appName := w.WMClass
+	if appName == "" {
+		appName = "Unknown"
+	}
+	windowTitle := w.Title
+	if windowTitle == "" {
+		windowTitle = "Unknown"
+	}
+	processName := appName
+	if w.PID > 0 {
+		if name := getProcessName(strconv.Itoa(w.PID)); name != "" {
+			processName = name
+		}
+	}
+	var client string
+	if w.ClientType != nil {
+		client = gnomeClient(strconv.Itoa(*w.ClientType))
+	}
+
+	return &window.WindowInfo{
+		AppName:       appName,
+		WindowTitle:   windowTitle,
+		ProcessName:   processName,
+		PID:           w.PID,
+		DisplayServer: "wayland",
+		Geometry:      window.Geometry{X: w.X, Y: w.Y, Width: w.Width, Height: w.Height},
+		IsFullscreen:  w.Fullscreen,
+		IsMaximized:   w.Maximized,
+		Client:        client,
+	}, nil
+}
+
+// gnomeEvalScript finds the focused window for Shell.Eval, as
+// "class|||title|||fullscreen|||maximized|||x,y,width,height|||client type".
+const gnomeEvalScript = `
+try {
+	let win = global.get_window_actors().find(w => w.meta_window && w.meta_window.has_focus());
+	if (win && win.meta_window) {
+		let mw = win.meta_window;
+		let wm_class = mw.get_wm_class() || 'Unknown';
+		let title = mw.get_title() || 'Unknown';
+		let rect = mw.get_frame_rect();
+		let maximized = mw.get_maximized() === 3;
+		wm_class + '|||' + title + '|||' + mw.is_fullscreen() + '|||' + maximized +
+			'|||' + [rect.x, rect.y, rect.width, rect.height].join(',') +
+			'|||' + mw.get_client_type();
+	} else {
+		'Unknown|||Unknown';
+	}
+} catch(e) {
+	'Unknown|||Unknown';
+}
+`
+
+// parseGnomeEval reads Shell.Eval's result for gnomeEvalScript, the
+// JSON-encoded string such as
+// "firefox|||Title|||false|||true|||0,0,1920,1080|||0". It returns nil when
+// there is no focused window.
+func parseGnomeEval(result string) *window.WindowInfo {
+	var decoded string
+	if err := json.Unmarshal([]byte(result), &decoded); err == nil {
+		result = decoded
+	}
+
+	parts := strings.Split(result, "|||")
+	if parts[0] == "" || parts[0] == "Unknown" {
+		return nil
+	}
+	info := &window.WindowInfo{
+		AppName:       parts[0],
+		WindowTitle:   "Unknown",
+		ProcessName:   parts[0],
+		DisplayServer: "wayland",
+	}
+	if len(parts) >= 2 && parts[1] != "" {
+		info.WindowTitle = parts[1]
+	}
+	if len(parts) >= 5 {
+		info.IsFullscreen = parts[2] == "true"
+		info.IsMaximized = parts[3] == "true"
+		info.Geometry = parseGeometryList(parts[4])
+	}
+	if len(parts) >= 6 {
+		info.Client = gnomeClient(parts[5])
+	}
+	return info
+}
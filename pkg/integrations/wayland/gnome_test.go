@@ -0,0 +1,98 @@
+package wayland
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/actionsum/actionsum/pkg/dbus/dbustest"
+	"github.com/actionsum/actionsum/pkg/window"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+func TestGnomeExtension(t *testing.T) {
+	var installed atomic.Bool
+	var calls atomic.Int32
+	address := dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		if msg.Member() != "Get" || msg.Path() != "/io/github/actionsum/FocusedWindow" {
+			return
+		}
+		calls.Add(1)
+		if !installed.Load() {
+			send(dbustest.ErrorReply(msg, "org.freedesktop.DBus.Error.UnknownObject", "No such object"))
+			return
+		}
+		send(dbustest.Reply(msg, `{"wmClass": "org.gnome.Nautilus", "title": "Home", "pid": 0, "x": 0, "y": 32, "width": 1920, "height": 1048, "fullscreen": false, "maximized": true, "clientType": 0}`))
+	})
+
+	g := newGnomeShell(address)
+	defer g.Close()
+
+	for range 2 {
+		if _, err := g.extensionWindow(); err == nil {
+			t.Fatal("extensionWindow() succeeded without the extension")
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls without the extension = %d, want 1 until the retry interval passes", got)
+	}
+
+	installed.Store(true)
+	g.retryAfter = g.retryAfter.Add(-gnomeExtensionRetry)
+	info, err := g.extensionWindow()
+	if err != nil {
+		t.Fatalf("extensionWindow() error = %v", err)
+	}
+	if info.AppName != "org.gnome.Nautilus" || info.WindowTitle != "Home" || !info.IsMaximized {
+		t.Errorf("window = %+v", info)
+	}
+	if info.Client != window.ClientNative || info.Geometry.Y != 32 {
+		t.Errorf("Client = %q, Geometry = %+v", info.Client, info.Geometry)
+	}
+}
+
+func TestGnomeShellEval(t *testing.T) {
+	var unsafe atomic.Bool
+	address := dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		if msg.Member() != "Eval" || msg.Path() != "/org/gnome/Shell" {
+			return
+		}
+		if !unsafe.Load() {
+			send(dbustest.Reply(msg, false, ""))
+			return
+		}
+		if !strings.Contains(msg.StringArg(0), "has_focus()") {
+			t.Errorf("Eval script = %q", msg.StringArg(0))
+		}
+		send(dbustest.Reply(msg, true, `"gedit|||notes.txt|||false|||true|||0,32,1920,1048|||1"`))
+	})
+
+	g := newGnomeShell(address)
+	defer g.Close()
+
+	if _, err := g.evalWindow(); err == nil {
+		t.Fatal("evalWindow() succeeded while GNOME refuses Eval")
+	}
+	unsafe.Store(true)
+	info, err := g.evalWindow()
+	if err != nil {
+		t.Fatalf("evalWindow() error = %v", err)
+	}
+	if info.AppName != "gedit" || info.WindowTitle != "notes.txt" || !info.IsMaximized || info.Geometry.Height != 1048 {
+		t.Errorf("window = %+v", info)
+	}
+	if info.Client != window.ClientXWayland {
+		t.Errorf("Client = %q, want XWayland", info.Client)
+	}
+}
+
+func TestParseGnomeExtensionWindowEmpty(t *testing.T) {
+	info, err := parseGnomeExtensionWindow("{}")
+	if err != nil {
+		t.Fatalf("parseGnomeExtensionWindow() error = %v", err)
+	}
+	if info.AppName != "Unknown" || info.Client != "" {
+		t.Errorf("window = %+v, want Unknown with no client type", info)
+	}
+}
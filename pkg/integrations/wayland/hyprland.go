@@ -0,0 +1,381 @@
+package wayland
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const (
+	// hyprlandTimeout bounds each request to Hyprland.
+	hyprlandTimeout = 2 * time.Second
+
+	// hyprlandCacheTTL is how long the active window is reused between
+	// events, as resizing a window sends none.
+	hyprlandCacheTTL = time.Minute
+
+	// ipcRetry is how long to wait before reconnecting to a compositor's
+	// event socket after losing it.
+	ipcRetry = 5 * time.Second
+)
+
+// hyprlandInvalidating are the events after which the active window, or
+// what is known about it, may have changed.
+var hyprlandInvalidating = map[string]bool{
+	"activewindow":       true,
+	"activewindowv2":     true,
+	"windowtitle":        true,
+	"windowtitlev2":      true,
+	"fullscreen":         true,
+	"closewindow":        true,
+	"movewindow":         true,
+	"movewindowv2":       true,
+	"changefloatingmode": true,
+	"workspace":          true,
+	"workspacev2":        true,
+	"focusedmon":         true,
+	"focusedmonv2":       true,
+	"monitorremoved":     true,
+	"configreloaded":     true,
+}
+
+// hyprlandIPC talks to Hyprland over its UNIX sockets instead of running
+// hyprctl on every poll. Requests go to .socket.sock, one connection each as
+// Hyprland closes it after answering. A persistent connection to
+// .socket2.sock follows Hyprland's events, so polls between focus changes
+// reuse the last answer.
+type hyprlandIPC struct {
+	dir string
+
+	mu       sync.Mutex
+	cached   *window.WindowInfo
+	cachedAt time.Time
+	listener net.Conn
+	closed   bool
+	done     chan struct{}
+}
+
+// hyprlandSocketDir finds the directory of the running Hyprland instance's
+// sockets: under $XDG_RUNTIME_DIR/hypr since Hyprland 0.40, in /tmp/hypr
+// before.
+func hyprlandSocketDir() (string, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" || strings.ContainsAny(signature, `/\`) {
+		return "", fmt.Errorf("HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+
+	var candidates []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, filepath.Join(runtimeDir, "hypr", signature))
+	}
+	candidates = append(candidates, filepath.Join("/tmp/hypr", signature))
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, ".socket.sock")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no Hyprland socket for instance %s", signature)
+}
+
+func newHyprlandIPC(dir string) *hyprlandIPC {
+	c := &hyprlandIPC{dir: dir, done: make(chan struct{})}
+	go c.listen()
+	return c
+}
+
+// request sends one command to Hyprland and returns its whole answer.
+func (c *hyprlandIPC) request(command string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", filepath.Join(c.dir, ".socket.sock"), hyprlandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Hyprland: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(hyprlandTimeout))
+
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return nil, fmt.Errorf("failed to send %q to Hyprland: %w", command, err)
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Hyprland's answer to %q: %w", command, err)
+	}
+	return reply, nil
+}
+
+// activeWindow returns the focused window, from the last answer while no
+// event has said it changed.
+func (c *hyprlandIPC) activeWindow() (*window.WindowInfo, error) {
+	c.mu.Lock()
+	if c.cached != nil && time.Since(c.cachedAt) < hyprlandCacheTTL {
+		info := *c.cached
+		c.mu.Unlock()
+		return &info, nil
+	}
+	c.mu.Unlock()
+
+	reply, err := c.request("j/activewindow")
+	if err != nil {
+		return nil, err
+	}
+	info := parseHyprlandWindow(string(reply))
+	info.DisplayServer = "wayland"
+	if id, ok := parseHyprlandMonitorID(string(reply)); ok {
+		// Monitors are only named in their own list; a window is still
+		// recorded without its monitor should that request fail.
+		if monitors, err := c.request("j/monitors"); err == nil {
+			info.Output = parseHyprlandMonitors(string(monitors))[id]
+		}
+	}
+
+	c.mu.Lock()
+	// Only cache while events can say when the answer goes stale.
+	if c.listener != nil {
+		cached := *info
+		c.cached, c.cachedAt = &cached, time.Now()
+	}
+	c.mu.Unlock()
+	return info, nil
+}
+
+func (c *hyprlandIPC) invalidate() {
+	c.mu.Lock()
+	c.cached = nil
+	c.mu.Unlock()
+}
+
+// listen follows Hyprland's event socket until Close, reconnecting when the
+// connection drops.
+func (c *hyprlandIPC) listen() {
+	for {
+		conn, err := net.DialTimeout("unix", filepath.Join(c.dir, ".socket2.sock"), hyprlandTimeout)
+		if err == nil {
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				conn.Close()
+				return
+			}
+			c.listener = conn
+			c.mu.Unlock()
+
+			c.readEvents(conn)
+
+			c.mu.Lock()
+			c.listener = nil
+			c.cached = nil
+			c.mu.Unlock()
+			conn.Close()
+		}
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(ipcRetry):
+		}
+	}
+}
+
+// readEvents drops the cached window on each event that may change it.
+// Events are "name>>data" lines.
+func (c *hyprlandIPC) readEvents(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		name, _, _ := strings.Cut(scanner.Text(), ">>")
+		if hyprlandInvalidating[name] {
+			c.invalidate()
+		}
+	}
+}
+
+func (c *hyprlandIPC) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	if c.listener != nil {
+		c.listener.Close()
+	}
+}
+
+func (d *Detector) getFocusedWindowHyprland() (*window.WindowInfo, error) {
+	if d.hyprland == nil {
+		return nil, fmt.Errorf("Hyprland IPC socket not found")
+	}
+	return d.hyprland.activeWindow()
+}
+
+// hyprlandWindow is the part of Hyprland's j/activewindow answer used here.
+// "fullscreen" was a boolean, with "fullscreenMode" telling fullscreen from
+// maximized, before it became a number.
+type hyprlandWindow struct {
+	Class          string          `json:"class"`
+	Title          string          `json:"title"`
+	PID            int             `json:"pid"`
+	At             []int           `json:"at"`
+	Size           []int           `json:"size"`
+	Fullscreen     json.RawMessage `json:"fullscreen"`
+	FullscreenMode *int            `json:"fullscreenMode"`
+	XWayland       *bool           `json:"xwayland"`
+	Monitor        *int            `json:"monitor"`
+}
+
+// parseHyprlandWindow decodes Hyprland's description of the active window.
+// An empty or unreadable answer, as when no window has focus, gives an
+// Unknown window.
+func parseHyprlandWindow(jsonOutput string) *window.WindowInfo {
+	var w hyprlandWindow
+	if err := json.Unmarshal(trimTrailingCommas([]byte(jsonOutput)), &w); err != nil {
+		w = hyprlandWindow{}
+	}
+
+	appName := w.Class
+	if appName == "" {
+		appName = "Unknown"
+	}
+	windowTitle := w.Title
+	if windowTitle == "" {
+		windowTitle = "Unknown"
+	}
+
+	processName := appName
+	if w.PID > 0 {
+		if name := getProcessName(strconv.Itoa(w.PID)); name != "" {
+			processName = name
+		}
+	}
+
+	var geometry window.Geometry
+	if len(w.At) == 2 {
+		geometry.X, geometry.Y = w.At[0], w.At[1]
+	}
+	if len(w.Size) == 2 {
+		geometry.Width, geometry.Height = w.Size[0], w.Size[1]
+	}
+
+	fullscreenMode := -1
+	if w.FullscreenMode != nil {
+		fullscreenMode = *w.FullscreenMode
+	}
+	fullscreen, maximized := hyprlandFullscreenState(string(w.Fullscreen), fullscreenMode)
+
+	var client string
+	if w.XWayland != nil {
+		client = window.ClientNative
+		if *w.XWayland {
+			client = window.ClientXWayland
+		}
+	}
+
+	return &window.WindowInfo{
+		AppName:      appName,
+		WindowTitle:  windowTitle,
+		ProcessName:  processName,
+		PID:          w.PID,
+		Geometry:     geometry,
+		IsFullscreen: fullscreen,
+		IsMaximized:  maximized,
+		Client:       client,
+	}
+}
+
+// parseHyprlandMonitorID returns the ID of the monitor the active window
+// is on, which j/monitors maps to a name.
+func parseHyprlandMonitorID(jsonOutput string) (int, bool) {
+	var w hyprlandWindow
+	if err := json.Unmarshal(trimTrailingCommas([]byte(jsonOutput)), &w); err != nil || w.Monitor == nil {
+		return 0, false
+	}
+	return *w.Monitor, true
+}
+
+// parseHyprlandMonitors maps monitor IDs to names, e.g. "DP-1", from
+// Hyprland's j/monitors answer.
+func parseHyprlandMonitors(jsonOutput string) map[int]string {
+	var monitors []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(trimTrailingCommas([]byte(jsonOutput)), &monitors); err != nil {
+		return nil
+	}
+	names := make(map[int]string, len(monitors))
+	for _, m := range monitors {
+		names[m.ID] = m.Name
+	}
+	return names
+}
+
+// trimTrailingCommas drops commas directly before a closing brace or
+// bracket, outside strings, which older Hyprland releases left in their
+// hand-written JSON.
+func trimTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	pendingComma := -1
+	for _, b := range data {
+		if inString {
+			out = append(out, b)
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			out = append(out, b)
+			continue
+		case '}', ']':
+			if pendingComma >= 0 {
+				out[pendingComma] = ' '
+			}
+		case '"':
+			inString = true
+		}
+		pendingComma = -1
+		if b == ',' {
+			pendingComma = len(out)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// hyprlandFullscreenState interprets both the legacy boolean "fullscreen" plus
+// "fullscreenMode" (0 fullscreen, 1 maximized) and the newer numeric state
+// (1 maximized, 2 fullscreen, 3 both).
+func hyprlandFullscreenState(value string, mode int) (fullscreen bool, maximized bool) {
+	switch value {
+	case "true":
+		if mode == 1 {
+			return false, true
+		}
+		return true, false
+	case "", "false", "null":
+		return false, false
+	}
+
+	state, err := strconv.Atoi(value)
+	if err != nil {
+		return false, false
+	}
+	return state&2 != 0, state&1 != 0
+}
@@ -0,0 +1,169 @@
+package wayland
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeHyprland serves j/activewindow on .socket.sock and returns the event
+// socket's connection once a client subscribes.
+func fakeHyprland(t *testing.T, reply string, requests *atomic.Int32) (dir string, events <-chan net.Conn) {
+	t.Helper()
+	dir = t.TempDir()
+
+	requestSocket, err := net.Listen("unix", filepath.Join(dir, ".socket.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	eventSocket, err := net.Listen("unix", filepath.Join(dir, ".socket2.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() {
+		requestSocket.Close()
+		eventSocket.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := requestSocket.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 64)
+			n, _ := conn.Read(buf)
+			switch string(buf[:n]) {
+			case "j/activewindow":
+				requests.Add(1)
+				io.WriteString(conn, reply)
+			case "j/monitors":
+				io.WriteString(conn, `[{"id": 0, "name": "eDP-1"}, {"id": 1, "name": "DP-3"}]`)
+			}
+			conn.Close()
+		}
+	}()
+
+	subscribed := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := eventSocket.Accept(); err == nil {
+			subscribed <- conn
+		}
+	}()
+	return dir, subscribed
+}
+
+func TestHyprlandIPC(t *testing.T) {
+	var requests atomic.Int32
+	dir, events := fakeHyprland(t, `{"class": "kitty", "title": "vim \"notes.md\"", "pid": 0, "xwayland": false, "monitor": 1}`, &requests)
+
+	c := newHyprlandIPC(dir)
+	defer c.Close()
+
+	var events2 net.Conn
+	select {
+	case events2 = <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not subscribe to events")
+	}
+
+	// Give listen() a moment to record the subscription before caching.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		subscribed := c.listener != nil
+		c.mu.Unlock()
+		if subscribed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	info, err := c.activeWindow()
+	if err != nil {
+		t.Fatalf("activeWindow() error = %v", err)
+	}
+	if info.AppName != "kitty" || info.WindowTitle != `vim "notes.md"` || info.DisplayServer != "wayland" || info.Output != "DP-3" {
+		t.Errorf("activeWindow() = %+v", info)
+	}
+
+	if _, err := c.activeWindow(); err != nil {
+		t.Fatalf("activeWindow() error = %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests before an event = %d, want 1", got)
+	}
+
+	io.WriteString(events2, "openlayer>>rofi\nactivewindow>>firefox,Mozilla Firefox\n")
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		invalidated := c.cached == nil
+		c.mu.Unlock()
+		if invalidated || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := c.activeWindow(); err != nil {
+		t.Fatalf("activeWindow() error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests after activewindow event = %d, want 2", got)
+	}
+}
+
+func TestHyprlandIPCUnavailable(t *testing.T) {
+	c := newHyprlandIPC(t.TempDir())
+	defer c.Close()
+	if _, err := c.activeWindow(); err == nil {
+		t.Error("activeWindow() without a socket succeeded")
+	}
+}
+
+func TestHyprlandSocketDir(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "abc_123")
+
+	if _, err := hyprlandSocketDir(); err == nil {
+		t.Error("hyprlandSocketDir() found a socket that doesn't exist")
+	}
+
+	want := filepath.Join(runtimeDir, "hypr", "abc_123")
+	if err := os.MkdirAll(want, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", filepath.Join(want, ".socket.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	if dir, err := hyprlandSocketDir(); err != nil || dir != want {
+		t.Errorf("hyprlandSocketDir() = %q, %v; want %q", dir, err, want)
+	}
+
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "../abc_123")
+	if _, err := hyprlandSocketDir(); err == nil {
+		t.Error("hyprlandSocketDir() accepted a signature with a path separator")
+	}
+}
+
+func TestParseHyprlandEscapedTitle(t *testing.T) {
+	info := parseHyprlandWindow(`{"class": "code", "title": "main.go — {a, b}, \"c\",", "at": [10, 20], "size": [800, 600],}`)
+	if info.WindowTitle != `main.go — {a, b}, "c",` {
+		t.Errorf("WindowTitle = %q", info.WindowTitle)
+	}
+	if info.Geometry.X != 10 || info.Geometry.Height != 600 {
+		t.Errorf("Geometry = %+v", info.Geometry)
+	}
+
+	if info := parseHyprlandWindow(`{"class": "broken`); info.AppName != "Unknown" {
+		t.Errorf("AppName of invalid JSON = %q, want Unknown", info.AppName)
+	}
+}
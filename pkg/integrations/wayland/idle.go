@@ -0,0 +1,203 @@
+package wayland
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Object IDs the idle monitor allocates after the registry handshake.
+const (
+	seatID         = 4
+	idleNotifierID = 5
+	idleTimeoutID  = 6
+)
+
+// idleNotifyTimeout is how long without input the compositor waits before
+// saying the user is idle, and so the resolution of the idle time.
+const idleNotifyTimeout = 5 * time.Second
+
+// errIdleUnsupported means the compositor offers neither idle protocol.
+var errIdleUnsupported = errors.New("compositor offers no idle notification protocol")
+
+// idleProtocols are the globals that report idleness, preferred first:
+// ext-idle-notify-v1 is the standard one, org_kde_kwin_idle is what KWin
+// offered before it. Both notify after a timeout without input and again
+// when input resumes.
+var idleProtocols = []struct {
+	iface string
+	// request creates the notification: ext_idle_notifier_v1.
+	// get_idle_notification(new_id, uint timeout, object seat) or
+	// org_kde_kwin_idle.get_idle_timeout(new_id, object seat, int timeout).
+	request func(w *bytes.Buffer, timeoutMS uint32)
+}{
+	{"ext_idle_notifier_v1", func(w *bytes.Buffer, timeoutMS uint32) {
+		writeRequest(w, idleNotifierID, 1, uint32(idleTimeoutID), timeoutMS, uint32(seatID))
+	}},
+	{"org_kde_kwin_idle", func(w *bytes.Buffer, timeoutMS uint32) {
+		writeRequest(w, idleNotifierID, 0, uint32(idleTimeoutID), uint32(seatID), timeoutMS)
+	}},
+}
+
+// idleMonitor keeps a connection to the compositor open and follows its idle
+// notifications, as Wayland lets no client ask how long the user has been
+// idle. The user has been idle since idleNotifyTimeout before the last
+// "idled" event, unless input has resumed since.
+type idleMonitor struct {
+	path string
+
+	mu        sync.Mutex
+	watching  bool
+	idleSince time.Time
+	conn      net.Conn
+	closed    bool
+	done      chan struct{}
+}
+
+func newIdleMonitor(path string) *idleMonitor {
+	m := &idleMonitor{path: path, done: make(chan struct{})}
+	go m.run()
+	return m
+}
+
+// idleSeconds returns how long the user has been idle, and false while the
+// compositor's idle notifications aren't being followed.
+func (m *idleMonitor) idleSeconds(now time.Time) (int64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.watching {
+		return 0, false
+	}
+	if m.idleSince.IsZero() {
+		return 0, true
+	}
+	return int64(now.Sub(m.idleSince).Seconds()), true
+}
+
+// run follows idle notifications until Close, reconnecting when the
+// connection drops. It gives up on compositors without an idle protocol.
+func (m *idleMonitor) run() {
+	for {
+		err := m.watch()
+		if errors.Is(err, errIdleUnsupported) {
+			log.Printf("Wayland idle time unavailable: %v", err)
+			return
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(ipcRetry):
+		}
+	}
+}
+
+func (m *idleMonitor) watch() error {
+	conn, err := net.DialTimeout("unix", m.path, registryTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to wayland socket: %w", err)
+	}
+	defer conn.Close()
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.conn = conn
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.conn = nil
+		m.watching = false
+		m.idleSince = time.Time{}
+		m.mu.Unlock()
+	}()
+
+	conn.SetDeadline(time.Now().Add(registryTimeout))
+	var request bytes.Buffer
+	writeMessage(&request, displayID, 1, registryID)
+	writeMessage(&request, displayID, 0, callbackID)
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return fmt.Errorf("failed to query wayland registry: %w", err)
+	}
+	globals, err := readRegistry(conn)
+	if err != nil {
+		return err
+	}
+
+	seat := findGlobal(globals, "wl_seat")
+	var notifier *global
+	var protocol int
+	for p, candidate := range idleProtocols {
+		if notifier = findGlobal(globals, candidate.iface); notifier != nil {
+			protocol = p
+			break
+		}
+	}
+	if seat == nil || notifier == nil {
+		return errIdleUnsupported
+	}
+
+	// wl_registry.bind(uint name, new_id id) with the new_id's interface
+	// and version spelled out, as the registry is untyped.
+	request.Reset()
+	writeRequest(&request, registryID, 0, seat.name, "wl_seat", uint32(1), uint32(seatID))
+	writeRequest(&request, registryID, 0, notifier.name, notifier.iface, uint32(1), uint32(idleNotifierID))
+	idleProtocols[protocol].request(&request, uint32(idleNotifyTimeout.Milliseconds()))
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return fmt.Errorf("failed to request idle notifications: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	m.mu.Lock()
+	m.watching = true
+	m.mu.Unlock()
+
+	for {
+		object, opcode, _, err := readEvent(conn)
+		if err != nil {
+			return fmt.Errorf("lost wayland idle notifications: %w", err)
+		}
+		switch {
+		case object == displayID && opcode == 0:
+			return fmt.Errorf("wayland compositor refused idle notifications")
+		case object == idleTimeoutID && opcode == 0:
+			// idled, or KWin's idle
+			m.mu.Lock()
+			m.idleSince = time.Now().Add(-idleNotifyTimeout)
+			m.mu.Unlock()
+		case object == idleTimeoutID && opcode == 1:
+			// resumed
+			m.mu.Lock()
+			m.idleSince = time.Time{}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func findGlobal(globals []global, iface string) *global {
+	for i := range globals {
+		if globals[i].iface == iface {
+			return &globals[i]
+		}
+	}
+	return nil
+}
+
+func (m *idleMonitor) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	close(m.done)
+	if m.conn != nil {
+		m.conn.Close()
+	}
+}
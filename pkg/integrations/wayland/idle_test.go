@@ -0,0 +1,160 @@
+package wayland
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeIdleCompositor advertises globals and hands over the connection once
+// the client has asked for idle notifications, along with that request.
+func fakeIdleCompositor(t *testing.T, globals ...string) (path string, requested <-chan []byte, conns <-chan net.Conn) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "actionsum-wayland")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path = filepath.Join(dir, "wayland-0")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	requests := make(chan []byte, 1)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		// get_registry and sync
+		for range 2 {
+			if _, _, _, err := readEvent(conn); err != nil {
+				conn.Close()
+				return
+			}
+		}
+		var events bytes.Buffer
+		for i, iface := range globals {
+			events.Write(encodeGlobal(uint32(i+1), iface))
+		}
+		writeMessage(&events, callbackID, 0, 0)
+		conn.Write(events.Bytes())
+
+		// Two binds, then the notification request.
+		for {
+			object, opcode, body, err := readEvent(conn)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			if object == idleNotifierID {
+				var request bytes.Buffer
+				writeMessage(&request, object, opcode)
+				request.Write(body)
+				binary.NativeEndian.PutUint32(request.Bytes()[4:8], uint32(request.Len())<<16|opcode)
+				requests <- request.Bytes()
+				accepted <- conn
+				return
+			}
+		}
+	}()
+	return path, requests, accepted
+}
+
+func TestIdleMonitor(t *testing.T) {
+	tests := []struct {
+		name    string
+		globals []string
+		// opcode and arguments of the expected notification request
+		opcode uint32
+		args   []uint32
+	}{
+		{"ext-idle-notify", []string{"wl_seat", "org_kde_kwin_idle", "ext_idle_notifier_v1"}, 1, []uint32{idleTimeoutID, 5000, seatID}},
+		{"kwin", []string{"wl_seat", "org_kde_kwin_idle"}, 0, []uint32{idleTimeoutID, seatID, 5000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, requested, conns := fakeIdleCompositor(t, tt.globals...)
+			m := newIdleMonitor(path)
+			defer m.Close()
+
+			var want bytes.Buffer
+			writeMessage(&want, idleNotifierID, tt.opcode, tt.args...)
+			select {
+			case got := <-requested:
+				if !bytes.Equal(got, want.Bytes()) {
+					t.Errorf("notification request = %v, want %v", got, want.Bytes())
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no idle notification requested")
+			}
+			conn := <-conns
+			waitUntil(t, "the notification request", func() bool {
+				_, ok := m.idleSeconds(time.Now())
+				return ok
+			})
+			if seconds, _ := m.idleSeconds(time.Now()); seconds != 0 {
+				t.Errorf("idleSeconds() before idled = %d, want 0", seconds)
+			}
+
+			var event bytes.Buffer
+			writeMessage(&event, idleTimeoutID, 0)
+			conn.Write(event.Bytes())
+			waitUntil(t, "the idled event", func() bool {
+				seconds, _ := m.idleSeconds(time.Now().Add(time.Minute))
+				return seconds >= 65
+			})
+
+			event.Reset()
+			writeMessage(&event, idleTimeoutID, 1)
+			conn.Write(event.Bytes())
+			waitUntil(t, "the resumed event", func() bool {
+				seconds, ok := m.idleSeconds(time.Now().Add(time.Minute))
+				return ok && seconds == 0
+			})
+
+			conn.Close()
+			waitUntil(t, "the disconnect", func() bool {
+				_, ok := m.idleSeconds(time.Now())
+				return !ok
+			})
+		})
+	}
+}
+
+func TestIdleMonitorUnsupported(t *testing.T) {
+	path, _, _ := fakeIdleCompositor(t, "wl_seat", "wl_compositor")
+	m := &idleMonitor{path: path, done: make(chan struct{})}
+	if err := m.watch(); err != errIdleUnsupported {
+		t.Errorf("watch() error = %v, want %v", err, errIdleUnsupported)
+	}
+	if _, ok := m.idleSeconds(time.Now()); ok {
+		t.Error("idleSeconds() reported idle time without an idle protocol")
+	}
+}
+
+func TestWriteRequest(t *testing.T) {
+	var got bytes.Buffer
+	writeRequest(&got, registryID, 0, uint32(7), "wl_seat", uint32(1), uint32(seatID))
+
+	var want bytes.Buffer
+	for _, v := range []uint32{registryID, 32 << 16, 7, 8} {
+		binary.Write(&want, binary.NativeEndian, v)
+	}
+	want.WriteString("wl_seat\x00")
+	for _, v := range []uint32{1, seatID} {
+		binary.Write(&want, binary.NativeEndian, v)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("writeRequest() = %v, want %v", got.Bytes(), want.Bytes())
+	}
+}
@@ -0,0 +1,175 @@
+//go:build integration
+
+package wayland
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+// These tests run the detector against sway on its headless backend, with
+// foot as a native Wayland client and xterm under XWayland. Run them with
+//
+//	go test -tags integration ./pkg/integrations/...
+
+func requireTools(t *testing.T, tools ...string) {
+	t.Helper()
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+}
+
+// startSway runs a headless sway in a private runtime directory and points
+// XDG_RUNTIME_DIR, WAYLAND_DISPLAY and SWAYSOCK at it for the rest of the test.
+func startSway(t *testing.T) {
+	t.Helper()
+	requireTools(t, "sway", "swaymsg")
+
+	// Socket paths are limited to about 100 bytes, more than t.TempDir()
+	// may leave room for.
+	runtimeDir, err := os.MkdirTemp("", "actionsum-sway")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(runtimeDir) })
+	os.Chmod(runtimeDir, 0700)
+
+	config := filepath.Join(runtimeDir, "config")
+	if err := os.WriteFile(config, []byte("xwayland enable\ndefault_border none\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", "")
+	t.Setenv("SWAYSOCK", "")
+	cmd := exec.Command("sway", "--config", config)
+	cmd.Env = append(os.Environ(),
+		"WLR_BACKENDS=headless",
+		"WLR_LIBINPUT_NO_DEVICES=1",
+		"WLR_RENDERER=pixman",
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start sway: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	waitFor(t, "sway's sockets", func() bool {
+		display, _ := filepath.Glob(filepath.Join(runtimeDir, "wayland-*[0-9]"))
+		ipc, _ := filepath.Glob(filepath.Join(runtimeDir, "sway-ipc.*.sock"))
+		if len(display) == 0 || len(ipc) == 0 {
+			return false
+		}
+		t.Setenv("WAYLAND_DISPLAY", filepath.Base(display[0]))
+		t.Setenv("SWAYSOCK", ipc[0])
+		return true
+	})
+}
+
+func waitFor(t *testing.T, what string, ready func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !ready() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func swaymsg(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("swaymsg", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("swaymsg %v: %v: %s", args, err, output)
+	}
+	return string(output)
+}
+
+// launch runs command through sway, so it gets the session's WAYLAND_DISPLAY
+// and DISPLAY, and waits until a window matching criteria is in the tree.
+func launch(t *testing.T, command, criteria, marker string) {
+	t.Helper()
+	swaymsg(t, "exec", command)
+	waitFor(t, marker, func() bool {
+		return strings.Contains(swaymsg(t, "-t", "get_tree"), marker)
+	})
+	swaymsg(t, criteria+" focus")
+}
+
+func focused(t *testing.T, d *Detector) *window.WindowInfo {
+	t.Helper()
+	info, err := d.GetFocusedWindow()
+	if err != nil {
+		t.Fatalf("GetFocusedWindow() error = %v", err)
+	}
+	return info
+}
+
+func TestIntegrationSwayCompositor(t *testing.T) {
+	startSway(t)
+
+	d := NewDetector()
+	if d.compositor != "sway" {
+		t.Errorf("compositor = %q, want sway", d.compositor)
+	}
+	if !d.IsAvailable() {
+		t.Error("IsAvailable() = false with SWAYSOCK set")
+	}
+}
+
+func TestIntegrationSwayNativeWindow(t *testing.T) {
+	startSway(t)
+	requireTools(t, "foot")
+	launch(t, "foot --app-id actionsum-native --title 'main.go - actionsum' sleep 600",
+		`[app_id="actionsum-native"]`, `"actionsum-native"`)
+
+	d := NewDetector()
+	info := focused(t, d)
+	if info.AppName != "actionsum-native" || info.WindowTitle != "main.go - actionsum" {
+		t.Errorf("window = %q %q, want actionsum-native %q", info.AppName, info.WindowTitle, "main.go - actionsum")
+	}
+	if info.ProcessName != "foot" {
+		t.Errorf("ProcessName = %q, want foot", info.ProcessName)
+	}
+	if info.DisplayServer != "wayland" || info.Client != window.ClientNative {
+		t.Errorf("DisplayServer = %q Client = %q, want wayland native", info.DisplayServer, info.Client)
+	}
+	if info.Geometry.Width == 0 || info.Geometry.Height == 0 {
+		t.Errorf("Geometry = %+v, want the window's size", info.Geometry)
+	}
+	if info.IsFullscreen {
+		t.Error("IsFullscreen = true before fullscreening")
+	}
+
+	swaymsg(t, "fullscreen", "enable")
+	if info := focused(t, d); !info.IsFullscreen {
+		t.Error("IsFullscreen = false after swaymsg fullscreen enable")
+	}
+}
+
+func TestIntegrationSwayXWaylandWindow(t *testing.T) {
+	startSway(t)
+	requireTools(t, "Xwayland", "xterm")
+	launch(t, "xterm -class ActionsumX -T 'Issue #12 - GitHub' -e sleep 600",
+		`[class="ActionsumX"]`, `"ActionsumX"`)
+
+	info := focused(t, NewDetector())
+	if info.AppName != "ActionsumX" || info.WindowTitle != "Issue #12 - GitHub" {
+		t.Errorf("window = %q %q, want ActionsumX %q", info.AppName, info.WindowTitle, "Issue #12 - GitHub")
+	}
+	if info.Client != window.ClientXWayland {
+		t.Errorf("Client = %q, want %s", info.Client, window.ClientXWayland)
+	}
+}
@@ -0,0 +1,225 @@
+package wayland
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/dbus"
+	"github.com/actionsum/actionsum/pkg/sandbox"
+	"github.com/actionsum/actionsum/pkg/window"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// kwinTimeout bounds each exchange with KWin, from connecting to the
+// script's answer.
+const kwinTimeout = 2 * time.Second
+
+// kwinScript reports the active window by calling back over D-Bus, as KWin
+// scripts have no other way to return anything. workspace.activeWindow is
+// KWin 6's name for KWin 5's workspace.activeClient. The placeholders are the
+// bus name to call and the token identifying this run.
+const kwinScript = `var w = workspace.activeWindow || workspace.activeClient;
+var info = {};
+if (w) {
+	var g = w.frameGeometry;
+	info = {resourceClass: String(w.resourceClass), caption: String(w.caption), pid: w.pid,
+		x: g.x, y: g.y, width: g.width, height: g.height, fullScreen: !!w.fullScreen};
+	try {
+		var a = workspace.clientArea(KWin.MaximizeArea, w);
+		info.maximized = !w.fullScreen && g.x == a.x && g.y == a.y && g.width == a.width && g.height == a.height;
+	} catch (e) {}
+}
+callDBus(%s, "/actionsum", "io.github.actionsum.KWin", "window", %s, JSON.stringify(info));
+`
+
+// kwinScripting asks KWin for the active window through its scripting API:
+// each query loads a small script with org.kde.kwin.Scripting.loadScript,
+// starts it and waits for the script to call back with the window, then
+// unloads it. KWin offers no D-Bus method that returns the active window.
+type kwinScripting struct {
+	address string
+
+	mu   sync.Mutex
+	bus  *dbus.Conn
+	runs int
+
+	// answers carries the scripts' calls back, token and window.
+	answers chan [2]string
+}
+
+func newKWinScripting(address string) *kwinScripting {
+	return &kwinScripting{address: address}
+}
+
+// activeWindow returns the focused window, reconnecting to the session bus
+// after a failed exchange.
+func (k *kwinScripting) activeWindow() (*window.WindowInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.bus == nil {
+		bus, err := dbus.Dial(k.address, kwinTimeout)
+		if err != nil {
+			return nil, err
+		}
+		answers := make(chan [2]string, 4)
+		err = bus.ExportMethodTable(map[string]any{
+			"window": func(token, answer string) *godbus.Error {
+				select {
+				case answers <- [2]string{token, answer}:
+				default:
+				}
+				return nil
+			},
+		}, "/actionsum", "io.github.actionsum.KWin")
+		if err != nil {
+			bus.Close()
+			return nil, err
+		}
+		k.bus, k.answers = bus, answers
+	}
+	info, err := k.query()
+	if err != nil {
+		k.bus.Close()
+		k.bus = nil
+		return nil, err
+	}
+	return info, nil
+}
+
+func (k *kwinScripting) query() (*window.WindowInfo, error) {
+	k.runs++
+	plugin := fmt.Sprintf("actionsum-%d-%d", os.Getpid(), k.runs)
+	path := filepath.Join(kwinScriptDir(), plugin+".js")
+	script := fmt.Sprintf(kwinScript, strconv.Quote(k.bus.Names()[0]), strconv.Quote(plugin))
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write KWin script: %w", err)
+	}
+	defer os.Remove(path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), kwinTimeout)
+	defer cancel()
+	scripting := k.bus.Object("org.kde.KWin", "/Scripting")
+
+	var id int32
+	if err := scripting.CallWithContext(ctx, "org.kde.kwin.Scripting.loadScript", 0, path, plugin).Store(&id); err != nil {
+		return nil, fmt.Errorf("failed to load KWin script: %w", err)
+	}
+	if id < 0 {
+		return nil, fmt.Errorf("KWin refused to load the script")
+	}
+	defer k.unloadScript(scripting, plugin)
+
+	if err := scripting.CallWithContext(ctx, "org.kde.kwin.Scripting.start", 0).Err; err != nil {
+		return nil, fmt.Errorf("failed to start KWin script: %w", err)
+	}
+
+	for {
+		select {
+		case answer := <-k.answers:
+			// Calls from earlier runs that timed out are ignored.
+			if answer[0] != plugin {
+				continue
+			}
+			return parseKWinWindow(answer[1])
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no answer from KWin script: %w", ctx.Err())
+		}
+	}
+}
+
+// unloadScript removes a script once it has run. It gets a context of its
+// own, since a slow KWin may have used up the query's, and a script left
+// loaded stays in KWin for the rest of the session.
+func (k *kwinScripting) unloadScript(scripting godbus.BusObject, plugin string) {
+	ctx, cancel := context.WithTimeout(context.Background(), kwinTimeout)
+	defer cancel()
+	if err := scripting.CallWithContext(ctx, "org.kde.kwin.Scripting.unloadScript", 0, plugin).Err; err != nil {
+		log.Printf("Failed to unload KWin script %s: %v", plugin, err)
+	}
+}
+
+// kwinScriptDir is where scripts are written for KWin to read. Inside
+// Flatpak only the app's directory under XDG_RUNTIME_DIR is shared with the
+// host at the same path.
+func kwinScriptDir() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		if id := sandbox.AppID(); id != "" {
+			return filepath.Join(runtimeDir, "app", id)
+		}
+		return runtimeDir
+	}
+	return os.TempDir()
+}
+
+func (k *kwinScripting) Close() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.bus != nil {
+		k.bus.Close()
+		k.bus = nil
+	}
+}
+
+// kwinWindow is what the script reports about the active window. KWin 6
+// gives fractional geometry.
+type kwinWindow struct {
+	ResourceClass string  `json:"resourceClass"`
+	Caption       string  `json:"caption"`
+	PID           int     `json:"pid"`
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Width         float64 `json:"width"`
+	Height        float64 `json:"height"`
+	FullScreen    bool    `json:"fullScreen"`
+	Maximized     bool    `json:"maximized"`
+}
+
+// parseKWinWindow decodes the script's answer. No active window, as on an
+// empty desktop, gives an Unknown window.
+func parseKWinWindow(answer string) (*window.WindowInfo, error) {
+	var w kwinWindow
+	if err := json.Unmarshal([]byte(answer), &w); err != nil {
+		return nil, fmt.Errorf("failed to parse KWin window: %w", err)
+	}
+
+	appName := w.ResourceClass
+	if appName == "" {
+		appName = "Unknown"
+	}
+	windowTitle := w.Caption
+	if windowTitle == "" {
+		windowTitle = "Unknown"
+	}
+	processName := appName
+	if w.PID > 0 {
+		if name := getProcessName(strconv.Itoa(w.PID)); name != "" {
+			processName = name
+		}
+	}
+
+	return &window.WindowInfo{
+		AppName:       appName,
+		WindowTitle:   windowTitle,
+		ProcessName:   processName,
+		PID:           w.PID,
+		DisplayServer: "wayland",
+		Geometry: window.Geometry{
+			X:      int(math.Round(w.X)),
+			Y:      int(math.Round(w.Y)),
+			Width:  int(math.Round(w.Width)),
+			Height: int(math.Round(w.Height)),
+		},
+		IsFullscreen: w.FullScreen,
+		IsMaximized:  w.Maximized,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package wayland
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/actionsum/actionsum/pkg/dbus/dbustest"
+
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// fakeKWin answers the scripting calls, and on start calls back with answer
+// as the script would, unless answer is empty. Each script it is asked to
+// load is sent on scripts, and each it unloads on unloaded.
+func fakeKWin(t *testing.T, answer string) (address string, scripts, unloaded <-chan string) {
+	t.Helper()
+	loaded := make(chan string, 4)
+	unloads := make(chan string, 4)
+	var plugin string
+	address = dbustest.NewBus(t, func(msg dbustest.Message, send func(m *godbus.Message)) {
+		switch msg.Member() {
+		case "loadScript":
+			plugin = msg.StringArg(1)
+			script, _ := os.ReadFile(msg.StringArg(0))
+			loaded <- string(script)
+			send(dbustest.Reply(msg, int32(3)))
+		case "start":
+			send(dbustest.Reply(msg))
+			if answer == "" {
+				return
+			}
+			// A stale answer from an earlier run comes first.
+			send(dbustest.MethodCall("/actionsum", "io.github.actionsum.KWin", "window", "actionsum-0-0", `{"resourceClass": "stale"}`))
+			send(dbustest.MethodCall("/actionsum", "io.github.actionsum.KWin", "window", plugin, answer))
+		case "unloadScript":
+			unloads <- msg.StringArg(0)
+			send(dbustest.Reply(msg, true))
+		}
+	})
+	return address, loaded, unloads
+}
+
+func TestKWinScripting(t *testing.T) {
+	address, scripts, _ := fakeKWin(t, `{"resourceClass": "org.kde.dolphin", "caption": "Home — Dolphin", "pid": 0, "x": 10.5, "y": 20, "width": 800, "height": 600, "fullScreen": false, "maximized": true}`)
+	k := newKWinScripting(address)
+	defer k.Close()
+
+	for range 2 {
+		info, err := k.activeWindow()
+		if err != nil {
+			t.Fatalf("activeWindow() error = %v", err)
+		}
+		if info.AppName != "org.kde.dolphin" || info.WindowTitle != "Home — Dolphin" {
+			t.Errorf("window = %q %q", info.AppName, info.WindowTitle)
+		}
+		if !info.IsMaximized || info.IsFullscreen || info.Geometry.X != 11 || info.Geometry.Width != 800 {
+			t.Errorf("window state = %+v", info)
+		}
+		script := <-scripts
+		if !strings.Contains(script, `callDBus(":1.42", "/actionsum"`) {
+			t.Errorf("script does not call back to the connection:\n%s", script)
+		}
+	}
+}
+
+func TestKWinScriptingTimeout(t *testing.T) {
+	address, scripts, unloaded := fakeKWin(t, "")
+	k := newKWinScripting(address)
+	defer k.Close()
+
+	if _, err := k.activeWindow(); err == nil {
+		t.Fatal("activeWindow() without an answer succeeded")
+	}
+	<-scripts
+	// The script is unloaded even though the query's time ran out.
+	select {
+	case plugin := <-unloaded:
+		if !strings.HasPrefix(plugin, "actionsum-") {
+			t.Errorf("unloaded %q, want the script that was loaded", plugin)
+		}
+	default:
+		t.Error("script left loaded after the query timed out")
+	}
+}
+
+func TestParseKWinWindowEmpty(t *testing.T) {
+	info, err := parseKWinWindow("{}")
+	if err != nil {
+		t.Fatalf("parseKWinWindow() error = %v", err)
+	}
+	if info.AppName != "Unknown" || info.WindowTitle != "Unknown" {
+		t.Errorf("window = %q %q, want Unknown without an active window", info.AppName, info.WindowTitle)
+	}
+	if _, err := parseKWinWindow("not json"); err == nil {
+		t.Error("parseKWinWindow() accepted invalid JSON")
+	}
+}
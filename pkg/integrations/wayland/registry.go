@@ -0,0 +1,235 @@
+package wayland
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// registryTimeout bounds the whole handshake with the compositor.
+const registryTimeout = 2 * time.Second
+
+// Object IDs the handshake allocates. wl_display is always 1.
+const (
+	displayID  = 1
+	registryID = 2
+	callbackID = 3
+)
+
+// socketPath returns the compositor socket named by WAYLAND_DISPLAY, relative
+// to XDG_RUNTIME_DIR unless absolute.
+func socketPath() (string, error) {
+	display := os.Getenv("WAYLAND_DISPLAY")
+	if display == "" {
+		display = "wayland-0"
+	}
+	if filepath.IsAbs(display) {
+		return display, nil
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return "", fmt.Errorf("XDG_RUNTIME_DIR is not set")
+	}
+	return filepath.Join(runtimeDir, display), nil
+}
+
+// queryGlobals connects to the compositor and returns the interfaces its
+// registry advertises, e.g. "wl_compositor" or "zwlr_layer_shell_v1". It
+// speaks just enough of the wire protocol to bind wl_registry and wait for a
+// wl_display.sync round trip, so it needs no client library and works
+// wherever the socket is reachable, including inside containers.
+func queryGlobals(path string) ([]string, error) {
+	conn, err := net.DialTimeout("unix", path, registryTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to wayland socket: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(registryTimeout))
+
+	// wl_display.get_registry(new_id), then wl_display.sync(new_id): the
+	// sync callback fires once every global has been announced.
+	var request bytes.Buffer
+	writeMessage(&request, displayID, 1, registryID)
+	writeMessage(&request, displayID, 0, callbackID)
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to query wayland registry: %w", err)
+	}
+
+	return readGlobals(conn)
+}
+
+// writeMessage encodes a request whose arguments are all 32-bit values.
+func writeMessage(w *bytes.Buffer, object, opcode uint32, args ...uint32) {
+	size := uint32(8 + 4*len(args))
+	binary.Write(w, binary.NativeEndian, object)
+	binary.Write(w, binary.NativeEndian, size<<16|opcode)
+	for _, arg := range args {
+		binary.Write(w, binary.NativeEndian, arg)
+	}
+}
+
+// writeRequest encodes a request whose arguments are 32-bit values or
+// strings, such as wl_registry.bind with its untyped new_id.
+func writeRequest(w *bytes.Buffer, object, opcode uint32, args ...any) {
+	var body bytes.Buffer
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case uint32:
+			binary.Write(&body, binary.NativeEndian, v)
+		case string:
+			binary.Write(&body, binary.NativeEndian, uint32(len(v)+1))
+			body.WriteString(v)
+			body.WriteByte(0)
+			for body.Len()%4 != 0 {
+				body.WriteByte(0)
+			}
+		}
+	}
+	binary.Write(w, binary.NativeEndian, object)
+	binary.Write(w, binary.NativeEndian, uint32(8+body.Len())<<16|opcode)
+	w.Write(body.Bytes())
+}
+
+// readEvent reads one event, returning the object it is for, its opcode and
+// its arguments.
+func readEvent(r io.Reader) (object, opcode uint32, body []byte, err error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+	object = binary.NativeEndian.Uint32(header[0:4])
+	sizeOpcode := binary.NativeEndian.Uint32(header[4:8])
+	size := sizeOpcode >> 16
+	if size < 8 {
+		return 0, 0, nil, fmt.Errorf("invalid wayland message size %d", size)
+	}
+	body = make([]byte, size-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return object, sizeOpcode & 0xffff, body, nil
+}
+
+// global is an interface the compositor's registry advertises.
+type global struct {
+	name    uint32
+	iface   string
+	version uint32
+}
+
+// readRegistry reads events until the sync callback is done, collecting each
+// wl_registry.global.
+func readRegistry(r io.Reader) ([]global, error) {
+	var globals []global
+	for {
+		object, opcode, body, err := readEvent(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read wayland registry: %w", err)
+		}
+
+		switch {
+		case object == callbackID && opcode == 0:
+			return globals, nil
+		case object == displayID && opcode == 0:
+			return nil, fmt.Errorf("wayland compositor reported an error")
+		case object == registryID && opcode == 0:
+			// wl_registry.global(uint name, string interface, uint version)
+			iface, ok := parseString(body, 4)
+			if !ok {
+				continue
+			}
+			g := global{name: binary.NativeEndian.Uint32(body[0:4]), iface: iface}
+			if end := 8 + (len(iface)+1+3)/4*4; len(body) >= end+4 {
+				g.version = binary.NativeEndian.Uint32(body[end : end+4])
+			}
+			globals = append(globals, g)
+		}
+	}
+}
+
+// readGlobals returns the interface of each wl_registry.global.
+func readGlobals(r io.Reader) ([]string, error) {
+	found, err := readRegistry(r)
+	if err != nil {
+		return nil, err
+	}
+	globals := make([]string, len(found))
+	for i, g := range found {
+		globals[i] = g.iface
+	}
+	return globals, nil
+}
+
+// parseString decodes the wire string at offset: a 32-bit length counting
+// the terminating NUL, then the bytes padded to 32 bits.
+func parseString(body []byte, offset int) (string, bool) {
+	if len(body) < offset+4 {
+		return "", false
+	}
+	length := int(binary.NativeEndian.Uint32(body[offset : offset+4]))
+	start := offset + 4
+	if length == 0 || len(body) < start+length {
+		return "", false
+	}
+	return string(body[start : start+length-1]), true
+}
+
+// compositorFromGlobals names the compositor from the protocols it
+// advertises, or returns "" when they don't identify one we support.
+// Generic wlroots protocols only point to sway when its IPC socket is known.
+func compositorFromGlobals(globals []string) string {
+	var wlroots bool
+	for _, global := range globals {
+		switch {
+		case strings.HasPrefix(global, "hyprland_"):
+			return "hyprland"
+		case strings.HasPrefix(global, "org_kde_kwin"), strings.HasPrefix(global, "org_kde_plasma"):
+			return "kde"
+		case global == "gtk_shell1":
+			return "gnome"
+		case global == "zriver_control_v1", global == "zriver_status_manager_v1":
+			return "river"
+		case strings.HasPrefix(global, "zwf_"):
+			return "wayfire"
+		case strings.HasPrefix(global, "zwlr_"):
+			wlroots = true
+		}
+	}
+	if wlroots && os.Getenv("SWAYSOCK") != "" {
+		return "sway"
+	}
+	return ""
+}
+
+// compositorFromEnvironment names the compositor from variables desktop
+// sessions export, for when the socket cannot be queried.
+func compositorFromEnvironment() string {
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return "hyprland"
+	case os.Getenv("SWAYSOCK") != "":
+		return "sway"
+	}
+	desktop := strings.ToLower(os.Getenv("XDG_CURRENT_DESKTOP"))
+	switch {
+	case strings.Contains(desktop, "gnome"):
+		return "gnome"
+	case strings.Contains(desktop, "kde"):
+		return "kde"
+	case strings.Contains(desktop, "hyprland"):
+		return "hyprland"
+	case strings.Contains(desktop, "sway"):
+		return "sway"
+	case strings.Contains(desktop, "wayfire"):
+		return "wayfire"
+	case strings.Contains(desktop, "river"):
+		return "river"
+	}
+	return ""
+}
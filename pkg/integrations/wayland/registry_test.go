@@ -0,0 +1,94 @@
+package wayland
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// encodeGlobal builds a wl_registry.global event.
+func encodeGlobal(name uint32, iface string) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, binary.NativeEndian, name)
+	binary.Write(&body, binary.NativeEndian, uint32(len(iface)+1))
+	body.WriteString(iface)
+	body.WriteByte(0)
+	for body.Len()%4 != 0 {
+		body.WriteByte(0)
+	}
+	binary.Write(&body, binary.NativeEndian, uint32(1))
+
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.NativeEndian, uint32(registryID))
+	binary.Write(&msg, binary.NativeEndian, uint32(8+body.Len())<<16)
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+func TestQueryGlobals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wayland-test")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// get_registry and sync, 12 bytes each.
+		if _, err := io.ReadFull(conn, make([]byte, 24)); err != nil {
+			return
+		}
+		var events bytes.Buffer
+		events.Write(encodeGlobal(1, "wl_compositor"))
+		events.Write(encodeGlobal(2, "gtk_shell1"))
+		var done bytes.Buffer
+		writeMessage(&done, callbackID, 0, 0)
+		events.Write(done.Bytes())
+		conn.Write(events.Bytes())
+	}()
+
+	globals, err := queryGlobals(path)
+	if err != nil {
+		t.Fatalf("queryGlobals() error = %v", err)
+	}
+	if len(globals) != 2 || globals[0] != "wl_compositor" || globals[1] != "gtk_shell1" {
+		t.Errorf("queryGlobals() = %v, want wl_compositor and gtk_shell1", globals)
+	}
+	if got := compositorFromGlobals(globals); got != "gnome" {
+		t.Errorf("compositorFromGlobals() = %q, want gnome", got)
+	}
+}
+
+func TestCompositorFromGlobals(t *testing.T) {
+	tests := []struct {
+		name     string
+		globals  []string
+		swaysock string
+		want     string
+	}{
+		{"hyprland", []string{"zwlr_layer_shell_v1", "hyprland_focus_grab_manager_v1"}, "", "hyprland"},
+		{"kde", []string{"wl_compositor", "org_kde_plasma_window_management"}, "", "kde"},
+		{"river", []string{"zwlr_layer_shell_v1", "zriver_status_manager_v1"}, "", "river"},
+		{"wayfire", []string{"zwf_shell_manager_v2"}, "", "wayfire"},
+		{"sway", []string{"zwlr_layer_shell_v1"}, "/run/user/1000/sway-ipc.sock", "sway"},
+		{"other wlroots", []string{"zwlr_layer_shell_v1"}, "", ""},
+		{"bare", []string{"wl_compositor", "xdg_wm_base"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SWAYSOCK", tt.swaysock)
+			if got := compositorFromGlobals(tt.globals); got != tt.want {
+				t.Errorf("compositorFromGlobals(%v) = %q, want %q", tt.globals, got, tt.want)
+			}
+		})
+	}
+}
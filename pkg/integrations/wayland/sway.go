@@ -0,0 +1,422 @@
+package wayland
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+// The i3 IPC protocol, which sway speaks too: each message is the magic
+// string, the payload's length and the message type as native-endian 32-bit
+// integers, then the payload. Replies carry the type of their request;
+// events have the high bit set.
+const (
+	swayMagic = "i3-ipc"
+
+	swayGetTree   uint32 = 4
+	swaySubscribe uint32 = 2
+
+	swayEventWindow    uint32 = 0x80000003
+	swayEventWorkspace uint32 = 0x80000000
+	swayEventShutdown  uint32 = 0x80000006
+
+	// swayMaxPayload bounds a message, far above the size of any real
+	// tree, so a corrupt length can't exhaust memory.
+	swayMaxPayload = 64 << 20
+)
+
+// swayTimeout bounds each request to sway.
+const swayTimeout = 2 * time.Second
+
+// swayIPC talks to sway, or i3, over its IPC socket instead of running
+// swaymsg on every poll. Requests share one connection, reopened after an
+// error. A second connection subscribed to window and workspace events
+// keeps the focused window up to date between them, so a poll only asks
+// for the whole tree when no event has described the focused window yet.
+type swayIPC struct {
+	path string
+
+	reqMu sync.Mutex
+	conn  net.Conn
+
+	mu       sync.Mutex
+	cached   *window.WindowInfo
+	output   string // Output of the focused workspace
+	listener net.Conn
+	closed   bool
+	done     chan struct{}
+}
+
+// swaySocketPath returns the IPC socket of the running sway, or of i3.
+func swaySocketPath() (string, error) {
+	for _, name := range []string{"SWAYSOCK", "I3SOCK"} {
+		if path := os.Getenv(name); path != "" {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("neither SWAYSOCK nor I3SOCK names a socket")
+}
+
+func newSwayIPC(path string) *swayIPC {
+	c := &swayIPC{path: path, done: make(chan struct{})}
+	go c.listen()
+	return c
+}
+
+func writeSwayMessage(conn net.Conn, msgType uint32, payload []byte) error {
+	msg := make([]byte, 0, len(swayMagic)+8+len(payload))
+	msg = append(msg, swayMagic...)
+	msg = binary.NativeEndian.AppendUint32(msg, uint32(len(payload)))
+	msg = binary.NativeEndian.AppendUint32(msg, msgType)
+	msg = append(msg, payload...)
+	_, err := conn.Write(msg)
+	return err
+}
+
+func readSwayMessage(r io.Reader) (uint32, []byte, error) {
+	header := make([]byte, len(swayMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	if string(header[:len(swayMagic)]) != swayMagic {
+		return 0, nil, fmt.Errorf("not an i3 IPC message")
+	}
+	length := binary.NativeEndian.Uint32(header[len(swayMagic):])
+	msgType := binary.NativeEndian.Uint32(header[len(swayMagic)+4:])
+	if length > swayMaxPayload {
+		return 0, nil, fmt.Errorf("i3 IPC message of %d bytes is too large", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return msgType, payload, nil
+}
+
+// request sends one message and returns the payload of its reply.
+func (c *swayIPC) request(msgType uint32, payload []byte) ([]byte, error) {
+	c.reqMu.Lock()
+	defer c.reqMu.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("unix", c.path, swayTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to sway: %w", err)
+		}
+		c.conn = conn
+	}
+
+	reply, err := func() ([]byte, error) {
+		c.conn.SetDeadline(time.Now().Add(swayTimeout))
+		if err := writeSwayMessage(c.conn, msgType, payload); err != nil {
+			return nil, err
+		}
+		replyType, reply, err := readSwayMessage(c.conn)
+		if err != nil {
+			return nil, err
+		}
+		if replyType != msgType {
+			return nil, fmt.Errorf("reply of type %d to a request of type %d", replyType, msgType)
+		}
+		return reply, nil
+	}()
+	if err != nil {
+		// The connection may be out of step; start afresh next time.
+		c.conn.Close()
+		c.conn = nil
+		return nil, fmt.Errorf("sway IPC request failed: %w", err)
+	}
+	return reply, nil
+}
+
+// focusedWindow returns the focused window, as last described by an event
+// or, failing that, found in the tree.
+func (c *swayIPC) focusedWindow() (*window.WindowInfo, error) {
+	c.mu.Lock()
+	if c.cached != nil {
+		info := *c.cached
+		c.mu.Unlock()
+		return &info, nil
+	}
+	c.mu.Unlock()
+
+	tree, err := c.request(swayGetTree, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseSwayTree(string(tree))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.output = info.Output
+	// Only cache while events can say when the answer goes stale.
+	if c.listener != nil {
+		cached := *info
+		c.cached = &cached
+	}
+	c.mu.Unlock()
+	return info, nil
+}
+
+// listen follows sway's window and workspace events until Close,
+// reconnecting when the connection drops.
+func (c *swayIPC) listen() {
+	for {
+		if conn, err := c.subscribe(); err == nil {
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				conn.Close()
+				return
+			}
+			c.listener = conn
+			c.mu.Unlock()
+
+			c.readEvents(conn)
+
+			c.mu.Lock()
+			c.listener = nil
+			c.cached = nil
+			c.mu.Unlock()
+			conn.Close()
+		}
+
+		select {
+		case <-c.done:
+			return
+		case <-time.After(ipcRetry):
+		}
+	}
+}
+
+func (c *swayIPC) subscribe() (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", c.path, swayTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(swayTimeout))
+	if err := writeSwayMessage(conn, swaySubscribe, []byte(`["window","workspace","shutdown"]`)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_, reply, err := readSwayMessage(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if json.Unmarshal(reply, &result) != nil || !result.Success {
+		conn.Close()
+		return nil, fmt.Errorf("sway refused the event subscription")
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// readEvents keeps the cached window current: a window event about the
+// focused container, such as window::focus or window::title, describes it
+// in full but for its output, which is the one of the focused workspace as
+// found in the tree or told by the last workspace event. Any other window
+// or workspace event, or a window moving, may have moved focus away, so the
+// next poll asks again.
+func (c *swayIPC) readEvents(conn net.Conn) {
+	for {
+		msgType, payload, err := readSwayMessage(conn)
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case swayEventWindow:
+			var event struct {
+				Change    string    `json:"change"`
+				Container *swayNode `json:"container"`
+			}
+			var info *window.WindowInfo
+			c.mu.Lock()
+			if json.Unmarshal(payload, &event) == nil && event.Container != nil &&
+				event.Container.Focused && event.Change != "close" && event.Change != "move" {
+				info = event.Container.windowInfo()
+				info.Output = c.output
+			}
+			c.cached = info
+			c.mu.Unlock()
+		case swayEventWorkspace:
+			var event struct {
+				Current *swayNode `json:"current"`
+			}
+			c.mu.Lock()
+			c.cached = nil
+			if json.Unmarshal(payload, &event) == nil && event.Current != nil && event.Current.Output != "" {
+				c.output = event.Current.Output
+			}
+			c.mu.Unlock()
+		case swayEventShutdown:
+			return
+		}
+	}
+}
+
+func (c *swayIPC) Close() {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		if c.listener != nil {
+			c.listener.Close()
+		}
+	}
+	c.mu.Unlock()
+
+	c.reqMu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.reqMu.Unlock()
+}
+
+func (d *Detector) getFocusedWindowSway() (*window.WindowInfo, error) {
+	if d.sway == nil {
+		return nil, fmt.Errorf("sway IPC socket not found")
+	}
+	return d.sway.focusedWindow()
+}
+
+// swayNode is the part of a node of sway's tree used here. XWayland windows
+// have a null app_id and their class under window_properties.
+type swayNode struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	AppID            string `json:"app_id"`
+	PID              int    `json:"pid"`
+	Focused          bool   `json:"focused"`
+	FullscreenMode   int    `json:"fullscreen_mode"`
+	Shell            string `json:"shell"`
+	Output           string `json:"output"`
+	WindowProperties struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Rect struct {
+		X      int `json:"x"`
+		Y      int `json:"y"`
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"rect"`
+	Nodes         []*swayNode `json:"nodes"`
+	FloatingNodes []*swayNode `json:"floating_nodes"`
+}
+
+// focusedNode returns the focused node under n, or nil, and the output it
+// is on: the output node above it, or the output a workspace names.
+func (n *swayNode) focusedNode(output string) (*swayNode, string) {
+	if n.Type == "output" {
+		output = n.Name
+	} else if n.Type == "workspace" && n.Output != "" {
+		output = n.Output
+	}
+	if n.Focused {
+		return n, output
+	}
+	for _, children := range [][]*swayNode{n.Nodes, n.FloatingNodes} {
+		for _, child := range children {
+			if child == nil {
+				continue
+			}
+			if found, output := child.focusedNode(output); found != nil {
+				return found, output
+			}
+		}
+	}
+	return nil, ""
+}
+
+// windowInfo describes the window of a node. A focused workspace or output,
+// which sway reports when no window has focus, gives an Unknown window.
+func (n *swayNode) windowInfo() *window.WindowInfo {
+	appName, windowTitle := "Unknown", "Unknown"
+	var geometry window.Geometry
+	isWindow := n.Type == "" || n.Type == "con" || n.Type == "floating_con"
+	if isWindow {
+		if n.AppID != "" {
+			appName = n.AppID
+		} else if n.WindowProperties.Class != "" {
+			appName = n.WindowProperties.Class
+		}
+		if n.Name != "" {
+			windowTitle = n.Name
+		}
+		geometry = window.Geometry{X: n.Rect.X, Y: n.Rect.Y, Width: n.Rect.Width, Height: n.Rect.Height}
+	}
+
+	processName, pid := appName, 0
+	if isWindow && n.PID > 0 {
+		pid = n.PID
+		if name := getProcessName(strconv.Itoa(n.PID)); name != "" {
+			processName = name
+		}
+	}
+
+	return &window.WindowInfo{
+		AppName:       appName,
+		WindowTitle:   windowTitle,
+		ProcessName:   processName,
+		PID:           pid,
+		Geometry:      geometry,
+		IsFullscreen:  isWindow && n.FullscreenMode > 0,
+		DisplayServer: "wayland",
+		Client:        swayClient(n.Shell),
+	}
+}
+
+// parseSwayTree finds the focused window in sway's GET_TREE reply. A tree
+// without a focused node gives an Unknown window.
+func parseSwayTree(jsonOutput string) (*window.WindowInfo, error) {
+	var root swayNode
+	if err := json.Unmarshal([]byte(jsonOutput), &root); err != nil {
+		return nil, fmt.Errorf("invalid sway tree: %w", err)
+	}
+	if focused, output := root.focusedNode(""); focused != nil {
+		info := focused.windowInfo()
+		info.Output = output
+		return info, nil
+	}
+	return (&swayNode{Type: "root"}).windowInfo(), nil
+}
+
+// parseSwayShell returns the "shell" of the focused node: "xdg_shell" for
+// native clients, "xwayland" for X11 ones, or "" when sway doesn't say.
+func parseSwayShell(jsonOutput string) string {
+	var root swayNode
+	if err := json.Unmarshal([]byte(jsonOutput), &root); err != nil {
+		return ""
+	}
+	if focused, _ := root.focusedNode(""); focused != nil {
+		return focused.Shell
+	}
+	return ""
+}
+
+func swayClient(shell string) string {
+	switch shell {
+	case "":
+		return ""
+	case "xwayland":
+		return window.ClientXWayland
+	default:
+		return window.ClientNative
+	}
+}
@@ -0,0 +1,209 @@
+package wayland
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const swayTestTree = `{
+	"type": "root",
+	"nodes": [{
+		"type": "output",
+		"name": "HEADLESS-1",
+		"nodes": [{
+			"type": "workspace",
+			"name": "1",
+			"nodes": [{"type": "con", "name": "unfocused", "app_id": "foot", "focused": false}],
+			"floating_nodes": [{
+				"type": "floating_con",
+				"focused": true,
+				"name": "Issue \"#12\" - GitHub",
+				"app_id": null,
+				"shell": "xwayland",
+				"window_properties": {"class": "Firefox"},
+				"rect": {"x": 5, "y": 10, "width": 800, "height": 600},
+				"fullscreen_mode": 0
+			}]
+		}]
+	}]
+}`
+
+func TestParseSwayTreeNested(t *testing.T) {
+	info, err := parseSwayTree(swayTestTree)
+	if err != nil {
+		t.Fatalf("parseSwayTree() error = %v", err)
+	}
+	if info.AppName != "Firefox" || info.WindowTitle != `Issue "#12" - GitHub` {
+		t.Errorf("window = %q %q", info.AppName, info.WindowTitle)
+	}
+	if info.Client != window.ClientXWayland {
+		t.Errorf("Client = %q, want %s", info.Client, window.ClientXWayland)
+	}
+	if want := (window.Geometry{X: 5, Y: 10, Width: 800, Height: 600}); info.Geometry != want {
+		t.Errorf("Geometry = %+v, want %+v", info.Geometry, want)
+	}
+	if info.Output != "HEADLESS-1" {
+		t.Errorf("Output = %q, want HEADLESS-1", info.Output)
+	}
+}
+
+func TestParseSwayTreeEmptyWorkspace(t *testing.T) {
+	info, err := parseSwayTree(`{"type": "root", "nodes": [{"type": "workspace", "name": "3", "focused": true}]}`)
+	if err != nil {
+		t.Fatalf("parseSwayTree() error = %v", err)
+	}
+	if info.AppName != "Unknown" || info.WindowTitle != "Unknown" {
+		t.Errorf("window = %q %q, want Unknown for a focused workspace", info.AppName, info.WindowTitle)
+	}
+
+	if _, err := parseSwayTree("not json"); err == nil {
+		t.Error("parseSwayTree() accepted invalid JSON")
+	}
+}
+
+// fakeSway answers GET_TREE with tree and hands over the connection of a
+// client that subscribes to events.
+func fakeSway(t *testing.T, tree string, requests *atomic.Int32) (path string, events <-chan net.Conn) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "actionsum-sway")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path = filepath.Join(dir, "sway-ipc.sock")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	subscribed := make(chan net.Conn, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					msgType, _, err := readSwayMessage(conn)
+					if err != nil {
+						conn.Close()
+						return
+					}
+					switch msgType {
+					case swayGetTree:
+						requests.Add(1)
+						writeSwayMessage(conn, swayGetTree, []byte(tree))
+					case swaySubscribe:
+						writeSwayMessage(conn, swaySubscribe, []byte(`{"success": true}`))
+						subscribed <- conn
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return path, subscribed
+}
+
+func waitUntil(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSwayIPC(t *testing.T) {
+	var requests atomic.Int32
+	path, events := fakeSway(t, swayTestTree, &requests)
+
+	c := newSwayIPC(path)
+	defer c.Close()
+
+	var conn net.Conn
+	select {
+	case conn = <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not subscribe to events")
+	}
+	waitUntil(t, "the subscription", func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.listener != nil
+	})
+
+	for range 2 {
+		info, err := c.focusedWindow()
+		if err != nil {
+			t.Fatalf("focusedWindow() error = %v", err)
+		}
+		if info.AppName != "Firefox" {
+			t.Errorf("AppName = %q, want Firefox", info.AppName)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GET_TREE requests = %d, want 1", got)
+	}
+
+	// A focus event describes the new window; no request is needed.
+	writeSwayMessage(conn, swayEventWindow, []byte(`{"change": "focus", "container": {"type": "con", "focused": true, "name": "notes", "app_id": "foot", "shell": "xdg_shell"}}`))
+	waitUntil(t, "the focus event", func() bool {
+		info, err := c.focusedWindow()
+		return err == nil && info.AppName == "foot" && info.Output == "HEADLESS-1"
+	})
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GET_TREE requests after window::focus = %d, want 1", got)
+	}
+
+	// Focus crossing to another output switches workspace first.
+	writeSwayMessage(conn, swayEventWorkspace, []byte(`{"change": "focus", "current": {"type": "workspace", "name": "2", "output": "DP-2"}}`))
+	writeSwayMessage(conn, swayEventWindow, []byte(`{"change": "focus", "container": {"type": "con", "focused": true, "name": "mail", "app_id": "thunderbird"}}`))
+	waitUntil(t, "the focus event on DP-2", func() bool {
+		info, err := c.focusedWindow()
+		return err == nil && info.AppName == "thunderbird" && info.Output == "DP-2"
+	})
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GET_TREE requests after crossing outputs = %d, want 1", got)
+	}
+
+	// Switching to another workspace may leave nothing focused.
+	writeSwayMessage(conn, swayEventWorkspace, []byte(`{"change": "focus"}`))
+	waitUntil(t, "the workspace event", func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.cached == nil
+	})
+	if _, err := c.focusedWindow(); err != nil {
+		t.Fatalf("focusedWindow() error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("GET_TREE requests after workspace::focus = %d, want 2", got)
+	}
+}
+
+func TestSwaySocketPath(t *testing.T) {
+	t.Setenv("SWAYSOCK", "")
+	t.Setenv("I3SOCK", "")
+	if _, err := swaySocketPath(); err == nil {
+		t.Error("swaySocketPath() found a socket with neither variable set")
+	}
+
+	var requests atomic.Int32
+	path, _ := fakeSway(t, "{}", &requests)
+	t.Setenv("I3SOCK", path)
+	if got, err := swaySocketPath(); err != nil || got != path {
+		t.Errorf("swaySocketPath() = %q, %v; want %q", got, err, path)
+	}
+}
@@ -0,0 +1,104 @@
+// Package windows detects the focused window and idle time on Windows
+// through the user32 and kernel32 APIs.
+package windows
+
+import "github.com/actionsum/actionsum/pkg/window"
+
+// DisplayServer is what events recorded on Windows give as their display
+// server.
+const DisplayServer = "windows"
+
+// idleThreshold is how many seconds without input count as idle, as on the
+// other backends.
+const idleThreshold = 300
+
+// foregroundWindow is the foreground window as reported by the platform
+// layer.
+type foregroundWindow struct {
+	processName string
+	title       string
+	geometry    window.Geometry
+	fullscreen  bool
+	maximized   bool
+}
+
+type Detector struct {
+	// locks follows the session's lock notifications, or is nil.
+	locks *lockWatcher
+}
+
+func NewDetector() *Detector {
+	return &Detector{locks: watchLocks()}
+}
+
+func (d *Detector) GetDisplayServer() string {
+	return DisplayServer
+}
+
+func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
+	front, err := focusedWindow()
+	if err != nil {
+		return nil, err
+	}
+
+	appName := front.processName
+	if appName == "" {
+		appName = "Unknown"
+	}
+
+	return &window.WindowInfo{
+		AppName:         appName,
+		WindowTitle:     front.title,
+		ProcessName:     front.processName,
+		DisplayServer:   DisplayServer,
+		Geometry:        front.geometry,
+		IsFullscreen:    front.fullscreen,
+		IsMaximized:     front.maximized,
+		DetectionMethod: "window",
+		Confidence:      1.0,
+	}, nil
+}
+
+func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
+	idleTime, err := idleSeconds()
+	if err != nil {
+		return nil, err
+	}
+
+	return &window.IdleInfo{
+		IsIdle:   idleTime > idleThreshold,
+		IsLocked: d.isLocked(),
+		IdleTime: idleTime,
+	}, nil
+}
+
+// isLocked trusts the last session notification over the input desktop,
+// which UAC prompts also make inaccessible.
+func (d *Detector) isLocked() bool {
+	if d.locks != nil {
+		if locked, known := d.locks.state(); known {
+			return locked
+		}
+	}
+	return screenLocked()
+}
+
+// LockChanges returns the session's lock transitions, or nil when session
+// notifications are unavailable.
+func (d *Detector) LockChanges() <-chan bool {
+	if d.locks == nil {
+		return nil
+	}
+	return d.locks.changes
+}
+
+func (d *Detector) IsAvailable() bool {
+	return available()
+}
+
+func (d *Detector) Close() error {
+	if d.locks != nil {
+		d.locks.Close()
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package windows
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+func TestDetector(t *testing.T) {
+	var _ window.Detector = (*Detector)(nil)
+
+	detector := NewDetector()
+	if got := detector.GetDisplayServer(); got != DisplayServer {
+		t.Errorf("GetDisplayServer() = %s, want %s", got, DisplayServer)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Logf("Windows detector available: %v", detector.IsAvailable())
+		return
+	}
+	if detector.IsAvailable() {
+		t.Error("IsAvailable() = true on " + runtime.GOOS)
+	}
+	if _, err := detector.GetFocusedWindow(); err == nil {
+		t.Error("GetFocusedWindow() succeeded on " + runtime.GOOS)
+	}
+	if _, err := detector.GetIdleInfo(); err == nil {
+		t.Error("GetIdleInfo() succeeded on " + runtime.GOOS)
+	}
+}
+
+func TestLockWatcher(t *testing.T) {
+	w := newLockWatcher()
+	if _, known := w.state(); known {
+		t.Error("state() known before any notification")
+	}
+
+	w.announce(true)
+	w.announce(true)
+	w.announce(false)
+	if locked, known := w.state(); !known || locked {
+		t.Errorf("state() = %v, %v; want unlocked", locked, known)
+	}
+
+	var got []bool
+	for len(w.changes) > 0 {
+		got = append(got, <-w.changes)
+	}
+	if len(got) != 2 || !got[0] || got[1] {
+		t.Errorf("changes = %v, want [true false]", got)
+	}
+}
@@ -0,0 +1,52 @@
+package windows
+
+import (
+	"log"
+	"sync"
+)
+
+// lockWatcher passes on the lock and unlock notifications Windows sends for
+// the session, so locking is recorded when it happens rather than at the
+// next poll, and remembers the latest.
+type lockWatcher struct {
+	changes chan bool
+	stop    func()
+
+	mu     sync.Mutex
+	known  bool
+	locked bool
+}
+
+func newLockWatcher() *lockWatcher {
+	return &lockWatcher{changes: make(chan bool, 8)}
+}
+
+func (w *lockWatcher) announce(locked bool) {
+	w.mu.Lock()
+	duplicate := w.known && w.locked == locked
+	w.known, w.locked = true, locked
+	w.mu.Unlock()
+	if duplicate {
+		return
+	}
+
+	select {
+	case w.changes <- locked:
+	default:
+		log.Printf("Dropping screen lock change: listener is not keeping up")
+	}
+}
+
+// state returns the lock state last announced, and false before the first
+// notification.
+func (w *lockWatcher) state() (locked bool, known bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.locked, w.known
+}
+
+func (w *lockWatcher) Close() {
+	if w.stop != nil {
+		w.stop()
+	}
+}
@@ -0,0 +1,188 @@
+//go:build windows
+
+package windows
+
+import (
+	"log"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	wtsapi32 = syscall.NewLazyDLL("wtsapi32.dll")
+
+	procWTSRegisterSessionNotification   = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegisterSessionNotification = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+
+	procRegisterClassExW = user32.NewProc("RegisterClassExW")
+	procCreateWindowExW  = user32.NewProc("CreateWindowExW")
+	procDestroyWindow    = user32.NewProc("DestroyWindow")
+	procDefWindowProcW   = user32.NewProc("DefWindowProcW")
+	procGetMessageW      = user32.NewProc("GetMessageW")
+	procDispatchMessageW = user32.NewProc("DispatchMessageW")
+	procPostMessageW     = user32.NewProc("PostMessageW")
+	procPostQuitMessage  = user32.NewProc("PostQuitMessage")
+
+	procGetModuleHandleW = kernel32.NewProc("GetModuleHandleW")
+)
+
+const (
+	wmDestroy          = 0x0002
+	wmClose            = 0x0010
+	wmWTSSessionChange = 0x02B1
+
+	wtsSessionLock   = 0x7
+	wtsSessionUnlock = 0x8
+
+	notifyForThisSession    = 0
+	errorClassAlreadyExists = 1410
+)
+
+// hwndMessage is HWND_MESSAGE, the parent of message-only windows.
+var hwndMessage = ^uintptr(2)
+
+type wndClassEx struct {
+	Size       uint32
+	Style      uint32
+	WndProc    uintptr
+	ClsExtra   int32
+	WndExtra   int32
+	Instance   uintptr
+	Icon       uintptr
+	Cursor     uintptr
+	Background uintptr
+	MenuName   *uint16
+	ClassName  *uint16
+	IconSm     uintptr
+}
+
+type msg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+	Private uint32
+}
+
+var (
+	// sessionWindows maps each session window to its watcher, as the
+	// window procedure is one callback for all of them.
+	sessionMu      sync.Mutex
+	sessionWindows = map[uintptr]*lockWatcher{}
+
+	wndProcOnce sync.Once
+	wndProc     uintptr
+)
+
+// watchLocks registers a hidden message-only window for session change
+// notifications (WTSRegisterSessionNotification) and pumps its messages on a
+// dedicated OS thread. It returns nil when that fails, e.g. without the
+// Remote Desktop Services API.
+func watchLocks() *lockWatcher {
+	if wtsapi32.Load() != nil {
+		return nil
+	}
+	wndProcOnce.Do(func() {
+		wndProc = syscall.NewCallback(sessionWndProc)
+	})
+
+	w := newLockWatcher()
+	ready := make(chan uintptr)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hwnd := createSessionWindow()
+		if hwnd == 0 {
+			ready <- 0
+			return
+		}
+		if ok, _, err := procWTSRegisterSessionNotification.Call(hwnd, notifyForThisSession); ok == 0 {
+			log.Printf("Failed to register for session notifications: %v", err)
+			procDestroyWindow.Call(hwnd)
+			ready <- 0
+			return
+		}
+		sessionMu.Lock()
+		sessionWindows[hwnd] = w
+		sessionMu.Unlock()
+		ready <- hwnd
+
+		var m msg
+		for {
+			// GetMessageW returns 0 for WM_QUIT and -1 on errors.
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				break
+			}
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
+		}
+
+		sessionMu.Lock()
+		delete(sessionWindows, hwnd)
+		sessionMu.Unlock()
+	}()
+
+	hwnd := <-ready
+	if hwnd == 0 {
+		return nil
+	}
+	w.stop = func() {
+		procPostMessageW.Call(hwnd, wmClose, 0, 0)
+	}
+	return w
+}
+
+func createSessionWindow() uintptr {
+	instance, _, _ := procGetModuleHandleW.Call(0)
+	className, _ := syscall.UTF16PtrFromString("actionsumSessionWatcher")
+	class := wndClassEx{
+		Size:      uint32(unsafe.Sizeof(wndClassEx{})),
+		WndProc:   wndProc,
+		Instance:  instance,
+		ClassName: className,
+	}
+	if atom, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&class))); atom == 0 {
+		if errno, ok := err.(syscall.Errno); !ok || errno != errorClassAlreadyExists {
+			log.Printf("Failed to register session window class: %v", err)
+			return 0
+		}
+	}
+
+	hwnd, _, err := procCreateWindowExW.Call(0, uintptr(unsafe.Pointer(className)), 0, 0, 0, 0, 0, 0, hwndMessage, 0, instance, 0)
+	if hwnd == 0 {
+		log.Printf("Failed to create session window: %v", err)
+	}
+	return hwnd
+}
+
+func sessionWndProc(hwnd, message, wParam, lParam uintptr) uintptr {
+	switch message {
+	case wmWTSSessionChange:
+		sessionMu.Lock()
+		w := sessionWindows[hwnd]
+		sessionMu.Unlock()
+		if w != nil {
+			switch wParam {
+			case wtsSessionLock:
+				w.announce(true)
+			case wtsSessionUnlock:
+				w.announce(false)
+			}
+		}
+		return 0
+	case wmClose:
+		procWTSUnRegisterSessionNotification.Call(hwnd)
+		procDestroyWindow.Call(hwnd)
+		return 0
+	case wmDestroy:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	ret, _, _ := procDefWindowProcW.Call(hwnd, message, wParam, lParam)
+	return ret
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package windows
+
+import "fmt"
+
+// errUnsupported is returned on other systems.
+var errUnsupported = fmt.Errorf("Windows window detection only works on Windows")
+
+func focusedWindow() (*foregroundWindow, error) {
+	return nil, errUnsupported
+}
+
+func idleSeconds() (int64, error) {
+	return 0, errUnsupported
+}
+
+func screenLocked() bool {
+	return false
+}
+
+func watchLocks() *lockWatcher {
+	return nil
+}
+
+func available() bool {
+	return false
+}
@@ -0,0 +1,150 @@
+//go:build windows
+
+package windows
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procGetWindowTextLengthW     = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procGetWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
+	procGetWindowRect            = user32.NewProc("GetWindowRect")
+	procIsZoomed                 = user32.NewProc("IsZoomed")
+	procMonitorFromWindow        = user32.NewProc("MonitorFromWindow")
+	procGetMonitorInfoW          = user32.NewProc("GetMonitorInfoW")
+	procGetLastInputInfo         = user32.NewProc("GetLastInputInfo")
+	procOpenInputDesktop         = user32.NewProc("OpenInputDesktop")
+	procCloseDesktop             = user32.NewProc("CloseDesktop")
+
+	procGetTickCount               = kernel32.NewProc("GetTickCount")
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	monitorDefaultToNearest        = 2
+	desktopSwitchDesktop           = 0x0100
+)
+
+type rect struct {
+	Left, Top, Right, Bottom int32
+}
+
+type monitorInfo struct {
+	Size    uint32
+	Monitor rect
+	Work    rect
+	Flags   uint32
+}
+
+type lastInputInfo struct {
+	Size uint32
+	Time uint32
+}
+
+func focusedWindow() (*foregroundWindow, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		// The lock screen, UAC prompts and session 0 have no foreground
+		// window of the user's.
+		return nil, fmt.Errorf("no foreground window")
+	}
+
+	front := &foregroundWindow{title: windowText(hwnd)}
+
+	var pid uint32
+	procGetWindowThreadProcessID.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	if pid != 0 {
+		if image, err := processImage(pid); err == nil {
+			front.processName = strings.TrimSuffix(strings.ToLower(filepath.Base(image)), ".exe")
+		}
+	}
+
+	var bounds rect
+	if ok, _, _ := procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&bounds))); ok != 0 {
+		front.geometry = window.Geometry{
+			X:      int(bounds.Left),
+			Y:      int(bounds.Top),
+			Width:  int(bounds.Right - bounds.Left),
+			Height: int(bounds.Bottom - bounds.Top),
+		}
+		// A window covering its whole monitor, taskbar included, is
+		// fullscreen.
+		if monitor, _, _ := procMonitorFromWindow.Call(hwnd, monitorDefaultToNearest); monitor != 0 {
+			info := monitorInfo{Size: uint32(unsafe.Sizeof(monitorInfo{}))}
+			if ok, _, _ := procGetMonitorInfoW.Call(monitor, uintptr(unsafe.Pointer(&info))); ok != 0 {
+				front.fullscreen = bounds == info.Monitor
+			}
+		}
+	}
+
+	zoomed, _, _ := procIsZoomed.Call(hwnd)
+	front.maximized = zoomed != 0 && !front.fullscreen
+
+	return front, nil
+}
+
+func windowText(hwnd uintptr) string {
+	length, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if length == 0 {
+		return ""
+	}
+	buf := make([]uint16, length+1)
+	copied, _, _ := procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf[:copied])
+}
+
+// processImage returns the path of the executable running as pid.
+func processImage(pid uint32) (string, error) {
+	process, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer syscall.CloseHandle(process)
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	ok, _, err := procQueryFullProcessImageNameW.Call(uintptr(process), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if ok == 0 {
+		return "", fmt.Errorf("failed to get image of process %d: %w", pid, err)
+	}
+	return syscall.UTF16ToString(buf[:size]), nil
+}
+
+func idleSeconds() (int64, error) {
+	info := lastInputInfo{Size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if ok, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0, fmt.Errorf("failed to get last input time: %w", err)
+	}
+	now, _, _ := procGetTickCount.Call()
+	// Both are milliseconds since boot in 32 bits; the subtraction stays
+	// right across the wrap every 49.7 days.
+	return int64((uint32(now) - info.Time) / 1000), nil
+}
+
+// screenLocked reports whether the input desktop is the secure desktop of
+// the lock screen, which other processes cannot open.
+func screenLocked() bool {
+	desktop, _, _ := procOpenInputDesktop.Call(0, 0, desktopSwitchDesktop)
+	if desktop == 0 {
+		return true
+	}
+	procCloseDesktop.Call(desktop)
+	return false
+}
+
+func available() bool {
+	return user32.Load() == nil && kernel32.Load() == nil && procGetLastInputInfo.Find() == nil
+}
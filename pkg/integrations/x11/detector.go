@@ -2,16 +2,21 @@ package x11
 
 import (
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/actionsum/actionsum/pkg/sandbox"
 	"github.com/actionsum/actionsum/pkg/window"
 )
 
 type Detector struct {
 	hasXdotool bool
 	hasWmctrl  bool
+
+	focus       *focusMonitor
+	screenSaver screenSaver
+	randr       randr
 }
 
 func NewDetector() *Detector {
@@ -22,7 +27,7 @@ func NewDetector() *Detector {
 }
 
 func (d *Detector) commandExists(cmd string) bool {
-	_, err := exec.LookPath(cmd)
+	_, err := sandbox.LookPath(cmd)
 	return err == nil
 }
 
@@ -36,6 +41,24 @@ func (d *Detector) IsAvailable() bool {
 	return false
 }
 
+// WatchFocus starts listening for changes of the active window, reported by
+// FocusChanges. It returns false when xprop is missing or fails to start.
+func (d *Detector) WatchFocus() bool {
+	if d.focus == nil && d.commandExists("xprop") {
+		d.focus = newFocusMonitor()
+	}
+	return d.focus != nil
+}
+
+// FocusChanges signals changes of the active window once WatchFocus has
+// been called, or returns nil. The channel is closed if xprop exits.
+func (d *Detector) FocusChanges() <-chan struct{} {
+	if d.focus == nil {
+		return nil
+	}
+	return d.focus.changes
+}
+
 func (d *Detector) GetDisplayServer() string {
 	return "x11"
 }
@@ -51,7 +74,7 @@ func (d *Detector) GetFocusedWindow() (*window.WindowInfo, error) {
 }
 
 func (d *Detector) getFocusedWindowXdotool() (*window.WindowInfo, error) {
-	windowIDCmd := exec.Command("xdotool", "getactivewindow")
+	windowIDCmd := sandbox.Command("xdotool", "getactivewindow")
 	windowIDOutput, err := windowIDCmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active x11 window ID: %w", err)
@@ -59,7 +82,7 @@ func (d *Detector) getFocusedWindowXdotool() (*window.WindowInfo, error) {
 
 	windowID := strings.TrimSpace(string(windowIDOutput))
 
-	windowNameCmd := exec.Command("xdotool", "getwindowname", windowID)
+	windowNameCmd := sandbox.Command("xdotool", "getwindowname", windowID)
 	windowNameOutput, err := windowNameCmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get window name: %w", err)
@@ -69,19 +92,21 @@ func (d *Detector) getFocusedWindowXdotool() (*window.WindowInfo, error) {
 
 	appName := "Unknown"
 	processName := ""
+	pidNumber := 0
 
-	classCmd := exec.Command("xprop", "-id", windowID, "WM_CLASS")
+	classCmd := sandbox.Command("xprop", "-id", windowID, "WM_CLASS")
 	if classOutput, err := classCmd.Output(); err == nil {
 		if class := parseWMClass(string(classOutput)); class != "" {
 			appName = class
 		}
 	}
 
-	pidCmd := exec.Command("xdotool", "getwindowpid", windowID)
+	pidCmd := sandbox.Command("xdotool", "getwindowpid", windowID)
 	if pidOutput, err := pidCmd.Output(); err == nil {
 		pid := strings.TrimSpace(string(pidOutput))
+		pidNumber, _ = strconv.Atoi(pid)
 
-		psCmd := exec.Command("ps", "-p", pid, "-o", "comm=")
+		psCmd := sandbox.Command("ps", "-p", pid, "-o", "comm=")
 		if psOutput, err := psCmd.Output(); err == nil {
 			processName = strings.TrimSpace(string(psOutput))
 			if appName == "Unknown" && processName != "" {
@@ -90,22 +115,45 @@ func (d *Detector) getFocusedWindowXdotool() (*window.WindowInfo, error) {
 		}
 	}
 
-	return &window.WindowInfo{
+	info := &window.WindowInfo{
 		AppName:       appName,
 		WindowTitle:   windowTitle,
 		ProcessName:   processName,
+		PID:           pidNumber,
 		DisplayServer: "x11",
-	}, nil
+	}
+
+	geometryCmd := sandbox.Command("xdotool", "getwindowgeometry", "--shell", windowID)
+	if geometryOutput, err := geometryCmd.Output(); err == nil {
+		info.Geometry = parseXdotoolGeometry(string(geometryOutput))
+	}
+	if info.Geometry.Width > 0 && info.Geometry.Height > 0 {
+		if output, err := d.randr.OutputAt(center(info.Geometry)); err == nil {
+			info.Output = output
+		}
+	}
+
+	d.fillWindowState(info, windowID)
+
+	return info, nil
+}
+
+// fillWindowState reads _NET_WM_STATE to flag fullscreen and maximized windows.
+func (d *Detector) fillWindowState(info *window.WindowInfo, windowID string) {
+	stateCmd := sandbox.Command("xprop", "-id", windowID, "_NET_WM_STATE")
+	if stateOutput, err := stateCmd.Output(); err == nil {
+		info.IsFullscreen, info.IsMaximized = parseNetWMState(string(stateOutput))
+	}
 }
 
 func (d *Detector) getFocusedWindowWmctrl() (*window.WindowInfo, error) {
-	cmd := exec.Command("wmctrl", "-l", "-p")
+	cmd := sandbox.Command("wmctrl", "-l", "-p")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute wmctrl: %w", err)
 	}
 
-	activeWindowCmd := exec.Command("xdotool", "getactivewindow")
+	activeWindowCmd := sandbox.Command("xdotool", "getactivewindow")
 	activeWindowOutput, err := activeWindowCmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active window: %w", err)
@@ -124,19 +172,22 @@ func (d *Detector) getFocusedWindowWmctrl() (*window.WindowInfo, error) {
 			pid := fields[2]
 			windowTitle := strings.Join(fields[4:], " ")
 
-			psCmd := exec.Command("ps", "-p", pid, "-o", "comm=")
+			psCmd := sandbox.Command("ps", "-p", pid, "-o", "comm=")
 			psOutput, err := psCmd.Output()
 			processName := "Unknown"
 			if err == nil {
 				processName = strings.TrimSpace(string(psOutput))
 			}
 
-			return &window.WindowInfo{
+			info := &window.WindowInfo{
 				AppName:       processName,
 				WindowTitle:   windowTitle,
 				ProcessName:   processName,
 				DisplayServer: "x11",
-			}, nil
+			}
+			d.fillWindowState(info, activeWindowID)
+
+			return info, nil
 		}
 	}
 
@@ -144,7 +195,7 @@ func (d *Detector) getFocusedWindowWmctrl() (*window.WindowInfo, error) {
 }
 
 func parseWMClass(output string) string {
-	parts := strings.Split(output, "=")
+	parts := strings.SplitN(output, "=", 2)
 	if len(parts) < 2 {
 		return ""
 	}
@@ -162,6 +213,56 @@ func parseWMClass(output string) string {
 	return ""
 }
 
+// parseNetWMState reports whether an xprop _NET_WM_STATE line contains the
+// fullscreen atom and both maximized atoms.
+func parseNetWMState(output string) (fullscreen bool, maximized bool) {
+	parts := strings.SplitN(output, "=", 2)
+	if len(parts) < 2 {
+		return false, false
+	}
+
+	var vert, horz bool
+	for _, atom := range strings.Split(parts[1], ",") {
+		switch strings.TrimSpace(atom) {
+		case "_NET_WM_STATE_FULLSCREEN":
+			fullscreen = true
+		case "_NET_WM_STATE_MAXIMIZED_VERT":
+			vert = true
+		case "_NET_WM_STATE_MAXIMIZED_HORZ":
+			horz = true
+		}
+	}
+
+	return fullscreen, vert && horz
+}
+
+// parseXdotoolGeometry parses the KEY=value output of
+// "xdotool getwindowgeometry --shell".
+func parseXdotoolGeometry(output string) window.Geometry {
+	var geometry window.Geometry
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "X":
+			geometry.X = n
+		case "Y":
+			geometry.Y = n
+		case "WIDTH":
+			geometry.Width = n
+		case "HEIGHT":
+			geometry.Height = n
+		}
+	}
+	return geometry
+}
+
 func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
 	idleTime, err := d.getIdleTime()
 	if err != nil {
@@ -180,9 +281,15 @@ func (d *Detector) GetIdleInfo() (*window.IdleInfo, error) {
 	}, nil
 }
 
+// getIdleTime asks the X server for the time since the last input, and
+// xprintidle when the server doesn't answer.
 func (d *Detector) getIdleTime() (int64, error) {
-	if d.hasXdotool {
-		cmd := exec.Command("xprintidle")
+	if idle, err := d.screenSaver.IdleTime(); err == nil {
+		return int64(idle / time.Second), nil
+	}
+
+	if d.commandExists("xprintidle") {
+		cmd := sandbox.Command("xprintidle")
 		output, err := cmd.Output()
 		if err != nil {
 			return 0, nil
@@ -211,7 +318,7 @@ func (d *Detector) isScreenLocked() bool {
 	}
 
 	for _, locker := range lockers {
-		cmd := exec.Command("pgrep", "-x", locker)
+		cmd := sandbox.Command("pgrep", "-x", locker)
 		if err := cmd.Run(); err == nil {
 			return true
 		}
@@ -221,5 +328,11 @@ func (d *Detector) isScreenLocked() bool {
 }
 
 func (d *Detector) Close() error {
+	if d.focus != nil {
+		d.focus.Close()
+		d.focus = nil
+	}
+	d.screenSaver.Close()
+	d.randr.Close()
 	return nil
 }
@@ -145,6 +145,58 @@ func TestParseWMClass(t *testing.T) {
 	}
 }
 
+func TestParseNetWMState(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantFullscreen bool
+		wantMaximized  bool
+	}{
+		{
+			name:           "Fullscreen",
+			input:          "_NET_WM_STATE(ATOM) = _NET_WM_STATE_FULLSCREEN",
+			wantFullscreen: true,
+		},
+		{
+			name:          "Maximized",
+			input:         "_NET_WM_STATE(ATOM) = _NET_WM_STATE_MAXIMIZED_VERT, _NET_WM_STATE_MAXIMIZED_HORZ",
+			wantMaximized: true,
+		},
+		{
+			name:  "Only vertically maximized",
+			input: "_NET_WM_STATE(ATOM) = _NET_WM_STATE_MAXIMIZED_VERT",
+		},
+		{
+			name:  "No state",
+			input: "_NET_WM_STATE(ATOM) =",
+		},
+		{
+			name:  "Property missing",
+			input: "_NET_WM_STATE:  not found.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullscreen, maximized := parseNetWMState(tt.input)
+			if fullscreen != tt.wantFullscreen || maximized != tt.wantMaximized {
+				t.Errorf("parseNetWMState(%q) = (%v, %v), want (%v, %v)",
+					tt.input, fullscreen, maximized, tt.wantFullscreen, tt.wantMaximized)
+			}
+		})
+	}
+}
+
+func TestParseXdotoolGeometry(t *testing.T) {
+	output := "WINDOW=48234505\nX=10\nY=42\nWIDTH=1920\nHEIGHT=1038\nSCREEN=0\n"
+
+	geometry := parseXdotoolGeometry(output)
+	want := window.Geometry{X: 10, Y: 42, Width: 1920, Height: 1038}
+	if geometry != want {
+		t.Errorf("parseXdotoolGeometry() = %+v, want %+v", geometry, want)
+	}
+}
+
 func TestClose(t *testing.T) {
 	detector := NewDetector()
 	err := detector.Close()
@@ -0,0 +1,85 @@
+package x11
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/actionsum/actionsum/pkg/sandbox"
+)
+
+// focusMonitor runs "xprop -spy" on the root window, which prints
+// _NET_ACTIVE_WINDOW each time a PropertyNotify reports it changed, and
+// signals on changes when the active window is a different one.
+type focusMonitor struct {
+	changes chan struct{}
+	cmd     *exec.Cmd
+}
+
+func newFocusMonitor() *focusMonitor {
+	cmd := sandbox.Command("xprop", "-root", "-spy", "_NET_ACTIVE_WINDOW")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+
+	m := &focusMonitor{changes: make(chan struct{}, 1), cmd: cmd}
+	go m.read(stdout)
+	return m
+}
+
+// read signals each change of active window until xprop exits, then closes
+// changes.
+func (m *focusMonitor) read(stdout io.Reader) {
+	defer close(m.changes)
+
+	var last string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		id, ok := parseActiveWindow(scanner.Text())
+		if !ok || id == last {
+			continue
+		}
+		// The first line is the window active when xprop started.
+		first := last == ""
+		last = id
+		if first {
+			continue
+		}
+		select {
+		case m.changes <- struct{}{}:
+		default:
+			// A change is already waiting to be picked up.
+		}
+	}
+}
+
+// parseActiveWindow extracts the window ID from an xprop line such as
+// "_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00007". No active window is
+// reported as 0x0.
+func parseActiveWindow(line string) (string, bool) {
+	if !strings.HasPrefix(line, "_NET_ACTIVE_WINDOW") {
+		return "", false
+	}
+	idx := strings.LastIndex(line, "# ")
+	if idx == -1 {
+		return "", false
+	}
+	id, _, _ := strings.Cut(line[idx+2:], ",")
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+func (m *focusMonitor) Close() {
+	if m.cmd.Process != nil {
+		_ = m.cmd.Process.Kill()
+		_ = m.cmd.Wait()
+	}
+}
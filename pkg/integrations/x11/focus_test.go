@@ -0,0 +1,45 @@
+package x11
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseActiveWindow(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		{"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00007", "0x3a00007", true},
+		{"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x0", "0x0", true},
+		{"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x1e00004, 0x0", "0x1e00004", true},
+		{"_NET_ACTIVE_WINDOW:  not found.", "", false},
+		{"WM_NAME(STRING) = \"# 0x1\"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseActiveWindow(tt.line)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseActiveWindow(%q) = %q, %v; want %q, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFocusMonitorRead(t *testing.T) {
+	m := &focusMonitor{changes: make(chan struct{}, 1)}
+	m.read(strings.NewReader(strings.Join([]string{
+		"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x1",
+		"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x1",
+		"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x2",
+		"_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3",
+	}, "\n")))
+
+	// Two changes coalesce into one waiting signal, then the channel closes
+	// with xprop's output.
+	if _, ok := <-m.changes; !ok {
+		t.Fatal("no focus change signalled")
+	}
+	if _, ok := <-m.changes; ok {
+		t.Error("more than one focus change waiting, or channel left open")
+	}
+}
@@ -0,0 +1,29 @@
+package x11
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func FuzzParseWMClass(f *testing.F) {
+	f.Add("navigator", "Firefox")
+	f.Add("a=b", "Key=Value")
+	f.Add("", "")
+	f.Fuzz(func(t *testing.T, instance, class string) {
+		got := parseWMClass(fmt.Sprintf("WM_CLASS(STRING) = %q, %q", instance, class))
+
+		// xprop quotes like %q; values it would escape, or that contain the
+		// separator, aren't expected back verbatim.
+		if !strings.Contains(class, ",") && class == strings.Trim(class, " ") && fmt.Sprintf("%q", class) == `"`+class+`"` {
+			if got != class {
+				t.Errorf("parseWMClass() = %q, want %q", got, class)
+			}
+		}
+
+		// Arbitrary output must not panic.
+		parseWMClass(class)
+		parseNetWMState(class)
+		parseXdotoolGeometry(class)
+	})
+}
@@ -0,0 +1,158 @@
+//go:build integration
+
+package x11
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// These tests run the detector against a real X server: Xvfb with xterm
+// windows, and the root window properties a window manager would set written
+// by hand so no window manager is needed. Run them with
+//
+//	go test -tags integration ./pkg/integrations/...
+
+func requireTools(t *testing.T, tools ...string) {
+	t.Helper()
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+}
+
+// startXvfb starts a virtual X server on a free display and points DISPLAY
+// at it for the rest of the test.
+func startXvfb(t *testing.T) {
+	t.Helper()
+	requireTools(t, "Xvfb", "xdotool", "xprop", "xterm")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	defer r.Close()
+
+	cmd := exec.Command("Xvfb", "-displayfd", "3", "-screen", "0", "1280x800x24", "-nolisten", "tcp")
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start Xvfb: %v", err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	display := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		display <- strings.TrimSpace(line)
+	}()
+	select {
+	case number := <-display:
+		if number == "" {
+			t.Fatal("Xvfb did not report a display")
+		}
+		t.Setenv("DISPLAY", ":"+number)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Xvfb")
+	}
+
+	// Advertise _NET_ACTIVE_WINDOW as a window manager would, so xdotool
+	// reads the active window from the root window.
+	xprop(t, "-root", "-f", "_NET_SUPPORTED", "32a", "-set", "_NET_SUPPORTED", "_NET_ACTIVE_WINDOW")
+}
+
+// startXterm opens an xterm and returns its window ID once it is mapped.
+func startXterm(t *testing.T, class, title, geometry string) string {
+	t.Helper()
+	cmd := exec.Command("xterm", "-class", class, "-T", title, "-geometry", geometry, "-e", "sleep", "600")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start xterm: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	output, err := exec.Command("xdotool", "search", "--sync", "--limit", "1", "--name", "^"+title+"$").Output()
+	if err != nil {
+		t.Fatalf("xterm %q never appeared: %v", title, err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func xprop(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("xprop", args...).CombinedOutput(); err != nil {
+		t.Fatalf("xprop %v: %v: %s", args, err, output)
+	}
+}
+
+func activate(t *testing.T, windowID string) {
+	t.Helper()
+	xprop(t, "-root", "-f", "_NET_ACTIVE_WINDOW", "32x", "-set", "_NET_ACTIVE_WINDOW", windowID)
+}
+
+func TestIntegrationFocusedWindow(t *testing.T) {
+	startXvfb(t)
+	editor := startXterm(t, "ActionsumEditor", "main.go - actionsum", "80x24+10+20")
+	browser := startXterm(t, "ActionsumBrowser", "Issue #12 - GitHub", "100x30+300+200")
+
+	detector := NewDetector()
+	if !detector.IsAvailable() {
+		t.Fatal("IsAvailable() = false with xdotool installed")
+	}
+
+	activate(t, editor)
+	info, err := detector.GetFocusedWindow()
+	if err != nil {
+		t.Fatalf("GetFocusedWindow() error = %v", err)
+	}
+	if info.AppName != "ActionsumEditor" || info.WindowTitle != "main.go - actionsum" {
+		t.Errorf("editor = %q %q, want ActionsumEditor %q", info.AppName, info.WindowTitle, "main.go - actionsum")
+	}
+	if info.ProcessName != "xterm" {
+		t.Errorf("ProcessName = %q, want xterm", info.ProcessName)
+	}
+	if info.DisplayServer != "x11" {
+		t.Errorf("DisplayServer = %q, want x11", info.DisplayServer)
+	}
+	if info.Geometry.X != 10 || info.Geometry.Y != 20 || info.Geometry.Width == 0 || info.Geometry.Height == 0 {
+		t.Errorf("Geometry = %+v, want a window at 10,20", info.Geometry)
+	}
+	if info.IsFullscreen || info.IsMaximized {
+		t.Errorf("editor fullscreen=%v maximized=%v, want neither", info.IsFullscreen, info.IsMaximized)
+	}
+
+	activate(t, browser)
+	xprop(t, "-id", browser, "-f", "_NET_WM_STATE", "32a", "-set", "_NET_WM_STATE", "_NET_WM_STATE_FULLSCREEN")
+	info, err = detector.GetFocusedWindow()
+	if err != nil {
+		t.Fatalf("GetFocusedWindow() error = %v", err)
+	}
+	if info.AppName != "ActionsumBrowser" || info.WindowTitle != "Issue #12 - GitHub" {
+		t.Errorf("browser = %q %q, want ActionsumBrowser %q", info.AppName, info.WindowTitle, "Issue #12 - GitHub")
+	}
+	if !info.IsFullscreen {
+		t.Error("IsFullscreen = false after setting _NET_WM_STATE_FULLSCREEN")
+	}
+}
+
+func TestIntegrationIdleInfo(t *testing.T) {
+	startXvfb(t)
+
+	info, err := NewDetector().GetIdleInfo()
+	if err != nil {
+		t.Fatalf("GetIdleInfo() error = %v", err)
+	}
+	if info.IdleTime < 0 || info.IsLocked {
+		t.Errorf("GetIdleInfo() = %+v, want a fresh, unlocked session", info)
+	}
+}
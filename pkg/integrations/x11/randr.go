@@ -0,0 +1,160 @@
+package x11
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/actionsum/actionsum/pkg/window"
+)
+
+const (
+	opGetAtomName = 17
+
+	randrQueryVersion = 0
+	randrGetMonitors  = 42
+)
+
+// monitor is one monitor from RRGetMonitors: usually a single output, or
+// outputs combined as one through xrandr --setmonitor.
+type monitor struct {
+	name                uint32 // Atom
+	x, y, width, height int
+}
+
+// randr names the monitor a window is on through the RANDR extension, which
+// is what xrandr --listmonitors asks. Like screenSaver, it keeps its
+// connection between queries and dials again after one fails.
+type randr struct {
+	mu     sync.Mutex
+	conn   *xConn
+	opcode byte
+}
+
+// OutputAt returns the name of the monitor showing the point x, y of the
+// screen, e.g. "DP-1", or "" when none does.
+func (r *randr) OutputAt(x, y int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		conn, opcode, err := dialRandr()
+		if err != nil {
+			return "", err
+		}
+		r.conn, r.opcode = conn, opcode
+	}
+
+	name, err := r.outputAt(x, y)
+	if err != nil {
+		r.conn.Close()
+		r.conn = nil
+		return "", fmt.Errorf("failed to query monitors: %w", err)
+	}
+	return name, nil
+}
+
+// dialRandr connects to the display and checks it has RANDR 1.5, the
+// first version with monitors. The version must be asked anyway before
+// other requests, as the server answers them as the client expects.
+func dialRandr() (*xConn, byte, error) {
+	conn, err := dialX(os.Getenv("DISPLAY"))
+	if err != nil {
+		return nil, 0, err
+	}
+	opcode, ok, err := conn.queryExtension("RANDR")
+	if err == nil && !ok {
+		err = fmt.Errorf("X server has no RANDR extension")
+	}
+	if err == nil {
+		var version [8]byte
+		binary.LittleEndian.PutUint32(version[0:], 1)
+		binary.LittleEndian.PutUint32(version[4:], 5)
+		var reply []byte
+		if reply, err = conn.request(opcode, randrQueryVersion, version[:]); err == nil {
+			major, minor := binary.LittleEndian.Uint32(reply[8:]), binary.LittleEndian.Uint32(reply[12:])
+			if major < 1 || (major == 1 && minor < 5) {
+				err = fmt.Errorf("X server has RANDR %d.%d, monitors need 1.5", major, minor)
+			}
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	return conn, opcode, nil
+}
+
+func (r *randr) outputAt(x, y int) (string, error) {
+	var body [8]byte
+	binary.LittleEndian.PutUint32(body[:], r.conn.root)
+	body[4] = 1 // Only active monitors
+	reply, err := r.conn.request(r.opcode, randrGetMonitors, body[:])
+	if err != nil {
+		return "", err
+	}
+	m, ok := monitorAt(parseMonitors(reply), x, y)
+	if !ok {
+		return "", nil
+	}
+
+	var atom [4]byte
+	binary.LittleEndian.PutUint32(atom[:], m.name)
+	reply, err = r.conn.request(opGetAtomName, 0, atom[:])
+	if err != nil {
+		return "", err
+	}
+	n := int(binary.LittleEndian.Uint16(reply[8:]))
+	if len(reply) < 32+n {
+		return "", fmt.Errorf("short atom name reply")
+	}
+	return string(reply[32 : 32+n]), nil
+}
+
+// parseMonitors reads the monitors from an RRGetMonitors reply. Each is 24
+// bytes followed by its outputs.
+func parseMonitors(reply []byte) []monitor {
+	if len(reply) < 32 {
+		return nil
+	}
+	count := int(binary.LittleEndian.Uint32(reply[12:]))
+	var monitors []monitor
+	offset := 32
+	for i := 0; i < count && offset+24 <= len(reply); i++ {
+		m := reply[offset:]
+		monitors = append(monitors, monitor{
+			name:   binary.LittleEndian.Uint32(m[0:]),
+			x:      int(int16(binary.LittleEndian.Uint16(m[8:]))),
+			y:      int(int16(binary.LittleEndian.Uint16(m[10:]))),
+			width:  int(binary.LittleEndian.Uint16(m[12:])),
+			height: int(binary.LittleEndian.Uint16(m[14:])),
+		})
+		offset += 24 + 4*int(binary.LittleEndian.Uint16(m[6:]))
+	}
+	return monitors
+}
+
+// monitorAt returns the first monitor containing the point x, y.
+func monitorAt(monitors []monitor, x, y int) (monitor, bool) {
+	for _, m := range monitors {
+		if x >= m.x && x < m.x+m.width && y >= m.y && y < m.y+m.height {
+			return m, true
+		}
+	}
+	return monitor{}, false
+}
+
+// center is the point a window is taken to be on when it spans monitors.
+func center(g window.Geometry) (int, int) {
+	return g.X + g.Width/2, g.Y + g.Height/2
+}
+
+func (r *randr) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
@@ -0,0 +1,63 @@
+package x11
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const screenSaverQueryInfo = 1
+
+// screenSaver reads the time since the last input from the X server's
+// MIT-SCREEN-SAVER extension, which is what xprintidle asks. The connection
+// is kept between queries and dialed again after one fails.
+type screenSaver struct {
+	mu     sync.Mutex
+	conn   *xConn
+	opcode byte
+}
+
+// IdleTime returns how long the user has not touched the keyboard or mouse.
+func (s *screenSaver) IdleTime() (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := dialX(os.Getenv("DISPLAY"))
+		if err != nil {
+			return 0, err
+		}
+		opcode, ok, err := conn.queryExtension("MIT-SCREEN-SAVER")
+		if err != nil || !ok {
+			conn.Close()
+			if err == nil {
+				err = fmt.Errorf("X server has no MIT-SCREEN-SAVER extension")
+			}
+			return 0, err
+		}
+		s.conn, s.opcode = conn, opcode
+	}
+
+	var drawable [4]byte
+	binary.LittleEndian.PutUint32(drawable[:], s.conn.root)
+	reply, err := s.conn.request(s.opcode, screenSaverQueryInfo, drawable[:])
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return 0, fmt.Errorf("failed to query screen saver info: %w", err)
+	}
+	// The reply holds the saver window, the time until the screen saver
+	// starts, then the time since the last input, in milliseconds.
+	return time.Duration(binary.LittleEndian.Uint32(reply[16:])) * time.Millisecond, nil
+}
+
+func (s *screenSaver) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,225 @@
+package x11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		display string
+		network string
+		address string
+		number  string
+	}{
+		{":0", "unix", "/tmp/.X11-unix/X0", "0"},
+		{":1.0", "unix", "/tmp/.X11-unix/X1", "1"},
+		{"unix:2", "unix", "/tmp/.X11-unix/X2", "2"},
+		{"localhost:10.0", "tcp", "localhost:6010", "10"},
+		{"[::1]:3", "tcp", "[::1]:6003", "3"},
+		{"/private/tmp/com.apple.launchd.x/org.xquartz:0", "unix", "/private/tmp/com.apple.launchd.x/org.xquartz:0", "0"},
+	}
+	for _, tt := range tests {
+		network, address, number, err := parseDisplay(tt.display)
+		if err != nil {
+			t.Errorf("parseDisplay(%q) error = %v", tt.display, err)
+			continue
+		}
+		if network != tt.network || address != tt.address || number != tt.number {
+			t.Errorf("parseDisplay(%q) = %q %q %q, want %q %q %q", tt.display, network, address, number, tt.network, tt.address, tt.number)
+		}
+	}
+
+	for _, display := range []string{"", "0", ":", ":x"} {
+		if _, _, _, err := parseDisplay(display); err == nil {
+			t.Errorf("parseDisplay(%q) succeeded, want an error", display)
+		}
+	}
+}
+
+func xauthEntry(family uint16, address, number, name string, data []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, family)
+	for _, field := range [][]byte{[]byte(address), []byte(number), []byte(name), data} {
+		binary.Write(&b, binary.BigEndian, uint16(len(field)))
+		b.Write(field)
+	}
+	return b.Bytes()
+}
+
+func TestFindCookie(t *testing.T) {
+	var file []byte
+	file = append(file, xauthEntry(familyLocal, "otherhost", "0", "MIT-MAGIC-COOKIE-1", []byte("other"))...)
+	file = append(file, xauthEntry(familyLocal, "desk", "1", "MIT-MAGIC-COOKIE-1", []byte("one"))...)
+	file = append(file, xauthEntry(familyLocal, "desk", "0", "XDM-AUTHORIZATION-1", []byte("xdm"))...)
+	file = append(file, xauthEntry(familyLocal, "desk", "0", "MIT-MAGIC-COOKIE-1", []byte("zero"))...)
+	file = append(file, xauthEntry(familyWild, "", "", "MIT-MAGIC-COOKIE-1", []byte("wild"))...)
+
+	tests := []struct {
+		family uint16
+		host   string
+		number string
+		want   string
+	}{
+		{familyLocal, "desk", "0", "zero"},
+		{familyLocal, "desk", "1", "one"},
+		{familyLocal, "laptop", "0", "wild"},
+		{familyInternet, "desk", "0", "wild"},
+	}
+	for _, tt := range tests {
+		name, cookie := findCookie(file, tt.family, tt.host, tt.number)
+		if name != "MIT-MAGIC-COOKIE-1" || string(cookie) != tt.want {
+			t.Errorf("findCookie(%d, %q, %q) = %q %q, want %q", tt.family, tt.host, tt.number, name, cookie, tt.want)
+		}
+	}
+
+	if name, cookie := findCookie(file[:10], familyLocal, "desk", "0"); name != "" || cookie != nil {
+		t.Errorf("findCookie(truncated) = %q %q, want none", name, cookie)
+	}
+}
+
+// fakeXServer accepts one connection at path and answers the connection
+// setup, QueryExtension for MIT-SCREEN-SAVER and ScreenSaverQueryInfo.
+func fakeXServer(t *testing.T, path string, idle time.Duration) {
+	t.Helper()
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var setup [12]byte
+		if _, err := io.ReadFull(conn, setup[:]); err != nil || setup[0] != 'l' {
+			return
+		}
+		auth := make([]byte, pad4(int(binary.LittleEndian.Uint16(setup[6:])))+pad4(int(binary.LittleEndian.Uint16(setup[8:]))))
+		if _, err := io.ReadFull(conn, auth); err != nil {
+			return
+		}
+
+		// A setup reply with the vendor "fake", one pixmap format and one
+		// screen whose root window is 0x1ab.
+		body := make([]byte, 32+4+8+4)
+		binary.LittleEndian.PutUint16(body[16:], 4)
+		body[20], body[21] = 1, 1
+		copy(body[32:], "fake")
+		binary.LittleEndian.PutUint32(body[44:], 0x1ab)
+		header := make([]byte, 8)
+		header[0] = 1
+		binary.LittleEndian.PutUint16(header[2:], 11)
+		binary.LittleEndian.PutUint16(header[6:], uint16(len(body)/4))
+		conn.Write(append(header, body...))
+
+		var seq uint16
+		for {
+			var head [4]byte
+			if _, err := io.ReadFull(conn, head[:]); err != nil {
+				return
+			}
+			req := make([]byte, int(binary.LittleEndian.Uint16(head[2:]))*4-4)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			seq++
+
+			reply := make([]byte, 32)
+			reply[0] = 1
+			binary.LittleEndian.PutUint16(reply[2:], seq)
+			switch {
+			case head[0] == opQueryExtension:
+				n := binary.LittleEndian.Uint16(req)
+				if string(req[4:4+n]) == "MIT-SCREEN-SAVER" {
+					reply[8], reply[9] = 1, 140
+				}
+			case head[0] == 140 && head[1] == screenSaverQueryInfo && binary.LittleEndian.Uint32(req) == 0x1ab:
+				binary.LittleEndian.PutUint32(reply[16:], uint32(idle/time.Millisecond))
+			default:
+				reply[0], reply[1] = 0, 1
+			}
+			// An unrelated PropertyNotify event first, as a real server
+			// may send.
+			event := make([]byte, 32)
+			event[0] = 28
+			conn.Write(append(event, reply...))
+		}
+	}()
+}
+
+func TestScreenSaverIdleTime(t *testing.T) {
+	display := filepath.Join(t.TempDir(), "x:0")
+	fakeXServer(t, display, 93*time.Second+250*time.Millisecond)
+	t.Setenv("DISPLAY", display)
+	t.Setenv("XAUTHORITY", filepath.Join(t.TempDir(), "missing"))
+
+	var s screenSaver
+	defer s.Close()
+	idle, err := s.IdleTime()
+	if err != nil {
+		t.Fatalf("IdleTime() error = %v", err)
+	}
+	if idle != 93*time.Second+250*time.Millisecond {
+		t.Errorf("IdleTime() = %v, want 1m33.25s", idle)
+	}
+	if s.conn == nil {
+		t.Error("connection not kept after a successful query")
+	}
+}
+
+func TestParseMonitors(t *testing.T) {
+	// Two monitors, the first made of two outputs, left of each other.
+	reply := make([]byte, 32+24+8+24+4)
+	binary.LittleEndian.PutUint32(reply[12:], 2)
+	first := reply[32:]
+	binary.LittleEndian.PutUint32(first[0:], 301)
+	binary.LittleEndian.PutUint16(first[6:], 2)
+	binary.LittleEndian.PutUint16(first[12:], 1920)
+	binary.LittleEndian.PutUint16(first[14:], 1080)
+	second := reply[32+24+8:]
+	binary.LittleEndian.PutUint32(second[0:], 302)
+	binary.LittleEndian.PutUint16(second[6:], 1)
+	binary.LittleEndian.PutUint16(second[8:], 1920)
+	binary.LittleEndian.PutUint16(second[12:], 2560)
+	binary.LittleEndian.PutUint16(second[14:], 1440)
+
+	monitors := parseMonitors(reply)
+	want := []monitor{
+		{name: 301, width: 1920, height: 1080},
+		{name: 302, x: 1920, width: 2560, height: 1440},
+	}
+	if len(monitors) != len(want) || monitors[0] != want[0] || monitors[1] != want[1] {
+		t.Fatalf("parseMonitors() = %+v, want %+v", monitors, want)
+	}
+
+	for _, tt := range []struct {
+		x, y int
+		name uint32
+		ok   bool
+	}{
+		{100, 100, 301, true},
+		{1919, 1079, 301, true},
+		{1920, 0, 302, true},
+		{2500, 1200, 302, true},
+		{100, 1200, 0, false},
+	} {
+		m, ok := monitorAt(monitors, tt.x, tt.y)
+		if ok != tt.ok || m.name != tt.name {
+			t.Errorf("monitorAt(%d, %d) = %d %v, want %d %v", tt.x, tt.y, m.name, ok, tt.name, tt.ok)
+		}
+	}
+
+	if got := parseMonitors(reply[:40]); len(got) != 0 {
+		t.Errorf("parseMonitors(truncated) = %+v", got)
+	}
+}
@@ -0,0 +1,296 @@
+// Package mdns advertises a service on the local network with multicast DNS
+// and DNS-SD (RFC 6762, RFC 6763), so clients can find it without knowing
+// its address. It only answers queries for the service it advertises.
+package mdns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+	typeANY = 255
+
+	classIN = 1
+
+	// cacheFlush marks unique records so caches replace rather than add.
+	cacheFlush = 0x8000
+
+	// unicastResponse in a question's class asks for a direct reply.
+	unicastResponse = 0x8000
+
+	hostTTL    = 120
+	serviceTTL = 4500
+
+	servicesName = "_services._dns-sd._udp.local."
+)
+
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service describes what to advertise.
+type Service struct {
+	// Instance is the human-readable name, e.g. "actionsum on laptop".
+	Instance string
+
+	// Type is the DNS-SD service type, e.g. "_actionsum._tcp".
+	Type string
+
+	// Host is the unqualified host name the service runs on.
+	Host string
+
+	Port int
+	IPs  []net.IP
+	TXT  []string
+}
+
+func (s *Service) typeName() string     { return s.Type + ".local." }
+func (s *Service) instanceName() string { return escapeLabel(s.Instance) + "." + s.typeName() }
+func (s *Service) hostName() string     { return s.Host + ".local." }
+
+// Run announces the service, answers queries for it until ctx is cancelled,
+// and then sends a goodbye so clients drop it.
+func (s *Service) Run(ctx context.Context) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+	if err != nil {
+		return fmt.Errorf("failed to join mDNS group: %w", err)
+	}
+	defer conn.Close()
+
+	// Announce twice, a second apart, as RFC 6762 section 8.3 asks.
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		if _, err := conn.WriteToUDP(s.records(false), groupAddr); err != nil {
+			return fmt.Errorf("failed to announce service: %w", err)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.WriteToUDP(s.records(true), groupAddr)
+		conn.Close()
+	}()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read mDNS query: %w", err)
+		}
+
+		response, unicast := s.Answer(buf[:n])
+		if response == nil {
+			continue
+		}
+		to := groupAddr
+		if unicast || from.Port != groupAddr.Port {
+			to = from
+		}
+		conn.WriteToUDP(response, to)
+	}
+}
+
+// Answer returns the response to an mDNS query packet, or nil if the query
+// does not concern the service, and whether the querier asked for a unicast
+// reply.
+func (s *Service) Answer(packet []byte) ([]byte, bool) {
+	questions, err := parseQuestions(packet)
+	if err != nil {
+		return nil, false
+	}
+
+	matched, unicast := false, false
+	for _, q := range questions {
+		if q.class&^unicastResponse != classIN {
+			continue
+		}
+		name := strings.ToLower(q.name)
+		switch {
+		case name == strings.ToLower(s.typeName()) && (q.qtype == typePTR || q.qtype == typeANY),
+			name == strings.ToLower(servicesName) && (q.qtype == typePTR || q.qtype == typeANY),
+			name == strings.ToLower(s.instanceName()),
+			name == strings.ToLower(s.hostName()) && (q.qtype == typeA || q.qtype == typeANY):
+			matched = true
+			unicast = unicast || q.class&unicastResponse != 0
+		}
+	}
+	if !matched {
+		return nil, false
+	}
+	return s.records(false), unicast
+}
+
+// records builds a response holding every record of the service. With
+// goodbye set the TTLs are zero, withdrawing them.
+func (s *Service) records(goodbye bool) []byte {
+	ttl := func(t uint32) uint32 {
+		if goodbye {
+			return 0
+		}
+		return t
+	}
+
+	var answers [][]byte
+	answers = append(answers, record(servicesName, typePTR, classIN, ttl(serviceTTL), encodeName(s.typeName())))
+	answers = append(answers, record(s.typeName(), typePTR, classIN, ttl(serviceTTL), encodeName(s.instanceName())))
+
+	srv := make([]byte, 6)
+	binary.BigEndian.PutUint16(srv[4:], uint16(s.Port))
+	answers = append(answers, record(s.instanceName(), typeSRV, classIN|cacheFlush, ttl(hostTTL), append(srv, encodeName(s.hostName())...)))
+
+	var txt []byte
+	for _, entry := range s.TXT {
+		txt = append(txt, byte(len(entry)))
+		txt = append(txt, entry...)
+	}
+	if len(txt) == 0 {
+		txt = []byte{0}
+	}
+	answers = append(answers, record(s.instanceName(), typeTXT, classIN|cacheFlush, ttl(serviceTTL), txt))
+
+	for _, ip := range s.IPs {
+		if v4 := ip.To4(); v4 != nil {
+			answers = append(answers, record(s.hostName(), typeA, classIN|cacheFlush, ttl(hostTTL), v4))
+		}
+	}
+
+	// Response header: ID 0, QR and AA set, no questions.
+	packet := make([]byte, 12)
+	binary.BigEndian.PutUint16(packet[2:], 0x8400)
+	binary.BigEndian.PutUint16(packet[6:], uint16(len(answers)))
+	for _, answer := range answers {
+		packet = append(packet, answer...)
+	}
+	return packet
+}
+
+func record(name string, rtype, class uint16, ttl uint32, data []byte) []byte {
+	b := encodeName(name)
+	fixed := make([]byte, 10)
+	binary.BigEndian.PutUint16(fixed[0:], rtype)
+	binary.BigEndian.PutUint16(fixed[2:], class)
+	binary.BigEndian.PutUint32(fixed[4:], ttl)
+	binary.BigEndian.PutUint16(fixed[8:], uint16(len(data)))
+	return append(append(b, fixed...), data...)
+}
+
+// encodeName writes a dotted name as DNS labels. Dots escaped as "\." stay
+// inside their label.
+func encodeName(name string) []byte {
+	var b []byte
+	var label []byte
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name):
+			i++
+			label = append(label, name[i])
+		case name[i] == '.':
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+			label = label[:0]
+		default:
+			label = append(label, name[i])
+		}
+	}
+	if len(label) > 0 {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func escapeLabel(label string) string {
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	label = strings.ReplaceAll(label, ".", `\.`)
+	if len(label) > 63 {
+		label = label[:63]
+	}
+	return label
+}
+
+type question struct {
+	name  string
+	qtype uint16
+	class uint16
+}
+
+var errMalformed = errors.New("malformed DNS packet")
+
+// parseQuestions returns the questions of a query; responses yield none.
+func parseQuestions(packet []byte) ([]question, error) {
+	if len(packet) < 12 {
+		return nil, errMalformed
+	}
+	if packet[2]&0x80 != 0 {
+		return nil, nil
+	}
+
+	count := int(binary.BigEndian.Uint16(packet[4:]))
+	offset := 12
+	questions := make([]question, 0, count)
+	for i := 0; i < count; i++ {
+		name, next, err := readName(packet, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(packet) {
+			return nil, errMalformed
+		}
+		questions = append(questions, question{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(packet[next:]),
+			class: binary.BigEndian.Uint16(packet[next+2:]),
+		})
+		offset = next + 4
+	}
+	return questions, nil
+}
+
+// readName decodes the name at offset, following compression pointers, and
+// returns it with the offset just past it. Dots inside labels come back
+// escaped so names compare equal to the ones the Service builds.
+func readName(packet []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(packet) {
+			return "", 0, errMalformed
+		}
+		length := int(packet[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(packet) || jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(packet[offset:]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(packet) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, escapeLabel(string(packet[offset+1:offset+1+length])))
+			offset += 1 + length
+		}
+	}
+}
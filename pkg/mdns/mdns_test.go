@@ -0,0 +1,124 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+func query(name string, qtype, class uint16) []byte {
+	packet := make([]byte, 12)
+	binary.BigEndian.PutUint16(packet[4:], 1)
+	packet = append(packet, encodeName(name)...)
+	return binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(packet, qtype), class)
+}
+
+func testService() *Service {
+	return &Service{
+		Instance: "actionsum on laptop",
+		Type:     "_actionsum._tcp",
+		Host:     "laptop",
+		Port:     11000,
+		IPs:      []net.IP{net.IPv4(192, 168, 1, 20)},
+		TXT:      []string{"path=/"},
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	s := testService()
+	tests := []struct {
+		name    string
+		packet  []byte
+		match   bool
+		unicast bool
+	}{
+		{"browse", query("_actionsum._tcp.local.", typePTR, classIN), true, false},
+		{"browse case-insensitive", query("_ActionSum._TCP.local.", typePTR, classIN), true, false},
+		{"unicast", query("_actionsum._tcp.local.", typePTR, classIN|unicastResponse), true, true},
+		{"enumerate", query("_services._dns-sd._udp.local.", typePTR, classIN), true, false},
+		{"resolve", query("actionsum on laptop._actionsum._tcp.local.", typeSRV, classIN), true, false},
+		{"host", query("laptop.local.", typeA, classIN), true, false},
+		{"other service", query("_http._tcp.local.", typePTR, classIN), false, false},
+		{"other host", query("desktop.local.", typeA, classIN), false, false},
+		{"truncated", query("_actionsum._tcp.local.", typePTR, classIN)[:20], false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, unicast := s.Answer(tt.packet)
+			if (response != nil) != tt.match {
+				t.Fatalf("Answer() matched = %v, want %v", response != nil, tt.match)
+			}
+			if unicast != tt.unicast {
+				t.Errorf("Answer() unicast = %v, want %v", unicast, tt.unicast)
+			}
+		})
+	}
+}
+
+func TestAnswerIgnoresResponses(t *testing.T) {
+	s := testService()
+	if response, _ := s.Answer(s.records(false)); response != nil {
+		t.Error("Answer() replied to a response")
+	}
+}
+
+func TestRecords(t *testing.T) {
+	s := testService()
+	packet := s.records(false)
+	if count := binary.BigEndian.Uint16(packet[6:]); count != 5 {
+		t.Fatalf("answer count = %d, want 5", count)
+	}
+
+	name, next, err := readName(packet, 12)
+	if err != nil {
+		t.Fatalf("readName() error = %v", err)
+	}
+	if name != servicesName {
+		t.Errorf("first record = %q, want %q", name, servicesName)
+	}
+	if ttl := binary.BigEndian.Uint32(packet[next+4:]); ttl != serviceTTL {
+		t.Errorf("TTL = %d, want %d", ttl, serviceTTL)
+	}
+
+	goodbye := s.records(true)
+	_, next, _ = readName(goodbye, 12)
+	if ttl := binary.BigEndian.Uint32(goodbye[next+4:]); ttl != 0 {
+		t.Errorf("goodbye TTL = %d, want 0", ttl)
+	}
+}
+
+func TestReadNameCompression(t *testing.T) {
+	// "local." at 12, then "laptop" pointing back to it.
+	packet := make([]byte, 12)
+	packet = append(packet, encodeName("local.")...)
+	packet = append(packet, 6)
+	packet = append(packet, "laptop"...)
+	packet = append(packet, 0xc0, 12)
+
+	name, next, err := readName(packet, 19)
+	if err != nil {
+		t.Fatalf("readName() error = %v", err)
+	}
+	if name != "laptop.local." {
+		t.Errorf("name = %q, want laptop.local.", name)
+	}
+	if next != len(packet) {
+		t.Errorf("next = %d, want %d", next, len(packet))
+	}
+
+	loop := append(make([]byte, 12), 0xc0, 12)
+	if _, _, err := readName(loop, 12); err == nil {
+		t.Error("readName() followed a pointer loop")
+	}
+}
+
+func TestInstanceNameEscaping(t *testing.T) {
+	s := testService()
+	s.Instance = "v1.2 host"
+	encoded := encodeName(s.instanceName())
+	if encoded[0] != byte(len("v1.2 host")) || !strings.HasPrefix(string(encoded[1:]), "v1.2 host") {
+		t.Errorf("instance label = %q, want dot kept inside it", encoded)
+	}
+}
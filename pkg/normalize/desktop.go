@@ -0,0 +1,209 @@
+package normalize
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// desktopEntry is what a .desktop file says about an installed app.
+type desktopEntry struct {
+	id      string // File name without .desktop, e.g. "org.mozilla.firefox"
+	name    string // Name, e.g. "Firefox"
+	icon    string // Icon, a freedesktop icon name or a file
+	exec    string
+	wmClass string // StartupWMClass
+	flatpak string // X-Flatpak, the Flatpak app ID
+	snap    string // X-SnapInstanceName
+	appOnly bool   // Type is Application
+	hidden  bool
+}
+
+// DesktopDirs lists the directories installed apps' .desktop files are in,
+// the user's first: the XDG data directories, and Flatpak's and Snap's
+// exports, which the session doesn't always add to them.
+func DesktopDirs() []string {
+	home, _ := os.UserHomeDir()
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	if dataHome != "" {
+		add(filepath.Join(dataHome, "applications"))
+		add(filepath.Join(dataHome, "flatpak", "exports", "share", "applications"))
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		add(filepath.Join(dir, "applications"))
+	}
+	add("/var/lib/flatpak/exports/share/applications")
+	add("/var/lib/snapd/desktop/applications")
+	return dirs
+}
+
+// LoadDesktopEntries learns from the .desktop files in dirs, earlier ones
+// first, each app's display name, and maps the IDs and window classes of
+// Flatpak and Snap installs to the command a native install runs, so that
+// e.g. "org.mozilla.firefox" and "firefox_firefox" count as "firefox".
+// Aliases already known, built in or from the user's mapping, are kept.
+// Unreadable directories and files are skipped.
+func (n *Normalizer) LoadDesktopEntries(dirs []string) {
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".desktop") {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil
+			}
+			// Subdirectories become part of the ID, as in "kde4-dolphin".
+			id := strings.ReplaceAll(strings.TrimSuffix(rel, ".desktop"), string(filepath.Separator), "-")
+			if entry, ok := readDesktopEntry(path, id); ok {
+				n.addDesktopEntry(entry)
+			}
+			return nil
+		})
+	}
+}
+
+func (n *Normalizer) addDesktopEntry(entry *desktopEntry) {
+	if !entry.appOnly || entry.hidden || entry.name == "" {
+		return
+	}
+	command := execCommand(entry.exec)
+	sandboxed := entry.flatpak != "" || entry.snap != "" || strings.Contains(entry.exec, "/snap/bin/")
+	if command == "" && entry.flatpak != "" {
+		// "flatpak run org.gnome.Maps" runs the app's own command, which
+		// the app ID names well enough.
+		command = entry.flatpak
+	}
+
+	var canonical string
+	if command != "" {
+		canonical = n.Normalize(command)
+	}
+	keys := []string{entry.id, entry.wmClass, entry.flatpak}
+	for _, key := range keys {
+		key = strings.ToLower(key)
+		if key == "" {
+			continue
+		}
+		if _, ok := n.aliases[key]; !ok && sandboxed && canonical != "" && key != canonical {
+			n.aliases[key] = canonical
+		}
+	}
+
+	for _, key := range append(keys, canonical) {
+		key = strings.ToLower(key)
+		if _, ok := n.displayNames[key]; key != "" && !ok {
+			n.displayNames[key] = entry.name
+		}
+		if _, ok := n.icons[key]; key != "" && entry.icon != "" && !ok {
+			n.icons[key] = entry.icon
+		}
+	}
+}
+
+// DisplayName returns the name the app called name, as reported or once
+// normalized, gives itself in its .desktop file, e.g. "Firefox" for
+// "org.mozilla.firefox", or "" when no entry was loaded for it.
+func (n *Normalizer) DisplayName(name string) string {
+	return n.fromDesktop(n.displayNames, name)
+}
+
+// IconName returns the icon the .desktop file of the app called name
+// gives, as DisplayName finds it: a freedesktop icon name such as
+// "org.gnome.Maps", or a file.
+func (n *Normalizer) IconName(name string) string {
+	return n.fromDesktop(n.icons, name)
+}
+
+func (n *Normalizer) fromDesktop(values map[string]string, name string) string {
+	if value, ok := values[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return value
+	}
+	return values[n.Normalize(name)]
+}
+
+// readDesktopEntry reads the keys of a .desktop file's [Desktop Entry]
+// group that name the app, leaving out translations.
+func readDesktopEntry(path, id string) (*desktopEntry, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	entry := &desktopEntry{id: id}
+	inEntry := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inEntry || !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Name":
+			entry.name = value
+		case "Type":
+			entry.appOnly = value == "Application"
+		case "Icon":
+			entry.icon = value
+		case "Exec":
+			entry.exec = value
+		case "StartupWMClass":
+			entry.wmClass = value
+		case "Hidden":
+			entry.hidden = value == "true"
+		case "X-Flatpak":
+			entry.flatpak = value
+		case "X-SnapInstanceName":
+			entry.snap = value
+		}
+	}
+	return entry, scanner.Err() == nil
+}
+
+// execCommand finds the command an Exec line runs, past env and its
+// variables, as the base name of the program, or for "flatpak run", its
+// --command. It is "" for a flatpak run without one.
+func execCommand(exec string) string {
+	fields := strings.Fields(exec)
+	for i := 0; i < len(fields); i++ {
+		field := strings.Trim(fields[i], `"'`)
+		switch {
+		case field == "env" || (strings.Contains(field, "=") && !strings.HasPrefix(field, "-")):
+			continue
+		case filepath.Base(field) == "flatpak":
+			for _, arg := range fields[i+1:] {
+				if command, ok := strings.CutPrefix(arg, "--command="); ok {
+					return filepath.Base(command)
+				}
+			}
+			return ""
+		}
+		return filepath.Base(field)
+	}
+	return ""
+}
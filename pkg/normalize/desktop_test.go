@@ -0,0 +1,126 @@
+package normalize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDesktop(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDesktopEntries(t *testing.T) {
+	native, flatpak, snap := t.TempDir(), t.TempDir(), t.TempDir()
+	writeDesktop(t, native, "firefox.desktop", `[Desktop Entry]
+Name=Firefox Web Browser
+Name[de]=Firefox-Webbrowser
+Type=Application
+Exec=firefox %u
+
+[Desktop Action new-window]
+Name=Open a New Window
+Exec=firefox --new-window
+`)
+	writeDesktop(t, native, "org.gnome.TextEditor.desktop", `[Desktop Entry]
+Name=Text Editor
+Type=Application
+Exec=gnome-text-editor %U
+`)
+	writeDesktop(t, native, "hidden.desktop", `[Desktop Entry]
+Name=Gone
+Type=Application
+Exec=gone
+Hidden=true
+`)
+	writeDesktop(t, flatpak, "org.kde.kdenlive.desktop", `[Desktop Entry]
+Name=Kdenlive
+Icon=org.kde.kdenlive
+Type=Application
+Exec=/usr/bin/flatpak run --branch=stable --arch=x86_64 --command=kdenlive --file-forwarding org.kde.kdenlive @@ %F @@
+StartupWMClass=kdenlive
+X-Flatpak=org.kde.kdenlive
+`)
+	writeDesktop(t, flatpak, "org.gnome.Maps.desktop", `[Desktop Entry]
+Name=Maps
+Type=Application
+Exec=/usr/bin/flatpak run org.gnome.Maps
+X-Flatpak=org.gnome.Maps
+`)
+	writeDesktop(t, flatpak, "com.example.Override.desktop", `[Desktop Entry]
+Name=Override
+Type=Application
+Exec=/usr/bin/flatpak run --command=other com.example.Override
+X-Flatpak=com.example.Override
+`)
+	writeDesktop(t, snap, "obsidian_obsidian.desktop", `[Desktop Entry]
+Name=Obsidian
+Type=Application
+Exec=env BAMF_DESKTOP_FILE_HINT=/var/lib/snapd/desktop/applications/obsidian_obsidian.desktop /snap/bin/obsidian %U
+X-SnapInstanceName=obsidian
+`)
+
+	n := New()
+	n.AddAlias("com.example.override", "mine")
+	n.LoadDesktopEntries([]string{native, flatpak, snap, filepath.Join(native, "missing")})
+
+	names := map[string]string{
+		"org.kde.kdenlive":     "kdenlive",
+		"obsidian_obsidian":    "obsidian",
+		"org.gnome.Maps":       "org.gnome.maps",
+		"org.gnome.TextEditor": "org.gnome.texteditor", // Native IDs are left alone
+		"org.mozilla.firefox":  "firefox",              // Built in
+		"com.example.Override": "mine",                 // The user's mapping
+	}
+	for name, want := range names {
+		if got := n.Normalize(name); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	displays := map[string]string{
+		"firefox":              "Firefox Web Browser",
+		"org.mozilla.firefox":  "Firefox Web Browser",
+		"kdenlive":             "Kdenlive",
+		"org.kde.kdenlive":     "Kdenlive",
+		"org.gnome.TextEditor": "Text Editor",
+		"obsidian_obsidian":    "Obsidian",
+		"gone":                 "",
+		"unknown":              "",
+	}
+	for name, want := range displays {
+		if got := n.DisplayName(name); got != want {
+			t.Errorf("DisplayName(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if got := n.IconName("kdenlive"); got != "org.kde.kdenlive" {
+		t.Errorf("IconName(kdenlive) = %q, want org.kde.kdenlive", got)
+	}
+	if got := n.IconName("firefox"); got != "" {
+		t.Errorf("IconName(firefox) = %q for an entry without an icon", got)
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	tests := map[string]string{
+		"firefox %u": "firefox",
+		"/opt/Signal/signal-desktop --no-sandbox %U":                 "signal-desktop",
+		`env GDK_BACKEND=x11 "/usr/bin/app" %f`:                      "app",
+		"/usr/bin/flatpak run --command=/app/bin/gimp org.gimp.GIMP": "gimp",
+		"flatpak run org.gnome.Maps":                                 "",
+		"":                                                           "",
+	}
+	for exec, want := range tests {
+		if got := execCommand(exec); got != want {
+			t.Errorf("execCommand(%q) = %q, want %q", exec, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package normalize
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// builtinSuffixes are packaging artifacts stripped from process and class
+// names so that e.g. "soffice.bin" and "firefox-bin" collapse to one app.
+var builtinSuffixes = []string{".exe", ".bin", "-bin", "-wrapped", "-stable"}
+
+// builtinAliases maps known variants (after suffix stripping) to the name the
+// app is reported under.
+var builtinAliases = map[string]string{
+	"soffice":                "libreoffice",
+	"libreoffice-writer":     "libreoffice",
+	"libreoffice-calc":       "libreoffice",
+	"libreoffice-impress":    "libreoffice",
+	"libreoffice-draw":       "libreoffice",
+	"navigator":              "firefox",
+	"org.mozilla.firefox":    "firefox",
+	"chromium-browser":       "chromium",
+	"org.chromium.chromium":  "chromium",
+	"code-oss":               "code",
+	"code-url-handler":       "code",
+	"com.visualstudio.code":  "code",
+	"gnome-terminal-server":  "gnome-terminal",
+	"telegram-desktop":       "telegram",
+	"org.telegram.desktop":   "telegram",
+	"com.slack.slack":        "slack",
+	"com.discordapp.discord": "discord",
+	"spotify-client":         "spotify",
+}
+
+// electronRuntime matches the shared Electron runtimes distributions
+// package, such as "electron" and "electron25", which run an app given on
+// their command line.
+var electronRuntime = regexp.MustCompile(`^electron\d*$`)
+
+type Normalizer struct {
+	suffixes     []string
+	aliases      map[string]string
+	displayNames map[string]string // From .desktop files, by lowercased name
+	icons        map[string]string
+}
+
+// New returns a normalizer with the built-in suffix and alias rules.
+func New() *Normalizer {
+	n := &Normalizer{
+		suffixes:     append([]string(nil), builtinSuffixes...),
+		aliases:      make(map[string]string, len(builtinAliases)),
+		displayNames: make(map[string]string),
+		icons:        make(map[string]string),
+	}
+	for variant, canonical := range builtinAliases {
+		n.aliases[variant] = canonical
+	}
+	return n
+}
+
+// Load returns a normalizer with the built-in rules plus the user mapping at
+// path. A missing file is not an error so the default location is optional.
+func Load(path string) (*Normalizer, error) {
+	n := New()
+	if path == "" {
+		return n, nil
+	}
+	if err := n.LoadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return n, nil
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+// LoadFile reads "variant = canonical" lines; blank lines and lines starting
+// with # are ignored. User entries override built-in aliases.
+func (n *Normalizer) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		variant, canonical, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected \"variant = canonical\"", path, lineNum)
+		}
+		variant = strings.TrimSpace(variant)
+		canonical = strings.TrimSpace(canonical)
+		if variant == "" || canonical == "" {
+			return fmt.Errorf("%s:%d: variant and canonical name cannot be empty", path, lineNum)
+		}
+		n.AddAlias(variant, canonical)
+	}
+
+	return scanner.Err()
+}
+
+func (n *Normalizer) AddAlias(variant, canonical string) {
+	n.aliases[strings.ToLower(variant)] = strings.ToLower(canonical)
+}
+
+// Normalize lowercases name, maps exact aliases, strips packaging suffixes and
+// then maps aliases again so both "soffice.bin" and "soffice" resolve.
+func (n *Normalizer) Normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return name
+	}
+
+	if canonical, ok := n.aliases[name]; ok {
+		return canonical
+	}
+
+	name = strings.TrimPrefix(name, ".")
+	for stripped := true; stripped; {
+		stripped = false
+		for _, suffix := range n.suffixes {
+			if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+				name = strings.TrimSuffix(name, suffix)
+				stripped = true
+			}
+		}
+	}
+
+	if canonical, ok := n.aliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// IsElectron reports whether a process or window class name is a shared
+// Electron runtime rather than an app.
+func IsElectron(name string) bool {
+	return electronRuntime.MatchString(strings.ToLower(name))
+}
+
+// ElectronProduct names the app a shared Electron runtime runs, from the
+// arguments after the program in its command line: the path given with
+// --app, or else the first argument that isn't a flag. An app.asar or app
+// directory is named after the directory holding it, or holding its
+// resources directory ("/usr/lib/signal-desktop/resources/app.asar" is
+// "signal-desktop"), any other .asar after its file name. It returns "" when no app is given.
+func ElectronProduct(args []string) string {
+	var app string
+	for i := 0; i < len(args) && app == ""; i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--app="):
+			app = strings.TrimPrefix(arg, "--app=")
+		case arg == "--app" && i+1 < len(args):
+			app = args[i+1]
+		case arg != "" && !strings.HasPrefix(arg, "-"):
+			app = arg
+		}
+	}
+	if app == "" {
+		return ""
+	}
+
+	app = filepath.Clean(app)
+	base := filepath.Base(app)
+	dir := filepath.Dir(app)
+	if base == "app.asar" || base == "app" {
+		if filepath.Base(dir) == "resources" {
+			dir = filepath.Dir(dir)
+		}
+		base = filepath.Base(dir)
+	}
+	base = strings.TrimSuffix(base, ".asar")
+	if base == "." || base == string(filepath.Separator) {
+		return ""
+	}
+	return strings.ToLower(base)
+}
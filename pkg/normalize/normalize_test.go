@@ -0,0 +1,104 @@
+package normalize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	n := New()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Firefox", "firefox"},
+		{"  Code  ", "code"},
+		{"soffice.bin", "libreoffice"},
+		{"soffice", "libreoffice"},
+		{"libreoffice-writer", "libreoffice"},
+		{"firefox-bin", "firefox"},
+		{".firefox-wrapped", "firefox"},
+		{"Navigator", "firefox"},
+		{"notepad.exe", "notepad"},
+		{"google-chrome-stable", "google-chrome"},
+		{"electron25", "electron25"},
+		{"code-oss", "code"},
+		{"org.mozilla.firefox", "firefox"},
+		{".bin", "bin"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := n.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app-names.conf")
+	content := "# custom mappings\n\nMyEditor-bin = editor\nsoffice = office\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	n, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if got := n.Normalize("myeditor-bin"); got != "editor" {
+		t.Errorf("Normalize(myeditor-bin) = %q, want editor", got)
+	}
+	if got := n.Normalize("soffice.bin"); got != "office" {
+		t.Errorf("Normalize(soffice.bin) = %q, want office (user alias overrides built-in)", got)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	n, err := Load(filepath.Join(t.TempDir(), "missing.conf"))
+	if err != nil {
+		t.Fatalf("Load() error for missing file: %v", err)
+	}
+	if got := n.Normalize("soffice.bin"); got != "libreoffice" {
+		t.Errorf("Normalize(soffice.bin) = %q, want libreoffice", got)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.conf")
+	if err := os.WriteFile(path, []byte("no separator here\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Load() expected error for malformed line")
+	}
+}
+
+func TestElectronProduct(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/usr/lib/signal-desktop/resources/app.asar"}, "signal-desktop"},
+		{[]string{"--no-sandbox", "/usr/lib/element/app.asar"}, "element"},
+		{[]string{"--app=/usr/share/Obsidian/resources/app"}, "obsidian"},
+		{[]string{"--app", "/opt/Todoist/todoist.asar", "--enable-features=x"}, "todoist"},
+		{[]string{"/usr/lib/code/out/cli.js/"}, "cli.js"},
+		{[]string{"--type=renderer"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := ElectronProduct(tt.args); got != tt.want {
+			t.Errorf("ElectronProduct(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if !IsElectron("electron25") || !IsElectron("Electron") || IsElectron("electron-fiddle") {
+		t.Error("IsElectron misclassified a runtime name")
+	}
+}
@@ -0,0 +1,439 @@
+// Package parquet writes flat tables as Apache Parquet files that DuckDB,
+// Spark, pandas and similar tools load directly. Rows are buffered into row
+// groups and written out as each fills, so memory use is bounded by the row
+// group size rather than the table. Pages are PLAIN encoded and compressed
+// with GZIP.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of a column's values.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int32
+	Int64
+	Double
+	String
+	// Timestamp takes time.Time values, stored as UTC microseconds.
+	Timestamp
+	// Date takes time.Time values or "2006-01-02" strings, stored as days
+	// since the Unix epoch.
+	Date
+)
+
+// Physical types, converted types and other enums from parquet.thrift.
+const (
+	physicalBoolean   = 0
+	physicalInt32     = 1
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedDate            = 6
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip    = 2
+	pageTypeData = 0
+)
+
+// DefaultRowGroupSize is the number of rows buffered before a row group is
+// written.
+const DefaultRowGroupSize = 65536
+
+var magic = []byte("PAR1")
+
+// Column describes one column of the table.
+type Column struct {
+	Name string
+	Type Type
+
+	// Optional columns accept nil values.
+	Optional bool
+}
+
+func (c Column) physical() int32 {
+	switch c.Type {
+	case Boolean:
+		return physicalBoolean
+	case Int32, Date:
+		return physicalInt32
+	case Int64, Timestamp:
+		return physicalInt64
+	case Double:
+		return physicalDouble
+	default:
+		return physicalByteArray
+	}
+}
+
+// Writer streams rows to a Parquet file. Call Close to write the footer;
+// the file is unreadable without it. After an error, all further writes
+// return it.
+type Writer struct {
+	// RowGroupSize is the number of rows per row group.
+	RowGroupSize int
+
+	out       *countingWriter
+	columns   []Column
+	chunks    []*chunk
+	rows      int
+	numRows   int64
+	rowGroups []rowGroup
+	createdBy string
+	err       error
+}
+
+type chunk struct {
+	levels []byte // definition levels, for optional columns
+	bools  []bool
+	values bytes.Buffer
+}
+
+type columnMeta struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroup struct {
+	columns []columnMeta
+	rows    int64
+	size    int64
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewWriter returns a writer of rows with the given columns to w. createdBy
+// names the application in the file metadata.
+func NewWriter(w io.Writer, createdBy string, columns ...Column) *Writer {
+	chunks := make([]*chunk, len(columns))
+	for i := range chunks {
+		chunks[i] = &chunk{}
+	}
+	return &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		out:          &countingWriter{w: w},
+		columns:      columns,
+		chunks:       chunks,
+		createdBy:    createdBy,
+	}
+}
+
+// Write adds a row with one value per column, in column order.
+func (w *Writer) Write(values ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(values), len(w.columns))
+	}
+	for i, value := range values {
+		if err := w.chunks[i].add(w.columns[i], value); err != nil {
+			// Earlier columns already hold this row's values.
+			w.err = fmt.Errorf("column %s: %w", w.columns[i].Name, err)
+			return w.err
+		}
+	}
+	w.rows++
+	if w.rows >= w.RowGroupSize {
+		w.err = w.flush()
+	}
+	return w.err
+}
+
+func (c *chunk) add(col Column, value interface{}) error {
+	if value == nil {
+		if !col.Optional {
+			return fmt.Errorf("nil value in required column")
+		}
+		c.levels = append(c.levels, 0)
+		return nil
+	}
+	if col.Optional {
+		c.levels = append(c.levels, 1)
+	}
+
+	var buf [8]byte
+	switch col.Type {
+	case Boolean:
+		v, ok := value.(bool)
+		if !ok {
+			return typeError(value)
+		}
+		c.bools = append(c.bools, v)
+	case Int32:
+		v, ok := toInt(value)
+		if !ok || v < math.MinInt32 || v > math.MaxInt32 {
+			return typeError(value)
+		}
+		binary.LittleEndian.PutUint32(buf[:], uint32(int32(v)))
+		c.values.Write(buf[:4])
+	case Int64:
+		v, ok := toInt(value)
+		if !ok {
+			return typeError(value)
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		c.values.Write(buf[:])
+	case Double:
+		v, ok := value.(float64)
+		if !ok {
+			return typeError(value)
+		}
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		c.values.Write(buf[:])
+	case String:
+		v, ok := value.(string)
+		if !ok {
+			return typeError(value)
+		}
+		binary.LittleEndian.PutUint32(buf[:], uint32(len(v)))
+		c.values.Write(buf[:4])
+		c.values.WriteString(v)
+	case Timestamp:
+		v, ok := value.(time.Time)
+		if !ok {
+			return typeError(value)
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.UnixMicro()))
+		c.values.Write(buf[:])
+	case Date:
+		var day time.Time
+		switch v := value.(type) {
+		case time.Time:
+			day = v
+		case string:
+			parsed, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				return fmt.Errorf("invalid date %q", v)
+			}
+			day = parsed
+		default:
+			return typeError(value)
+		}
+		days := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		binary.LittleEndian.PutUint32(buf[:], uint32(int32(days)))
+		c.values.Write(buf[:4])
+	}
+	return nil
+}
+
+func toInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func typeError(value interface{}) error {
+	return fmt.Errorf("unsupported value %T", value)
+}
+
+// flush writes the buffered rows as a row group, one data page per column.
+func (w *Writer) flush() error {
+	if w.out.n == 0 {
+		if _, err := w.out.Write(magic); err != nil {
+			return fmt.Errorf("failed to write parquet header: %w", err)
+		}
+	}
+	if w.rows == 0 {
+		return nil
+	}
+
+	group := rowGroup{rows: int64(w.rows)}
+	for i, c := range w.chunks {
+		meta, err := w.writePage(w.columns[i], c)
+		if err != nil {
+			return err
+		}
+		group.columns = append(group.columns, meta)
+		group.size += meta.uncompressed
+		w.chunks[i] = &chunk{}
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+func (w *Writer) writePage(col Column, c *chunk) (columnMeta, error) {
+	var page bytes.Buffer
+	if col.Optional {
+		levels := encodeLevels(c.levels)
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(levels)))
+		page.Write(length[:])
+		page.Write(levels)
+	}
+	if col.Type == Boolean {
+		page.Write(packBools(c.bools))
+	} else {
+		page.Write(c.values.Bytes())
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(page.Bytes()); err != nil {
+		return columnMeta{}, fmt.Errorf("failed to compress page: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return columnMeta{}, fmt.Errorf("failed to compress page: %w", err)
+	}
+
+	header := &thriftWriter{}
+	header.body(func() {
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(page.Len()))
+		header.i32(3, int32(compressed.Len()))
+		header.structField(5, func() {
+			header.i32(1, int32(w.rows))
+			header.i32(2, encodingPlain)
+			header.i32(3, encodingRLE)
+			header.i32(4, encodingRLE)
+		})
+	})
+
+	meta := columnMeta{
+		offset:       w.out.n,
+		uncompressed: int64(len(header.buf) + page.Len()),
+		compressed:   int64(len(header.buf) + compressed.Len()),
+	}
+	if _, err := w.out.Write(header.buf); err != nil {
+		return columnMeta{}, fmt.Errorf("failed to write page: %w", err)
+	}
+	if _, err := w.out.Write(compressed.Bytes()); err != nil {
+		return columnMeta{}, fmt.Errorf("failed to write page: %w", err)
+	}
+	return meta, nil
+}
+
+// encodeLevels writes definition levels (0 or 1) with the RLE/bit-packing
+// hybrid encoding, using only RLE runs.
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		out = append(out, levels[i])
+		i = j
+	}
+	return out
+}
+
+func packBools(values []bool) []byte {
+	out := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+// Close writes any buffered rows and the file footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	meta := &thriftWriter{}
+	meta.body(func() {
+		meta.i32(1, 1)
+		meta.structList(2, len(w.columns)+1, func(i int) {
+			if i == 0 {
+				meta.string(4, "schema")
+				meta.i32(5, int32(len(w.columns)))
+				return
+			}
+			col := w.columns[i-1]
+			meta.i32(1, col.physical())
+			repetition := int32(repetitionRequired)
+			if col.Optional {
+				repetition = repetitionOptional
+			}
+			meta.i32(3, repetition)
+			meta.string(4, col.Name)
+			switch col.Type {
+			case String:
+				meta.i32(6, convertedUTF8)
+			case Date:
+				meta.i32(6, convertedDate)
+			case Timestamp:
+				meta.i32(6, convertedTimestampMicros)
+			}
+		})
+		meta.i64(3, w.numRows)
+		meta.structList(4, len(w.rowGroups), func(i int) {
+			group := w.rowGroups[i]
+			meta.structList(1, len(group.columns), func(j int) {
+				chunk := group.columns[j]
+				col := w.columns[j]
+				meta.i64(2, chunk.offset)
+				meta.structField(3, func() {
+					meta.i32(1, col.physical())
+					meta.i32List(2, encodingPlain, encodingRLE)
+					meta.stringList(3, col.Name)
+					meta.i32(4, codecGzip)
+					meta.i64(5, group.rows)
+					meta.i64(6, chunk.uncompressed)
+					meta.i64(7, chunk.compressed)
+					meta.i64(9, chunk.offset)
+				})
+			})
+			meta.i64(2, group.size)
+			meta.i64(3, group.rows)
+		})
+		if w.createdBy != "" {
+			meta.string(6, w.createdBy)
+		}
+	})
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(meta.buf)))
+	for _, b := range [][]byte{meta.buf, length[:], magic} {
+		if _, err := w.out.Write(b); err != nil {
+			return fmt.Errorf("failed to write parquet footer: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes the compact protocol into maps keyed by field ID,
+// enough to check the metadata the writer produces.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (t *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(t.buf[t.pos:])
+	t.pos += n
+	return v
+}
+
+func (t *thriftReader) zigzag() int64 {
+	v := t.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (t *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case ctI32, ctI64:
+		return t.zigzag()
+	case ctBinary:
+		n := int(t.varint())
+		s := string(t.buf[t.pos : t.pos+n])
+		t.pos += n
+		return s
+	case ctList:
+		header := t.buf[t.pos]
+		t.pos++
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(t.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = t.value(elem)
+		}
+		return list
+	case ctStruct:
+		fields := map[int16]interface{}{}
+		var last int16
+		for {
+			header := t.buf[t.pos]
+			t.pos++
+			if header == 0 {
+				return fields
+			}
+			id := last + int16(header>>4)
+			if header>>4 == 0 {
+				id = int16(t.zigzag())
+			}
+			fields[id] = t.value(header & 0x0f)
+			last = id
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+func readFooter(t *testing.T, file []byte) map[int16]interface{} {
+	t.Helper()
+	if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+		t.Fatal("missing PAR1 magic")
+	}
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	r := &thriftReader{buf: file[len(file)-8-length : len(file)-8]}
+	meta := r.value(ctStruct).(map[int16]interface{})
+	if r.pos != length {
+		t.Fatalf("footer decoded %d of %d bytes", r.pos, length)
+	}
+	return meta
+}
+
+// readPage returns the decompressed data of the page at offset.
+func readPage(t *testing.T, file []byte, offset int64) []byte {
+	t.Helper()
+	r := &thriftReader{buf: file, pos: int(offset)}
+	header := r.value(ctStruct).(map[int16]interface{})
+	size := int(header[3].(int64))
+	gz, err := gzip.NewReader(bytes.NewReader(file[r.pos : r.pos+size]))
+	if err != nil {
+		t.Fatalf("page is not gzip: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress page: %v", err)
+	}
+	if int64(len(data)) != header[2].(int64) {
+		t.Errorf("page size = %d, header says %d", len(data), header[2])
+	}
+	return data
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, "test",
+		Column{Name: "id", Type: Int64},
+		Column{Name: "app", Type: String},
+		Column{Name: "at", Type: Timestamp},
+		Column{Name: "day", Type: Date},
+		Column{Name: "idle", Type: Boolean},
+		Column{Name: "share", Type: Double},
+		Column{Name: "offset", Type: Int32, Optional: true},
+	)
+	w.RowGroupSize = 2
+
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{int64(1), "firefox", at, "2024-03-01", true, 0.5, 3600},
+		{int64(2), "code", at.Add(time.Minute), at, false, 0.25, nil},
+		{int64(3), "kitty", at.Add(time.Hour), "1970-01-02", true, 1.0, -7200},
+	}
+	for _, row := range rows {
+		if err := w.Write(row...); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file := out.Bytes()
+	meta := readFooter(t, file)
+	if meta[3].(int64) != 3 {
+		t.Errorf("num_rows = %v, want 3", meta[3])
+	}
+	if meta[6] != "test" {
+		t.Errorf("created_by = %v, want test", meta[6])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 8 {
+		t.Fatalf("schema has %d elements, want 8", len(schema))
+	}
+	if offset := schema[7].(map[int16]interface{}); offset[3].(int64) != repetitionOptional {
+		t.Errorf("offset repetition = %v, want optional", offset[3])
+	}
+
+	groups := meta[4].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("row groups = %d, want 2", len(groups))
+	}
+	first := groups[0].(map[int16]interface{})
+	columns := first[1].([]interface{})
+	page := func(i int) []byte {
+		chunk := columns[i].(map[int16]interface{})[3].(map[int16]interface{})
+		return readPage(t, file, chunk[9].(int64))
+	}
+
+	if ids := page(0); binary.LittleEndian.Uint64(ids) != 1 || binary.LittleEndian.Uint64(ids[8:]) != 2 {
+		t.Errorf("id page = %v", ids)
+	}
+	if apps := page(1); string(apps[4:11]) != "firefox" || string(apps[15:]) != "code" {
+		t.Errorf("app page = %q", apps)
+	}
+	if times := page(2); int64(binary.LittleEndian.Uint64(times)) != at.UnixMicro() {
+		t.Errorf("at = %d, want %d", binary.LittleEndian.Uint64(times), at.UnixMicro())
+	}
+	if days := page(3); binary.LittleEndian.Uint32(days) != 19783 || binary.LittleEndian.Uint32(days[4:]) != 19783 {
+		t.Errorf("day page = %v, want 19783 twice", days)
+	}
+	if idle := page(4); !bytes.Equal(idle, []byte{0b01}) {
+		t.Errorf("idle page = %08b, want 00000001", idle)
+	}
+	if share := page(5); math.Float64frombits(binary.LittleEndian.Uint64(share[8:])) != 0.25 {
+		t.Errorf("share page = %v", share)
+	}
+	// Levels 1, 0 as two RLE runs, then the single non-null value.
+	want := []byte{4, 0, 0, 0, 2, 1, 2, 0, 0x10, 0x0e, 0, 0}
+	if offsets := page(6); !bytes.Equal(offsets, want) {
+		t.Errorf("offset page = %v, want %v", offsets, want)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, "", Column{Name: "id", Type: Int64})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	meta := readFooter(t, out.Bytes())
+	if meta[3].(int64) != 0 || len(meta[4].([]interface{})) != 0 {
+		t.Errorf("empty file metadata = %v", meta)
+	}
+}
+
+func TestWriterRejectsBadValues(t *testing.T) {
+	tests := [][]interface{}{
+		{int64(1)},
+		{nil, "2024-01-01"},
+		{"1", "2024-01-01"},
+		{int64(1), "01/01/2024"},
+	}
+	for _, row := range tests {
+		w := NewWriter(io.Discard, "",
+			Column{Name: "id", Type: Int64},
+			Column{Name: "day", Type: Date},
+		)
+		if err := w.Write(row...); err == nil {
+			t.Errorf("Write(%v) succeeded, want error", row)
+		}
+		// A partly written row leaves the writer unusable.
+		if err := w.Close(); len(row) == 2 && err == nil {
+			t.Errorf("Close() after Write(%v) succeeded, want error", row)
+		}
+	}
+}
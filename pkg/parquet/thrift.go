@@ -0,0 +1,104 @@
+package parquet
+
+// Parquet metadata is serialized with Thrift's compact protocol. Only the
+// writing side, and only the types the file metadata uses, are needed.
+
+const (
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+type thriftWriter struct {
+	buf    []byte
+	lastID int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf = append(t.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	t.buf = append(t.buf, byte(v))
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.zigzag(int64(id))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) listHeader(size int, elem byte) {
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elem)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, ctI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, ctI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) string(id int16, s string) {
+	t.field(id, ctBinary)
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// body writes a struct's fields with fn and terminates it. Field IDs inside
+// are relative to the struct, so the enclosing position is saved.
+func (t *thriftWriter) body(fn func()) {
+	saved := t.lastID
+	t.lastID = 0
+	fn()
+	t.buf = append(t.buf, 0)
+	t.lastID = saved
+}
+
+func (t *thriftWriter) structField(id int16, fn func()) {
+	t.field(id, ctStruct)
+	t.body(fn)
+}
+
+func (t *thriftWriter) structList(id int16, n int, fn func(i int)) {
+	t.field(id, ctList)
+	t.listHeader(n, ctStruct)
+	for i := 0; i < n; i++ {
+		t.body(func() { fn(i) })
+	}
+}
+
+func (t *thriftWriter) i32List(id int16, values ...int32) {
+	t.field(id, ctList)
+	t.listHeader(len(values), ctI32)
+	for _, v := range values {
+		t.zigzag(int64(v))
+	}
+}
+
+func (t *thriftWriter) stringList(id int16, values ...string) {
+	t.field(id, ctList)
+	t.listHeader(len(values), ctBinary)
+	for _, v := range values {
+		t.varint(uint64(len(v)))
+		t.buf = append(t.buf, v...)
+	}
+}
@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTo(t *testing.T) {
+	doc := &Document{Title: "Weekly (digest)", Created: time.Date(2024, 3, 11, 9, 0, 0, 0, time.FixedZone("", 3600))}
+	p := doc.AddPage()
+	p.Text(56, 780, 20, true, Gray(0), "Café – 5h 30m")
+	p.Rect(56, 700, 120.5, 8, Color{0.2, 0.4, 0.8})
+	doc.AddPage().Line(0, 0, 10, 10, 1, Gray(0.5))
+
+	data := doc.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing header or trailer:\n%s", data)
+	}
+	for _, want := range []string{
+		"/Count 2",
+		"/Kids [6 0 R 8 0 R]",
+		"/Title (Weekly \\(digest\\))",
+		"/CreationDate (D:20240311090000+01'00')",
+		"/BaseFont /Helvetica-Bold",
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("document lacks %q", want)
+		}
+	}
+
+	// Every cross-reference entry points at its object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 10\n")) {
+		t.Fatalf("startxref %d doesn't point at the table", xref)
+	}
+	entries := strings.Split(string(data[xref:]), "\n")[3:12]
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("entry %d points at %q", i+1, data[offset:offset+10])
+		}
+	}
+
+	// The first page's content stream.
+	start := bytes.Index(data, []byte("7 0 obj\n"))
+	start += bytes.Index(data[start:], []byte("stream\n")) + len("stream\n")
+	zr, err := zlib.NewReader(bytes.NewReader(data[start:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0 0 0 rg BT /F2 20 Tf 56 780 Td (Caf\xe9 \x96 5h 30m) Tj ET\n" +
+		"0.2 0.4 0.8 rg 56 700 120.5 8 re f\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	for in, want := range map[string]string{
+		`a(b)c\d`: `(a\(b\)c\\d)`,
+		"line\n":  `(line\n)`,
+		"日本":      "(??)",
+	} {
+		if got := literal(in); got != want {
+			t.Errorf("literal(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if got := TextWidth("Hi", 10, false); got != 9.44 {
+		t.Errorf("TextWidth = %v, want 9.44", got)
+	}
+	if got := TextWidth("Hi", 10, true); got != 10 {
+		t.Errorf("bold TextWidth = %v, want 10", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short", 100, 10, false); got != "short" {
+		t.Errorf("Truncate = %q", got)
+	}
+	got := Truncate("a rather long application name", 60, 10, false)
+	if !strings.HasSuffix(got, "…") || TextWidth(got, 10, false) > 60 {
+		t.Errorf("Truncate = %q, %v wide", got, TextWidth(got, 10, false))
+	}
+}
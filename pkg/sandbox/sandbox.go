@@ -0,0 +1,148 @@
+// Package sandbox runs the external tools actionsum relies on so that it
+// also works when distributed as a Flatpak. Inside the sandbox those tools
+// would only see the sandbox, or not exist at all, so they are run on the
+// host through flatpak-spawn when the app may talk to
+// org.freedesktop.Flatpak, and inside the sandbox otherwise. Outside Flatpak
+// everything runs directly.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// infoFile exists in every Flatpak sandbox.
+const infoFile = "/.flatpak-info"
+
+var (
+	flatpakOnce sync.Once
+	flatpak     bool
+
+	hostOnce sync.Once
+	host     bool
+
+	pathsMu sync.Mutex
+	paths   = map[string]string{}
+)
+
+// Flatpak reports whether actionsum runs inside a Flatpak sandbox.
+func Flatpak() bool {
+	flatpakOnce.Do(func() {
+		_, err := os.Stat(infoFile)
+		flatpak = err == nil || os.Getenv("FLATPAK_ID") != ""
+	})
+	return flatpak
+}
+
+// AppID returns the Flatpak application ID, or "" outside Flatpak.
+func AppID() string {
+	return os.Getenv("FLATPAK_ID")
+}
+
+// HostAccess reports whether commands can be run on the host, which needs
+// the --talk-name=org.freedesktop.Flatpak permission.
+func HostAccess() bool {
+	if !Flatpak() {
+		return false
+	}
+	hostOnce.Do(func() {
+		host = exec.Command("flatpak-spawn", "--host", "true").Run() == nil
+	})
+	return host
+}
+
+// Command returns a command running name on the host when inside Flatpak
+// with host access, and directly otherwise.
+func Command(name string, args ...string) *exec.Cmd {
+	if HostAccess() {
+		return exec.Command("flatpak-spawn", hostArgs(name, args)...)
+	}
+	return exec.Command(name, args...)
+}
+
+// CommandContext is Command with a context that kills the command.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if HostAccess() {
+		return exec.CommandContext(ctx, "flatpak-spawn", hostArgs(name, args)...)
+	}
+	return exec.CommandContext(ctx, name, args...)
+}
+
+// SetEnv adds NAME=value variables to the environment cmd runs with. Host
+// commands don't inherit the sandbox's environment, so for them the variables
+// are passed to flatpak-spawn instead.
+func SetEnv(cmd *exec.Cmd, vars ...string) {
+	if HostAccess() && len(cmd.Args) > 1 && cmd.Args[1] == "--host" {
+		flags := make([]string, len(vars))
+		for i, v := range vars {
+			flags[i] = "--env=" + v
+		}
+		cmd.Args = append(cmd.Args[:2], append(flags, cmd.Args[2:]...)...)
+		return
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, vars...)
+}
+
+func hostArgs(name string, args []string) []string {
+	return append([]string{"--host", "--watch-bus", name}, args...)
+}
+
+// LookPath finds name where Command would run it. Answers from the host are
+// cached, since asking costs a process spawn.
+func LookPath(name string) (string, error) {
+	if !HostAccess() {
+		return exec.LookPath(name)
+	}
+
+	pathsMu.Lock()
+	path, ok := paths[name]
+	pathsMu.Unlock()
+	if !ok {
+		output, err := exec.Command("flatpak-spawn", "--host", "sh", "-c", `command -v "$1"`, "sh", name).Output()
+		if err == nil {
+			path = strings.TrimSpace(string(output))
+		}
+		pathsMu.Lock()
+		paths[name] = path
+		pathsMu.Unlock()
+	}
+	if path == "" {
+		return "", fmt.Errorf("%s: not found on the host", name)
+	}
+	return path, nil
+}
+
+// RuntimeDir is where per-session files such as the PID file belong. /tmp is
+// private to each Flatpak instance, so inside Flatpak they go to the app's
+// directory under XDG_RUNTIME_DIR, which every instance shares.
+func RuntimeDir() string {
+	if Flatpak() {
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && AppID() != "" {
+			return filepath.Join(runtimeDir, "app", AppID())
+		}
+	}
+	return "/tmp"
+}
+
+// UserRuntimeDir is where per-session files that only the user may read or
+// write belong: actionsum's directory under XDG_RUNTIME_DIR, which is the
+// app's own directory inside Flatpak, or one named after the user in
+// RuntimeDir without XDG_RUNTIME_DIR.
+func UserRuntimeDir() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	switch {
+	case runtimeDir == "":
+		return filepath.Join(RuntimeDir(), fmt.Sprintf("actionsum-%d", os.Getuid()))
+	case Flatpak() && AppID() != "":
+		return filepath.Join(runtimeDir, "app", AppID())
+	}
+	return filepath.Join(runtimeDir, "actionsum")
+}
@@ -0,0 +1,38 @@
+package sandbox
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestHostArgs(t *testing.T) {
+	got := hostArgs("xdotool", []string{"getactivewindow"})
+	want := []string{"--host", "--watch-bus", "xdotool", "getactivewindow"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hostArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestSetEnv(t *testing.T) {
+	if HostAccess() {
+		t.Skip("running inside Flatpak with host access")
+	}
+	cmd := exec.Command("true")
+	SetEnv(cmd, "ACTIONSUM_TEST=1")
+	if len(cmd.Env) == 0 || cmd.Env[len(cmd.Env)-1] != "ACTIONSUM_TEST=1" {
+		t.Errorf("Env ends with %v, want ACTIONSUM_TEST=1", cmd.Env[len(cmd.Env)-1:])
+	}
+	if len(cmd.Env) < 2 {
+		t.Error("SetEnv() dropped the inherited environment")
+	}
+}
+
+func TestRuntimeDir(t *testing.T) {
+	if Flatpak() {
+		t.Skip("running inside Flatpak")
+	}
+	if got := RuntimeDir(); got != "/tmp" {
+		t.Errorf("RuntimeDir() = %q, want /tmp", got)
+	}
+}
@@ -0,0 +1,191 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring weekly time range such as "mon-fri 09:00-17:00".
+// Start and End are minutes after midnight; an End before Start wraps past
+// midnight and belongs to the day it started on.
+type Window struct {
+	Days  [7]bool
+	Start int
+	End   int
+}
+
+// Parse reads "DAYS HH:MM-HH:MM". DAYS is "*", a range ("mon-fri") or a
+// comma separated list ("mon,wed,sat"), and may be omitted for every day.
+func Parse(spec string) (Window, error) {
+	var w Window
+
+	fields := strings.Fields(strings.ToLower(spec))
+	var days, hours string
+	switch len(fields) {
+	case 1:
+		days, hours = "*", fields[0]
+	case 2:
+		days, hours = fields[0], fields[1]
+	default:
+		return w, fmt.Errorf("invalid schedule %q: want \"DAYS HH:MM-HH:MM\"", spec)
+	}
+
+	if err := w.parseDays(days); err != nil {
+		return w, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid schedule %q: want HH:MM-HH:MM", spec)
+	}
+	var err error
+	if w.Start, err = parseClock(from); err != nil {
+		return w, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if w.End, err = parseClock(to); err != nil {
+		return w, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if w.Start == w.End {
+		return w, fmt.Errorf("invalid schedule %q: empty time range", spec)
+	}
+
+	return w, nil
+}
+
+// ParseList parses windows separated by ";".
+func ParseList(spec string) ([]Window, error) {
+	var windows []Window
+	for _, part := range strings.Split(spec, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		w, err := Parse(part)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func (w *Window) parseDays(spec string) error {
+	if spec == "*" {
+		for i := range w.Days {
+			w.Days[i] = true
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := dayNames[from]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		if !isRange {
+			w.Days[start] = true
+			continue
+		}
+		end, ok := dayNames[to]
+		if !ok {
+			return fmt.Errorf("unknown day %q", to)
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			w.Days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t falls inside the window, using t's location.
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return w.Days[t.Weekday()] && minute >= w.Start && minute < w.End
+	}
+	if minute >= w.Start {
+		return w.Days[t.Weekday()]
+	}
+	if minute < w.End {
+		return w.Days[(t.Weekday()+6)%7]
+	}
+	return false
+}
+
+// Any reports whether t falls inside at least one of the windows.
+func Any(windows []Window, t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkDays reports which weekdays the windows fall on, indexed by
+// time.Weekday. With no windows every day is a working day.
+func WorkDays(windows []Window) [7]bool {
+	var days [7]bool
+	for _, w := range windows {
+		for d, ok := range w.Days {
+			days[d] = days[d] || ok
+		}
+	}
+	if len(windows) == 0 {
+		days = [7]bool{true, true, true, true, true, true, true}
+	}
+	return days
+}
+
+// Overlap returns how much of [start, end) falls inside any of the windows,
+// evaluated minute by minute in start's location.
+func Overlap(windows []Window, start, end time.Time) time.Duration {
+	var total time.Duration
+	for t := start; t.Before(end); {
+		next := t.Truncate(time.Minute).Add(time.Minute)
+		if next.After(end) {
+			next = end
+		}
+		if Any(windows, t) {
+			total += next.Sub(t)
+		}
+		t = next
+	}
+	return total
+}
+
+func (w Window) String() string {
+	var days []string
+	for _, name := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		if w.Days[dayNames[name]] {
+			days = append(days, name)
+		}
+	}
+	return fmt.Sprintf("%s %02d:%02d-%02d:%02d", strings.Join(days, ","),
+		w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
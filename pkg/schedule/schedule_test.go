@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"mon-fri",
+		"mon-fri 9-17",
+		"funday 09:00-17:00",
+		"mon-fri 09:00-09:00",
+		"mon-fri 25:00-26:00",
+		"mon fri 09:00-17:00",
+	}
+
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", spec)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		at       time.Time
+		expected bool
+	}{
+		{"Weekday inside", "mon-fri 09:00-17:00", at(1, 9, 0), true},
+		{"Weekday end is exclusive", "mon-fri 09:00-17:00", at(1, 17, 0), false},
+		{"Weekday before start", "mon-fri 09:00-17:00", at(1, 8, 59), false},
+		{"Weekend", "mon-fri 09:00-17:00", at(6, 12, 0), false},
+		{"Day list", "tue,thu 10:00-12:00", at(4, 11, 0), true},
+		{"Day list miss", "tue,thu 10:00-12:00", at(3, 11, 0), false},
+		{"Wrapping week range", "fri-mon 10:00-12:00", at(7, 11, 0), true},
+		{"Every day", "08:00-09:00", at(7, 8, 30), true},
+		{"Overnight same day", "fri 22:00-02:00", at(5, 23, 0), true},
+		{"Overnight next morning", "fri 22:00-02:00", at(6, 1, 0), true},
+		{"Overnight wrong day", "fri 22:00-02:00", at(5, 1, 0), false},
+		{"Until midnight", "* 18:00-24:00", at(2, 23, 59), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.spec, err)
+			}
+			if got := w.Contains(tt.at); got != tt.expected {
+				t.Errorf("%q.Contains(%v) = %v, want %v", tt.spec, tt.at, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	windows, err := ParseList("mon-fri 09:00-12:00; mon-fri 13:00-17:00")
+	if err != nil {
+		t.Fatalf("ParseList() error: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("ParseList() returned %d windows, want 2", len(windows))
+	}
+
+	lunch := time.Date(2024, 1, 2, 12, 30, 0, 0, time.UTC)
+	if Any(windows, lunch) {
+		t.Error("Any() = true during lunch break, want false")
+	}
+
+	afternoon := time.Date(2024, 1, 2, 14, 0, 0, 0, time.UTC)
+	if !Any(windows, afternoon) {
+		t.Error("Any() = false in afternoon, want true")
+	}
+
+	if got := windows[0].String(); got != "mon,tue,wed,thu,fri 09:00-12:00" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	windows, err := ParseList("mon-fri 09:00-17:00")
+	if err != nil {
+		t.Fatalf("ParseList() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		duration time.Duration
+		expected time.Duration
+	}{
+		{"Fully inside", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 5 * time.Minute, 5 * time.Minute},
+		{"Fully outside", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC), 5 * time.Minute, 0},
+		{"Straddles end", time.Date(2024, 1, 1, 16, 59, 30, 0, time.UTC), time.Minute, 30 * time.Second},
+		{"Straddles start", time.Date(2024, 1, 1, 8, 58, 0, 0, time.UTC), 5 * time.Minute, 3 * time.Minute},
+		{"Empty", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlap(windows, tt.start, tt.start.Add(tt.duration)); got != tt.expected {
+				t.Errorf("Overlap() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorkDays(t *testing.T) {
+	windows, err := ParseList("mon-thu 09:00-17:00; fri 09:00-12:00")
+	if err != nil {
+		t.Fatalf("ParseList() error: %v", err)
+	}
+	want := [7]bool{false, true, true, true, true, true, false}
+	if got := WorkDays(windows); got != want {
+		t.Errorf("WorkDays() = %v, want %v", got, want)
+	}
+	if got := WorkDays(nil); got != [7]bool{true, true, true, true, true, true, true} {
+		t.Errorf("WorkDays(nil) = %v, want every day", got)
+	}
+}
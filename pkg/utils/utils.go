@@ -1,6 +1,9 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"unicode/utf8"
+)
 
 func FormatRoundedUnit(seconds int64) string {
 	if seconds < 0 {
@@ -14,3 +17,46 @@ func FormatRoundedUnit(seconds int64) string {
 	}
 	return fmt.Sprintf("%dm", int64(seconds/60))
 }
+
+func FormatHoursMinutes(seconds int64) string {
+	if seconds < 0 {
+		seconds = -seconds
+	}
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	if minutes == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%dm", hours, minutes)
+}
+
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// TruncateBytes cuts s to at most n bytes without splitting a UTF-8
+// character.
+func TruncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
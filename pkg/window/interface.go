@@ -4,13 +4,50 @@ type WindowInfo struct {
 	AppName       string
 	WindowTitle   string
 	ProcessName   string
-	DisplayServer string // "x11" or "wayland"
+	PID           int    // Process owning the window, 0 when the backend can't tell
+	DisplayServer string // "x11", "wayland", "macos" or "windows"
+	Geometry      Geometry
+	IsFullscreen  bool
+	IsMaximized   bool
+
+	// DetectionMethod says how the window was found: "window" from the
+	// display server, "hybrid" when a process guess was confirmed by it, or
+	// "process-based" for a guess from running processes alone. Confidence
+	// is from 0 to 1.
+	DetectionMethod string
+	Confidence      float64
+
+	// Client is ClientNative or ClientXWayland for windows on a Wayland
+	// session when the backend can tell, and empty otherwise.
+	Client string
+
+	// Output is the monitor the window is on as the display server names
+	// it, e.g. "DP-1" or "eDP-1", or empty when the backend can't tell.
+	Output string
+}
+
+const (
+	ClientNative   = "native"
+	ClientXWayland = "xwayland"
+)
+
+// Geometry is the window position and size in screen pixels. A zero value
+// means the backend could not report it.
+type Geometry struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
 }
 
 type IdleInfo struct {
 	IsIdle   bool
 	IsLocked bool
 	IdleTime int64 // Idle time in seconds
+
+	// HasAudioPlaying is set when sound is playing, as while a video plays.
+	// Only detectors asked to check, currently on Linux, set it.
+	HasAudioPlaying bool
 }
 
 type Detector interface {
@@ -20,3 +57,36 @@ type Detector interface {
 	GetDisplayServer() string
 	Close() error
 }
+
+// LockNotifier is implemented by detectors that can push screen lock
+// transitions as they happen instead of waiting for the next poll.
+type LockNotifier interface {
+	LockChanges() <-chan bool
+}
+
+// FocusNotifier is implemented by detectors that can tell when the focused
+// window changes, so it can be looked up right away instead of at the next
+// poll. Changes are coalesced: a receive means at least one change since the
+// last.
+type FocusNotifier interface {
+	FocusChanges() <-chan struct{}
+}
+
+// BackendStats counts the lookups a detection backend attempted.
+type BackendStats struct {
+	Successes int64 `json:"successes"`
+	Failures  int64 `json:"failures"`
+}
+
+// StatsReporter is implemented by detectors that count lookups per backend,
+// keyed by backend name such as "x11", "wayland" or "process".
+type StatsReporter interface {
+	BackendStats() map[string]BackendStats
+}
+
+// CoverageReporter is implemented by detectors that know when some windows
+// are invisible to them, such as native Wayland apps when only XWayland can
+// be queried. Limitations describes each such gap currently in effect.
+type CoverageReporter interface {
+	Limitations() []string
+}
@@ -0,0 +1,18 @@
+//go:build noweb
+
+package main
+
+import (
+	"log"
+
+	"github.com/actionsum/actionsum/internal/daemon"
+)
+
+// webIncluded reports whether this build has the web server; builds tagged
+// noweb leave it out.
+const webIncluded = false
+
+// runServeDaemon is never reached: serveDaemon refuses first.
+func (h *CommandHandler) runServeDaemon(dm *daemon.Daemon, customPort int) {
+	log.Fatalf("Cannot serve: %s", h.webUnavailable())
+}
@@ -0,0 +1,114 @@
+//go:build !noweb
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/actionsum/actionsum/internal/alert"
+	"github.com/actionsum/actionsum/internal/budget"
+	"github.com/actionsum/actionsum/internal/daemon"
+	"github.com/actionsum/actionsum/internal/database"
+	"github.com/actionsum/actionsum/internal/digest"
+	"github.com/actionsum/actionsum/internal/notify"
+	"github.com/actionsum/actionsum/internal/tracker"
+	"github.com/actionsum/actionsum/internal/web"
+)
+
+// webIncluded reports whether this build has the web server; builds tagged
+// noweb leave it out.
+const webIncluded = true
+
+func (h *CommandHandler) runServeDaemon(dm *daemon.Daemon, customPort int) {
+	logPath := daemonLogPath()
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err == nil {
+		log.SetOutput(logFile)
+		defer logFile.Close()
+	}
+	if err := daemon.ApplyResourceLimits(h.cfg); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	h.recoverCorruptDatabase()
+	db, repo := h.openDatabase()
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	det, err := h.newDetector()
+	if err != nil {
+		log.Fatalf("Failed to initialize window detector: %v", err)
+	}
+	defer det.Close()
+	log.Printf("Window detector initialized: %s", det.GetDisplayServer())
+	if err := dm.WritePID(); err != nil {
+		log.Fatalf("Failed to write PID file: %v", err)
+	}
+	defer dm.RemovePID()
+	trackerSvc := tracker.NewService(h.cfg, repo, det)
+	notifier := notify.FromConfig(h.cfg)
+	webServer := web.NewServer(h.cfg, repo, customPort)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	if h.cfg.Notify.WeeklyDigest && notifier != nil {
+		go digest.NewScheduler(repo, notifier, budget.FromConfig(h.cfg, repo), h.cfg.Holidays, h.cfg.WorkHours.Windows, h.cfg.Notify.DigestPDF).Run(ctx)
+	}
+	if enforcer := budget.NewEnforcer(h.cfg, repo, notifier); enforcer != nil {
+		go enforcer.Run(ctx)
+	}
+	if evaluator := alert.NewEvaluator(h.cfg, repo, trackerSvc.Live(), notifier); evaluator != nil {
+		go evaluator.Run(ctx)
+	}
+	go daemon.NewSizeMonitor(db, h.cfg, notifier).Run(ctx)
+	if publisher := daemon.NewStatsPublisher(h.cfg.Daemon.StatsFile, det); publisher != nil {
+		publisher.UsePolls(trackerSvc.Polls())
+		go publisher.Run(ctx)
+	}
+	webServer.UseHistory(trackerSvc.History())
+	webServer.UseLive(trackerSvc.Live())
+	if h.cfg.Report.SnapshotInterval > 0 {
+		snapshot := database.NewSnapshot(db, repo)
+		defer snapshot.Close()
+		webServer.UseSnapshot(snapshot)
+		go snapshot.Run(ctx, h.cfg.Report.SnapshotInterval)
+	}
+	if h.cfg.Web.MDNS {
+		go webServer.Advertise(ctx)
+	}
+	go func() {
+		if err := webServer.Start(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Web server error: %v", err)
+		}
+	}()
+	go func() {
+		if err := trackerSvc.Start(ctx); err != nil && err != context.Canceled {
+			log.Printf("Tracker error: %v", err)
+			cancel()
+		}
+	}()
+	log.Println("Starting actionsum daemon with web API...")
+	log.Printf("Web API available at: http://%s", webServer.GetAddress())
+	if h.cfg.WebExposed() && !h.cfg.Web.RequireToken {
+		log.Printf("Warning: serving window titles on %s to anyone who can reach it, without requiring a token", h.cfg.Web.Host)
+	}
+	log.Printf("Configuration:\n%s", h.cfg.String())
+	<-sigChan
+	log.Println("Received shutdown signal")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	cancel()
+	trackerSvc.Stop()
+	if err := webServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down web server: %v", err)
+	}
+	log.Println("Daemon stopped successfully")
+}
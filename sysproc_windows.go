@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// detachedProcess starts a console process without a console.
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the daemon without a console and in a process
+// group of its own, so closing the terminal doesn't stop it.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}